@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 
@@ -33,15 +35,17 @@ type Manager interface {
 
 	// Increases Approval votes by 1
 	Approve(identifier, voter string) (*types.Approval, error)
-	// Rejects Approval
-	Reject(identifier string) (*types.Approval, error)
+	// Rejects Approval, voter is recorded in the audit log
+	Reject(identifier, voter string) (*types.Approval, error)
 
 	Get(identifier string) (*types.Approval, error)
 	List() ([]*types.Approval, error)
-	Delete(*types.Approval) error
+	// Delete approval, voter is recorded in the audit log
+	Delete(approval *types.Approval, voter string) error
 	Archive(identifier string) error
 
 	StartExpiryService(ctx context.Context) error
+	StartReminderService(ctx context.Context) error
 }
 
 // Approvals related errors
@@ -49,11 +53,31 @@ var (
 	ErrApprovalAlreadyExists = errors.New("approval already exists")
 )
 
+// VoterNotAllowedError - returned when voter is not in the approval's approvers list
+type VoterNotAllowedError struct {
+	Voter      string
+	Identifier string
+}
+
+func (e *VoterNotAllowedError) Error() string {
+	if e.Voter == "" {
+		return fmt.Sprintf("anonymous votes are not allowed for '%s', only listed approvers can vote", e.Identifier)
+	}
+	return fmt.Sprintf("user '%s' is not allowed to vote for '%s', only listed approvers can vote", e.Voter, e.Identifier)
+}
+
 // Approvals cache prefix
 const (
 	ApprovalsPrefix = "approvals"
 )
 
+// expiryCheckInterval - how often approval deadlines are checked
+const expiryCheckInterval = time.Minute
+
+// ExpiryVoter - voter recorded when approval is approved or rejected once its
+// deadline is reached
+const ExpiryVoter = "keel"
+
 // DefaultManager - default manager implementation
 type DefaultManager struct {
 	// cache is used to store approvals, key example:
@@ -62,6 +86,17 @@ type DefaultManager struct {
 
 	store store.Store
 
+	// sender notifies escalation channels once approval deadline is reached, optional
+	sender notification.Sender
+
+	// approverGroups - members of @groups used in approvers lists
+	approverGroups map[string][]string
+
+	// reminderAfter - pending approvals are requested again after this period, 0 disables reminders
+	reminderAfter time.Duration
+	// digestTime - "HH:MM" UTC when pending approvals digest is sent, empty disables digest
+	digestTime string
+
 	// subscriber channels
 	channels map[uint32]chan *types.Approval
 	index    uint32
@@ -74,7 +109,16 @@ type DefaultManager struct {
 }
 
 type Opts struct {
-	Store store.Store
+	Store  store.Store
+	Sender notification.Sender
+	// ApproverGroups - group name (without "@") to members, see ParseApproverGroups
+	ApproverGroups map[string][]string
+	// ReminderAfter - pending approvals are requested again each time this period
+	// passes, 0 disables reminders
+	ReminderAfter time.Duration
+	// DigestTime - time of day in UTC ("09:00") when pending approvals digest is sent,
+	// empty disables digest, see ParseDigestTime
+	DigestTime string
 	// Cache cache.Cache
 }
 
@@ -82,21 +126,60 @@ type Opts struct {
 func New(opts *Opts) *DefaultManager {
 	man := &DefaultManager{
 		// cache:      opts.Cache,
-		store:      opts.Store,
-		channels:   make(map[uint32]chan *types.Approval),
-		approvedCh: make(map[uint32]chan *types.Approval),
-		index:      0,
-		mu:         &sync.Mutex{},
-		subMu:      &sync.RWMutex{},
+		store:          opts.Store,
+		sender:         opts.Sender,
+		approverGroups: opts.ApproverGroups,
+		reminderAfter:  opts.ReminderAfter,
+		digestTime:     opts.DigestTime,
+		channels:       make(map[uint32]chan *types.Approval),
+		approvedCh:     make(map[uint32]chan *types.Approval),
+		index:          0,
+		mu:             &sync.Mutex{},
+		subMu:          &sync.RWMutex{},
 	}
 
 	return man
 }
 
+// ParseApproverGroups - parses approver groups, ie: "platform-team=alice,bob;sre=carol"
+func ParseApproverGroups(groups string) (map[string][]string, error) {
+	parsed := make(map[string][]string)
+	for _, group := range strings.Split(groups, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		parts := strings.SplitN(group, "=", 2)
+		name := strings.TrimPrefix(strings.TrimSpace(parts[0]), "@")
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid approver group '%s', expected <group>=<user>,<user>", group)
+		}
+		for _, member := range strings.Split(parts[1], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				parsed[name] = append(parsed[name], member)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// checkVoter - only users listed in approvers (directly or through a group) can vote
+func (m *DefaultManager) checkVoter(approval *types.Approval, voter string) error {
+	if approval.CanVote(voter, m.approverGroups) {
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"identifier": approval.Identifier,
+		"voter":      voter,
+		"approvers":  approval.Approvers,
+	}).Warn("approvals.manager: vote from user that is not an approver refused")
+	return &VoterNotAllowedError{Voter: voter, Identifier: approval.Identifier}
+}
+
 // StartExpiryService - starts approval expiry service which deletes approvals
 // that already reached their deadline
 func (m *DefaultManager) StartExpiryService(ctx context.Context) error {
-	ticker := time.NewTicker(60 * time.Minute)
+	ticker := time.NewTicker(expiryCheckInterval)
 	defer ticker.Stop()
 	err := m.expireEntries()
 	if err != nil {
@@ -129,20 +212,115 @@ func (m *DefaultManager) expireEntries() error {
 	}
 
 	for _, approval := range approvals {
-		if approval.Expired() {
-			err = m.Delete(approval)
+		if !approval.Expired() {
+			continue
+		}
+
+		if !approval.Archived && !approval.Escalated && approval.Status() == types.ApprovalStatusPending &&
+			approval.ExpiryAction != "" && approval.ExpiryAction != types.ApprovalExpiryExpire {
+			err = m.takeExpiryAction(approval)
 			if err != nil {
 				log.WithFields(log.Fields{
-					"error": err,
-					// "identifier": k,
-				}).Error("approvals.expireEntries: failed to delete expired approval")
-				continue
+					"error":      err,
+					"identifier": approval.Identifier,
+					"action":     approval.ExpiryAction,
+				}).Error("approvals.expireEntries: failed to take expiry action")
 			}
+			continue
+		}
+
+		err = m.Delete(approval, "")
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				// "identifier": k,
+			}).Error("approvals.expireEntries: failed to delete expired approval")
+			continue
+		}
+
+		m.addAuditEntry(approval, types.AuditActionApprovalExpired, "")
+	}
 
-			m.addAuditEntry(approval, types.AuditActionApprovalExpired, "")
+	return nil
+}
+
+// takeExpiryAction - escalates, approves or rejects pending approval that reached its
+// deadline. Deadline is extended by the original period so escalated requests can still
+// be voted on and rejections hold, approval expires once the new deadline is reached
+func (m *DefaultManager) takeExpiryAction(approval *types.Approval) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	period := approval.Deadline.Sub(approval.CreatedAt)
+	if period <= 0 {
+		period = time.Duration(types.KeelApprovalDeadlineDefault) * time.Hour
+	}
+	approval.Deadline = time.Now().Add(period)
+	approval.Escalated = true
+
+	var (
+		action  string
+		voter   string
+		message string
+		level   = types.LevelWarn
+	)
+	switch approval.ExpiryAction {
+	case types.ApprovalExpiryEscalate:
+		action = types.AuditActionApprovalEscalated
+		message = fmt.Sprintf("Approval deadline reached, %d/%d votes received: %s", approval.VotesReceived, approval.VotesRequired, approval.Message)
+	case types.ApprovalExpiryApprove:
+		action, voter = types.AuditActionApprovalApproved, ExpiryVoter
+		approval.AddVoter(ExpiryVoter)
+		approval.VotesReceived = approval.VotesRequired
+		message = fmt.Sprintf("Approval deadline reached, update was approved: %s", approval.Message)
+		level = types.LevelInfo
+	case types.ApprovalExpiryReject:
+		action, voter = types.AuditActionApprovalRejected, ExpiryVoter
+		approval.Rejected = true
+		message = fmt.Sprintf("Approval deadline reached, update was rejected: %s", approval.Message)
+	default:
+		return fmt.Errorf("unknown expiry action '%s'", approval.ExpiryAction)
+	}
+
+	// publishing approved approvals so the update is applied
+	if err := m.Update(approval); err != nil {
+		return err
+	}
+	m.addAuditEntry(approval, action, voter)
+
+	if approval.ExpiryAction == types.ApprovalExpiryEscalate {
+		m.publishRequest(approval)
+	}
+
+	if m.sender != nil {
+		var channels []string
+		if approval.EscalationChannels != "" {
+			channels = strings.Split(approval.EscalationChannels, ",")
+		}
+		err := m.sender.Send(types.EventNotification{
+			ResourceKind: types.AuditResourceKindApproval,
+			Identifier:   approval.Identifier,
+			Name:         action,
+			Message:      message,
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationApprovalExpired,
+			Level:        level,
+			Channels:     channels,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"identifier": approval.Identifier,
+			}).Error("approvals.takeExpiryAction: failed to send notification")
 		}
 	}
 
+	log.WithFields(log.Fields{
+		"identifier": approval.Identifier,
+		"action":     approval.ExpiryAction,
+		"deadline":   approval.Deadline,
+	}).Info("approvals.manager: approval deadline reached")
+
 	return nil
 }
 
@@ -251,6 +429,11 @@ func (m *DefaultManager) Approve(identifier, voter string) (*types.Approval, err
 		return nil, err
 	}
 
+	if err := m.checkVoter(existing, voter); err != nil {
+		return nil, err
+	}
+
+	// quorum is reached by distinct approvers, repeated votes are not counted
 	for _, v := range existing.GetVoters() {
 		if v == voter {
 			// nothing to do, same voter
@@ -310,7 +493,7 @@ func (m *DefaultManager) addAuditEntry(approval *types.Approval, action string,
 
 // Reject - rejects approval (marks rejected=true), approval will not be valid even if it
 // collects required votes
-func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
+func (m *DefaultManager) Reject(identifier, voter string) (*types.Approval, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -319,6 +502,10 @@ func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
 		return nil, err
 	}
 
+	if err := m.checkVoter(existing, voter); err != nil {
+		return nil, err
+	}
+
 	existing.Rejected = true
 
 	err = m.Update(existing)
@@ -326,7 +513,12 @@ func (m *DefaultManager) Reject(identifier string) (*types.Approval, error) {
 		return nil, err
 	}
 
-	m.addAuditEntry(existing, types.AuditActionApprovalRejected, "")
+	m.addAuditEntry(existing, types.AuditActionApprovalRejected, voter)
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"voter":      voter,
+	}).Info("approvals.manager: rejected")
 
 	return existing, nil
 }
@@ -359,7 +551,7 @@ func (m *DefaultManager) List() ([]*types.Approval, error) {
 }
 
 // Delete - delete specified approval
-func (m *DefaultManager) Delete(approval *types.Approval) error {
+func (m *DefaultManager) Delete(approval *types.Approval, voter string) error {
 	existing, err := m.store.GetApproval(&types.GetApprovalQuery{
 		ID: approval.ID,
 	})
@@ -367,7 +559,7 @@ func (m *DefaultManager) Delete(approval *types.Approval) error {
 		return err
 	}
 
-	m.addAuditEntry(existing, types.AuditActionDeleted, "")
+	m.addAuditEntry(existing, types.AuditActionDeleted, voter)
 
 	return m.store.DeleteApproval(existing)
 }
@@ -394,6 +586,14 @@ func (m *DefaultManager) Create(r *types.Approval) error {
 	r.CreatedAt = time.Now()
 	r.UpdatedAt = time.Now()
 
+	if approvers := r.GetApprovers(); len(approvers) > 0 && !strings.Contains(r.Approvers, "@") && len(approvers) < r.VotesRequired {
+		log.WithFields(log.Fields{
+			"identifier":     r.Identifier,
+			"approvers":      r.Approvers,
+			"votes_required": r.VotesRequired,
+		}).Warn("approvals.manager: there are fewer approvers than required votes, approval can't be approved")
+	}
+
 	created, err := m.store.CreateApproval(r)
 	if err != nil {
 		return fmt.Errorf("failed to create approval: %s", err)
@@ -11,6 +11,7 @@ import (
 
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 
+	"github.com/keel-hq/keel/extension/notification"
 	"github.com/keel-hq/keel/pkg/store/sql"
 	"github.com/keel-hq/keel/types"
 )
@@ -90,7 +91,7 @@ func TestDeleteApproval(t *testing.T) {
 		t.Fatalf("didn't find approval: %s", err)
 	}
 
-	err = am.Delete(stored)
+	err = am.Delete(stored, "user")
 	if err != nil {
 		t.Errorf("failed to delete approval: %s", err)
 	}
@@ -380,7 +381,7 @@ func TestReject(t *testing.T) {
 		t.Fatalf("failed to create approval: %s", err)
 	}
 
-	am.Reject("xxx/app-1")
+	am.Reject("xxx/app-1", "user")
 
 	stored, err := am.Get("xxx/app-1")
 	if err != nil {
@@ -469,3 +470,214 @@ func TestGetArchived(t *testing.T) {
 		t.Errorf("didn't expect approval to be archived")
 	}
 }
+
+type fakeSender struct {
+	events []types.EventNotification
+}
+
+func (s *fakeSender) Configure(*notification.Config) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeSender) Send(event types.EventNotification) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// createExpired - creates approval that was requested 2 hours ago with 1 hour deadline
+func createExpired(t *testing.T, am *DefaultManager, action string) *types.Approval {
+	err := am.Create(&types.Approval{
+		Provider:           types.ProviderTypeKubernetes,
+		Identifier:         "xxx/app-1",
+		CurrentVersion:     "1.2.3",
+		NewVersion:         "1.2.5",
+		VotesRequired:      2,
+		ExpiryAction:       action,
+		EscalationChannels: "oncall,releases",
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+	approval, err := am.Get("xxx/app-1")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	approval.CreatedAt = time.Now().Add(-2 * time.Hour)
+	approval.Deadline = time.Now().Add(-1 * time.Hour)
+	if err := am.store.UpdateApproval(approval); err != nil {
+		t.Fatalf("failed to update approval: %s", err)
+	}
+	return approval
+}
+
+func TestExpireEscalate(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	sender := &fakeSender{}
+	am := New(&Opts{
+		Store:  store,
+		Sender: sender,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests, _ := am.Subscribe(ctx)
+
+	createExpired(t, am, types.ApprovalExpiryEscalate)
+	<-requests
+
+	if err := am.expireEntries(); err != nil {
+		t.Fatalf("got error while expiring entries: %s", err)
+	}
+
+	select {
+	case approval := <-requests:
+		if approval.Identifier != "xxx/app-1" {
+			t.Errorf("unexpected approval request: %s", approval.Identifier)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval request to be sent again")
+	}
+
+	escalated, err := am.Get("xxx/app-1")
+	if err != nil {
+		t.Fatalf("expected approval to be kept: %s", err)
+	}
+	if !escalated.Escalated || escalated.Status() != types.ApprovalStatusPending || escalated.Expired() {
+		t.Errorf("expected pending approval with extended deadline, got: %+v", escalated)
+	}
+	if escalated.Deadline.After(time.Now().Add(61 * time.Minute)) {
+		t.Errorf("expected deadline to be extended by the original period, got: %s", escalated.Deadline)
+	}
+
+	if len(sender.events) != 1 {
+		t.Fatalf("expected escalation notification, got: %v", sender.events)
+	}
+	if ev := sender.events[0]; ev.Type != types.NotificationApprovalExpired || len(ev.Channels) != 2 || ev.Channels[0] != "oncall" {
+		t.Errorf("unexpected notification: %+v", ev)
+	}
+
+	// escalated approval expires once new deadline is reached
+	escalated.Deadline = time.Now().Add(-time.Minute)
+	am.store.UpdateApproval(escalated)
+	if err := am.expireEntries(); err != nil {
+		t.Fatalf("got error while expiring entries: %s", err)
+	}
+	if _, err := am.Get("xxx/app-1"); err == nil {
+		t.Errorf("expected escalated approval to be deleted")
+	}
+}
+
+func TestExpireApprove(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := New(&Opts{
+		Store: store,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	approved, _ := am.SubscribeApproved(ctx)
+
+	createExpired(t, am, types.ApprovalExpiryApprove)
+
+	if err := am.expireEntries(); err != nil {
+		t.Fatalf("got error while expiring entries: %s", err)
+	}
+
+	select {
+	case approval := <-approved:
+		if approval.Status() != types.ApprovalStatusApproved || approval.GetVoters()[0] != ExpiryVoter {
+			t.Errorf("unexpected approval: %+v", approval)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval to be approved")
+	}
+}
+
+func TestExpireReject(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := New(&Opts{
+		Store: store,
+	})
+
+	createExpired(t, am, types.ApprovalExpiryReject)
+
+	if err := am.expireEntries(); err != nil {
+		t.Fatalf("got error while expiring entries: %s", err)
+	}
+
+	rejected, err := am.Get("xxx/app-1")
+	if err != nil {
+		t.Fatalf("expected rejected approval to be kept until extended deadline: %s", err)
+	}
+	if rejected.Status() != types.ApprovalStatusRejected || rejected.Expired() {
+		t.Errorf("unexpected approval: %+v", rejected)
+	}
+}
+
+func TestApproveAllowedApprovers(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := New(&Opts{
+		Store:          store,
+		ApproverGroups: map[string][]string{"platform-team": {"carol", "dave"}},
+	})
+
+	err := am.Create(&types.Approval{
+		Identifier:     "xxx/app-1",
+		CurrentVersion: "1.2.3",
+		NewVersion:     "1.2.5",
+		VotesRequired:  2,
+		Approvers:      "alice, @platform-team",
+		Deadline:       time.Now().Add(5 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+
+	for _, voter := range []string{"mallory", ""} {
+		_, err = am.Approve("xxx/app-1", voter)
+		if _, ok := err.(*VoterNotAllowedError); !ok {
+			t.Errorf("expected vote from '%s' to be refused, got: %v", voter, err)
+		}
+	}
+	if _, err = am.Reject("xxx/app-1", "mallory"); err == nil || err.Error() != "user 'mallory' is not allowed to vote for 'xxx/app-1', only listed approvers can vote" {
+		t.Errorf("expected rejection to be refused, got: %v", err)
+	}
+
+	// same approver is counted once
+	am.Approve("xxx/app-1", "alice")
+	approval, err := am.Approve("xxx/app-1", "alice")
+	if err != nil {
+		t.Fatalf("failed to approve: %s", err)
+	}
+	if approval.VotesReceived != 1 || approval.Status() != types.ApprovalStatusPending {
+		t.Errorf("expected single vote, got: %d", approval.VotesReceived)
+	}
+
+	approval, err = am.Approve("xxx/app-1", "dave")
+	if err != nil {
+		t.Fatalf("failed to approve: %s", err)
+	}
+	if approval.Status() != types.ApprovalStatusApproved {
+		t.Errorf("expected approval to reach quorum, got: %s", approval.Status())
+	}
+}
+
+func TestParseApproverGroups(t *testing.T) {
+	groups, err := ParseApproverGroups("platform-team=alice, bob; @sre=carol;")
+	if err != nil {
+		t.Fatalf("failed to parse groups: %s", err)
+	}
+	if len(groups) != 2 || len(groups["platform-team"]) != 2 || groups["platform-team"][1] != "bob" || groups["sre"][0] != "carol" {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+
+	if _, err := ParseApproverGroups("alice,bob"); err == nil {
+		t.Errorf("expected error for group without name")
+	}
+}
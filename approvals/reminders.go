@@ -0,0 +1,211 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reminderCheckInterval - how often pending approvals are checked for reminders and digest
+const reminderCheckInterval = time.Minute
+
+// ParseDigestTime - parses digest time of day in UTC, ie: "09:00", returns
+// offset from midnight
+func ParseDigestTime(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid digest time '%s', expected HH:MM: %s", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// StartReminderService - requests votes again for approvals that are pending longer than
+// the reminder period and sends a daily digest of pending approvals. Last digest time is
+// not persisted, digest is skipped for the current day when the service starts after
+// digest time.
+func (m *DefaultManager) StartReminderService(ctx context.Context) error {
+	if m.reminderAfter <= 0 && m.digestTime == "" {
+		return nil
+	}
+
+	var digestAt time.Duration
+	if m.digestTime != "" {
+		d, err := ParseDigestTime(m.digestTime)
+		if err != nil {
+			return err
+		}
+		digestAt = d
+	}
+
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+	lastDigest := timeutil.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if m.reminderAfter > 0 {
+				err := m.sendReminders()
+				if err != nil {
+					log.WithFields(log.Fields{
+						"error": err,
+					}).Error("approvals.StartReminderService: got error while sending reminders")
+				}
+			}
+
+			if m.digestTime == "" {
+				continue
+			}
+			now := timeutil.Now().UTC()
+			scheduled := now.Truncate(24 * time.Hour).Add(digestAt)
+			if now.Before(scheduled) || !lastDigest.Before(scheduled) {
+				continue
+			}
+			lastDigest = now
+			err := m.sendDigest()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("approvals.StartReminderService: got error while sending digest")
+			}
+		}
+	}
+}
+
+// sendReminders - sends approval requests again to bots and notifies approval channels
+// once reminder period passes since the request or the last reminder
+func (m *DefaultManager) sendReminders() error {
+	approvals, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	now := timeutil.Now()
+	for _, approval := range approvals {
+		if approval.Archived || approval.Status() != types.ApprovalStatusPending || approval.Expired() {
+			continue
+		}
+		last := approval.CreatedAt
+		if approval.RemindedAt.After(last) {
+			last = approval.RemindedAt
+		}
+		if now.Sub(last) < m.reminderAfter {
+			continue
+		}
+
+		err := m.remind(approval.Identifier, now)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"identifier": approval.Identifier,
+			}).Error("approvals.sendReminders: failed to send reminder")
+		}
+	}
+	return nil
+}
+
+func (m *DefaultManager) remind(identifier string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// fetching again so votes received in the meantime are kept
+	approval, err := m.Get(identifier)
+	if err != nil {
+		return err
+	}
+	approval.RemindedAt = now
+	if err := m.store.UpdateApproval(approval); err != nil {
+		return err
+	}
+
+	m.publishRequest(approval)
+
+	log.WithFields(log.Fields{
+		"identifier": approval.Identifier,
+	}).Info("approvals.manager: reminder sent")
+
+	if m.sender == nil {
+		return nil
+	}
+	return m.sender.Send(types.EventNotification{
+		ResourceKind: types.AuditResourceKindApproval,
+		Identifier:   approval.Identifier,
+		Name:         "approval reminder",
+		Message: fmt.Sprintf("Approval pending for %s, %d/%d votes received, deadline %s: %s",
+			now.Sub(approval.CreatedAt).Round(time.Minute), approval.VotesReceived, approval.VotesRequired,
+			approval.Deadline.UTC().Format(time.RFC822), approval.Message),
+		CreatedAt: now,
+		Type:      types.NotificationApprovalReminder,
+		Level:     types.LevelWarn,
+		Channels:  approval.GetChannels(),
+	})
+}
+
+// sendDigest - sends a list of pending approvals to each of their notification channels,
+// approvals without channels are listed in the default channels digest
+func (m *DefaultManager) sendDigest() error {
+	if m.sender == nil {
+		return nil
+	}
+	approvals, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	byChannel := make(map[string][]*types.Approval)
+	for _, approval := range approvals {
+		if approval.Archived || approval.Status() != types.ApprovalStatusPending || approval.Expired() {
+			continue
+		}
+		channels := approval.GetChannels()
+		if len(channels) == 0 {
+			channels = []string{""}
+		}
+		for _, channel := range channels {
+			byChannel[channel] = append(byChannel[channel], approval)
+		}
+	}
+
+	now := timeutil.Now()
+	for channel, pending := range byChannel {
+		sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+
+		buf := &bytes.Buffer{}
+		fmt.Fprintf(buf, "%d approval(s) waiting for votes:", len(pending))
+		for _, approval := range pending {
+			fmt.Fprintf(buf, "\n- %s (%s): %d/%d votes, pending for %s, deadline %s",
+				approval.Identifier, approval.Delta(), approval.VotesReceived, approval.VotesRequired,
+				now.Sub(approval.CreatedAt).Round(time.Minute), approval.Deadline.UTC().Format(time.RFC822))
+		}
+
+		var channels []string
+		if channel != "" {
+			channels = []string{channel}
+		}
+		err := m.sender.Send(types.EventNotification{
+			ResourceKind: types.AuditResourceKindApproval,
+			Name:         "pending approvals",
+			Message:      buf.String(),
+			CreatedAt:    now,
+			Type:         types.NotificationApprovalDigest,
+			Level:        types.LevelInfo,
+			Channels:     channels,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"channel": channel,
+			}).Error("approvals.sendDigest: failed to send digest")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package approvals
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSendReminders(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	sender := &fakeSender{}
+	am := New(&Opts{
+		Store:         store,
+		Sender:        sender,
+		ReminderAfter: time.Hour,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests, _ := am.Subscribe(ctx)
+
+	for _, identifier := range []string{"xxx/app-1", "xxx/app-2"} {
+		err := am.Create(&types.Approval{
+			Identifier:     identifier,
+			CurrentVersion: "1.2.3",
+			NewVersion:     "1.2.5",
+			VotesRequired:  1,
+			Channels:       "releases",
+			Deadline:       time.Now().Add(24 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+		<-requests
+	}
+
+	// only first approval is pending longer than reminder period
+	approval, _ := am.Get("xxx/app-1")
+	approval.CreatedAt = time.Now().Add(-2 * time.Hour)
+	am.store.UpdateApproval(approval)
+
+	if err := am.sendReminders(); err != nil {
+		t.Fatalf("failed to send reminders: %s", err)
+	}
+
+	select {
+	case req := <-requests:
+		if req.Identifier != "xxx/app-1" {
+			t.Errorf("unexpected reminder: %s", req.Identifier)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval request to be sent again")
+	}
+
+	if len(sender.events) != 1 {
+		t.Fatalf("expected reminder notification, got: %v", sender.events)
+	}
+	if ev := sender.events[0]; ev.Type != types.NotificationApprovalReminder || ev.Identifier != "xxx/app-1" || len(ev.Channels) != 1 || ev.Channels[0] != "releases" {
+		t.Errorf("unexpected notification: %+v", ev)
+	}
+
+	// reminded recently
+	if err := am.sendReminders(); err != nil {
+		t.Fatalf("failed to send reminders: %s", err)
+	}
+	if len(sender.events) != 1 {
+		t.Errorf("expected reminder to be sent once per period, got: %d", len(sender.events))
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	sender := &fakeSender{}
+	am := New(&Opts{
+		Store:  store,
+		Sender: sender,
+	})
+
+	approvals := []*types.Approval{
+		{Identifier: "xxx/app-1", Channels: "releases,ops"},
+		{Identifier: "xxx/app-2", Channels: "ops"},
+		{Identifier: "xxx/app-3"},
+		{Identifier: "xxx/app-4", Rejected: true},
+	}
+	for _, approval := range approvals {
+		approval.CurrentVersion = "1.2.3"
+		approval.NewVersion = "1.2.5"
+		approval.VotesRequired = 1
+		approval.Deadline = time.Now().Add(time.Hour)
+		if err := am.Create(approval); err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+
+	if err := am.sendDigest(); err != nil {
+		t.Fatalf("failed to send digest: %s", err)
+	}
+
+	digests := make(map[string]string)
+	for _, ev := range sender.events {
+		if ev.Type != types.NotificationApprovalDigest {
+			t.Errorf("unexpected notification type: %s", ev.Type)
+		}
+		digests[strings.Join(ev.Channels, ",")] = ev.Message
+	}
+	if len(digests) != 3 {
+		t.Fatalf("expected digest for releases, ops and default channels, got: %v", digests)
+	}
+	if !strings.HasPrefix(digests["ops"], "2 approval(s) waiting for votes:") || !strings.Contains(digests["ops"], "- xxx/app-2 (1.2.3 -> 1.2.5): 0/1 votes") {
+		t.Errorf("unexpected ops digest: %s", digests["ops"])
+	}
+	if !strings.Contains(digests[""], "xxx/app-3") || strings.Contains(digests[""], "xxx/app-4") {
+		t.Errorf("unexpected default channels digest: %s", digests[""])
+	}
+}
+
+func TestParseDigestTime(t *testing.T) {
+	d, err := ParseDigestTime("09:30")
+	if err != nil {
+		t.Fatalf("failed to parse digest time: %s", err)
+	}
+	if d != 9*time.Hour+30*time.Minute {
+		t.Errorf("unexpected digest time: %s", d)
+	}
+	if _, err := ParseDigestTime("9am"); err == nil {
+		t.Errorf("expected error for invalid digest time")
+	}
+}
@@ -104,7 +104,7 @@ func (bm *BotManager) processRejectedResponse(approvalResponse *ApprovalResponse
 	}
 
 	for _, identifier := range identifiers {
-		approval, err := bm.approvalsManager.Reject(identifier)
+		approval, err := bm.approvalsManager.Reject(identifier, approvalResponse.User)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":      err,
@@ -149,10 +149,18 @@ func ApprovalsResponse(approvalsManager approvals.Manager) string {
 	return buf.String()
 }
 
-func IsApproval(eventUser string, eventText string) (resp *ApprovalResponse, ok bool) {
+// VoterID - voter identity namespaced by the bot platform ("slack:U024BE7LH"), user IDs and
+// names of different platforms (or API users) can't be mistaken for each other in approvers
+func VoterID(platform, user string) string {
+	return platform + ":" + user
+}
+
+// IsApproval - checks whether message is an approve or reject command, user is the
+// platform's user ID the vote is recorded for (see VoterID)
+func IsApproval(platform, eventUser, eventText string) (resp *ApprovalResponse, ok bool) {
 	if strings.HasPrefix(strings.ToLower(eventText), ApprovalResponseKeyword) {
 		return &ApprovalResponse{
-			User:   eventUser,
+			User:   VoterID(platform, eventUser),
 			Status: types.ApprovalStatusApproved,
 			Text:   eventText,
 		}, true
@@ -160,7 +168,7 @@ func IsApproval(eventUser string, eventText string) (resp *ApprovalResponse, ok
 
 	if strings.HasPrefix(strings.ToLower(eventText), RejectResponseKeyword) {
 		return &ApprovalResponse{
-			User:   eventUser,
+			User:   VoterID(platform, eventUser),
 			Status: types.ApprovalStatusRejected,
 			Text:   eventText,
 		}, true
@@ -169,12 +177,12 @@ func IsApproval(eventUser string, eventText string) (resp *ApprovalResponse, ok
 	return nil, false
 }
 
-func RemoveApprovalHandler(identifier string, approvalsManager approvals.Manager) string {
+func RemoveApprovalHandler(identifier, user string, approvalsManager approvals.Manager) string {
 	approval, err := approvalsManager.Get(identifier)
 	if err != nil {
 		return fmt.Sprintf("approval with identifier '%s' was not found", identifier)
 	}
-	err = approvalsManager.Delete(approval)
+	err = approvalsManager.Delete(approval, user)
 	if err != nil {
 		return fmt.Sprintf("failed to remove '%s' approval: %s.", identifier, err)
 	}
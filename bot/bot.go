@@ -3,10 +3,12 @@ package bot
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/provider/kubernetes"
 	"github.com/keel-hq/keel/types"
 
@@ -15,6 +17,7 @@ import (
 
 const (
 	RemoveApprovalPrefix = "rm approval"
+	ExplainPolicyPrefix  = "explain"
 )
 
 var (
@@ -26,6 +29,7 @@ var (
 			`- "rm approval <approval identifier>" -> remove approval`,
 			`- "approve <approval identifier>" -> approve update request`,
 			`- "reject <approval identifier>" -> reject update request`,
+			`- "explain <image>:<current tag> <new tag> <policy>" -> explain whether policy allows the update`,
 			// `- "get deployments all" -> get a list of all deployments`,
 			// `- "describe deployment <deployment>" -> get details for specified deployment`,
 		},
@@ -38,7 +42,7 @@ var (
 	}
 
 	// dynamic bot command prefixes have to be matched
-	dynamicBotCommandPrefixes = []string{RemoveApprovalPrefix}
+	dynamicBotCommandPrefixes = []string{RemoveApprovalPrefix, ExplainPolicyPrefix}
 
 	ApprovalResponseKeyword = "approve"
 	RejectResponseKeyword   = "reject"
@@ -52,6 +56,13 @@ type Bot interface {
 	ReplyToApproval(approval *types.Approval) error
 }
 
+// Interactive - implemented by bots that receive interactive message callbacks
+// (such as button clicks) over HTTP
+type Interactive interface {
+	// InteractionHandler - nil when interactivity is not configured
+	InteractionHandler() http.Handler
+}
+
 type teardown func()
 type BotMessageResponder func(response string, channel string)
 
@@ -81,6 +92,7 @@ type ApprovalResponse struct {
 type BotManager struct {
 	approvalsManager   approvals.Manager
 	k8sImplementer     kubernetes.Implementer
+	namespaceFilter    *k8s.NamespaceFilter
 	botMessagesChannel chan *BotMessage
 	approvalsRespCh    chan *ApprovalResponse
 }
@@ -109,11 +121,12 @@ func RegisterBot(name string, b Bot) {
 	bots[name] = b
 }
 
-// Run all implemented bots
-func Run(k8sImplementer kubernetes.Implementer, approvalsManager approvals.Manager) {
+// Run all implemented bots, namespaceFilter restricts resources listed by bot commands
+func Run(k8sImplementer kubernetes.Implementer, approvalsManager approvals.Manager, namespaceFilter *k8s.NamespaceFilter) {
 	bm := &BotManager{
 		approvalsManager:   approvalsManager,
 		k8sImplementer:     k8sImplementer,
+		namespaceFilter:    namespaceFilter,
 		approvalsRespCh:    make(chan *ApprovalResponse), // don't add buffer to make it blocking
 		botMessagesChannel: make(chan *BotMessage),
 	}
@@ -181,11 +194,11 @@ func IsBotCommand(eventText string) bool {
 	return false
 }
 
-func (bm *BotManager) handleCommand(eventText string) string {
+func (bm *BotManager) handleCommand(eventText, user string) string {
 	switch eventText {
 	case "get deployments":
 		log.Info("HandleCommand: getting deployments")
-		return DeploymentsResponse(Filter{}, bm.k8sImplementer)
+		return DeploymentsResponse(Filter{NamespaceFilter: bm.namespaceFilter}, bm.k8sImplementer)
 	case "get approvals":
 		log.Info("HandleCommand: getting approvals")
 		return ApprovalsResponse(bm.approvalsManager)
@@ -194,7 +207,11 @@ func (bm *BotManager) handleCommand(eventText string) string {
 	// handle dynamic commands
 	if strings.HasPrefix(eventText, RemoveApprovalPrefix) {
 		id := strings.TrimSpace(strings.TrimPrefix(eventText, RemoveApprovalPrefix))
-		return RemoveApprovalHandler(id, bm.approvalsManager)
+		return RemoveApprovalHandler(id, user, bm.approvalsManager)
+	}
+
+	if strings.HasPrefix(eventText, ExplainPolicyPrefix) {
+		return ExplainPolicyHandler(strings.TrimPrefix(eventText, ExplainPolicyPrefix))
 	}
 
 	log.Infof("bot.HandleCommand(): command [%s] not found", eventText)
@@ -214,7 +231,7 @@ func (bm *BotManager) handleBotMessage(m *BotMessage) string {
 	}
 
 	if IsBotCommand(command) {
-		return bm.handleCommand(command)
+		return bm.handleCommand(command, VoterID(m.Name, m.User))
 	}
 
 	log.WithFields(log.Fields{
@@ -233,3 +250,15 @@ func UnregisterBot(name string) {
 
 	delete(bots, name)
 }
+
+// InteractionHandler - interactive callbacks handler of the named bot, nil if the bot
+// is not registered or doesn't have interactivity configured
+func InteractionHandler(name string) http.Handler {
+	botsM.RLock()
+	defer botsM.RUnlock()
+
+	if i, ok := bots[name].(Interactive); ok {
+		return i.InteractionHandler()
+	}
+	return nil
+}
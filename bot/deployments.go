@@ -5,9 +5,11 @@ import (
 	"fmt"
 
 	"github.com/keel-hq/keel/bot/formatter"
+	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/provider/kubernetes"
 
 	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,10 +18,12 @@ import (
 type Filter struct {
 	Namespace string
 	All       bool // keel or not
+	// NamespaceFilter - namespaces keel is restricted to, nil allows all
+	NamespaceFilter *k8s.NamespaceFilter
 }
 
 // deployments - gets all deployments
-func deployments(k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, error) {
+func deployments(filter Filter, k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, error) {
 	deploymentLists := []*apps_v1.DeploymentList{}
 
 	n, err := k8sImplementer.Namespaces()
@@ -28,6 +32,9 @@ func deployments(k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, e
 	}
 
 	for _, n := range n.Items {
+		if !filter.NamespaceFilter.Allowed(n.GetName()) {
+			continue
+		}
 		l, err := k8sImplementer.Deployments(n.GetName())
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -50,19 +57,53 @@ func deployments(k8sImplementer kubernetes.Implementer) ([]apps_v1.Deployment, e
 	return impacted, nil
 }
 
+// daemonSets - gets all daemonsets
+func daemonSets(filter Filter, k8sImplementer kubernetes.Implementer) ([]apps_v1.DaemonSet, error) {
+	n, err := k8sImplementer.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+
+	impacted := []apps_v1.DaemonSet{}
+
+	for _, n := range n.Items {
+		if !filter.NamespaceFilter.Allowed(n.GetName()) {
+			continue
+		}
+		l, err := k8sImplementer.DaemonSets(n.GetName())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"namespace": n.GetName(),
+			}).Error("provider.kubernetes: failed to list daemonsets")
+			continue
+		}
+		if l == nil {
+			continue
+		}
+		impacted = append(impacted, l.Items...)
+	}
+
+	return impacted, nil
+}
+
 func DeploymentsResponse(filter Filter, k8sImplementer kubernetes.Implementer) string {
-	deps, err := deployments(k8sImplementer)
+	deps, err := deployments(filter, k8sImplementer)
 	if err != nil {
 		return fmt.Sprintf("got error while fetching deployments: %s", err)
 	}
-	log.Debugf("%d deployments fetched, formatting", len(deps))
+	dss, err := daemonSets(filter, k8sImplementer)
+	if err != nil {
+		return fmt.Sprintf("got error while fetching daemonsets: %s", err)
+	}
+	log.Debugf("%d deployments and %d daemonsets fetched, formatting", len(deps), len(dss))
 	buf := &bytes.Buffer{}
 
 	DeploymentCtx := formatter.Context{
 		Output: buf,
 		Format: formatter.NewDeploymentsFormat(formatter.TableFormatKey, false),
 	}
-	err = formatter.DeploymentWrite(DeploymentCtx, convertToInternal(deps))
+	err = formatter.DeploymentWrite(DeploymentCtx, append(convertToInternal(deps), convertDaemonSetsToInternal(dss)...))
 
 	if err != nil {
 		return fmt.Sprintf(" got error while formatting deployments: %s", err)
@@ -86,9 +127,27 @@ func convertToInternal(deployments []apps_v1.Deployment) []formatter.Deployment
 	return formatted
 }
 
+func convertDaemonSetsToInternal(daemonSets []apps_v1.DaemonSet) []formatter.Deployment {
+	formatted := []formatter.Deployment{}
+	for _, d := range daemonSets {
+		formatted = append(formatted, formatter.Deployment{
+			Namespace:         d.Namespace,
+			Name:              d.Name,
+			Replicas:          d.Status.DesiredNumberScheduled,
+			AvailableReplicas: d.Status.NumberAvailable,
+			Images:            getContainerImages(d.Spec.Template.Spec.Containers),
+		})
+	}
+	return formatted
+}
+
 func getImages(deployment *apps_v1.Deployment) []string {
+	return getContainerImages(deployment.Spec.Template.Spec.Containers)
+}
+
+func getContainerImages(containers []v1.Container) []string {
 	var images []string
-	for _, c := range deployment.Spec.Template.Spec.Containers {
+	for _, c := range containers {
 		images = append(images, c.Image)
 	}
 
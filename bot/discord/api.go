@@ -0,0 +1,147 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiURL - Discord REST API, see https://discord.com/developers/docs/reference
+const apiURL = "https://discord.com/api/v10"
+
+// maxMessageLength - Discord rejects longer message contents
+const maxMessageLength = 2000
+
+// client - Discord REST API client authenticated with the bot token
+type client struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type message struct {
+	ID      string   `json:"id,omitempty"`
+	Content string   `json:"content,omitempty"`
+	Embeds  []*embed `json:"embeds,omitempty"`
+	Flags   int      `json:"flags,omitempty"`
+}
+
+// embed - rich message content, see https://discord.com/developers/docs/resources/channel#embed-object
+type embed struct {
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Color       int           `json:"color,omitempty"`
+	Fields      []*embedField `json:"fields,omitempty"`
+	Footer      *embedFooter  `json:"footer,omitempty"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type embedFooter struct {
+	Text string `json:"text"`
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+type application struct {
+	ID string `json:"id"`
+}
+
+// command - application command, see https://discord.com/developers/docs/interactions/application-commands
+type command struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Options     []*commandOption `json:"options,omitempty"`
+}
+
+type commandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// createMessage - returns created message ID
+func (c *client) createMessage(channel string, m *message) (string, error) {
+	var created message
+	if err := c.do(http.MethodPost, "/channels/"+channel+"/messages", m, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (c *client) editMessage(channel, id string, m *message) error {
+	return c.do(http.MethodPatch, "/channels/"+channel+"/messages/"+id, m, nil)
+}
+
+// addReaction - reacts with the bot account so voters only have to click
+func (c *client) addReaction(channel, id, emoji string) error {
+	return c.do(http.MethodPut, "/channels/"+channel+"/messages/"+id+"/reactions/"+url.PathEscape(emoji)+"/@me", nil, nil)
+}
+
+// reactions - users that reacted to the message with the emoji
+func (c *client) reactions(channel, id, emoji string) ([]*user, error) {
+	var users []*user
+	err := c.do(http.MethodGet, "/channels/"+channel+"/messages/"+id+"/reactions/"+url.PathEscape(emoji)+"?limit=100", nil, &users)
+	return users, err
+}
+
+// registerCommand - creates or updates global application command
+func (c *client) registerCommand(cmd *command) error {
+	var app application
+	if err := c.do(http.MethodGet, "/oauth2/applications/@me", nil, &app); err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, "/applications/"+app.ID+"/commands", cmd, nil)
+}
+
+func (c *client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.url, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: unexpected status code %d: %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package discord
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// approvalMessage - approval request message and the reactions already counted as votes
+type approvalMessage struct {
+	id      string
+	counted map[string]bool // "<keyword> <user ID>"
+}
+
+// RequestApproval - posts approval request to the approvals channel and adds the vote
+// reactions to it
+func (b *Bot) RequestApproval(req *types.Approval) error {
+	e := b.approvalEmbed(req, "Approval required", types.LevelSuccess)
+	e.Description = req.Message + "\n\n" + fmt.Sprintf("To vote for change react with %s, to reject it: %s.", b.approveEmoji, b.rejectEmoji)
+
+	id, err := b.api.createMessage(b.approvalsChannel, &message{Embeds: []*embed{e}})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":             err,
+			"approvals_channel": b.approvalsChannel,
+		}).Error("bot.discord.RequestApproval: failed to send message")
+		return err
+	}
+
+	b.mu.Lock()
+	b.messages[req.Identifier] = &approvalMessage{id: id, counted: make(map[string]bool)}
+	b.mu.Unlock()
+
+	for _, emoji := range []string{b.approveEmoji, b.rejectEmoji} {
+		if err := b.api.addReaction(b.approvalsChannel, id, emoji); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"emoji": emoji,
+			}).Warn("bot.discord.RequestApproval: failed to add reaction")
+		}
+	}
+	return nil
+}
+
+// ReplyToApproval - updates the approval request message with votes or the outcome, posts
+// a new message if the request message isn't known
+func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	var e *embed
+	switch approval.Status() {
+	case types.ApprovalStatusPending:
+		e = b.approvalEmbed(approval, "Vote received", types.LevelInfo)
+		e.Description = "Waiting for remaining votes."
+	case types.ApprovalStatusRejected:
+		e = b.approvalEmbed(approval, "Change rejected", types.LevelWarn)
+		e.Description = "Change was rejected."
+	case types.ApprovalStatusApproved:
+		e = b.approvalEmbed(approval, "Update approved", types.LevelSuccess)
+		e.Description = "All approvals received, thanks for voting!"
+	default:
+		return nil
+	}
+	if voters := approval.GetVoters(); len(voters) > 0 {
+		for i := range voters {
+			voters[i] = "<@" + voters[i] + ">"
+		}
+		sort.Strings(voters)
+		e.Fields = append(e.Fields, &embedField{Name: "Voted by", Value: strings.Join(voters, ", ")})
+	}
+
+	b.mu.Lock()
+	m, ok := b.messages[approval.Identifier]
+	if ok && approval.Status() != types.ApprovalStatusPending {
+		delete(b.messages, approval.Identifier)
+	}
+	b.mu.Unlock()
+
+	if ok {
+		e.Description = approval.Message + "\n\n" + e.Description
+		err := b.api.editMessage(b.approvalsChannel, m.id, &message{Embeds: []*embed{e}})
+		if err == nil {
+			return nil
+		}
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": approval.Identifier,
+		}).Warn("bot.discord.ReplyToApproval: failed to update approval request, posting reply")
+	}
+
+	_, err := b.api.createMessage(b.approvalsChannel, &message{Embeds: []*embed{e}})
+	return err
+}
+
+func (b *Bot) approvalEmbed(approval *types.Approval, title string, level types.Level) *embed {
+	color, _ := strconv.ParseInt(strings.TrimPrefix(level.Color(), "#"), 16, 32)
+	return &embed{
+		Title: title,
+		Color: int(color),
+		Fields: []*embedField{
+			{Name: "Votes", Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired), Inline: true},
+			{Name: "Delta", Value: approval.Delta(), Inline: true},
+			{Name: "Identifier", Value: approval.Identifier, Inline: true},
+			{Name: "Provider", Value: approval.Provider.String(), Inline: true},
+		},
+		Footer: &embedFooter{Text: fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version)},
+	}
+}
+
+// pollReactions - sends new approve/reject reactions from approvers as votes, Discord
+// only delivers reaction events over the gateway so they are fetched periodically
+func (b *Bot) pollReactions() {
+	b.mu.Lock()
+	pending := make(map[string]*approvalMessage, len(b.messages))
+	for identifier, m := range b.messages {
+		pending[identifier] = m
+	}
+	b.mu.Unlock()
+
+	for identifier, m := range pending {
+		for keyword, emoji := range map[string]string{
+			bot.ApprovalResponseKeyword: b.approveEmoji,
+			bot.RejectResponseKeyword:   b.rejectEmoji,
+		} {
+			users, err := b.api.reactions(b.approvalsChannel, m.id, emoji)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":      err,
+					"identifier": identifier,
+					"emoji":      emoji,
+				}).Warn("bot.discord.pollReactions: failed to get reactions")
+				continue
+			}
+
+			for _, u := range users {
+				if u.Bot || !b.isApprover(u.ID) {
+					continue
+				}
+				key := keyword + " " + u.ID
+				b.mu.Lock()
+				counted := m.counted[key]
+				m.counted[key] = true
+				b.mu.Unlock()
+				if counted {
+					continue
+				}
+
+				approval, _ := bot.IsApproval("discord", u.ID, keyword+" "+identifier)
+				b.sendApproval(approval)
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reactionsPollInterval - how often approval request reactions are checked for votes
+const reactionsPollInterval = 10 * time.Second
+
+// Bot - Discord approvals bot. Approval requests are posted with the bot account and
+// votes are collected from emoji reactions, commands are received through the /keel
+// slash command at /v1/bots/discord/interactions
+type Bot struct {
+	approvalsChannel string
+	approvers        map[string]bool // authorized voter user IDs, anyone when empty
+	approveEmoji     string
+	rejectEmoji      string
+	publicKey        ed25519.PublicKey
+
+	api *client
+
+	mu       sync.Mutex
+	messages map[string]*approvalMessage // approval request messages by approval identifier
+
+	ctx                context.Context
+	botMessagesChannel chan *bot.BotMessage
+	approvalsRespCh    chan *bot.ApprovalResponse
+}
+
+func init() {
+	if isDiscordConfigured() {
+		bot.RegisterBot("discord", &Bot{})
+	}
+}
+
+func isDiscordConfigured() bool {
+	return os.Getenv(constants.EnvDiscordBotToken) != "" && os.Getenv(constants.EnvDiscordApprovalsChannel) != ""
+}
+
+func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
+	if !isDiscordConfigured() {
+		log.Info("bot.discord.Configure(): Discord approval bot is not configured")
+		return false
+	}
+
+	if key := os.Getenv(constants.EnvDiscordPublicKey); key != "" {
+		publicKey, err := hex.DecodeString(key)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("bot.discord.Configure(): invalid application public key, slash command disabled")
+		} else {
+			b.publicKey = publicKey
+		}
+	}
+
+	b.approvalsChannel = os.Getenv(constants.EnvDiscordApprovalsChannel)
+	b.approvers = make(map[string]bool)
+	for _, id := range strings.Split(os.Getenv(constants.EnvDiscordApprovers), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			b.approvers[id] = true
+		}
+	}
+	if len(b.approvers) == 0 {
+		log.Warn("bot.discord.Configure(): no approvers configured, anyone in the approvals channel can vote")
+	}
+	b.approveEmoji = "✅"
+	if emoji := os.Getenv(constants.EnvDiscordApproveEmoji); emoji != "" {
+		b.approveEmoji = emoji
+	}
+	b.rejectEmoji = "❌"
+	if emoji := os.Getenv(constants.EnvDiscordRejectEmoji); emoji != "" {
+		b.rejectEmoji = emoji
+	}
+	b.api = &client{
+		url:    apiURL,
+		token:  os.Getenv(constants.EnvDiscordBotToken),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	b.messages = make(map[string]*approvalMessage)
+	b.approvalsRespCh = approvalsRespCh
+	b.botMessagesChannel = botMessagesChannel
+
+	return true
+}
+
+// Start - registers the slash command and starts polling approval request reactions
+func (b *Bot) Start(ctx context.Context) error {
+	b.ctx = ctx
+
+	if b.publicKey != nil {
+		err := b.api.registerCommand(&command{
+			Name:        "keel",
+			Description: "Keel commands, type 'help' for the list",
+			Options: []*commandOption{
+				{Type: 3, Name: "command", Description: "Command, for example 'approve <approval identifier>'", Required: true},
+			},
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("bot.discord.Start(): failed to register slash command")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(reactionsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.pollReactions()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Respond - posts command response to the channel
+func (b *Bot) Respond(text string, channel string) {
+	if len(text) > maxMessageLength-8 {
+		text = text[:maxMessageLength-12] + "\n..."
+	}
+	_, err := b.api.createMessage(channel, &message{Content: "```\n" + text + "\n```"})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"channel": channel,
+		}).Error("bot.discord.Respond: failed to send message")
+	}
+}
+
+// interaction - see https://discord.com/developers/docs/interactions/receiving-and-responding
+type interaction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User *user `json:"user"`
+	} `json:"member"`
+	User *user `json:"user"`
+	Data *struct {
+		Name    string `json:"name"`
+		Options []*struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+const (
+	interactionTypePing               = 1
+	interactionTypeApplicationCommand = 2
+
+	responseTypePong           = 1
+	responseTypeChannelMessage = 4
+	messageFlagEphemeral       = 64
+)
+
+type interactionResponse struct {
+	Type int      `json:"type"`
+	Data *message `json:"data,omitempty"`
+}
+
+// InteractionHandler - slash command handler, nil unless application public key is configured
+func (b *Bot) InteractionHandler() http.Handler {
+	if b.publicKey == nil || b.ctx == nil {
+		return nil
+	}
+	return http.HandlerFunc(b.interactionHandler)
+}
+
+func (b *Bot) interactionHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signature, err := hex.DecodeString(req.Header.Get("X-Signature-Ed25519"))
+	if err != nil || !ed25519.Verify(b.publicKey, append([]byte(req.Header.Get("X-Signature-Timestamp")), body...), signature) {
+		http.Error(resp, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var i interaction
+	if err := json.Unmarshal(body, &i); err != nil {
+		http.Error(resp, fmt.Sprintf("failed to decode interaction: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var reply *interactionResponse
+	switch i.Type {
+	case interactionTypePing:
+		reply = &interactionResponse{Type: responseTypePong}
+	case interactionTypeApplicationCommand:
+		reply = &interactionResponse{
+			Type: responseTypeChannelMessage,
+			Data: &message{Content: b.handleCommand(&i), Flags: messageFlagEphemeral},
+		}
+	default:
+		http.Error(resp, fmt.Sprintf("unsupported interaction type %d", i.Type), http.StatusBadRequest)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(reply)
+}
+
+func (b *Bot) handleCommand(i *interaction) string {
+	u := i.User
+	if i.Member != nil {
+		u = i.Member.User
+	}
+	if u == nil || i.Data == nil {
+		return "unknown command"
+	}
+
+	var text string
+	for _, option := range i.Data.Options {
+		if option.Name == "command" {
+			text = strings.ToLower(strings.TrimSpace(option.Value))
+		}
+	}
+
+	if approval, ok := bot.IsApproval("discord", u.ID, text); ok {
+		// only accepting approvals from approvals channel
+		if i.ChannelID != b.approvalsChannel {
+			return "please use approvals channel"
+		}
+		if !b.isApprover(u.ID) {
+			return "you are not allowed to vote"
+		}
+		b.sendApproval(approval)
+		return "Vote received, thanks!"
+	}
+
+	if responseLines, ok := bot.BotEventTextToResponse[text]; ok {
+		return strings.Join(responseLines, "\n")
+	}
+
+	if bot.IsBotCommand(text) {
+		msg := &bot.BotMessage{
+			Message: text,
+			User:    u.ID,
+			Channel: i.ChannelID,
+			Name:    "discord",
+		}
+		go func() {
+			select {
+			case b.botMessagesChannel <- msg:
+			case <-b.ctx.Done():
+			}
+		}()
+		return "Working on it..."
+	}
+
+	log.WithFields(log.Fields{
+		"user":    u.Username,
+		"command": text,
+	}).Debug("bot.discord.handleCommand: bot couldn't recognize command")
+	return fmt.Sprintf("unknown command '%s', type 'help' for the list of commands", text)
+}
+
+func (b *Bot) isApprover(id string) bool {
+	return len(b.approvers) == 0 || b.approvers[id]
+}
+
+// sendApproval - bot manager channels are unbuffered, Discord expects a response
+// before the vote is processed
+func (b *Bot) sendApproval(approval *bot.ApprovalResponse) {
+	go func() {
+		select {
+		case b.approvalsRespCh <- approval:
+		case <-b.ctx.Done():
+		}
+	}()
+}
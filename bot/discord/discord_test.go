@@ -0,0 +1,216 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+type apiCall struct {
+	method  string
+	path    string
+	message message
+}
+
+type fakeServer struct {
+	mu        sync.Mutex
+	calls     []apiCall
+	reactions map[string][]*user // by emoji
+}
+
+func (f *fakeServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bot bot-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var m message
+	json.NewDecoder(r.Body).Decode(&m)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, apiCall{method: r.Method, path: r.URL.Path, message: m})
+
+	if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/channels/approvals-id/messages/msg1/reactions/") {
+		emoji := strings.TrimPrefix(r.URL.Path, "/channels/approvals-id/messages/msg1/reactions/")
+		json.NewEncoder(w).Encode(f.reactions[emoji])
+		return
+	}
+	w.Write([]byte(`{"id": "msg1"}`))
+}
+
+func newTestBot(t *testing.T) (*Bot, *fakeServer, func()) {
+	f := &fakeServer{reactions: make(map[string][]*user)}
+	srv := httptest.NewServer(http.HandlerFunc(f.handler))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Bot{
+		approvalsChannel:   "approvals-id",
+		approvers:          map[string]bool{"u1": true, "u2": true},
+		approveEmoji:       "✅",
+		rejectEmoji:        "❌",
+		api:                &client{url: srv.URL, token: "bot-token", client: http.DefaultClient},
+		messages:           make(map[string]*approvalMessage),
+		approvalsRespCh:    make(chan *bot.ApprovalResponse, 1),
+		botMessagesChannel: make(chan *bot.BotMessage, 1),
+		ctx:                ctx,
+	}
+	return b, f, func() {
+		cancel()
+		srv.Close()
+	}
+}
+
+func TestReactionApproval(t *testing.T) {
+	b, f, teardown := newTestBot(t)
+	defer teardown()
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		Message:        "New image is available for deployment default/app",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+	}
+	if err := b.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(f.calls) != 3 || f.calls[0].path != "/channels/approvals-id/messages" {
+		t.Fatalf("expected approval request message and reactions, got %+v", f.calls)
+	}
+	if f.calls[1].method != "PUT" || f.calls[1].path != "/channels/approvals-id/messages/msg1/reactions/✅/@me" {
+		t.Errorf("expected bot to add approve reaction, got %s %s", f.calls[1].method, f.calls[1].path)
+	}
+
+	f.mu.Lock()
+	f.reactions["✅"] = []*user{
+		{ID: "bot", Username: "keel", Bot: true},
+		{ID: "u3", Username: "mallory"},
+		{ID: "u1", Username: "jane"},
+	}
+	f.mu.Unlock()
+
+	b.pollReactions()
+	select {
+	case resp := <-b.approvalsRespCh:
+		if resp.User != "discord:u1" || resp.Status != types.ApprovalStatusApproved || resp.Text != "approve default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	// reactions are only counted once
+	b.pollReactions()
+	select {
+	case resp := <-b.approvalsRespCh:
+		t.Errorf("unexpected approval response: %+v", resp)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// bot manager approves and replies
+	approval.AddVoter("u1")
+	approval.VotesReceived = 1
+	if err := b.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	edit := f.calls[len(f.calls)-1]
+	if edit.method != "PATCH" || edit.path != "/channels/approvals-id/messages/msg1" {
+		t.Fatalf("expected approval request to be updated, got %s %s", edit.method, edit.path)
+	}
+	e := edit.message.Embeds[0]
+	if e.Title != "Update approved" {
+		t.Errorf("unexpected title: %s", e.Title)
+	}
+	if voters := e.Fields[len(e.Fields)-1]; voters.Name != "Voted by" || voters.Value != "<@u1>" {
+		t.Errorf("unexpected voters field: %+v", voters)
+	}
+	if len(b.messages) != 0 {
+		t.Errorf("expected approved request to stop being polled")
+	}
+}
+
+func TestSlashCommands(t *testing.T) {
+	b, _, teardown := newTestBot(t)
+	defer teardown()
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	b.publicKey = publicKey
+
+	send := func(key ed25519.PrivateKey, i map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(i)
+		timestamp := "1700000000"
+		req := httptest.NewRequest("POST", "/v1/bots/discord/interactions", bytes.NewReader(body))
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, append([]byte(timestamp), body...))))
+		rec := httptest.NewRecorder()
+		b.InteractionHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	command := func(user, channel, text string) string {
+		rec := send(privateKey, map[string]interface{}{
+			"type":       interactionTypeApplicationCommand,
+			"channel_id": channel,
+			"member":     map[string]interface{}{"user": map[string]string{"id": user, "username": user}},
+			"data": map[string]interface{}{
+				"name":    "keel",
+				"options": []map[string]string{{"name": "command", "value": text}},
+			},
+		})
+		var resp interactionResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp.Type != responseTypeChannelMessage || resp.Data == nil {
+			t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+		}
+		return resp.Data.Content
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	if rec := send(otherKey, map[string]interface{}{"type": interactionTypePing}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid signature to be rejected, got %d", rec.Code)
+	}
+	if rec := send(privateKey, map[string]interface{}{"type": interactionTypePing}); !strings.Contains(rec.Body.String(), `"type":1`) {
+		t.Errorf("expected pong, got %s", rec.Body.String())
+	}
+
+	if resp := command("u1", "general-id", "approve default/app:1.1.0"); resp != "please use approvals channel" {
+		t.Errorf("expected approval outside of approvals channel to be refused: %s", resp)
+	}
+	if resp := command("u3", "approvals-id", "approve default/app:1.1.0"); resp != "you are not allowed to vote" {
+		t.Errorf("expected approval from unauthorized user to be refused: %s", resp)
+	}
+
+	command("u2", "approvals-id", "Reject default/app:1.1.0")
+	select {
+	case resp := <-b.approvalsRespCh:
+		if resp.User != "discord:u2" || resp.Status != types.ApprovalStatusRejected || resp.Text != "reject default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	command("u3", "general-id", "get approvals")
+	select {
+	case msg := <-b.botMessagesChannel:
+		if msg.Message != "get approvals" || msg.Channel != "general-id" || msg.Name != "discord" {
+			t.Errorf("unexpected bot message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected bot message")
+	}
+
+	if resp := command("u3", "general-id", "help"); !strings.Contains(resp, "get approvals") {
+		t.Errorf("expected help response: %s", resp)
+	}
+}
@@ -0,0 +1,192 @@
+package github
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiURL - GitHub REST API, see https://docs.github.com/en/rest
+const apiURL = "https://api.github.com"
+
+// client - GitHub REST API client for a single repository, authenticated with a
+// personal access token or GitHub App installation token
+type client struct {
+	url    string
+	repo   string // owner/name
+	token  string
+	client *http.Client
+}
+
+type user struct {
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+type issue struct {
+	Number int      `json:"number,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Body   string   `json:"body,omitempty"`
+	State  string   `json:"state,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// issueListItem - labels are objects when issues are listed
+type issueListItem struct {
+	Number int    `json:"number"`
+	Body   string `json:"body"`
+	User   *user  `json:"user"`
+}
+
+type comment struct {
+	Body              string `json:"body"`
+	User              *user  `json:"user,omitempty"`
+	AuthorAssociation string `json:"author_association,omitempty"`
+}
+
+type pullRequest struct {
+	Number int    `json:"number,omitempty"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+}
+
+// authenticatedUser - login of the token owner, its own comments and events are ignored
+func (c *client) authenticatedUser() (*user, error) {
+	var u user
+	if err := c.do(http.MethodGet, "/user", nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// collaboratorPermission - user's permission in the repository: admin, write, read or none
+func (c *client) collaboratorPermission(login string) (string, error) {
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	err := c.do(http.MethodGet, c.repoPath("/collaborators/"+url.PathEscape(login)+"/permission"), nil, &p)
+	return p.Permission, err
+}
+
+// createIssue - returns created issue number
+func (c *client) createIssue(i *issue) (int, error) {
+	var created issue
+	if err := c.do(http.MethodPost, c.repoPath("/issues"), i, &created); err != nil {
+		return 0, err
+	}
+	return created.Number, nil
+}
+
+// openIssues - open issues and pull requests with the label
+func (c *client) openIssues(label string) ([]*issueListItem, error) {
+	var issues []*issueListItem
+	err := c.do(http.MethodGet, c.repoPath("/issues?state=open&per_page=100&labels="+url.QueryEscape(label)), nil, &issues)
+	return issues, err
+}
+
+func (c *client) addLabels(number int, labels []string) error {
+	return c.do(http.MethodPost, c.repoPath(fmt.Sprintf("/issues/%d/labels", number)), map[string][]string{"labels": labels}, nil)
+}
+
+func (c *client) createComment(number int, body string) error {
+	return c.do(http.MethodPost, c.repoPath(fmt.Sprintf("/issues/%d/comments", number)), &comment{Body: body}, nil)
+}
+
+// closeIssue - closes issue or pull request
+func (c *client) closeIssue(number int) error {
+	return c.do(http.MethodPatch, c.repoPath(fmt.Sprintf("/issues/%d", number)), &issue{State: "closed"}, nil)
+}
+
+// createBranch - creates branch from the head of the default branch, returns default branch
+func (c *client) createBranch(branch string) (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.do(http.MethodGet, c.repoPath(""), nil, &repo); err != nil {
+		return "", err
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := c.do(http.MethodGet, c.repoPath("/git/ref/heads/"+repo.DefaultBranch), nil, &ref); err != nil {
+		return "", err
+	}
+
+	err := c.do(http.MethodPost, c.repoPath("/git/refs"), map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": ref.Object.SHA,
+	}, nil)
+	return repo.DefaultBranch, err
+}
+
+// createFile - commits a new file to the branch
+func (c *client) createFile(branch, path, message string, content []byte) error {
+	return c.do(http.MethodPut, c.repoPath("/contents/"+path), map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(content),
+		"branch":  branch,
+	}, nil)
+}
+
+// createPullRequest - returns created pull request number
+func (c *client) createPullRequest(pr *pullRequest) (int, error) {
+	var created pullRequest
+	if err := c.do(http.MethodPost, c.repoPath("/pulls"), pr, &created); err != nil {
+		return 0, err
+	}
+	return created.Number, nil
+}
+
+func (c *client) repoPath(path string) string {
+	return "/repos/" + c.repo + path
+}
+
+func (c *client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.url, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: unexpected status code %d: %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
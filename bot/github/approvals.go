@@ -0,0 +1,165 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// unsafeName - characters replaced in branch and file names
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// RequestApproval - opens an issue or pull request for the approval, requests sent again
+// (reminders, escalations) are posted as comments to the open one
+func (b *Bot) RequestApproval(req *types.Approval) error {
+	if r := b.findRequest(req.Identifier); r != nil && !r.closed {
+		return b.api.createComment(r.number, fmt.Sprintf("**Reminder:** %d/%d votes received, deadline %s.",
+			req.VotesReceived, req.VotesRequired, req.Deadline.UTC().Format("2006-01-02 15:04 MST")))
+	}
+
+	title := fmt.Sprintf("Keel: approve %s update %s", req.Identifier, req.Delta())
+	body := b.requestBody(req)
+
+	var (
+		number int
+		err    error
+	)
+	if b.mode == ModePullRequest {
+		number, err = b.openPullRequest(req, title, body)
+	} else {
+		number, err = b.api.createIssue(&issue{Title: title, Body: body, Labels: []string{approvalLabel}})
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"repository": b.api.repo,
+		}).Error("bot.github.RequestApproval: failed to open approval request")
+		return err
+	}
+
+	b.setRequest(req.Identifier, number, false)
+	return nil
+}
+
+// openPullRequest - commits a file describing the update to a new branch and opens a
+// pull request from it
+func (b *Bot) openPullRequest(req *types.Approval, title, body string) (int, error) {
+	name := strings.Trim(unsafeName.ReplaceAllString(req.Identifier, "-"), "-")
+	branch := "keel/approval-" + name
+
+	base, err := b.api.createBranch(branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create branch: %s", err)
+	}
+	err = b.api.createFile(branch, ".keel/approvals/"+name+".md", title, []byte(b.requestBody(req)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit approval request: %s", err)
+	}
+
+	number, err := b.api.createPullRequest(&pullRequest{Title: title, Body: body, Head: branch, Base: base})
+	if err != nil {
+		return 0, err
+	}
+	if err := b.api.addLabels(number, []string{approvalLabel}); err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"number": number,
+		}).Warn("bot.github.RequestApproval: failed to label pull request")
+	}
+	return number, nil
+}
+
+// ReplyToApproval - comments with votes or the outcome, approval request is closed once
+// the update is approved or rejected
+func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	var text string
+	switch approval.Status() {
+	case types.ApprovalStatusPending:
+		text = fmt.Sprintf("Vote received, %d/%d votes. Waiting for remaining votes.", approval.VotesReceived, approval.VotesRequired)
+	case types.ApprovalStatusRejected:
+		text = "Change was rejected."
+	case types.ApprovalStatusApproved:
+		text = "All approvals received, thanks for voting! Update will be applied."
+	default:
+		return nil
+	}
+	if voters := approval.GetVoters(); len(voters) > 0 {
+		sort.Strings(voters)
+		text += "\n\nVoted by: " + strings.Join(voters, ", ")
+	}
+
+	r := b.findRequest(approval.Identifier)
+	if r == nil {
+		log.WithFields(log.Fields{
+			"identifier": approval.Identifier,
+		}).Warn("bot.github.ReplyToApproval: approval request not found")
+		return nil
+	}
+
+	if err := b.api.createComment(r.number, text); err != nil {
+		return err
+	}
+	if approval.Status() == types.ApprovalStatusPending {
+		return nil
+	}
+
+	b.mu.Lock()
+	delete(b.requests, approval.Identifier)
+	b.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	return b.api.closeIssue(r.number)
+}
+
+// findRequest - approval request, open requests are listed when the bot doesn't know
+// about it (ie: after restart), nil if there isn't one
+func (b *Bot) findRequest(identifier string) *request {
+	b.mu.Lock()
+	r, ok := b.requests[identifier]
+	b.mu.Unlock()
+	if ok {
+		return r
+	}
+
+	issues, err := b.api.openIssues(approvalLabel)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("bot.github: failed to list open approval requests")
+		return nil
+	}
+	for _, i := range issues {
+		if b.approvalIdentifier(i.Body, i.User) == identifier {
+			b.setRequest(identifier, i.Number, false)
+			return &request{number: i.Number}
+		}
+	}
+	return nil
+}
+
+func (b *Bot) requestBody(approval *types.Approval) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s\n\n", approval.Message)
+	fmt.Fprintf(buf, "| | |\n|---|---|\n")
+	fmt.Fprintf(buf, "| Identifier | `%s` |\n", approval.Identifier)
+	fmt.Fprintf(buf, "| Provider | %s |\n", approval.Provider)
+	fmt.Fprintf(buf, "| Delta | %s |\n", approval.Delta())
+	fmt.Fprintf(buf, "| Votes required | %d |\n", approval.VotesRequired)
+	fmt.Fprintf(buf, "| Deadline | %s |\n\n", approval.Deadline.UTC().Format("2006-01-02 15:04 MST"))
+	if b.mode == ModePullRequest {
+		fmt.Fprintf(buf, "Merge this pull request or comment `/approve` to vote for the update, close it or comment `/reject` to reject it.\n\n")
+	} else {
+		fmt.Fprintf(buf, "Comment `/approve` to vote for the update or `/reject` to reject it.\n\n")
+	}
+	fmt.Fprintf(buf, "_https://keel.sh %s_\n\n", version.GetKeelVersion().Version)
+	fmt.Fprintf(buf, "<!-- keel-approval: %s -->\n", approval.Identifier)
+	return buf.String()
+}
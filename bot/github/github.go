@@ -0,0 +1,342 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Approval modes
+const (
+	// ModeIssue - approval request is an issue, votes are "/approve" and "/reject" comments
+	ModeIssue = "issue"
+	// ModePullRequest - approval request is a pull request adding a file describing the
+	// update, merging it approves the update, closing it rejects the update. Comments are
+	// counted as votes too.
+	ModePullRequest = "pr"
+)
+
+// approvalLabel - added to approval requests so they can be found after restarts
+const approvalLabel = "keel-approval"
+
+// approvalMarker - hidden in the request body, identifies the approval
+var approvalMarker = regexp.MustCompile(`<!-- keel-approval: (\S+) -->`)
+
+// trustedAssociations - repository roles allowed to vote when approvers aren't listed
+var trustedAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// Bot - GitHub approvals bot, approval requests are opened as issues or pull requests
+// in the approvals repository and votes are received from the repository webhook at
+// /v1/bots/github/interactions, discussion and votes stay in GitHub
+type Bot struct {
+	mode          string
+	webhookSecret string
+	approvers     map[string]bool // lowercase logins, repository collaborators when empty
+	login         string          // token owner, its own events are ignored
+
+	api *client
+
+	mu       sync.Mutex
+	requests map[string]*request // approval requests by identifier
+
+	ctx             context.Context
+	approvalsRespCh chan *bot.ApprovalResponse
+}
+
+func init() {
+	if isGithubConfigured() {
+		bot.RegisterBot("github", &Bot{})
+	}
+}
+
+func isGithubConfigured() bool {
+	return os.Getenv(constants.EnvGithubApprovalsRepo) != "" && os.Getenv(constants.EnvGithubApprovalsToken) != ""
+}
+
+func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
+	if !isGithubConfigured() {
+		log.Info("bot.github.Configure(): GitHub approvals bot is not configured")
+		return false
+	}
+
+	b.mode = ModeIssue
+	if mode := os.Getenv(constants.EnvGithubApprovalsMode); mode != "" {
+		if mode != ModeIssue && mode != ModePullRequest {
+			log.WithFields(log.Fields{
+				"mode": mode,
+			}).Error("bot.github.Configure(): unknown approvals mode, expected 'issue' or 'pr'")
+			return false
+		}
+		b.mode = mode
+	}
+
+	b.webhookSecret = os.Getenv(constants.EnvGithubApprovalsWebhookSecret)
+	if b.webhookSecret == "" {
+		log.Warn("bot.github.Configure(): webhook secret is not set, votes can't be received")
+	}
+
+	b.approvers = make(map[string]bool)
+	for _, login := range strings.Split(os.Getenv(constants.EnvGithubApprovers), ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			b.approvers[strings.ToLower(login)] = true
+		}
+	}
+
+	url := apiURL
+	if u := os.Getenv(constants.EnvGithubAPIURL); u != "" {
+		url = u
+	}
+	b.api = &client{
+		url:    url,
+		repo:   os.Getenv(constants.EnvGithubApprovalsRepo),
+		token:  os.Getenv(constants.EnvGithubApprovalsToken),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	b.requests = make(map[string]*request)
+	b.approvalsRespCh = approvalsRespCh
+
+	return true
+}
+
+// Start - checks the token, votes are received over HTTP
+func (b *Bot) Start(ctx context.Context) error {
+	u, err := b.api.authenticatedUser()
+	if err != nil {
+		return fmt.Errorf("failed to get authenticated user: %s", err)
+	}
+	b.login = u.Login
+	b.ctx = ctx
+
+	log.WithFields(log.Fields{
+		"repository": b.api.repo,
+		"mode":       b.mode,
+		"login":      b.login,
+	}).Info("bot.github: approval requests will be opened in the repository")
+	return nil
+}
+
+// Respond - GitHub bot doesn't receive bot commands
+func (b *Bot) Respond(text string, channel string) {
+	log.WithFields(log.Fields{
+		"channel": channel,
+	}).Debug("bot.github.Respond: bot commands are not supported")
+}
+
+// request - approval request issue or pull request
+type request struct {
+	number int
+	closed bool // pull request merged or closed by a voter
+}
+
+// webhookEvent - issue_comment and pull_request webhook payloads, see
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads
+type webhookEvent struct {
+	Action string `json:"action"`
+	Issue  *struct {
+		Number int    `json:"number"`
+		Body   string `json:"body"`
+		User   *user  `json:"user"`
+	} `json:"issue"`
+	Comment     *comment `json:"comment"`
+	PullRequest *struct {
+		Number int    `json:"number"`
+		Body   string `json:"body"`
+		Merged bool   `json:"merged"`
+		User   *user  `json:"user"`
+	} `json:"pull_request"`
+	Sender *user `json:"sender"`
+}
+
+// InteractionHandler - repository webhook handler
+func (b *Bot) InteractionHandler() http.Handler {
+	if b.ctx == nil || b.webhookSecret == "" {
+		return nil
+	}
+	return http.HandlerFunc(b.webhookHandler)
+}
+
+func (b *Bot) webhookHandler(resp http.ResponseWriter, req *http.Request) {
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !validSignature(req.Header.Get("X-Hub-Signature-256"), payload, b.webhookSecret) {
+		log.Warn("bot.github.webhookHandler: invalid webhook signature")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(resp, fmt.Sprintf("failed to decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Header.Get("X-GitHub-Event") {
+	case "issue_comment":
+		if event.Action == "created" && event.Issue != nil && event.Comment != nil {
+			b.handleComment(event.Issue.Number, b.approvalIdentifier(event.Issue.Body, event.Issue.User), event.Comment)
+		}
+	case "pull_request":
+		if event.Action == "closed" && event.PullRequest != nil && event.Sender != nil {
+			b.handlePullRequestClosed(event.PullRequest.Number, b.approvalIdentifier(event.PullRequest.Body, event.PullRequest.User), event.PullRequest.Merged, event.Sender.Login)
+		}
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+// handleComment - "/approve" and "/reject" comments are votes
+func (b *Bot) handleComment(number int, identifier string, c *comment) {
+	if identifier == "" || c.User == nil || strings.EqualFold(c.User.Login, b.login) {
+		return
+	}
+
+	var keyword string
+	switch command := strings.ToLower(strings.TrimSpace(strings.SplitN(strings.TrimSpace(c.Body), "\n", 2)[0])); command {
+	case "/approve", "/lgtm":
+		keyword = bot.ApprovalResponseKeyword
+	case "/reject":
+		keyword = bot.RejectResponseKeyword
+	default:
+		return
+	}
+
+	b.setRequest(identifier, number, false)
+	if !b.canVote(c.User.Login, c.AuthorAssociation) {
+		b.refuseVote(number, c.User.Login)
+		return
+	}
+	b.vote(c.User.Login, keyword, identifier)
+}
+
+// handlePullRequestClosed - merged pull request approves the update, closed one rejects it
+func (b *Bot) handlePullRequestClosed(number int, identifier string, merged bool, sender string) {
+	if identifier == "" || strings.EqualFold(sender, b.login) {
+		return
+	}
+
+	b.setRequest(identifier, number, true)
+
+	// pull request events don't carry sender's association, it's looked up when
+	// approvers aren't listed
+	var association string
+	if len(b.approvers) == 0 {
+		association = b.association(sender)
+	}
+	if !b.canVote(sender, association) {
+		b.refuseVote(number, sender)
+		return
+	}
+
+	keyword := bot.RejectResponseKeyword
+	if merged {
+		keyword = bot.ApprovalResponseKeyword
+	}
+	b.vote(sender, keyword, identifier)
+}
+
+// association - repository association of the user derived from their permission,
+// users with write access are collaborators
+func (b *Bot) association(login string) string {
+	permission, err := b.api.collaboratorPermission(login)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"user":  login,
+		}).Warn("bot.github: failed to get user permission")
+		return "NONE"
+	}
+	if permission == "admin" || permission == "write" {
+		return "COLLABORATOR"
+	}
+	return "NONE"
+}
+
+func (b *Bot) canVote(login, association string) bool {
+	if len(b.approvers) > 0 {
+		return b.approvers[strings.ToLower(login)]
+	}
+	return trustedAssociations[association]
+}
+
+func (b *Bot) refuseVote(number int, login string) {
+	log.WithFields(log.Fields{
+		"user":   login,
+		"number": number,
+	}).Warn("bot.github: vote from user that is not an approver refused")
+
+	err := b.api.createComment(number, fmt.Sprintf("@%s you are not allowed to vote on this update, the vote was not counted.", login))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"number": number,
+		}).Error("bot.github: failed to comment")
+	}
+}
+
+// vote - bot manager channels are unbuffered, GitHub expects a quick webhook response
+func (b *Bot) vote(login, keyword, identifier string) {
+	approval, ok := bot.IsApproval("github", login, keyword+" "+identifier)
+	if !ok {
+		return
+	}
+	go func() {
+		select {
+		case b.approvalsRespCh <- approval:
+		case <-b.ctx.Done():
+		}
+	}()
+}
+
+func (b *Bot) setRequest(identifier string, number int, closed bool) {
+	b.mu.Lock()
+	b.requests[identifier] = &request{number: number, closed: closed}
+	b.mu.Unlock()
+}
+
+// approvalIdentifier - approval identifier from the request body, empty for issues and
+// pull requests not opened by Keel
+func (b *Bot) approvalIdentifier(body string, author *user) string {
+	if author == nil || !strings.EqualFold(author.Login, b.login) {
+		return ""
+	}
+	m := approvalMarker.FindStringSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// validSignature - checks "sha256=<hex>" HMAC signature of the payload
+func validSignature(signature string, payload []byte, secret string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
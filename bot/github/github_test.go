@@ -0,0 +1,295 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+type apiCall struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+type fakeServer struct {
+	mu    sync.Mutex
+	calls []apiCall
+}
+
+func (f *fakeServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer gh-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, apiCall{method: r.Method, path: r.URL.Path, body: body})
+	f.mu.Unlock()
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/repos/acme/deploys":
+		w.Write([]byte(`{"default_branch": "main"}`))
+	case r.Method == "GET" && r.URL.Path == "/repos/acme/deploys/issues":
+		w.Write([]byte(`[]`))
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/permission"):
+		if strings.Contains(r.URL.Path, "/collaborators/jane/") {
+			w.Write([]byte(`{"permission": "write"}`))
+		} else {
+			w.Write([]byte(`{"permission": "read"}`))
+		}
+	case r.Method == "GET":
+		w.Write([]byte(`{"object": {"sha": "abc"}}`))
+	default:
+		w.Write([]byte(`{"number": 7}`))
+	}
+}
+
+func (f *fakeServer) lastCall() apiCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[len(f.calls)-1]
+}
+
+func newTestBot(t *testing.T, mode string) (*Bot, *fakeServer, func()) {
+	f := &fakeServer{}
+	srv := httptest.NewServer(http.HandlerFunc(f.handler))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Bot{
+		mode:            mode,
+		webhookSecret:   "secret",
+		approvers:       map[string]bool{},
+		login:           "keel-bot",
+		api:             &client{url: srv.URL, repo: "acme/deploys", token: "gh-token", client: http.DefaultClient},
+		requests:        make(map[string]*request),
+		approvalsRespCh: make(chan *bot.ApprovalResponse, 1),
+		ctx:             ctx,
+	}
+	return b, f, func() {
+		cancel()
+		srv.Close()
+	}
+}
+
+func sendWebhook(b *Bot, event string, payload interface{}) int {
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	req := httptest.NewRequest("POST", "/v1/bots/github/interactions", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	b.InteractionHandler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func commentEvent(body, login, association, issueBody string) map[string]interface{} {
+	return map[string]interface{}{
+		"action":  "created",
+		"issue":   map[string]interface{}{"number": 7, "body": issueBody, "user": map[string]string{"login": "keel-bot"}},
+		"comment": map[string]interface{}{"body": body, "user": map[string]string{"login": login}, "author_association": association},
+		"sender":  map[string]string{"login": login},
+	}
+}
+
+func expectVote(t *testing.T, b *Bot, user string, status types.ApprovalStatus, text string) {
+	select {
+	case resp := <-b.approvalsRespCh:
+		if resp.User != user || resp.Status != status || resp.Text != text {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+}
+
+func expectNoVote(t *testing.T, b *Bot) {
+	select {
+	case resp := <-b.approvalsRespCh:
+		t.Errorf("unexpected approval response: %+v", resp)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestIssueApproval(t *testing.T) {
+	b, f, teardown := newTestBot(t, ModeIssue)
+	defer teardown()
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		Message:        "New image is available for deployment default/app",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+	}
+	if err := b.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	created := f.lastCall()
+	if created.method != "POST" || created.path != "/repos/acme/deploys/issues" {
+		t.Fatalf("expected issue to be opened, got %s %s", created.method, created.path)
+	}
+	issueBody := created.body["body"].(string)
+	if !strings.Contains(issueBody, "<!-- keel-approval: default/app:1.1.0 -->") || created.body["labels"].([]interface{})[0] != approvalLabel {
+		t.Errorf("unexpected issue: %v", created.body)
+	}
+
+	if code := sendWebhook(b, "ping", map[string]string{"zen": "hi"}); code != http.StatusOK {
+		t.Errorf("unexpected ping response: %d", code)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/bots/github/interactions", strings.NewReader(`{}`))
+	req.Header.Set("X-Hub-Signature-256", "sha256=00")
+	rec := httptest.NewRecorder()
+	b.InteractionHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid signature to be rejected, got %d", rec.Code)
+	}
+
+	// own comments and comments that aren't commands are ignored
+	sendWebhook(b, "issue_comment", commentEvent("/approve", "keel-bot", "OWNER", issueBody))
+	sendWebhook(b, "issue_comment", commentEvent("looks good", "jane", "MEMBER", issueBody))
+	expectNoVote(t, b)
+
+	// issues not opened by keel are ignored even if they carry the marker
+	forged := commentEvent("/approve", "jane", "MEMBER", issueBody)
+	forged["issue"] = map[string]interface{}{"number": 8, "body": issueBody, "user": map[string]string{"login": "mallory"}}
+	sendWebhook(b, "issue_comment", forged)
+	expectNoVote(t, b)
+	if r := b.findRequest("default/app:1.1.0"); r == nil || r.number != 7 {
+		t.Errorf("expected request to stay bound to issue 7, got: %+v", r)
+	}
+
+	sendWebhook(b, "issue_comment", commentEvent("/approve", "mallory", "NONE", issueBody))
+	expectNoVote(t, b)
+	if refused := f.lastCall(); refused.path != "/repos/acme/deploys/issues/7/comments" || !strings.Contains(refused.body["body"].(string), "@mallory you are not allowed to vote") {
+		t.Errorf("expected refusal comment, got %s %v", refused.path, refused.body)
+	}
+
+	sendWebhook(b, "issue_comment", commentEvent("/Approve\nship it", "jane", "MEMBER", issueBody))
+	expectVote(t, b, "github:jane", types.ApprovalStatusApproved, "approve default/app:1.1.0")
+
+	approval.AddVoter("github:jane")
+	approval.VotesReceived = 1
+	if err := b.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	closed := f.lastCall()
+	if closed.method != "PATCH" || closed.path != "/repos/acme/deploys/issues/7" || closed.body["state"] != "closed" {
+		t.Errorf("expected issue to be closed, got %s %s %v", closed.method, closed.path, closed.body)
+	}
+	if len(b.requests) != 0 {
+		t.Errorf("expected approved request to be forgotten")
+	}
+}
+
+func TestPullRequestApproval(t *testing.T) {
+	b, f, teardown := newTestBot(t, ModePullRequest)
+	defer teardown()
+	b.approvers = map[string]bool{"jane": true}
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+	}
+	if err := b.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var paths []string
+	var prBody string
+	for _, c := range f.calls {
+		paths = append(paths, c.method+" "+c.path)
+		if c.path == "/repos/acme/deploys/pulls" {
+			prBody = c.body["body"].(string)
+			if c.body["head"] != "keel/approval-default-app-1.1.0" || c.body["base"] != "main" {
+				t.Errorf("unexpected pull request: %v", c.body)
+			}
+		}
+	}
+	for _, expected := range []string{
+		"POST /repos/acme/deploys/git/refs",
+		"PUT /repos/acme/deploys/contents/.keel/approvals/default-app-1.1.0.md",
+		"POST /repos/acme/deploys/pulls",
+		"POST /repos/acme/deploys/issues/7/labels",
+	} {
+		if !strings.Contains(strings.Join(paths, "\n"), expected) {
+			t.Errorf("expected %s, got:\n%s", expected, strings.Join(paths, "\n"))
+		}
+	}
+
+	closeEvent := func(login string, merged bool) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       "closed",
+			"pull_request": map[string]interface{}{"number": 7, "body": prBody, "merged": merged, "user": map[string]string{"login": "keel-bot"}},
+			"sender":       map[string]string{"login": login},
+		}
+	}
+
+	// pull requests not opened by keel are ignored
+	forged := closeEvent("jane", false)
+	forged["pull_request"] = map[string]interface{}{"number": 9, "body": prBody, "merged": false, "user": map[string]string{"login": "mallory"}}
+	sendWebhook(b, "pull_request", forged)
+	expectNoVote(t, b)
+
+	sendWebhook(b, "pull_request", closeEvent("bob", true))
+	expectNoVote(t, b)
+	if refused := f.lastCall(); !strings.Contains(refused.body["body"].(string), "@bob you are not allowed to vote") {
+		t.Errorf("expected refusal comment, got %v", refused.body)
+	}
+
+	sendWebhook(b, "pull_request", closeEvent("jane", true))
+	expectVote(t, b, "github:jane", types.ApprovalStatusApproved, "approve default/app:1.1.0")
+
+	// merged pull request isn't closed again
+	approval.AddVoter("github:jane")
+	approval.VotesReceived = 1
+	calls := len(f.calls)
+	if err := b.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(f.calls) != calls+1 || f.lastCall().method != "POST" {
+		t.Errorf("expected only a comment, got: %+v", f.calls[calls:])
+	}
+}
+
+func TestPullRequestClosedWithoutApprovers(t *testing.T) {
+	b, f, teardown := newTestBot(t, ModePullRequest)
+	defer teardown()
+
+	body := "<!-- keel-approval: default/app:1.1.0 -->"
+	closeEvent := func(login string) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       "closed",
+			"pull_request": map[string]interface{}{"number": 7, "body": body, "merged": false, "user": map[string]string{"login": "keel-bot"}},
+			"sender":       map[string]string{"login": login},
+		}
+	}
+
+	// users without write access can't reject updates by closing the pull request
+	sendWebhook(b, "pull_request", closeEvent("mallory"))
+	expectNoVote(t, b)
+	if refused := f.lastCall(); !strings.Contains(refused.body["body"].(string), "@mallory you are not allowed to vote") {
+		t.Errorf("expected refusal comment, got %v", refused.body)
+	}
+
+	sendWebhook(b, "pull_request", closeEvent("jane"))
+	expectVote(t, b, "github:jane", types.ApprovalStatusRejected, "reject default/app:1.1.0")
+}
@@ -112,7 +112,7 @@ func (b *Bot) handleMessage(message *h.Message) {
 		return
 	}
 
-	approval, ok := bot.IsApproval(msg.From, msg.Body)
+	approval, ok := bot.IsApproval("hipchat", msg.From, msg.Body)
 	if ok {
 		b.approvalsRespCh <- approval
 		return
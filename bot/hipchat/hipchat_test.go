@@ -43,6 +43,11 @@ func (p *fakeProvider) List() []string {
 func (p *fakeProvider) Stop() {
 	return
 }
+func (p *fakeProvider) Pause()  {}
+func (p *fakeProvider) Resume() {}
+func (p *fakeProvider) Paused() bool {
+	return false
+}
 func (p *fakeProvider) GetName() string {
 	return "fp"
 }
@@ -99,7 +104,7 @@ func NewBot(k8sImplementer kubernetes.Implementer,
 	os.Setenv("HIPCHAT_CONNECTION_ATTEMPTS", "0")
 
 	b.RegisterBot("fakechat", fakeBot)
-	b.Run(k8sImplementer, approvalsManager)
+	b.Run(k8sImplementer, approvalsManager, nil)
 	return fakeBot
 }
 
@@ -0,0 +1,113 @@
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// client - Mattermost REST API (https://api.mattermost.com/) client authenticated with
+// the bot account token
+type client struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type post struct {
+	ID        string     `json:"id,omitempty"`
+	ChannelID string     `json:"channel_id,omitempty"`
+	Message   string     `json:"message"`
+	Props     *postProps `json:"props,omitempty"`
+}
+
+type postProps struct {
+	Attachments []*attachment `json:"attachments"`
+}
+
+// attachment - message attachment, see https://docs.mattermost.com/developer/message-attachments.html
+type attachment struct {
+	Fallback string             `json:"fallback"`
+	Color    string             `json:"color,omitempty"`
+	Title    string             `json:"title,omitempty"`
+	Text     string             `json:"text,omitempty"`
+	Fields   []*attachmentField `json:"fields,omitempty"`
+	Footer   string             `json:"footer,omitempty"`
+	Actions  []*action          `json:"actions,omitempty"`
+}
+
+type attachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// action - interactive message button, see https://docs.mattermost.com/developer/interactive-messages.html
+type action struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Style       string       `json:"style,omitempty"`
+	Integration *integration `json:"integration"`
+}
+
+type integration struct {
+	URL     string         `json:"url"`
+	Context *buttonContext `json:"context"`
+}
+
+// buttonContext - sent back by Mattermost when the button is clicked, signed so
+// the callback doesn't need a separate secret
+type buttonContext struct {
+	Action     string `json:"action"`
+	Identifier string `json:"identifier"`
+	Signature  string `json:"signature"`
+}
+
+// createPost - returns created post ID
+func (c *client) createPost(p *post) (string, error) {
+	var created post
+	if err := c.do(http.MethodPost, "/api/v4/posts", p, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// patchPost - replaces message and attachments of the post
+func (c *client) patchPost(id string, p *post) error {
+	return c.do(http.MethodPut, "/api/v4/posts/"+id+"/patch", p, nil)
+}
+
+func (c *client) do(method, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: unexpected status code %d: %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
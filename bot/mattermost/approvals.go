@@ -0,0 +1,171 @@
+package mattermost
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestApproval - posts approval request to the approvals channel, with Approve/Reject
+// buttons when callback URL is configured
+func (b *Bot) RequestApproval(req *types.Approval) error {
+	a := b.approvalAttachment(req, "Approval required", types.LevelSuccess.Color())
+	a.Text = req.Message
+	if b.callbackURL != "" {
+		a.Actions = []*action{
+			b.button(bot.ApprovalResponseKeyword, "Approve", "good", req.Identifier),
+			b.button(bot.RejectResponseKeyword, "Reject", "danger", req.Identifier),
+		}
+	} else {
+		a.Text += "\n" + fmt.Sprintf("To vote for change type '%s approve %s' to reject it: '%s reject %s'.", b.name, req.Identifier, b.name, req.Identifier)
+	}
+
+	id, err := b.api.createPost(&post{ChannelID: b.approvalsChannel, Props: &postProps{Attachments: []*attachment{a}}})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":             err,
+			"approvals_channel": b.approvalsChannel,
+		}).Error("bot.mattermost.RequestApproval: failed to send message")
+		return err
+	}
+	b.setApprovalPost(req.Identifier, id)
+	return nil
+}
+
+// ReplyToApproval - updates the approval request post with votes or the outcome, posts
+// a new message if the request post isn't known
+func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	var a *attachment
+	switch approval.Status() {
+	case types.ApprovalStatusPending:
+		a = b.approvalAttachment(approval, "Vote received", types.LevelInfo.Color())
+		a.Text = "Waiting for remaining votes."
+	case types.ApprovalStatusRejected:
+		a = b.approvalAttachment(approval, "Change rejected", types.LevelWarn.Color())
+		a.Text = "Change was rejected."
+	case types.ApprovalStatusApproved:
+		a = b.approvalAttachment(approval, "Update approved", types.LevelSuccess.Color())
+		a.Text = "All approvals received, thanks for voting!"
+	default:
+		return nil
+	}
+	if voters := b.voters(approval); voters != "" {
+		a.Fields = append(a.Fields, &attachmentField{Title: "Voted by", Value: voters, Short: false})
+	}
+
+	if id := b.approvalPost(approval.Identifier); id != "" {
+		if approval.Status() == types.ApprovalStatusPending && b.callbackURL != "" {
+			a.Actions = []*action{
+				b.button(bot.ApprovalResponseKeyword, "Approve", "good", approval.Identifier),
+				b.button(bot.RejectResponseKeyword, "Reject", "danger", approval.Identifier),
+			}
+		} else {
+			b.removeApprovalPost(approval.Identifier)
+		}
+		a.Text = approval.Message + "\n\n" + a.Text
+		err := b.api.patchPost(id, &post{Props: &postProps{Attachments: []*attachment{a}}})
+		if err == nil {
+			return nil
+		}
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": approval.Identifier,
+		}).Warn("bot.mattermost.ReplyToApproval: failed to update approval request, posting reply")
+	}
+
+	_, err := b.api.createPost(&post{ChannelID: b.approvalsChannel, Props: &postProps{Attachments: []*attachment{a}}})
+	return err
+}
+
+func (b *Bot) approvalAttachment(approval *types.Approval, title, color string) *attachment {
+	return &attachment{
+		Fallback: title + ": " + approval.Message,
+		Color:    color,
+		Title:    title,
+		Fields: []*attachmentField{
+			{Title: "Votes", Value: fmt.Sprintf("%d/%d", approval.VotesReceived, approval.VotesRequired), Short: true},
+			{Title: "Delta", Value: approval.Delta(), Short: true},
+			{Title: "Identifier", Value: approval.Identifier, Short: true},
+			{Title: "Provider", Value: approval.Provider.String(), Short: true},
+		},
+		Footer: fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
+	}
+}
+
+func (b *Bot) button(keyword, name, style, identifier string) *action {
+	return &action{
+		ID:    keyword,
+		Name:  name,
+		Style: style,
+		Integration: &integration{
+			URL: b.callbackURL,
+			Context: &buttonContext{
+				Action:     keyword,
+				Identifier: identifier,
+				Signature:  b.sign(keyword, identifier),
+			},
+		},
+	}
+}
+
+// sign - button context signature, keyed with the bot token which only Keel and the
+// Mattermost server know
+func (b *Bot) sign(keyword, identifier string) string {
+	mac := hmac.New(sha256.New, []byte(b.api.token))
+	fmt.Fprintf(mac, "%s\x00%s", keyword, identifier)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// voters - voter user names, IDs for voters that haven't used the bot since start
+func (b *Bot) voters(approval *types.Approval) string {
+	voters := approval.GetVoters()
+	for i := range voters {
+		if name := b.userName(voters[i]); name != "" {
+			voters[i] = "@" + name
+		}
+	}
+	sort.Strings(voters)
+	return strings.Join(voters, ", ")
+}
+
+func (b *Bot) setApprovalPost(identifier, id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.posts[identifier] = id
+}
+
+func (b *Bot) approvalPost(identifier string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.posts[identifier]
+}
+
+func (b *Bot) removeApprovalPost(identifier string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.posts, identifier)
+}
+
+func (b *Bot) setUserName(id, name string) {
+	if id == "" || name == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.users[id] = name
+}
+
+func (b *Bot) userName(id string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.users[id]
+}
@@ -0,0 +1,264 @@
+package mattermost
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Bot - Mattermost approvals bot. Approval requests are posted with the bot account,
+// commands are received from an outgoing webhook and button clicks from interactive
+// messages, both at /v1/bots/mattermost/interactions
+type Bot struct {
+	name             string
+	approvalsChannel string // approvals channel ID
+	webhookToken     string
+	callbackURL      string
+
+	api *client
+
+	mu    sync.Mutex
+	posts map[string]string // approval request post IDs by approval identifier
+	users map[string]string // user names by ID
+
+	ctx                context.Context
+	botMessagesChannel chan *bot.BotMessage
+	approvalsRespCh    chan *bot.ApprovalResponse
+}
+
+func init() {
+	if isMattermostConfigured() {
+		bot.RegisterBot("mattermost", &Bot{})
+	}
+}
+
+func isMattermostConfigured() bool {
+	return os.Getenv(constants.EnvMattermostURL) != "" && os.Getenv(constants.EnvMattermostBotToken) != "" &&
+		os.Getenv(constants.EnvMattermostApprovalsChannel) != ""
+}
+
+func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
+	if !isMattermostConfigured() {
+		log.Info("bot.mattermost.Configure(): Mattermost approval bot is not configured")
+		return false
+	}
+
+	b.name = "keel"
+	if name := os.Getenv(constants.EnvMattermostBotName); name != "" {
+		b.name = name
+	}
+	b.approvalsChannel = os.Getenv(constants.EnvMattermostApprovalsChannel)
+	b.webhookToken = os.Getenv(constants.EnvMattermostWebhookToken)
+	b.callbackURL = os.Getenv(constants.EnvMattermostCallbackURL)
+	b.api = &client{
+		url:    os.Getenv(constants.EnvMattermostURL),
+		token:  os.Getenv(constants.EnvMattermostBotToken),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	b.posts = make(map[string]string)
+	b.users = make(map[string]string)
+	b.approvalsRespCh = approvalsRespCh
+	b.botMessagesChannel = botMessagesChannel
+
+	return true
+}
+
+// Start - commands and button clicks are received over HTTP, nothing to connect to
+func (b *Bot) Start(ctx context.Context) error {
+	b.ctx = ctx
+	return nil
+}
+
+// Respond - posts command response to the channel
+func (b *Bot) Respond(text string, channel string) {
+	_, err := b.api.createPost(&post{ChannelID: channel, Message: "```\n" + text + "\n```"})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"channel": channel,
+		}).Error("bot.mattermost.Respond: failed to send message")
+	}
+}
+
+// request - outgoing webhook (https://docs.mattermost.com/developer/webhooks-outgoing.html)
+// or interactive message button request
+type request struct {
+	Token       string `json:"token"`
+	ChannelID   string `json:"channel_id"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	Text        string `json:"text"`
+	TriggerWord string `json:"trigger_word"`
+
+	PostID  string         `json:"post_id"`
+	Context *buttonContext `json:"context"`
+}
+
+type response struct {
+	Text          string `json:"text,omitempty"`
+	EphemeralText string `json:"ephemeral_text,omitempty"`
+}
+
+// InteractionHandler - outgoing webhook and interactive message buttons handler
+func (b *Bot) InteractionHandler() http.Handler {
+	if b.ctx == nil {
+		return nil
+	}
+	return http.HandlerFunc(b.interactionHandler)
+}
+
+func (b *Bot) interactionHandler(resp http.ResponseWriter, req *http.Request) {
+	r, err := parseRequest(req)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b.setUserName(r.UserID, r.UserName)
+
+	var reply *response
+	if r.Context != nil {
+		reply, err = b.handleButton(r)
+	} else {
+		reply, err = b.handleCommand(r)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"user":  r.UserName,
+		}).Warn("bot.mattermost.interactionHandler: request rejected")
+		http.Error(resp, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if reply == nil {
+		reply = &response{}
+	}
+	json.NewEncoder(resp).Encode(reply)
+}
+
+func parseRequest(req *http.Request) (*request, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var r request
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.Unmarshal(body, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode request: %s", err)
+		}
+		return &r, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Token = form.Get("token")
+	r.ChannelID = form.Get("channel_id")
+	r.UserID = form.Get("user_id")
+	r.UserName = form.Get("user_name")
+	r.Text = form.Get("text")
+	r.TriggerWord = form.Get("trigger_word")
+	return &r, nil
+}
+
+func (b *Bot) handleButton(r *request) (*response, error) {
+	if !hmac.Equal([]byte(r.Context.Signature), []byte(b.sign(r.Context.Action, r.Context.Identifier))) {
+		return nil, fmt.Errorf("invalid button signature")
+	}
+
+	approval, ok := bot.IsApproval("mattermost", r.UserID, r.Context.Action+" "+r.Context.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("unknown action '%s'", r.Context.Action)
+	}
+	if r.PostID != "" {
+		b.setApprovalPost(r.Context.Identifier, r.PostID)
+	}
+	b.sendApproval(approval)
+
+	return &response{EphemeralText: "Vote received, thanks!"}, nil
+}
+
+func (b *Bot) handleCommand(r *request) (*response, error) {
+	if b.webhookToken == "" || subtle.ConstantTimeCompare([]byte(r.Token), []byte(b.webhookToken)) != 1 {
+		return nil, fmt.Errorf("invalid outgoing webhook token")
+	}
+
+	text := b.trimBot(r.Text, r.TriggerWord)
+
+	if approval, ok := bot.IsApproval("mattermost", r.UserID, text); ok {
+		// only accepting approvals from approvals channel
+		if r.ChannelID != b.approvalsChannel {
+			return &response{Text: "please use approvals channel"}, nil
+		}
+		b.sendApproval(approval)
+		return nil, nil
+	}
+
+	if responseLines, ok := bot.BotEventTextToResponse[text]; ok {
+		return &response{Text: strings.Join(responseLines, "\n")}, nil
+	}
+
+	if bot.IsBotCommand(text) {
+		msg := &bot.BotMessage{
+			Message: text,
+			User:    r.UserID,
+			Channel: r.ChannelID,
+			Name:    "mattermost",
+		}
+		go func() {
+			select {
+			case b.botMessagesChannel <- msg:
+			case <-b.ctx.Done():
+			}
+		}()
+		return nil, nil
+	}
+
+	log.WithFields(log.Fields{
+		"user":    r.UserName,
+		"command": text,
+	}).Debug("bot.mattermost.handleCommand: bot couldn't recognize command")
+	return nil, nil
+}
+
+// sendApproval - bot manager channels are unbuffered, Mattermost expects a response
+// before the vote is processed
+func (b *Bot) sendApproval(approval *bot.ApprovalResponse) {
+	go func() {
+		select {
+		case b.approvalsRespCh <- approval:
+		case <-b.ctx.Done():
+		}
+	}()
+}
+
+// trimBot - lowercase command without trigger word and bot mention, same as Slack commands
+func (b *Bot) trimBot(text, triggerWord string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, prefix := range []string{strings.ToLower(triggerWord), "@" + b.name, b.name} {
+		if prefix != "" && strings.HasPrefix(text, prefix) {
+			text = strings.TrimPrefix(text, prefix)
+			break
+		}
+	}
+	return strings.Trim(text, " :\n")
+}
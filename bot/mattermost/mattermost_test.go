@@ -0,0 +1,194 @@
+package mattermost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+type apiCall struct {
+	method string
+	path   string
+	post   post
+}
+
+type fakeServer struct {
+	mu    sync.Mutex
+	calls []apiCall
+}
+
+func (f *fakeServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer bot-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var p post
+	json.NewDecoder(r.Body).Decode(&p)
+	f.mu.Lock()
+	f.calls = append(f.calls, apiCall{method: r.Method, path: r.URL.Path, post: p})
+	f.mu.Unlock()
+	w.Write([]byte(`{"id": "post1"}`))
+}
+
+func newTestBot(t *testing.T) (*Bot, *fakeServer, func()) {
+	f := &fakeServer{}
+	srv := httptest.NewServer(http.HandlerFunc(f.handler))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Bot{
+		name:               "keel",
+		approvalsChannel:   "approvals-id",
+		webhookToken:       "webhook-token",
+		callbackURL:        "https://keel.example.com/v1/bots/mattermost/interactions",
+		api:                &client{url: srv.URL, token: "bot-token", client: http.DefaultClient},
+		posts:              make(map[string]string),
+		users:              make(map[string]string),
+		approvalsRespCh:    make(chan *bot.ApprovalResponse, 1),
+		botMessagesChannel: make(chan *bot.BotMessage, 1),
+	}
+	b.Start(ctx)
+	return b, f, func() {
+		cancel()
+		srv.Close()
+	}
+}
+
+func serve(b *Bot, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	b.InteractionHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestButtonApproval(t *testing.T) {
+	b, f, teardown := newTestBot(t)
+	defer teardown()
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		Message:        "New image is available for deployment default/app",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+	}
+	if err := b.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(f.calls) != 1 || f.calls[0].path != "/api/v4/posts" || f.calls[0].post.ChannelID != "approvals-id" {
+		t.Fatalf("expected approval request post, got %+v", f.calls)
+	}
+	actions := f.calls[0].post.Props.Attachments[0].Actions
+	if len(actions) != 2 || actions[0].Integration.URL != b.callbackURL {
+		t.Fatalf("expected Approve and Reject buttons, got %+v", actions)
+	}
+
+	click := func(ctx *buttonContext) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"user_id":   "u1",
+			"user_name": "jane",
+			"post_id":   "post1",
+			"context":   ctx,
+		})
+		req := httptest.NewRequest("POST", "/v1/bots/mattermost/interactions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return serve(b, req)
+	}
+
+	tampered := *actions[0].Integration.Context
+	tampered.Identifier = "default/other:1.1.0"
+	if rec := click(&tampered); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected tampered button to be rejected, got %d", rec.Code)
+	}
+
+	if rec := click(actions[0].Integration.Context); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+	select {
+	case resp := <-b.approvalsRespCh:
+		if resp.User != "mattermost:u1" || resp.Status != types.ApprovalStatusApproved || resp.Text != "approve default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	// bot manager approves and replies
+	approval.AddVoter("u1")
+	approval.VotesReceived = 1
+	if err := b.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	patch := f.calls[len(f.calls)-1]
+	if patch.method != "PUT" || patch.path != "/api/v4/posts/post1/patch" {
+		t.Fatalf("expected approval request to be updated, got %s %s", patch.method, patch.path)
+	}
+	a := patch.post.Props.Attachments[0]
+	if len(a.Actions) != 0 {
+		t.Errorf("expected buttons to be removed once approved")
+	}
+	if voters := a.Fields[len(a.Fields)-1]; voters.Title != "Voted by" || voters.Value != "@jane" {
+		t.Errorf("unexpected voters field: %+v", voters)
+	}
+}
+
+func TestOutgoingWebhookCommands(t *testing.T) {
+	b, _, teardown := newTestBot(t)
+	defer teardown()
+
+	command := func(token, channel, text string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"token":        {token},
+			"channel_id":   {channel},
+			"user_id":      {"u2"},
+			"user_name":    {"john"},
+			"text":         {text},
+			"trigger_word": {"@keel"},
+		}
+		req := httptest.NewRequest("POST", "/v1/bots/mattermost/interactions", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(b, req)
+	}
+
+	if rec := command("wrong", "approvals-id", "@keel approve default/app:1.1.0"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid token to be rejected, got %d", rec.Code)
+	}
+
+	rec := command("webhook-token", "town-square", "@keel approve default/app:1.1.0")
+	if !strings.Contains(rec.Body.String(), "please use approvals channel") {
+		t.Errorf("expected approval outside of approvals channel to be refused: %s", rec.Body.String())
+	}
+
+	command("webhook-token", "approvals-id", "@keel Reject default/app:1.1.0")
+	select {
+	case resp := <-b.approvalsRespCh:
+		if resp.User != "mattermost:u2" || resp.Status != types.ApprovalStatusRejected || resp.Text != "reject default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	command("webhook-token", "town-square", "@keel get approvals")
+	select {
+	case msg := <-b.botMessagesChannel:
+		if msg.Message != "get approvals" || msg.Channel != "town-square" || msg.Name != "mattermost" {
+			t.Errorf("unexpected bot message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected bot message")
+	}
+
+	rec = command("webhook-token", "town-square", "@keel help")
+	if !strings.Contains(rec.Body.String(), "get approvals") {
+		t.Errorf("expected help response: %s", rec.Body.String())
+	}
+}
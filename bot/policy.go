@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// ExplainPolicyHandler - evaluates policy for "<image>:<current tag> <new tag> <policy>"
+// and describes the decision
+func ExplainPolicyHandler(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		return fmt.Sprintf("usage: %s <image>:<current tag> <new tag> <policy>", ExplainPolicyPrefix)
+	}
+
+	ref, err := image.Parse(fields[0])
+	if err != nil {
+		return fmt.Sprintf("invalid image '%s': %s", fields[0], err)
+	}
+	newTag := fields[1]
+	policyName := strings.Join(fields[2:], " ")
+
+	p := policy.GetPolicy(policyName, &policy.Options{MatchPreRelease: true})
+	explanation := policy.Explain(p, &policy.UpdateContext{Image: ref.Name()}, ref.Tag(), newTag)
+	return fmt.Sprintf("%s %s -> %s:\n%s", ref.Name(), ref.Tag(), newTag, explanation)
+}
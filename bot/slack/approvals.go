@@ -7,8 +7,12 @@ import (
 	"github.com/nlopes/slack"
 )
 
-// Request - request approval
+// Request - request approval, with Approve/Reject buttons when interactivity is configured
 func (b *Bot) RequestApproval(req *types.Approval) error {
+	if b.signingSecret != "" {
+		return b.requestInteractiveApproval(req)
+	}
+
 	return b.postMessage(
 		"Approval required",
 		req.Message,
@@ -42,7 +46,13 @@ func (b *Bot) RequestApproval(req *types.Approval) error {
 		})
 }
 
+// ReplyToApproval - updates the approval request message when it was posted with buttons,
+// otherwise replies with a new message
 func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	if updated, err := b.updateApprovalMessage(approval); updated {
+		return err
+	}
+
 	switch approval.Status() {
 	case types.ApprovalStatusPending:
 		b.postMessage(
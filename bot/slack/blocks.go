@@ -0,0 +1,202 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+)
+
+// approval buttons action IDs, button value is the approval identifier
+const (
+	actionApprove = "keel_approve"
+	actionReject  = "keel_reject"
+)
+
+// Block Kit (https://api.slack.com/block-kit) message elements, vendored Slack client
+// predates blocks
+type block struct {
+	Type     string        `json:"type"`
+	BlockID  string        `json:"block_id,omitempty"`
+	Text     *textObject   `json:"text,omitempty"`
+	Fields   []*textObject `json:"fields,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"`
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type buttonElement struct {
+	Type     string      `json:"type"`
+	Text     *textObject `json:"text"`
+	ActionID string      `json:"action_id"`
+	Value    string      `json:"value"`
+	Style    string      `json:"style,omitempty"`
+}
+
+func markdown(text string) *textObject {
+	return &textObject{Type: "mrkdwn", Text: text}
+}
+
+func plainText(text string) *textObject {
+	return &textObject{Type: "plain_text", Text: text}
+}
+
+// approvalBlocks - approval details with the outcome (or current votes), Approve/Reject
+// buttons are added while the approval is pending
+func approvalBlocks(approval *types.Approval, outcome string) []block {
+	blocks := []block{
+		{
+			Type: "section",
+			Text: markdown(fmt.Sprintf("*Approval required*\n%s", approval.Message)),
+		},
+		{
+			Type: "section",
+			Fields: []*textObject{
+				markdown(fmt.Sprintf("*Identifier*\n%s", approval.Identifier)),
+				markdown(fmt.Sprintf("*Delta*\n%s", approval.Delta())),
+				markdown(fmt.Sprintf("*Votes*\n%d/%d", approval.VotesReceived, approval.VotesRequired)),
+				markdown(fmt.Sprintf("*Provider*\n%s", approval.Provider.String())),
+			},
+		},
+	}
+
+	if outcome != "" {
+		blocks = append(blocks, block{Type: "section", Text: markdown(outcome)})
+	}
+
+	if approval.Status() == types.ApprovalStatusPending {
+		blocks = append(blocks, block{
+			Type:    "actions",
+			BlockID: "keel_approval",
+			Elements: []interface{}{
+				&buttonElement{Type: "button", Text: plainText("Approve"), ActionID: actionApprove, Value: approval.Identifier, Style: "primary"},
+				&buttonElement{Type: "button", Text: plainText("Reject"), ActionID: actionReject, Value: approval.Identifier, Style: "danger"},
+			},
+		})
+	}
+
+	return append(blocks, block{
+		Type:     "context",
+		Elements: []interface{}{markdown(fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version))},
+	})
+}
+
+// slackUserID - Slack user and workspace user IDs, other voters (for example REST API
+// users) are displayed as they are
+var slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+func mention(user string) string {
+	if slackUserID.MatchString(user) {
+		return "<@" + user + ">"
+	}
+	return user
+}
+
+// approvalOutcome - who voted on the approval, rejectedBy is empty if unknown
+func approvalOutcome(approval *types.Approval, rejectedBy string) string {
+	voters := approval.GetVoters()
+	sort.Strings(voters)
+	for i := range voters {
+		voters[i] = mention(voters[i])
+	}
+
+	switch approval.Status() {
+	case types.ApprovalStatusRejected:
+		if rejectedBy != "" {
+			return fmt.Sprintf(":no_entry: Rejected by %s", mention(rejectedBy))
+		}
+		return ":no_entry: Rejected"
+	case types.ApprovalStatusApproved:
+		return fmt.Sprintf(":white_check_mark: Approved by %s", strings.Join(voters, ", "))
+	}
+	if len(voters) > 0 {
+		return fmt.Sprintf("Approved by %s, waiting for remaining votes", strings.Join(voters, ", "))
+	}
+	return ""
+}
+
+// webAPI - Slack Web API client posting and updating Block Kit messages
+type webAPI struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newWebAPI(token string) *webAPI {
+	return &webAPI{
+		url:    slack.APIURL,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type chatRequest struct {
+	Channel  string  `json:"channel"`
+	TS       string  `json:"ts,omitempty"`
+	Text     string  `json:"text"`
+	Blocks   []block `json:"blocks"`
+	Username string  `json:"username,omitempty"`
+}
+
+type chatResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// postBlocks - posts message, returns channel ID and message timestamp
+func (a *webAPI) postBlocks(channel, username, text string, blocks []block) (string, string, error) {
+	return a.call("chat.postMessage", &chatRequest{Channel: channel, Username: username, Text: text, Blocks: blocks})
+}
+
+// updateBlocks - replaces message posted to channel (ID) at ts
+func (a *webAPI) updateBlocks(channel, ts, text string, blocks []block) error {
+	_, _, err := a.call("chat.update", &chatRequest{Channel: channel, TS: ts, Text: text, Blocks: blocks})
+	return err
+}
+
+func (a *webAPI) call(method string, payload *chatRequest) (string, string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.url+method, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s: unexpected status code %d", method, resp.StatusCode)
+	}
+
+	var cr chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+		return "", "", fmt.Errorf("%s: failed to decode response: %s", method, err)
+	}
+	if !cr.OK {
+		return "", "", fmt.Errorf("%s: %s", method, cr.Error)
+	}
+	return cr.Channel, cr.TS, nil
+}
@@ -0,0 +1,186 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/nlopes/slack"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// approvalMessage - posted approval request, updated with the outcome
+type approvalMessage struct {
+	channel string // channel ID
+	ts      string
+	// rejectedBy - user that clicked Reject
+	rejectedBy string
+}
+
+// blockActions - block_actions interaction payload, see
+// https://api.slack.com/reference/interaction-payloads/block-actions
+type blockActions struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Container struct {
+		MessageTS string `json:"message_ts"`
+		ChannelID string `json:"channel_id"`
+	} `json:"container"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// InteractionHandler - handles Approve/Reject button clicks, nil when signing secret
+// is not configured
+func (b *Bot) InteractionHandler() http.Handler {
+	if b.signingSecret == "" || b.approvalsRespCh == nil || b.ctx == nil {
+		return nil
+	}
+	return http.HandlerFunc(b.interactionHandler)
+}
+
+func (b *Bot) interactionHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sv, err := slack.NewSecretsVerifier(req.Header, b.signingSecret)
+	if err == nil {
+		sv.Write(body)
+		err = sv.Ensure()
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("bot.slack.interactionHandler: request signature verification failed")
+		http.Error(resp, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload blockActions
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		http.Error(resp, fmt.Sprintf("failed to decode payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if payload.Type != "block_actions" {
+		resp.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for _, action := range payload.Actions {
+		var status types.ApprovalStatus
+		var keyword string
+		switch action.ActionID {
+		case actionApprove:
+			status, keyword = types.ApprovalStatusApproved, bot.ApprovalResponseKeyword
+		case actionReject:
+			status, keyword = types.ApprovalStatusRejected, bot.RejectResponseKeyword
+		default:
+			continue
+		}
+
+		msg := &approvalMessage{channel: payload.Container.ChannelID, ts: payload.Container.MessageTS}
+		if status == types.ApprovalStatusRejected {
+			msg.rejectedBy = payload.User.ID
+		}
+		b.setApprovalMessage(action.Value, msg)
+
+		log.WithFields(log.Fields{
+			"identifier": action.Value,
+			"user":       payload.User.Username,
+			"action":     keyword,
+		}).Info("bot.slack.interactionHandler: approval button clicked")
+
+		// Slack expects a response within 3 seconds, votes are processed asynchronously
+		go func(r *bot.ApprovalResponse) {
+			select {
+			case b.approvalsRespCh <- r:
+			case <-b.ctx.Done():
+			}
+		}(&bot.ApprovalResponse{
+			User:   bot.VoterID("slack", payload.User.ID),
+			Status: status,
+			Text:   keyword + " " + action.Value,
+		})
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+func (b *Bot) setApprovalMessage(identifier string, msg *approvalMessage) {
+	b.messagesMu.Lock()
+	defer b.messagesMu.Unlock()
+	if b.messages == nil {
+		b.messages = make(map[string]*approvalMessage)
+	}
+	b.messages[identifier] = msg
+}
+
+func (b *Bot) approvalMessage(identifier string) *approvalMessage {
+	b.messagesMu.Lock()
+	defer b.messagesMu.Unlock()
+	return b.messages[identifier]
+}
+
+func (b *Bot) removeApprovalMessage(identifier string) {
+	b.messagesMu.Lock()
+	defer b.messagesMu.Unlock()
+	delete(b.messages, identifier)
+}
+
+// requestInteractiveApproval - posts approval request with Approve/Reject buttons
+func (b *Bot) requestInteractiveApproval(req *types.Approval) error {
+	channel, ts, err := b.api.postBlocks(b.approvalsChannel, b.name, "Approval required: "+req.Message, approvalBlocks(req, ""))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":             err,
+			"approvals_channel": b.approvalsChannel,
+		}).Error("bot.slack.requestInteractiveApproval: failed to send message")
+		return err
+	}
+	b.setApprovalMessage(req.Identifier, &approvalMessage{channel: channel, ts: ts})
+	return nil
+}
+
+// updateApprovalMessage - replaces approval request message with current votes or the
+// outcome, returns false if the request message isn't known
+func (b *Bot) updateApprovalMessage(approval *types.Approval) (bool, error) {
+	msg := b.approvalMessage(approval.Identifier)
+	if msg == nil {
+		return false, nil
+	}
+
+	outcome := approvalOutcome(approval, msg.rejectedBy)
+	if approval.Status() != types.ApprovalStatusPending {
+		b.removeApprovalMessage(approval.Identifier)
+	}
+
+	err := b.api.updateBlocks(msg.channel, msg.ts, fmt.Sprintf("Approval %s: %s", approval.Status(), approval.Message), approvalBlocks(approval, outcome))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": approval.Identifier,
+		}).Error("bot.slack.updateApprovalMessage: failed to update message")
+	}
+	return true, err
+}
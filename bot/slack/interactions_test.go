@@ -0,0 +1,174 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	b "github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeSlackAPI struct {
+	mu       sync.Mutex
+	requests map[string][]chatRequest
+}
+
+func newFakeSlackAPI() (*fakeSlackAPI, *httptest.Server) {
+	f := &fakeSlackAPI{requests: make(map[string][]chatRequest)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-token" {
+			w.Write([]byte(`{"ok": false, "error": "not_authed"}`))
+			return
+		}
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.requests[strings.TrimPrefix(r.URL.Path, "/")] = append(f.requests[strings.TrimPrefix(r.URL.Path, "/")], req)
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1500000000.000100"}`))
+	}))
+	return f, srv
+}
+
+func (f *fakeSlackAPI) get(method string) []chatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[method]
+}
+
+func hasButtons(blocks []block) bool {
+	for _, bl := range blocks {
+		if bl.Type == "actions" {
+			return true
+		}
+	}
+	return false
+}
+
+func signedInteraction(t *testing.T, secret, payload string) *http.Request {
+	body := url.Values{"payload": {payload}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+
+	req := httptest.NewRequest("POST", "/v1/bots/slack/interactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestInteractiveApproval(t *testing.T) {
+	fake, srv := newFakeSlackAPI()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	respCh := make(chan *b.ApprovalResponse, 1)
+	bot := &Bot{
+		name:             "keel",
+		approvalsChannel: "approvals",
+		signingSecret:    "secret",
+		api:              &webAPI{url: srv.URL + "/", token: "xoxb-token", client: http.DefaultClient},
+		approvalsRespCh:  respCh,
+		ctx:              ctx,
+	}
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		Message:        "New image is available for deployment default/app",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+		Provider:       types.ProviderTypeKubernetes,
+	}
+	if err := bot.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	posted := fake.get("chat.postMessage")
+	if len(posted) != 1 || posted[0].Channel != "approvals" || !hasButtons(posted[0].Blocks) {
+		t.Fatalf("expected approval request with buttons, got %+v", posted)
+	}
+
+	handler := bot.InteractionHandler()
+	if handler == nil {
+		t.Fatalf("expected interaction handler")
+	}
+
+	payload := `{"type": "block_actions", "user": {"id": "U1234", "username": "jane"},
+		"container": {"message_ts": "1500000000.000100", "channel_id": "C123"},
+		"actions": [{"action_id": "keel_reject", "value": "default/app:1.1.0"}]}`
+
+	// signed with a different secret
+	req := signedInteraction(t, "wrong", payload)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected request with invalid signature to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedInteraction(t, "secret", payload))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.User != "slack:U1234" || resp.Status != types.ApprovalStatusRejected || resp.Text != "reject default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	// bot manager rejects the approval and replies
+	approval.Rejected = true
+	if err := bot.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	updated := fake.get("chat.update")
+	if len(updated) != 1 || updated[0].Channel != "C123" || updated[0].TS != "1500000000.000100" {
+		t.Fatalf("expected original message to be updated, got %+v", updated)
+	}
+	if hasButtons(updated[0].Blocks) {
+		t.Errorf("expected buttons to be removed once approval is rejected")
+	}
+	var rejectedBy bool
+	for _, bl := range updated[0].Blocks {
+		if bl.Text != nil && strings.Contains(bl.Text.Text, "Rejected by <@U1234>") {
+			rejectedBy = true
+		}
+	}
+	if !rejectedBy {
+		t.Errorf("expected message to show who rejected the change")
+	}
+}
+
+func TestApprovalOutcome(t *testing.T) {
+	approval := &types.Approval{VotesRequired: 2}
+	approval.AddVoter("U2")
+	approval.VotesReceived = 1
+	if got := approvalOutcome(approval, ""); got != "Approved by <@U2>, waiting for remaining votes" {
+		t.Errorf("unexpected pending outcome: %s", got)
+	}
+
+	approval.AddVoter("admin")
+	approval.VotesReceived = 2
+	if got := approvalOutcome(approval, ""); got != ":white_check_mark: Approved by <@U2>, admin" {
+		t.Errorf("unexpected approved outcome: %s", got)
+	}
+}
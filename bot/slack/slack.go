@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nlopes/slack"
@@ -41,6 +42,13 @@ type Bot struct {
 
 	approvalsChannel string // slack approvals channel name
 
+	// signingSecret - enables approvals with interactive buttons
+	signingSecret string
+	api           *webAPI
+
+	messagesMu sync.Mutex
+	messages   map[string]*approvalMessage // approval request messages by approval identifier
+
 	ctx                context.Context
 	botMessagesChannel chan *bot.BotMessage
 	approvalsRespCh    chan *bot.ApprovalResponse
@@ -68,6 +76,8 @@ func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesC
 
 		b.slackClient = client
 		b.slackHTTPClient = client
+		b.signingSecret = os.Getenv(constants.EnvSlackSigningSecret)
+		b.api = newWebAPI(token)
 		b.approvalsRespCh = approvalsRespCh
 		b.botMessagesChannel = botMessagesChannel
 
@@ -220,7 +230,7 @@ func (b *Bot) handleMessage(event *slack.MessageEvent) {
 
 	eventText = b.trimBot(eventText)
 
-	approval, ok := bot.IsApproval(event.User, eventText)
+	approval, ok := bot.IsApproval("slack", event.User, eventText)
 	// only accepting approvals from approvals channel
 	if ok && b.isApprovalsChannel(event) {
 		b.approvalsRespCh <- approval
@@ -38,7 +38,7 @@ func New(name, token, channel string,
 
 	slack := &Bot{}
 	b.RegisterBot(name, slack)
-	b.Run(k8sImplementer, approvalsManager)
+	b.Run(k8sImplementer, approvalsManager, nil)
 	slack.slackHTTPClient = fi
 	return slack
 }
@@ -63,6 +63,11 @@ func (p *fakeProvider) List() []string {
 func (p *fakeProvider) Stop() {
 	return
 }
+func (p *fakeProvider) Pause()  {}
+func (p *fakeProvider) Resume() {}
+func (p *fakeProvider) Paused() bool {
+	return false
+}
 func (p *fakeProvider) GetName() string {
 	return "fp"
 }
@@ -357,7 +362,7 @@ func TestIsApproval(t *testing.T) {
 			User:    "user-x",
 		},
 	}
-	_, isApproval := b.IsApproval(event.User, "approve k8s/project/repo:1.2.3")
+	_, isApproval := b.IsApproval("slack", event.User, "approve k8s/project/repo:1.2.3")
 
 	if !isApproval {
 		t.Errorf("event expected to be an approval")
@@ -0,0 +1,125 @@
+package teams
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+)
+
+// message - Teams message with Adaptive Card attachments, see
+// https://docs.microsoft.com/en-us/microsoftteams/platform/task-modules-and-cards/cards/cards-reference#adaptive-card
+type message struct {
+	Type        string       `json:"type"`
+	Text        string       `json:"text,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
+}
+
+type attachment struct {
+	ContentType string        `json:"contentType"`
+	Content     *adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string    `json:"$schema"`
+	Type    string    `json:"type"`
+	Version string    `json:"version"`
+	Body    []element `json:"body"`
+	Actions []action  `json:"actions,omitempty"`
+}
+
+type element struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Weight   string `json:"weight,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Wrap     bool   `json:"wrap,omitempty"`
+	Facts    []fact `json:"facts,omitempty"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type action struct {
+	Type  string      `json:"type"`
+	Title string      `json:"title"`
+	Style string      `json:"style,omitempty"`
+	Data  *submitData `json:"data,omitempty"`
+}
+
+// submitData - Action.Submit data, sent back with the activity of the clicking user
+type submitData struct {
+	Keel       string `json:"keel"`
+	Identifier string `json:"identifier"`
+}
+
+func cardMessage(card *adaptiveCard) *message {
+	return &message{
+		Type: "message",
+		Attachments: []attachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+}
+
+func newCard(title, color string, body ...element) *adaptiveCard {
+	return &adaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.2",
+		Body: append(append([]element{
+			{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+		}, body...), element{
+			Type: "TextBlock", Text: fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version), IsSubtle: true, Size: "Small",
+		}),
+	}
+}
+
+func approvalFacts(approval *types.Approval) element {
+	return element{
+		Type: "FactSet",
+		Facts: []fact{
+			{Title: "Identifier", Value: approval.Identifier},
+			{Title: "Delta", Value: approval.Delta()},
+			{Title: "Votes", Value: strconv.Itoa(approval.VotesReceived) + "/" + strconv.Itoa(approval.VotesRequired)},
+			{Title: "Provider", Value: approval.Provider.String()},
+		},
+	}
+}
+
+// approvalRequestCard - approval request with Approve/Reject actions, the equivalent
+// command is shown for conversations where card actions aren't delivered to Keel
+func approvalRequestCard(botName string, approval *types.Approval) *adaptiveCard {
+	card := newCard("Approval required", "Accent",
+		element{Type: "TextBlock", Text: approval.Message, Wrap: true},
+		approvalFacts(approval),
+		element{
+			Type: "TextBlock",
+			Text: fmt.Sprintf("To vote reply '@%s approve %s', to reject it '@%s reject %s'.", botName, approval.Identifier, botName, approval.Identifier),
+			Wrap: true, IsSubtle: true,
+		},
+	)
+	card.Actions = []action{
+		{Type: "Action.Submit", Title: "Approve", Style: "positive", Data: &submitData{Keel: "approve", Identifier: approval.Identifier}},
+		{Type: "Action.Submit", Title: "Reject", Style: "destructive", Data: &submitData{Keel: "reject", Identifier: approval.Identifier}},
+	}
+	return card
+}
+
+// approvalReplyCard - votes or outcome of the approval, voters are displayed by name
+func approvalReplyCard(approval *types.Approval, voters []string) *adaptiveCard {
+	votedBy := element{Type: "TextBlock", Text: "Voted by " + strings.Join(voters, ", "), Wrap: true}
+	switch approval.Status() {
+	case types.ApprovalStatusRejected:
+		return newCard("Change rejected", "Attention", element{Type: "TextBlock", Text: "Change was rejected.", Wrap: true}, approvalFacts(approval))
+	case types.ApprovalStatusApproved:
+		return newCard("Update approved", "Good", element{Type: "TextBlock", Text: "All approvals received, thanks for voting!", Wrap: true}, votedBy, approvalFacts(approval))
+	}
+	return newCard("Vote received", "Accent", element{Type: "TextBlock", Text: "Waiting for remaining votes.", Wrap: true}, votedBy, approvalFacts(approval))
+}
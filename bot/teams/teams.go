@@ -0,0 +1,271 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Bot - Microsoft Teams approvals bot, approval requests are posted as Adaptive Cards
+// to an incoming webhook and responses are received from an outgoing webhook
+// (https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/add-outgoing-webhook)
+// at /v1/bots/teams/interactions
+type Bot struct {
+	name       string
+	webhookURL string
+	// secret - outgoing webhook security token
+	secret []byte
+
+	client *http.Client
+
+	namesMu sync.Mutex
+	names   map[string]string // user display names by ID
+
+	ctx                context.Context
+	botMessagesChannel chan *bot.BotMessage
+	approvalsRespCh    chan *bot.ApprovalResponse
+}
+
+func init() {
+	if isTeamsConfigured() {
+		bot.RegisterBot("teams", &Bot{})
+	}
+}
+
+func isTeamsConfigured() bool {
+	return os.Getenv(constants.EnvTeamsWebhookURL) != "" && os.Getenv(constants.EnvTeamsOutgoingWebhookSecret) != ""
+}
+
+func (b *Bot) Configure(approvalsRespCh chan *bot.ApprovalResponse, botMessagesChannel chan *bot.BotMessage) bool {
+	if !isTeamsConfigured() {
+		log.Info("bot.teams.Configure(): Teams approval bot is not configured")
+		return false
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(os.Getenv(constants.EnvTeamsOutgoingWebhookSecret))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Errorf("bot.teams.Configure(): %s should be the base64 security token of the outgoing webhook", constants.EnvTeamsOutgoingWebhookSecret)
+		return false
+	}
+
+	b.name = "keel"
+	if name := os.Getenv(constants.EnvTeamsBotName); name != "" {
+		b.name = name
+	}
+	b.webhookURL = os.Getenv(constants.EnvTeamsWebhookURL)
+	b.secret = secret
+	b.client = &http.Client{Timeout: 10 * time.Second}
+	b.approvalsRespCh = approvalsRespCh
+	b.botMessagesChannel = botMessagesChannel
+
+	return true
+}
+
+// Start - responses are received over HTTP, nothing to connect to
+func (b *Bot) Start(ctx context.Context) error {
+	b.ctx = ctx
+	return nil
+}
+
+// Respond - posts bot command response to the channel of the incoming webhook
+func (b *Bot) Respond(text string, channel string) {
+	err := b.post(&message{Type: "message", Text: "```\n" + text + "\n```"})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("bot.teams.Respond: failed to send message")
+	}
+}
+
+// RequestApproval - posts approval request card
+func (b *Bot) RequestApproval(req *types.Approval) error {
+	return b.post(cardMessage(approvalRequestCard(b.name, req)))
+}
+
+// ReplyToApproval - posts approval votes or outcome card
+func (b *Bot) ReplyToApproval(approval *types.Approval) error {
+	voters := approval.GetVoters()
+	for i := range voters {
+		voters[i] = b.displayName(voters[i])
+	}
+	sort.Strings(voters)
+	return b.post(cardMessage(approvalReplyCard(approval, voters)))
+}
+
+func (b *Bot) post(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Post(b.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("incoming webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// activity - outgoing webhook message, card actions carry submitted data in value
+type activity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	From struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		AADObjectID string `json:"aadObjectId"`
+	} `json:"from"`
+	Conversation struct {
+		ID string `json:"id"`
+	} `json:"conversation"`
+	Value *submitData `json:"value"`
+}
+
+// InteractionHandler - outgoing webhook handler
+func (b *Bot) InteractionHandler() http.Handler {
+	if b.ctx == nil {
+		return nil
+	}
+	return http.HandlerFunc(b.interactionHandler)
+}
+
+func (b *Bot) interactionHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !b.verify(req.Header.Get("Authorization"), body) {
+		log.Warn("bot.teams.interactionHandler: request signature verification failed")
+		http.Error(resp, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var a activity
+	if err := json.Unmarshal(body, &a); err != nil {
+		http.Error(resp, fmt.Sprintf("failed to decode activity: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	user := a.From.AADObjectID
+	if user == "" {
+		user = a.From.ID
+	}
+	b.setDisplayName(user, a.From.Name)
+
+	text := commandText(a.Text)
+	if a.Value != nil && a.Value.Keel != "" {
+		text = a.Value.Keel + " " + a.Value.Identifier
+	}
+
+	var reply string
+	if approval, ok := bot.IsApproval("teams", user, text); ok {
+		log.WithFields(log.Fields{
+			"user":    a.From.Name,
+			"command": text,
+		}).Info("bot.teams.interactionHandler: approval response received")
+		// outgoing webhooks have to respond within 5 seconds, bot manager channels are unbuffered
+		go func() {
+			select {
+			case b.approvalsRespCh <- approval:
+			case <-b.ctx.Done():
+			}
+		}()
+		reply = fmt.Sprintf("Thanks %s, processing your vote.", a.From.Name)
+	} else if bot.IsBotCommand(text) {
+		msg := &bot.BotMessage{
+			Message: text,
+			User:    user,
+			Channel: a.Conversation.ID,
+			Name:    "teams",
+		}
+		go func() {
+			select {
+			case b.botMessagesChannel <- msg:
+			case <-b.ctx.Done():
+			}
+		}()
+		reply = "Working on it."
+	} else {
+		reply = fmt.Sprintf("Unknown command '%s', type '@%s help' for a list of commands.", text, b.name)
+		if responseLines, ok := bot.BotEventTextToResponse[text]; ok {
+			reply = strings.Join(responseLines, "\n\n")
+		}
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(&message{Type: "message", Text: reply})
+}
+
+// verify - outgoing webhook HMAC signature, see
+// https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/add-outgoing-webhook#2-create-a-method-to-verify-the-outgoing-webhook-hmac-token
+func (b *Bot) verify(authorization string, body []byte) bool {
+	if !strings.HasPrefix(authorization, "HMAC ") {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authorization, "HMAC "))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+var (
+	mentionTag = regexp.MustCompile(`(?s)<at>.*?</at>`)
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// commandText - lowercase message text without bot mention and HTML formatting, same as
+// Slack messages
+func commandText(text string) string {
+	text = mentionTag.ReplaceAllString(text, " ")
+	text = htmlTag.ReplaceAllString(text, " ")
+	return strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(text)), " "))
+}
+
+func (b *Bot) setDisplayName(user, name string) {
+	if user == "" || name == "" {
+		return
+	}
+	b.namesMu.Lock()
+	defer b.namesMu.Unlock()
+	if b.names == nil {
+		b.names = make(map[string]string)
+	}
+	b.names[user] = name
+}
+
+func (b *Bot) displayName(user string) string {
+	b.namesMu.Lock()
+	defer b.namesMu.Unlock()
+	if name, ok := b.names[user]; ok {
+		return name
+	}
+	return user
+}
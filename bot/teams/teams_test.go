@@ -0,0 +1,151 @@
+package teams
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/bot"
+	"github.com/keel-hq/keel/types"
+)
+
+func newTestBot(t *testing.T, webhookURL string) (*Bot, chan *bot.ApprovalResponse, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	respCh := make(chan *bot.ApprovalResponse, 1)
+	b := &Bot{
+		name:               "keel",
+		webhookURL:         webhookURL,
+		secret:             []byte("outgoing webhook secret"),
+		client:             http.DefaultClient,
+		approvalsRespCh:    respCh,
+		botMessagesChannel: make(chan *bot.BotMessage, 1),
+	}
+	b.Start(ctx)
+	return b, respCh, cancel
+}
+
+func signedActivity(secret []byte, body string) *http.Request {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	req := httptest.NewRequest("POST", "/v1/bots/teams/interactions", strings.NewReader(body))
+	req.Header.Set("Authorization", "HMAC "+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestOutgoingWebhookApproval(t *testing.T) {
+	b, respCh, cancel := newTestBot(t, "")
+	defer cancel()
+
+	handler := b.InteractionHandler()
+
+	body := `{"type": "message", "text": "<at>Keel</at>&nbsp;Approve default/app:1.1.0\n",
+		"from": {"id": "29:1abc", "name": "Jane Doe", "aadObjectId": "6a0c1c3e"}}`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedActivity([]byte("wrong"), body))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected request with invalid signature to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedActivity(b.secret, body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+	var reply message
+	json.Unmarshal(rec.Body.Bytes(), &reply)
+	if !strings.Contains(reply.Text, "Jane Doe") {
+		t.Errorf("unexpected reply: %s", reply.Text)
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.User != "teams:6a0c1c3e" || resp.Status != types.ApprovalStatusApproved || resp.Text != "approve default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+
+	// card action
+	body = `{"type": "message", "from": {"id": "29:2def", "name": "John Doe"},
+		"value": {"keel": "reject", "identifier": "default/app:1.1.0"}}`
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedActivity(b.secret, body))
+	select {
+	case resp := <-respCh:
+		if resp.User != "teams:29:2def" || resp.Status != types.ApprovalStatusRejected || resp.Text != "reject default/app:1.1.0" {
+			t.Errorf("unexpected approval response: %+v", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected approval response")
+	}
+}
+
+func TestApprovalCards(t *testing.T) {
+	var mu sync.Mutex
+	var posted []message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg message
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		posted = append(posted, msg)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	b, _, cancel := newTestBot(t, srv.URL)
+	defer cancel()
+	b.setDisplayName("6a0c1c3e", "Jane Doe")
+
+	approval := &types.Approval{
+		Identifier:     "default/app:1.1.0",
+		Message:        "New image is available for deployment default/app",
+		VotesRequired:  1,
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+	}
+	if err := b.RequestApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	approval.AddVoter("6a0c1c3e")
+	approval.VotesReceived = 1
+	if err := b.ReplyToApproval(approval); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(posted) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(posted))
+	}
+	request := posted[0].Attachments[0].Content
+	if len(request.Actions) != 2 || request.Actions[0].Data.Keel != "approve" || request.Actions[1].Data.Identifier != "default/app:1.1.0" {
+		t.Errorf("unexpected approval request actions: %+v", request.Actions)
+	}
+
+	reply := posted[1].Attachments[0].Content
+	if reply.Body[0].Text != "Update approved" || reply.Body[2].Text != "Voted by Jane Doe" {
+		t.Errorf("unexpected approval reply: %+v", reply.Body)
+	}
+}
+
+func TestCommandText(t *testing.T) {
+	tests := map[string]string{
+		"<at>Keel</at> get approvals":             "get approvals",
+		"<p><at>Keel</at>&nbsp;rm approval x</p>": "rm approval x",
+		"Reject  default/app:1.1.0\n":             "reject default/app:1.1.0",
+	}
+	for text, want := range tests {
+		if got := commandText(text); got != want {
+			t.Errorf("%q: expected %q, got %q", text, want, got)
+		}
+	}
+}
@@ -15,6 +15,52 @@ const (
 	EnvSlackBotName          = "SLACK_BOT_NAME"
 	EnvSlackChannels         = "SLACK_CHANNELS"
 	EnvSlackApprovalsChannel = "SLACK_APPROVALS_CHANNEL"
+	// Slack app signing secret, enables approvals with interactive buttons, see
+	// https://api.slack.com/authentication/verifying-requests-from-slack
+	EnvSlackSigningSecret = "SLACK_SIGNING_SECRET"
+
+	// Microsoft Teams incoming webhook approval requests are posted to, and outgoing
+	// webhook security token used to verify approval responses
+	EnvTeamsWebhookURL            = "TEAMS_WEBHOOK_URL"
+	EnvTeamsOutgoingWebhookSecret = "TEAMS_OUTGOING_WEBHOOK_SECRET"
+	EnvTeamsBotName               = "TEAMS_BOT_NAME"
+
+	// Microsoft Teams incoming webhook URLs (comma separated) notifications are posted to
+	EnvTeamsNotificationsWebhookURL = "TEAMS_NOTIFICATIONS_WEBHOOK_URL"
+
+	// Telegram bot token, notifications are sent to TELEGRAM_CHAT_IDS (comma separated chat
+	// IDs or @channel usernames), see https://core.telegram.org/bots#how-do-i-create-a-bot
+	EnvTelegramBotToken = "TELEGRAM_BOT_TOKEN"
+	EnvTelegramChatIDs  = "TELEGRAM_CHAT_IDS"
+
+	// PagerDuty Events API v2 integration routing key, error and fatal notifications trigger
+	// incidents that are resolved once the resource is updated successfully
+	EnvPagerDutyRoutingKey = "PAGERDUTY_ROUTING_KEY"
+
+	// Opsgenie API integration key, error and fatal notifications create alerts that are
+	// closed once the resource is updated successfully. OPSGENIE_API_URL is set for EU
+	// accounts (https://api.eu.opsgenie.com)
+	EnvOpsgenieAPIKey = "OPSGENIE_API_KEY"
+	EnvOpsgenieAPIURL = "OPSGENIE_API_URL"
+
+	// Amazon SNS topic notifications are published to as JSON events, region is taken from
+	// the topic ARN. Credentials come from the default chain or IAM roles for service accounts
+	EnvSNSTopicARN = "SNS_TOPIC_ARN"
+
+	// Matrix homeserver and access token of the user notifications are sent as, messages are
+	// sent to MATRIX_ROOMS (comma separated room IDs the user has joined, e.g. !abc:matrix.org)
+	EnvMatrixHomeserverURL = "MATRIX_HOMESERVER_URL"
+	EnvMatrixAccessToken   = "MATRIX_ACCESS_TOKEN"
+	EnvMatrixRooms         = "MATRIX_ROOMS"
+
+	// Rocket.Chat incoming webhook, see https://docs.rocket.chat/use-rocket.chat/workspace-administration/integrations
+	EnvRocketChatWebhookURL = "ROCKETCHAT_WEBHOOK_URL"
+	EnvRocketChatUsername   = "ROCKETCHAT_USERNAME"
+
+	// Outbound webhook receives every notification as versioned JSON event, payloads are
+	// signed with OUTBOUND_WEBHOOK_SECRET and the signature passed as X-Keel-Signature: sha256=<hex>
+	EnvOutboundWebhookURL    = "OUTBOUND_WEBHOOK_URL"
+	EnvOutboundWebhookSecret = "OUTBOUND_WEBHOOK_SECRET"
 
 	EnvHipchatToken    = "HIPCHAT_TOKEN"
 	EnvHipchatBotName  = "HIPCHAT_BOT_NAME"
@@ -31,6 +77,42 @@ const (
 	EnvMattermostEndpoint = "MATTERMOST_ENDPOINT"
 	EnvMattermostName     = "MATTERMOST_USERNAME"
 
+	// Mattermost approvals bot, approval requests are posted to the approvals channel (ID)
+	// with the bot account token. Commands are received from an outgoing webhook with
+	// MATTERMOST_WEBHOOK_TOKEN, buttons are added when Keel is reachable at MATTERMOST_CALLBACK_URL
+	EnvMattermostURL              = "MATTERMOST_URL"
+	EnvMattermostBotToken         = "MATTERMOST_BOT_TOKEN"
+	EnvMattermostBotName          = "MATTERMOST_BOT_NAME"
+	EnvMattermostApprovalsChannel = "MATTERMOST_APPROVALS_CHANNEL"
+	EnvMattermostWebhookToken     = "MATTERMOST_WEBHOOK_TOKEN"
+	EnvMattermostCallbackURL      = "MATTERMOST_CALLBACK_URL"
+
+	// Discord bot token, notifications are posted to DISCORD_CHANNELS (channel IDs) and
+	// approval requests to DISCORD_APPROVALS_CHANNEL. Votes are approve/reject emoji reactions
+	// from DISCORD_APPROVERS (user IDs, anyone in the channel when empty). The /keel slash
+	// command is enabled with the application public key, interactions endpoint has to be
+	// set to https://<keel>/v1/bots/discord/interactions
+	EnvDiscordBotToken         = "DISCORD_BOT_TOKEN"
+	EnvDiscordChannels         = "DISCORD_CHANNELS"
+	EnvDiscordApprovalsChannel = "DISCORD_APPROVALS_CHANNEL"
+	EnvDiscordApprovers        = "DISCORD_APPROVERS"
+	EnvDiscordApproveEmoji     = "DISCORD_APPROVE_EMOJI"
+	EnvDiscordRejectEmoji      = "DISCORD_REJECT_EMOJI"
+	EnvDiscordPublicKey        = "DISCORD_PUBLIC_KEY"
+
+	// GitHub approvals bot, approval requests are opened as issues (or pull requests when
+	// GITHUB_APPROVALS_MODE=pr) in GITHUB_APPROVALS_REPO (owner/name). Repository webhook
+	// (issue comments and pull requests) has to be sent to https://<keel>/v1/bots/github/interactions
+	// with GITHUB_APPROVALS_WEBHOOK_SECRET. "/approve" and "/reject" comments from GITHUB_APPROVERS
+	// (logins, repository collaborators when empty) are votes, merging the pull request approves
+	// the update and closing it rejects the update. GITHUB_API_URL is set for GitHub Enterprise
+	EnvGithubApprovalsRepo          = "GITHUB_APPROVALS_REPO"
+	EnvGithubApprovalsToken         = "GITHUB_APPROVALS_TOKEN"
+	EnvGithubApprovalsMode          = "GITHUB_APPROVALS_MODE"
+	EnvGithubApprovalsWebhookSecret = "GITHUB_APPROVALS_WEBHOOK_SECRET"
+	EnvGithubApprovers              = "GITHUB_APPROVERS"
+	EnvGithubAPIURL                 = "GITHUB_API_URL"
+
 	// Mail notification settings
 	EnvMailTo         = "MAIL_TO"
 	EnvMailFrom       = "MAIL_FROM"
@@ -38,16 +120,78 @@ const (
 	EnvMailSmtpPort   = "MAIL_SMTP_PORT"
 	EnvMailSmtpUser   = "MAIL_SMTP_USER"
 	EnvMailSmtpPass   = "MAIL_SMTP_PASS"
+
+	// MAIL_SMTP_TLS - "starttls" requires STARTTLS, "tls" connects over TLS (port 465),
+	// "none" disables TLS, STARTTLS is used when the server supports it by default
+	EnvMailSmtpTLS = "MAIL_SMTP_TLS"
+	// MAIL_TO_LEVELS - additional recipients of notifications with the level, ie:
+	// "error=oncall@example.com;success=releases@example.com,dev@example.com"
+	EnvMailToLevels = "MAIL_TO_LEVELS"
+	// Subject template and text, HTML body template file paths (Go templates), see
+	// extension/notification/mail/template.go for defaults
+	EnvMailSubjectTemplate = "MAIL_SUBJECT_TEMPLATE"
+	EnvMailTextTemplate    = "MAIL_TEXT_TEMPLATE"
+	EnvMailHTMLTemplate    = "MAIL_HTML_TEMPLATE"
 )
 
 // EnvNotificationLevel - minimum level for notifications, defaults to info
 const EnvNotificationLevel = "NOTIFICATION_LEVEL"
 
+// EnvNotificationTemplates - path to YAML file with Go templates of notification messages
+// by notification type and sender, see notification.LoadTemplates
+const EnvNotificationTemplates = "NOTIFICATION_TEMPLATES"
+
+// EnvNotificationFilters - path to YAML file with level and notification type filters of
+// senders, see notification.LoadFilters
+const EnvNotificationFilters = "NOTIFICATION_FILTERS"
+
+// EnvNotificationRoutes - path to YAML file with notification channels and webhooks by
+// resource namespace and labels, see notification.LoadRoutes
+const EnvNotificationRoutes = "NOTIFICATION_ROUTES"
+
+// EnvNotificationDeadLetterURL - optional endpoint notifications that couldn't be sent after
+// all retries are posted to as JSON, they are always logged
+const EnvNotificationDeadLetterURL = "NOTIFICATION_DEAD_LETTER_URL"
+
+// EnvNotificationDigestInterval - optional interval (ie: 15m) notifications are batched over
+// into a single summarized notification per sender and channel
+const EnvNotificationDigestInterval = "NOTIFICATION_DIGEST_INTERVAL"
+
 // Basic Auth - User / Password
 const EnvBasicAuthUser = "BASIC_AUTH_USER"
 const EnvBasicAuthPassword = "BASIC_AUTH_PASSWORD"
 const EnvAuthenticatedWebhooks = "AUTHENTICATED_WEBHOOKS"
 const EnvTokenSecret = "TOKEN_SECRET"
 
+// EnvQuayWebhookSecret - optional shared secret Quay notifications have to include, either as
+// "secret" query parameter of the webhook URL or X-Keel-Webhook-Secret header
+const EnvQuayWebhookSecret = "QUAY_WEBHOOK_SECRET"
+
+// EnvHarborWebhookAuthHeader - optional "Auth Header" configured in Harbor webhook policy,
+// Harbor sends it as Authorization header with every notification
+const EnvHarborWebhookAuthHeader = "HARBOR_WEBHOOK_AUTH_HEADER"
+
+// EnvGitlabWebhookToken - optional "Secret token" of GitLab webhooks, sent as X-Gitlab-Token header
+const EnvGitlabWebhookToken = "GITLAB_WEBHOOK_TOKEN"
+
+// EnvGitlabRegistry - container registry of self-hosted GitLab, defaults to registry.gitlab.com
+const EnvGitlabRegistry = "GITLAB_REGISTRY"
+
+// EnvGithubWebhookSecret - optional secret of GitHub webhooks, payloads are verified
+// against X-Hub-Signature-256 header
+const EnvGithubWebhookSecret = "GITHUB_WEBHOOK_SECRET"
+
+// EnvGenericWebhooks - path to YAML or JSON file with generic webhook mappings,
+// see http.GenericWebhook
+const EnvGenericWebhooks = "GENERIC_WEBHOOKS"
+
+// EnvNativeWebhookSecret - optional secret, native webhook payloads have to be signed with
+// HMAC SHA256 and the signature passed as X-Keel-Signature: sha256=<hex> header
+const EnvNativeWebhookSecret = "NATIVE_WEBHOOK_SECRET"
+
+// EnvNativeWebhookTokens - optional comma separated list of <source>=<token> entries,
+// sources that can't sign payloads pass their token as X-Keel-Token header
+const EnvNativeWebhookTokens = "NATIVE_WEBHOOK_TOKENS"
+
 // KeelLogoURL - is a logo URL for bot icon
 const KeelLogoURL = "https://keel.sh/images/logo.png"
@@ -6,15 +6,15 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
-	// "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecr"
 
 	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/awsauth"
 	"github.com/keel-hq/keel/types"
 
 	log "github.com/sirupsen/logrus"
@@ -32,15 +32,30 @@ func init() {
 	registryRegxp = regexp.MustCompile(`(?P<registryID>\d+)\.dkr\.ecr\.(?P<region>\S+)\.amazonaws\.com`)
 }
 
+// ecrTokenExpiryWindow - ECR tokens are valid for 12 hours, new token is requested this
+// long before the current one expires so that long running polling never uses an expired one
+const ecrTokenExpiryWindow = 30 * time.Minute
+
+// ecrAPI - subset of ECR client used to get authorization tokens
+type ecrAPI interface {
+	GetAuthorizationToken(input *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error)
+}
+
 // CredentialsHelper provides authorization to ECR.
 // Authentication details: https://docs.aws.amazon.com/sdk-for-go/api/aws/session/
 // # Access Key ID
 // AWS_ACCESS_KEY_ID=AKID
 // AWS_ACCESS_KEY=AKID # only read if AWS_ACCESS_KEY_ID is not set.
 // more on auth: https://stackoverflow.com/questions/41544554/how-to-run-aws-sdk-with-credentials-from-variables
+// IAM roles for service accounts (AWS_ROLE_ARN, AWS_WEB_IDENTITY_TOKEN_FILE) and instance
+// roles are supported as well.
 type CredentialsHelper struct {
 	enabled bool
 	cache   *Cache
+
+	mu        sync.Mutex
+	clients   map[string]ecrAPI // ECR clients by region, reusing their refreshed credentials
+	newClient func(region string) (ecrAPI, error)
 }
 
 // New creates a new instance of aws credentials helper
@@ -48,6 +63,14 @@ func New() *CredentialsHelper {
 	ch := &CredentialsHelper{
 		enabled: true,
 		cache:   NewCache(AWSCredentialsExpiry),
+		clients: make(map[string]ecrAPI),
+		newClient: func(region string) (ecrAPI, error) {
+			sess, err := awsauth.NewSession(region)
+			if err != nil {
+				return nil, err
+			}
+			return ecr.New(sess), nil
+		},
 	}
 
 	return ch
@@ -58,7 +81,24 @@ func (h *CredentialsHelper) IsEnabled() bool {
 	return h.enabled
 }
 
-// GetCredentials - finds credentials
+func (h *CredentialsHelper) client(region string) (ecrAPI, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	svc, ok := h.clients[region]
+	if ok {
+		return svc, nil
+	}
+	svc, err := h.newClient(region)
+	if err != nil {
+		return nil, err
+	}
+	h.clients[region] = svc
+	return svc, nil
+}
+
+// GetCredentials - finds credentials, tokens are cached by registry host until they
+// are about to expire
 func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
 
 	if !h.enabled {
@@ -67,60 +107,54 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 
 	registry := image.Image.Registry()
 
-	_, region, err := parseRegistry(registry)
+	registryID, region, err := parseRegistry(registry)
 	if err != nil {
 		return nil, err
 	}
 
-	// set region
-	sess := newAwsSession(region)
-	svc := ecr.New(sess)
-
 	cached, err := h.cache.Get(registry)
 	if err == nil {
 		return cached, nil
 	}
 
-	input := &ecr.GetAuthorizationTokenInput{}
+	svc, err := h.client(region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %s", err)
+	}
 
-	result, err := svc.GetAuthorizationToken(input)
+	// registry ID is required for registries of other accounts
+	result, err := svc.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registryID)},
+	})
 	if err != nil {
+		fields := log.Fields{
+			"error":    err,
+			"registry": registry,
+		}
 		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case ecr.ErrCodeServerException:
-				fmt.Println(ecr.ErrCodeServerException, aerr.Error())
-			case ecr.ErrCodeInvalidParameterException:
-				fmt.Println(ecr.ErrCodeInvalidParameterException, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			log.WithFields(log.Fields{
-				"error": err,
-			}).Error("credentialshelper.aws: failed to get authorization token")
+			fields["code"] = aerr.Code()
 		}
+		log.WithFields(fields).Error("credentialshelper.aws: failed to get authorization token")
 		return nil, err
 	}
 
 	for _, ad := range result.AuthorizationData {
 
-		u, err := url.Parse(*ad.ProxyEndpoint)
+		u, err := url.Parse(aws.StringValue(ad.ProxyEndpoint))
 		if err != nil {
-			log.WithError(err).Errorf("credentialshelper.aws: failed to parse registry endpoint: %s", *ad.ProxyEndpoint)
+			log.WithError(err).Errorf("credentialshelper.aws: failed to parse registry endpoint: %s", aws.StringValue(ad.ProxyEndpoint))
 			continue
 		}
 
 		log.WithFields(log.Fields{
 			"current_registry": u.Host,
-			"token":            *ad.AuthorizationToken,
 			"registry":         registry,
+			"expires_at":       aws.TimeValue(ad.ExpiresAt),
 		}).Debug("checking registry")
 		if u.Host == registry {
-			username, password, err := decodeBase64Secret(*ad.AuthorizationToken)
+			username, password, err := decodeBase64Secret(aws.StringValue(ad.AuthorizationToken))
 			if err != nil {
-				return nil, fmt.Errorf("failed to decode authentication token: %s, error: %s", *ad.AuthorizationToken, err)
+				return nil, fmt.Errorf("failed to decode authentication token of %s: %s", registry, err)
 			}
 
 			creds := &types.Credentials{
@@ -128,7 +162,11 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 				Password: password,
 			}
 
-			h.cache.Put(registry, creds)
+			if ad.ExpiresAt != nil {
+				h.cache.PutUntil(registry, creds, ad.ExpiresAt.Add(-ecrTokenExpiryWindow))
+			} else {
+				h.cache.Put(registry, creds)
+			}
 
 			return creds, nil
 		}
@@ -137,14 +175,6 @@ func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Cr
 	return nil, fmt.Errorf("not found")
 }
 
-func newAwsSession(region string) *session.Session {
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(region),
-	}))
-
-	return sess
-}
-
 func decodeBase64Secret(authSecret string) (username, password string, err error) {
 	decoded, err := base64.StdEncoding.DecodeString(authSecret)
 	if err != nil {
@@ -1,8 +1,14 @@
 package aws
 
 import (
+	"encoding/base64"
+	"fmt"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
 
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
@@ -78,3 +84,68 @@ func TestAWSRegistryParse(t *testing.T) {
 		t.Fatalf("parseRegistry parse region(us-east-2) not as expected: %s", region)
 	}
 }
+
+type fakeECR struct {
+	calls     int
+	input     *ecr.GetAuthorizationTokenInput
+	expiresAt time.Time
+}
+
+func (f *fakeECR) GetAuthorizationToken(input *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error) {
+	f.calls++
+	f.input = input
+	token := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("AWS:token-%d", f.calls)))
+	return &ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []*ecr.AuthorizationData{
+			{
+				AuthorizationToken: aws.String(token),
+				ExpiresAt:          aws.Time(f.expiresAt),
+				ProxyEndpoint:      aws.String("https://528670773427.dkr.ecr.us-east-2.amazonaws.com"),
+			},
+		},
+	}, nil
+}
+
+func TestGetCredentialsRefresh(t *testing.T) {
+	fake := &fakeECR{expiresAt: time.Now().Add(12 * time.Hour)}
+	ch := New()
+	ch.newClient = func(region string) (ecrAPI, error) {
+		if region != "us-east-2" {
+			t.Errorf("unexpected region: %s", region)
+		}
+		return fake, nil
+	}
+
+	imgRef, _ := image.Parse("528670773427.dkr.ecr.us-east-2.amazonaws.com/webhook-demo:master")
+	ti := &types.TrackedImage{Image: imgRef}
+
+	for i := 0; i < 3; i++ {
+		creds, err := ch.GetCredentials(ti)
+		if err != nil {
+			t.Fatalf("cred helper got error: %s", err)
+		}
+		if creds.Username != "AWS" || creds.Password != "token-1" {
+			t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+		}
+	}
+	if fake.calls != 1 {
+		t.Errorf("expected token to be cached, got %d calls", fake.calls)
+	}
+	if len(fake.input.RegistryIds) != 1 || *fake.input.RegistryIds[0] != "528670773427" {
+		t.Errorf("expected token for registry 528670773427")
+	}
+
+	// token about to expire is replaced
+	fake.expiresAt = time.Now().Add(10 * time.Minute)
+	ch.cache.PutUntil(imgRef.Registry(), &types.Credentials{Username: "AWS", Password: "old"}, time.Now())
+	creds, err := ch.GetCredentials(ti)
+	if err != nil {
+		t.Fatalf("cred helper got error: %s", err)
+	}
+	if creds.Password != "token-2" {
+		t.Errorf("expected new token, got %s", creds.Password)
+	}
+	if _, err := ch.cache.Get(imgRef.Registry()); err == nil {
+		t.Errorf("token expiring within refresh window shouldn't be cached")
+	}
+}
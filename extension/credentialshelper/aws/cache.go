@@ -11,6 +11,11 @@ import (
 type item struct {
 	credentials *types.Credentials
 	created     time.Time
+	expires     time.Time // zero if only ttl applies
+}
+
+func (i *item) expired(t time.Time, ttl time.Duration) bool {
+	return t.Sub(i.created) > ttl || (!i.expires.IsZero() && !t.Before(i.expires))
 }
 
 // Cache - internal cache for aws
@@ -49,7 +54,7 @@ func (c *Cache) expire() {
 	defer c.mu.Unlock()
 	t := time.Now()
 	for k, v := range c.creds {
-		if t.Sub(v.created) > c.ttl {
+		if v.expired(t, c.ttl) {
 			delete(c.creds, k)
 		}
 	}
@@ -62,13 +67,21 @@ func (c *Cache) Put(registry string, creds *types.Credentials) {
 	c.creds[registry] = &item{credentials: creds, created: time.Now()}
 }
 
+// PutUntil - saves new creds that stop being returned at expires (or after cache ttl,
+// whichever comes first)
+func (c *Cache) PutUntil(registry string, creds *types.Credentials, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.creds[registry] = &item{credentials: creds, created: time.Now(), expires: expires}
+}
+
 // Get - retrieves creds
 func (c *Cache) Get(registry string) (*types.Credentials, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	item, ok := c.creds[registry]
-	if !ok {
+	if !ok || item.expired(time.Now(), c.ttl) {
 		return nil, fmt.Errorf("not found")
 	}
 
@@ -57,3 +57,22 @@ func TestExpiry(t *testing.T) {
 	}
 
 }
+
+func TestPutUntil(t *testing.T) {
+	c := NewCache(time.Hour)
+
+	creds := &types.Credentials{
+		Username: "user-1",
+		Password: "pass-1",
+	}
+
+	c.PutUntil("reg1", creds, time.Now().Add(time.Minute))
+	c.PutUntil("reg2", creds, time.Now().Add(-time.Second))
+
+	if _, err := c.Get("reg1"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := c.Get("reg2"); err == nil {
+		t.Errorf("expected expired creds not to be returned")
+	}
+}
@@ -0,0 +1,135 @@
+package awssecrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/awssecrets"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EnvRegistryCredentials - reference of registry credentials secret or parameter, {registry}
+// is replaced with registry host, for example aws-sm:keel/registries/{registry} or
+// aws-ssm:/keel/registries/{registry}. Value is a JSON object with username and password
+const EnvRegistryCredentials = "AWS_REGISTRY_CREDENTIALS"
+
+// credentials are read again after this long so rotated secrets are picked up
+const refreshInterval = 5 * time.Minute
+
+func init() {
+	credentialshelper.RegisterCredentialsHelper("awssecrets", New())
+}
+
+// resolver - reads referenced secret value
+type resolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// CredentialsHelper - reads registry credentials from AWS Secrets Manager or SSM
+// Parameter Store
+type CredentialsHelper struct {
+	enabled   bool
+	resolver  resolver
+	reference string
+
+	mu    sync.Mutex
+	cache map[string]*cached // by registry host
+}
+
+type cached struct {
+	creds     *types.Credentials
+	refreshAt time.Time
+}
+
+// New creates a new instance of AWS secrets credentials helper, it's enabled when
+// credentials reference is set
+func New() *CredentialsHelper {
+	ch := &CredentialsHelper{cache: make(map[string]*cached)}
+
+	ref := os.Getenv(EnvRegistryCredentials)
+	if ref == "" {
+		return ch
+	}
+	if !awssecrets.IsReference(ref) {
+		log.WithFields(log.Fields{
+			"reference": ref,
+		}).Errorf("credentialshelper.awssecrets: reference has to start with %s or %s", awssecrets.SecretsManagerPrefix, awssecrets.ParameterStorePrefix)
+		return ch
+	}
+
+	ch.enabled = true
+	ch.resolver = awssecrets.NewResolver()
+	ch.reference = ref
+	return ch
+}
+
+// IsEnabled returns a bool whether this credentials helper is initialised or not
+func (h *CredentialsHelper) IsEnabled() bool {
+	return h.enabled
+}
+
+// GetCredentials - returns credentials stored for image registry
+func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	if !h.enabled {
+		return nil, fmt.Errorf("not initialised")
+	}
+
+	registry := image.Image.Registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.cache[registry]
+	if ok && time.Now().Before(c.refreshAt) {
+		if c.creds == nil {
+			return nil, credentialshelper.ErrCredentialsNotAvailable
+		}
+		return copyCredentials(c.creds), nil
+	}
+
+	ref := strings.Replace(h.reference, "{registry}", registry, -1)
+	value, err := h.resolver.Resolve(ref)
+	if err != nil {
+		if awssecrets.IsNotFound(err) {
+			h.cache[registry] = &cached{refreshAt: time.Now().Add(refreshInterval)}
+			return nil, credentialshelper.ErrCredentialsNotAvailable
+		}
+		if ok && c.creds != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"registry": registry,
+			}).Warn("credentialshelper.awssecrets: failed to refresh registry credentials, using previous ones")
+			return copyCredentials(c.creds), nil
+		}
+		log.WithFields(log.Fields{
+			"error":     err,
+			"registry":  registry,
+			"reference": ref,
+		}).Error("credentialshelper.awssecrets: failed to read registry credentials")
+		return nil, err
+	}
+
+	var fields struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil || fields.Username == "" || fields.Password == "" {
+		return nil, fmt.Errorf("%s: expected JSON object with username and password", ref)
+	}
+	creds := &types.Credentials{Username: fields.Username, Password: fields.Password}
+	h.cache[registry] = &cached{creds: creds, refreshAt: time.Now().Add(refreshInterval)}
+
+	return copyCredentials(creds), nil
+}
+
+func copyCredentials(c *types.Credentials) *types.Credentials {
+	cr := *c
+	return &cr
+}
@@ -0,0 +1,79 @@
+package awssecrets
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeResolver struct {
+	values map[string]string
+	err    error
+	calls  int
+}
+
+func (r *fakeResolver) Resolve(ref string) (string, error) {
+	r.calls++
+	if r.err != nil {
+		return "", r.err
+	}
+	value, ok := r.values[ref]
+	if !ok {
+		return "", awserr.New("ResourceNotFoundException", "not found", nil)
+	}
+	return value, nil
+}
+
+func trackedImage(ref string) *types.TrackedImage {
+	imgRef, _ := image.Parse(ref)
+	return &types.TrackedImage{Image: imgRef}
+}
+
+func TestGetCredentials(t *testing.T) {
+	r := &fakeResolver{values: map[string]string{
+		"aws-sm:keel/registries/quay.io": `{"username": "quay-user", "password": "quay-pass"}`,
+		"aws-sm:keel/registries/gcr.io":  `{"username": "gcr-user"}`,
+	}}
+	h := &CredentialsHelper{enabled: true, resolver: r, reference: "aws-sm:keel/registries/{registry}", cache: make(map[string]*cached)}
+
+	for i := 0; i < 2; i++ {
+		creds, err := h.GetCredentials(trackedImage("quay.io/foo/bar:1.0.0"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if creds.Username != "quay-user" || creds.Password != "quay-pass" {
+			t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+		}
+	}
+	if r.calls != 1 {
+		t.Errorf("expected credentials to be cached, got %d reads", r.calls)
+	}
+
+	if _, err := h.GetCredentials(trackedImage("gcr.io/project/app:1.0.0")); err == nil {
+		t.Errorf("expected error for credentials without password")
+	}
+
+	// missing secrets aren't read again until refresh
+	for i := 0; i < 2; i++ {
+		if _, err := h.GetCredentials(trackedImage("index.docker.io/foo/bar:1.0.0")); err != credentialshelper.ErrCredentialsNotAvailable {
+			t.Errorf("expected credentials not available error, got %v", err)
+		}
+	}
+	if r.calls != 3 {
+		t.Errorf("expected missing secret to be cached, got %d reads", r.calls)
+	}
+
+	// previous credentials are used when refresh fails
+	r.err = fmt.Errorf("throttled")
+	h.cache["quay.io"].refreshAt = time.Now()
+	creds, err := h.GetCredentials(trackedImage("quay.io/foo/bar:1.0.0"))
+	if err != nil || creds.Username != "quay-user" {
+		t.Errorf("expected previous credentials, got %v, %v", creds, err)
+	}
+}
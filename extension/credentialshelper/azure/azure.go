@@ -0,0 +1,174 @@
+package azure
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// acrUsername - username of registry credentials using ACR refresh token as password
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// refreshTokenExpiryWindow - ACR refresh tokens are valid for 3 hours, new one is
+// requested this long before the current one expires
+const refreshTokenExpiryWindow = 15 * time.Minute
+
+func init() {
+	credentialshelper.RegisterCredentialsHelper("azure", New())
+}
+
+// CredentialsHelper provides authorization to Azure Container Registry. Azure AD token of
+// managed identity (or service principal, see AZURE_CLIENT_ID, AZURE_CLIENT_SECRET,
+// AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE) is exchanged for refresh tokens of
+// each registry
+type CredentialsHelper struct {
+	enabled bool
+	client  *http.Client
+	aad     tokenSource
+	// scheme - registry endpoint scheme, tests use plain HTTP
+	scheme string
+
+	mu     sync.Mutex
+	tokens map[string]*refreshToken // by registry host
+}
+
+type refreshToken struct {
+	token   string
+	expires time.Time
+}
+
+// New creates a new instance of azure credentials helper
+func New() *CredentialsHelper {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &CredentialsHelper{
+		enabled: true,
+		client:  client,
+		aad:     newTokenSource(client),
+		scheme:  "https",
+		tokens:  make(map[string]*refreshToken),
+	}
+}
+
+// IsEnabled returns a bool whether this credentials helper is initialised or not
+func (h *CredentialsHelper) IsEnabled() bool {
+	return h.enabled
+}
+
+// GetCredentials - returns registry refresh token credentials, token is requested again
+// when it's about to expire
+func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	if !h.enabled {
+		return nil, fmt.Errorf("not initialised")
+	}
+
+	registry := image.Image.Registry()
+	if !isACR(registry) {
+		return nil, credentialshelper.ErrUnsupportedRegistry
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cached, ok := h.tokens[registry]
+	if ok && time.Now().Add(refreshTokenExpiryWindow).Before(cached.expires) {
+		return &types.Credentials{Username: acrUsername, Password: cached.token}, nil
+	}
+
+	aadToken, err := h.aad.token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure AD token: %s", err)
+	}
+
+	rt, err := h.exchange(registry, aadToken.accessToken)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": registry,
+		}).Error("credentialshelper.azure: failed to exchange Azure AD token for registry refresh token")
+		return nil, err
+	}
+	h.tokens[registry] = rt
+
+	return &types.Credentials{Username: acrUsername, Password: rt.token}, nil
+}
+
+// exchange - exchanges Azure AD access token for registry refresh token
+func (h *CredentialsHelper) exchange(registry, accessToken string) (*refreshToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", registry)
+	form.Set("access_token", accessToken)
+	if tenantID := os.Getenv(EnvTenantID); tenantID != "" {
+		form.Set("tenant", tenantID)
+	}
+
+	resp, err := h.client.PostForm(h.scheme+"://"+registry+"/oauth2/exchange", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token exchange failed with status code %d: %s", resp.StatusCode, body)
+	}
+
+	var er struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &er); err != nil {
+		return nil, fmt.Errorf("failed to decode token exchange response: %s", err)
+	}
+	if er.RefreshToken == "" {
+		return nil, fmt.Errorf("token exchange response has no refresh token")
+	}
+
+	return &refreshToken{token: er.RefreshToken, expires: tokenExpiry(er.RefreshToken)}, nil
+}
+
+// tokenExpiry - expiry from exp claim of the refresh token (JWT), signature doesn't
+// matter as the token only decides when to request a new one
+func tokenExpiry(token string) time.Time {
+	fallback := time.Now().Add(3 * time.Hour)
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fallback
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return fallback
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return fallback
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// isACR - Azure Container Registry hosts of public and sovereign clouds
+func isACR(registry string) bool {
+	for _, suffix := range []string{".azurecr.io", ".azurecr.cn", ".azurecr.de", ".azurecr.us"} {
+		if strings.HasSuffix(registry, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+func fakeJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp": %d}`, exp.Unix())))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".signature"
+}
+
+// clientFor - client sending all requests to test server whatever the host
+func clientFor(srv *httptest.Server) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("tcp", srv.Listener.Addr().String())
+		},
+	}}
+}
+
+func TestGetCredentials(t *testing.T) {
+	exchanges := 0
+	expires := time.Now().Add(3 * time.Hour)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			if r.Header.Get("Metadata") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"access_token": "aad-token", "expires_on": "%d"}`, time.Now().Add(time.Hour).Unix())
+		case "/oauth2/exchange":
+			exchanges++
+			r.ParseForm()
+			if r.Host != "myacr.azurecr.io" || r.Form.Get("service") != "myacr.azurecr.io" || r.Form.Get("access_token") != "aad-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"refresh_token": "%s"}`, fakeJWT(expires))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := clientFor(srv)
+	ch := &CredentialsHelper{
+		enabled: true,
+		client:  client,
+		aad:     &cachedTokenSource{src: &managedIdentitySource{client: client, tokenURL: srv.URL + "/metadata/identity/oauth2/token"}},
+		scheme:  "http",
+		tokens:  make(map[string]*refreshToken),
+	}
+
+	imgRef, _ := image.Parse("myacr.azurecr.io/app:1.0.0")
+	ti := &types.TrackedImage{Image: imgRef}
+	for i := 0; i < 2; i++ {
+		creds, err := ch.GetCredentials(ti)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if creds.Username != acrUsername || creds.Password != fakeJWT(expires) {
+			t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("expected refresh token to be reused, got %d exchanges", exchanges)
+	}
+
+	// refresh token about to expire is replaced
+	ch.tokens["myacr.azurecr.io"].expires = time.Now().Add(time.Minute)
+	if _, err := ch.GetCredentials(ti); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exchanges != 2 {
+		t.Errorf("expected refresh token to be requested again, got %d exchanges", exchanges)
+	}
+
+	imgRef, _ = image.Parse("gcr.io/project/app:1.0.0")
+	if _, err := ch.GetCredentials(&types.TrackedImage{Image: imgRef}); err != credentialshelper.ErrUnsupportedRegistry {
+		t.Errorf("expected unsupported registry error, got %v", err)
+	}
+}
+
+func TestClientCredentialsSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" || r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "sp-token", "expires_in": 3599}`)
+	}))
+	defer srv.Close()
+
+	src := &clientCredentialsSource{client: http.DefaultClient, tokenURL: srv.URL + "/tenant/oauth2/v2.0/token", clientID: "client", secret: "secret"}
+	token, err := src.token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token.accessToken != "sp-token" {
+		t.Errorf("unexpected token: %s", token.accessToken)
+	}
+	if time.Until(token.expires) < 59*time.Minute {
+		t.Errorf("unexpected expiry: %s", token.expires)
+	}
+
+	src.secret = "wrong"
+	if _, err := src.token(); err == nil {
+		t.Errorf("expected error with wrong secret")
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	if got := tokenExpiry(fakeJWT(exp)); !got.Equal(exp) {
+		t.Errorf("unexpected expiry: %s", got)
+	}
+	if got := tokenExpiry("opaque"); time.Until(got) < 2*time.Hour {
+		t.Errorf("expected fallback expiry, got %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Azure AD configuration, same variables as used by Azure SDKs
+const (
+	EnvTenantID           = "AZURE_TENANT_ID"
+	EnvClientID           = "AZURE_CLIENT_ID"
+	EnvClientSecret       = "AZURE_CLIENT_SECRET"
+	EnvFederatedTokenFile = "AZURE_FEDERATED_TOKEN_FILE"
+	EnvAuthorityHost      = "AZURE_AUTHORITY_HOST"
+)
+
+const (
+	defaultAuthorityHost = "https://login.microsoftonline.com/"
+	imdsTokenURL         = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// managementResource - resource of AAD tokens accepted by ACR token exchange
+	managementResource = "https://management.azure.com/"
+)
+
+// aadTokenExpiryWindow - AAD tokens are requested again this long before they expire
+const aadTokenExpiryWindow = 5 * time.Minute
+
+// aadToken - Azure AD access token
+type aadToken struct {
+	accessToken string
+	expires     time.Time
+}
+
+// tokenSource - source of Azure AD access tokens
+type tokenSource interface {
+	token() (*aadToken, error)
+}
+
+// newTokenSource - service principal with client secret, workload identity (federated
+// token) or managed identity, depending on the environment
+func newTokenSource(client *http.Client) tokenSource {
+	authority := os.Getenv(EnvAuthorityHost)
+	if authority == "" {
+		authority = defaultAuthorityHost
+	}
+	tenantID := os.Getenv(EnvTenantID)
+	clientID := os.Getenv(EnvClientID)
+
+	var src tokenSource
+	switch {
+	case tenantID != "" && clientID != "" && os.Getenv(EnvClientSecret) != "":
+		src = &clientCredentialsSource{
+			client:   client,
+			tokenURL: strings.TrimSuffix(authority, "/") + "/" + tenantID + "/oauth2/v2.0/token",
+			clientID: clientID,
+			secret:   os.Getenv(EnvClientSecret),
+		}
+	case tenantID != "" && clientID != "" && os.Getenv(EnvFederatedTokenFile) != "":
+		src = &clientCredentialsSource{
+			client:        client,
+			tokenURL:      strings.TrimSuffix(authority, "/") + "/" + tenantID + "/oauth2/v2.0/token",
+			clientID:      clientID,
+			assertionFile: os.Getenv(EnvFederatedTokenFile),
+		}
+	default:
+		// user assigned identity is selected by client ID
+		src = &managedIdentitySource{client: client, tokenURL: imdsTokenURL, clientID: clientID}
+	}
+	return &cachedTokenSource{src: src}
+}
+
+// cachedTokenSource - reuses token until it's about to expire
+type cachedTokenSource struct {
+	src tokenSource
+
+	mu      sync.Mutex
+	current *aadToken
+}
+
+func (s *cachedTokenSource) token() (*aadToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && time.Now().Add(aadTokenExpiryWindow).Before(s.current.expires) {
+		return s.current, nil
+	}
+	t, err := s.src.token()
+	if err != nil {
+		return nil, err
+	}
+	s.current = t
+	return t, nil
+}
+
+// managedIdentitySource - tokens of system or user assigned managed identity from
+// instance metadata service
+type managedIdentitySource struct {
+	client   *http.Client
+	tokenURL string
+	clientID string
+}
+
+func (s *managedIdentitySource) token() (*aadToken, error) {
+	q := url.Values{}
+	q.Set("api-version", "2018-02-01")
+	q.Set("resource", managementResource)
+	if s.clientID != "" {
+		q.Set("client_id", s.clientID)
+	}
+	req, err := http.NewRequest(http.MethodGet, s.tokenURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	return doTokenRequest(s.client, req)
+}
+
+// clientCredentialsSource - service principal tokens, authenticated with client secret
+// or with federated token of AKS workload identity
+type clientCredentialsSource struct {
+	client        *http.Client
+	tokenURL      string
+	clientID      string
+	secret        string
+	assertionFile string
+}
+
+func (s *clientCredentialsSource) token() (*aadToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.clientID)
+	form.Set("scope", managementResource+".default")
+	if s.assertionFile != "" {
+		// token is rotated by kubelet, reading it every time
+		assertion, err := ioutil.ReadFile(s.assertionFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read federated token: %s", err)
+		}
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	} else {
+		form.Set("client_secret", s.secret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(s.client, req)
+}
+
+// tokenResponse - expiry is a number or a string depending on the endpoint
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+	ExpiresOn   json.Number `json:"expires_on"`
+}
+
+func doTokenRequest(client *http.Client, req *http.Request) (*aadToken, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status code %d: %s", resp.StatusCode, body)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %s", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+
+	t := &aadToken{accessToken: tr.AccessToken, expires: time.Now().Add(time.Hour)}
+	if on, err := strconv.ParseInt(tr.ExpiresOn.String(), 10, 64); err == nil {
+		t.expires = time.Unix(on, 0)
+	} else if in, err := strconv.ParseInt(tr.ExpiresIn.String(), 10, 64); err == nil {
+		t.expires = time.Now().Add(time.Duration(in) * time.Second)
+	}
+	return t, nil
+}
@@ -2,6 +2,7 @@ package credentialshelper
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/keel-hq/keel/types"
@@ -63,6 +64,22 @@ func UnregisterCredentialsHelper(name string) {
 	delete(credHelpers, name)
 }
 
+// helperNames - registered helpers in the order they are tried, image pull secrets
+// configured by users come before credentials provided by the cloud environment
+func helperNames() []string {
+	var names []string
+	for name := range credHelpers {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "secrets") != (names[j] == "secrets") {
+			return names[i] == "secrets"
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
 // GetCredentials - generic function for getting credentials
 // func (ch *CredentialsHelpers) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
 func GetCredentials(image *types.TrackedImage) *types.Credentials {
@@ -71,7 +88,8 @@ func GetCredentials(image *types.TrackedImage) *types.Credentials {
 
 	creds := &types.Credentials{}
 
-	for name, credHelper := range credHelpers {
+	for _, name := range helperNames() {
+		credHelper := credHelpers[name]
 		if credHelper.IsEnabled() {
 			credsFound, err := credHelper.GetCredentials(image)
 			if err != nil {
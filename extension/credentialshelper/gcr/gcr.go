@@ -4,53 +4,106 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/keel-hq/keel/extension/credentialshelper"
 	"github.com/keel-hq/keel/types"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// EnvWorkloadIdentity - set to "false" to disable access tokens from metadata server
+// (Workload Identity or node service account) when no JSON key is configured
+const EnvWorkloadIdentity = "GCR_WORKLOAD_IDENTITY"
+
+// cloudPlatformScope - scope of access tokens, registries check IAM permissions of the
+// service account
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
 func init() {
 	credentialshelper.RegisterCredentialsHelper("gcr", New())
 }
 
+// CredentialsHelper provides authorization to Container Registry and Artifact Registry,
+// with a JSON key from GOOGLE_APPLICATION_CREDENTIALS or with access tokens of the
+// service account bound to the pod through Workload Identity
 type CredentialsHelper struct {
 	enabled     bool
 	credentials string
+
+	// tokenSource - refreshes metadata server access tokens before they expire
+	tokenSource oauth2.TokenSource
 }
 
+// New creates a new instance of gcr credentials helper
 func New() *CredentialsHelper {
 	ch := &CredentialsHelper{}
 
 	credentialsFile, ok := os.LookupEnv("GOOGLE_APPLICATION_CREDENTIALS")
-	if !ok {
-		return ch
+	if ok {
+		credentials, err := ioutil.ReadFile(credentialsFile)
+		if err == nil {
+			ch.enabled = true
+			ch.credentials = string(credentials)
+			return ch
+		}
+		log.WithFields(log.Fields{
+			"error": err,
+			"file":  credentialsFile,
+		}).Warn("credentialshelper.gcr: failed to read credentials file")
 	}
 
-	credentials, err := ioutil.ReadFile(credentialsFile)
-	if err != nil {
+	if os.Getenv(EnvWorkloadIdentity) == "false" {
 		return ch
 	}
 
+	// metadata server is only contacted for Google registries, token source fails
+	// when not running on GCP
 	ch.enabled = true
-	ch.credentials = string(credentials)
+	ch.tokenSource = google.ComputeTokenSource("", cloudPlatformScope)
 	return ch
 }
 
+// IsEnabled returns a bool whether this credentials helper is initialised or not
 func (h *CredentialsHelper) IsEnabled() bool {
 	return h.enabled
 }
 
+// GetCredentials - returns JSON key or access token credentials for Google registries
 func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
 	if !h.enabled {
 		return nil, fmt.Errorf("not initialised")
 	}
 
-	if image.Image.Registry() != "gcr.io" {
-		return nil, nil
+	if !isGoogleRegistry(image.Image.Registry()) {
+		return nil, credentialshelper.ErrUnsupportedRegistry
+	}
+
+	if h.credentials != "" {
+		return &types.Credentials{
+			Username: "_json_key",
+			Password: h.credentials,
+		}, nil
+	}
+
+	token, err := h.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token from metadata server: %s", err)
 	}
 
 	return &types.Credentials{
-		Username: "_json_key",
-		Password: h.credentials,
+		Username: "oauth2accesstoken",
+		Password: token.AccessToken,
 	}, nil
 }
+
+// isGoogleRegistry - Container Registry (gcr.io, us.gcr.io, ...) and Artifact Registry
+// (europe-west1-docker.pkg.dev, ...) hosts
+func isGoogleRegistry(registry string) bool {
+	return registry == "gcr.io" ||
+		strings.HasSuffix(registry, ".gcr.io") ||
+		strings.HasSuffix(registry, "-docker.pkg.dev")
+}
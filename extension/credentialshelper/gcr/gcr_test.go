@@ -0,0 +1,63 @@
+package gcr
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"golang.org/x/oauth2"
+)
+
+func TestIsGoogleRegistry(t *testing.T) {
+	for registry, expected := range map[string]bool{
+		"gcr.io":                      true,
+		"eu.gcr.io":                   true,
+		"europe-west1-docker.pkg.dev": true,
+		"index.docker.io":             false,
+		"quay.io":                     false,
+		"gcr.io.example.com":          false,
+	} {
+		if isGoogleRegistry(registry) != expected {
+			t.Errorf("isGoogleRegistry(%s) != %t", registry, expected)
+		}
+	}
+}
+
+func TestWorkloadIdentityCredentials(t *testing.T) {
+	ch := &CredentialsHelper{
+		enabled:     true,
+		tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access-token"}),
+	}
+
+	imgRef, _ := image.Parse("us-central1-docker.pkg.dev/project/repo/app:1.0.0")
+	creds, err := ch.GetCredentials(&types.TrackedImage{Image: imgRef})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if creds.Username != "oauth2accesstoken" || creds.Password != "access-token" {
+		t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+	}
+
+	imgRef, _ = image.Parse("karolisr/keel:0.2.0")
+	if _, err := ch.GetCredentials(&types.TrackedImage{Image: imgRef}); err != credentialshelper.ErrUnsupportedRegistry {
+		t.Errorf("expected unsupported registry error, got %v", err)
+	}
+}
+
+func TestJSONKeyCredentials(t *testing.T) {
+	ch := &CredentialsHelper{
+		enabled:     true,
+		credentials: `{"type": "service_account"}`,
+	}
+
+	imgRef, _ := image.Parse("gcr.io/project/app:1.0.0")
+	creds, err := ch.GetCredentials(&types.TrackedImage{Image: imgRef})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if creds.Username != "_json_key" || creds.Password != `{"type": "service_account"}` {
+		t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+	}
+}
@@ -0,0 +1,251 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// renewBefore - tokens and leases are renewed once less than this part of their
+// duration is left
+const renewBefore = 0.2
+
+// Opts - Vault connection and authentication options. Token is used when set, otherwise
+// keel logs in with its service account token through Kubernetes auth method
+type Opts struct {
+	Address   string
+	Namespace string // Vault Enterprise namespace
+	Token     string
+
+	KubernetesRole      string
+	KubernetesAuthMount string // defaults to "kubernetes"
+	KubernetesTokenFile string // defaults to service account token
+
+	CACert     string
+	SkipVerify bool
+}
+
+// secret - Vault API response
+type secret struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// client - minimal Vault HTTP API client, keeps its token valid by renewing it or
+// logging in again
+type client struct {
+	opts Opts
+	http *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenExpires time.Time // zero for tokens that don't expire
+	renewAt      time.Time
+	renewable    bool
+	lookedUp     bool // ttl of static token was looked up
+}
+
+// responseError - Vault API error response
+type responseError struct {
+	status int
+	method string
+	path   string
+	errors []string
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("%s %s: status code %d: %s", e.method, e.path, e.status, strings.Join(e.errors, ", "))
+}
+
+func isNotFound(err error) bool {
+	re, ok := err.(*responseError)
+	return ok && re.status == http.StatusNotFound
+}
+
+func newClient(opts Opts) (*client, error) {
+	if opts.KubernetesAuthMount == "" {
+		opts.KubernetesAuthMount = "kubernetes"
+	}
+	if opts.KubernetesTokenFile == "" {
+		opts.KubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	if opts.Token == "" && opts.KubernetesRole == "" {
+		return nil, fmt.Errorf("either Vault token or Kubernetes auth role has to be set")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.SkipVerify}
+	if opts.CACert != "" {
+		pem, err := ioutil.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &client{
+		opts: opts,
+		http: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+		},
+		token: opts.Token,
+	}, nil
+}
+
+// read - reads secret at path
+func (c *client) read(path string) (*secret, error) {
+	token, err := c.validToken()
+	if err != nil {
+		return nil, err
+	}
+	return c.do(http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), token, nil)
+}
+
+// renewLease - extends lease of a dynamic secret
+func (c *client) renewLease(leaseID string) (*secret, error) {
+	token, err := c.validToken()
+	if err != nil {
+		return nil, err
+	}
+	return c.do(http.MethodPut, "/v1/sys/leases/renew", token, map[string]interface{}{"lease_id": leaseID})
+}
+
+// validToken - returns token, renewing it or logging in again when it's about to expire
+func (c *client) validToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.opts.Token != "" && !c.lookedUp {
+		c.lookedUp = true
+		c.lookupToken()
+	}
+
+	now := time.Now()
+	if c.token != "" && (c.renewAt.IsZero() || now.Before(c.renewAt)) {
+		return c.token, nil
+	}
+
+	if c.token != "" && c.renewable && (c.tokenExpires.IsZero() || now.Before(c.tokenExpires)) {
+		resp, err := c.do(http.MethodPost, "/v1/auth/token/renew-self", c.token, map[string]interface{}{})
+		if err == nil && resp.Auth != nil {
+			c.setToken(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)
+			return c.token, nil
+		}
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("credentialshelper.vault: failed to renew token")
+	}
+
+	if c.opts.KubernetesRole == "" {
+		if c.token == "" || (!c.tokenExpires.IsZero() && !now.Before(c.tokenExpires)) {
+			return "", fmt.Errorf("Vault token expired")
+		}
+		// static token that can't be renewed, using it until it expires
+		return c.token, nil
+	}
+
+	jwt, err := ioutil.ReadFile(c.opts.KubernetesTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %s", err)
+	}
+	resp, err := c.do(http.MethodPost, "/v1/auth/"+c.opts.KubernetesAuthMount+"/login", "", map[string]interface{}{
+		"role": c.opts.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Kubernetes auth login failed: %s", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("Kubernetes auth login returned no token")
+	}
+	c.setToken(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)
+	return c.token, nil
+}
+
+// lookupToken - finds out when static token expires and whether it can be renewed
+func (c *client) lookupToken() {
+	resp, err := c.do(http.MethodGet, "/v1/auth/token/lookup-self", c.token, nil)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("credentialshelper.vault: failed to look up token, it won't be renewed")
+		return
+	}
+	ttl, _ := resp.Data["ttl"].(float64)
+	renewable, _ := resp.Data["renewable"].(bool)
+	c.setToken(c.token, int(ttl), renewable)
+}
+
+func (c *client) setToken(token string, leaseDuration int, renewable bool) {
+	c.token = token
+	c.renewable = renewable
+	c.tokenExpires, c.renewAt = time.Time{}, time.Time{}
+	if leaseDuration > 0 {
+		ttl := time.Duration(leaseDuration) * time.Second
+		c.tokenExpires = time.Now().Add(ttl)
+		c.renewAt = time.Now().Add(ttl - time.Duration(float64(ttl)*renewBefore))
+	}
+}
+
+func (c *client) do(method, path, token string, body interface{}) (*secret, error) {
+	var reqBody *bytes.Reader
+	if body != nil {
+		bts, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(bts)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.opts.Address, "/")+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.opts.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var s secret
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode response: %s", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &responseError{status: resp.StatusCode, method: method, path: path, errors: s.Errors}
+	}
+	return &s, nil
+}
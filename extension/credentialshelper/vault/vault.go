@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Vault configuration, connection settings use the same variables as Vault CLI
+const (
+	EnvAddress             = "VAULT_ADDR"
+	EnvToken               = "VAULT_TOKEN"
+	EnvNamespace           = "VAULT_NAMESPACE"
+	EnvCACert              = "VAULT_CACERT"
+	EnvSkipVerify          = "VAULT_SKIP_VERIFY"
+	EnvKubernetesRole      = "VAULT_K8S_ROLE"
+	EnvKubernetesAuthMount = "VAULT_K8S_AUTH_MOUNT"
+	// EnvRegistryPath - path of registry credentials, {registry} is replaced with registry
+	// host, for example secret/data/keel/{registry}
+	EnvRegistryPath = "VAULT_REGISTRY_PATH"
+)
+
+// credentials of secrets without lease (KV) are read again after this long
+const defaultRefresh = 5 * time.Minute
+
+func init() {
+	credentialshelper.RegisterCredentialsHelper("vault", New())
+}
+
+// CredentialsHelper - reads registry username and password from Vault. KV (v1 and v2)
+// secrets are re-read periodically, leases of dynamic secrets are renewed before they
+// expire and the secret is read again once its lease can't be renewed
+type CredentialsHelper struct {
+	enabled bool
+	client  *client
+	path    string
+
+	mu    sync.Mutex
+	cache map[string]*cached // by registry host
+}
+
+type cached struct {
+	creds     *types.Credentials
+	leaseID   string
+	renewable bool
+	refreshAt time.Time
+}
+
+// New creates a new instance of Vault credentials helper, it's enabled when Vault
+// address and registry path are set
+func New() *CredentialsHelper {
+	ch := &CredentialsHelper{cache: make(map[string]*cached)}
+
+	addr := os.Getenv(EnvAddress)
+	path := os.Getenv(EnvRegistryPath)
+	if addr == "" || path == "" {
+		return ch
+	}
+
+	c, err := newClient(Opts{
+		Address:             addr,
+		Namespace:           os.Getenv(EnvNamespace),
+		Token:               os.Getenv(EnvToken),
+		KubernetesRole:      os.Getenv(EnvKubernetesRole),
+		KubernetesAuthMount: os.Getenv(EnvKubernetesAuthMount),
+		CACert:              os.Getenv(EnvCACert),
+		SkipVerify:          os.Getenv(EnvSkipVerify) == "true",
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"address": addr,
+		}).Error("credentialshelper.vault: failed to configure Vault client")
+		return ch
+	}
+
+	ch.enabled = true
+	ch.client = c
+	ch.path = path
+	return ch
+}
+
+// IsEnabled returns a bool whether this credentials helper is initialised or not
+func (h *CredentialsHelper) IsEnabled() bool {
+	return h.enabled
+}
+
+// GetCredentials - returns credentials stored in Vault for image registry
+func (h *CredentialsHelper) GetCredentials(image *types.TrackedImage) (*types.Credentials, error) {
+	if !h.enabled {
+		return nil, fmt.Errorf("not initialised")
+	}
+
+	registry := image.Image.Registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.cache[registry]
+	if ok && time.Now().Before(c.refreshAt) {
+		return copyCredentials(c.creds), nil
+	}
+
+	if ok && c.renewable {
+		renewed, err := h.client.renewLease(c.leaseID)
+		if err == nil && renewed.LeaseDuration > 0 {
+			c.refreshAt = refreshTime(renewed.LeaseDuration)
+			c.renewable = renewed.Renewable
+			return copyCredentials(c.creds), nil
+		}
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": registry,
+		}).Debug("credentialshelper.vault: failed to renew lease, reading secret again")
+	}
+
+	path := strings.Replace(h.path, "{registry}", registry, -1)
+	s, err := h.client.read(path)
+	if err != nil {
+		delete(h.cache, registry)
+		if isNotFound(err) {
+			return nil, credentialshelper.ErrCredentialsNotAvailable
+		}
+		log.WithFields(log.Fields{
+			"error":    err,
+			"registry": registry,
+			"path":     path,
+		}).Error("credentialshelper.vault: failed to read registry credentials")
+		return nil, err
+	}
+
+	creds, err := credentialsFromData(s.Data)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %s", path, err)
+	}
+
+	c = &cached{creds: creds, leaseID: s.LeaseID, renewable: s.Renewable && s.LeaseID != ""}
+	if s.LeaseID != "" && s.LeaseDuration > 0 {
+		c.refreshAt = refreshTime(s.LeaseDuration)
+	} else {
+		c.refreshAt = time.Now().Add(defaultRefresh)
+	}
+	h.cache[registry] = c
+
+	return copyCredentials(creds), nil
+}
+
+// credentialsFromData - username and password fields, KV v2 nests them in data
+func credentialsFromData(data map[string]interface{}) (*types.Credentials, error) {
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	username, _ := data["username"].(string)
+	password, _ := data["password"].(string)
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username or password field is missing")
+	}
+	return &types.Credentials{Username: username, Password: password}, nil
+}
+
+func refreshTime(leaseDuration int) time.Time {
+	ttl := time.Duration(leaseDuration) * time.Second
+	return time.Now().Add(ttl - time.Duration(float64(ttl)*renewBefore))
+}
+
+func copyCredentials(c *types.Credentials) *types.Credentials {
+	cr := *c
+	return &cr
+}
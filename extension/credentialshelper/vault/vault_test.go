@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// fakeVault - Vault API with Kubernetes auth, KV v2 and a dynamic secret engine
+type fakeVault struct {
+	mu       sync.Mutex
+	requests map[string]int
+	leaseTTL int
+	renewErr bool
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.Method+" "+r.URL.Path]++
+	f.mu.Unlock()
+
+	if r.URL.Path != "/v1/auth/kubernetes/login" && r.Header.Get("X-Vault-Token") != "s.token" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role"] != "keel" || body["jwt"] != "service-account-jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"auth": {"client_token": "s.token", "lease_duration": 3600, "renewable": true}}`)
+	case "/v1/auth/token/lookup-self":
+		fmt.Fprint(w, `{"data": {"ttl": 1, "renewable": true}}`)
+	case "/v1/auth/token/renew-self":
+		fmt.Fprint(w, `{"auth": {"client_token": "s.token", "lease_duration": 3600, "renewable": true}}`)
+	case "/v1/secret/data/keel/quay.io":
+		fmt.Fprint(w, `{"data": {"data": {"username": "quay-user", "password": "quay-pass"}, "metadata": {"version": 1}}}`)
+	case "/v1/registry-creds/creds/gcr.io":
+		fmt.Fprintf(w, `{"lease_id": "registry-creds/creds/gcr.io/abc", "lease_duration": %d, "renewable": true, "data": {"username": "gcr-user", "password": "gcr-pass"}}`, f.leaseTTL)
+	case "/v1/sys/leases/renew":
+		if f.renewErr {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors": ["lease not found"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"lease_id": "registry-creds/creds/gcr.io/abc", "lease_duration": 3600, "renewable": true}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors": []}`)
+	}
+}
+
+func (f *fakeVault) count(req string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[req]
+}
+
+func newTestHelper(t *testing.T, srv *httptest.Server, path string, opts Opts) *CredentialsHelper {
+	opts.Address = srv.URL
+	c, err := newClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	return &CredentialsHelper{enabled: true, client: c, path: path, cache: make(map[string]*cached)}
+}
+
+func trackedImage(ref string) *types.TrackedImage {
+	imgRef, _ := image.Parse(ref)
+	return &types.TrackedImage{Image: imgRef}
+}
+
+func TestKubernetesAuthKVSecret(t *testing.T) {
+	fake := &fakeVault{requests: map[string]int{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	f, _ := ioutil.TempFile("", "jwt")
+	defer os.Remove(f.Name())
+	f.WriteString("service-account-jwt\n")
+	f.Close()
+
+	h := newTestHelper(t, srv, "secret/data/keel/{registry}", Opts{KubernetesRole: "keel", KubernetesTokenFile: f.Name()})
+
+	for i := 0; i < 3; i++ {
+		creds, err := h.GetCredentials(trackedImage("quay.io/foo/bar:1.0.0"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if creds.Username != "quay-user" || creds.Password != "quay-pass" {
+			t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+		}
+	}
+	if fake.count("POST /v1/auth/kubernetes/login") != 1 {
+		t.Errorf("expected single login, got %d", fake.count("POST /v1/auth/kubernetes/login"))
+	}
+	if fake.count("GET /v1/secret/data/keel/quay.io") != 1 {
+		t.Errorf("expected secret to be cached, got %d reads", fake.count("GET /v1/secret/data/keel/quay.io"))
+	}
+
+	_, err := h.GetCredentials(trackedImage("index.docker.io/foo/bar:1.0.0"))
+	if err != credentialshelper.ErrCredentialsNotAvailable {
+		t.Errorf("expected credentials not available error, got %v", err)
+	}
+}
+
+func TestDynamicSecretLeaseRenewal(t *testing.T) {
+	fake := &fakeVault{requests: map[string]int{}, leaseTTL: 3600}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	h := newTestHelper(t, srv, "registry-creds/creds/{registry}", Opts{Token: "s.token"})
+	ti := trackedImage("gcr.io/project/app:1.0.0")
+
+	creds, err := h.GetCredentials(ti)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if creds.Username != "gcr-user" {
+		t.Errorf("unexpected username: %s", creds.Username)
+	}
+	// static token ttl is looked up
+	if fake.count("GET /v1/auth/token/lookup-self") != 1 {
+		t.Errorf("expected token lookup")
+	}
+
+	// lease and token about to expire are renewed
+	h.cache["gcr.io"].refreshAt = time.Now()
+	h.client.renewAt = time.Now()
+	if _, err := h.GetCredentials(ti); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.count("PUT /v1/sys/leases/renew") != 1 || fake.count("GET /v1/registry-creds/creds/gcr.io") != 1 {
+		t.Errorf("expected lease to be renewed")
+	}
+	if fake.count("POST /v1/auth/token/renew-self") != 1 {
+		t.Errorf("expected token to be renewed")
+	}
+	if !h.cache["gcr.io"].refreshAt.After(time.Now().Add(time.Hour / 2)) {
+		t.Errorf("unexpected refresh time: %s", h.cache["gcr.io"].refreshAt)
+	}
+
+	// secret is read again when lease can't be renewed
+	fake.renewErr = true
+	h.cache["gcr.io"].refreshAt = time.Now()
+	if _, err := h.GetCredentials(ti); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.count("GET /v1/registry-creds/creds/gcr.io") != 2 {
+		t.Errorf("expected secret to be read again")
+	}
+}
+
+func TestCredentialsFromData(t *testing.T) {
+	if _, err := credentialsFromData(map[string]interface{}{"username": "user"}); err == nil {
+		t.Errorf("expected error without password")
+	}
+	creds, err := credentialsFromData(map[string]interface{}{"username": "user", "password": "pass"})
+	if err != nil || creds.Username != "user" || creds.Password != "pass" {
+		t.Errorf("unexpected credentials: %v, %v", creds, err)
+	}
+}
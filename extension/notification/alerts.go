@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// AlertKey - deduplication key for incident management senders (PagerDuty, Opsgenie),
+// failures of the same resource and image are reported as a single alert. Notifications
+// without resource metadata are keyed by their identifier or name
+func AlertKey(event types.EventNotification) string {
+	parts := []string{"keel"}
+	if cluster := event.Metadata["cluster"]; cluster != "" {
+		parts = append(parts, cluster)
+	}
+	switch {
+	case event.Metadata["namespace"] != "":
+		parts = append(parts, event.Metadata["namespace"]+"/"+event.Metadata["name"])
+	case event.Identifier != "":
+		parts = append(parts, event.Identifier)
+	default:
+		parts = append(parts, event.Name)
+	}
+	if image := event.Metadata["image"]; image != "" {
+		parts = append(parts, image)
+	}
+	return strings.Join(parts, ":")
+}
+
+// OpenAlerts - keys of alerts that were triggered, resolved once the resource is
+// successfully updated. Kept in memory only, alerts triggered before a restart have to be
+// resolved manually
+type OpenAlerts struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// Add - records triggered alert
+func (a *OpenAlerts) Add(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keys == nil {
+		a.keys = make(map[string]bool)
+	}
+	a.keys[key] = true
+}
+
+// Remove - removes the alert, returns false if it wasn't triggered
+func (a *OpenAlerts) Remove(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.keys[key] {
+		return false
+	}
+	delete(a.keys, key)
+	return true
+}
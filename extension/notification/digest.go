@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// digestMaxLines - notifications listed in a digest message, the rest are only counted
+const digestMaxLines = 50
+
+// digestBatch - notifications of the sender waiting for the next digest, batched by
+// channels and webhooks they are routed to
+type digestBatch struct {
+	sender   string
+	channels []string
+	webhooks []string
+	events   []types.EventNotification
+}
+
+// digestQueue - notification batches in order they were started
+type digestQueue struct {
+	mu      sync.Mutex
+	batches []*digestBatch
+	index   map[string]*digestBatch
+}
+
+func digestKey(sender string, event types.EventNotification) string {
+	return sender + "|" + strings.Join(event.Channels, ",") + "|" + strings.Join(event.Webhooks, ",")
+}
+
+// add - appends the notification to the batch of the sender and its channels
+func (q *digestQueue) add(sender string, event types.EventNotification) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.index == nil {
+		q.index = make(map[string]*digestBatch)
+	}
+
+	key := digestKey(sender, event)
+	batch, ok := q.index[key]
+	if !ok {
+		batch = &digestBatch{
+			sender:   sender,
+			channels: event.Channels,
+			webhooks: event.Webhooks,
+		}
+		q.index[key] = batch
+		q.batches = append(q.batches, batch)
+	}
+	batch.events = append(batch.events, event)
+}
+
+// take - removes and returns all batches
+func (q *digestQueue) take() []*digestBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batches := q.batches
+	q.batches = nil
+	q.index = nil
+	return batches
+}
+
+// event - single notification summarizing the batch, a batch of one notification is
+// sent as is
+func (b *digestBatch) event(interval time.Duration) types.EventNotification {
+	if len(b.events) == 1 {
+		return b.events[0]
+	}
+
+	level := types.LevelDebug
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "%d notifications in the last %s:", len(b.events), interval)
+	for i, event := range b.events {
+		if event.Level > level {
+			level = event.Level
+		}
+		if i < digestMaxLines {
+			fmt.Fprintf(msg, "\n- %s: %s", typeName(event.Type), event.Message)
+		}
+	}
+	if len(b.events) > digestMaxLines {
+		fmt.Fprintf(msg, "\n... and %d more", len(b.events)-digestMaxLines)
+	}
+
+	return types.EventNotification{
+		Name:      "notification digest",
+		Message:   msg.String(),
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDigest,
+		Level:     level,
+		Channels:  b.channels,
+		Webhooks:  b.webhooks,
+		Metadata: map[string]string{
+			"count": strconv.Itoa(len(b.events)),
+		},
+	}
+}
+
+// queueDigest - batches the notification until the next digest is sent
+func (m *DefaultNotificationSender) queueDigest(sender string, event types.EventNotification) {
+	m.digests.add(sender, event)
+
+	m.digestOnce.Do(func() {
+		m.stopper.Begin()
+		go m.processDigests()
+	})
+}
+
+func (m *DefaultNotificationSender) processDigests() {
+	defer m.stopper.End()
+
+	for m.stopper.Sleep(m.config.DigestInterval) {
+		m.flushDigests()
+	}
+	// batched notifications are not lost on shutdown
+	m.flushDigests()
+}
+
+// flushDigests - sends a digest of every batch, failed digests are retried like other
+// notifications
+func (m *DefaultNotificationSender) flushDigests() {
+	m.configM.RLock()
+	defer m.configM.RUnlock()
+
+	senders := m.Senders()
+	for _, batch := range m.digests.take() {
+		sender, ok := senders[batch.sender]
+		if !ok {
+			// sender was unregistered in the meantime
+			continue
+		}
+
+		event := batch.event(m.config.DigestInterval)
+		if err := sender.Send(event); err != nil {
+			log.WithError(err).WithFields(log.Fields{logSenderName: batch.sender, logNotiName: event.Name}).Error("could not send notification digest via notifier")
+			m.queueRetry(&retryItem{sender: batch.sender, event: event, attempts: 1, err: err})
+		}
+	}
+}
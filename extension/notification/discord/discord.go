@@ -0,0 +1,129 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// apiURL - Discord REST API, see https://discord.com/developers/docs/reference
+const apiURL = "https://discord.com/api/v10"
+
+type sender struct {
+	apiURL   string
+	token    string
+	channels []string
+	client   *http.Client
+}
+
+func init() {
+	notification.RegisterSender("discord", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.token = os.Getenv(constants.EnvDiscordBotToken)
+	if s.token == "" || os.Getenv(constants.EnvDiscordChannels) == "" {
+		return false, nil
+	}
+	s.channels = strings.Split(os.Getenv(constants.EnvDiscordChannels), ",")
+	s.apiURL = apiURL
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":     "discord",
+		"channels": s.channels,
+	}).Info("extension.notification.discord: sender configured")
+
+	return true, nil
+}
+
+type message struct {
+	Embeds []*embed `json:"embeds"`
+}
+
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Color       int          `json:"color"`
+	Footer      *embedFooter `json:"footer"`
+	Timestamp   string       `json:"timestamp"`
+}
+
+type embedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url"`
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	color, _ := strconv.ParseInt(strings.TrimPrefix(event.Level.Color(), "#"), 16, 32)
+	body, err := json.Marshal(&message{
+		Embeds: []*embed{
+			{
+				Title:       event.Type.String(),
+				Description: event.Message,
+				Color:       int(color),
+				Footer: &embedFooter{
+					Text:    fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version),
+					IconURL: constants.KeelLogoURL,
+				},
+				Timestamp: event.CreatedAt.UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	chans := s.channels
+	if len(event.Channels) > 0 {
+		chans = event.Channels
+	}
+
+	for _, channel := range chans {
+		err := s.post(channel, body)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"channel": channel,
+			}).Error("extension.notification.discord: failed to send notification")
+		}
+	}
+	return nil
+}
+
+func (s *sender) post(channel string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.apiURL+"/channels/"+channel+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
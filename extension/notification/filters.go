@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// Filter - notifications the sender receives, level overrides the global notification
+// level and types (ie: "deployment update", "deployment rollback") limit notification types,
+// all types are sent when empty
+type Filter struct {
+	Sender string   `json:"sender"`
+	Level  string   `json:"level,omitempty"`
+	Types  []string `json:"types,omitempty"`
+
+	level *types.Level
+	types map[string]bool
+}
+
+// Filters - notification filters by sender name
+type Filters struct {
+	filters map[string]*Filter
+}
+
+// LoadFilters - reads notification filters from a YAML file, ie:
+//
+//   - sender: slack
+//     level: success
+//     types: [deployment update, deployment rollback]
+func LoadFilters(path string) (*Filters, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filters: %s", err)
+	}
+	return ParseFilters(b)
+}
+
+// ParseFilters - parses YAML or JSON list of notification filters
+func ParseFilters(b []byte) (*Filters, error) {
+	var list []*Filter
+	if err := yaml.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse filters: %s", err)
+	}
+
+	known := make(map[string]bool)
+	for _, t := range typeNames() {
+		known[t] = true
+	}
+
+	filters := &Filters{filters: make(map[string]*Filter)}
+	for i, f := range list {
+		if f.Sender == "" {
+			return nil, fmt.Errorf("filter %d: sender is required", i)
+		}
+		if _, ok := filters.filters[f.Sender]; ok {
+			return nil, fmt.Errorf("filter %d: duplicate filter for sender '%s'", i, f.Sender)
+		}
+
+		if f.Level != "" {
+			level, err := types.ParseLevel(f.Level)
+			if err != nil {
+				return nil, fmt.Errorf("filter %d: %s", i, err)
+			}
+			f.level = &level
+		}
+
+		if len(f.Types) > 0 {
+			f.types = make(map[string]bool)
+			for _, t := range f.Types {
+				if !known[t] {
+					return nil, fmt.Errorf("filter %d: unknown notification type '%s'", i, t)
+				}
+				f.types[t] = true
+			}
+		}
+
+		filters.filters[f.Sender] = f
+	}
+	return filters, nil
+}
+
+// Allows - whether the notification should be sent by the sender, notifications below
+// the level are dropped unless the sender filter sets its own level
+func (f *Filters) Allows(sender string, event types.EventNotification, level types.Level) bool {
+	if f != nil {
+		if filter, ok := f.filters[sender]; ok {
+			if filter.level != nil {
+				level = *filter.level
+			}
+			if filter.types != nil && !filter.types[typeName(event.Type)] {
+				return false
+			}
+		}
+	}
+	return event.Level >= level
+}
@@ -1,9 +1,14 @@
 package mail
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/smtp"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/keel-hq/keel/constants"
 	"github.com/keel-hq/keel/extension/notification"
@@ -12,13 +17,30 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const timeout = 10 * time.Second
+
+// TLS modes
+const (
+	// TLSAuto - STARTTLS when the server supports it
+	TLSAuto = ""
+	// TLSStartTLS - STARTTLS is required
+	TLSStartTLS = "starttls"
+	// TLSImplicit - connection is encrypted from the start, usually port 465
+	TLSImplicit = "tls"
+	// TLSNone - plain text connection
+	TLSNone = "none"
+)
+
 type sender struct {
 	from       string
-	to         string
+	to         []string
+	toLevels   map[types.Level][]string
 	smtpServer string
 	smtpPort   int
 	smtpUser   string
 	smtpPass   string
+	tlsMode    string
+	templates  *templates
 }
 
 func init() {
@@ -26,7 +48,7 @@ func init() {
 }
 
 func (s *sender) Configure(config *notification.Config) (bool, error) {
-	// Server, from and to are mandatory
+	// Server, from and recipients are mandatory
 	if os.Getenv(constants.EnvMailSmtpServer) != "" {
 		s.smtpServer = os.Getenv(constants.EnvMailSmtpServer)
 	} else {
@@ -37,11 +59,23 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 	} else {
 		return false, nil
 	}
-	if os.Getenv(constants.EnvMailTo) != "" {
-		s.to = os.Getenv(constants.EnvMailTo)
-	} else {
+	s.to = splitAddresses(os.Getenv(constants.EnvMailTo))
+	toLevels, err := ParseLevelRecipients(os.Getenv(constants.EnvMailToLevels))
+	if err != nil {
+		return false, err
+	}
+	s.toLevels = toLevels
+	if len(s.to) == 0 && len(s.toLevels) == 0 {
 		return false, nil
 	}
+
+	s.tlsMode = strings.ToLower(os.Getenv(constants.EnvMailSmtpTLS))
+	switch s.tlsMode {
+	case TLSAuto, TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return false, fmt.Errorf("unknown SMTP TLS mode '%s', expected 'starttls', 'tls' or 'none'", s.tlsMode)
+	}
+
 	// Port, user and pass are optional
 	if os.Getenv(constants.EnvMailSmtpPort) != "" {
 		port, err := strconv.Atoi(os.Getenv(constants.EnvMailSmtpPort))
@@ -52,6 +86,8 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 			return false, nil
 		}
 		s.smtpPort = port
+	} else if s.tlsMode == TLSImplicit {
+		s.smtpPort = 465
 	} else {
 		s.smtpPort = 25
 	}
@@ -62,33 +98,82 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 		s.smtpPass = os.Getenv(constants.EnvMailSmtpPass)
 	}
 
+	s.templates, err = loadTemplates(
+		os.Getenv(constants.EnvMailSubjectTemplate),
+		os.Getenv(constants.EnvMailTextTemplate),
+		os.Getenv(constants.EnvMailHTMLTemplate),
+	)
+	if err != nil {
+		return false, err
+	}
+
 	log.WithFields(log.Fields{
 		"name": "mail",
+		"tls":  s.tlsMode,
 	}).Info("extension.notification.mail: sender configured")
 
 	return true, nil
 }
 
+// ParseLevelRecipients - parses "level=address,address;level=address" recipient lists
+func ParseLevelRecipients(s string) (map[types.Level][]string, error) {
+	recipients := make(map[types.Level][]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid recipients '%s', expected level=address,address", entry)
+		}
+		level, err := types.ParseLevel(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		recipients[level] = append(recipients[level], splitAddresses(parts[1])...)
+	}
+	return recipients, nil
+}
+
+func splitAddresses(s string) []string {
+	var addresses []string
+	for _, address := range strings.Split(s, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// recipients - default recipients and recipients of the event level, without duplicates
+func (s *sender) recipients(level types.Level) []string {
+	seen := make(map[string]bool)
+	var to []string
+	for _, address := range append(append([]string{}, s.to...), s.toLevels[level]...) {
+		if seen[strings.ToLower(address)] {
+			continue
+		}
+		seen[strings.ToLower(address)] = true
+		to = append(to, address)
+	}
+	return to
+}
+
 func (s *sender) Send(event types.EventNotification) error {
-	body := event.CreatedAt.String() + "\n" + event.Level.String() + "-" +
-		event.Type.String() + "\n" + event.Message
-	msg := "From: " + s.from + "\n" +
-		"To: " + s.to + "\n" +
-		"Subject: Keel notification\n\n" +
-		body
+	to := s.recipients(event.Level)
+	if len(to) == 0 {
+		return nil
+	}
 
-	// Support only plain auth
-	var auth smtp.Auth = nil
-	if s.smtpUser != "" {
-		auth = smtp.PlainAuth(
-			"",
-			s.smtpUser,
-			s.smtpPass,
-			s.smtpServer,
-		)
+	msg, err := s.templates.message(s.from, to, event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("extension.notification.mail: failed to create message")
+		return nil
 	}
 
-	err := smtp.SendMail(s.smtpServer+":"+strconv.Itoa(s.smtpPort), auth, s.from, []string{s.to}, []byte(msg))
+	err = s.send(to, msg)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -97,3 +182,69 @@ func (s *sender) Send(event types.EventNotification) error {
 
 	return nil
 }
+
+func (s *sender) send(to []string, msg []byte) error {
+	addr := net.JoinHostPort(s.smtpServer, strconv.Itoa(s.smtpPort))
+	tlsConfig := &tls.Config{ServerName: s.smtpServer}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: timeout}
+	if s.tlsMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, s.smtpServer)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.tlsMode == TLSAuto || s.tlsMode == TLSStartTLS {
+		ok, _ := c.Extension("STARTTLS")
+		if !ok && s.tlsMode == TLSStartTLS {
+			return fmt.Errorf("server %s doesn't support STARTTLS", s.smtpServer)
+		}
+		if ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Support only plain auth
+	if s.smtpUser != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.smtpUser, s.smtpPass, s.smtpServer)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := c.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
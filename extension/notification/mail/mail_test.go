@@ -0,0 +1,191 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+// fakeSMTPServer - accepts a single message, returns recipients and data
+func fakeSMTPServer(t *testing.T) (port int, received chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	received = make(chan []string, 1)
+
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		write := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		write("220 localhost ESMTP")
+
+		var rcpts []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				write("250 localhost")
+			case strings.HasPrefix(line, "MAIL FROM"):
+				write("250 OK")
+			case strings.HasPrefix(line, "RCPT TO:"):
+				rcpts = append(rcpts, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+				write("250 OK")
+			case line == "DATA":
+				write("354 go ahead")
+				var data []string
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.TrimSpace(l) == "." {
+						break
+					}
+					data = append(data, l)
+				}
+				write("250 OK")
+				received <- append(rcpts, strings.Join(data, ""))
+			case line == "QUIT":
+				write("221 bye")
+				return
+			default:
+				write("502 not implemented")
+			}
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSendMail(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+
+	tmpl, err := loadTemplates("", "", "")
+	if err != nil {
+		t.Fatalf("failed to load templates: %s", err)
+	}
+	s := &sender{
+		from:       "keel@example.com",
+		to:         []string{"dev@example.com"},
+		toLevels:   map[types.Level][]string{types.LevelError: {"oncall@example.com", "DEV@example.com"}},
+		smtpServer: "127.0.0.1",
+		smtpPort:   port,
+		tlsMode:    TLSAuto,
+		templates:  tmpl,
+	}
+
+	s.Send(types.EventNotification{
+		Name:      "update resource",
+		Message:   "Deployment default/wd update 1.0.0->1.1.0 failed, error: <timeout>",
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDeploymentUpdate,
+		Level:     types.LevelError,
+		Metadata: map[string]string{
+			"namespace": "default",
+			"name":      "wd",
+			"new":       "1.1.0",
+		},
+	})
+
+	var result []string
+	select {
+	case result = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message not received")
+	}
+
+	rcpts, data := result[:len(result)-1], result[len(result)-1]
+	if strings.Join(rcpts, ",") != "dev@example.com,oncall@example.com" {
+		t.Errorf("unexpected recipients: %v", rcpts)
+	}
+
+	for _, expected := range []string{
+		"Subject: [Keel] deployment update default/wd 1.1.0",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"Resource: default/wd",
+		"error: &lt;timeout",
+	} {
+		if !strings.Contains(data, expected) {
+			t.Errorf("expected %q in message:\n%s", expected, data)
+		}
+	}
+}
+
+func TestSendMailStartTLSRequired(t *testing.T) {
+	port, _ := fakeSMTPServer(t)
+
+	tmpl, _ := loadTemplates("", "", "")
+	s := &sender{
+		from:       "keel@example.com",
+		to:         []string{"dev@example.com"},
+		smtpServer: "127.0.0.1",
+		smtpPort:   port,
+		tlsMode:    TLSStartTLS,
+		templates:  tmpl,
+	}
+
+	err := s.send([]string{"dev@example.com"}, []byte("test"))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected STARTTLS error, got: %v", err)
+	}
+}
+
+func TestParseLevelRecipients(t *testing.T) {
+	recipients, err := ParseLevelRecipients("error=oncall@example.com; success = releases@example.com, dev@example.com;")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Join(recipients[types.LevelError], ",") != "oncall@example.com" {
+		t.Errorf("unexpected error recipients: %v", recipients[types.LevelError])
+	}
+	if strings.Join(recipients[types.LevelSuccess], ",") != "releases@example.com,dev@example.com" {
+		t.Errorf("unexpected success recipients: %v", recipients[types.LevelSuccess])
+	}
+
+	for _, invalid := range []string{"oncall@example.com", "critical=oncall@example.com"} {
+		if _, err := ParseLevelRecipients(invalid); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestSubjectTemplate(t *testing.T) {
+	tmpl, err := loadTemplates("{{ .Level }}: {{ .Name }}", "", "")
+	if err != nil {
+		t.Fatalf("failed to load templates: %s", err)
+	}
+	msg, err := tmpl.message("keel@example.com", []string{"dev@example.com"}, types.EventNotification{
+		Name:  "approval reminder",
+		Level: types.LevelWarn,
+	})
+	if err != nil {
+		t.Fatalf("failed to create message: %s", err)
+	}
+	if !strings.Contains(string(msg), "Subject: warn: approval reminder\r\n") {
+		t.Errorf("unexpected subject: %s", msg)
+	}
+
+	if _, err := loadTemplates("{{ .Level ", "", ""); err == nil {
+		t.Errorf("expected invalid template error")
+	}
+	if _, err := loadTemplates("", "/does/not/exist", ""); err == nil {
+		t.Errorf("expected missing template file error")
+	}
+}
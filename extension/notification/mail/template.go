@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+)
+
+const defaultSubjectTemplate = `[Keel] {{ .Type }}{{ with .Metadata.namespace }} {{ . }}/{{ $.Metadata.name }}{{ end }}{{ with .Metadata.new }} {{ . }}{{ end }}`
+
+const defaultTextTemplate = `{{ .Message }}
+
+Level:    {{ .Level }}
+Type:     {{ .Type }}
+{{- with .Metadata.cluster }}
+Cluster:  {{ . }}{{ end }}
+{{- with .Metadata.namespace }}
+Resource: {{ . }}/{{ $.Metadata.name }}{{ end }}
+{{- with .Metadata.image }}
+Image:    {{ . }}{{ end }}
+{{- with .Metadata.previous }}
+Old tag:  {{ . }}{{ end }}
+{{- with .Metadata.new }}
+New tag:  {{ . }}{{ end }}
+Time:     {{ .CreatedAt.UTC.Format "2006-01-02 15:04:05 MST" }}
+
+--
+https://keel.sh {{ .Version }}
+`
+
+const defaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; font-size: 14px;">
+<h3 style="color: {{ .Level.Color }};">{{ .Type }}</h3>
+<p>{{ .Message }}</p>
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><td><b>Level</b></td><td>{{ .Level }}</td></tr>
+{{- with .Metadata.cluster }}
+<tr><td><b>Cluster</b></td><td>{{ . }}</td></tr>{{ end }}
+{{- with .Metadata.namespace }}
+<tr><td><b>Resource</b></td><td>{{ . }}/{{ $.Metadata.name }}</td></tr>{{ end }}
+{{- with .Metadata.image }}
+<tr><td><b>Image</b></td><td><code>{{ . }}</code></td></tr>{{ end }}
+{{- with .Metadata.previous }}
+<tr><td><b>Old tag</b></td><td><code>{{ . }}</code></td></tr>{{ end }}
+{{- with .Metadata.new }}
+<tr><td><b>New tag</b></td><td><code>{{ . }}</code></td></tr>{{ end }}
+<tr><td><b>Time</b></td><td>{{ .CreatedAt.UTC.Format "2006-01-02 15:04:05 MST" }}</td></tr>
+</table>
+<p style="color: #9E9E9E; font-size: 12px;">https://keel.sh {{ .Version }}</p>
+</body>
+</html>
+`
+
+// templateData - notification fields available to templates, metadata keys set by the
+// kubernetes provider are "cluster", "namespace", "name", "image", "previous" and "new"
+type templateData struct {
+	types.EventNotification
+	Version string
+}
+
+type templates struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+// loadTemplates - parses subject template and text, HTML template files, defaults are
+// used for the ones that aren't set
+func loadTemplates(subject, textFile, htmlFile string) (*templates, error) {
+	if subject == "" {
+		subject = defaultSubjectTemplate
+	}
+	text, err := readTemplate(textFile, defaultTextTemplate)
+	if err != nil {
+		return nil, err
+	}
+	html, err := readTemplate(htmlFile, defaultHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &templates{}
+	if t.subject, err = template.New("subject").Parse(subject); err != nil {
+		return nil, fmt.Errorf("invalid subject template: %s", err)
+	}
+	if t.text, err = template.New("text").Parse(text); err != nil {
+		return nil, fmt.Errorf("invalid text template: %s", err)
+	}
+	if t.html, err = htmltemplate.New("html").Parse(html); err != nil {
+		return nil, fmt.Errorf("invalid HTML template: %s", err)
+	}
+	return t, nil
+}
+
+func readTemplate(path, def string) (string, error) {
+	if path == "" {
+		return def, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %s", err)
+	}
+	return string(b), nil
+}
+
+// message - multipart/alternative message with plain text and HTML bodies
+func (t *templates) message(from string, to []string, event types.EventNotification) ([]byte, error) {
+	data := &templateData{EventNotification: event, Version: version.GetKeelVersion().Version}
+
+	subject := &bytes.Buffer{}
+	if err := t.subject.Execute(subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %s", err)
+	}
+	text := &bytes.Buffer{}
+	if err := t.text.Execute(text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text body: %s", err)
+	}
+	html := &bytes.Buffer{}
+	if err := t.html.Execute(html, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML body: %s", err)
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		qw.Write(part.content)
+		qw.Close()
+	}
+	w.Close()
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
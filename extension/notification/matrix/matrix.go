@@ -0,0 +1,172 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+type sender struct {
+	homeserver string
+	token      string
+	rooms      []string
+	client     *http.Client
+
+	// txnPrefix and txnCounter - transaction IDs have to be unique per access token
+	txnPrefix  string
+	txnCounter uint64
+}
+
+func init() {
+	notification.RegisterSender("matrix", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.homeserver = strings.TrimSuffix(os.Getenv(constants.EnvMatrixHomeserverURL), "/")
+	s.token = os.Getenv(constants.EnvMatrixAccessToken)
+	if s.homeserver == "" || s.token == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(s.homeserver); err != nil {
+		return false, fmt.Errorf("could not parse homeserver URL: %s", err)
+	}
+
+	s.rooms = nil
+	for _, room := range strings.Split(os.Getenv(constants.EnvMatrixRooms), ",") {
+		if room = strings.TrimSpace(room); room != "" {
+			s.rooms = append(s.rooms, room)
+		}
+	}
+	if len(s.rooms) == 0 {
+		return false, fmt.Errorf("%s is required", constants.EnvMatrixRooms)
+	}
+
+	s.txnPrefix = fmt.Sprintf("keel-%d", time.Now().UnixNano())
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":       "matrix",
+		"homeserver": s.homeserver,
+		"rooms":      s.rooms,
+	}).Info("extension.notification.matrix: sender configured")
+
+	return true, nil
+}
+
+// roomMessage - m.room.message event content, see
+// https://spec.matrix.org/v1.8/client-server-api/#mroommessage
+type roomMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// formatMessage - plain text body and HTML formatted body with the notification type as
+// the title followed by the message and the resource and image details
+func formatMessage(event types.EventNotification) *roomMessage {
+	facts := notification.Facts(event)
+
+	text := &bytes.Buffer{}
+	fmt.Fprintf(text, "%s\n%s\n", event.Type.String(), event.Message)
+	for _, f := range facts {
+		fmt.Fprintf(text, "%s: %s\n", f.Title, f.Value)
+	}
+	text.WriteString(notification.Footer())
+
+	formatted := &bytes.Buffer{}
+	fmt.Fprintf(formatted, `<h4><font color="%s">%s</font></h4>`, event.Level.Color(), html.EscapeString(event.Type.String()))
+	fmt.Fprintf(formatted, "<p>%s</p>", html.EscapeString(event.Message))
+	if len(facts) > 0 {
+		formatted.WriteString("<ul>")
+		for _, f := range facts {
+			fmt.Fprintf(formatted, "<li><strong>%s:</strong> <code>%s</code></li>", html.EscapeString(f.Title), html.EscapeString(f.Value))
+		}
+		formatted.WriteString("</ul>")
+	}
+	fmt.Fprintf(formatted, "<p><sub>%s</sub></p>", html.EscapeString(notification.Footer()))
+
+	return &roomMessage{
+		// notices are not answered by bots and are usually displayed less prominently
+		MsgType:       "m.notice",
+		Body:          text.String(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted.String(),
+	}
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	rooms := s.rooms
+	if len(event.Channels) > 0 {
+		rooms = event.Channels
+	}
+
+	body, err := json.Marshal(formatMessage(event))
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	failed := &notification.ChannelsError{}
+	for _, room := range rooms {
+		err := s.send(room, body)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"room":  room,
+			}).Error("extension.notification.matrix: failed to send notification")
+			failed.Channels = append(failed.Channels, room)
+			failed.Err = err
+		}
+	}
+	if len(failed.Channels) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func (s *sender) send(room string, body []byte) error {
+	txnID := fmt.Sprintf("%s-%d", s.txnPrefix, atomic.AddUint64(&s.txnCounter, 1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		s.homeserver, url.PathEscape(room), url.PathEscape(txnID))
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("got status %d, expected 2xx: %s %s", resp.StatusCode, apiErr.ErrCode, apiErr.Error)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestMatrixSend(t *testing.T) {
+	var paths []string
+	var received roomMessage
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut {
+			t.Errorf("unexpected method: %s", req.Method)
+		}
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected authorization: %s", req.Header.Get("Authorization"))
+		}
+		paths = append(paths, req.URL.EscapedPath())
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		resp.Write([]byte(`{"event_id":"$event"}`))
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	s := &sender{
+		homeserver: ts.URL,
+		token:      "secret",
+		rooms:      []string{"!ops:example.org"},
+		client:     &http.Client{},
+		txnPrefix:  "keel-test",
+	}
+
+	event := types.EventNotification{
+		Message: "Successfully updated deployment default/wd 1.0.0->1.1.0 <b>",
+		Type:    types.NotificationDeploymentUpdate,
+		Level:   types.LevelSuccess,
+		Metadata: map[string]string{
+			"namespace": "default",
+			"name":      "wd",
+			"new":       "1.1.0",
+		},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	event.Channels = []string{"!a:example.org", "!b:example.org"}
+	s.Send(event)
+
+	expected := []string{
+		"/_matrix/client/v3/rooms/%21ops:example.org/send/m.room.message/keel-test-1",
+		"/_matrix/client/v3/rooms/%21a:example.org/send/m.room.message/keel-test-2",
+		"/_matrix/client/v3/rooms/%21b:example.org/send/m.room.message/keel-test-3",
+	}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+
+	if received.MsgType != "m.notice" || received.Format != "org.matrix.custom.html" {
+		t.Errorf("unexpected message: %+v", received)
+	}
+	if !strings.Contains(received.Body, "New tag: 1.1.0") {
+		t.Errorf("unexpected body: %s", received.Body)
+	}
+	if !strings.Contains(received.FormattedBody, "1.1.0 &lt;b&gt;</p>") ||
+		!strings.Contains(received.FormattedBody, "<li><strong>Name:</strong> <code>wd</code></li>") {
+		t.Errorf("unexpected formatted body: %s", received.FormattedBody)
+	}
+}
+
+func TestMatrixSendError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusForbidden)
+		resp.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"User not in room"}`))
+	}))
+	defer ts.Close()
+
+	s := &sender{homeserver: ts.URL, token: "secret", client: &http.Client{}}
+	err := s.send("!ops:example.org", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -3,12 +3,13 @@ package notification
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/stopper"
-	"github.com/keel-hq/keel/util/timeutil"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -31,9 +32,23 @@ var (
 // Config is the configuration for the Notifier service and its registered
 // notifiers.
 type Config struct {
+	// Attempts - how many times notification is sent before it's dead-lettered, failed
+	// notifications aren't retried when 0
 	Attempts int
 	Level    types.Level
-	Params   map[string]interface{} `yaml:",inline"`
+	// Templates - optional message templates, see LoadTemplates
+	Templates *Templates
+	// Filters - optional level and type filters of senders, see LoadFilters
+	Filters *Filters
+	// Routes - optional notification routes, webhook sender is enabled by routes with webhooks
+	Routes *Routes
+	// DeadLetterURL - optional endpoint notifications that couldn't be sent after all attempts
+	// are posted to, see DeadLetter
+	DeadLetterURL string
+	// DigestInterval - optional interval notifications are batched over, each sender gets
+	// a single summarized notification per channel instead of one per update
+	DigestInterval time.Duration
+	Params         map[string]interface{} `yaml:",inline"`
 }
 
 // Sender represents anything that can transmit notifications.
@@ -78,6 +93,17 @@ type DefaultNotificationSender struct {
 	config  *Config
 	stopper *stopper.Stopper
 	level   types.Level
+
+	// configM - held while sending so senders aren't reconfigured mid-send
+	configM sync.RWMutex
+
+	// retries - failed notifications, processed once the first one is queued
+	retries   retryQueue
+	retryOnce sync.Once
+
+	// digests - notifications batched in digest mode, sent every digest interval
+	digests    digestQueue
+	digestOnce sync.Once
 }
 
 // New - create new sender
@@ -105,6 +131,22 @@ func (m *DefaultNotificationSender) Configure(config *Config) (bool, error) {
 	return true, nil
 }
 
+// Reconfigure - configures registered senders again with the current configuration,
+// used when their settings (such as API tokens) change. Senders that failed initial
+// configuration were unregistered and stay disabled
+func (m *DefaultNotificationSender) Reconfigure() {
+	m.configM.Lock()
+	defer m.configM.Unlock()
+
+	for senderName, sender := range m.Senders() {
+		if _, err := sender.Configure(m.config); err != nil {
+			log.WithError(err).WithField(logSenderName, senderName).Error("could not reconfigure notifier")
+			continue
+		}
+		log.WithField(logSenderName, senderName).Info("notificationSender: sender reconfigured")
+	}
+}
+
 // Senders returns the list of the registered Senders.
 func (m *DefaultNotificationSender) Senders() map[string]Sender {
 	sendersM.RLock()
@@ -118,58 +160,46 @@ func (m *DefaultNotificationSender) Senders() map[string]Sender {
 	return ret
 }
 
-// Send - send notifications through all configured senders
+// Send - send notifications through all configured senders, senders without a filter
+// receive notifications of the configured level and above. Failed notifications are
+// retried in the background with exponential backoff up to the configured attempts.
+// In digest mode notifications are batched and sent every digest interval instead
 func (m *DefaultNotificationSender) Send(event types.EventNotification) error {
-	if event.Level < m.config.Level {
-		return nil
-	}
+	m.configM.RLock()
+	defer m.configM.RUnlock()
 
 	sendersM.RLock()
 	defer sendersM.RUnlock()
 
+	var failed []string
 	for senderName, sender := range m.Senders() {
-		// TODO: move this into goroutine if we have enough senders
-		var attempts int
-		var backOff time.Duration
-		for {
-			// Max attempts exceeded.
-			if attempts >= m.config.Attempts {
-				log.WithFields(log.Fields{
-					logNotiName:    event.Name,
-					logSenderName:  senderName,
-					"max attempts": m.config.Attempts,
-				}).Info("giving up on sending notification : max attempts exceeded")
-				return fmt.Errorf("failed to send notification, max attempts (%d) reached", m.config.Attempts)
-			}
+		if !m.config.Filters.Allows(senderName, event, m.config.Level) {
+			continue
+		}
 
-			// Backoff
-			if backOff > 0 {
-				log.WithFields(log.Fields{
-					"duration":     backOff,
-					logNotiName:    event.Name,
-					logSenderName:  senderName,
-					"attempts":     attempts + 1,
-					"max attempts": m.config.Attempts,
-				}).Info("waiting before retrying to send notification")
-				if !m.stopper.Sleep(backOff) {
-					return nil
-				}
-			}
+		// original message is sent when the template fails
+		event, err := m.config.Templates.Render(senderName, event)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{logSenderName: senderName, logNotiName: event.Name}).Error("could not render notification message template")
+		}
 
-			// Send using the current notifier.
-			if err := sender.Send(event); err != nil {
-				// Send failed; increase attempts/backoff and retry.
-				log.WithError(err).WithFields(log.Fields{logSenderName: senderName, logNotiName: event.Name}).Error("could not send notification via notifier")
-				backOff = timeutil.ExpBackoff(backOff, notifierMaxBackOff)
-				attempts++
-				continue
-			}
+		// audit log records every notification as it happens
+		if m.config.DigestInterval > 0 && senderName != auditorSender {
+			m.queueDigest(senderName, event)
+			continue
+		}
 
-			// Send has been successful. Go to the next notifier.
-			break
+		if err := sender.Send(event); err != nil {
+			log.WithError(err).WithFields(log.Fields{logSenderName: senderName, logNotiName: event.Name}).Error("could not send notification via notifier")
+			failed = append(failed, senderName)
+			m.queueRetry(&retryItem{sender: senderName, event: event, attempts: 1, err: err})
 		}
 	}
 
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed to send notification via %s", strings.Join(failed, ", "))
+	}
 	return nil
 }
 
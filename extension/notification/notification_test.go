@@ -2,8 +2,12 @@ package notification
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/keel-hq/keel/types"
 )
@@ -13,9 +17,11 @@ type fakeSender struct {
 
 	shouldConfigure bool
 	shouldError     error
+	configured      int
 }
 
 func (s *fakeSender) Configure(*Config) (bool, error) {
+	s.configured++
 	return s.shouldConfigure, nil
 }
 
@@ -162,3 +168,458 @@ func TestSendLevelNotificationC(t *testing.T) {
 		t.Errorf("unexpected level: %s", fs.sent.Level)
 	}
 }
+
+func TestReconfigure(t *testing.T) {
+	fs := &fakeSender{shouldConfigure: true}
+	RegisterSender("fakeSender", fs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{
+		Level:    types.LevelInfo,
+		Attempts: 1,
+	})
+	defer sndr.UnregisterSender("fakeSender")
+
+	sndr.Reconfigure()
+	if fs.configured != 2 {
+		t.Errorf("expected sender to be configured again, got %d", fs.configured)
+	}
+
+	if err := sndr.Send(types.EventNotification{Level: types.LevelInfo, Message: "foo"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if fs.sent == nil || fs.sent.Message != "foo" {
+		t.Errorf("expected notification to be sent after reconfiguration")
+	}
+}
+
+func TestAlertKey(t *testing.T) {
+	tests := []struct {
+		event types.EventNotification
+		want  string
+	}{
+		{
+			event: types.EventNotification{Metadata: map[string]string{"cluster": "prod", "namespace": "default", "name": "wd", "image": "karolisr/webhook-demo:1.1.0"}},
+			want:  "keel:prod:default/wd:karolisr/webhook-demo:1.1.0",
+		},
+		{
+			event: types.EventNotification{Identifier: "deployment/default/wd", Metadata: map[string]string{"namespace": "default", "name": "wd"}},
+			want:  "keel:default/wd",
+		},
+		{
+			event: types.EventNotification{Identifier: "release/default/wd", Name: "update release"},
+			want:  "keel:release/default/wd",
+		},
+		{
+			event: types.EventNotification{Name: "provider error"},
+			want:  "keel:provider error",
+		},
+	}
+	for _, tt := range tests {
+		if got := AlertKey(tt.event); got != tt.want {
+			t.Errorf("AlertKey() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestOpenAlerts(t *testing.T) {
+	var alerts OpenAlerts
+	if alerts.Remove("a") {
+		t.Errorf("unexpected alert")
+	}
+	alerts.Add("a")
+	if !alerts.Remove("a") {
+		t.Errorf("expected alert to be open")
+	}
+	if alerts.Remove("a") {
+		t.Errorf("expected alert to be removed")
+	}
+}
+
+func TestFacts(t *testing.T) {
+	facts := Facts(types.EventNotification{
+		Metadata: map[string]string{"provider": "kubernetes", "new": "1.1.0", "namespace": "default", "name": "wd"},
+	})
+	expected := []Fact{{Title: "Namespace", Value: "default"}, {Title: "Name", Value: "wd"}, {Title: "New tag", Value: "1.1.0"}}
+	if len(facts) != len(expected) {
+		t.Fatalf("unexpected facts: %+v", facts)
+	}
+	for i := range expected {
+		if facts[i] != expected[i] {
+			t.Errorf("fact %d: expected %+v, got %+v", i, expected[i], facts[i])
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := ParseTemplates([]byte(`
+- type: deployment update
+  sender: slack
+  message: ":rocket: {{ .Namespace }}/{{ .Name }} {{ .Previous }} -> {{ .New }}{{ with .ApprovedBy }} (approved by {{ . }}){{ end }}"
+- type: update rejected
+  message: "rejected"
+- message: "[{{ .Level }}] {{ .Message }}"
+`))
+	if err != nil {
+		t.Fatalf("failed to parse templates: %s", err)
+	}
+
+	event := types.EventNotification{
+		Message: "Successfully updated deployment default/wd 1.0.0->1.1.0",
+		Type:    types.NotificationDeploymentUpdate,
+		Level:   types.LevelSuccess,
+		Metadata: map[string]string{
+			"namespace":  "default",
+			"name":       "wd",
+			"previous":   "1.0.0",
+			"new":        "1.1.0",
+			"approvedBy": "jane",
+		},
+	}
+
+	for _, tt := range []struct {
+		sender   string
+		event    types.EventNotification
+		expected string
+	}{
+		{sender: "slack", event: event, expected: ":rocket: default/wd 1.0.0 -> 1.1.0 (approved by jane)"},
+		{sender: "teams", event: event, expected: "[success] Successfully updated deployment default/wd 1.0.0->1.1.0"},
+		{sender: "slack", event: types.EventNotification{Type: types.NotificationUpdateRejected}, expected: "rejected"},
+		{sender: "auditor", event: event, expected: event.Message},
+	} {
+		rendered, err := templates.Render(tt.sender, tt.event)
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		if rendered.Message != tt.expected {
+			t.Errorf("%s %s: expected %q, got %q", tt.sender, tt.event.Type, tt.expected, rendered.Message)
+		}
+	}
+
+	var none *Templates
+	if rendered, _ := none.Render("slack", event); rendered.Message != event.Message {
+		t.Errorf("unexpected message without templates: %s", rendered.Message)
+	}
+}
+
+func TestParseTemplatesInvalid(t *testing.T) {
+	for _, invalid := range []string{
+		`- type: deployment updated
+  message: "x"`,
+		`- type: deployment update`,
+		`- message: "{{ .Name "`,
+	} {
+		if _, err := ParseTemplates([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestSendTemplate(t *testing.T) {
+	fs := &fakeSender{shouldConfigure: true}
+	RegisterSender("templated", fs)
+
+	templates, _ := ParseTemplates([]byte(`[{"sender": "templated", "message": "{{ upper .Message }}"}]`))
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 1, Level: types.LevelDebug, Templates: templates})
+	defer sndr.UnregisterSender("templated")
+
+	sndr.Send(types.EventNotification{Message: "updated", Level: types.LevelInfo})
+	if fs.sent == nil || fs.sent.Message != "UPDATED" {
+		t.Errorf("unexpected notification: %+v", fs.sent)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	filters, err := ParseFilters([]byte(`
+- sender: pagerduty
+  types: [deployment update, deployment rollback]
+- sender: webhook
+  level: debug
+- sender: mail
+  level: error
+  types: [deployment update]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse filters: %s", err)
+	}
+
+	for _, tt := range []struct {
+		sender   string
+		event    types.EventNotification
+		expected bool
+	}{
+		{sender: "slack", event: types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelDebug}, expected: false},
+		{sender: "slack", event: types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelInfo}, expected: true},
+		{sender: "webhook", event: types.EventNotification{Type: types.NotificationPreDeploymentUpdate, Level: types.LevelDebug}, expected: true},
+		{sender: "pagerduty", event: types.EventNotification{Type: types.NotificationDeploymentRollback, Level: types.LevelError}, expected: true},
+		{sender: "pagerduty", event: types.EventNotification{Type: types.NotificationSignatureRejected, Level: types.LevelError}, expected: false},
+		{sender: "mail", event: types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelSuccess}, expected: false},
+		{sender: "mail", event: types.EventNotification{Type: types.NotificationDeploymentUpdate, Level: types.LevelError}, expected: true},
+	} {
+		if allowed := filters.Allows(tt.sender, tt.event, types.LevelInfo); allowed != tt.expected {
+			t.Errorf("%s %s %s: expected %t, got %t", tt.sender, tt.event.Type, tt.event.Level, tt.expected, allowed)
+		}
+	}
+}
+
+func TestParseFiltersInvalid(t *testing.T) {
+	for _, invalid := range []string{
+		`- level: error`,
+		`- sender: slack
+  level: loud`,
+		`- sender: slack
+  types: [deployment updated]`,
+		`[{"sender": "slack"}, {"sender": "slack"}]`,
+	} {
+		if _, err := ParseFilters([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestSendFiltered(t *testing.T) {
+	fs := &fakeSender{shouldConfigure: true}
+	RegisterSender("filtered", fs)
+
+	filters, _ := ParseFilters([]byte(`[{"sender": "filtered", "level": "debug"}]`))
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 1, Level: types.LevelError, Filters: filters})
+	defer sndr.UnregisterSender("filtered")
+
+	sndr.Send(types.EventNotification{Message: "preparing", Level: types.LevelDebug})
+	if fs.sent == nil || fs.sent.Message != "preparing" {
+		t.Errorf("expected notification below the global level to be sent, got: %+v", fs.sent)
+	}
+}
+
+func TestParseRoutesInvalid(t *testing.T) {
+	for _, invalid := range []string{
+		`- channels: ["#team-a"]`,
+		`- namespaces: [team-a]`,
+		`- namespaces: [team-a]
+  webhooks: ["team-a"]`,
+	} {
+		if _, err := ParseRoutes([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+
+	routes, err := ParseRoutes([]byte(`[{"labels": {"team": "a", "tier": "web"}, "channels": ["#team-a"]}]`))
+	if err != nil {
+		t.Fatalf("failed to parse routes: %s", err)
+	}
+	if routes.Match("default", map[string]string{"team": "a"}) != nil {
+		t.Errorf("expected all labels to be required")
+	}
+	if routes.Match("default", map[string]string{"team": "a", "tier": "web", "app": "x"}) == nil {
+		t.Errorf("expected route to match")
+	}
+	if routes.HasWebhooks() {
+		t.Errorf("unexpected webhooks")
+	}
+}
+
+func TestSendRetryDeadLetter(t *testing.T) {
+	var deadLetters []DeadLetter
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var dl DeadLetter
+		if err := json.NewDecoder(req.Body).Decode(&dl); err != nil {
+			t.Errorf("failed to decode dead letter: %s", err)
+		}
+		deadLetters = append(deadLetters, dl)
+	}))
+	defer ts.Close()
+
+	fs := &fakeSender{shouldConfigure: true, shouldError: fmt.Errorf("got status 429")}
+	RegisterSender("failing", fs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 3, Level: types.LevelDebug, DeadLetterURL: ts.URL})
+	defer sndr.UnregisterSender("failing")
+	// retries are processed by the test
+	sndr.retryOnce.Do(func() {})
+
+	if err := sndr.Send(types.EventNotification{Name: "update resource", Message: "updated", Level: types.LevelSuccess}); err == nil {
+		t.Errorf("expected error")
+	}
+	if sndr.retries.len() != 1 {
+		t.Fatalf("expected notification to be queued, got %d", sndr.retries.len())
+	}
+
+	for i := 0; i < 2; i++ {
+		due := sndr.retries.due(time.Now().Add(notifierMaxBackOff))
+		if len(due) != 1 {
+			t.Fatalf("expected notification to be due, got %d", len(due))
+		}
+		sndr.retry(due[0])
+	}
+
+	if sndr.retries.len() != 0 {
+		t.Errorf("expected empty queue after max attempts, got %d", sndr.retries.len())
+	}
+	// dead letters are posted by the retries goroutine once sender locks are released
+	if len(deadLetters) != 0 {
+		t.Fatalf("expected dead letter to be queued, got %d posted", len(deadLetters))
+	}
+	sndr.sendDeadLetters()
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected a dead letter, got %d", len(deadLetters))
+	}
+	dl := deadLetters[0]
+	if dl.Sender != "failing" || dl.Attempts != 3 || dl.Error != "got status 429" || dl.Notification.Message != "updated" {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+}
+
+func TestSendNoRetries(t *testing.T) {
+	var deadLetters int
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		deadLetters++
+	}))
+	defer ts.Close()
+
+	fs := &fakeSender{shouldConfigure: true, shouldError: fmt.Errorf("got status 429")}
+	RegisterSender("failing", fs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Level: types.LevelDebug, DeadLetterURL: ts.URL})
+	defer sndr.UnregisterSender("failing")
+	sndr.retryOnce.Do(func() {})
+
+	if err := sndr.Send(types.EventNotification{Message: "updated", Level: types.LevelSuccess}); err == nil {
+		t.Errorf("expected error")
+	}
+	sndr.sendDeadLetters()
+	if sndr.retries.len() != 0 || deadLetters != 0 {
+		t.Errorf("expected failed notification to be dropped, got %d queued and %d dead letters", sndr.retries.len(), deadLetters)
+	}
+}
+
+func TestSendRetrySucceeds(t *testing.T) {
+	fs := &fakeSender{shouldConfigure: true, shouldError: fmt.Errorf("connection refused")}
+	RegisterSender("flaky", fs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 3, Level: types.LevelDebug})
+	defer sndr.UnregisterSender("flaky")
+	sndr.retryOnce.Do(func() {})
+
+	sndr.Send(types.EventNotification{Message: "updated", Level: types.LevelSuccess})
+
+	due := sndr.retries.due(time.Now())
+	if len(due) != 0 {
+		t.Errorf("expected retry to be delayed, got %d due", len(due))
+	}
+
+	fs.shouldError = nil
+	fs.sent = nil
+	for _, item := range sndr.retries.due(time.Now().Add(notifierMaxBackOff)) {
+		sndr.retry(item)
+	}
+	if fs.sent == nil || fs.sent.Message != "updated" {
+		t.Errorf("expected notification to be sent on retry, got: %+v", fs.sent)
+	}
+	if sndr.retries.len() != 0 {
+		t.Errorf("expected empty queue, got %d", sndr.retries.len())
+	}
+}
+
+type channelsSender struct {
+	sent [][]string
+	fail map[string]bool
+}
+
+func (s *channelsSender) Configure(*Config) (bool, error) { return true, nil }
+
+func (s *channelsSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event.Channels)
+	failed := &ChannelsError{}
+	for _, c := range event.Channels {
+		if s.fail[c] {
+			failed.Channels = append(failed.Channels, c)
+			failed.Err = fmt.Errorf("rate limited")
+		}
+	}
+	if len(failed.Channels) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func TestSendRetryFailedChannels(t *testing.T) {
+	cs := &channelsSender{fail: map[string]bool{"#b": true}}
+	RegisterSender("channels", cs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 3, Level: types.LevelDebug})
+	defer sndr.UnregisterSender("channels")
+	sndr.retryOnce.Do(func() {})
+
+	sndr.Send(types.EventNotification{Message: "updated", Level: types.LevelSuccess, Channels: []string{"#a", "#b"}})
+
+	cs.fail = nil
+	for _, item := range sndr.retries.due(time.Now().Add(notifierMaxBackOff)) {
+		sndr.retry(item)
+	}
+	if len(cs.sent) != 2 || fmt.Sprint(cs.sent[1]) != "[#b]" {
+		t.Errorf("expected only the failed channel to be retried, got %v", cs.sent)
+	}
+}
+
+type recordingSender struct {
+	sent []types.EventNotification
+}
+
+func (s *recordingSender) Configure(*Config) (bool, error) { return true, nil }
+
+func (s *recordingSender) Send(event types.EventNotification) error {
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func TestSendDigest(t *testing.T) {
+	rs := &recordingSender{}
+	RegisterSender("digest", rs)
+
+	sndr := New(context.Background())
+	sndr.Configure(&Config{Attempts: 1, Level: types.LevelDebug, DigestInterval: 15 * time.Minute})
+	defer sndr.UnregisterSender("digest")
+	// digests are flushed by the test
+	sndr.digestOnce.Do(func() {})
+
+	sndr.Send(types.EventNotification{Message: "updated a", Level: types.LevelSuccess, Type: types.NotificationDeploymentUpdate, Channels: []string{"#a"}})
+	sndr.Send(types.EventNotification{Message: "rolled back a", Level: types.LevelError, Type: types.NotificationDeploymentRollback, Channels: []string{"#a"}})
+	sndr.Send(types.EventNotification{Message: "updated b", Level: types.LevelSuccess, Type: types.NotificationDeploymentUpdate, Channels: []string{"#b"}})
+
+	if len(rs.sent) != 0 {
+		t.Fatalf("expected notifications to be batched, got %d sent", len(rs.sent))
+	}
+
+	sndr.flushDigests()
+	if len(rs.sent) != 2 {
+		t.Fatalf("expected a notification per channel, got %d", len(rs.sent))
+	}
+
+	digest := rs.sent[0]
+	if digest.Type != types.NotificationDigest || digest.Level != types.LevelError || fmt.Sprint(digest.Channels) != "[#a]" {
+		t.Errorf("unexpected digest: %+v", digest)
+	}
+	expected := "2 notifications in the last 15m0s:\n- deployment update: updated a\n- deployment rollback: rolled back a"
+	if digest.Message != expected {
+		t.Errorf("unexpected digest message: %q", digest.Message)
+	}
+	if digest.Metadata["count"] != "2" {
+		t.Errorf("unexpected count: %s", digest.Metadata["count"])
+	}
+
+	// single notification is sent as is
+	if rs.sent[1].Message != "updated b" || rs.sent[1].Type != types.NotificationDeploymentUpdate {
+		t.Errorf("unexpected notification: %+v", rs.sent[1])
+	}
+
+	sndr.flushDigests()
+	if len(rs.sent) != 2 {
+		t.Errorf("expected no notifications after flush, got %d", len(rs.sent))
+	}
+}
@@ -0,0 +1,173 @@
+package opsgenie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// apiURL - Opsgenie Alert API, see https://docs.opsgenie.com/docs/alert-api
+const apiURL = "https://api.opsgenie.com"
+
+// field limits, longer values are truncated by Opsgenie
+const (
+	maxMessageLength     = 130
+	maxAliasLength       = 512
+	maxDescriptionLength = 15000
+)
+
+type sender struct {
+	apiURL string
+	apiKey string
+	client *http.Client
+
+	open notification.OpenAlerts
+}
+
+func init() {
+	notification.RegisterSender("opsgenie", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.apiKey = os.Getenv(constants.EnvOpsgenieAPIKey)
+	if s.apiKey == "" {
+		return false, nil
+	}
+	s.apiURL = apiURL
+	if u := os.Getenv(constants.EnvOpsgenieAPIURL); u != "" {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return false, fmt.Errorf("could not parse API URL: %s", err)
+		}
+		s.apiURL = strings.TrimSuffix(u, "/")
+	}
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name": "opsgenie",
+		"url":  s.apiURL,
+	}).Info("extension.notification.opsgenie: sender configured")
+
+	return true, nil
+}
+
+type alert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Priority    string            `json:"priority"`
+}
+
+type closeRequest struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Send - error and fatal notifications create alerts, successful updates close alerts
+// created for the same resource and image
+func (s *sender) Send(e types.EventNotification) error {
+	alias := truncate(notification.AlertKey(e), maxAliasLength)
+
+	switch {
+	case e.Level >= types.LevelError:
+		err := s.post("/v2/alerts", newAlert(e, alias))
+		if err != nil {
+			return err
+		}
+		s.open.Add(alias)
+	case e.Level == types.LevelSuccess && s.open.Remove(alias):
+		return s.post("/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", &closeRequest{
+			Source: "keel",
+			Note:   e.Message,
+		})
+	}
+	return nil
+}
+
+func newAlert(e types.EventNotification, alias string) *alert {
+	priority := "P2"
+	if e.Level == types.LevelFatal {
+		priority = "P1"
+	}
+
+	var entity string
+	if e.Metadata["namespace"] != "" {
+		entity = e.Metadata["namespace"] + "/" + e.Metadata["name"]
+	}
+
+	tags := []string{"keel", e.Type.String()}
+	if cluster := e.Metadata["cluster"]; cluster != "" {
+		tags = append(tags, cluster)
+	}
+
+	details := map[string]string{"name": e.Name}
+	for k, v := range e.Metadata {
+		details[k] = v
+	}
+
+	return &alert{
+		Message:     truncate(e.Message, maxMessageLength),
+		Alias:       alias,
+		Description: truncate(e.Message, maxDescriptionLength),
+		Source:      "keel",
+		Entity:      entity,
+		Tags:        tags,
+		Details:     details,
+		Priority:    priority,
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
+func (s *sender) post(path string, in interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("got status %d, expected 2xx: %s", resp.StatusCode, apiErr.Message)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestCreateAndCloseAlert(t *testing.T) {
+	type request struct {
+		path  string
+		query string
+		auth  string
+		body  map[string]interface{}
+	}
+	var received []request
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		r := request{path: req.URL.EscapedPath(), query: req.URL.RawQuery, auth: req.Header.Get("Authorization")}
+		if err := json.NewDecoder(req.Body).Decode(&r.body); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		received = append(received, r)
+		resp.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	s := &sender{apiURL: ts.URL, apiKey: "key", client: &http.Client{}}
+
+	metadata := map[string]string{"cluster": "prod", "namespace": "default", "name": "wd", "image": "karolisr/webhook-demo:1.1.0"}
+	err := s.Send(types.EventNotification{
+		Name:     "update resource",
+		Message:  strings.Repeat("x", 200),
+		Type:     types.NotificationDeploymentUpdate,
+		Level:    types.LevelFatal,
+		Metadata: metadata,
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected alert, got %d requests", len(received))
+	}
+
+	create := received[0]
+	if create.path != "/v2/alerts" || create.auth != "GenieKey key" {
+		t.Errorf("unexpected request: %+v", create)
+	}
+	if create.body["alias"] != "keel:prod:default/wd:karolisr/webhook-demo:1.1.0" {
+		t.Errorf("unexpected alias: %v", create.body["alias"])
+	}
+	if create.body["priority"] != "P1" || create.body["entity"] != "default/wd" {
+		t.Errorf("unexpected alert: %v", create.body)
+	}
+	if len(create.body["message"].(string)) != maxMessageLength {
+		t.Errorf("expected message to be truncated, got %d characters", len(create.body["message"].(string)))
+	}
+
+	// unrelated resource
+	s.Send(types.EventNotification{Level: types.LevelSuccess, Metadata: map[string]string{"namespace": "default", "name": "other"}})
+	if len(received) != 1 {
+		t.Fatalf("unexpected request for another resource")
+	}
+
+	s.Send(types.EventNotification{Level: types.LevelSuccess, Message: "updated", Metadata: metadata})
+	if len(received) != 2 {
+		t.Fatalf("expected close request, got %d requests", len(received))
+	}
+	if received[1].path != "/v2/alerts/keel:prod:default%2Fwd:karolisr%2Fwebhook-demo:1.1.0/close" || received[1].query != "identifierType=alias" {
+		t.Errorf("unexpected close request: %s?%s", received[1].path, received[1].query)
+	}
+}
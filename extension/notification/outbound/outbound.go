@@ -0,0 +1,183 @@
+package outbound
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// SchemaVersion - version of the Event schema, fields are only added within a version
+const SchemaVersion = "v1"
+
+// request headers, signature is "sha256=<hex>" HMAC of the body, same as the one
+// accepted by the native webhook trigger
+const (
+	SignatureHeader = "X-Keel-Signature"
+	EventHeader     = "X-Keel-Event"
+	DeliveryHeader  = "X-Keel-Delivery"
+)
+
+type sender struct {
+	endpoint string
+	secret   []byte
+	client   *http.Client
+}
+
+func init() {
+	notification.RegisterSender("outbound", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.endpoint = os.Getenv(constants.EnvOutboundWebhookURL)
+	if s.endpoint == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(s.endpoint); err != nil {
+		return false, fmt.Errorf("could not parse webhook URL: %s", err)
+	}
+
+	secret := os.Getenv(constants.EnvOutboundWebhookSecret)
+	if secret == "" {
+		return false, fmt.Errorf("%s is required", constants.EnvOutboundWebhookSecret)
+	}
+	s.secret = []byte(secret)
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":     "outbound",
+		"endpoint": s.endpoint,
+	}).Info("extension.notification.outbound: sender configured")
+
+	return true, nil
+}
+
+// Event - JSON payload posted for every notification
+type Event struct {
+	Version string `json:"version"`
+	// ID - delivery ID, the same for retries of the notification
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Level     string            `json:"level"`
+	Name      string            `json:"name"`
+	Message   string            `json:"message"`
+	Timestamp time.Time         `json:"timestamp"`
+	Resource  *Resource         `json:"resource,omitempty"`
+	Update    *Update           `json:"update,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Resource - Kubernetes resource or Helm release the event is about
+type Resource struct {
+	Kind       string `json:"kind,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// Update - image versions of the update
+type Update struct {
+	Image      string `json:"image,omitempty"`
+	Previous   string `json:"previous,omitempty"`
+	New        string `json:"new,omitempty"`
+	ApprovedBy string `json:"approvedBy,omitempty"`
+}
+
+// deliveryID - derived from the notification so receivers can deduplicate retries
+func deliveryID(event types.EventNotification) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d|%s|%s", event.Identifier, event.Type, event.CreatedAt.UnixNano(), event.Name, event.Message)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// NewEvent - converts the notification into the outbound event
+func NewEvent(event types.EventNotification) *Event {
+	out := &Event{
+		Version:   SchemaVersion,
+		ID:        deliveryID(event),
+		Type:      strings.TrimSpace(event.Type.String()),
+		Level:     event.Level.String(),
+		Name:      event.Name,
+		Message:   event.Message,
+		Timestamp: event.CreatedAt.UTC(),
+		Metadata:  event.Metadata,
+	}
+
+	md := event.Metadata
+	resource := &Resource{
+		Kind:       event.ResourceKind,
+		Identifier: event.Identifier,
+		Cluster:    md["cluster"],
+		Namespace:  md["namespace"],
+		Name:       md["name"],
+	}
+	if *resource != (Resource{}) {
+		out.Resource = resource
+	}
+
+	update := &Update{
+		Image:      md["image"],
+		Previous:   md["previous"],
+		New:        md["new"],
+		ApprovedBy: md["approvedBy"],
+	}
+	if *update != (Update{}) {
+		out.Update = update
+	}
+	return out
+}
+
+// Sign - "sha256=<hex>" HMAC signature of the payload
+func Sign(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	out := NewEvent(event)
+	body, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(body, s.secret))
+	req.Header.Set(EventHeader, out.Type)
+	req.Header.Set(DeliveryHeader, out.ID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package outbound
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestSendSigned(t *testing.T) {
+	var (
+		received  Event
+		signature string
+		delivery  string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %s", err)
+		}
+		if req.Header.Get(SignatureHeader) != Sign(body, []byte("secret")) {
+			t.Errorf("invalid signature: %s", req.Header.Get(SignatureHeader))
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("failed to decode event: %s", err)
+		}
+		signature = req.Header.Get(SignatureHeader)
+		delivery = req.Header.Get(DeliveryHeader)
+	}))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint: ts.URL,
+		secret:   []byte("secret"),
+		client:   &http.Client{},
+	}
+
+	event := types.EventNotification{
+		Name:         "update resource",
+		Message:      "Successfully updated deployment default/wd 0.1.0->0.2.0",
+		CreatedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Metadata: map[string]string{
+			"namespace": "default",
+			"name":      "wd",
+			"image":     "karolisr/keel:0.2.0",
+			"previous":  "0.1.0",
+			"new":       "0.2.0",
+		},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if signature == "" {
+		t.Fatalf("request wasn't received")
+	}
+	if received.Version != "v1" || received.Type != "deployment update" || received.Level != "success" {
+		t.Errorf("unexpected event: %+v", received)
+	}
+	if received.ID != delivery || received.ID != deliveryID(event) {
+		t.Errorf("unexpected delivery ID: %s", delivery)
+	}
+	if received.Resource == nil || received.Resource.Kind != "deployment" || received.Resource.Namespace != "default" || received.Resource.Name != "wd" {
+		t.Errorf("unexpected resource: %+v", received.Resource)
+	}
+	if received.Update == nil || received.Update.Previous != "0.1.0" || received.Update.New != "0.2.0" {
+		t.Errorf("unexpected update: %+v", received.Update)
+	}
+}
+
+func TestNewEventWithoutResource(t *testing.T) {
+	event := NewEvent(types.EventNotification{
+		Name:    "update rejected",
+		Message: "update to 0.2.0 was rejected",
+		Type:    types.NotificationUpdateRejected,
+		Level:   types.LevelInfo,
+	})
+	if event.Resource != nil || event.Update != nil {
+		t.Errorf("expected no resource and update details, got: %+v", event)
+	}
+	if event.Type != "update rejected" {
+		t.Errorf("expected type without trailing space, got: %q", event.Type)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint: ts.URL,
+		secret:   []byte("secret"),
+		client:   &http.Client{},
+	}
+	if err := s.Send(types.EventNotification{Message: "foo"}); err == nil {
+		t.Errorf("expected error")
+	}
+}
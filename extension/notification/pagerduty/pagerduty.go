@@ -0,0 +1,161 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// eventsURL - PagerDuty Events API v2, see
+// https://developer.pagerduty.com/docs/events-api-v2/trigger-events/
+const eventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// maxSummaryLength - longer summaries are truncated by PagerDuty
+const maxSummaryLength = 1024
+
+type sender struct {
+	eventsURL  string
+	routingKey string
+	client     *http.Client
+
+	open notification.OpenAlerts
+}
+
+func init() {
+	notification.RegisterSender("pagerduty", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.routingKey = os.Getenv(constants.EnvPagerDutyRoutingKey)
+	if s.routingKey == "" {
+		return false, nil
+	}
+	s.eventsURL = eventsURL
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name": "pagerduty",
+	}).Info("extension.notification.pagerduty: sender configured")
+
+	return true, nil
+}
+
+type event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *payload `json:"payload,omitempty"`
+}
+
+type payload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Send - error and fatal notifications trigger incidents, successful updates resolve
+// incidents triggered for the same resource and image
+func (s *sender) Send(e types.EventNotification) error {
+	key := notification.AlertKey(e)
+
+	switch {
+	case e.Level >= types.LevelError:
+		err := s.enqueue(&event{
+			RoutingKey:  s.routingKey,
+			EventAction: "trigger",
+			DedupKey:    key,
+			Payload:     newPayload(e),
+		})
+		if err != nil {
+			return err
+		}
+		s.open.Add(key)
+	case e.Level == types.LevelSuccess && s.open.Remove(key):
+		return s.enqueue(&event{
+			RoutingKey:  s.routingKey,
+			EventAction: "resolve",
+			DedupKey:    key,
+		})
+	}
+	return nil
+}
+
+func newPayload(e types.EventNotification) *payload {
+	summary := e.Message
+	if len(summary) > maxSummaryLength {
+		summary = summary[:maxSummaryLength-3] + "..."
+	}
+
+	severity := "error"
+	if e.Level == types.LevelFatal {
+		severity = "critical"
+	}
+
+	source := "keel"
+	if cluster := e.Metadata["cluster"]; cluster != "" {
+		source = "keel/" + cluster
+	}
+
+	var component string
+	if e.Metadata["namespace"] != "" {
+		component = e.Metadata["namespace"] + "/" + e.Metadata["name"]
+	}
+
+	details := map[string]string{"name": e.Name}
+	for k, v := range e.Metadata {
+		details[k] = v
+	}
+
+	return &payload{
+		Summary:       summary,
+		Source:        source,
+		Severity:      severity,
+		Timestamp:     e.CreatedAt.UTC().Format(time.RFC3339),
+		Component:     component,
+		Group:         e.ResourceKind,
+		Class:         e.Type.String(),
+		CustomDetails: details,
+	}
+}
+
+func (s *sender) enqueue(e *event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	resp, err := s.client.Post(s.eventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("got status %d, expected 2xx: %s %v", resp.StatusCode, apiErr.Message, apiErr.Errors)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestTriggerAndResolve(t *testing.T) {
+	var received []event
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var e event
+		if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		received = append(received, e)
+		resp.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	s := &sender{eventsURL: ts.URL, routingKey: "key", client: &http.Client{}}
+
+	metadata := map[string]string{"namespace": "default", "name": "wd", "image": "karolisr/webhook-demo:1.1.0"}
+
+	// not a failure, nothing to resolve
+	s.Send(types.EventNotification{Level: types.LevelInfo, Message: "preparing", Metadata: metadata})
+	s.Send(types.EventNotification{Level: types.LevelSuccess, Message: "updated", Metadata: metadata})
+	if len(received) != 0 {
+		t.Fatalf("expected no events, got %d", len(received))
+	}
+
+	err := s.Send(types.EventNotification{
+		Name:         "update resource",
+		Message:      "deployment default/wd update 1.0.0->1.1.0 failed",
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		ResourceKind: "deployment",
+		Metadata:     metadata,
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected trigger event, got %d events", len(received))
+	}
+	trigger := received[0]
+	if trigger.EventAction != "trigger" || trigger.RoutingKey != "key" {
+		t.Errorf("unexpected event: %+v", trigger)
+	}
+	if trigger.DedupKey != "keel:default/wd:karolisr/webhook-demo:1.1.0" {
+		t.Errorf("unexpected dedup key: %s", trigger.DedupKey)
+	}
+	if trigger.Payload.Severity != "error" || trigger.Payload.Component != "default/wd" || trigger.Payload.Group != "deployment" {
+		t.Errorf("unexpected payload: %+v", trigger.Payload)
+	}
+
+	s.Send(types.EventNotification{Level: types.LevelSuccess, Message: "updated", Metadata: metadata})
+	if len(received) != 2 {
+		t.Fatalf("expected resolve event, got %d events", len(received))
+	}
+	if received[1].EventAction != "resolve" || received[1].DedupKey != trigger.DedupKey || received[1].Payload != nil {
+		t.Errorf("unexpected resolve event: %+v", received[1])
+	}
+}
+
+func TestTriggerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.Write([]byte(`{"status":"invalid event","message":"Event object is invalid","errors":["Length of 'routing_key' is incorrect"]}`))
+	}))
+	defer ts.Close()
+
+	s := &sender{eventsURL: ts.URL, routingKey: "key", client: &http.Client{}}
+	err := s.Send(types.EventNotification{Level: types.LevelFatal, Message: "provider failed"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if s.open.Remove("keel:") {
+		t.Errorf("failed trigger shouldn't be recorded")
+	}
+}
@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+)
+
+// Fact - notification detail displayed by chat senders
+type Fact struct {
+	Title string
+	Value string
+}
+
+// factKeys - notification metadata displayed by chat senders, in display order
+var factKeys = []struct {
+	key   string
+	title string
+}{
+	{"cluster", "Cluster"},
+	{"namespace", "Namespace"},
+	{"name", "Name"},
+	{"image", "Image"},
+	{"previous", "Old tag"},
+	{"new", "New tag"},
+	{"approvedBy", "Approved by"},
+	{"replicas", "Replicas"},
+	{"duration", "Duration"},
+}
+
+// Facts - resource and image details of the notification, only the ones providers added
+// to notification metadata are returned
+func Facts(event types.EventNotification) []Fact {
+	var facts []Fact
+	for _, f := range factKeys {
+		if v := event.Metadata[f.key]; v != "" {
+			facts = append(facts, Fact{Title: f.title, Value: v})
+		}
+	}
+	return facts
+}
+
+// Footer - footer of chat notifications
+func Footer() string {
+	return fmt.Sprintf("https://keel.sh %s", version.GetKeelVersion().Version)
+}
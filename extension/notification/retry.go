@@ -0,0 +1,253 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// retryCheckInterval - how often queued notifications are checked for retries
+	retryCheckInterval = time.Second
+	// retryQueueSize - notifications failing while the queue is full are dead-lettered
+	retryQueueSize = 1000
+
+	deadLetterTimeout = 5 * time.Second
+)
+
+// ChannelsError - sender failed to send the notification to some of the channels, only
+// these channels are retried so the others don't receive it twice
+type ChannelsError struct {
+	Channels []string
+	Err      error
+}
+
+func (e *ChannelsError) Error() string {
+	return fmt.Sprintf("failed to send to %s: %s", strings.Join(e.Channels, ", "), e.Err)
+}
+
+// retryItem - notification that failed to send with the sender
+type retryItem struct {
+	sender   string
+	event    types.EventNotification
+	attempts int
+	backOff  time.Duration
+	next     time.Time
+	err      error
+}
+
+// retryQueue - failed notifications waiting for their next attempt and notifications
+// waiting to be posted to the dead-letter endpoint
+type retryQueue struct {
+	mu    sync.Mutex
+	items []*retryItem
+	dead  []*retryItem
+}
+
+// add - queues the item, returns false when the queue is full
+func (q *retryQueue) add(item *retryItem) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) >= retryQueueSize {
+		return false
+	}
+	q.items = append(q.items, item)
+	return true
+}
+
+// addDead - queues the item for the dead-letter endpoint, returns false when the queue is full
+func (q *retryQueue) addDead(item *retryItem) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.dead) >= retryQueueSize {
+		return false
+	}
+	q.dead = append(q.dead, item)
+	return true
+}
+
+// due - removes and returns items that should be retried
+func (q *retryQueue) due(now time.Time) []*retryItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []*retryItem
+	pending := q.items[:0]
+	for _, item := range q.items {
+		if now.Before(item.next) {
+			pending = append(pending, item)
+		} else {
+			due = append(due, item)
+		}
+	}
+	q.items = pending
+	return due
+}
+
+// takeDead - removes and returns items that should be posted to the dead-letter endpoint
+func (q *retryQueue) takeDead() []*retryItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dead := q.dead
+	q.dead = nil
+	return dead
+}
+
+func (q *retryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// DeadLetter - notification that couldn't be sent, posted to the dead-letter endpoint
+type DeadLetter struct {
+	Sender       string                  `json:"sender"`
+	Attempts     int                     `json:"attempts"`
+	Error        string                  `json:"error"`
+	Notification types.EventNotification `json:"notification"`
+}
+
+// queueRetry - schedules another attempt of the failed notification, notification is
+// dead-lettered once max attempts are reached or the queue is full. Failed notifications
+// are dropped when attempts aren't configured
+func (m *DefaultNotificationSender) queueRetry(item *retryItem) {
+	if m.config.Attempts <= 0 {
+		return
+	}
+
+	if err, ok := item.err.(*ChannelsError); ok {
+		item.event.Channels = err.Channels
+	}
+
+	if item.attempts >= m.config.Attempts {
+		m.deadLetter(item)
+		return
+	}
+
+	item.backOff = timeutil.ExpBackoff(item.backOff, notifierMaxBackOff)
+	item.next = time.Now().Add(item.backOff)
+	if !m.retries.add(item) {
+		item.err = fmt.Errorf("retry queue full, last error: %s", item.err)
+		m.deadLetter(item)
+		return
+	}
+	m.startRetries()
+
+	log.WithFields(log.Fields{
+		logSenderName:  item.sender,
+		logNotiName:    item.event.Name,
+		"attempts":     item.attempts,
+		"max attempts": m.config.Attempts,
+		"retry in":     item.backOff,
+	}).Info("notificationSender: notification queued for retry")
+}
+
+// startRetries - starts processing retries and dead letters once the first one is queued
+func (m *DefaultNotificationSender) startRetries() {
+	m.retryOnce.Do(func() {
+		m.stopper.Begin()
+		go m.processRetries()
+	})
+}
+
+func (m *DefaultNotificationSender) processRetries() {
+	defer m.stopper.End()
+
+	for m.stopper.Sleep(retryCheckInterval) {
+		for _, item := range m.retries.due(time.Now()) {
+			m.retry(item)
+		}
+		// posted after retries released the sender locks
+		m.sendDeadLetters()
+	}
+}
+
+func (m *DefaultNotificationSender) retry(item *retryItem) {
+	m.configM.RLock()
+	defer m.configM.RUnlock()
+
+	sender, ok := m.Senders()[item.sender]
+	if !ok {
+		// sender was unregistered in the meantime
+		return
+	}
+
+	item.attempts++
+	if err := sender.Send(item.event); err != nil {
+		log.WithError(err).WithFields(log.Fields{logSenderName: item.sender, logNotiName: item.event.Name}).Error("could not send notification via notifier")
+		item.err = err
+		m.queueRetry(item)
+	}
+}
+
+// deadLetter - logs notification that couldn't be sent and queues it for the dead-letter
+// endpoint when one is configured, it's posted by the retries goroutine so senders
+// aren't blocked by the endpoint
+func (m *DefaultNotificationSender) deadLetter(item *retryItem) {
+	log.WithFields(log.Fields{
+		logSenderName:  item.sender,
+		logNotiName:    item.event.Name,
+		"attempts":     item.attempts,
+		"error":        item.err,
+		"type":         item.event.Type.String(),
+		"level":        item.event.Level.String(),
+		"identifier":   item.event.Identifier,
+		"notification": item.event.Message,
+	}).Error("notificationSender: giving up on sending notification, max attempts exceeded")
+
+	if m.config.DeadLetterURL == "" {
+		return
+	}
+	if !m.retries.addDead(item) {
+		log.WithFields(log.Fields{
+			logSenderName: item.sender,
+			logNotiName:   item.event.Name,
+		}).Error("notificationSender: dead letter queue full, dropping notification")
+		return
+	}
+	m.startRetries()
+}
+
+// sendDeadLetters - posts queued dead letters, must be called without holding sender locks
+func (m *DefaultNotificationSender) sendDeadLetters() {
+	for _, item := range m.retries.takeDead() {
+		m.postDeadLetter(m.config.DeadLetterURL, item)
+	}
+}
+
+func (m *DefaultNotificationSender) postDeadLetter(url string, item *retryItem) {
+	body, err := json.Marshal(&DeadLetter{
+		Sender:       item.sender,
+		Attempts:     item.attempts,
+		Error:        item.err.Error(),
+		Notification: item.event,
+	})
+	if err != nil {
+		log.WithError(err).Error("notificationSender: failed to marshal dead letter")
+		return
+	}
+
+	client := &http.Client{Timeout: deadLetterTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).Error("notificationSender: failed to post dead letter")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.WithField("status", resp.StatusCode).Error("notificationSender: dead letter endpoint returned unexpected status")
+	}
+}
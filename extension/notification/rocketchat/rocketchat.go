@@ -0,0 +1,145 @@
+package rocketchat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+type sender struct {
+	endpoint string
+	username string
+	client   *http.Client
+}
+
+func init() {
+	notification.RegisterSender("rocketchat", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.endpoint = os.Getenv(constants.EnvRocketChatWebhookURL)
+	if s.endpoint == "" {
+		return false, nil
+	}
+	if _, err := url.ParseRequestURI(s.endpoint); err != nil {
+		return false, fmt.Errorf("could not parse webhook URL: %s", err)
+	}
+
+	s.username = "keel"
+	if name := os.Getenv(constants.EnvRocketChatUsername); name != "" {
+		s.username = name
+	}
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":     "rocketchat",
+		"username": s.username,
+	}).Info("extension.notification.rocketchat: sender configured")
+
+	return true, nil
+}
+
+// message - incoming webhook message, the default integration script accepts the
+// Slack-compatible attachment format. Channel overrides the integration channel
+type message struct {
+	Channel     string       `json:"channel,omitempty"`
+	Alias       string       `json:"alias"`
+	Avatar      string       `json:"avatar"`
+	Text        string       `json:"text"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type attachment struct {
+	Title  string  `json:"title"`
+	Text   string  `json:"text"`
+	Color  string  `json:"color"`
+	Fields []field `json:"fields,omitempty"`
+}
+
+type field struct {
+	Short bool   `json:"short"`
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+func (s *sender) newMessage(event types.EventNotification) *message {
+	var fields []field
+	for _, f := range notification.Facts(event) {
+		fields = append(fields, field{Short: true, Title: f.Title, Value: f.Value})
+	}
+
+	return &message{
+		Alias:  s.username,
+		Avatar: constants.KeelLogoURL,
+		Text:   notification.Footer(),
+		Attachments: []attachment{
+			{
+				Title:  event.Type.String(),
+				Text:   event.Message,
+				Color:  event.Level.Color(),
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	msg := s.newMessage(event)
+
+	// integration channel is used unless the resource requested specific channels
+	channels := []string{""}
+	if len(event.Channels) > 0 {
+		channels = event.Channels
+	}
+
+	failed := &notification.ChannelsError{}
+	for _, channel := range channels {
+		msg.Channel = channel
+		err := s.post(msg)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"channel": channel,
+			}).Error("extension.notification.rocketchat: failed to send notification")
+			failed.Channels = append(failed.Channels, channel)
+			failed.Err = err
+		}
+	}
+	if len(failed.Channels) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func (s *sender) post(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package rocketchat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestRocketChatSend(t *testing.T) {
+	var received []message
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		var msg message
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		received = append(received, msg)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint: ts.URL,
+		username: "keel",
+		client:   &http.Client{},
+	}
+
+	event := types.EventNotification{
+		Message: "Failed to update deployment default/wd",
+		Type:    types.NotificationDeploymentUpdate,
+		Level:   types.LevelError,
+		Metadata: map[string]string{
+			"namespace": "default",
+			"name":      "wd",
+		},
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(received) != 1 || received[0].Channel != "" || received[0].Alias != "keel" {
+		t.Fatalf("unexpected messages: %+v", received)
+	}
+	a := received[0].Attachments[0]
+	if a.Title != types.NotificationDeploymentUpdate.String() || a.Color != types.LevelError.Color() || a.Text != event.Message {
+		t.Errorf("unexpected attachment: %+v", a)
+	}
+	if len(a.Fields) != 2 || a.Fields[0].Title != "Namespace" || a.Fields[1].Value != "wd" {
+		t.Errorf("unexpected fields: %+v", a.Fields)
+	}
+
+	received = nil
+	event.Channels = []string{"#ops", "@jane"}
+	s.Send(event)
+	if len(received) != 2 || received[0].Channel != "#ops" || received[1].Channel != "@jane" {
+		t.Errorf("unexpected messages: %+v", received)
+	}
+}
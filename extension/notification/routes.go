@@ -0,0 +1,109 @@
+package notification
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/ghodss/yaml"
+)
+
+// Route - notification channels and webhook URLs of resources in the namespaces that have
+// all the labels, channels are used by chat senders (slack, mattermost, ...) and webhooks
+// by the webhook sender instead of their defaults
+type Route struct {
+	Namespaces []string          `json:"namespaces,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Channels   []string          `json:"channels,omitempty"`
+	Webhooks   []string          `json:"webhooks,omitempty"`
+}
+
+// Routes - notification routing rules, the first matching route is used
+type Routes struct {
+	routes []*Route
+}
+
+// LoadRoutes - reads notification routes from a YAML file, ie:
+//
+//   - namespaces: [team-a]
+//     channels: ["#team-a"]
+//   - labels: {team: b}
+//     channels: ["#team-b"]
+//     webhooks: ["https://team-b.example.com/keel"]
+func LoadRoutes(path string) (*Routes, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routes: %s", err)
+	}
+	return ParseRoutes(b)
+}
+
+// ParseRoutes - parses YAML or JSON list of notification routes
+func ParseRoutes(b []byte) (*Routes, error) {
+	var list []*Route
+	if err := yaml.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %s", err)
+	}
+
+	for i, r := range list {
+		if len(r.Namespaces) == 0 && len(r.Labels) == 0 {
+			return nil, fmt.Errorf("route %d: namespaces or labels are required", i)
+		}
+		if len(r.Channels) == 0 && len(r.Webhooks) == 0 {
+			return nil, fmt.Errorf("route %d: channels or webhooks are required", i)
+		}
+		for _, webhook := range r.Webhooks {
+			if _, err := url.ParseRequestURI(webhook); err != nil {
+				return nil, fmt.Errorf("route %d: could not parse webhook URL: %s", i, err)
+			}
+		}
+	}
+	return &Routes{routes: list}, nil
+}
+
+func (r *Route) matches(namespace string, labels map[string]string) bool {
+	if len(r.Namespaces) > 0 {
+		found := false
+		for _, ns := range r.Namespaces {
+			if ns == namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range r.Labels {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Match - first route matching resource namespace and labels, nil when none match
+func (r *Routes) Match(namespace string, labels map[string]string) *Route {
+	if r == nil {
+		return nil
+	}
+	for _, route := range r.routes {
+		if route.matches(namespace, labels) {
+			return route
+		}
+	}
+	return nil
+}
+
+// HasWebhooks - whether any route sends notifications to webhook URLs
+func (r *Routes) HasWebhooks() bool {
+	if r == nil {
+		return false
+	}
+	for _, route := range r.routes {
+		if len(route.Webhooks) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -117,6 +117,7 @@ func (s *sender) Send(event types.EventNotification) error {
 	mgsOpts = append(mgsOpts, slack.MsgOptionPostMessageParameters(params))
 	mgsOpts = append(mgsOpts, slack.MsgOptionAttachments(attachements...))
 
+	failed := &notification.ChannelsError{}
 	for _, channel := range chans {
 		_, _, err := s.slackClient.PostMessage(channel, mgsOpts...)
 		if err != nil {
@@ -124,7 +125,12 @@ func (s *sender) Send(event types.EventNotification) error {
 				"error":   err,
 				"channel": channel,
 			}).Error("extension.notification.slack: failed to send notification")
+			failed.Channels = append(failed.Channels, channel)
+			failed.Err = err
 		}
 	}
+	if len(failed.Channels) > 0 {
+		return failed
+	}
 	return nil
 }
@@ -0,0 +1,149 @@
+package sns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/awsauth"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/version"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 10 * time.Second
+
+// topicClient - the part of SNS API the sender needs
+type topicClient interface {
+	PublishWithContext(ctx aws.Context, input *awssns.PublishInput, opts ...request.Option) (*awssns.PublishOutput, error)
+}
+
+type sender struct {
+	topicARN string
+	client   topicClient
+}
+
+func init() {
+	notification.RegisterSender("sns", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.topicARN = os.Getenv(constants.EnvSNSTopicARN)
+	if s.topicARN == "" {
+		return false, nil
+	}
+
+	region, err := topicRegion(s.topicARN)
+	if err != nil {
+		return false, err
+	}
+	sess, err := awsauth.NewSession(region)
+	if err != nil {
+		return false, fmt.Errorf("failed to create AWS session: %s", err)
+	}
+	s.client = awssns.New(sess)
+
+	log.WithFields(log.Fields{
+		"name":  "sns",
+		"topic": s.topicARN,
+	}).Info("extension.notification.sns: sender configured")
+
+	return true, nil
+}
+
+// topicRegion - region from topic ARN, arn:<partition>:sns:<region>:<account>:<name>
+func topicRegion(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return "", fmt.Errorf("invalid SNS topic ARN '%s'", arn)
+	}
+	return parts[3], nil
+}
+
+// Event - JSON message published to the topic. Type and level are also set as message
+// attributes ("type", "level", "resourceKind", "namespace", "cluster") so subscriptions
+// can filter them
+type Event struct {
+	Source       string            `json:"source"`
+	Version      string            `json:"version"`
+	Name         string            `json:"name"`
+	Message      string            `json:"message"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Type         string            `json:"type"`
+	Level        string            `json:"level"`
+	ResourceKind string            `json:"resourceKind,omitempty"`
+	Identifier   string            `json:"identifier,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	body, err := json.Marshal(&Event{
+		Source:       "keel",
+		Version:      version.GetKeelVersion().Version,
+		Name:         event.Name,
+		Message:      event.Message,
+		CreatedAt:    event.CreatedAt.UTC(),
+		Type:         event.Type.String(),
+		Level:        event.Level.String(),
+		ResourceKind: event.ResourceKind,
+		Identifier:   event.Identifier,
+		Metadata:     event.Metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	attributes := map[string]*awssns.MessageAttributeValue{}
+	for name, value := range map[string]string{
+		"type":         event.Type.String(),
+		"level":        event.Level.String(),
+		"resourceKind": event.ResourceKind,
+		"namespace":    event.Metadata["namespace"],
+		"cluster":      event.Metadata["cluster"],
+	} {
+		if value != "" {
+			attributes[name] = &awssns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+		}
+	}
+
+	input := &awssns.PublishInput{
+		TopicArn:          aws.String(s.topicARN),
+		Message:           aws.String(string(body)),
+		MessageAttributes: attributes,
+	}
+	if strings.HasSuffix(s.topicARN, ".fifo") {
+		// events of a resource are kept in order
+		group := event.Identifier
+		if group == "" {
+			group = "keel"
+		}
+		sum := sha256.Sum256(body)
+		input.MessageGroupId = aws.String(group)
+		input.MessageDeduplicationId = aws.String(hex.EncodeToString(sum[:]))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err = s.client.PublishWithContext(ctx, input)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"topic": s.topicARN,
+		}).Error("extension.notification.sns: failed to publish notification")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package sns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func testSender(t *testing.T, topicARN string) (*sender, *[]url.Values, func()) {
+	var published []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "Publish" {
+			t.Errorf("unexpected action: %s", r.Form.Get("Action"))
+		}
+		published = append(published, r.Form)
+		fmt.Fprint(w, `<PublishResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <PublishResult><MessageId>567910cd-659e-55d4-8ccb-5aaf14679dc0</MessageId></PublishResult>
+  <ResponseMetadata><RequestId>d74b8436-ae13-5ab4-a9ff-ce54dfea72a0</RequestId></ResponseMetadata>
+</PublishResponse>`)
+	}))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	return &sender{topicARN: topicARN, client: awssns.New(sess)}, &published, srv.Close
+}
+
+func TestPublish(t *testing.T) {
+	s, published, teardown := testSender(t, "arn:aws:sns:eu-west-1:123456789012:keel")
+	defer teardown()
+
+	createdAt := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	err := s.Send(types.EventNotification{
+		Name:         "update resource",
+		Message:      "Successfully updated deployment default/wd 1.0.0->1.1.0",
+		CreatedAt:    createdAt,
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelSuccess,
+		ResourceKind: "deployment",
+		Identifier:   "deployment/default/wd",
+		Metadata:     map[string]string{"namespace": "default", "name": "wd", "new": "1.1.0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	if len(*published) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(*published))
+	}
+
+	form := (*published)[0]
+	if form.Get("TopicArn") != "arn:aws:sns:eu-west-1:123456789012:keel" {
+		t.Errorf("unexpected topic: %s", form.Get("TopicArn"))
+	}
+	if form.Get("MessageGroupId") != "" {
+		t.Errorf("unexpected message group for standard topic")
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(form.Get("Message")), &event); err != nil {
+		t.Fatalf("failed to decode message: %s", err)
+	}
+	if event.Source != "keel" || event.Type != "deployment update" || event.Level != "success" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if !event.CreatedAt.Equal(createdAt) || event.Identifier != "deployment/default/wd" || event.Metadata["new"] != "1.1.0" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	attributes := map[string]string{}
+	for i := 1; form.Get(fmt.Sprintf("MessageAttributes.entry.%d.Name", i)) != ""; i++ {
+		name := form.Get(fmt.Sprintf("MessageAttributes.entry.%d.Name", i))
+		if dataType := form.Get(fmt.Sprintf("MessageAttributes.entry.%d.Value.DataType", i)); dataType != "String" {
+			t.Errorf("unexpected data type of %s: %s", name, dataType)
+		}
+		attributes[name] = form.Get(fmt.Sprintf("MessageAttributes.entry.%d.Value.StringValue", i))
+	}
+	expected := map[string]string{"type": "deployment update", "level": "success", "resourceKind": "deployment", "namespace": "default"}
+	if len(attributes) != len(expected) {
+		t.Errorf("unexpected attributes: %v", attributes)
+	}
+	for k, v := range expected {
+		if attributes[k] != v {
+			t.Errorf("attribute %s: expected %s, got %s", k, v, attributes[k])
+		}
+	}
+}
+
+func TestPublishFIFO(t *testing.T) {
+	s, published, teardown := testSender(t, "arn:aws:sns:eu-west-1:123456789012:keel.fifo")
+	defer teardown()
+
+	s.Send(types.EventNotification{
+		Message:    "preparing to update",
+		Type:       types.NotificationPreDeploymentUpdate,
+		Identifier: "deployment/default/wd",
+	})
+
+	form := (*published)[0]
+	if form.Get("MessageGroupId") != "deployment/default/wd" {
+		t.Errorf("unexpected message group: %s", form.Get("MessageGroupId"))
+	}
+	if len(form.Get("MessageDeduplicationId")) != 64 {
+		t.Errorf("unexpected deduplication ID: %s", form.Get("MessageDeduplicationId"))
+	}
+}
+
+func TestTopicRegion(t *testing.T) {
+	region, err := topicRegion("arn:aws:sns:us-west-2:123456789012:keel")
+	if err != nil || region != "us-west-2" {
+		t.Errorf("unexpected region: %s, %v", region, err)
+	}
+	for _, invalid := range []string{"keel", "arn:aws:sqs:us-west-2:123456789012:keel", "arn:aws:sns::123456789012:keel"} {
+		if _, err := topicRegion(invalid); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
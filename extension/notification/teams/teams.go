@@ -0,0 +1,170 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+type sender struct {
+	endpoints []string
+	client    *http.Client
+}
+
+func init() {
+	notification.RegisterSender("teams", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.endpoints = nil
+	for _, endpoint := range strings.Split(os.Getenv(constants.EnvTeamsNotificationsWebhookURL), ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return false, fmt.Errorf("could not parse webhook URL: %s", err)
+		}
+		s.endpoints = append(s.endpoints, endpoint)
+	}
+	if len(s.endpoints) == 0 {
+		return false, nil
+	}
+
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":     "teams",
+		"webhooks": len(s.endpoints),
+	}).Info("extension.notification.teams: sender configured")
+
+	return true, nil
+}
+
+// message - incoming webhook message with an Adaptive Card attachment, see
+// https://docs.microsoft.com/en-us/microsoftteams/platform/task-modules-and-cards/cards/cards-reference#adaptive-card
+type message struct {
+	Type        string       `json:"type"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type attachment struct {
+	ContentType string        `json:"contentType"`
+	Content     *adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string    `json:"$schema"`
+	Type    string    `json:"type"`
+	Version string    `json:"version"`
+	Body    []element `json:"body"`
+}
+
+type element struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Weight   string `json:"weight,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Wrap     bool   `json:"wrap,omitempty"`
+	Facts    []fact `json:"facts,omitempty"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// levelColor - Adaptive Card text color of the notification level
+func levelColor(level types.Level) string {
+	switch level {
+	case types.LevelSuccess:
+		return "Good"
+	case types.LevelWarn:
+		return "Warning"
+	case types.LevelError, types.LevelFatal:
+		return "Attention"
+	case types.LevelInfo:
+		return "Accent"
+	default:
+		return "Default"
+	}
+}
+
+// notificationCard - notification type and message, resource and image details are
+// listed when the provider added them to the notification metadata
+func notificationCard(event types.EventNotification) *adaptiveCard {
+	body := []element{
+		{Type: "TextBlock", Text: event.Type.String(), Weight: "Bolder", Size: "Medium", Color: levelColor(event.Level), Wrap: true},
+		{Type: "TextBlock", Text: event.Message, Wrap: true},
+	}
+
+	var set []fact
+	for _, f := range notification.Facts(event) {
+		set = append(set, fact{Title: f.Title, Value: f.Value})
+	}
+	if len(set) > 0 {
+		body = append(body, element{Type: "FactSet", Facts: set})
+	}
+
+	return &adaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.2",
+		Body: append(body, element{
+			Type: "TextBlock", Text: notification.Footer(), IsSubtle: true, Size: "Small",
+		}),
+	}
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	body, err := json.Marshal(&message{
+		Type: "message",
+		Attachments: []attachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: notificationCard(event)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	for _, endpoint := range s.endpoints {
+		err := s.post(endpoint, body)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("extension.notification.teams: failed to send notification")
+		}
+	}
+	return nil
+}
+
+func (s *sender) post(endpoint string, body []byte) error {
+	resp, err := s.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %d, expected 2xx", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestTeamsCard(t *testing.T) {
+	var received message
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	s := &sender{
+		endpoints: []string{ts.URL},
+		client:    &http.Client{},
+	}
+
+	err := s.Send(types.EventNotification{
+		Name:      "update resource",
+		Message:   "Successfully updated deployment default/wd 1.0.0->1.1.0",
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDeploymentUpdate,
+		Level:     types.LevelSuccess,
+		Metadata: map[string]string{
+			"cluster":   "prod",
+			"namespace": "default",
+			"name":      "wd",
+			"image":     "karolisr/webhook-demo:1.1.0",
+			"previous":  "1.0.0",
+			"new":       "1.1.0",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(received.Attachments) != 1 || received.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("expected adaptive card attachment, got: %+v", received.Attachments)
+	}
+
+	card := received.Attachments[0].Content
+	if card.Body[0].Text != types.NotificationDeploymentUpdate.String() || card.Body[0].Color != "Good" {
+		t.Errorf("unexpected title: %+v", card.Body[0])
+	}
+
+	var set *element
+	for i := range card.Body {
+		if card.Body[i].Type == "FactSet" {
+			set = &card.Body[i]
+		}
+	}
+	if set == nil {
+		t.Fatalf("facts missing")
+	}
+
+	expected := []fact{
+		{Title: "Cluster", Value: "prod"},
+		{Title: "Namespace", Value: "default"},
+		{Title: "Name", Value: "wd"},
+		{Title: "Image", Value: "karolisr/webhook-demo:1.1.0"},
+		{Title: "Old tag", Value: "1.0.0"},
+		{Title: "New tag", Value: "1.1.0"},
+	}
+	if len(set.Facts) != len(expected) {
+		t.Fatalf("expected %d facts, got %d", len(expected), len(set.Facts))
+	}
+	for i, f := range expected {
+		if set.Facts[i] != f {
+			t.Errorf("fact %d: expected %+v, got %+v", i, f, set.Facts[i])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/constants"
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const timeout = 5 * time.Second
+
+// apiURL - Telegram Bot API, see https://core.telegram.org/bots/api
+const apiURL = "https://api.telegram.org"
+
+// markdownEscaper - characters that have to be escaped in MarkdownV2 text, see
+// https://core.telegram.org/bots/api#markdownv2-style
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`,
+	"`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`,
+	"{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// codeEscaper - characters that have to be escaped inside code entities
+var codeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+type sender struct {
+	apiURL  string
+	token   string
+	chatIDs []string
+	client  *http.Client
+}
+
+func init() {
+	notification.RegisterSender("telegram", &sender{})
+}
+
+func (s *sender) Configure(config *notification.Config) (bool, error) {
+	s.token = os.Getenv(constants.EnvTelegramBotToken)
+	if s.token == "" || os.Getenv(constants.EnvTelegramChatIDs) == "" {
+		return false, nil
+	}
+
+	s.chatIDs = nil
+	for _, id := range strings.Split(os.Getenv(constants.EnvTelegramChatIDs), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			s.chatIDs = append(s.chatIDs, id)
+		}
+	}
+	s.apiURL = apiURL
+	s.client = &http.Client{
+		Transport: http.DefaultTransport,
+		Timeout:   timeout,
+	}
+
+	log.WithFields(log.Fields{
+		"name":  "telegram",
+		"chats": s.chatIDs,
+	}).Info("extension.notification.telegram: sender configured")
+
+	return true, nil
+}
+
+type sendMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// icon - message prefix for the notification, approval notifications are marked
+// regardless of their level so they stand out in busy chats
+func icon(event types.EventNotification) string {
+	switch event.Type {
+	case types.NotificationUpdateApproved:
+		return "👍"
+	case types.NotificationUpdateRejected:
+		return "👎"
+	case types.NotificationApprovalExpired, types.NotificationApprovalReminder, types.NotificationApprovalDigest:
+		return "🗳"
+	}
+	switch event.Level {
+	case types.LevelSuccess:
+		return "✅"
+	case types.LevelWarn:
+		return "⚠️"
+	case types.LevelError, types.LevelFatal:
+		return "❌"
+	default:
+		return "ℹ️"
+	}
+}
+
+// formatMessage - MarkdownV2 message with the notification type as the title, resource
+// and image details are listed when the provider added them to the notification metadata
+func formatMessage(event types.EventNotification) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s *%s*\n\n", icon(event), markdownEscaper.Replace(event.Type.String()))
+
+	message := markdownEscaper.Replace(event.Message)
+	if event.Level >= types.LevelError {
+		// errors are kept verbatim so they can be copied
+		message = "```\n" + codeEscaper.Replace(event.Message) + "\n```"
+	}
+	fmt.Fprintf(buf, "%s\n", message)
+
+	facts := notification.Facts(event)
+	if len(facts) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, f := range facts {
+		fmt.Fprintf(buf, "*%s:* `%s`\n", markdownEscaper.Replace(f.Title), codeEscaper.Replace(f.Value))
+	}
+
+	fmt.Fprintf(buf, "\n_%s_", markdownEscaper.Replace(notification.Footer()))
+	return buf.String()
+}
+
+func (s *sender) Send(event types.EventNotification) error {
+	chats := s.chatIDs
+	if len(event.Channels) > 0 {
+		chats = event.Channels
+	}
+
+	text := formatMessage(event)
+	failed := &notification.ChannelsError{}
+	for _, chat := range chats {
+		err := s.send(&sendMessage{
+			ChatID:                chat,
+			Text:                  text,
+			ParseMode:             "MarkdownV2",
+			DisableWebPagePreview: true,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"chat":  chat,
+			}).Error("extension.notification.telegram: failed to send notification")
+			failed.Channels = append(failed.Channels, chat)
+			failed.Err = err
+		}
+	}
+	if len(failed.Channels) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func (s *sender) send(msg *sendMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %s", err)
+	}
+
+	resp, err := s.client.Post(s.apiURL+"/bot"+s.token+"/sendMessage", "application/json", bytes.NewReader(body))
+	if err != nil {
+		// error contains the request URL with the bot token
+		return fmt.Errorf("request failed: %s", strings.Replace(err.Error(), s.token, "<token>", -1))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("got status %d, expected 2xx: %s", resp.StatusCode, apiErr.Description)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestTelegramSend(t *testing.T) {
+	var received []sendMessage
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/botsecret/sendMessage" {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+		}
+		var msg sendMessage
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		received = append(received, msg)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	s := &sender{
+		apiURL:  ts.URL,
+		token:   "secret",
+		chatIDs: []string{"-100123", "@keel"},
+		client:  &http.Client{},
+	}
+
+	err := s.Send(types.EventNotification{
+		Name:      "update resource",
+		Message:   "Successfully updated deployment default/wd 1.0.0->1.1.0",
+		CreatedAt: time.Now(),
+		Type:      types.NotificationDeploymentUpdate,
+		Level:     types.LevelSuccess,
+		Metadata: map[string]string{
+			"namespace": "default",
+			"name":      "wd",
+			"previous":  "1.0.0",
+			"new":       "1.1.0",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+
+	if len(received) != 2 || received[0].ChatID != "-100123" || received[1].ChatID != "@keel" {
+		t.Fatalf("expected message to both chats, got: %+v", received)
+	}
+	if received[0].ParseMode != "MarkdownV2" {
+		t.Errorf("unexpected parse mode: %s", received[0].ParseMode)
+	}
+
+	text := received[0].Text
+	for _, expected := range []string{
+		"*deployment update*",
+		`Successfully updated deployment default/wd 1\.0\.0\-\>1\.1\.0`,
+		"*Old tag:* `1.0.0`",
+		"*New tag:* `1.1.0`",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in message: %s", expected, text)
+		}
+	}
+}
+
+func TestTelegramChannelsOverride(t *testing.T) {
+	var chats []string
+	ts := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var msg sendMessage
+		json.NewDecoder(req.Body).Decode(&msg)
+		chats = append(chats, msg.ChatID)
+	}))
+	defer ts.Close()
+
+	s := &sender{apiURL: ts.URL, token: "secret", chatIDs: []string{"-100123"}, client: &http.Client{}}
+	s.Send(types.EventNotification{
+		Message:  "failed",
+		Type:     types.NotificationDeploymentUpdate,
+		Level:    types.LevelError,
+		Channels: []string{"-100456"},
+	})
+
+	if len(chats) != 1 || chats[0] != "-100456" {
+		t.Errorf("expected message to overridden chat, got: %v", chats)
+	}
+}
+
+func TestFormatErrorMessage(t *testing.T) {
+	text := formatMessage(types.EventNotification{
+		Message: "update failed, error: `bad` request",
+		Type:    types.NotificationDeploymentUpdate,
+		Level:   types.LevelError,
+	})
+	if !strings.Contains(text, "```\nupdate failed, error: \\`bad\\` request\n```") {
+		t.Errorf("expected error in code block: %s", text)
+	}
+	if !strings.HasPrefix(text, "❌") {
+		t.Errorf("expected error icon: %s", text)
+	}
+}
@@ -0,0 +1,145 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/templates"
+	"github.com/keel-hq/keel/version"
+)
+
+// auditorSender - audit log keeps the original messages
+const auditorSender = "auditor"
+
+// MessageTemplate - Go template replacing the message of notifications with the type
+// (ie: "deployment update") sent by the sender (ie: "slack"), empty type or sender match
+// any notification type or sender
+type MessageTemplate struct {
+	Type    string `json:"type,omitempty"`
+	Sender  string `json:"sender,omitempty"`
+	Message string `json:"message"`
+
+	tmpl *template.Template
+}
+
+// Templates - message templates, the first matching template is used so templates for
+// specific senders and types should come before generic ones
+type Templates struct {
+	templates []*MessageTemplate
+}
+
+// TemplateData - data available to message templates, resource and image details are
+// copied from notification metadata
+type TemplateData struct {
+	types.EventNotification
+
+	Cluster    string
+	Namespace  string
+	Name       string
+	Image      string
+	Previous   string
+	New        string
+	ApprovedBy string
+	Version    string
+}
+
+// LoadTemplates - reads message templates from a YAML file, ie:
+//
+//   - type: deployment update
+//     sender: slack
+//     message: "{{ .Namespace }}/{{ .Name }} updated to {{ .New }}"
+func LoadTemplates(path string) (*Templates, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %s", err)
+	}
+	return ParseTemplates(b)
+}
+
+// ParseTemplates - parses YAML or JSON list of message templates
+func ParseTemplates(b []byte) (*Templates, error) {
+	var list []*MessageTemplate
+	if err := yaml.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %s", err)
+	}
+
+	known := make(map[string]bool)
+	for _, t := range typeNames() {
+		known[t] = true
+	}
+
+	for i, t := range list {
+		if t.Type != "" && !known[t.Type] {
+			return nil, fmt.Errorf("template %d: unknown notification type '%s'", i, t.Type)
+		}
+		if t.Message == "" {
+			return nil, fmt.Errorf("template %d: message is required", i)
+		}
+		tmpl, err := templates.NewParse(fmt.Sprintf("%s/%s", t.Sender, t.Type), t.Message)
+		if err != nil {
+			return nil, fmt.Errorf("template %d: %s", i, err)
+		}
+		t.tmpl = tmpl
+	}
+	return &Templates{templates: list}, nil
+}
+
+// typeNames - notification type names templates can match
+func typeNames() []string {
+	var names []string
+	for n := types.PreProviderSubmitNotification; ; n++ {
+		name := typeName(n)
+		if name == "unknown" {
+			return names
+		}
+		names = append(names, name)
+	}
+}
+
+// typeName - notification type name without surrounding whitespace
+func typeName(n types.Notification) string {
+	return strings.TrimSpace(n.String())
+}
+
+func (t *MessageTemplate) matches(sender string, event types.EventNotification) bool {
+	return (t.Sender == "" || t.Sender == sender) && (t.Type == "" || t.Type == typeName(event.Type))
+}
+
+// Render - notification with the message rendered from the first template matching the
+// sender and notification type, notification is returned unchanged when none match
+func (t *Templates) Render(sender string, event types.EventNotification) (types.EventNotification, error) {
+	if t == nil || sender == auditorSender {
+		return event, nil
+	}
+
+	for _, tmpl := range t.templates {
+		if !tmpl.matches(sender, event) {
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		err := tmpl.tmpl.Execute(buf, &TemplateData{
+			EventNotification: event,
+			Cluster:           event.Metadata["cluster"],
+			Namespace:         event.Metadata["namespace"],
+			Name:              event.Metadata["name"],
+			Image:             event.Metadata["image"],
+			Previous:          event.Metadata["previous"],
+			New:               event.Metadata["new"],
+			ApprovedBy:        event.Metadata["approvedBy"],
+			Version:           version.GetKeelVersion().Version,
+		})
+		if err != nil {
+			return event, fmt.Errorf("failed to render template: %s", err)
+		}
+		event.Message = strings.TrimSpace(buf.String())
+		return event, nil
+	}
+	return event, nil
+}
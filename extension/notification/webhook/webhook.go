@@ -38,16 +38,15 @@ func (s *sender) Configure(config *notification.Config) (bool, error) {
 
 	if os.Getenv(constants.WebhookEndpointEnv) != "" {
 		httpConfig.Endpoint = os.Getenv(constants.WebhookEndpointEnv)
-	} else {
+	} else if !config.Routes.HasWebhooks() {
 		return false, nil
 	}
 
-	// Validate endpoint URL.
-	if httpConfig.Endpoint == "" {
-		return false, nil
-	}
-	if _, err := url.ParseRequestURI(httpConfig.Endpoint); err != nil {
-		return false, fmt.Errorf("could not parse endpoint URL: %s\n", err)
+	// Validate endpoint URL, routed notifications are sent without a default endpoint
+	if httpConfig.Endpoint != "" {
+		if _, err := url.ParseRequestURI(httpConfig.Endpoint); err != nil {
+			return false, fmt.Errorf("could not parse endpoint URL: %s\n", err)
+		}
 	}
 	s.endpoint = httpConfig.Endpoint
 
@@ -76,10 +75,35 @@ func (s *sender) Send(event types.EventNotification) error {
 		return fmt.Errorf("could not marshal: %s", err)
 	}
 
+	// Routed notifications are sent to their webhooks instead of the default endpoint
+	endpoints := event.Webhooks
+	if len(endpoints) == 0 {
+		if s.endpoint == "" {
+			return nil
+		}
+		endpoints = []string{s.endpoint}
+	}
+
+	// failed notifications are retried, endpoints may receive them more than once
+	var lastErr error
+	for _, endpoint := range endpoints {
+		if err := s.post(endpoint, jsonNotification); err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"endpoint": endpoint,
+			}).Error("extension.notification.webhook: failed to send notification")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (s *sender) post(endpoint string, body []byte) error {
 	// Send notification via HTTP POST.
-	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewBuffer(jsonNotification))
+	resp, err := s.client.Post(endpoint, "application/json", bytes.NewBuffer(body))
 	if err != nil || resp == nil || (resp.StatusCode != 200 && resp.StatusCode != 201) {
 		if resp != nil {
+			resp.Body.Close()
 			return fmt.Errorf("got status %d, expected 200/201", resp.StatusCode)
 		}
 		return err
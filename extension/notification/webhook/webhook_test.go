@@ -57,3 +57,33 @@ func TestWebhookRequest(t *testing.T) {
 		Level:     types.LevelDebug,
 	})
 }
+
+func TestWebhookRouted(t *testing.T) {
+	var received []string
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		received = append(received, req.URL.Path)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	s := &sender{
+		endpoint: ts.URL + "/default",
+		client:   &http.Client{},
+	}
+
+	s.Send(types.EventNotification{Message: "message here"})
+	s.Send(types.EventNotification{Message: "message here", Webhooks: []string{ts.URL + "/team-a", ts.URL + "/team-b"}})
+
+	if strings.Join(received, ",") != "/default,/team-a,/team-b" {
+		t.Errorf("unexpected requests: %v", received)
+	}
+
+	// routes only, without the default endpoint
+	s.endpoint = ""
+	received = nil
+	s.Send(types.EventNotification{Message: "message here"})
+	if len(received) != 0 {
+		t.Errorf("unexpected requests: %v", received)
+	}
+}
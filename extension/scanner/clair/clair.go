@@ -0,0 +1,91 @@
+package clair
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/extension/scanner"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Clair configuration
+const (
+	// EnvURL - address of Clair v4 (combined mode or matcher), for example http://clair:6060
+	EnvURL = "CLAIR_URL"
+)
+
+func init() {
+	scanner.RegisterScanner("clair", New(os.Getenv(EnvURL)))
+}
+
+// Scanner - gets vulnerability reports of images already indexed by Clair, images are
+// usually indexed by the registry (for example Quay) when pushed
+type Scanner struct {
+	url    string
+	client *http.Client
+}
+
+// New creates a new instance of Clair scanner, it's enabled when url is set
+func New(url string) *Scanner {
+	return &Scanner{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// IsEnabled returns a bool whether this scanner is enabled
+func (s *Scanner) IsEnabled() bool {
+	return s.url != ""
+}
+
+type vulnerabilityReport struct {
+	Vulnerabilities map[string]struct {
+		Name               string `json:"name"`
+		NormalizedSeverity string `json:"normalized_severity"`
+	} `json:"vulnerabilities"`
+}
+
+// Scan - gets vulnerability report of the manifest, images Clair hasn't indexed yet are
+// reported as not scanned
+func (s *Scanner) Scan(target *scanner.Target) (*scanner.Report, error) {
+	if target.Digest == "" {
+		return nil, fmt.Errorf("clair requires image digest")
+	}
+
+	resp, err := s.client.Get(s.url + "/matcher/api/v1/vulnerability_report/" + target.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, scanner.ErrNotScanned
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected clair response status: %s", resp.Status)
+	}
+
+	var r vulnerabilityReport
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode clair report: %s", err)
+	}
+
+	result := &scanner.Report{Counts: make(map[scanner.Severity]int)}
+	for id, v := range r.Vulnerabilities {
+		severity, err := scanner.ParseSeverity(v.NormalizedSeverity)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":         err,
+				"vulnerability": v.Name,
+				"id":            id,
+			}).Warn("scanner.clair: unknown severity")
+		}
+		result.Counts[severity]++
+	}
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package clair
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/scanner"
+	"github.com/keel-hq/keel/util/image"
+)
+
+func TestScan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/matcher/api/v1/vulnerability_report/sha256:abc":
+			fmt.Fprint(w, `{
+				"manifest_hash": "sha256:abc",
+				"vulnerabilities": {
+					"1": {"name": "CVE-2023-0001", "normalized_severity": "Critical"},
+					"2": {"name": "CVE-2023-0002", "normalized_severity": "Medium"},
+					"3": {"name": "CVE-2023-0003", "normalized_severity": "Negligible"}
+				}
+			}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s := New(srv.URL + "/")
+	ref, _ := image.Parse("quay.io/org/app:1.0.0")
+
+	report, err := s.Scan(&scanner.Target{Image: ref, Digest: "sha256:abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.AtLeast(scanner.SeverityHigh) != 1 || report.AtLeast(scanner.SeverityLow) != 3 {
+		t.Errorf("unexpected counts: %v", report.Counts)
+	}
+
+	if _, err := s.Scan(&scanner.Target{Image: ref, Digest: "sha256:def"}); err != scanner.ErrNotScanned {
+		t.Errorf("expected not scanned error, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package harbor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/extension/scanner"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Harbor configuration
+const (
+	// EnvURL - Harbor address, for example https://harbor.example.com
+	EnvURL = "HARBOR_URL"
+	// EnvUsername, EnvPassword - Harbor user (or robot account) allowed to read scan
+	// results, image pull credentials are used when not set
+	EnvUsername = "HARBOR_USERNAME"
+	EnvPassword = "HARBOR_PASSWORD"
+)
+
+func init() {
+	scanner.RegisterScanner("harbor", New(os.Getenv(EnvURL), os.Getenv(EnvUsername), os.Getenv(EnvPassword)))
+}
+
+// Scanner - reads scan results Harbor keeps for artifacts of its registry
+type Scanner struct {
+	url      string
+	host     string
+	username string
+	password string
+	client   *http.Client
+}
+
+// New creates a new instance of Harbor scanner, it's enabled when Harbor url is set
+func New(harborURL, username, password string) *Scanner {
+	s := &Scanner{
+		url:      strings.TrimSuffix(harborURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if u, err := url.Parse(s.url); err == nil {
+		s.host = u.Host
+	}
+	return s
+}
+
+// IsEnabled returns a bool whether this scanner is enabled
+func (s *Scanner) IsEnabled() bool {
+	return s.url != ""
+}
+
+type artifact struct {
+	ScanOverview map[string]struct {
+		ScanStatus string `json:"scan_status"`
+		Summary    struct {
+			Summary map[string]int `json:"summary"`
+		} `json:"summary"`
+	} `json:"scan_overview"`
+}
+
+// Scan - gets scan overview of the artifact, only images of this Harbor registry are supported
+func (s *Scanner) Scan(target *scanner.Target) (*scanner.Report, error) {
+	if target.Image.Registry() != s.host {
+		return nil, scanner.ErrUnsupportedRegistry
+	}
+
+	// project/repository, repository itself may contain slashes
+	parts := strings.SplitN(target.Image.ShortName(), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("image %s isn't in a Harbor project", target.Image.Remote())
+	}
+	reference := target.Digest
+	if reference == "" {
+		reference = target.Image.Tag()
+	}
+
+	// repository name has to be encoded twice
+	u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s?with_scan_overview=true",
+		s.url, url.PathEscape(parts[0]), url.PathEscape(url.PathEscape(parts[1])), url.PathEscape(reference))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	} else if target.Credentials != nil && target.Credentials.Username != "" {
+		req.SetBasicAuth(target.Credentials.Username, target.Credentials.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected harbor response status: %s", resp.Status)
+	}
+
+	var a artifact
+	if err := json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return nil, fmt.Errorf("failed to decode harbor artifact: %s", err)
+	}
+
+	// overview is keyed by report mime type, there is one per scanner
+	result := &scanner.Report{Counts: make(map[scanner.Severity]int)}
+	scanned := false
+	for _, overview := range a.ScanOverview {
+		if overview.ScanStatus != "Success" {
+			continue
+		}
+		scanned = true
+		for name, n := range overview.Summary.Summary {
+			severity, err := scanner.ParseSeverity(name)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"image": target.Image.Remote(),
+				}).Warn("scanner.harbor: unknown severity")
+			}
+			if n > result.Counts[severity] {
+				result.Counts[severity] = n
+			}
+		}
+	}
+	if !scanned {
+		return nil, scanner.ErrNotScanned
+	}
+	return result, nil
+}
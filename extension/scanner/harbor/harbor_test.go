@@ -0,0 +1,60 @@
+package harbor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/scanner"
+	"github.com/keel-hq/keel/util/image"
+)
+
+func TestScan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "robot$keel" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("with_scan_overview") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/v2.0/projects/library/repositories/team%252Fapp/artifacts/sha256:abc":
+			fmt.Fprint(w, `{"scan_overview": {"application/vnd.security.vulnerability.report; version=1.1": {
+				"scan_status": "Success",
+				"severity": "High",
+				"summary": {"total": 5, "summary": {"High": 2, "Medium": 3}}
+			}}}`)
+		case "/api/v2.0/projects/library/repositories/team%252Fapp/artifacts/sha256:def":
+			fmt.Fprint(w, `{"scan_overview": {"application/vnd.security.vulnerability.report; version=1.1": {"scan_status": "Running"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s := New(srv.URL, "robot$keel", "token")
+	host := strings.TrimPrefix(srv.URL, "http://")
+	ref, _ := image.Parse(host + "/library/team/app:1.0.0")
+
+	report, err := s.Scan(&scanner.Target{Image: ref, Digest: "sha256:abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.Counts[scanner.SeverityHigh] != 2 || report.Counts[scanner.SeverityMedium] != 3 {
+		t.Errorf("unexpected counts: %v", report.Counts)
+	}
+
+	if _, err := s.Scan(&scanner.Target{Image: ref, Digest: "sha256:def"}); err != scanner.ErrNotScanned {
+		t.Errorf("expected not scanned error, got %v", err)
+	}
+
+	other, _ := image.Parse("quay.io/org/app:1.0.0")
+	if _, err := s.Scan(&scanner.Target{Image: other, Digest: "sha256:abc"}); err != scanner.ErrUnsupportedRegistry {
+		t.Errorf("expected unsupported registry error, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Severity - vulnerability severity
+type Severity int
+
+// Available severities
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSeverity - parses severity names used by Trivy, Clair and Harbor
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "unknown", "none", "":
+		return SeverityUnknown, nil
+	case "negligible", "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high", "important":
+		return SeverityHigh, nil
+	case "critical", "defcon1":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", s)
+}
+
+// Target - image to scan, digest identifies the exact image that would be rolled out
+type Target struct {
+	Image       *image.Reference
+	Digest      string
+	Credentials *types.Credentials
+}
+
+// Report - vulnerabilities found in the image
+type Report struct {
+	Scanner string
+	// Counts - number of vulnerabilities by severity
+	Counts map[Severity]int
+}
+
+// AtLeast - number of vulnerabilities with severity at or above the threshold
+func (r *Report) AtLeast(threshold Severity) int {
+	count := 0
+	for severity, n := range r.Counts {
+		if severity >= threshold {
+			count += n
+		}
+	}
+	return count
+}
+
+// Summary - for example "2 critical, 5 high"
+func (r *Report) Summary() string {
+	var parts []string
+	for severity := SeverityCritical; severity >= SeverityUnknown; severity-- {
+		if n := r.Counts[severity]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, severity))
+		}
+	}
+	if len(parts) == 0 {
+		return "no vulnerabilities"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Scanner - vulnerability scanner integration
+type Scanner interface {
+	Scan(target *Target) (*Report, error)
+	IsEnabled() bool
+}
+
+// Common errors
+var (
+	// ErrNotScanned - scanner has no results for the image yet, update should be retried later
+	ErrNotScanned = errors.New("image hasn't been scanned yet")
+	// ErrUnsupportedRegistry - scanner doesn't scan images of this registry
+	ErrUnsupportedRegistry = errors.New("unsupported registry")
+	// ErrNoScanner - no enabled scanner supports the image
+	ErrNoScanner = errors.New("no scanner available for this image")
+)
+
+// reportTTL - reports are cached by image digest for this long as the same update is
+// evaluated on every poll
+const reportTTL = time.Hour
+
+var (
+	scannersM sync.RWMutex
+	scanners  = make(map[string]Scanner)
+
+	reportsM sync.Mutex
+	reports  = make(map[string]*cachedReport)
+)
+
+type cachedReport struct {
+	report  *Report
+	expires time.Time
+}
+
+// RegisterScanner - registering new scanner
+func RegisterScanner(name string, s Scanner) {
+	if name == "" {
+		panic("scanner: could not register a Scanner with an empty name")
+	}
+
+	if s == nil {
+		panic("scanner: could not register a nil Scanner")
+	}
+
+	scannersM.Lock()
+	defer scannersM.Unlock()
+
+	if _, dup := scanners[name]; dup {
+		panic("scanner: RegisterScanner called twice for " + name)
+	}
+
+	log.WithFields(log.Fields{
+		"name": name,
+	}).Info("extension.scanner: scanner registered")
+
+	scanners[name] = s
+}
+
+// UnregisterScanner - unregister existing scanner, used for testing
+func UnregisterScanner(name string) {
+	scannersM.Lock()
+	defer scannersM.Unlock()
+
+	delete(scanners, name)
+}
+
+// Enabled - whether any scanner is enabled
+func Enabled() bool {
+	scannersM.RLock()
+	defer scannersM.RUnlock()
+
+	for _, s := range scanners {
+		if s.IsEnabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan - report of the first enabled scanner (in name order) that supports the image
+func Scan(target *Target) (*Report, error) {
+	key := target.Image.Remote() + "@" + target.Digest
+
+	reportsM.Lock()
+	cached, ok := reports[key]
+	reportsM.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.report, nil
+	}
+
+	scannersM.RLock()
+	defer scannersM.RUnlock()
+
+	var names []string
+	for name := range scanners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := scanners[name]
+		if !s.IsEnabled() {
+			continue
+		}
+		report, err := s.Scan(target)
+		if err == ErrUnsupportedRegistry {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Scanner = name
+
+		reportsM.Lock()
+		reports[key] = &cachedReport{report: report, expires: time.Now().Add(reportTTL)}
+		reportsM.Unlock()
+
+		return report, nil
+	}
+	return nil, ErrNoScanner
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/util/image"
+)
+
+type fakeScanner struct {
+	enabled bool
+	report  *Report
+	err     error
+	scanned int
+}
+
+func (s *fakeScanner) Scan(target *Target) (*Report, error) {
+	s.scanned++
+	return s.report, s.err
+}
+
+func (s *fakeScanner) IsEnabled() bool {
+	return s.enabled
+}
+
+func mustParse(t *testing.T, name string) *image.Reference {
+	ref, err := image.Parse(name)
+	if err != nil {
+		t.Fatalf("failed to parse image: %s", err)
+	}
+	return ref
+}
+
+func TestScan(t *testing.T) {
+	unsupported := &fakeScanner{enabled: true, err: ErrUnsupportedRegistry}
+	disabled := &fakeScanner{report: &Report{}}
+	s := &fakeScanner{enabled: true, report: &Report{Counts: map[Severity]int{SeverityHigh: 2, SeverityLow: 3}}}
+	RegisterScanner("a-unsupported", unsupported)
+	RegisterScanner("b-disabled", disabled)
+	RegisterScanner("c-fake", s)
+	defer UnregisterScanner("a-unsupported")
+	defer UnregisterScanner("b-disabled")
+	defer UnregisterScanner("c-fake")
+
+	target := &Target{Image: mustParse(t, "karolisr/keel:0.2.0"), Digest: "sha256:abc"}
+	report, err := Scan(target)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.Scanner != "c-fake" || report.AtLeast(SeverityMedium) != 2 || report.AtLeast(SeverityUnknown) != 5 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if disabled.scanned != 0 {
+		t.Errorf("disabled scanner shouldn't be used")
+	}
+
+	// report is cached by digest
+	if _, err := Scan(target); err != nil || s.scanned != 1 {
+		t.Errorf("expected cached report, scanned %d times: %v", s.scanned, err)
+	}
+
+	s.err = ErrNotScanned
+	if _, err := Scan(&Target{Image: target.Image, Digest: "sha256:def"}); err != ErrNotScanned {
+		t.Errorf("expected not scanned error, got %v", err)
+	}
+}
+
+func TestScanNoScanner(t *testing.T) {
+	if _, err := Scan(&Target{Image: mustParse(t, "karolisr/keel:0.2.0"), Digest: "sha256:unscanned"}); err != ErrNoScanner {
+		t.Errorf("expected no scanner error, got %v", err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	r := &Report{Counts: map[Severity]int{SeverityCritical: 1, SeverityMedium: 4}}
+	if r.Summary() != "1 critical, 4 medium" {
+		t.Errorf("unexpected summary: %s", r.Summary())
+	}
+	if (&Report{}).Summary() != "no vulnerabilities" {
+		t.Errorf("unexpected empty summary")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := map[string]Severity{
+		"CRITICAL":   SeverityCritical,
+		"High":       SeverityHigh,
+		"moderate":   SeverityMedium,
+		"Negligible": SeverityLow,
+		"UNKNOWN":    SeverityUnknown,
+	}
+	for in, want := range tests {
+		got, err := ParseSeverity(in)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (%v)", in, want, got, err)
+		}
+	}
+	if _, err := ParseSeverity("severe"); err == nil {
+		t.Errorf("expected error")
+	}
+}
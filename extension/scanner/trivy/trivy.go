@@ -0,0 +1,138 @@
+package trivy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/extension/scanner"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Trivy configuration
+const (
+	// EnvServer - address of Trivy server, images are scanned in client mode so the
+	// vulnerability database is only kept by the server
+	EnvServer = "TRIVY_SERVER"
+	// EnvBinary - path to trivy binary, defaults to trivy in PATH
+	EnvBinary = "TRIVY_BINARY"
+	// EnvToken - Trivy server token
+	EnvToken = "TRIVY_TOKEN"
+)
+
+const scanTimeout = 5 * time.Minute
+
+func init() {
+	scanner.RegisterScanner("trivy", New())
+}
+
+// Scanner - scans images with trivy CLI against a Trivy server
+type Scanner struct {
+	server string
+	binary string
+	token  string
+
+	// run - executes trivy, replaced in tests
+	run func(ctx context.Context, binary string, args, env []string) ([]byte, error)
+}
+
+// New creates a new instance of Trivy scanner, it's enabled when Trivy server is set
+func New() *Scanner {
+	binary := os.Getenv(EnvBinary)
+	if binary == "" {
+		binary = "trivy"
+	}
+	return &Scanner{
+		server: os.Getenv(EnvServer),
+		binary: binary,
+		token:  os.Getenv(EnvToken),
+		run:    run,
+	}
+}
+
+// IsEnabled returns a bool whether this scanner is enabled
+func (s *Scanner) IsEnabled() bool {
+	return s.server != ""
+}
+
+type report struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// Scan - scans image by digest so the result matches the image that would be deployed
+func (s *Scanner) Scan(target *scanner.Target) (*scanner.Report, error) {
+	img := target.Image.Repository()
+	if target.Digest != "" {
+		img = img + "@" + target.Digest
+	} else {
+		img = target.Image.Remote()
+	}
+
+	args := []string{"image", "--server", s.server, "--format", "json", "--quiet", "--scanners", "vuln"}
+	if s.token != "" {
+		args = append(args, "--token", s.token)
+	}
+	args = append(args, img)
+
+	env := os.Environ()
+	if target.Credentials != nil && target.Credentials.Username != "" {
+		env = append(env, "TRIVY_USERNAME="+target.Credentials.Username, "TRIVY_PASSWORD="+target.Credentials.Password)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	out, err := s.run(ctx, s.binary, args, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var r report
+	if err := json.Unmarshal(out, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode trivy report: %s", err)
+	}
+
+	// the same vulnerability may be reported for several packages
+	seen := make(map[string]bool)
+	result := &scanner.Report{Counts: make(map[scanner.Severity]int)}
+	for _, res := range r.Results {
+		for _, v := range res.Vulnerabilities {
+			if seen[v.VulnerabilityID] {
+				continue
+			}
+			seen[v.VulnerabilityID] = true
+			severity, err := scanner.ParseSeverity(v.Severity)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":         err,
+					"vulnerability": v.VulnerabilityID,
+				}).Warn("scanner.trivy: unknown severity")
+			}
+			result.Counts[severity]++
+		}
+	}
+	return result, nil
+}
+
+func run(ctx context.Context, binary string, args, env []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("trivy failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
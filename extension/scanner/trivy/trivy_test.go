@@ -0,0 +1,74 @@
+package trivy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/scanner"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+const testReport = `{
+  "Results": [
+    {"Target": "alpine", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2023-0001", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2023-0002", "Severity": "HIGH"},
+      {"VulnerabilityID": "CVE-2023-0003", "Severity": "LOW"}
+    ]},
+    {"Target": "app", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2023-0002", "Severity": "HIGH"}
+    ]},
+    {"Target": "config"}
+  ]
+}`
+
+func TestScan(t *testing.T) {
+	var gotArgs, gotEnv []string
+	s := &Scanner{
+		server: "http://trivy:4954",
+		binary: "trivy",
+		token:  "secret",
+		run: func(ctx context.Context, binary string, args, env []string) ([]byte, error) {
+			gotArgs, gotEnv = args, env
+			return []byte(testReport), nil
+		},
+	}
+
+	ref, _ := image.Parse("registry.example.com/org/app:1.0.0")
+	report, err := s.Scan(&scanner.Target{
+		Image:       ref,
+		Digest:      "sha256:abc",
+		Credentials: &types.Credentials{Username: "user", Password: "pass"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.Counts[scanner.SeverityCritical] != 1 || report.Counts[scanner.SeverityHigh] != 1 || report.Counts[scanner.SeverityLow] != 1 {
+		t.Errorf("unexpected counts: %v", report.Counts)
+	}
+
+	args := strings.Join(gotArgs, " ")
+	if !strings.Contains(args, "--server http://trivy:4954") || !strings.Contains(args, "--token secret") || !strings.HasSuffix(args, "registry.example.com/org/app@sha256:abc") {
+		t.Errorf("unexpected args: %s", args)
+	}
+	env := strings.Join(gotEnv, " ")
+	if !strings.Contains(env, "TRIVY_USERNAME=user") || !strings.Contains(env, "TRIVY_PASSWORD=pass") {
+		t.Errorf("expected registry credentials in environment")
+	}
+}
+
+func TestScanFailed(t *testing.T) {
+	s := &Scanner{
+		server: "http://trivy:4954",
+		run: func(ctx context.Context, binary string, args, env []string) ([]byte, error) {
+			return nil, fmt.Errorf("trivy failed: exit status 1")
+		},
+	}
+	ref, _ := image.Parse("org/app:1.0.0")
+	if _, err := s.Scan(&scanner.Target{Image: ref, Digest: "sha256:abc"}); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,19 @@
+// Package awsauth - AWS sessions shared by ECR credentials helper and secrets resolver
+package awsauth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// NewSession - session for the region (default region from AWS_REGION when empty),
+// credentials come from the default chain: environment, IAM roles for service accounts
+// (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN injected by EKS), shared credentials
+// file, instance or task role
+func NewSession(region string) (*session.Session, error) {
+	cfg := &aws.Config{}
+	if region != "" {
+		cfg.Region = aws.String(region)
+	}
+	return session.NewSession(cfg)
+}
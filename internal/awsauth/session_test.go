@@ -0,0 +1,35 @@
+package awsauth
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+)
+
+func TestNewSessionWebIdentity(t *testing.T) {
+	for _, env := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_PROFILE"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	defer os.Unsetenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	defer os.Unsetenv("AWS_ROLE_ARN")
+	os.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "/does/not/exist/token")
+	os.Setenv("AWS_ROLE_ARN", "arn:aws:iam::528670773427:role/keel")
+
+	sess, err := NewSession("eu-west-1")
+	if err != nil {
+		t.Fatalf("failed to create session: %s", err)
+	}
+	if *sess.Config.Region != "eu-west-1" {
+		t.Errorf("unexpected region: %s", *sess.Config.Region)
+	}
+
+	// token is read when credentials are retrieved
+	_, err = sess.Config.Credentials.Get()
+	aerr, ok := err.(awserr.Error)
+	if !ok || aerr.Code() != stscreds.ErrCodeWebIdentity {
+		t.Errorf("expected web identity credentials, got error: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package awssecrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Env - environment variables whose values reference secrets or parameters, for example
+// SLACK_TOKEN=aws-sm:keel/slack#token. Variables are replaced with resolved values so
+// configuration reading environment doesn't need to know about them
+type Env struct {
+	resolver *Resolver
+
+	mu     sync.Mutex
+	refs   map[string]string // variable name to reference
+	values map[string]string // variable name to last resolved value
+}
+
+// ResolveEnv - replaces all referencing variables with their values, error lists
+// variables that couldn't be resolved
+func ResolveEnv(resolver *Resolver) (*Env, error) {
+	e := &Env{
+		resolver: resolver,
+		refs:     make(map[string]string),
+		values:   make(map[string]string),
+	}
+
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && IsReference(parts[1]) {
+			e.refs[parts[0]] = parts[1]
+		}
+	}
+
+	var failed []string
+	for _, name := range e.Names() {
+		value, err := resolver.Resolve(e.refs[name])
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"variable":  name,
+				"reference": e.refs[name],
+			}).Error("awssecrets: failed to resolve variable")
+			failed = append(failed, name)
+			continue
+		}
+		e.values[name] = value
+		os.Setenv(name, value)
+	}
+
+	if len(failed) > 0 {
+		return e, fmt.Errorf("failed to resolve %s", strings.Join(failed, ", "))
+	}
+	return e, nil
+}
+
+// Names - sorted names of referencing variables
+func (e *Env) Names() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.refs))
+	for name := range e.refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Refresh - resolves variables again, returns names of the ones that changed. Variables
+// that fail to resolve keep their previous value
+func (e *Env) Refresh() []string {
+	var changed []string
+	for _, name := range e.Names() {
+		e.mu.Lock()
+		ref := e.refs[name]
+		e.mu.Unlock()
+
+		value, err := e.resolver.Resolve(ref)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"variable":  name,
+				"reference": ref,
+			}).Warn("awssecrets: failed to refresh variable, keeping previous value")
+			continue
+		}
+
+		e.mu.Lock()
+		previous, ok := e.values[name]
+		e.values[name] = value
+		e.mu.Unlock()
+
+		if !ok || previous != value {
+			os.Setenv(name, value)
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// Watch - refreshes variables every interval until context is cancelled, onChange is
+// called with names of changed variables
+func (e *Env) Watch(ctx context.Context, interval time.Duration, onChange func(changed []string)) {
+	if len(e.Names()) == 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed := e.Refresh()
+			if len(changed) == 0 {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"variables": changed,
+			}).Info("awssecrets: referenced secrets changed")
+			onChange(changed)
+		}
+	}
+}
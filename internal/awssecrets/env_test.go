@@ -0,0 +1,105 @@
+package awssecrets
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveEnv(t *testing.T) {
+	fake := newFakeAWS()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	defer os.Unsetenv("KEEL_TEST_SLACK_TOKEN")
+	defer os.Unsetenv("KEEL_TEST_GITHUB_SECRET")
+	defer os.Unsetenv("KEEL_TEST_PLAIN")
+	os.Setenv("KEEL_TEST_SLACK_TOKEN", "aws-sm:keel/slack#token")
+	os.Setenv("KEEL_TEST_GITHUB_SECRET", "aws-ssm:/keel/github-secret")
+	os.Setenv("KEEL_TEST_PLAIN", "plain")
+
+	env, err := ResolveEnv(newTestResolver(srv, fake))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(env.Names(), []string{"KEEL_TEST_GITHUB_SECRET", "KEEL_TEST_SLACK_TOKEN"}) {
+		t.Errorf("unexpected variables: %v", env.Names())
+	}
+	if os.Getenv("KEEL_TEST_SLACK_TOKEN") != "xoxb-1" || os.Getenv("KEEL_TEST_GITHUB_SECRET") != "github-secret" {
+		t.Errorf("variables weren't replaced")
+	}
+	if os.Getenv("KEEL_TEST_PLAIN") != "plain" {
+		t.Errorf("unexpected plain value: %s", os.Getenv("KEEL_TEST_PLAIN"))
+	}
+
+	if changed := env.Refresh(); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+
+	fake.set("keel/slack", `{"token": "xoxb-2"}`)
+	if changed := env.Refresh(); !reflect.DeepEqual(changed, []string{"KEEL_TEST_SLACK_TOKEN"}) {
+		t.Errorf("unexpected changes: %v", changed)
+	}
+	if os.Getenv("KEEL_TEST_SLACK_TOKEN") != "xoxb-2" {
+		t.Errorf("variable wasn't refreshed: %s", os.Getenv("KEEL_TEST_SLACK_TOKEN"))
+	}
+
+	// value is kept when secret can't be read
+	fake.set("keel/slack", `not json`)
+	if changed := env.Refresh(); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+	if os.Getenv("KEEL_TEST_SLACK_TOKEN") != "xoxb-2" {
+		t.Errorf("previous value should be kept: %s", os.Getenv("KEEL_TEST_SLACK_TOKEN"))
+	}
+}
+
+func TestResolveEnvFailure(t *testing.T) {
+	fake := newFakeAWS()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	defer os.Unsetenv("KEEL_TEST_MISSING")
+	os.Setenv("KEEL_TEST_MISSING", "aws-sm:keel/missing")
+
+	if _, err := ResolveEnv(newTestResolver(srv, fake)); err == nil {
+		t.Errorf("expected error for missing secret")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fake := newFakeAWS()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	defer os.Unsetenv("KEEL_TEST_SLACK_TOKEN")
+	os.Setenv("KEEL_TEST_SLACK_TOKEN", "aws-sm:keel/slack#token")
+
+	env, err := ResolveEnv(newTestResolver(srv, fake))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fake.set("keel/slack", `{"token": "xoxb-2"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []string, 1)
+	go env.Watch(ctx, 10*time.Millisecond, func(changed []string) {
+		changes <- changed
+		cancel()
+	})
+
+	select {
+	case changed := <-changes:
+		if !reflect.DeepEqual(changed, []string{"KEEL_TEST_SLACK_TOKEN"}) {
+			t.Errorf("unexpected changes: %v", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("change wasn't reported")
+	}
+}
@@ -0,0 +1,170 @@
+// Package awssecrets - resolves configuration values referencing AWS Secrets Manager
+// secrets or SSM Parameter Store parameters
+package awssecrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+
+	"github.com/keel-hq/keel/internal/awsauth"
+)
+
+// Reference prefixes, values are either:
+//
+//	aws-sm:<secret name or ARN>[#<JSON key>]
+//	aws-ssm:<parameter name or ARN>
+//
+// Region is taken from the ARN, names are looked up in the default region (AWS_REGION).
+// JSON key selects a single field of a secret storing JSON object
+const (
+	SecretsManagerPrefix = "aws-sm:"
+	ParameterStorePrefix = "aws-ssm:"
+)
+
+// IsReference - whether value references a secret or parameter
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, SecretsManagerPrefix) || strings.HasPrefix(value, ParameterStorePrefix)
+}
+
+// Resolver - reads referenced values, clients are created once per region so their
+// credentials are reused
+type Resolver struct {
+	newSession func(region string) (client.ConfigProvider, error)
+
+	mu      sync.Mutex
+	clients map[string]*regionClients
+}
+
+type regionClients struct {
+	sm  *secretsmanager.SecretsManager
+	ssm *ssm.SSM
+}
+
+// NewResolver - resolver using IAM roles for service accounts or the default
+// credentials chain
+func NewResolver() *Resolver {
+	return &Resolver{
+		newSession: func(region string) (client.ConfigProvider, error) {
+			return awsauth.NewSession(region)
+		},
+		clients: make(map[string]*regionClients),
+	}
+}
+
+// Resolve - returns current value of the referenced secret or parameter
+func (r *Resolver) Resolve(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, SecretsManagerPrefix):
+		id, key := splitKey(strings.TrimPrefix(ref, SecretsManagerPrefix))
+		c, err := r.clientsFor(id)
+		if err != nil {
+			return "", err
+		}
+		out, err := c.sm.GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+		if err != nil {
+			return "", wrapError("secret", id, err)
+		}
+		value := aws.StringValue(out.SecretString)
+		if out.SecretString == nil {
+			value = string(out.SecretBinary)
+		}
+		if key == "" {
+			return value, nil
+		}
+		return jsonField(value, key)
+	case strings.HasPrefix(ref, ParameterStorePrefix):
+		name := strings.TrimPrefix(ref, ParameterStorePrefix)
+		c, err := r.clientsFor(name)
+		if err != nil {
+			return "", err
+		}
+		out, err := c.ssm.GetParameter(&ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+		if err != nil {
+			return "", wrapError("parameter", name, err)
+		}
+		if out.Parameter == nil {
+			return "", fmt.Errorf("parameter %s has no value", name)
+		}
+		return aws.StringValue(out.Parameter.Value), nil
+	}
+	return "", fmt.Errorf("%q is not a secret reference", ref)
+}
+
+// clientsFor - clients of the region in the ARN, default region for names
+func (r *Resolver) clientsFor(id string) (*regionClients, error) {
+	region := arnRegion(id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.clients[region]; ok {
+		return c, nil
+	}
+	sess, err := r.newSession(region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %s", err)
+	}
+	c := &regionClients{sm: secretsmanager.New(sess), ssm: ssm.New(sess)}
+	r.clients[region] = c
+	return c, nil
+}
+
+// IsNotFound - whether the error reports missing secret or parameter
+func IsNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException || aerr.Code() == ssm.ErrCodeParameterNotFound
+	}
+	return false
+}
+
+// wrapError - adds context to errors other than not found, those are kept for IsNotFound
+func wrapError(kind, id string, err error) error {
+	if IsNotFound(err) {
+		return err
+	}
+	return fmt.Errorf("failed to get %s %s: %s", kind, id, err)
+}
+
+// arnRegion - region of arn:partition:service:region:account:resource, empty for names
+func arnRegion(id string) string {
+	parts := strings.SplitN(id, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
+
+func splitKey(ref string) (id, key string) {
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// jsonField - string (or other scalar as JSON) field of a secret storing JSON object
+func jsonField(value, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %s", err)
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no %q key", key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(bts), nil
+}
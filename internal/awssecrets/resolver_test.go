@@ -0,0 +1,158 @@
+package awssecrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// fakeAWS - Secrets Manager and SSM JSON RPC APIs
+type fakeAWS struct {
+	mu         sync.Mutex
+	secrets    map[string]string
+	parameters map[string]string
+	regions    map[string]int
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var input map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	switch r.Header.Get("X-Amz-Target") {
+	case "secretsmanager.GetSecretValue":
+		value, ok := f.secrets[input["SecretId"].(string)]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Name": input["SecretId"].(string), "SecretString": value})
+	case "AmazonSSM.GetParameter":
+		value, ok := f.parameters[input["Name"].(string)]
+		if !ok || input["WithDecryption"] != true {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "ParameterNotFound"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"Parameter": {"Name": %q, "Value": %q}}`, input["Name"], value)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type": "UnknownOperationException"}`)
+	}
+}
+
+func (f *fakeAWS) set(secret, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[secret] = value
+}
+
+func newTestResolver(srv *httptest.Server, fake *fakeAWS) *Resolver {
+	return &Resolver{
+		newSession: func(region string) (client.ConfigProvider, error) {
+			fake.mu.Lock()
+			fake.regions[region]++
+			fake.mu.Unlock()
+			if region == "" {
+				region = "us-east-1"
+			}
+			return session.NewSession(&aws.Config{
+				Region:      aws.String(region),
+				Endpoint:    aws.String(srv.URL),
+				Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+			})
+		},
+		clients: make(map[string]*regionClients),
+	}
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{
+		secrets: map[string]string{
+			"keel/slack": `{"token": "xoxb-1", "channels": 2}`,
+			"arn:aws:secretsmanager:eu-west-1:123456789012:secret:keel/quay-AbCdEf": "quay-secret",
+		},
+		parameters: map[string]string{
+			"/keel/github-secret": "github-secret",
+		},
+		regions: make(map[string]int),
+	}
+}
+
+func TestResolve(t *testing.T) {
+	fake := newFakeAWS()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	r := newTestResolver(srv, fake)
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "aws-sm:keel/slack#token", want: "xoxb-1"},
+		{ref: "aws-sm:keel/slack#channels", want: "2"},
+		{ref: "aws-sm:keel/slack", want: `{"token": "xoxb-1", "channels": 2}`},
+		{ref: "aws-sm:keel/slack#missing", wantErr: true},
+		{ref: "aws-sm:arn:aws:secretsmanager:eu-west-1:123456789012:secret:keel/quay-AbCdEf", want: "quay-secret"},
+		{ref: "aws-ssm:/keel/github-secret", want: "github-secret"},
+		{ref: "aws-ssm:/keel/missing", wantErr: true},
+		{ref: "plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error: %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+
+	// clients are created once per region
+	if fake.regions[""] != 1 || fake.regions["eu-west-1"] != 1 {
+		t.Errorf("unexpected sessions: %v", fake.regions)
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	fake := newFakeAWS()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	r := newTestResolver(srv, fake)
+	for _, ref := range []string{"aws-sm:keel/missing", "aws-ssm:/keel/missing"} {
+		if _, err := r.Resolve(ref); !IsNotFound(err) {
+			t.Errorf("%s: expected not found error, got %v", ref, err)
+		}
+	}
+}
+
+func TestArnRegion(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:secretsmanager:eu-west-1:123456789012:secret:keel/quay-AbCdEf": "eu-west-1",
+		"arn:aws:ssm:us-east-2:123456789012:parameter/keel/token":               "us-east-2",
+		"keel/quay":                  "",
+		"/keel/token":                "",
+		"arn:aws:secretsmanager:bad": "",
+	}
+	for id, want := range tests {
+		if got := arnRegion(id); got != want {
+			t.Errorf("%s: expected %q, got %q", id, want, got)
+		}
+	}
+}
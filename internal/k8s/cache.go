@@ -2,12 +2,54 @@ package k8s
 
 import (
 	"sort"
+	"strings"
 	"sync"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 )
 
 type genericResourceCache struct {
 	sync.Mutex
 	values []*GenericResource
+
+	// index - image repository to resource identifiers index
+	index map[string]map[string]bool
+
+	// defaults - optional source of default annotations for returned resources
+	defaults Defaulter
+}
+
+// Defaulter - provides default annotations for workloads, for example from
+// KeelPolicy resources
+type Defaulter interface {
+	Defaults(namespace string, labels map[string]string) map[string]string
+}
+
+// SetDefaulter - sets source of default annotations, they are applied to
+// resources returned by Values and ValuesByRepository
+func (cc *genericResourceCache) SetDefaulter(defaults Defaulter) {
+	cc.Lock()
+	cc.defaults = defaults
+	cc.Unlock()
+}
+
+// copy returns a copy of a cached resource with default annotations applied
+func (cc *genericResourceCache) copy(gr *GenericResource) *GenericResource {
+	c := gr.DeepCopy()
+	if cc.defaults != nil && inheritsDefaults(c) {
+		c.SetDefaultAnnotations(cc.defaults.Defaults(c.Namespace, c.GetLabels()))
+	}
+	return c
+}
+
+// inheritsDefaults - workloads can opt out of default annotations with keel.sh/inherit-defaults=false
+func inheritsDefaults(gr *GenericResource) bool {
+	value, ok := gr.GetLabels()[types.KeelInheritDefaultsAnnotation]
+	if !ok {
+		value = gr.GetResourceAnnotations()[types.KeelInheritDefaultsAnnotation]
+	}
+	return strings.ToLower(strings.TrimSpace(value)) != "false"
 }
 
 // GenericResourceCache - storage for generic resources with a rendezvous point for goroutines
@@ -22,9 +64,33 @@ func (cc *genericResourceCache) Values() []*GenericResource {
 	cc.Lock()
 	r := []*GenericResource{}
 	for _, v := range cc.values {
-		r = append(r, v.DeepCopy())
+		r = append(r, cc.copy(v))
+	}
+	cc.Unlock()
+	return r
+}
+
+// ValuesByRepository returns a copy of cached resources that are using
+// specified image repository (for example "gcr.io/v2-namespace/hello-world",
+// Docker Hub images are in "index.docker.io/library/redis" form), repositories
+// are matched according to image match mode.
+func (cc *genericResourceCache) ValuesByRepository(repository string) []*GenericResource {
+	r := []*GenericResource{}
+	ref, err := image.Parse(repository)
+	if err != nil {
+		return r
+	}
+	key := image.MatchKey(ref)
+
+	cc.Lock()
+	for identifier := range cc.index[key] {
+		i := sort.Search(len(cc.values), func(i int) bool { return cc.values[i].Identifier >= identifier })
+		if i < len(cc.values) && cc.values[i].Identifier == identifier {
+			r = append(r, cc.copy(cc.values[i]))
+		}
 	}
 	cc.Unlock()
+	sort.Sort(genericResource(r))
 	return r
 }
 
@@ -48,6 +114,7 @@ func (cc *genericResourceCache) add(c *GenericResource) {
 	i := sort.Search(len(cc.values), func(i int) bool { return cc.values[i].Identifier >= c.Identifier })
 	if i < len(cc.values) && cc.values[i].Identifier == c.Identifier {
 		// c is already present, replace
+		cc.unindex(cc.values[i])
 		cc.values[i] = c
 	} else {
 		// c is not present, append
@@ -55,6 +122,7 @@ func (cc *genericResourceCache) add(c *GenericResource) {
 		// restort to convert append into insert
 		sort.Sort(genericResource(cc.values))
 	}
+	cc.reindex(c)
 }
 
 // Remove removes the named entry from the cache. If the entry
@@ -77,10 +145,47 @@ func (cc *genericResourceCache) remove(identifier string) {
 	i := sort.Search(len(cc.values), func(i int) bool { return cc.values[i].Identifier >= identifier })
 	if i < len(cc.values) && cc.values[i].Identifier == identifier {
 		// c is present, remove
+		cc.unindex(cc.values[i])
 		cc.values = append(cc.values[:i], cc.values[i+1:]...)
 	}
 }
 
+// reindex adds resource to image repository index, index is keyed by image match key
+func (cc *genericResourceCache) reindex(c *GenericResource) {
+	if cc.index == nil {
+		cc.index = make(map[string]map[string]bool)
+	}
+	for _, repository := range getRepositories(c) {
+		if cc.index[repository] == nil {
+			cc.index[repository] = make(map[string]bool)
+		}
+		cc.index[repository][c.Identifier] = true
+	}
+}
+
+// unindex removes resource from image repository index
+func (cc *genericResourceCache) unindex(c *GenericResource) {
+	for _, repository := range getRepositories(c) {
+		delete(cc.index[repository], c.Identifier)
+		if len(cc.index[repository]) == 0 {
+			delete(cc.index, repository)
+		}
+	}
+}
+
+func getRepositories(c *GenericResource) []string {
+	var repositories []string
+	// init container images are indexed as well, providers decide whether to update them
+	for _, img := range append(c.GetImages(), c.GetInitImages()...) {
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		repositories = append(repositories, image.MatchKey(ref))
+	}
+	return repositories
+}
+
 // Cond implements a condition variable, a rendezvous point for goroutines
 // waiting for or announcing the occurence of an event.
 type Cond struct {
@@ -51,3 +51,58 @@ func TestAddGet(t *testing.T) {
 		t.Errorf("cached entry got modified: %s", stored2.Containers()[0].Image)
 	}
 }
+
+func TestValuesByRepository(t *testing.T) {
+	cc := &GenericResourceCache{}
+
+	newDeployment := func(name string, images ...string) *GenericResource {
+		var containers []core_v1.Container
+		for _, img := range images {
+			containers = append(containers, core_v1.Container{Image: img})
+		}
+		gr, err := NewGenericResource(&apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "xxxx"},
+			Spec: apps_v1.DeploymentSpec{
+				Template: core_v1.PodTemplateSpec{
+					Spec: core_v1.PodSpec{Containers: containers},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create generic resource: %s", err)
+		}
+		return gr
+	}
+
+	cc.Add(
+		newDeployment("dep-1", "gcr.io/v2-namespace/hi-world:1.1.1", "redis:4"),
+		newDeployment("dep-2", "gcr.io/v2-namespace/hi-world:1.2.0"),
+		newDeployment("dep-3", "gcr.io/v2-namespace/bye-world:1.1.1"),
+	)
+
+	found := cc.ValuesByRepository("gcr.io/v2-namespace/hi-world")
+	if len(found) != 2 {
+		t.Fatalf("expected to find 2 resources, got: %d", len(found))
+	}
+	if found[0].Name != "dep-1" || found[1].Name != "dep-2" {
+		t.Errorf("unexpected resources: %s, %s", found[0].Name, found[1].Name)
+	}
+
+	if found := cc.ValuesByRepository("index.docker.io/library/redis"); len(found) != 1 {
+		t.Errorf("expected to find 1 redis resource, got: %d", len(found))
+	}
+
+	// image changed, resource should be reindexed
+	cc.Add(newDeployment("dep-2", "gcr.io/v2-namespace/bye-world:1.2.0"))
+	if found := cc.ValuesByRepository("gcr.io/v2-namespace/hi-world"); len(found) != 1 {
+		t.Errorf("expected to find 1 resource after update, got: %d", len(found))
+	}
+	if found := cc.ValuesByRepository("gcr.io/v2-namespace/bye-world"); len(found) != 2 {
+		t.Errorf("expected to find 2 resources after update, got: %d", len(found))
+	}
+
+	cc.Remove("deployment/xxxx/dep-3")
+	if found := cc.ValuesByRepository("gcr.io/v2-namespace/bye-world"); len(found) != 1 {
+		t.Errorf("expected to find 1 resource after removal, got: %d", len(found))
+	}
+}
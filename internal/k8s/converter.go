@@ -1,9 +1,14 @@
 package k8s
 
 import (
+	"fmt"
+
 	apps_v1 "k8s.io/api/apps/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func getContainerImages(containers []core_v1.Container) []string {
@@ -61,3 +66,137 @@ func getCronJobIdentifier(s *v1beta1.CronJob) string {
 func updateCronJobContainer(s *v1beta1.CronJob, index int, image string) {
 	s.Spec.JobTemplate.Spec.Template.Spec.Containers[index].Image = image
 }
+
+// custom resources with a pod template in spec.template, handled as unstructured objects:
+// argo rollouts https://argoproj.github.io/argo-rollouts/ and
+// knative services https://knative.dev/docs/serving/. Jobs are handled the same way so fields
+// unknown to the vendored API (such as spec.suspend) survive Job re-creation.
+
+type customResource struct {
+	kind     string
+	resource schema.GroupVersionResource
+}
+
+var customResources = map[schema.GroupKind]customResource{
+	{Group: RolloutResource.Group, Kind: "Rollout"}:        {kind: "rollout", resource: RolloutResource},
+	{Group: KnativeServiceResource.Group, Kind: "Service"}: {kind: "knativeservice", resource: KnativeServiceResource},
+	{Group: JobResource.Group, Kind: "Job"}:                {kind: "job", resource: JobResource},
+}
+
+func getCustomResource(u *unstructured.Unstructured) (customResource, bool) {
+	cr, ok := customResources[u.GroupVersionKind().GroupKind()]
+	return cr, ok
+}
+
+// CustomResource - returns group, version and resource of a supported custom resource
+func CustomResource(u *unstructured.Unstructured) (schema.GroupVersionResource, bool) {
+	cr, ok := getCustomResource(u)
+	if !ok {
+		return schema.GroupVersionResource{}, false
+	}
+	gvr := cr.resource
+	if version := u.GroupVersionKind().Version; version != "" {
+		gvr.Version = version
+	}
+	return gvr, true
+}
+
+func getCustomResourceKind(u *unstructured.Unstructured) string {
+	cr, _ := getCustomResource(u)
+	return cr.kind
+}
+
+func getCustomResourceIdentifier(u *unstructured.Unstructured) string {
+	return getCustomResourceKind(u) + "/" + u.GetNamespace() + "/" + u.GetName()
+}
+
+// getCustomResourceContainers - containers were validated by NewGenericResource
+// so conversion errors aren't expected here
+func getCustomResourceContainers(u *unstructured.Unstructured) []core_v1.Container {
+	containers, _ := getCustomResourceContainerList(u, "containers")
+	return containers
+}
+
+func getCustomResourceInitContainers(u *unstructured.Unstructured) []core_v1.Container {
+	containers, _ := getCustomResourceContainerList(u, "initContainers")
+	return containers
+}
+
+// validateCustomResourceContainers - containers are updated by their index in
+// spec.template.spec, every item has to convert so the indices match
+func validateCustomResourceContainers(u *unstructured.Unstructured) error {
+	for _, field := range []string{"containers", "initContainers"} {
+		if _, err := getCustomResourceContainerList(u, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getCustomResourceContainerList(u *unstructured.Unstructured, field string) ([]core_v1.Container, error) {
+	items, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", field)
+	var containers []core_v1.Container
+	for idx, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s[%d]: unexpected type %T", field, idx, item)
+		}
+		var c core_v1.Container
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s[%d]: %s", field, idx, err)
+		}
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+func getCustomResourceImagePullSecrets(u *unstructured.Unstructured) []string {
+	items, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "imagePullSecrets")
+	var secrets []string
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := m["name"].(string); ok {
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets
+}
+
+func updateCustomResourceContainer(u *unstructured.Unstructured, index int, image string) {
+	updateCustomResourceContainerList(u, "containers", index, image)
+}
+
+func updateCustomResourceInitContainer(u *unstructured.Unstructured, index int, image string) {
+	updateCustomResourceContainerList(u, "initContainers", index, image)
+}
+
+func updateCustomResourceContainerList(u *unstructured.Unstructured, field string, index int, image string) {
+	items, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", field)
+	if index >= len(items) {
+		return
+	}
+	m, ok := items[index].(map[string]interface{})
+	if !ok {
+		return
+	}
+	m["image"] = image
+	unstructured.SetNestedSlice(u.Object, items, "spec", "template", "spec", field)
+}
+
+func getCustomResourceSpecAnnotations(u *unstructured.Unstructured) map[string]string {
+	annotations, _, _ := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "annotations")
+	return annotations
+}
+
+func setCustomResourceSpecAnnotations(u *unstructured.Unstructured, annotations map[string]string) {
+	unstructured.SetNestedStringMap(u.Object, annotations, "spec", "template", "metadata", "annotations")
+}
+
+func getCustomResourceStatusField(u *unstructured.Unstructured, field string) int32 {
+	val, _, _ := unstructured.NestedInt64(u.Object, "status", field)
+	return int32(val)
+}
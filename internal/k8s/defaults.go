@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Defaulters - combines several sources of default annotations, earlier
+// defaulters take precedence, for example KeelPolicies, NamespaceDefaults, StaticDefaults
+type Defaulters []Defaulter
+
+// Defaults - merged default annotations from all defaulters
+func (d Defaulters) Defaults(namespace string, labels map[string]string) map[string]string {
+	var defaults map[string]string
+	for i := len(d) - 1; i >= 0; i-- {
+		for key, value := range d[i].Defaults(namespace, labels) {
+			if defaults == nil {
+				defaults = make(map[string]string)
+			}
+			defaults[key] = value
+		}
+	}
+	return defaults
+}
+
+// StaticDefaults - cluster wide default annotations, for example default policy
+// set in keel configuration
+type StaticDefaults map[string]string
+
+// Defaults - returns the same annotations for all workloads
+func (s StaticDefaults) Defaults(namespace string, labels map[string]string) map[string]string {
+	return s
+}
+
+// NamespaceDefaults - keel.sh/* annotations set on Namespaces are inherited by workloads
+// in them, implements cache.ResourceEventHandler
+type NamespaceDefaults struct {
+	logrus.FieldLogger
+
+	mu         sync.RWMutex
+	namespaces map[string]map[string]string
+}
+
+// NewNamespaceDefaults - creates empty namespace defaults storage
+func NewNamespaceDefaults(log logrus.FieldLogger) *NamespaceDefaults {
+	return &NamespaceDefaults{
+		FieldLogger: log,
+		namespaces:  make(map[string]map[string]string),
+	}
+}
+
+// Defaults - returns keel annotations of the workload namespace
+func (n *NamespaceDefaults) Defaults(namespace string, labels map[string]string) map[string]string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.namespaces[namespace]
+}
+
+func (n *NamespaceDefaults) OnAdd(obj interface{}) {
+	ns, ok := obj.(*v1.Namespace)
+	if !ok {
+		n.Errorf("OnAdd unexpected Namespace type %T", obj)
+		return
+	}
+
+	var defaults map[string]string
+	for key, value := range ns.GetAnnotations() {
+		if strings.HasPrefix(key, "keel.sh/") {
+			if defaults == nil {
+				defaults = make(map[string]string)
+			}
+			defaults[key] = value
+		}
+	}
+
+	n.mu.Lock()
+	if defaults == nil {
+		delete(n.namespaces, ns.Name)
+	} else {
+		n.Debugf("namespace %s defaults: %v", ns.Name, defaults)
+		n.namespaces[ns.Name] = defaults
+	}
+	n.mu.Unlock()
+}
+
+func (n *NamespaceDefaults) OnUpdate(oldObj, newObj interface{}) {
+	n.OnAdd(newObj)
+}
+
+func (n *NamespaceDefaults) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ns, ok := obj.(*v1.Namespace)
+	if !ok {
+		n.Errorf("OnDelete unexpected Namespace type %T", obj)
+		return
+	}
+	n.mu.Lock()
+	delete(n.namespaces, ns.Name)
+	n.mu.Unlock()
+}
+
+// WatchNamespaces creates a SharedInformer for v1.Namespace and registers it with g.
+func WatchNamespaces(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
+	watch(g, client.CoreV1().RESTClient(), log, "", "namespaces", new(v1.Namespace), rs...)
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/sirupsen/logrus"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceDefaults(t *testing.T) {
+	namespaces := NewNamespaceDefaults(logrus.New())
+	namespaces.OnAdd(&core_v1.Namespace{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "prod",
+			Annotations: map[string]string{
+				types.KeelPolicyLabel:           "patch",
+				types.KeelMinimumApprovalsLabel: "1",
+				"owner":                         "team-a",
+			},
+		},
+	})
+
+	defaults := namespaces.Defaults("prod", nil)
+	if len(defaults) != 2 || defaults[types.KeelPolicyLabel] != "patch" {
+		t.Errorf("unexpected defaults: %v", defaults)
+	}
+	if defaults := namespaces.Defaults("staging", nil); defaults != nil {
+		t.Errorf("expected no defaults, got: %v", defaults)
+	}
+
+	// annotation removed
+	namespaces.OnUpdate(nil, &core_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "prod"}})
+	if defaults := namespaces.Defaults("prod", nil); defaults != nil {
+		t.Errorf("expected no defaults, got: %v", defaults)
+	}
+}
+
+func TestDefaultersPrecedence(t *testing.T) {
+	namespaces := NewNamespaceDefaults(logrus.New())
+	namespaces.OnAdd(&core_v1.Namespace{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "prod",
+			Annotations: map[string]string{types.KeelPolicyLabel: "patch"},
+		},
+	})
+	global := StaticDefaults{types.KeelPolicyLabel: "minor", types.KeelTriggerLabel: "poll"}
+
+	cc := &GenericResourceCache{}
+	cc.SetDefaulter(Defaulters{namespaces, global})
+
+	for _, d := range []*apps_v1.Deployment{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "prod"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-2", Namespace: "staging"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-3", Namespace: "staging", Annotations: map[string]string{types.KeelPolicyLabel: "never"}}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-4", Namespace: "prod", Labels: map[string]string{types.KeelInheritDefaultsAnnotation: "false"}}},
+	} {
+		d.Spec.Template.Spec.Containers = []core_v1.Container{{Image: "gcr.io/v2-namespace/hi-world:1.1.1"}}
+		gr, err := NewGenericResource(d)
+		if err != nil {
+			t.Fatalf("failed to create generic resource: %s", err)
+		}
+		cc.Add(gr)
+	}
+
+	expected := map[string]string{
+		"deployment/prod/dep-1":    "patch",
+		"deployment/staging/dep-2": "minor",
+		"deployment/staging/dep-3": "never",
+	}
+	for _, gr := range cc.Values() {
+		annotations := gr.GetAnnotations()
+		if gr.Identifier == "deployment/prod/dep-4" {
+			if len(annotations) != 0 {
+				t.Errorf("expected opted out workload not to inherit defaults, got: %v", annotations)
+			}
+			continue
+		}
+		if annotations[types.KeelPolicyLabel] != expected[gr.Identifier] {
+			t.Errorf("%s: expected policy %s, got: %s", gr.Identifier, expected[gr.Identifier], annotations[types.KeelPolicyLabel])
+		}
+		if annotations[types.KeelTriggerLabel] != "poll" {
+			t.Errorf("%s: expected global trigger, got: %s", gr.Identifier, annotations[types.KeelTriggerLabel])
+		}
+	}
+}
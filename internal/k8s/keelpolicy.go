@@ -0,0 +1,206 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/keel-hq/keel/types"
+	"github.com/sirupsen/logrus"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KeelPolicyResource - KeelPolicy custom resource definition group, version and resource
+var KeelPolicyResource = schema.GroupVersionResource{
+	Group:    "keel.sh",
+	Version:  "v1alpha1",
+	Resource: "keelpolicies",
+}
+
+// KeelPolicySpec - update rules for workloads matching the selector, settings
+// are equivalent to keel.sh/* labels and annotations
+type KeelPolicySpec struct {
+	// Selector - workloads in the same namespace matching this selector
+	// inherit the settings, empty selector matches all workloads
+	Selector *meta_v1.LabelSelector `json:"selector,omitempty"`
+
+	Policy           string `json:"policy,omitempty"`
+	Trigger          string `json:"trigger,omitempty"`
+	PollSchedule     string `json:"pollSchedule,omitempty"`
+	Approvals        *int   `json:"approvals,omitempty"`
+	ApprovalDeadline *int   `json:"approvalDeadline,omitempty"`
+	MatchTag         *bool  `json:"matchTag,omitempty"`
+	MatchPreRelease  *bool  `json:"matchPreRelease,omitempty"`
+	UpdateWindow     string `json:"updateWindow,omitempty"`
+
+	ApprovalExpiry     string   `json:"approvalExpiry,omitempty"`
+	EscalationChannels []string `json:"escalationChannels,omitempty"`
+	Approvers          []string `json:"approvers,omitempty"`
+}
+
+// KeelPolicy - namespaced KeelPolicy custom resource
+type KeelPolicy struct {
+	Name      string
+	Namespace string
+	Spec      KeelPolicySpec
+
+	selector labels.Selector
+}
+
+// NewKeelPolicy - converts unstructured KeelPolicy resource
+func NewKeelPolicy(obj *unstructured.Unstructured) (*KeelPolicy, error) {
+	kp := &KeelPolicy{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if ok {
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &kp.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec: %s", err)
+		}
+	}
+
+	if kp.Spec.Selector == nil {
+		kp.selector = labels.Everything()
+		return kp, nil
+	}
+
+	selector, err := meta_v1.LabelSelectorAsSelector(kp.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %s", err)
+	}
+	kp.selector = selector
+
+	return kp, nil
+}
+
+// Matches - checks whether workload is selected by the policy
+func (kp *KeelPolicy) Matches(namespace string, lbls map[string]string) bool {
+	return kp.Namespace == namespace && kp.selector.Matches(labels.Set(lbls))
+}
+
+// Annotations - returns policy settings as keel annotations
+func (kp *KeelPolicy) Annotations() map[string]string {
+	annotations := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	set(types.KeelPolicyLabel, kp.Spec.Policy)
+	set(types.KeelTriggerLabel, kp.Spec.Trigger)
+	set(types.KeelPollScheduleAnnotation, kp.Spec.PollSchedule)
+	set(types.KeelUpdateWindowAnnotation, kp.Spec.UpdateWindow)
+	set(types.KeelApprovalExpiryAnnotation, kp.Spec.ApprovalExpiry)
+	set(types.KeelApprovalEscalationChannelsAnnotation, strings.Join(kp.Spec.EscalationChannels, ","))
+	set(types.KeelApproversAnnotation, strings.Join(kp.Spec.Approvers, ","))
+	if kp.Spec.Approvals != nil {
+		set(types.KeelMinimumApprovalsLabel, strconv.Itoa(*kp.Spec.Approvals))
+	}
+	if kp.Spec.ApprovalDeadline != nil {
+		set(types.KeelApprovalDeadlineLabel, strconv.Itoa(*kp.Spec.ApprovalDeadline))
+	}
+	if kp.Spec.MatchTag != nil {
+		set(types.KeelForceTagMatchLabel, strconv.FormatBool(*kp.Spec.MatchTag))
+	}
+	if kp.Spec.MatchPreRelease != nil {
+		set(types.KeelMatchPreReleaseAnnotation, strconv.FormatBool(*kp.Spec.MatchPreRelease))
+	}
+	return annotations
+}
+
+// KeelPolicies - storage for KeelPolicy resources, implements cache.ResourceEventHandler
+type KeelPolicies struct {
+	logrus.FieldLogger
+
+	mu       sync.RWMutex
+	policies map[string]*KeelPolicy
+}
+
+// NewKeelPolicies - creates empty KeelPolicy storage
+func NewKeelPolicies(log logrus.FieldLogger) *KeelPolicies {
+	return &KeelPolicies{
+		FieldLogger: log,
+		policies:    make(map[string]*KeelPolicy),
+	}
+}
+
+// Defaults - returns annotations from all policies matching the workload, when
+// several policies match, settings from policies later in name order take precedence
+func (k *KeelPolicies) Defaults(namespace string, lbls map[string]string) map[string]string {
+	k.mu.RLock()
+	var matching []*KeelPolicy
+	for _, kp := range k.policies {
+		if kp.Matches(namespace, lbls) {
+			matching = append(matching, kp)
+		}
+	}
+	k.mu.RUnlock()
+
+	if len(matching) == 0 {
+		return nil
+	}
+
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Name < matching[j].Name })
+
+	defaults := make(map[string]string)
+	for _, kp := range matching {
+		for key, value := range kp.Annotations() {
+			defaults[key] = value
+		}
+	}
+	return defaults
+}
+
+func (k *KeelPolicies) OnAdd(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		k.Errorf("OnAdd unexpected KeelPolicy type %T", obj)
+		return
+	}
+	kp, err := NewKeelPolicy(u)
+	if err != nil {
+		k.Errorf("OnAdd failed to add KeelPolicy %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+		return
+	}
+	k.Debugf("added KeelPolicy %s/%s", kp.Namespace, kp.Name)
+	k.mu.Lock()
+	k.policies[kp.Namespace+"/"+kp.Name] = kp
+	k.mu.Unlock()
+}
+
+func (k *KeelPolicies) OnUpdate(oldObj, newObj interface{}) {
+	k.OnAdd(newObj)
+}
+
+func (k *KeelPolicies) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		k.Errorf("OnDelete unexpected KeelPolicy type %T", obj)
+		return
+	}
+	k.Debugf("deleted KeelPolicy %s/%s", u.GetNamespace(), u.GetName())
+	k.mu.Lock()
+	delete(k.policies, u.GetNamespace()+"/"+u.GetName())
+	k.mu.Unlock()
+}
+
+// WatchKeelPolicies creates a SharedInformer for keel.sh/v1alpha1.KeelPolicy and registers it with g.
+func WatchKeelPolicies(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, namespace, KeelPolicyResource, rs...)
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/sirupsen/logrus"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newUnstructuredKeelPolicy(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "keel.sh/v1alpha1",
+		"kind":       "KeelPolicy",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}}
+}
+
+func TestNewKeelPolicy(t *testing.T) {
+	kp, err := NewKeelPolicy(newUnstructuredKeelPolicy("default", "frontend", map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"tier": "frontend"},
+		},
+		"policy":             "minor",
+		"trigger":            "poll",
+		"approvals":          int64(2),
+		"matchTag":           true,
+		"updateWindow":       "Mon-Fri 22:00-04:00 UTC",
+		"approvalExpiry":     "escalate",
+		"escalationChannels": []interface{}{"oncall", "releases"},
+	}))
+	if err != nil {
+		t.Fatalf("failed to convert KeelPolicy: %s", err)
+	}
+
+	if !kp.Matches("default", map[string]string{"tier": "frontend", "app": "web"}) {
+		t.Errorf("expected policy to match workload")
+	}
+	if kp.Matches("default", map[string]string{"tier": "backend"}) {
+		t.Errorf("expected policy not to match workload with different labels")
+	}
+	if kp.Matches("other", map[string]string{"tier": "frontend"}) {
+		t.Errorf("expected policy not to match workload in another namespace")
+	}
+
+	expected := map[string]string{
+		types.KeelPolicyLabel:                          "minor",
+		types.KeelTriggerLabel:                         "poll",
+		types.KeelMinimumApprovalsLabel:                "2",
+		types.KeelForceTagMatchLabel:                   "true",
+		types.KeelUpdateWindowAnnotation:               "Mon-Fri 22:00-04:00 UTC",
+		types.KeelApprovalExpiryAnnotation:             "escalate",
+		types.KeelApprovalEscalationChannelsAnnotation: "oncall,releases",
+	}
+	annotations := kp.Annotations()
+	if len(annotations) != len(expected) {
+		t.Errorf("unexpected annotations: %v", annotations)
+	}
+	for k, v := range expected {
+		if annotations[k] != v {
+			t.Errorf("expected %s=%s, got: %s", k, v, annotations[k])
+		}
+	}
+}
+
+func TestKeelPoliciesDefaults(t *testing.T) {
+	policies := NewKeelPolicies(logrus.New())
+
+	policies.OnAdd(newUnstructuredKeelPolicy("default", "a-all", map[string]interface{}{
+		"policy":  "patch",
+		"trigger": "poll",
+	}))
+	policies.OnAdd(newUnstructuredKeelPolicy("default", "b-frontend", map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"tier": "frontend"},
+		},
+		"policy": "major",
+	}))
+
+	defaults := policies.Defaults("default", map[string]string{"tier": "frontend"})
+	if defaults[types.KeelPolicyLabel] != "major" {
+		t.Errorf("expected later policy to take precedence, got: %s", defaults[types.KeelPolicyLabel])
+	}
+	if defaults[types.KeelTriggerLabel] != "poll" {
+		t.Errorf("expected trigger from matching policy, got: %s", defaults[types.KeelTriggerLabel])
+	}
+
+	defaults = policies.Defaults("default", map[string]string{"tier": "backend"})
+	if defaults[types.KeelPolicyLabel] != "patch" {
+		t.Errorf("unexpected policy: %s", defaults[types.KeelPolicyLabel])
+	}
+
+	if defaults := policies.Defaults("other", nil); defaults != nil {
+		t.Errorf("expected no defaults in other namespace, got: %v", defaults)
+	}
+
+	policies.OnDelete(newUnstructuredKeelPolicy("default", "b-frontend", nil))
+	defaults = policies.Defaults("default", map[string]string{"tier": "frontend"})
+	if defaults[types.KeelPolicyLabel] != "patch" {
+		t.Errorf("expected deleted policy to be ignored, got: %s", defaults[types.KeelPolicyLabel])
+	}
+}
+
+func TestCacheDefaultAnnotations(t *testing.T) {
+	policies := NewKeelPolicies(logrus.New())
+	policies.OnAdd(newUnstructuredKeelPolicy("xxxx", "all", map[string]interface{}{
+		"policy":       "minor",
+		"trigger":      "poll",
+		"updateWindow": "Sat 00:00-06:00 UTC",
+	}))
+
+	cc := &GenericResourceCache{}
+	cc.SetDefaulter(policies)
+
+	gr, err := NewGenericResource(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelTriggerLabel: "default"},
+			Annotations: map[string]string{types.KeelUpdateWindowAnnotation: "Sun 00:00-06:00 UTC"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: core_v1.PodTemplateSpec{
+				Spec: core_v1.PodSpec{
+					Containers: []core_v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hi-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+	cc.Add(gr)
+
+	resource := cc.Values()[0]
+	annotations := resource.GetAnnotations()
+
+	if annotations[types.KeelPolicyLabel] != "minor" {
+		t.Errorf("expected policy from KeelPolicy, got: %s", annotations[types.KeelPolicyLabel])
+	}
+	if _, ok := annotations[types.KeelTriggerLabel]; ok {
+		t.Errorf("expected workload label to take precedence over KeelPolicy")
+	}
+	if annotations[types.KeelUpdateWindowAnnotation] != "Sun 00:00-06:00 UTC" {
+		t.Errorf("expected workload annotation to take precedence, got: %s", annotations[types.KeelUpdateWindowAnnotation])
+	}
+
+	// defaults are not written back to the resource
+	annotations[types.KeelUpdateTimeAnnotation] = "now"
+	resource.SetAnnotations(annotations)
+
+	stored := resource.GetResource().(*apps_v1.Deployment).GetAnnotations()
+	if _, ok := stored[types.KeelPolicyLabel]; ok {
+		t.Errorf("default annotations shouldn't be stored on the resource: %v", stored)
+	}
+	if stored[types.KeelUpdateTimeAnnotation] != "now" {
+		t.Errorf("expected new annotation to be stored, got: %v", stored)
+	}
+}
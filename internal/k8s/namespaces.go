@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// NamespaceFilter - restricts resources that keel tracks to an allow-list
+// and/or excludes namespaces from a deny-list. Empty filter allows everything.
+type NamespaceFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// NewNamespaceFilter - creates namespace filter from comma separated
+// include and exclude namespace lists
+func NewNamespaceFilter(include, exclude string) *NamespaceFilter {
+	return &NamespaceFilter{
+		Include: splitNamespaces(include),
+		Exclude: splitNamespaces(exclude),
+	}
+}
+
+func splitNamespaces(namespaces string) []string {
+	var result []string
+	for _, ns := range strings.Split(namespaces, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" {
+			result = append(result, ns)
+		}
+	}
+	return result
+}
+
+// Allowed - checks whether namespace is allowed by the filter
+func (f *NamespaceFilter) Allowed(namespace string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, ns := range f.Exclude {
+		if ns == namespace {
+			return false
+		}
+	}
+
+	if len(f.Include) == 0 {
+		return true
+	}
+
+	for _, ns := range f.Include {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchedNamespaces - returns namespaces that informers should watch, when
+// allow-list is not set - all namespaces are watched
+func (f *NamespaceFilter) WatchedNamespaces() []string {
+	if f == nil || len(f.Include) == 0 {
+		return []string{v1.NamespaceAll}
+	}
+
+	var namespaces []string
+	for _, ns := range f.Include {
+		if f.Allowed(ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,64 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNamespaceFilterAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   string
+		exclude   string
+		namespace string
+		want      bool
+	}{
+		{name: "no filter", namespace: "default", want: true},
+		{name: "included", include: "default, staging", namespace: "staging", want: true},
+		{name: "not included", include: "default,staging", namespace: "prod", want: false},
+		{name: "excluded", exclude: "kube-system", namespace: "kube-system", want: false},
+		{name: "not excluded", exclude: "kube-system", namespace: "default", want: true},
+		{name: "included and excluded", include: "default", exclude: "default", namespace: "default", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewNamespaceFilter(tt.include, tt.exclude)
+			if got := f.Allowed(tt.namespace); got != tt.want {
+				t.Errorf("NamespaceFilter.Allowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceFilterWatchedNamespaces(t *testing.T) {
+	if got := NewNamespaceFilter("", "kube-system").WatchedNamespaces(); !reflect.DeepEqual(got, []string{""}) {
+		t.Errorf("expected all namespaces to be watched, got: %v", got)
+	}
+
+	if got := NewNamespaceFilter("a,b,c", "b").WatchedNamespaces(); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("unexpected watched namespaces: %v", got)
+	}
+}
+
+func TestTranslatorNamespaceFilter(t *testing.T) {
+	tr := &Translator{
+		FieldLogger:     logrus.New(),
+		NamespaceFilter: NewNamespaceFilter("", "kube-system"),
+	}
+
+	tr.OnAdd(&apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "kube-system"}})
+	tr.OnAdd(&apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-2", Namespace: "default"}})
+
+	values := tr.Values()
+	if len(values) != 1 {
+		t.Fatalf("expected 1 resource in cache, got: %d", len(values))
+	}
+	if values[0].Namespace != "default" {
+		t.Errorf("unexpected namespace: %s", values[0].Namespace)
+	}
+}
@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/workgroup"
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RegoModulesLabel - ConfigMaps with this label set to "true" in keel namespace
+// provide Rego modules for rego:<rule> policies, every data key ending with
+// .rego is a module
+const RegoModulesLabel = "keel.sh/rego-policy"
+
+// RegoModules - collects Rego modules from ConfigMaps and loads them with all
+// modules known so far, implements cache.ResourceEventHandler
+type RegoModules struct {
+	logrus.FieldLogger
+
+	load func(modules map[string]string) error
+
+	mu         sync.Mutex
+	configMaps map[string]map[string]string
+}
+
+// NewRegoModules - creates module collection, load is called with all modules
+// (<namespace>/<configmap>/<key> -> source) every time a ConfigMap changes,
+// for example policy.LoadRegoModules
+func NewRegoModules(log logrus.FieldLogger, load func(modules map[string]string) error) *RegoModules {
+	return &RegoModules{
+		FieldLogger: log,
+		load:        load,
+		configMaps:  make(map[string]map[string]string),
+	}
+}
+
+func (r *RegoModules) OnAdd(obj interface{}) {
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		r.Errorf("OnAdd unexpected ConfigMap type %T", obj)
+		return
+	}
+
+	var modules map[string]string
+	for key, value := range cm.Data {
+		if strings.HasSuffix(key, ".rego") {
+			if modules == nil {
+				modules = make(map[string]string)
+			}
+			modules[cm.Namespace+"/"+cm.Name+"/"+key] = value
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if modules == nil {
+		delete(r.configMaps, configMapKey(cm))
+	} else {
+		r.configMaps[configMapKey(cm)] = modules
+	}
+	r.reload()
+}
+
+func (r *RegoModules) OnUpdate(oldObj, newObj interface{}) {
+	r.OnAdd(newObj)
+}
+
+func (r *RegoModules) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		r.Errorf("OnDelete unexpected ConfigMap type %T", obj)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.configMaps, configMapKey(cm))
+	r.reload()
+}
+
+// reload - loads modules of all ConfigMaps, r.mu must be held
+func (r *RegoModules) reload() {
+	modules := make(map[string]string)
+	for _, cmModules := range r.configMaps {
+		for name, source := range cmModules {
+			modules[name] = source
+		}
+	}
+
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := r.load(modules); err != nil {
+		r.WithFields(logrus.Fields{
+			"error":   err,
+			"modules": names,
+		}).Error("failed to compile rego modules, keeping previously loaded modules")
+		return
+	}
+	r.Infof("loaded rego modules: %v", names)
+}
+
+func configMapKey(cm *v1.ConfigMap) string {
+	return cm.Namespace + "/" + cm.Name
+}
+
+// WatchRegoModules creates a SharedInformer for v1.ConfigMap labelled with RegoModulesLabel
+// in the namespace and registers it with g.
+func WatchRegoModules(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	lw := cache.NewFilteredListWatchFromClient(client.CoreV1().RESTClient(), "configmaps", namespace, func(options *meta_v1.ListOptions) {
+		options.LabelSelector = RegoModulesLabel + "=true"
+	})
+	sw := cache.NewSharedInformer(lw, new(v1.ConfigMap), 30*time.Minute)
+	for _, h := range rs {
+		sw.AddEventHandler(h)
+	}
+	g.Add(func(stop <-chan struct{}) {
+		log := log.WithFields(logrus.Fields{"resource": "configmaps", "namespace": namespace})
+		log.Println("started")
+		defer log.Println("stopped")
+		sw.Run(stop)
+	})
+}
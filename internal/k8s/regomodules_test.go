@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegoModules(t *testing.T) {
+	var loaded map[string]string
+	modules := NewRegoModules(logrus.New(), func(m map[string]string) error {
+		if _, ok := m["keel/broken/keel.rego"]; ok {
+			return fmt.Errorf("compile error")
+		}
+		loaded = m
+		return nil
+	})
+
+	cm := &core_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "policies", Namespace: "keel"},
+		Data: map[string]string{
+			"keel.rego": "package keel",
+			"README.md": "docs",
+		},
+	}
+	modules.OnAdd(cm)
+	if len(loaded) != 1 || loaded["keel/policies/keel.rego"] != "package keel" {
+		t.Errorf("unexpected modules: %v", loaded)
+	}
+
+	modules.OnAdd(&core_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "broken", Namespace: "keel"},
+		Data:       map[string]string{"keel.rego": "package"},
+	})
+	if len(loaded) != 1 {
+		t.Errorf("expected previously loaded modules to be kept, got: %v", loaded)
+	}
+
+	modules.OnDelete(cm)
+	if len(loaded) != 1 {
+		t.Errorf("expected failed reload to keep modules, got: %v", loaded)
+	}
+
+	modules.OnDelete(&core_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "broken", Namespace: "keel"}})
+	if len(loaded) != 0 {
+		t.Errorf("expected no modules, got: %v", loaded)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	apps_v1 "k8s.io/api/apps/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // GenericResource - generic resource,
@@ -19,6 +20,10 @@ type GenericResource struct {
 	Identifier string
 	Namespace  string
 	Name       string
+
+	// defaults - annotations inherited from matching KeelPolicy resources,
+	// they are visible through GetAnnotations but never written back
+	defaults map[string]string
 }
 
 type genericResource []*GenericResource
@@ -38,11 +43,19 @@ func (c genericResource) Less(i, j int) bool {
 // NewGenericResource - create new generic k8s resource
 func NewGenericResource(obj interface{}) (*GenericResource, error) {
 
-	switch obj.(type) {
+	switch o := obj.(type) {
 	case *apps_v1.Deployment, *apps_v1.StatefulSet, *apps_v1.DaemonSet:
 		// ok
 	case *v1beta1.CronJob:
 		// ok
+	case *unstructured.Unstructured:
+		// only Argo Rollouts, Knative Services and Jobs are supported as unstructured resources
+		if _, ok := getCustomResource(o); !ok {
+			return nil, fmt.Errorf("unsupported custom resource: %s", o.GetKind())
+		}
+		if err := validateCustomResourceContainers(o); err != nil {
+			return nil, fmt.Errorf("invalid %s %s/%s: %s", o.GetKind(), o.GetNamespace(), o.GetName(), err)
+		}
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %v", reflect.TypeOf(obj).Kind())
 	}
@@ -71,6 +84,7 @@ func (r *GenericResource) DeepCopy() *GenericResource {
 	gr.Identifier = r.Identifier
 	gr.Namespace = r.Namespace
 	gr.Name = r.Name
+	gr.defaults = r.defaults
 
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
@@ -81,6 +95,8 @@ func (r *GenericResource) DeepCopy() *GenericResource {
 		gr.obj = obj.DeepCopy()
 	case *v1beta1.CronJob:
 		gr.obj = obj.DeepCopy()
+	case *unstructured.Unstructured:
+		gr.obj = obj.DeepCopy()
 	}
 
 	return gr
@@ -97,6 +113,8 @@ func (r *GenericResource) GetIdentifier() string {
 		return getDaemonsetSetIdentifier(obj)
 	case *v1beta1.CronJob:
 		return getCronJobIdentifier(obj)
+	case *unstructured.Unstructured:
+		return getCustomResourceIdentifier(obj)
 	}
 	return ""
 }
@@ -112,6 +130,8 @@ func (r *GenericResource) GetName() string {
 		return obj.GetName()
 	case *v1beta1.CronJob:
 		return obj.GetName()
+	case *unstructured.Unstructured:
+		return obj.GetName()
 	}
 	return ""
 }
@@ -127,13 +147,15 @@ func (r *GenericResource) GetNamespace() string {
 		return obj.GetNamespace()
 	case *v1beta1.CronJob:
 		return obj.GetNamespace()
+	case *unstructured.Unstructured:
+		return obj.GetNamespace()
 	}
 	return ""
 }
 
 // Kind returns a type of resource that this structure represents
 func (r *GenericResource) Kind() string {
-	switch r.obj.(type) {
+	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		return "deployment"
 	case *apps_v1.StatefulSet:
@@ -142,6 +164,8 @@ func (r *GenericResource) Kind() string {
 		return "daemonset"
 	case *v1beta1.CronJob:
 		return "cronjob"
+	case *unstructured.Unstructured:
+		return getCustomResourceKind(obj)
 	}
 	return ""
 }
@@ -162,6 +186,8 @@ func (r *GenericResource) GetLabels() (labels map[string]string) {
 		return getOrInitialise(obj.GetLabels())
 	case *v1beta1.CronJob:
 		return getOrInitialise(obj.GetLabels())
+	case *unstructured.Unstructured:
+		return getOrInitialise(obj.GetLabels())
 	}
 	return
 }
@@ -177,6 +203,8 @@ func (r *GenericResource) SetLabels(labels map[string]string) {
 		obj.SetLabels(labels)
 	case *v1beta1.CronJob:
 		obj.SetLabels(labels)
+	case *unstructured.Unstructured:
+		obj.SetLabels(labels)
 	}
 }
 
@@ -190,7 +218,10 @@ func (r *GenericResource) GetSpecAnnotations() (annotations map[string]string) {
 	case *apps_v1.DaemonSet:
 		return getOrInitialise(obj.Spec.Template.GetAnnotations())
 	case *v1beta1.CronJob:
-		return getOrInitialise(obj.Spec.JobTemplate.GetAnnotations())
+		// annotating pod template so jobs created on the next scheduled run carry them
+		return getOrInitialise(obj.Spec.JobTemplate.Spec.Template.GetAnnotations())
+	case *unstructured.Unstructured:
+		return getOrInitialise(getCustomResourceSpecAnnotations(obj))
 	}
 	return
 }
@@ -205,7 +236,9 @@ func (r *GenericResource) SetSpecAnnotations(annotations map[string]string) {
 	case *apps_v1.DaemonSet:
 		obj.Spec.Template.SetAnnotations(annotations)
 	case *v1beta1.CronJob:
-		obj.Spec.JobTemplate.SetAnnotations(annotations)
+		obj.Spec.JobTemplate.Spec.Template.SetAnnotations(annotations)
+	case *unstructured.Unstructured:
+		setCustomResourceSpecAnnotations(obj, annotations)
 	}
 }
 
@@ -216,8 +249,39 @@ func getOrInitialise(a map[string]string) map[string]string {
 	return a
 }
 
-// GetAnnotations - get resource annotations
+// SetDefaultAnnotations - sets annotations that apply to the resource unless
+// it has the same key in its own annotations or labels
+func (r *GenericResource) SetDefaultAnnotations(defaults map[string]string) {
+	r.defaults = defaults
+}
+
+// GetAnnotations - get resource annotations, merged with default annotations
 func (r *GenericResource) GetAnnotations() (annotations map[string]string) {
+	annotations = r.getAnnotations()
+	if len(r.defaults) == 0 {
+		return annotations
+	}
+
+	labels := r.GetLabels()
+	merged := make(map[string]string, len(annotations)+len(r.defaults))
+	for k, v := range r.defaults {
+		if _, ok := labels[k]; !ok {
+			merged[k] = v
+		}
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	return merged
+}
+
+// GetResourceAnnotations - get annotations stored on the resource itself, without
+// default annotations
+func (r *GenericResource) GetResourceAnnotations() (annotations map[string]string) {
+	return r.getAnnotations()
+}
+
+func (r *GenericResource) getAnnotations() (annotations map[string]string) {
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		return getOrInitialise(obj.GetAnnotations())
@@ -227,12 +291,24 @@ func (r *GenericResource) GetAnnotations() (annotations map[string]string) {
 		return getOrInitialise(obj.GetAnnotations())
 	case *v1beta1.CronJob:
 		return getOrInitialise(obj.GetAnnotations())
+	case *unstructured.Unstructured:
+		return getOrInitialise(obj.GetAnnotations())
 	}
 	return
 }
 
-// SetAnnotations - set resource annotations
+// SetAnnotations - set resource annotations, unchanged default annotations are not
+// stored on the resource
 func (r *GenericResource) SetAnnotations(annotations map[string]string) {
+	if len(r.defaults) > 0 {
+		current := r.getAnnotations()
+		for k, v := range r.defaults {
+			if _, ok := current[k]; !ok && annotations[k] == v {
+				delete(annotations, k)
+			}
+		}
+	}
+
 	switch obj := r.obj.(type) {
 	case *apps_v1.Deployment:
 		obj.SetAnnotations(annotations)
@@ -242,6 +318,8 @@ func (r *GenericResource) SetAnnotations(annotations map[string]string) {
 		obj.SetAnnotations(annotations)
 	case *v1beta1.CronJob:
 		obj.SetAnnotations(annotations)
+	case *unstructured.Unstructured:
+		obj.SetAnnotations(annotations)
 	}
 }
 
@@ -256,10 +334,34 @@ func (r *GenericResource) GetImagePullSecrets() (secrets []string) {
 		return getImagePullSecrets(obj.Spec.Template.Spec.ImagePullSecrets)
 	case *v1beta1.CronJob:
 		return getImagePullSecrets(obj.Spec.JobTemplate.Spec.Template.Spec.ImagePullSecrets)
+	case *unstructured.Unstructured:
+		return getCustomResourceImagePullSecrets(obj)
 	}
 	return
 }
 
+// GetServiceAccountName - returns service account of pods, "default" if pod spec
+// doesn't set it
+func (r *GenericResource) GetServiceAccountName() string {
+	var name string
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		name = obj.Spec.Template.Spec.ServiceAccountName
+	case *apps_v1.StatefulSet:
+		name = obj.Spec.Template.Spec.ServiceAccountName
+	case *apps_v1.DaemonSet:
+		name = obj.Spec.Template.Spec.ServiceAccountName
+	case *v1beta1.CronJob:
+		name = obj.Spec.JobTemplate.Spec.Template.Spec.ServiceAccountName
+	case *unstructured.Unstructured:
+		name, _, _ = unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName")
+	}
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
 // GetImages - returns images used by this resource
 func (r *GenericResource) GetImages() (images []string) {
 	switch obj := r.obj.(type) {
@@ -271,6 +373,8 @@ func (r *GenericResource) GetImages() (images []string) {
 		return getContainerImages(obj.Spec.Template.Spec.Containers)
 	case *v1beta1.CronJob:
 		return getContainerImages(obj.Spec.JobTemplate.Spec.Template.Spec.Containers)
+	case *unstructured.Unstructured:
+		return getContainerImages(getCustomResourceContainers(obj))
 	}
 	return
 }
@@ -286,6 +390,8 @@ func (r *GenericResource) Containers() (containers []core_v1.Container) {
 		return obj.Spec.Template.Spec.Containers
 	case *v1beta1.CronJob:
 		return obj.Spec.JobTemplate.Spec.Template.Spec.Containers
+	case *unstructured.Unstructured:
+		return getCustomResourceContainers(obj)
 	}
 	return
 }
@@ -301,6 +407,46 @@ func (r *GenericResource) UpdateContainer(index int, image string) {
 		updateDaemonsetSetContainer(obj, index, image)
 	case *v1beta1.CronJob:
 		updateCronJobContainer(obj, index, image)
+	case *unstructured.Unstructured:
+		updateCustomResourceContainer(obj, index, image)
+	}
+}
+
+// InitContainers - returns init containers managed by this resource
+func (r *GenericResource) InitContainers() (containers []core_v1.Container) {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		return obj.Spec.Template.Spec.InitContainers
+	case *apps_v1.StatefulSet:
+		return obj.Spec.Template.Spec.InitContainers
+	case *apps_v1.DaemonSet:
+		return obj.Spec.Template.Spec.InitContainers
+	case *v1beta1.CronJob:
+		return obj.Spec.JobTemplate.Spec.Template.Spec.InitContainers
+	case *unstructured.Unstructured:
+		return getCustomResourceInitContainers(obj)
+	}
+	return
+}
+
+// GetInitImages - returns images used by init containers of this resource
+func (r *GenericResource) GetInitImages() (images []string) {
+	return getContainerImages(r.InitContainers())
+}
+
+// UpdateInitContainer - updates init container image
+func (r *GenericResource) UpdateInitContainer(index int, image string) {
+	switch obj := r.obj.(type) {
+	case *apps_v1.Deployment:
+		obj.Spec.Template.Spec.InitContainers[index].Image = image
+	case *apps_v1.StatefulSet:
+		obj.Spec.Template.Spec.InitContainers[index].Image = image
+	case *apps_v1.DaemonSet:
+		obj.Spec.Template.Spec.InitContainers[index].Image = image
+	case *v1beta1.CronJob:
+		obj.Spec.JobTemplate.Spec.Template.Spec.InitContainers[index].Image = image
+	case *unstructured.Unstructured:
+		updateCustomResourceInitContainer(obj, index, image)
 	}
 }
 
@@ -362,6 +508,14 @@ func (r *GenericResource) GetStatus() Status {
 			AvailableReplicas:   0,
 			UnavailableReplicas: 0,
 		}
+	case *unstructured.Unstructured:
+		return Status{
+			Replicas:            getCustomResourceStatusField(obj, "replicas"),
+			UpdatedReplicas:     getCustomResourceStatusField(obj, "updatedReplicas"),
+			ReadyReplicas:       getCustomResourceStatusField(obj, "readyReplicas"),
+			AvailableReplicas:   getCustomResourceStatusField(obj, "availableReplicas"),
+			UnavailableReplicas: getCustomResourceStatusField(obj, "replicas") - getCustomResourceStatusField(obj, "availableReplicas"),
+		}
 	}
 	return Status{}
 }
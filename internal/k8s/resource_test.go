@@ -4,8 +4,11 @@ import (
 	"testing"
 
 	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestDeployment(t *testing.T) {
@@ -185,3 +188,175 @@ func TestDaemonsetlSetMultipleContainers(t *testing.T) {
 		t.Errorf("unexpected image: %s", updated.Spec.Template.Spec.Containers[0].Image)
 	}
 }
+
+func TestCronJobMultipleContainers(t *testing.T) {
+	c := &v1beta1.CronJob{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "cron-1",
+			Namespace:   "xxxx",
+			Annotations: map[string]string{},
+			Labels:      map[string]string{},
+		},
+		Spec: v1beta1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			JobTemplate: v1beta1.JobTemplateSpec{
+				Spec: batch_v1.JobSpec{
+					Template: core_v1.PodTemplateSpec{
+						Spec: core_v1.PodSpec{
+							Containers: []core_v1.Container{
+								{
+									Image: "gcr.io/v2-namespace/hi-world:1.1.1",
+								},
+								{
+									Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	gr, err := NewGenericResource(c)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	if gr.Identifier != "cronjob/xxxx/cron-1" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+
+	gr.UpdateContainer(1, "hey/there")
+
+	ann := gr.GetSpecAnnotations()
+	ann["foo"] = "bar"
+	gr.SetSpecAnnotations(ann)
+
+	updated, ok := gr.GetResource().(*v1beta1.CronJob)
+	if !ok {
+		t.Fatalf("conversion failed")
+	}
+
+	if updated.Spec.JobTemplate.Spec.Template.Spec.Containers[1].Image != "hey/there" {
+		t.Errorf("unexpected image: %s", updated.Spec.JobTemplate.Spec.Template.Spec.Containers[1].Image)
+	}
+
+	if updated.Spec.JobTemplate.Spec.Template.Annotations["foo"] != "bar" {
+		t.Errorf("expected pod template to be annotated, got: %v", updated.Spec.JobTemplate.Spec.Template.Annotations)
+	}
+}
+
+func TestArgoRollout(t *testing.T) {
+	r := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata": map[string]interface{}{
+			"name":      "rollout-1",
+			"namespace": "xxxx",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"imagePullSecrets": []interface{}{
+						map[string]interface{}{"name": "very-secret"},
+					},
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "hi",
+							"image": "gcr.io/v2-namespace/hi-world:1.1.1",
+						},
+						map[string]interface{}{
+							"name":  "hello",
+							"image": "gcr.io/v2-namespace/hello-world:1.1.1",
+							"ports": []interface{}{
+								map[string]interface{}{"containerPort": int64(8080)},
+							},
+						},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"replicas":          int64(2),
+			"availableReplicas": int64(1),
+		},
+	}}
+
+	gr, err := NewGenericResource(r)
+	if err != nil {
+		t.Fatalf("failed to create generic resource: %s", err)
+	}
+
+	if gr.Identifier != "rollout/xxxx/rollout-1" {
+		t.Errorf("unexpected identifier: %s", gr.Identifier)
+	}
+	if gr.Kind() != "rollout" {
+		t.Errorf("unexpected kind: %s", gr.Kind())
+	}
+	if len(gr.Containers()) != 2 || gr.Containers()[1].Name != "hello" {
+		t.Fatalf("unexpected containers: %v", gr.Containers())
+	}
+	if secrets := gr.GetImagePullSecrets(); len(secrets) != 1 || secrets[0] != "very-secret" {
+		t.Errorf("unexpected image pull secrets: %v", secrets)
+	}
+	if status := gr.GetStatus(); status.AvailableReplicas != 1 || status.UnavailableReplicas != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	gr.UpdateContainer(1, "hey/there")
+
+	ann := gr.GetSpecAnnotations()
+	ann["foo"] = "bar"
+	gr.SetSpecAnnotations(ann)
+
+	copied := gr.DeepCopy()
+	if copied.GetImages()[1] != "hey/there" {
+		t.Errorf("unexpected image: %s", copied.GetImages()[1])
+	}
+	if copied.GetSpecAnnotations()["foo"] != "bar" {
+		t.Errorf("expected pod template to be annotated, got: %v", copied.GetSpecAnnotations())
+	}
+	if copied.Containers()[1].Ports[0].ContainerPort != 8080 {
+		t.Errorf("expected other container fields to be preserved, got: %v", copied.Containers()[1])
+	}
+
+	_, err = NewGenericResource(&unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+	}})
+	if err == nil {
+		t.Errorf("expected error for unsupported custom resource")
+	}
+
+	// container which can't be converted would shift indices of the following ones
+	_, err = NewGenericResource(&unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata": map[string]interface{}{
+			"name":      "rollout-2",
+			"namespace": "xxxx",
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "hi",
+							"image": "gcr.io/v2-namespace/hi-world:1.1.1",
+							"ports": "8080",
+						},
+						map[string]interface{}{
+							"name":  "hello",
+							"image": "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}})
+	if err == nil {
+		t.Errorf("expected error for custom resource with invalid container")
+	}
+}
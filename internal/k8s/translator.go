@@ -2,6 +2,8 @@ package k8s
 
 import (
 	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/tools/cache"
 )
 
 type Translator struct {
@@ -10,6 +12,10 @@ type Translator struct {
 	GenericResourceCache
 
 	KeelSelector string
+
+	// NamespaceFilter - optional namespace allow/deny list, resources
+	// from filtered out namespaces are not added to the cache
+	NamespaceFilter *NamespaceFilter
 }
 
 func (t *Translator) OnAdd(obj interface{}) {
@@ -18,6 +24,9 @@ func (t *Translator) OnAdd(obj interface{}) {
 		t.Errorf("OnAdd failed to add resource %T: %#v", obj, obj)
 		return
 	}
+	if !t.NamespaceFilter.Allowed(gr.Namespace) {
+		return
+	}
 	t.Debugf("added %s %s", gr.Kind(), gr.Name)
 	t.GenericResourceCache.Add(gr)
 }
@@ -28,11 +37,18 @@ func (t *Translator) OnUpdate(oldObj, newObj interface{}) {
 		t.Errorf("OnUpdate failed to update resource %T: %#v", newObj, newObj)
 		return
 	}
+	if !t.NamespaceFilter.Allowed(gr.Namespace) {
+		return
+	}
 	t.Debugf("updated %s %s", gr.Kind(), gr.Name)
 	t.GenericResourceCache.Add(gr)
 }
 
 func (t *Translator) OnDelete(obj interface{}) {
+	// deletions missed during a watch disconnect are delivered as tombstones
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
 	gr, err := NewGenericResource(obj)
 	if err != nil {
 		t.Errorf("OnDelete failed to delete resource %T: %#v", obj, obj)
@@ -0,0 +1,23 @@
+package k8s
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTranslatorDeleteTombstone(t *testing.T) {
+	tr := &Translator{FieldLogger: logrus.New()}
+
+	dep := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "default"}}
+	tr.OnAdd(dep)
+	tr.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/dep-1", Obj: dep})
+
+	if values := tr.Values(); len(values) != 0 {
+		t.Errorf("expected resource to be removed from cache, got: %d", len(values))
+	}
+}
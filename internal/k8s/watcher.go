@@ -8,42 +8,106 @@ import (
 
 	apps_v1 "k8s.io/api/apps/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
-	"k8s.io/api/core/v1"
 
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	watchapi "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
 // WatchDeployments creates a SharedInformer for apps/v1.Deployments and registers it with g.
-func WatchDeployments(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "deployments", new(apps_v1.Deployment), rs...)
+// Use v1.NamespaceAll to watch resources across all namespaces.
+func WatchDeployments(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watch(g, client.AppsV1().RESTClient(), log, namespace, "deployments", new(apps_v1.Deployment), rs...)
 }
 
 // WatchStatefulSets creates a SharedInformer for apps/v1.StatefulSet and registers it with g.
-func WatchStatefulSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "statefulsets", new(apps_v1.StatefulSet), rs...)
+func WatchStatefulSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watch(g, client.AppsV1().RESTClient(), log, namespace, "statefulsets", new(apps_v1.StatefulSet), rs...)
 }
 
 // WatchDaemonSets creates a SharedInformer for apps/v1.DaemonSet and registers it with g.
-func WatchDaemonSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.AppsV1().RESTClient(), log, "daemonsets", new(apps_v1.DaemonSet), rs...)
+func WatchDaemonSets(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watch(g, client.AppsV1().RESTClient(), log, namespace, "daemonsets", new(apps_v1.DaemonSet), rs...)
 }
 
 // WatchCronJobs creates a SharedInformer for v1beta1.CronJob and registers it with g.
-func WatchCronJobs(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, rs ...cache.ResourceEventHandler) {
-	watch(g, client.BatchV1beta1().RESTClient(), log, "cronjobs", new(v1beta1.CronJob), rs...)
+func WatchCronJobs(g *workgroup.Group, client *kubernetes.Clientset, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watch(g, client.BatchV1beta1().RESTClient(), log, namespace, "cronjobs", new(v1beta1.CronJob), rs...)
 }
 
-func watch(g *workgroup.Group, c cache.Getter, log logrus.FieldLogger, resource string, objType runtime.Object, rs ...cache.ResourceEventHandler) {
-	lw := cache.NewListWatchFromClient(c, resource, v1.NamespaceAll, fields.Everything())
+// RolloutResource - Argo Rollouts custom resource group, version and resource
+var RolloutResource = schema.GroupVersionResource{
+	Group:    "argoproj.io",
+	Version:  "v1alpha1",
+	Resource: "rollouts",
+}
+
+// WatchRollouts creates a SharedInformer for argoproj.io/v1alpha1.Rollout and registers it with g.
+func WatchRollouts(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, namespace, RolloutResource, rs...)
+}
+
+// KnativeServiceResource - Knative Serving service group, version and resource
+var KnativeServiceResource = schema.GroupVersionResource{
+	Group:    "serving.knative.dev",
+	Version:  "v1",
+	Resource: "services",
+}
+
+// WatchKnativeServices creates a SharedInformer for serving.knative.dev/v1.Service and registers it with g.
+func WatchKnativeServices(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, namespace, KnativeServiceResource, rs...)
+}
+
+// JobResource - batch/v1 Jobs, they are watched through dynamic client, see customResources
+var JobResource = schema.GroupVersionResource{
+	Group:    "batch",
+	Version:  "v1",
+	Resource: "jobs",
+}
+
+// WatchJobs creates a SharedInformer for batch/v1.Job and registers it with g.
+func WatchJobs(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, namespace string, rs ...cache.ResourceEventHandler) {
+	watchDynamic(g, client, log, namespace, JobResource, rs...)
+}
+
+func watch(g *workgroup.Group, c cache.Getter, log logrus.FieldLogger, namespace, resource string, objType runtime.Object, rs ...cache.ResourceEventHandler) {
+	lw := cache.NewListWatchFromClient(c, resource, namespace, fields.Everything())
 	sw := cache.NewSharedInformer(lw, objType, 30*time.Minute)
 	for _, r := range rs {
 		sw.AddEventHandler(r)
 	}
 	g.Add(func(stop <-chan struct{}) {
-		log := log.WithField("resource", resource)
+		log := log.WithFields(logrus.Fields{"resource": resource, "namespace": namespace})
+		log.Println("started")
+		defer log.Println("stopped")
+		sw.Run(stop)
+	})
+}
+
+// watchDynamic - watches custom resources, objects are passed to handlers as *unstructured.Unstructured
+func watchDynamic(g *workgroup.Group, client dynamic.Interface, log logrus.FieldLogger, namespace string, gvr schema.GroupVersionResource, rs ...cache.ResourceEventHandler) {
+	resource := client.Resource(gvr).Namespace(namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			return resource.List(options)
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watchapi.Interface, error) {
+			return resource.Watch(options)
+		},
+	}
+	sw := cache.NewSharedInformer(lw, &unstructured.Unstructured{}, 30*time.Minute)
+	for _, r := range rs {
+		sw.AddEventHandler(r)
+	}
+	g.Add(func(stop <-chan struct{}) {
+		log := log.WithFields(logrus.Fields{"resource": gvr.Resource, "namespace": namespace})
 		log.Println("started")
 		defer log.Println("stopped")
 		sw.Run(stop)
@@ -0,0 +1,152 @@
+// Package leader runs Lease based leader election (client-go leaderelection) so several
+// Keel replicas can run while only one of them processes events.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaults, same as kube-controller-manager
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// PodLabel - set to "true" on the leader pod so Service can route webhooks only to the leader
+const PodLabel = "keel.sh/leader"
+
+// ErrLeadershipLost - returned by Run when lease couldn't be renewed before renew deadline
+var ErrLeadershipLost = errors.New("leadership lost")
+
+// Config - leader election configuration
+type Config struct {
+	Client coordinationv1client.LeasesGetter
+	// Namespace - lease namespace
+	Namespace string
+	// Name - lease name
+	Name string
+	// Identity - unique replica identity, usually pod name
+	Identity string
+
+	// LeaseDuration - how long followers wait before taking over a lease they haven't
+	// seen renewed, measured with the local clock from the last observed change
+	LeaseDuration time.Duration
+	// RenewDeadline - how long leader retries renewing the lease before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod - how often lease is acquired or renewed
+	RetryPeriod time.Duration
+}
+
+// Elector - takes part in leader election
+type Elector struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	leader bool
+}
+
+// New - creates new elector, zero durations are replaced with defaults
+func New(cfg Config) *Elector {
+	if cfg.LeaseDuration == 0 {
+		cfg.LeaseDuration = DefaultLeaseDuration
+	}
+	if cfg.RenewDeadline == 0 {
+		cfg.RenewDeadline = DefaultRenewDeadline
+	}
+	if cfg.RetryPeriod == 0 {
+		cfg.RetryPeriod = DefaultRetryPeriod
+	}
+	return &Elector{cfg: cfg}
+}
+
+// IsLeader - returns true if this replica currently holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+}
+
+// Run - waits until lease is acquired, calls onStartedLeading and keeps renewing the lease.
+// Context passed to onStartedLeading is cancelled when leadership is lost. Run returns
+// ErrLeadershipLost if lease couldn't be renewed or ctx error once ctx is done, the lease
+// is released in that case so other replicas don't have to wait for it to expire.
+func (e *Elector) Run(ctx context.Context, onStartedLeading func(ctx context.Context)) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: meta_v1.ObjectMeta{
+			Namespace: e.cfg.Namespace,
+			Name:      e.cfg.Name,
+		},
+		Client: e.cfg.Client,
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: e.cfg.Identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   e.cfg.LeaseDuration,
+		RenewDeadline:   e.cfg.RenewDeadline,
+		RetryPeriod:     e.cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.cfg.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.WithFields(log.Fields{
+					"lease":    e.cfg.Name,
+					"identity": e.cfg.Identity,
+				}).Info("leader: lease acquired, started leading")
+				e.setLeader(true)
+				onStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				e.setLeader(false)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// blocks until leadership is lost or ctx is done
+	elector.Run(ctx)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	log.WithFields(log.Fields{
+		"lease":    e.cfg.Name,
+		"identity": e.cfg.Identity,
+	}).Error("leader: failed to renew lease, stopped leading")
+	return ErrLeadershipLost
+}
+
+// LabelPod - sets or removes PodLabel on the pod
+func LabelPod(client corev1client.PodsGetter, namespace, name string, leader bool) error {
+	value := "null"
+	if leader {
+		value = `"true"`
+	}
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%s}}}`, PodLabel, value)
+	_, err := client.Pods(namespace).Patch(name, k8stypes.MergePatchType, []byte(patch))
+	return err
+}
@@ -0,0 +1,231 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	coordination_v1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeLeaseClient - single lease store, only Get, Create and Update are implemented
+type fakeLeaseClient struct {
+	coordinationv1client.LeaseInterface
+
+	mu      sync.Mutex
+	lease   *coordination_v1.Lease
+	failing bool
+}
+
+func (c *fakeLeaseClient) Leases(namespace string) coordinationv1client.LeaseInterface {
+	return c
+}
+
+func (c *fakeLeaseClient) Get(name string, options meta_v1.GetOptions) (*coordination_v1.Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing {
+		return nil, errors.New("unavailable")
+	}
+	if c.lease == nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, name)
+	}
+	return c.lease.DeepCopy(), nil
+}
+
+func (c *fakeLeaseClient) Create(lease *coordination_v1.Lease) (*coordination_v1.Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing {
+		return nil, errors.New("unavailable")
+	}
+	if c.lease != nil {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, lease.Name)
+	}
+	c.lease = lease.DeepCopy()
+	return lease, nil
+}
+
+func (c *fakeLeaseClient) Update(lease *coordination_v1.Lease) (*coordination_v1.Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing {
+		return nil, errors.New("unavailable")
+	}
+	c.lease = lease.DeepCopy()
+	return lease, nil
+}
+
+// renew - renews the lease on behalf of another replica
+func (c *fakeLeaseClient) renew(holder string, renewTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	duration := int32(1)
+	t := meta_v1.NewMicroTime(renewTime)
+	c.lease = &coordination_v1.Lease{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "keel", Namespace: "keel"},
+		Spec: coordination_v1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &t,
+			RenewTime:            &t,
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	client := &fakeLeaseClient{}
+	cfg := Config{
+		Client:        client,
+		Namespace:     "keel",
+		Name:          "keel",
+		LeaseDuration: time.Second,
+		RenewDeadline: 50 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+	cfg.Identity = "a"
+	a := New(cfg)
+	cfg.Identity = "b"
+	b := New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string, 2)
+	errs := make(chan error, 2)
+	go func() { errs <- a.Run(ctx, func(context.Context) { started <- "a" }) }()
+
+	select {
+	case id := <-started:
+		if id != "a" {
+			t.Fatalf("unexpected leader: %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("a didn't start leading")
+	}
+	if !a.IsLeader() {
+		t.Errorf("expected a to be the leader")
+	}
+
+	go func() { errs <- b.Run(ctx, func(context.Context) { started <- "b" }) }()
+	time.Sleep(50 * time.Millisecond)
+	if b.IsLeader() {
+		t.Fatalf("expected b to be a follower")
+	}
+
+	// renewals start failing, a should give up leadership and b take over once lease expires
+	client.mu.Lock()
+	client.failing = true
+	client.mu.Unlock()
+
+	select {
+	case err := <-errs:
+		if err != ErrLeadershipLost {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("a didn't stop leading")
+	}
+	if a.IsLeader() {
+		t.Errorf("expected a to stop leading")
+	}
+
+	client.mu.Lock()
+	client.failing = false
+	client.mu.Unlock()
+
+	select {
+	case id := <-started:
+		if id != "b" {
+			t.Fatalf("unexpected leader: %s", id)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("b didn't start leading")
+	}
+}
+
+func TestRunClockSkew(t *testing.T) {
+	// leader's clock is an hour behind, its renew times look expired to us
+	client := &fakeLeaseClient{}
+	skewed := time.Now().Add(-time.Hour)
+	client.renew("a", skewed)
+
+	b := New(Config{
+		Client:        client,
+		Namespace:     "keel",
+		Name:          "keel",
+		Identity:      "b",
+		LeaseDuration: time.Second,
+		RenewDeadline: 50 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{}, 1)
+	go b.Run(ctx, func(context.Context) { started <- struct{}{} })
+
+	// a keeps renewing the lease
+	for i := 0; i < 30; i++ {
+		skewed = skewed.Add(10 * time.Millisecond)
+		client.renew("a", skewed)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-started:
+		t.Fatalf("expected b not to take over lease renewed by a")
+	default:
+	}
+	if b.IsLeader() {
+		t.Errorf("expected b to be a follower")
+	}
+}
+
+type fakePodClient struct {
+	corev1client.PodInterface
+
+	patches []string
+}
+
+func (c *fakePodClient) Pods(namespace string) corev1client.PodInterface {
+	return c
+}
+
+func (c *fakePodClient) Patch(name string, pt k8stypes.PatchType, data []byte, subresources ...string) (*v1.Pod, error) {
+	c.patches = append(c.patches, name+" "+string(data))
+	return &v1.Pod{}, nil
+}
+
+func TestLabelPod(t *testing.T) {
+	client := &fakePodClient{}
+	if err := LabelPod(client, "keel", "keel-0", true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := LabelPod(client, "keel", "keel-0", false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`keel-0 {"metadata":{"labels":{"keel.sh/leader":"true"}}}`,
+		`keel-0 {"metadata":{"labels":{"keel.sh/leader":null}}}`,
+	}
+	if len(client.patches) != len(expected) {
+		t.Fatalf("unexpected patches: %v", client.patches)
+	}
+	for idx := range expected {
+		if client.patches[idx] != expected[idx] {
+			t.Errorf("unexpected patch: %s", client.patches[idx])
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter/functions"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// CELPolicy - inline Common Expression Language (https://github.com/google/cel-spec)
+// policy, for example cel:'semver(new) > semver(current) && !new.contains("rc")'
+//
+// Besides standard CEL functions and macros, semver(string) parses a version, versions
+// can be compared and have major(), minor(), patch() and prerelease() methods.
+//
+// Available variables are current and new tags and resource map with namespace, name,
+// kind, container, image and provider of the updated resource, ie: resource.namespace
+type CELPolicy struct {
+	policy  string
+	program cel.Program
+}
+
+// compiled expressions are cached, policies are parsed for every event
+var (
+	celProgramsM sync.Mutex
+	celPrograms  = make(map[string]cel.Program)
+)
+
+var (
+	celEnvOnce sync.Once
+	celEnv     cel.Env
+	celEnvErr  error
+)
+
+// versionType - type of values returned by semver()
+var versionType = decls.NewAbstractType("keel.Version")
+
+var versionTypeValue = types.NewTypeValue("keel.Version")
+
+func NewCELPolicy(policy string) (*CELPolicy, error) {
+	expression := strings.TrimSpace(strings.TrimPrefix(policy, "cel:"))
+	if len(expression) >= 2 && (expression[0] == '\'' || expression[0] == '"') && expression[len(expression)-1] == expression[0] {
+		expression = expression[1 : len(expression)-1]
+	}
+
+	celProgramsM.Lock()
+	defer celProgramsM.Unlock()
+
+	program, ok := celPrograms[expression]
+	if !ok {
+		var err error
+		program, err = compileCEL(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cel expression, error: %s", err)
+		}
+		celPrograms[expression] = program
+	}
+
+	return &CELPolicy{
+		policy:  policy,
+		program: program,
+	}, nil
+}
+
+func (p *CELPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateContext(nil, current, new)
+}
+
+// ShouldUpdateContext - evaluates expression, it has to return a bool
+func (p *CELPolicy) ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error) {
+	if ctx == nil {
+		ctx = &UpdateContext{}
+	}
+	vars := map[string]interface{}{
+		"current": current,
+		"new":     new,
+		"resource": map[string]string{
+			"namespace": ctx.Namespace,
+			"name":      ctx.Name,
+			"kind":      ctx.Kind,
+			"container": ctx.Container,
+			"image":     ctx.Image,
+			"provider":  ctx.Provider,
+		},
+	}
+
+	result, _, err := p.program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	allowed, ok := result.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("cel expression returned %T instead of bool", result.Value())
+	}
+	return allowed, nil
+}
+
+func (p *CELPolicy) Name() string     { return p.policy }
+func (p *CELPolicy) Type() PolicyType { return PolicyTypeCEL }
+
+// compileCEL - parses and type checks the expression, it has to return a bool
+func compileCEL(expression string) (cel.Program, error) {
+	env, err := getCELEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, issues := env.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	checked, issues := env.Check(parsed)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !reflect.DeepEqual(checked.ResultType(), decls.Bool) {
+		return nil, fmt.Errorf("expression has to return bool")
+	}
+
+	return env.Program(checked, cel.Functions(celVersionFunctions()...))
+}
+
+func getCELEnv() (cel.Env, error) {
+	celEnvOnce.Do(func() {
+		versions := []*exprpb.Type{versionType, versionType}
+		celEnv, celEnvErr = cel.NewEnv(cel.Declarations(
+			decls.NewIdent("current", decls.String, nil),
+			decls.NewIdent("new", decls.String, nil),
+			decls.NewIdent("resource", decls.NewMapType(decls.String, decls.String), nil),
+			decls.NewFunction("semver",
+				decls.NewOverload("semver_string", []*exprpb.Type{decls.String}, versionType)),
+			decls.NewFunction(operators.Less,
+				decls.NewOverload("less_version", versions, decls.Bool)),
+			decls.NewFunction(operators.LessEquals,
+				decls.NewOverload("less_equals_version", versions, decls.Bool)),
+			decls.NewFunction(operators.Greater,
+				decls.NewOverload("greater_version", versions, decls.Bool)),
+			decls.NewFunction(operators.GreaterEquals,
+				decls.NewOverload("greater_equals_version", versions, decls.Bool)),
+			decls.NewFunction("major",
+				decls.NewInstanceOverload("version_major", []*exprpb.Type{versionType}, decls.Int)),
+			decls.NewFunction("minor",
+				decls.NewInstanceOverload("version_minor", []*exprpb.Type{versionType}, decls.Int)),
+			decls.NewFunction("patch",
+				decls.NewInstanceOverload("version_patch", []*exprpb.Type{versionType}, decls.Int)),
+			decls.NewFunction("prerelease",
+				decls.NewInstanceOverload("version_prerelease", []*exprpb.Type{versionType}, decls.String)),
+		))
+	})
+	return celEnv, celEnvErr
+}
+
+// celVersionFunctions - implementations of semver() and version overloads
+func celVersionFunctions() []*functions.Overload {
+	compare := func(fn func(cmp int) bool) functions.BinaryOp {
+		return func(lhs, rhs ref.Val) ref.Val {
+			l, ok := lhs.(celVersion)
+			if !ok {
+				return types.ValOrErr(lhs, "no such overload")
+			}
+			r, ok := rhs.(celVersion)
+			if !ok {
+				return types.ValOrErr(rhs, "no such overload")
+			}
+			return types.Bool(fn(l.Compare(r.Version)))
+		}
+	}
+	method := func(fn func(v celVersion) ref.Val) functions.UnaryOp {
+		return func(val ref.Val) ref.Val {
+			v, ok := val.(celVersion)
+			if !ok {
+				return types.ValOrErr(val, "no such overload")
+			}
+			return fn(v)
+		}
+	}
+
+	return []*functions.Overload{
+		{Operator: "semver_string", Unary: func(val ref.Val) ref.Val {
+			s, ok := val.(types.String)
+			if !ok {
+				return types.ValOrErr(val, "no such overload")
+			}
+			v, err := semver.NewVersion(string(s))
+			if err != nil {
+				return types.NewErr("invalid version '%s': %s", s, err)
+			}
+			return celVersion{v}
+		}},
+		{Operator: "less_version", Binary: compare(func(cmp int) bool { return cmp < 0 })},
+		{Operator: "less_equals_version", Binary: compare(func(cmp int) bool { return cmp <= 0 })},
+		{Operator: "greater_version", Binary: compare(func(cmp int) bool { return cmp > 0 })},
+		{Operator: "greater_equals_version", Binary: compare(func(cmp int) bool { return cmp >= 0 })},
+		{Operator: "version_major", Unary: method(func(v celVersion) ref.Val { return types.Int(v.Major()) })},
+		{Operator: "version_minor", Unary: method(func(v celVersion) ref.Val { return types.Int(v.Minor()) })},
+		{Operator: "version_patch", Unary: method(func(v celVersion) ref.Val { return types.Int(v.Patch()) })},
+		{Operator: "version_prerelease", Unary: method(func(v celVersion) ref.Val { return types.String(v.Prerelease()) })},
+	}
+}
+
+// celVersion - CEL value of a parsed version
+type celVersion struct {
+	*semver.Version
+}
+
+func (v celVersion) ConvertToNative(typeDesc reflect.Type) (interface{}, error) {
+	if reflect.TypeOf(v.Version).AssignableTo(typeDesc) {
+		return v.Version, nil
+	}
+	return nil, fmt.Errorf("type conversion error from version to '%v'", typeDesc)
+}
+
+func (v celVersion) ConvertToType(typeValue ref.Type) ref.Val {
+	switch typeValue {
+	case versionTypeValue:
+		return v
+	case types.StringType:
+		return types.String(v.Original())
+	case types.TypeType:
+		return versionTypeValue
+	}
+	return types.NewErr("type conversion error from version to '%s'", typeValue)
+}
+
+func (v celVersion) Equal(other ref.Val) ref.Val {
+	o, ok := other.(celVersion)
+	if !ok {
+		return types.ValOrErr(other, "no such overload")
+	}
+	return types.Bool(v.Version.Equal(o.Version))
+}
+
+func (v celVersion) Type() ref.Type {
+	return versionTypeValue
+}
+
+func (v celVersion) Value() interface{} {
+	return v.Version
+}
@@ -0,0 +1,73 @@
+package policy
+
+import "testing"
+
+func TestCELPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		ctx     *UpdateContext
+		current string
+		new     string
+		want    bool
+		wantErr bool
+	}{
+		{name: "newer version", policy: `cel:'semver(new) > semver(current) && !new.contains("rc")'`, current: "1.0.0", new: "1.1.0", want: true},
+		{name: "release candidate", policy: `cel:'semver(new) > semver(current) && !new.contains("rc")'`, current: "1.0.0", new: "1.1.0-rc1", want: false},
+		{name: "older version", policy: `cel:semver(new) > semver(current)`, current: "1.2.0", new: "1.1.0", want: false},
+		{name: "same major", policy: `cel:semver(new).major() == semver(current).major() && semver(new) > semver(current)`, current: "1.2.0", new: "2.0.0", want: false},
+		{name: "prefix and int", policy: `cel:new.startsWith("build-") && int("42") == 42 && new.endsWith("2")`, current: "build-1", new: "build-2", want: true},
+		{name: "matches", policy: `cel:new.matches("^[0-9]+\\.[0-9]+$") || new == "stable"`, current: "1.0", new: "stable", want: true},
+		{name: "namespace", policy: `cel:resource.namespace != "prod" || semver(new).patch() > 0`, ctx: &UpdateContext{Namespace: "prod"}, current: "1.0.0", new: "1.1.0", want: false},
+		{name: "resource", policy: `cel:resource.kind == "deployment" && resource.name.startsWith("web-")`, ctx: &UpdateContext{Kind: "deployment", Name: "web-1"}, current: "1.0.0", new: "1.1.0", want: true},
+		{name: "macro", policy: `cel:["rc", "beta"].all(s, !new.contains(s))`, current: "1.0.0", new: "1.1.0-beta", want: false},
+		{name: "prerelease", policy: `cel:semver(new).prerelease() == "" && semver(new) >= semver(current)`, current: "1.0.0", new: "1.0.0", want: true},
+		{name: "int arithmetic", policy: `cel:size(new) - 1 >= 5 && -1 < 0`, current: "1", new: "123456", want: true},
+		{name: "invalid version", policy: `cel:semver(new) > semver(current)`, current: "1.0.0", new: "latest", wantErr: true},
+		{name: "not bool", policy: `cel:new + "x"`, current: "1.0.0", new: "1.1.0", wantErr: true},
+		{name: "type mismatch", policy: `cel:new > 1`, current: "1.0.0", new: "1.1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCELPolicy(tt.policy)
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatalf("failed to parse policy: %s", err)
+				}
+				return
+			}
+			got, err := ShouldUpdate(p, tt.ctx, tt.current, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CELPolicy.ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CELPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCELPolicyInvalid(t *testing.T) {
+	for _, policy := range []string{"cel:", "cel:new ==", "cel:(new == current", "cel:new.contains(", "cel:'unterminated", `cel:new == "x`, "cel:new # 1", "cel:namespace == \"prod\"", "cel:unknown == new"} {
+		if _, err := NewCELPolicy(policy); err == nil {
+			t.Errorf("expected error for '%s'", policy)
+		}
+	}
+}
+
+func TestGetCELPolicy(t *testing.T) {
+	p := GetPolicy(`cel:'semver(new) > semver(current) || new == "stable"'`, &Options{})
+	if p.Type() != PolicyTypeCEL {
+		t.Fatalf("expected cel policy, got: %s", p.Name())
+	}
+
+	composite := GetPolicy(`glob:1.* && (cel:'new.contains(")") || new.endsWith("0")')`, &Options{})
+	if composite.Type() != PolicyTypeComposite {
+		t.Fatalf("expected composite policy, got: %s", composite.Name())
+	}
+	allowed, err := composite.ShouldUpdate("1.0.0", "1.1.0")
+	if err != nil || !allowed {
+		t.Errorf("expected update to be allowed, got: %v, %v", allowed, err)
+	}
+}
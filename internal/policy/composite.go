@@ -0,0 +1,191 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// composite policy operators, && binds tighter than ||
+const (
+	operatorAnd = "&&"
+	operatorOr  = "||"
+)
+
+// CompositePolicy - combines multiple policies, for example "glob:release-* && minor".
+// Parentheses can be used for grouping: "(glob:release-* && minor) || force"
+type CompositePolicy struct {
+	policy   string
+	operator string
+	policies []Policy
+}
+
+// NewCompositePolicy - parses policy expression. Semver constraints use || themselves so
+// they only end at && or a closing parenthesis, wrap them in parentheses to combine them
+// with || and other policies. CEL expressions only end at a closing parenthesis
+func NewCompositePolicy(policy string, options *Options) (Policy, error) {
+	parser := &policyParser{input: policy, options: options}
+	p, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.pos < len(parser.input) {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", parser.input[parser.pos:], parser.pos)
+	}
+	return p, nil
+}
+
+func (p *CompositePolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateContext(nil, current, new)
+}
+
+// ShouldUpdateContext - with && every policy has to allow the update, with || at least one
+func (p *CompositePolicy) ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error) {
+	var lastErr error
+	for _, policy := range p.policies {
+		update, err := ShouldUpdate(policy, ctx, current, new)
+		if p.operator == operatorAnd {
+			if err != nil || !update {
+				return false, err
+			}
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if update {
+			return true, nil
+		}
+	}
+	if p.operator == operatorAnd {
+		return true, nil
+	}
+	return false, lastErr
+}
+
+func (p *CompositePolicy) Name() string     { return p.policy }
+func (p *CompositePolicy) Type() PolicyType { return PolicyTypeComposite }
+
+// isCompositePolicy - whether policy has to go through the expression parser, CEL
+// policies have their own operators
+func isCompositePolicy(policy string) bool {
+	if strings.HasPrefix(policy, "cel:") {
+		return false
+	}
+	return strings.Contains(policy, operatorAnd) || strings.Contains(policy, operatorOr)
+}
+
+type policyParser struct {
+	input   string
+	pos     int
+	options *Options
+}
+
+func (p *policyParser) skipSpaces() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *policyParser) parseOr() (Policy, error) {
+	return p.parseBinary(operatorOr, p.parseAnd)
+}
+
+func (p *policyParser) parseAnd() (Policy, error) {
+	return p.parseBinary(operatorAnd, p.parseOperand)
+}
+
+func (p *policyParser) parseBinary(operator string, next func() (Policy, error)) (Policy, error) {
+	p.skipSpaces()
+	start := p.pos
+
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+	policies := []Policy{first}
+	for {
+		p.skipSpaces()
+		if !strings.HasPrefix(p.input[p.pos:], operator) {
+			break
+		}
+		p.pos += len(operator)
+		policy, err := next()
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if len(policies) == 1 {
+		return first, nil
+	}
+
+	return &CompositePolicy{
+		policy:   strings.TrimSpace(p.input[start:p.pos]),
+		operator: operator,
+		policies: policies,
+	}, nil
+}
+
+func (p *policyParser) parseOperand() (Policy, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("expected policy at the end of '%s'", p.input)
+	}
+
+	if p.input[p.pos] == '(' {
+		p.pos++
+		policy, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis in '%s'", p.input)
+		}
+		p.pos++
+		return policy, nil
+	}
+
+	// parentheses within a policy (regexp groups) are kept as part of it
+	start := p.pos
+	semver := strings.HasPrefix(p.input[p.pos:], "semver:")
+	cel := strings.HasPrefix(p.input[p.pos:], "cel:")
+	depth := 0
+	var quote byte
+	for ; p.pos < len(p.input); p.pos++ {
+		rest := p.input[p.pos:]
+		if cel && quote != 0 {
+			if rest[0] == quote {
+				quote = 0
+			}
+			continue
+		}
+		if depth == 0 && !cel && (strings.HasPrefix(rest, operatorAnd) || (!semver && strings.HasPrefix(rest, operatorOr))) {
+			break
+		}
+		if cel && (rest[0] == '"' || rest[0] == '\'') {
+			quote = rest[0]
+			continue
+		}
+		if rest[0] == '(' {
+			depth++
+		} else if rest[0] == ')' {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+	}
+
+	name := strings.TrimSpace(p.input[start:p.pos])
+	if name == "" {
+		return nil, fmt.Errorf("expected policy at position %d of '%s'", start, p.input)
+	}
+	policy := getPolicy(name, p.options)
+	if policy.Type() == PolicyTypeNone {
+		return nil, fmt.Errorf("invalid policy '%s'", name)
+	}
+	return policy, nil
+}
@@ -0,0 +1,62 @@
+package policy
+
+import "testing"
+
+func TestCompositePolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{name: "and, both match", policy: "glob:1.* && minor", current: "1.2.0", new: "1.3.0", want: true},
+		{name: "and, glob doesn't match", policy: "glob:1.* && major", current: "1.2.0", new: "2.0.0", want: false},
+		{name: "and, semver doesn't allow", policy: "glob:1.* && patch", current: "1.2.0", new: "1.3.0", want: false},
+		{name: "or, second matches", policy: "patch || glob:1.3.*", current: "1.2.0", new: "1.3.0", want: true},
+		{name: "or, none match", policy: "patch || glob:2.*", current: "1.2.0", new: "1.3.0", want: false},
+		{name: "precedence", policy: "glob:9.* && all || patch", current: "1.2.0", new: "1.2.1", want: true},
+		{name: "parentheses", policy: "glob:9.* && (all || patch)", current: "1.2.0", new: "1.2.1", want: false},
+		{name: "semver constraint", policy: "semver:>=1.2.0 <2.0.0 || ~3.1.x && glob:3.*", current: "1.2.0", new: "3.1.1", want: true},
+		{name: "semver constraint in parentheses", policy: "(semver:~1.2) || glob:3.*", current: "1.2.0", new: "3.5.0", want: true},
+		{name: "regexp groups", policy: `regexp:^(?P<build>\d+)$ && (regexp:^1\d+$)`, current: "9", new: "10", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCompositePolicy(tt.policy, &Options{})
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("CompositePolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCompositePolicyErrors(t *testing.T) {
+	for _, policy := range []string{"minor &&", "&& minor", "(minor || patch", "minor && unknown", "minor) && patch"} {
+		if _, err := NewCompositePolicy(policy, &Options{}); err == nil {
+			t.Errorf("expected error for '%s'", policy)
+		}
+	}
+}
+
+func TestGetCompositePolicy(t *testing.T) {
+	p := GetPolicy("glob:release-* && minor", &Options{})
+	if p.Type() != PolicyTypeComposite {
+		t.Fatalf("expected composite policy, got: %s", p.Name())
+	}
+	if p.Name() != "glob:release-* && minor" {
+		t.Errorf("unexpected name: %s", p.Name())
+	}
+
+	// semver constraint alone stays a semver policy
+	if _, ok := GetPolicy("semver:^1.2 || ^2.0", &Options{}).(*SemverConstraintPolicy); !ok {
+		t.Errorf("expected semver constraint policy")
+	}
+}
@@ -0,0 +1,30 @@
+package policy
+
+// UpdateContext - describes the resource and image being updated, passed on to
+// policies that decide based on more than just tags
+type UpdateContext struct {
+	Provider    string            `json:"provider"`
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Container   string            `json:"container,omitempty"`
+	Image       string            `json:"image"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ContextPolicy - policy that also needs to know what is being updated, ctx
+// might be nil when the caller doesn't know it (for example poll trigger)
+type ContextPolicy interface {
+	Policy
+	ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error)
+}
+
+// ShouldUpdate - checks whether policy allows the update, update context is passed
+// on to policies that support it
+func ShouldUpdate(p Policy, ctx *UpdateContext, current, new string) (bool, error) {
+	if cp, ok := p.(ContextPolicy); ok {
+		return cp.ShouldUpdateContext(ctx, current, new)
+	}
+	return p.ShouldUpdate(current, new)
+}
@@ -0,0 +1,181 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// Explanation - policy decision with a human readable reason, composite and
+// filtered policies include decisions of the policies they are made of
+type Explanation struct {
+	Policy  string         `json:"policy"`
+	Type    PolicyType     `json:"type"`
+	Allowed bool           `json:"allowed"`
+	Reason  string         `json:"reason"`
+	Error   string         `json:"error,omitempty"`
+	Rules   []*Explanation `json:"rules,omitempty"`
+}
+
+// String - explanation as indented text, used by bots
+func (e *Explanation) String() string {
+	var b strings.Builder
+	e.write(&b, 0)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (e *Explanation) write(b *strings.Builder, depth int) {
+	decision := "denied"
+	if e.Allowed {
+		decision = "allowed"
+	}
+	fmt.Fprintf(b, "%s- %s [%s]: %s", strings.Repeat("  ", depth), e.Policy, decision, e.Reason)
+	if e.Error != "" {
+		fmt.Fprintf(b, " (error: %s)", e.Error)
+	}
+	b.WriteString("\n")
+	for _, rule := range e.Rules {
+		rule.write(b, depth+1)
+	}
+}
+
+// Explain - evaluates policy for the given tags and describes which rule decided
+// the outcome, ctx might be nil
+func Explain(p Policy, ctx *UpdateContext, current, new string) *Explanation {
+	e := &Explanation{
+		Policy: p.Name(),
+		Type:   p.Type(),
+	}
+
+	switch policy := p.(type) {
+	case *NilPolicy:
+		e.Reason = "no policy set, updates are disabled"
+		return e
+	case *TagFilterPolicy:
+		if matchAnyTag(policy.blocked, new) {
+			e.Reason = fmt.Sprintf("tag %s matches blocked tags", new)
+			return e
+		}
+		if len(policy.allowed) > 0 && !matchAnyTag(policy.allowed, new) {
+			e.Reason = fmt.Sprintf("tag %s doesn't match any of allowed tags", new)
+			return e
+		}
+		inner := Explain(policy.policy, ctx, current, new)
+		e.Allowed, e.Error, e.Rules = inner.Allowed, inner.Error, []*Explanation{inner}
+		e.Reason = fmt.Sprintf("tag %s passed allowed and blocked tags, decided by policy %s", new, inner.Policy)
+		return e
+	case *CompositePolicy:
+		return explainComposite(e, policy, ctx, current, new)
+	}
+
+	allowed, err := ShouldUpdate(p, ctx, current, new)
+	e.Allowed = allowed
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	switch policy := p.(type) {
+	case *SemverPolicy:
+		e.Reason = explainSemver(policy, current, new)
+	case *ForcePolicy:
+		if allowed {
+			e.Reason = "force policy updates to any tag"
+		} else {
+			e.Reason = fmt.Sprintf("keel.sh/matchTag is set and tag %s differs from %s", new, current)
+		}
+	case *GlobPolicy, *RegexpPolicy, *NumberPolicy, *SemverConstraintPolicy, *LexicographicPolicy, *TimestampPolicy:
+		e.Reason = explainDecision(p, allowed, err, fmt.Sprintf("tag %s", new))
+	default:
+		e.Reason = explainDecision(p, allowed, err, fmt.Sprintf("update from %s to %s", current, new))
+	}
+	return e
+}
+
+func explainDecision(p Policy, allowed bool, err error, subject string) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("policy %s failed to evaluate %s", p.Name(), subject)
+	case allowed:
+		return fmt.Sprintf("policy %s allows %s", p.Name(), subject)
+	}
+	return fmt.Sprintf("policy %s doesn't allow %s", p.Name(), subject)
+}
+
+func explainComposite(e *Explanation, p *CompositePolicy, ctx *UpdateContext, current, new string) *Explanation {
+	var decidedBy *Explanation
+	for _, policy := range p.policies {
+		rule := Explain(policy, ctx, current, new)
+		e.Rules = append(e.Rules, rule)
+		if decidedBy != nil {
+			continue
+		}
+		if p.operator == operatorAnd && !rule.Allowed {
+			decidedBy = rule
+		}
+		if p.operator == operatorOr && rule.Allowed {
+			decidedBy = rule
+		}
+	}
+
+	switch {
+	case p.operator == operatorAnd && decidedBy == nil:
+		e.Allowed = true
+		e.Reason = "all policies allow the update"
+	case p.operator == operatorAnd:
+		e.Reason = fmt.Sprintf("policy %s doesn't allow the update", decidedBy.Policy)
+		e.Error = decidedBy.Error
+	case decidedBy != nil:
+		e.Allowed = true
+		e.Reason = fmt.Sprintf("policy %s allows the update", decidedBy.Policy)
+	default:
+		e.Reason = "none of the policies allow the update"
+	}
+	return e
+}
+
+// explainSemver - follows the same steps as shouldUpdate
+func explainSemver(p *SemverPolicy, current, new string) string {
+	if current == "latest" {
+		return "current tag is latest, any version is an update"
+	}
+	if len(strings.SplitN(new, ".", 3)) != 3 {
+		return fmt.Sprintf("tag %s doesn't have major.minor.patch elements", new)
+	}
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return fmt.Sprintf("current tag %s is not a semver version", current)
+	}
+	newVersion, err := semver.NewVersion(new)
+	if err != nil {
+		return fmt.Sprintf("tag %s is not a semver version", new)
+	}
+	if currentVersion.Prerelease() != newVersion.Prerelease() && p.spt != SemverPolicyTypeAll && p.matchPreRelease {
+		return fmt.Sprintf("pre-release '%s' doesn't match current '%s', see keel.sh/matchPreRelease", newVersion.Prerelease(), currentVersion.Prerelease())
+	}
+	direction := "update"
+	switch {
+	case newVersion.Equal(currentVersion):
+		return fmt.Sprintf("%s is the same version as current %s", new, current)
+	case newVersion.LessThan(currentVersion) && !p.allowDowngrade:
+		return fmt.Sprintf("%s is lower than current version %s, use %s%s to follow downgrades", new, current, p.Name(), allowDowngradeSuffix)
+	case newVersion.LessThan(currentVersion):
+		direction = "downgrade"
+	}
+
+	change := "patch"
+	switch {
+	case newVersion.Major() != currentVersion.Major():
+		change = "major"
+	case newVersion.Minor() != currentVersion.Minor():
+		change = "minor"
+	case newVersion.Patch() == currentVersion.Patch():
+		change = "pre-release"
+	}
+
+	allowed, _ := p.ShouldUpdate(current, new)
+	if allowed {
+		return fmt.Sprintf("%s -> %s is a %s %s, allowed by %s policy", current, new, change, direction, p.Name())
+	}
+	return fmt.Sprintf("%s -> %s is a %s %s, %s policy doesn't allow it", current, new, change, direction, p.Name())
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		options *Options
+		current string
+		new     string
+		allowed bool
+		reason  string
+	}{
+		{
+			name:    "no policy",
+			policy:  "never",
+			current: "1.0.0",
+			new:     "1.0.1",
+			reason:  "no policy set, updates are disabled",
+		},
+		{
+			name:    "minor update",
+			policy:  "minor",
+			current: "1.0.0",
+			new:     "1.1.0",
+			allowed: true,
+			reason:  "1.0.0 -> 1.1.0 is a minor update, allowed by minor policy",
+		},
+		{
+			name:    "major update with patch policy",
+			policy:  "patch",
+			current: "1.0.0",
+			new:     "2.0.0",
+			reason:  "1.0.0 -> 2.0.0 is a major update, patch policy doesn't allow it",
+		},
+		{
+			name:    "pre-release mismatch",
+			policy:  "major",
+			current: "1.0.0",
+			new:     "1.1.0-rc1",
+			reason:  "pre-release 'rc1' doesn't match current '', see keel.sh/matchPreRelease",
+		},
+		{
+			name:    "downgrade",
+			policy:  "patch",
+			current: "1.0.1",
+			new:     "1.0.0",
+			reason:  "1.0.0 is lower than current version 1.0.1, use patch:allow-downgrade to follow downgrades",
+		},
+		{
+			name:    "allowed downgrade",
+			policy:  "patch:allow-downgrade",
+			current: "1.0.1",
+			new:     "1.0.0",
+			allowed: true,
+			reason:  "1.0.1 -> 1.0.0 is a patch downgrade, allowed by patch:allow-downgrade policy",
+		},
+		{
+			name:    "blocked tag",
+			policy:  "all",
+			options: &Options{BlockedTags: []string{"*-debug"}},
+			current: "1.0.0",
+			new:     "1.0.1-debug",
+			reason:  "tag 1.0.1-debug matches blocked tags",
+		},
+		{
+			name:    "composite and",
+			policy:  "glob:release-* && force",
+			current: "release-1",
+			new:     "release-2",
+			allowed: true,
+			reason:  "all policies allow the update",
+		},
+		{
+			name:    "composite or",
+			policy:  "glob:release-* || minor",
+			current: "1.0.0",
+			new:     "1.1.0",
+			allowed: true,
+			reason:  "policy minor allows the update",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if options == nil {
+				options = &Options{MatchPreRelease: true}
+			}
+			e := Explain(GetPolicy(tt.policy, options), nil, tt.current, tt.new)
+			if e.Allowed != tt.allowed {
+				t.Errorf("Explain() allowed = %v, want %v", e.Allowed, tt.allowed)
+			}
+			if e.Reason != tt.reason {
+				t.Errorf("Explain() reason = %q, want %q", e.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestExplainString(t *testing.T) {
+	e := Explain(GetPolicy("glob:release-* && minor", &Options{}), nil, "1.0.0", "1.1.0")
+	expected := strings.Join([]string{
+		"- glob:release-* && minor [denied]: policy glob:release-* doesn't allow the update",
+		"  - glob:release-* [denied]: policy glob:release-* doesn't allow tag 1.1.0",
+		"  - minor [allowed]: 1.0.0 -> 1.1.0 is a minor update, allowed by minor policy",
+	}, "\n")
+	if e.String() != expected {
+		t.Errorf("unexpected explanation:\n%s", e.String())
+	}
+}
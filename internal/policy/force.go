@@ -22,3 +22,19 @@ func (fp *ForcePolicy) Name() string {
 }
 
 func (fp *ForcePolicy) Type() PolicyType { return PolicyTypeForce }
+
+// MatchTag - whether only the current tag is accepted, updates of such images can only
+// be detected by the digest behind the tag
+func (fp *ForcePolicy) MatchTag() bool { return fp.matchTag }
+
+// MatchesTag - checks whether policy only accepts the current tag, that is force policy
+// with keel.sh/match-tag (possibly wrapped with tag filters)
+func MatchesTag(p Policy) bool {
+	switch plc := p.(type) {
+	case *ForcePolicy:
+		return plc.MatchTag()
+	case *TagFilterPolicy:
+		return MatchesTag(plc.policy)
+	}
+	return false
+}
@@ -2,32 +2,95 @@ package policy
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
+	"github.com/Masterminds/semver"
 	"github.com/ryanuber/go-glob"
 )
 
+// globSemverPlaceholder - part of the glob pattern that holds semver version, for example
+// "glob:app-{semver}", extracted versions are compared so only higher versions are updated to
+const globSemverPlaceholder = "{semver}"
+
+const globSemverExpression = `(v?\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)`
+
 type GlobPolicy struct {
 	policy  string // original string
 	pattern string // without prefix
+
+	// version - set when pattern has {semver} placeholder
+	version *regexp.Regexp
 }
 
 func NewGlobPolicy(policy string) (*GlobPolicy, error) {
 	if strings.Contains(policy, ":") {
 		parts := strings.Split(policy, ":")
 		if len(parts) == 2 {
-			return &GlobPolicy{
+			p := &GlobPolicy{
 				policy:  policy,
 				pattern: parts[1],
-			}, nil
+			}
+			if strings.Contains(p.pattern, globSemverPlaceholder) {
+				version, err := compileGlobSemver(p.pattern)
+				if err != nil {
+					return nil, fmt.Errorf("invalid glob policy: %s: %s", policy, err)
+				}
+				p.version = version
+			}
+			return p, nil
 		}
 	}
 
 	return nil, fmt.Errorf("invalid glob policy: %s", policy)
 }
 
+// compileGlobSemver - converts glob pattern with a single {semver} placeholder to regexp
+func compileGlobSemver(pattern string) (*regexp.Regexp, error) {
+	parts := strings.Split(pattern, globSemverPlaceholder)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("only one %s placeholder is allowed", globSemverPlaceholder)
+	}
+
+	expression := make([]string, len(parts))
+	for idx, part := range parts {
+		literals := strings.Split(part, "*")
+		for i := range literals {
+			literals[i] = regexp.QuoteMeta(literals[i])
+		}
+		expression[idx] = strings.Join(literals, ".*")
+	}
+	return regexp.Compile("^" + strings.Join(expression, globSemverExpression) + "$")
+}
+
+// extractVersion - version embedded in the tag, false if tag doesn't match the pattern
+func (p *GlobPolicy) extractVersion(tag string) (*semver.Version, bool) {
+	match := p.version.FindStringSubmatch(tag)
+	if match == nil {
+		return nil, false
+	}
+	version, err := semver.NewVersion(match[1])
+	if err != nil {
+		return nil, false
+	}
+	return version, true
+}
+
 func (p *GlobPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return glob.Glob(p.pattern, new), nil
+	if p.version == nil {
+		return glob.Glob(p.pattern, new), nil
+	}
+
+	newVersion, ok := p.extractVersion(new)
+	if !ok {
+		return false, nil
+	}
+	currentVersion, ok := p.extractVersion(current)
+	if !ok {
+		// current tag doesn't follow the pattern, any matching tag is an update
+		return true, nil
+	}
+	return currentVersion.LessThan(newVersion), nil
 }
 
 func (p *GlobPolicy) Name() string     { return p.policy }
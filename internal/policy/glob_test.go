@@ -57,3 +57,44 @@ func TestGlobPolicy_ShouldUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestGlobPolicySemver(t *testing.T) {
+	tests := []struct {
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{"glob:app-{semver}", "app-1.2.3", "app-1.2.4", true},
+		{"glob:app-{semver}", "app-1.2.3", "app-1.10.0", true},
+		{"glob:app-{semver}", "app-1.10.0", "app-1.9.0", false},
+		{"glob:app-{semver}", "app-1.2.3", "app-1.2.3", false},
+		{"glob:app-{semver}", "app-1.2.3", "web-1.2.4", false},
+		{"glob:app-{semver}", "app-1.2.3", "app-latest", false},
+		{"glob:app-{semver}", "latest", "app-1.2.4", true},
+		{"glob:app-{semver}-alpine", "app-1.2.3-alpine", "app-1.2.4-alpine", true},
+		{"glob:app-{semver}-alpine", "app-1.2.3-alpine", "app-1.2.4", false},
+		{"glob:*-{semver}", "web-v1.0.0", "app-v1.1.0", true},
+		{"glob:app-{semver}", "app-1.2.3", "app-1.2.4-rc.1", true},
+		{"glob:app.{semver}", "app.1.2.3", "appx1.2.4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.current+"->"+tt.new, func(t *testing.T) {
+			p, err := NewGlobPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to parse policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("ShouldUpdate(%s, %s) = %v, want %v", tt.current, tt.new, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewGlobPolicy("glob:{semver}-{semver}"); err == nil {
+		t.Errorf("expected error for multiple placeholders")
+	}
+}
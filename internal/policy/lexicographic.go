@@ -0,0 +1,40 @@
+package policy
+
+import (
+	"strings"
+
+	"github.com/ryanuber/go-glob"
+)
+
+// LexicographicPolicy - updates to tags that sort after the current one, for tags that are
+// sortable strings (20240117T1030-gitsha). Optional glob pattern ("lexicographic:2024*")
+// limits which tags are considered
+type LexicographicPolicy struct {
+	policy  string
+	pattern string
+}
+
+func NewLexicographicPolicy(policy string) *LexicographicPolicy {
+	return &LexicographicPolicy{
+		policy:  policy,
+		pattern: strings.TrimPrefix(strings.TrimPrefix(policy, "lexicographic"), ":"),
+	}
+}
+
+func (p *LexicographicPolicy) matches(tag string) bool {
+	return p.pattern == "" || glob.Glob(p.pattern, tag)
+}
+
+// ShouldUpdate - current tag that doesn't match the pattern (for example "latest") is always updated
+func (p *LexicographicPolicy) ShouldUpdate(current, new string) (bool, error) {
+	if !p.matches(new) {
+		return false, nil
+	}
+	if !p.matches(current) {
+		return true, nil
+	}
+	return new > current, nil
+}
+
+func (p *LexicographicPolicy) Name() string     { return p.policy }
+func (p *LexicographicPolicy) Type() PolicyType { return PolicyTypeLexicographic }
@@ -0,0 +1,34 @@
+package policy
+
+import "testing"
+
+func TestLexicographicPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{"lexicographic", "20240117T1030-abc123", "20240118T0900-def456", true},
+		{"lexicographic", "20240118T0900-def456", "20240117T1030-abc123", false},
+		{"lexicographic", "20240117T1030-abc123", "20240117T1030-abc123", false},
+		{"lexicographic:2024*", "latest", "20240117T1030-abc123", true},
+		{"lexicographic:2024*", "20240117T1030-abc123", "latest", false},
+		{"lexicographic:release-*", "release-2024-01-17", "release-2024-02-01", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.current+"->"+tt.new, func(t *testing.T) {
+			p := GetPolicy(tt.policy, &Options{})
+			if p.Type() != PolicyTypeLexicographic {
+				t.Fatalf("unexpected policy type: %v", p.Type())
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("ShouldUpdate(%s, %s) = %v, want %v", tt.current, tt.new, got, tt.want)
+			}
+		})
+	}
+}
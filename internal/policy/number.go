@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NumberPolicy - updates to strictly larger build numbers, tags are plain integers
+// ("number" policy, 1234) or integers with a prefix ("number:build-" policy, build-1234)
+type NumberPolicy struct {
+	policy string
+	prefix string
+}
+
+func NewNumberPolicy(policy string) *NumberPolicy {
+	return &NumberPolicy{
+		policy: policy,
+		prefix: strings.TrimPrefix(strings.TrimPrefix(policy, "number"), ":"),
+	}
+}
+
+func (p *NumberPolicy) parse(tag string) (uint64, bool) {
+	if !strings.HasPrefix(tag, p.prefix) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(tag, p.prefix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// ShouldUpdate - tags without the prefix or with non numeric suffix are ignored, current
+// tag that isn't a build number (for example "latest") is always updated
+func (p *NumberPolicy) ShouldUpdate(current, new string) (bool, error) {
+	newNumber, ok := p.parse(new)
+	if !ok {
+		return false, nil
+	}
+	currentNumber, ok := p.parse(current)
+	if !ok {
+		return true, nil
+	}
+	return newNumber > currentNumber, nil
+}
+
+func (p *NumberPolicy) Name() string     { return p.policy }
+func (p *NumberPolicy) Type() PolicyType { return PolicyTypeNumber }
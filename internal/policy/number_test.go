@@ -0,0 +1,38 @@
+package policy
+
+import "testing"
+
+func TestNumberPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{name: "larger number", policy: "number", current: "1234", new: "1235", want: true},
+		{name: "numeric, not lexicographic", policy: "number", current: "999", new: "1000", want: true},
+		{name: "smaller number", policy: "number", current: "1235", new: "1234", want: false},
+		{name: "same number", policy: "number", current: "1235", new: "1235", want: false},
+		{name: "not a number", policy: "number", current: "1235", new: "1236-debug", want: false},
+		{name: "current not a number", policy: "number", current: "latest", new: "12", want: true},
+		{name: "prefix", policy: "number:build-", current: "build-99", new: "build-100", want: true},
+		{name: "prefix missing", policy: "number:build-", current: "build-99", new: "100", want: false},
+		{name: "other prefix", policy: "number:build-", current: "build-99", new: "nightly-100", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := GetPolicy(tt.policy, &Options{})
+			if p.Type() != PolicyTypeNumber {
+				t.Fatalf("expected number policy, got: %s", p.Name())
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("NumberPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
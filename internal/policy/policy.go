@@ -16,6 +16,13 @@ const (
 	PolicyTypeForce
 	PolicyTypeGlob
 	PolicyTypeRegexp
+	PolicyTypeComposite
+	PolicyTypeNumber
+	PolicyTypeWebhook
+	PolicyTypeRego
+	PolicyTypeCEL
+	PolicyTypeLexicographic
+	PolicyTypeTimestamp
 )
 
 type Policy interface {
@@ -30,30 +37,149 @@ func (np *NilPolicy) ShouldUpdate(c, n string) (bool, error) { return false, nil
 func (np *NilPolicy) Name() string                           { return "nil policy" }
 func (np *NilPolicy) Type() PolicyType                       { return PolicyTypeNone }
 
-// GetPolicyFromLabelsOrAnnotations - gets policy from k8s labels or annotations
+// GetPolicyFromLabelsOrAnnotations - gets policy from k8s labels or annotations.
+// Labels and annotations are merged, annotations take precedence over labels for
+// every setting (policy, match-tag, match-pre-release)
 func GetPolicyFromLabelsOrAnnotations(labels map[string]string, annotations map[string]string) Policy {
+	meta := mergeMeta(labels, annotations)
 
-	policyNameA, ok := getPolicyFromLabels(annotations)
-	if ok {
-		return GetPolicy(policyNameA, &Options{MatchTag: getMatchTag(annotations), MatchPreRelease: getMatchPreRelease(annotations)})
+	policyName, ok := getPolicyFromLabels(meta)
+	if !ok {
+		return &NilPolicy{}
 	}
 
-	policyNameL, ok := getPolicyFromLabels(labels)
+	return GetPolicy(policyName, getOptions(meta))
+}
+
+// mergeMeta - merges labels and annotations into a new map, annotations win
+func mergeMeta(labels map[string]string, annotations map[string]string) map[string]string {
+	meta := make(map[string]string, len(labels)+len(annotations))
+	for k, v := range labels {
+		meta[k] = v
+	}
+	for k, v := range annotations {
+		meta[k] = v
+	}
+	// policy set in annotations (current or legacy key) overrides any policy label
+	if _, ok := annotations[types.KeelPolicyLabel]; ok {
+		delete(meta, "keel.observer/policy")
+	} else if legacy, ok := annotations["keel.observer/policy"]; ok {
+		meta[types.KeelPolicyLabel] = legacy
+	}
+	return meta
+}
+
+// GetContainerPolicyFromLabelsOrAnnotations - gets policy for a specific container. Container
+// specific settings (keel.sh/policy.<container name>, keel.sh/matchTag.<container name>, etc.)
+// override resource wide settings
+func GetContainerPolicyFromLabelsOrAnnotations(containerName string, labels map[string]string, annotations map[string]string) Policy {
+	meta := mergeMeta(labels, annotations)
+
+	overrides := make(map[string]string)
+	suffix := "." + containerName
+	for k, v := range meta {
+		if strings.HasPrefix(k, "keel.sh/") && strings.HasSuffix(k, suffix) {
+			overrides[strings.TrimSuffix(k, suffix)] = v
+		}
+	}
+	if _, ok := overrides[types.KeelPolicyLabel]; ok {
+		delete(meta, "keel.observer/policy")
+	}
+	for k, v := range overrides {
+		meta[k] = v
+	}
+	if legacy, ok := overrides[types.KeelForceTagMatchLegacyLabel]; ok {
+		if _, ok := overrides[types.KeelForceTagMatchLabel]; !ok {
+			meta[types.KeelForceTagMatchLabel] = legacy
+		}
+	}
+
+	policyName, ok := getPolicyFromLabels(meta)
 	if !ok {
 		return &NilPolicy{}
 	}
 
-	return GetPolicy(policyNameL, &Options{MatchTag: getMatchTag(labels), MatchPreRelease: getMatchPreRelease(labels)})
+	return GetPolicy(policyName, getOptions(meta))
+}
+
+// HasContainerPolicies - checks whether any container specific policy is set
+func HasContainerPolicies(labels map[string]string, annotations map[string]string) bool {
+	for _, meta := range []map[string]string{annotations, labels} {
+		for k := range meta {
+			if strings.HasPrefix(k, types.KeelPolicyLabel+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Ordered - checks whether policy orders tags that aren't semver versions (build numbers,
+// timestamps, sortable strings, versions captured by glob or regexp patterns), the best
+// tag for such policies is picked from all tags of the repository
+func Ordered(p Policy) bool {
+	switch plc := p.(type) {
+	case *NumberPolicy, *LexicographicPolicy, *TimestampPolicy:
+		return true
+	case *GlobPolicy:
+		return plc.version != nil
+	case *RegexpPolicy:
+		return len(plc.groups) > 0
+	case *TagFilterPolicy:
+		return Ordered(plc.policy)
+	}
+	return false
 }
 
 // Options - additional options when parsing policy
 type Options struct {
 	MatchTag        bool
 	MatchPreRelease bool
+	// AllowedTags and BlockedTags - glob or "regexp:" patterns evaluated before the policy
+	AllowedTags []string
+	BlockedTags []string
 }
 
 // GetPolicy - policy getter used by Helm config
 func GetPolicy(policyName string, options *Options) Policy {
+	var p Policy
+	if isCompositePolicy(policyName) {
+		composite, err := NewCompositePolicy(policyName, options)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse composite policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		p = composite
+	} else {
+		p = getPolicy(policyName, options)
+	}
+
+	if p.Type() == PolicyTypeNone || (len(options.AllowedTags) == 0 && len(options.BlockedTags) == 0) {
+		return p
+	}
+
+	filtered, err := NewTagFilterPolicy(p, options.AllowedTags, options.BlockedTags)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"policy": policyName,
+		}).Error("failed to parse allowed or blocked tags, check your deployment configuration")
+		return &NilPolicy{}
+	}
+	return filtered
+}
+
+func getPolicy(policyName string, options *Options) Policy {
+	if strings.HasSuffix(policyName, allowDowngradeSuffix) {
+		level := strings.TrimPrefix(strings.TrimSuffix(policyName, allowDowngradeSuffix), "semver:")
+		if p, ok := ParseSemverPolicy(level, options.MatchPreRelease).(*SemverPolicy); ok {
+			p.allowDowngrade = true
+			return p
+		}
+	}
 
 	switch {
 	case strings.HasPrefix(policyName, "glob:"):
@@ -76,6 +202,60 @@ func GetPolicy(policyName string, options *Options) Policy {
 			return &NilPolicy{}
 		}
 		return p
+	case strings.HasPrefix(policyName, "webhook:"):
+		p, err := NewWebhookPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse webhook policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "rego:"):
+		p, err := NewRegoPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse rego policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "cel:"):
+		p, err := NewCELPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse cel policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "number:"):
+		return NewNumberPolicy(policyName)
+	case strings.HasPrefix(policyName, "lexicographic:"):
+		return NewLexicographicPolicy(policyName)
+	case strings.HasPrefix(policyName, "timestamp:"):
+		p, err := NewTimestampPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse timestamp policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
+	case strings.HasPrefix(policyName, "semver:"):
+		p, err := NewSemverConstraintPolicy(policyName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"policy": policyName,
+			}).Error("failed to parse semver policy, check your deployment configuration")
+			return &NilPolicy{}
+		}
+		return p
 	}
 
 	switch policyName {
@@ -83,6 +263,10 @@ func GetPolicy(policyName string, options *Options) Policy {
 		return ParseSemverPolicy(policyName, options.MatchPreRelease)
 	case "force":
 		return NewForcePolicy(options.MatchTag)
+	case "number":
+		return NewNumberPolicy(policyName)
+	case "lexicographic":
+		return NewLexicographicPolicy(policyName)
 	case "", "never":
 		return &NilPolicy{}
 	}
@@ -119,6 +303,15 @@ func getPolicyFromLabels(labels map[string]string) (string, bool) {
 	return legacy, ok
 }
 
+func getOptions(meta map[string]string) *Options {
+	return &Options{
+		MatchTag:        getMatchTag(meta),
+		MatchPreRelease: getMatchPreRelease(meta),
+		AllowedTags:     getTagPatterns(meta, types.KeelAllowedTagsAnnotation),
+		BlockedTags:     getTagPatterns(meta, types.KeelBlockedTagsAnnotation),
+	}
+}
+
 func getMatchTag(labels map[string]string) bool {
 	mt, ok := labels[types.KeelForceTagMatchLabel]
 	if ok {
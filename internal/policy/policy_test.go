@@ -113,13 +113,43 @@ func TestGetPolicyFromLabelsOrAnnotations(t *testing.T) {
 		{
 			name: "annotations overrides labels",
 			args: args{
-				// The "annotations overrides labels" can be quite mis-leading for end-users (here the default value of MatchPreRelease)
-				// is taken from the annotations section, along with the policy...
-				// Shouldn't we rather merge both labels and annotations, with priority given to annotation (and a warning)?
+				// labels and annotations are merged, annotations take precedence
 				labels:      map[string]string{types.KeelPolicyLabel: "patch", types.KeelMatchPreReleaseAnnotation: "false"},
 				annotations: map[string]string{types.KeelPolicyLabel: "all"},
 			},
-			want: NewSemverPolicy(SemverPolicyTypeAll, true),
+			want: NewSemverPolicy(SemverPolicyTypeAll, false),
+		},
+		{
+			name: "annotation matchPreRelease overrides label",
+			args: args{
+				labels:      map[string]string{types.KeelPolicyLabel: "patch", types.KeelMatchPreReleaseAnnotation: "false"},
+				annotations: map[string]string{types.KeelMatchPreReleaseAnnotation: "true"},
+			},
+			want: NewSemverPolicy(SemverPolicyTypePatch, true),
+		},
+		{
+			name: "annotation policy overrides legacy label",
+			args: args{
+				labels:      map[string]string{"keel.observer/policy": "patch"},
+				annotations: map[string]string{types.KeelPolicyLabel: "major"},
+			},
+			want: NewSemverPolicy(SemverPolicyTypeMajor, true),
+		},
+		{
+			name: "legacy annotation policy overrides label",
+			args: args{
+				labels:      map[string]string{types.KeelPolicyLabel: "patch"},
+				annotations: map[string]string{"keel.observer/policy": "major"},
+			},
+			want: NewSemverPolicy(SemverPolicyTypeMajor, true),
+		},
+		{
+			name: "force policy with match tag annotation",
+			args: args{
+				labels:      map[string]string{types.KeelPolicyLabel: "force"},
+				annotations: map[string]string{types.KeelForceTagMatchLabel: "true"},
+			},
+			want: NewForcePolicy(true),
 		},
 		{
 			name: "label matchPreRelease set to false",
@@ -146,3 +176,127 @@ func TestGetPolicyFromLabelsOrAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestGetContainerPolicyFromLabelsOrAnnotations(t *testing.T) {
+	type args struct {
+		container   string
+		labels      map[string]string
+		annotations map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want Policy
+	}{
+		{
+			name: "resource policy",
+			args: args{
+				container:   "app",
+				labels:      map[string]string{types.KeelPolicyLabel: "minor"},
+				annotations: map[string]string{types.KeelPolicyLabel + ".sidecar": "force"},
+			},
+			want: NewSemverPolicy(SemverPolicyTypeMinor, true),
+		},
+		{
+			name: "container policy",
+			args: args{
+				container:   "sidecar",
+				labels:      map[string]string{types.KeelPolicyLabel: "minor"},
+				annotations: map[string]string{types.KeelPolicyLabel + ".sidecar": "force"},
+			},
+			want: NewForcePolicy(false),
+		},
+		{
+			name: "container match tag",
+			args: args{
+				container: "sidecar",
+				annotations: map[string]string{
+					types.KeelPolicyLabel:                     "force",
+					types.KeelForceTagMatchLabel:              "false",
+					types.KeelForceTagMatchLabel + ".sidecar": "true",
+				},
+			},
+			want: NewForcePolicy(true),
+		},
+		{
+			name: "container legacy match tag",
+			args: args{
+				container: "sidecar",
+				annotations: map[string]string{
+					types.KeelPolicyLabel + ".sidecar":              "force",
+					types.KeelForceTagMatchLabel:                    "true",
+					types.KeelForceTagMatchLegacyLabel + ".sidecar": "false",
+				},
+			},
+			want: NewForcePolicy(false),
+		},
+		{
+			name: "container without policy",
+			args: args{
+				container:   "app",
+				annotations: map[string]string{types.KeelPolicyLabel + ".sidecar": "force"},
+			},
+			want: &NilPolicy{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetContainerPolicyFromLabelsOrAnnotations(tt.args.container, tt.args.labels, tt.args.annotations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetContainerPolicyFromLabelsOrAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesTag(t *testing.T) {
+	filtered, err := NewTagFilterPolicy(NewForcePolicy(true), nil, []string{"*-debug"})
+	if err != nil {
+		t.Fatalf("failed to create policy: %s", err)
+	}
+
+	tests := []struct {
+		policy Policy
+		want   bool
+	}{
+		{NewForcePolicy(true), true},
+		{NewForcePolicy(false), false},
+		{filtered, true},
+		{NewSemverPolicy(SemverPolicyTypeMajor, true), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := MatchesTag(tt.policy); got != tt.want {
+			t.Errorf("MatchesTag(%v) = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	filtered, err := NewTagFilterPolicy(NewNumberPolicy("number"), nil, []string{"*-debug"})
+	if err != nil {
+		t.Fatalf("failed to create policy: %s", err)
+	}
+
+	tests := []struct {
+		policy string
+		want   bool
+	}{
+		{"number:build-", true},
+		{"lexicographic:2024*", true},
+		{"timestamp:20060102T1504", true},
+		{"glob:app-{semver}", true},
+		{"glob:app-*", false},
+		{`regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, true},
+		{"regexp:^build-", false},
+		{"major", false},
+		{"force", false},
+	}
+	for _, tt := range tests {
+		if got := Ordered(GetPolicy(tt.policy, &Options{})); got != tt.want {
+			t.Errorf("Ordered(%s) = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+	if !Ordered(filtered) {
+		t.Errorf("expected filtered number policy to be ordered")
+	}
+}
@@ -9,19 +9,33 @@ import (
 
 var (
 	_PolicyTypeNameToValue = map[string]PolicyType{
-		"PolicyTypeNone":   PolicyTypeNone,
-		"PolicyTypeSemver": PolicyTypeSemver,
-		"PolicyTypeForce":  PolicyTypeForce,
-		"PolicyTypeGlob":   PolicyTypeGlob,
-		"PolicyTypeRegexp": PolicyTypeRegexp,
+		"PolicyTypeNone":          PolicyTypeNone,
+		"PolicyTypeSemver":        PolicyTypeSemver,
+		"PolicyTypeForce":         PolicyTypeForce,
+		"PolicyTypeGlob":          PolicyTypeGlob,
+		"PolicyTypeRegexp":        PolicyTypeRegexp,
+		"PolicyTypeComposite":     PolicyTypeComposite,
+		"PolicyTypeNumber":        PolicyTypeNumber,
+		"PolicyTypeWebhook":       PolicyTypeWebhook,
+		"PolicyTypeRego":          PolicyTypeRego,
+		"PolicyTypeCEL":           PolicyTypeCEL,
+		"PolicyTypeLexicographic": PolicyTypeLexicographic,
+		"PolicyTypeTimestamp":     PolicyTypeTimestamp,
 	}
 
 	_PolicyTypeValueToName = map[PolicyType]string{
-		PolicyTypeNone:   "PolicyTypeNone",
-		PolicyTypeSemver: "PolicyTypeSemver",
-		PolicyTypeForce:  "PolicyTypeForce",
-		PolicyTypeGlob:   "PolicyTypeGlob",
-		PolicyTypeRegexp: "PolicyTypeRegexp",
+		PolicyTypeNone:          "PolicyTypeNone",
+		PolicyTypeSemver:        "PolicyTypeSemver",
+		PolicyTypeForce:         "PolicyTypeForce",
+		PolicyTypeGlob:          "PolicyTypeGlob",
+		PolicyTypeRegexp:        "PolicyTypeRegexp",
+		PolicyTypeComposite:     "PolicyTypeComposite",
+		PolicyTypeNumber:        "PolicyTypeNumber",
+		PolicyTypeWebhook:       "PolicyTypeWebhook",
+		PolicyTypeRego:          "PolicyTypeRego",
+		PolicyTypeCEL:           "PolicyTypeCEL",
+		PolicyTypeLexicographic: "PolicyTypeLexicographic",
+		PolicyTypeTimestamp:     "PolicyTypeTimestamp",
 	}
 )
 
@@ -29,11 +43,18 @@ func init() {
 	var v PolicyType
 	if _, ok := interface{}(v).(fmt.Stringer); ok {
 		_PolicyTypeNameToValue = map[string]PolicyType{
-			interface{}(PolicyTypeNone).(fmt.Stringer).String():   PolicyTypeNone,
-			interface{}(PolicyTypeSemver).(fmt.Stringer).String(): PolicyTypeSemver,
-			interface{}(PolicyTypeForce).(fmt.Stringer).String():  PolicyTypeForce,
-			interface{}(PolicyTypeGlob).(fmt.Stringer).String():   PolicyTypeGlob,
-			interface{}(PolicyTypeRegexp).(fmt.Stringer).String(): PolicyTypeRegexp,
+			interface{}(PolicyTypeNone).(fmt.Stringer).String():          PolicyTypeNone,
+			interface{}(PolicyTypeSemver).(fmt.Stringer).String():        PolicyTypeSemver,
+			interface{}(PolicyTypeForce).(fmt.Stringer).String():         PolicyTypeForce,
+			interface{}(PolicyTypeGlob).(fmt.Stringer).String():          PolicyTypeGlob,
+			interface{}(PolicyTypeRegexp).(fmt.Stringer).String():        PolicyTypeRegexp,
+			interface{}(PolicyTypeComposite).(fmt.Stringer).String():     PolicyTypeComposite,
+			interface{}(PolicyTypeNumber).(fmt.Stringer).String():        PolicyTypeNumber,
+			interface{}(PolicyTypeWebhook).(fmt.Stringer).String():       PolicyTypeWebhook,
+			interface{}(PolicyTypeRego).(fmt.Stringer).String():          PolicyTypeRego,
+			interface{}(PolicyTypeCEL).(fmt.Stringer).String():           PolicyTypeCEL,
+			interface{}(PolicyTypeLexicographic).(fmt.Stringer).String(): PolicyTypeLexicographic,
+			interface{}(PolicyTypeTimestamp).(fmt.Stringer).String():     PolicyTypeTimestamp,
 		}
 	}
 }
@@ -3,13 +3,18 @@ package policy
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-// RegexpPolicy - regular expression based pattern
+// RegexpPolicy - regular expression based pattern. If the pattern has named capture
+// groups, for example "regexp:^(?P<major>\d+)\.(?P<build>\d+)$", tags are also
+// compared group by group numerically (in the order groups appear in the pattern) and
+// only higher versions are accepted
 type RegexpPolicy struct {
 	policy string
 	regexp *regexp.Regexp
+	groups []int
 }
 
 func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
@@ -22,9 +27,17 @@ func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
 				return nil, fmt.Errorf("failed to parse regexp pattern, error: %s", err)
 			}
 
+			var groups []int
+			for idx, name := range rx.SubexpNames() {
+				if name != "" {
+					groups = append(groups, idx)
+				}
+			}
+
 			return &RegexpPolicy{
 				regexp: rx,
 				policy: policy,
+				groups: groups,
 			}, nil
 		}
 	}
@@ -33,7 +46,36 @@ func NewRegexpPolicy(policy string) (*RegexpPolicy, error) {
 }
 
 func (p *RegexpPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return p.regexp.MatchString(new), nil
+	newMatch := p.regexp.FindStringSubmatch(new)
+	if newMatch == nil {
+		return false, nil
+	}
+	if len(p.groups) == 0 {
+		return true, nil
+	}
+
+	currentMatch := p.regexp.FindStringSubmatch(current)
+	if currentMatch == nil {
+		// current tag doesn't follow the scheme, nothing to compare against
+		return true, nil
+	}
+
+	names := p.regexp.SubexpNames()
+	for _, idx := range p.groups {
+		newValue, err := strconv.ParseUint(newMatch[idx], 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse capture group '%s' of new version '%s': %s", names[idx], new, err)
+		}
+		currentValue, err := strconv.ParseUint(currentMatch[idx], 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse capture group '%s' of current version '%s': %s", names[idx], current, err)
+		}
+		if newValue != currentValue {
+			return newValue > currentValue, nil
+		}
+	}
+
+	return false, nil
 }
 
 func (p *RegexpPolicy) Name() string     { return p.policy }
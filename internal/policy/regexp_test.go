@@ -0,0 +1,39 @@
+package policy
+
+import "testing"
+
+func TestRegexpPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+		wantErr bool
+	}{
+		{name: "match without groups", policy: `regexp:^release-\d+$`, current: "release-5", new: "release-3", want: true},
+		{name: "no match", policy: `regexp:^release-\d+$`, current: "release-5", new: "dev-6", want: false},
+		{name: "higher build", policy: `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, current: "4.9", new: "4.10", want: true},
+		{name: "lower build", policy: `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, current: "4.10", new: "4.9", want: false},
+		{name: "higher major lower build", policy: `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, current: "4.10", new: "5.1", want: true},
+		{name: "same version", policy: `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, current: "4.10", new: "4.10", want: false},
+		{name: "current doesn't match", policy: `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`, current: "latest", new: "4.10", want: true},
+		{name: "non numeric group", policy: `regexp:^(?P<major>\d+)-(?P<name>[a-z]+)$`, current: "1-abc", new: "1-abd", want: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewRegexpPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to create policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RegexpPolicy.ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RegexpPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// regoPolicyTimeout - how long a rule has to be evaluated
+var regoPolicyTimeout = 10 * time.Second
+
+var regoRuleRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)*$`)
+
+// regoModules - compiled Rego modules shared by all rego policies, queries are
+// prepared once per rule and dropped when modules are reloaded
+var regoModules = struct {
+	sync.RWMutex
+	compiler *ast.Compiler
+	queries  map[string]rego.PreparedEvalQuery
+}{
+	queries: make(map[string]rego.PreparedEvalQuery),
+}
+
+// LoadRegoModules - compiles Rego modules (file name -> source) and replaces
+// previously loaded ones, on compilation error modules in use are kept
+func LoadRegoModules(modules map[string]string) error {
+	var compiler *ast.Compiler
+	if len(modules) > 0 {
+		var err error
+		compiler, err = ast.CompileModules(modules)
+		if err != nil {
+			return err
+		}
+	}
+
+	regoModules.Lock()
+	regoModules.compiler = compiler
+	regoModules.queries = make(map[string]rego.PreparedEvalQuery)
+	regoModules.Unlock()
+	return nil
+}
+
+// regoQuery - returns prepared query for the rule, ok is false when no modules
+// are loaded
+func regoQuery(ctx context.Context, rule string) (query rego.PreparedEvalQuery, ok bool, err error) {
+	regoModules.RLock()
+	query, ok = regoModules.queries[rule]
+	compiler := regoModules.compiler
+	regoModules.RUnlock()
+	if ok || compiler == nil {
+		return query, ok, nil
+	}
+
+	query, err = rego.New(
+		rego.Query("data."+rule),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return query, false, err
+	}
+
+	regoModules.Lock()
+	// modules could have been reloaded in the meantime, cache only current ones
+	if regoModules.compiler == compiler {
+		regoModules.queries[rule] = query
+	}
+	regoModules.Unlock()
+	return query, true, nil
+}
+
+// RegoPolicy - evaluates Rego rule ("rego:keel.allow" policy) with embedded Open Policy
+// Agent, modules are loaded from labelled ConfigMaps in keel namespace (see LoadRegoModules).
+// Rule gets tags and update context as input and has to evaluate to true for the update
+// to be allowed
+type RegoPolicy struct {
+	policy string
+	rule   string
+}
+
+func NewRegoPolicy(policy string) (*RegoPolicy, error) {
+	rule := strings.TrimPrefix(policy, "rego:")
+	if !regoRuleRegexp.MatchString(rule) {
+		return nil, fmt.Errorf("invalid rego policy: %s", policy)
+	}
+
+	return &RegoPolicy{
+		policy: policy,
+		rule:   rule,
+	}, nil
+}
+
+func (p *RegoPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateContext(nil, current, new)
+}
+
+// ShouldUpdateContext - evaluates the rule, undefined result denies the update
+func (p *RegoPolicy) ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error) {
+	input, err := regoInput(&policyInput{
+		Policy:        p.policy,
+		CurrentTag:    current,
+		NewTag:        new,
+		UpdateContext: ctx,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	evalCtx, cancel := context.WithTimeout(context.Background(), regoPolicyTimeout)
+	defer cancel()
+
+	query, ok, err := regoQuery(evalCtx, p.rule)
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare rego query: %s", err)
+	}
+	if !ok {
+		log.WithFields(log.Fields{
+			"policy": p.policy,
+		}).Warn("policy.rego: no rego modules loaded, denying update")
+		return false, nil
+	}
+
+	rs, err := query.Eval(evalCtx, rego.EvalInput(input))
+	if err != nil {
+		return false, fmt.Errorf("rego policy evaluation failed: %s", err)
+	}
+
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		log.WithFields(log.Fields{
+			"policy": p.policy,
+		}).Warn("policy.rego: rule is undefined, denying update")
+		return false, nil
+	}
+
+	allowed, ok := rs[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("rego rule %s evaluated to %v, expected boolean", p.rule, rs[0].Expressions[0].Value)
+	}
+	return allowed, nil
+}
+
+// regoInput - converts input to plain JSON values so rules see the same
+// document as webhook policies
+func regoInput(in *policyInput) (interface{}, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	err = json.Unmarshal(body, &input)
+	return input, err
+}
+
+func (p *RegoPolicy) Name() string     { return p.policy }
+func (p *RegoPolicy) Type() PolicyType { return PolicyTypeRego }
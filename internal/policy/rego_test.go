@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"testing"
+)
+
+const testRegoModule = `package keel
+
+default allow = false
+
+allow {
+	input.namespace != "prod"
+	input.currentTag == "1.0.0"
+}
+`
+
+func TestRegoPolicy_ShouldUpdate(t *testing.T) {
+	if err := LoadRegoModules(map[string]string{"keel.rego": testRegoModule}); err != nil {
+		t.Fatalf("failed to load modules: %s", err)
+	}
+	defer LoadRegoModules(nil)
+
+	p := GetPolicy("rego:keel.allow", &Options{})
+	if p.Type() != PolicyTypeRego {
+		t.Fatalf("expected rego policy, got: %s", p.Name())
+	}
+
+	allowed, err := ShouldUpdate(p, &UpdateContext{Namespace: "staging"}, "1.0.0", "1.1.0")
+	if err != nil || !allowed {
+		t.Errorf("expected update to be allowed, got: %v, %v", allowed, err)
+	}
+
+	allowed, err = ShouldUpdate(p, &UpdateContext{Namespace: "prod"}, "1.0.0", "1.1.0")
+	if err != nil || allowed {
+		t.Errorf("expected update to be denied, got: %v, %v", allowed, err)
+	}
+
+	allowed, err = GetPolicy("rego:keel.missing", &Options{}).ShouldUpdate("1.0.0", "1.1.0")
+	if err != nil || allowed {
+		t.Errorf("expected undefined rule to deny update, got: %v, %v", allowed, err)
+	}
+
+	// reloaded modules replace prepared queries
+	if err := LoadRegoModules(map[string]string{"keel.rego": "package keel\n\nallow = true\n"}); err != nil {
+		t.Fatalf("failed to load modules: %s", err)
+	}
+	allowed, err = ShouldUpdate(p, &UpdateContext{Namespace: "prod"}, "1.0.0", "1.1.0")
+	if err != nil || !allowed {
+		t.Errorf("expected update to be allowed after reload, got: %v, %v", allowed, err)
+	}
+}
+
+func TestRegoPolicy_NoModules(t *testing.T) {
+	LoadRegoModules(nil)
+	allowed, err := GetPolicy("rego:keel.allow", &Options{}).ShouldUpdate("1.0.0", "1.1.0")
+	if err != nil || allowed {
+		t.Errorf("expected update to be denied without modules, got: %v, %v", allowed, err)
+	}
+}
+
+func TestRegoPolicy_NotBoolean(t *testing.T) {
+	if err := LoadRegoModules(map[string]string{"keel.rego": "package keel\n\nallow = \"yes\"\n"}); err != nil {
+		t.Fatalf("failed to load modules: %s", err)
+	}
+	defer LoadRegoModules(nil)
+
+	if _, err := GetPolicy("rego:keel.allow", &Options{}).ShouldUpdate("1.0.0", "1.1.0"); err == nil {
+		t.Errorf("expected error for non boolean result")
+	}
+}
+
+func TestLoadRegoModulesInvalid(t *testing.T) {
+	if err := LoadRegoModules(map[string]string{"keel.rego": "package keel\n\nallow {"}); err == nil {
+		t.Errorf("expected compilation error")
+	}
+}
+
+func TestNewRegoPolicyInvalid(t *testing.T) {
+	for _, policy := range []string{"rego:", "rego:keel/allow", "rego:keel..allow"} {
+		if _, err := NewRegoPolicy(policy); err == nil {
+			t.Errorf("expected error for '%s'", policy)
+		}
+	}
+}
@@ -48,16 +48,28 @@ func NewSemverPolicy(spt SemverPolicyType, matchPreRelease bool) *SemverPolicy {
 	}
 }
 
+// allowDowngradeSuffix - semver policies with this suffix (for example "patch:allow-downgrade")
+// also follow lower versions, so re-tagged rollbacks are applied
+const allowDowngradeSuffix = ":allow-downgrade"
+
 type SemverPolicy struct {
 	spt             SemverPolicyType
 	matchPreRelease bool
+	allowDowngrade  bool
 }
 
 func (sp *SemverPolicy) ShouldUpdate(current, new string) (bool, error) {
-	return shouldUpdate(sp.spt, sp.matchPreRelease, current, new)
+	update, err := shouldUpdate(sp.spt, sp.matchPreRelease, current, new)
+	if err != nil || update || !sp.allowDowngrade {
+		return update, err
+	}
+	return shouldDowngrade(sp.spt, sp.matchPreRelease, current, new), nil
 }
 
 func (sp *SemverPolicy) Name() string {
+	if sp.allowDowngrade {
+		return sp.spt.String() + allowDowngradeSuffix
+	}
 	return sp.spt.String()
 }
 
@@ -105,3 +117,34 @@ func shouldUpdate(spt SemverPolicyType, matchPreRelease bool, current, new strin
 	}
 	return false, nil
 }
+
+// shouldDowngrade - checks whether new version is lower than current one and the
+// change stays within policy bounds, same as upgrades
+func shouldDowngrade(spt SemverPolicyType, matchPreRelease bool, current, new string) bool {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	newVersion, err := semver.NewVersion(new)
+	if err != nil {
+		return false
+	}
+
+	if currentVersion.Prerelease() != newVersion.Prerelease() && spt != SemverPolicyTypeAll && matchPreRelease {
+		return false
+	}
+
+	if !newVersion.LessThan(currentVersion) {
+		return false
+	}
+
+	switch spt {
+	case SemverPolicyTypeAll, SemverPolicyTypeMajor:
+		return true
+	case SemverPolicyTypeMinor:
+		return newVersion.Major() == currentVersion.Major()
+	case SemverPolicyTypePatch:
+		return newVersion.Major() == currentVersion.Major() && newVersion.Minor() == currentVersion.Minor()
+	}
+	return false
+}
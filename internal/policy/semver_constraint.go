@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// SemverConstraintPolicy - updates to higher versions that satisfy semver constraint
+// expression, for example "semver:>=1.2.0 <2.0.0 || ~3.1.x"
+type SemverConstraintPolicy struct {
+	policy      string
+	constraints *semver.Constraints
+}
+
+// NewSemverConstraintPolicy - parses "semver:<constraints>" policy
+func NewSemverConstraintPolicy(policy string) (*SemverConstraintPolicy, error) {
+	expression := strings.TrimSpace(strings.TrimPrefix(policy, "semver:"))
+	if expression == "" {
+		return nil, fmt.Errorf("invalid semver policy: %s", policy)
+	}
+
+	constraints, err := semver.NewConstraint(normalizeConstraint(expression))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse semver constraint, error: %s", err)
+	}
+
+	return &SemverConstraintPolicy{
+		policy:      policy,
+		constraints: constraints,
+	}, nil
+}
+
+// normalizeConstraint - semver library only accepts commas between AND-ed constraints,
+// space separated constraints (">=1.2.0 <2.0.0") are rewritten while operators
+// followed by a space (">= 1.2.0") and hyphen ranges ("1.2 - 1.4") are kept together
+func normalizeConstraint(expression string) string {
+	ors := strings.Split(expression, "||")
+	for idx, or := range ors {
+		var (
+			constraints []string
+			operator    string
+			hyphen      bool
+		)
+		for _, token := range strings.FieldsFunc(or, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			switch {
+			case token == "-" && len(constraints) > 0:
+				hyphen = true
+			case hyphen:
+				constraints[len(constraints)-1] += " - " + token
+				hyphen = false
+			case strings.Trim(token, "<>=!~^") == "":
+				operator += token
+			default:
+				constraints = append(constraints, operator+token)
+				operator = ""
+			}
+		}
+		ors[idx] = strings.Join(constraints, ", ")
+	}
+	return strings.Join(ors, " || ")
+}
+
+// ShouldUpdate - new version has to satisfy constraints and be higher than current
+func (p *SemverConstraintPolicy) ShouldUpdate(current, new string) (bool, error) {
+	newVersion, err := semver.NewVersion(new)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse new version: %s", err)
+	}
+	if !p.constraints.Check(newVersion) {
+		return false, nil
+	}
+
+	if current == "latest" {
+		return true, nil
+	}
+
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse current version: %s", err)
+	}
+
+	return currentVersion.LessThan(newVersion), nil
+}
+
+func (p *SemverConstraintPolicy) Name() string     { return p.policy }
+func (p *SemverConstraintPolicy) Type() PolicyType { return PolicyTypeSemver }
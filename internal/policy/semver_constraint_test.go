@@ -0,0 +1,72 @@
+package policy
+
+import "testing"
+
+func TestNormalizeConstraint(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: ">=1.2.0 <2.0.0", want: ">=1.2.0, <2.0.0"},
+		{expression: ">=1.2.0 <2.0.0 || ~3.1.x", want: ">=1.2.0, <2.0.0 || ~3.1.x"},
+		{expression: ">= 1.2.0, < 2.0.0", want: ">=1.2.0, <2.0.0"},
+		{expression: "1.2 - 1.4.5", want: "1.2 - 1.4.5"},
+		{expression: "^1.2", want: "^1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			if got := normalizeConstraint(tt.expression); got != tt.want {
+				t.Errorf("normalizeConstraint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemverConstraintPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		current string
+		new     string
+		want    bool
+		wantErr bool
+	}{
+		{name: "within range", policy: "semver:>=1.2.0 <2.0.0 || ~3.1.x", current: "1.2.0", new: "1.9.3", want: true},
+		{name: "above range", policy: "semver:>=1.2.0 <2.0.0 || ~3.1.x", current: "1.2.0", new: "2.0.0", want: false},
+		{name: "second range", policy: "semver:>=1.2.0 <2.0.0 || ~3.1.x", current: "1.9.0", new: "3.1.4", want: true},
+		{name: "outside tilde range", policy: "semver:>=1.2.0 <2.0.0 || ~3.1.x", current: "1.9.0", new: "3.2.0", want: false},
+		{name: "downgrade", policy: "semver:>=1.2.0 <2.0.0", current: "1.5.0", new: "1.3.0", want: false},
+		{name: "latest", policy: "semver:^1.2", current: "latest", new: "1.4.0", want: true},
+		{name: "v prefix", policy: "semver:~1.2", current: "v1.2.0", new: "v1.2.7", want: true},
+		{name: "invalid new", policy: "semver:^1.2", current: "1.2.0", new: "alpha", want: false, wantErr: true},
+		{name: "invalid current", policy: "semver:^1.2", current: "alpha", new: "1.2.1", want: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewSemverConstraintPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("failed to create policy: %s", err)
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SemverConstraintPolicy.ShouldUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SemverConstraintPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSemverConstraintPolicy(t *testing.T) {
+	if _, ok := GetPolicy("semver:>=1.2.0 <2.0.0", &Options{}).(*SemverConstraintPolicy); !ok {
+		t.Errorf("expected semver constraint policy")
+	}
+	if _, ok := GetPolicy("semver:>=>1.2", &Options{}).(*NilPolicy); !ok {
+		t.Errorf("expected nil policy for invalid constraint")
+	}
+	if _, err := NewSemverConstraintPolicy("semver:"); err == nil {
+		t.Errorf("expected error for empty constraint")
+	}
+}
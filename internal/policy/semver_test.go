@@ -227,3 +227,35 @@ func Test_shouldUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestSemverPolicyAllowDowngrade(t *testing.T) {
+	tests := []struct {
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{"patch:allow-downgrade", "1.4.5", "1.4.3", true},
+		{"semver:patch:allow-downgrade", "1.4.5", "1.4.3", true},
+		{"patch:allow-downgrade", "1.4.5", "1.3.9", false},
+		{"patch:allow-downgrade", "1.4.5", "1.4.6", true},
+		{"patch:allow-downgrade", "1.4.5", "1.4.5", false},
+		{"patch:allow-downgrade", "1.4.5", "1.4.3-rc1", false},
+		{"minor:allow-downgrade", "1.4.5", "1.2.0", true},
+		{"minor:allow-downgrade", "2.0.0", "1.9.0", false},
+		{"all:allow-downgrade", "2.0.0", "1.0.0", true},
+		{"patch", "1.4.5", "1.4.3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.current+"->"+tt.new, func(t *testing.T) {
+			p := GetPolicy(tt.policy, &Options{MatchPreRelease: true})
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("%s.ShouldUpdate(%s, %s) = %v, want %v", p.Name(), tt.current, tt.new, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ryanuber/go-glob"
+)
+
+// TagFilterPolicy - rejects blocked tags and tags that aren't allowed before the wrapped
+// policy is evaluated, see keel.sh/allowed-tags and keel.sh/blocked-tags
+type TagFilterPolicy struct {
+	policy  Policy
+	allowed []tagPattern
+	blocked []tagPattern
+}
+
+// tagPattern - glob pattern ("*-debug", optionally prefixed with "glob:") or regular
+// expression prefixed with "regexp:"
+type tagPattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func (p tagPattern) match(tag string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(tag)
+	}
+	return glob.Glob(p.glob, tag)
+}
+
+func parseTagPatterns(patterns []string) ([]tagPattern, error) {
+	var parsed []tagPattern
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+			continue
+		case strings.HasPrefix(pattern, "regexp:"):
+			rx, err := regexp.Compile(strings.TrimPrefix(pattern, "regexp:"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse tag pattern '%s', error: %s", pattern, err)
+			}
+			parsed = append(parsed, tagPattern{regexp: rx})
+		default:
+			parsed = append(parsed, tagPattern{glob: strings.TrimPrefix(pattern, "glob:")})
+		}
+	}
+	return parsed, nil
+}
+
+func matchAnyTag(patterns []tagPattern, tag string) bool {
+	for _, p := range patterns {
+		if p.match(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTagFilterPolicy - wraps policy with allowed and blocked tag patterns
+func NewTagFilterPolicy(policy Policy, allowed, blocked []string) (*TagFilterPolicy, error) {
+	allowedPatterns, err := parseTagPatterns(allowed)
+	if err != nil {
+		return nil, err
+	}
+	blockedPatterns, err := parseTagPatterns(blocked)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TagFilterPolicy{
+		policy:  policy,
+		allowed: allowedPatterns,
+		blocked: blockedPatterns,
+	}, nil
+}
+
+func (p *TagFilterPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateContext(nil, current, new)
+}
+
+// ShouldUpdateContext - blocked tags always lose, when allowed tags are set new tag has
+// to match one of them
+func (p *TagFilterPolicy) ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error) {
+	if matchAnyTag(p.blocked, new) {
+		return false, nil
+	}
+	if len(p.allowed) > 0 && !matchAnyTag(p.allowed, new) {
+		return false, nil
+	}
+	return ShouldUpdate(p.policy, ctx, current, new)
+}
+
+func (p *TagFilterPolicy) Name() string     { return p.policy.Name() }
+func (p *TagFilterPolicy) Type() PolicyType { return p.policy.Type() }
+
+// getTagPatterns - comma separated tag patterns from labels or annotations
+func getTagPatterns(labels map[string]string, key string) []string {
+	value, ok := labels[key]
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
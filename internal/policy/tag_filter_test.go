@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+)
+
+func TestTagFilterPolicy_ShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		current     string
+		new         string
+		want        bool
+	}{
+		{
+			name:        "blocked glob",
+			annotations: map[string]string{types.KeelPolicyLabel: "all", types.KeelBlockedTagsAnnotation: "*-debug, *-dirty"},
+			current:     "1.0.0",
+			new:         "1.1.0-debug",
+			want:        false,
+		},
+		{
+			name:        "not blocked",
+			annotations: map[string]string{types.KeelPolicyLabel: "all", types.KeelBlockedTagsAnnotation: "*-debug,*-dirty"},
+			current:     "1.0.0",
+			new:         "1.1.0",
+			want:        true,
+		},
+		{
+			name:        "blocked regexp with force policy",
+			annotations: map[string]string{types.KeelPolicyLabel: "force", types.KeelBlockedTagsAnnotation: "regexp:^nightly-\\d+$"},
+			current:     "latest",
+			new:         "nightly-20190301",
+			want:        false,
+		},
+		{
+			name:        "not allowed",
+			annotations: map[string]string{types.KeelPolicyLabel: "force", types.KeelAllowedTagsAnnotation: "release-*"},
+			current:     "latest",
+			new:         "dev-1",
+			want:        false,
+		},
+		{
+			name:        "allowed but rejected by policy",
+			annotations: map[string]string{types.KeelPolicyLabel: "patch", types.KeelAllowedTagsAnnotation: "1.*"},
+			current:     "1.0.0",
+			new:         "1.1.0",
+			want:        false,
+		},
+		{
+			name:        "allowed and blocked",
+			annotations: map[string]string{types.KeelPolicyLabel: "all", types.KeelAllowedTagsAnnotation: "1.*", types.KeelBlockedTagsAnnotation: "*-rc*"},
+			current:     "1.0.0",
+			new:         "1.1.0-rc1",
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := GetPolicyFromLabelsOrAnnotations(nil, tt.annotations)
+			if _, ok := p.(*TagFilterPolicy); !ok {
+				t.Fatalf("expected tag filter policy, got: %s", p.Name())
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("TagFilterPolicy.ShouldUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagFilterPolicyWithoutPolicy(t *testing.T) {
+	p := GetPolicyFromLabelsOrAnnotations(nil, map[string]string{types.KeelBlockedTagsAnnotation: "*-debug"})
+	if p.Type() != PolicyTypeNone {
+		t.Errorf("tag filters alone shouldn't enable updates, got: %s", p.Name())
+	}
+
+	p = GetPolicy("all", &Options{BlockedTags: []string{"regexp:("}})
+	if p.Type() != PolicyTypeNone {
+		t.Errorf("expected nil policy for invalid pattern")
+	}
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampPolicy - updates to tags with a later timestamp, timestamp is parsed from the
+// beginning of the tag using Go time layout, anything after it is ignored, for example
+// "timestamp:20060102T1504" matches 20240117T1030-gitsha and "timestamp:build-2006.01.02"
+// matches build-2024.01.17
+type TimestampPolicy struct {
+	policy string
+	layout string
+}
+
+func NewTimestampPolicy(policy string) (*TimestampPolicy, error) {
+	layout := strings.TrimPrefix(policy, "timestamp:")
+	if layout == "" {
+		return nil, fmt.Errorf("invalid timestamp policy: %s, time layout is missing", policy)
+	}
+	return &TimestampPolicy{
+		policy: policy,
+		layout: layout,
+	}, nil
+}
+
+func (p *TimestampPolicy) parse(tag string) (time.Time, bool) {
+	if len(tag) < len(p.layout) {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(p.layout, tag[:len(p.layout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
+
+// ShouldUpdate - tags without the timestamp are ignored, current tag without the timestamp
+// (for example "latest") is always updated
+func (p *TimestampPolicy) ShouldUpdate(current, new string) (bool, error) {
+	newTimestamp, ok := p.parse(new)
+	if !ok {
+		return false, nil
+	}
+	currentTimestamp, ok := p.parse(current)
+	if !ok {
+		return true, nil
+	}
+	return newTimestamp.After(currentTimestamp), nil
+}
+
+func (p *TimestampPolicy) Name() string     { return p.policy }
+func (p *TimestampPolicy) Type() PolicyType { return PolicyTypeTimestamp }
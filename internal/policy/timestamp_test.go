@@ -0,0 +1,41 @@
+package policy
+
+import "testing"
+
+func TestTimestampPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		current string
+		new     string
+		want    bool
+	}{
+		{"timestamp:20060102T1504", "20240117T1030-abc123", "20240118T0900-def456", true},
+		{"timestamp:20060102T1504", "20240118T0900-def456", "20240117T1030-abc123", false},
+		{"timestamp:20060102T1504", "20240117T1030-abc123", "20240117T1030-def456", false},
+		{"timestamp:20060102T1504", "latest", "20240117T1030-abc123", true},
+		{"timestamp:20060102T1504", "20240117T1030-abc123", "latest", false},
+		{"timestamp:20060102T1504", "20240117T1030-abc123", "20241317T1030-abc123", false},
+		// day before month, lexicographic order would be wrong
+		{"timestamp:build-02.01.2006", "build-31.12.2023", "build-01.01.2024", true},
+		{"timestamp:2006-01-02T15:04", "2024-01-17T10:30", "2024-01-17T11:00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.current+"->"+tt.new, func(t *testing.T) {
+			p := GetPolicy(tt.policy, &Options{})
+			if p.Type() != PolicyTypeTimestamp {
+				t.Fatalf("unexpected policy type: %v", p.Type())
+			}
+			got, err := p.ShouldUpdate(tt.current, tt.new)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("ShouldUpdate(%s, %s) = %v, want %v", tt.current, tt.new, got, tt.want)
+			}
+		})
+	}
+
+	if p := GetPolicy("timestamp:", &Options{}); p.Type() != PolicyTypeNone {
+		t.Errorf("expected timestamp policy without layout to be invalid")
+	}
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// webhookPolicyTimeout - how long external policy service has to answer
+var webhookPolicyTimeout = 10 * time.Second
+
+// WebhookPolicy - delegates decision to an external HTTP service ("webhook:https://..."
+// policy). Service receives a POST request with tags and update context and answers
+// with {"allow": true|false, "reason": "..."}
+type WebhookPolicy struct {
+	policy string
+	url    string
+	client *http.Client
+}
+
+// policyInput - tags and update context sent to external policy services
+type policyInput struct {
+	Policy     string `json:"policy"`
+	CurrentTag string `json:"currentTag"`
+	NewTag     string `json:"newTag"`
+	*UpdateContext
+}
+
+type webhookPolicyResponse struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+func NewWebhookPolicy(policy string) (*WebhookPolicy, error) {
+	endpoint := strings.TrimPrefix(policy, "webhook:")
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook policy: %s", policy)
+	}
+
+	return &WebhookPolicy{
+		policy: policy,
+		url:    endpoint,
+		client: &http.Client{Timeout: webhookPolicyTimeout},
+	}, nil
+}
+
+func (p *WebhookPolicy) ShouldUpdate(current, new string) (bool, error) {
+	return p.ShouldUpdateContext(nil, current, new)
+}
+
+// ShouldUpdateContext - asks external service, errors and non 2xx responses deny the update
+func (p *WebhookPolicy) ShouldUpdateContext(ctx *UpdateContext, current, new string) (bool, error) {
+	body, err := json.Marshal(&policyInput{
+		Policy:        p.policy,
+		CurrentTag:    current,
+		NewTag:        new,
+		UpdateContext: ctx,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("policy webhook request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("policy webhook returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	var decision webhookPolicyResponse
+	err = json.NewDecoder(resp.Body).Decode(&decision)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode policy webhook response: %s", err)
+	}
+
+	if !decision.Allow {
+		fields := log.Fields{
+			"current": current,
+			"new":     new,
+			"reason":  decision.Reason,
+		}
+		if ctx != nil {
+			fields["namespace"] = ctx.Namespace
+			fields["name"] = ctx.Name
+		}
+		log.WithFields(fields).Info("policy.webhook: update denied")
+	}
+
+	return decision.Allow, nil
+}
+
+func (p *WebhookPolicy) Name() string     { return p.policy }
+func (p *WebhookPolicy) Type() PolicyType { return PolicyTypeWebhook }
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPolicy_ShouldUpdate(t *testing.T) {
+	var received policyInput
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = policyInput{}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode request: %s", err)
+		}
+		switch received.NewTag {
+		case "1.1.0":
+			fmt.Fprintln(w, `{"allow": true}`)
+		case "2.0.0":
+			fmt.Fprintln(w, `{"allow": false, "reason": "major upgrades need review"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	p := GetPolicy("webhook:"+ts.URL, &Options{})
+	if p.Type() != PolicyTypeWebhook {
+		t.Fatalf("expected webhook policy, got: %s", p.Name())
+	}
+
+	ctx := &UpdateContext{Provider: "kubernetes", Kind: "deployment", Namespace: "prod", Name: "web", Image: "karolisr/keel"}
+	allowed, err := ShouldUpdate(p, ctx, "1.0.0", "1.1.0")
+	if err != nil || !allowed {
+		t.Errorf("expected update to be allowed, got: %v, %v", allowed, err)
+	}
+	if received.CurrentTag != "1.0.0" || received.Namespace != "prod" || received.Name != "web" || received.Image != "karolisr/keel" {
+		t.Errorf("unexpected request: %+v", received)
+	}
+
+	allowed, err = ShouldUpdate(p, ctx, "1.0.0", "2.0.0")
+	if err != nil || allowed {
+		t.Errorf("expected update to be denied, got: %v, %v", allowed, err)
+	}
+
+	allowed, err = p.ShouldUpdate("1.0.0", "3.0.0")
+	if err == nil || allowed {
+		t.Errorf("expected error for failed webhook, got: %v, %v", allowed, err)
+	}
+
+	// context is passed through composite policies
+	composite := GetPolicy("major && webhook:"+ts.URL, &Options{})
+	allowed, err = ShouldUpdate(composite, ctx, "1.0.0", "2.0.0")
+	if err != nil || allowed {
+		t.Errorf("expected update to be denied, got: %v, %v", allowed, err)
+	}
+	if received.Namespace != "prod" {
+		t.Errorf("expected update context to be passed on, got: %+v", received)
+	}
+}
+
+func TestNewWebhookPolicyInvalid(t *testing.T) {
+	for _, policy := range []string{"webhook:", "webhook:ftp://example.com", "webhook:example.com/policy"} {
+		if _, err := NewWebhookPolicy(policy); err == nil {
+			t.Errorf("expected error for '%s'", policy)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package window implements maintenance windows, such as "Mon-Fri 22:00-04:00 UTC",
+// that restrict when updates can be applied.
+package window
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window - maintenance window. Days are days when window opens, windows
+// that end before they start (22:00-04:00) continue into the next day.
+type Window struct {
+	days     map[time.Weekday]bool
+	start    time.Duration // offset from midnight
+	end      time.Duration // offset from midnight
+	location *time.Location
+}
+
+// Parse - parses window definition in "[days] HH:MM-HH:MM [timezone]" format, where days
+// are either a range (Mon-Fri) or a comma separated list (Sat,Sun) and default to every day.
+// Timezone defaults to UTC.
+func Parse(s string) (*Window, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty window")
+	}
+
+	w := &Window{
+		days:     make(map[time.Weekday]bool),
+		location: time.UTC,
+	}
+
+	var err error
+	timeIdx := 0
+	if !strings.Contains(fields[0], ":") {
+		w.days, err = parseDays(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		timeIdx = 1
+	} else {
+		for _, d := range weekdays {
+			w.days[d] = true
+		}
+	}
+
+	if len(fields) <= timeIdx {
+		return nil, fmt.Errorf("window '%s' is missing time range", s)
+	}
+
+	w.start, w.end, err = parseTimeRange(fields[timeIdx])
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(fields) - timeIdx {
+	case 1:
+		// using default location
+	case 2:
+		w.location, err = time.LoadLocation(fields[timeIdx+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone '%s': %s", fields[timeIdx+1], err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid window '%s'", s)
+	}
+
+	return w, nil
+}
+
+func parseDays(s string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.Split(part, "-")
+		switch len(bounds) {
+		case 1:
+			d, ok := weekdays[strings.ToLower(bounds[0])]
+			if !ok {
+				return nil, fmt.Errorf("invalid day '%s'", bounds[0])
+			}
+			days[d] = true
+		case 2:
+			from, ok := weekdays[strings.ToLower(bounds[0])]
+			if !ok {
+				return nil, fmt.Errorf("invalid day '%s'", bounds[0])
+			}
+			to, ok := weekdays[strings.ToLower(bounds[1])]
+			if !ok {
+				return nil, fmt.Errorf("invalid day '%s'", bounds[1])
+			}
+			for d := from; ; d = (d + 1) % 7 {
+				days[d] = true
+				if d == to {
+					break
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid days '%s'", part)
+		}
+	}
+	return days, nil
+}
+
+func parseTimeRange(s string) (start, end time.Duration, err error) {
+	bounds := strings.Split(s, "-")
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid time range '%s', expected HH:MM-HH:MM", s)
+	}
+	start, err = parseClock(bounds[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = parseClock(bounds[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("invalid time range '%s', start and end are equal", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains - checks whether window is open at given time
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.location)
+	offset := t.Sub(midnight)
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && offset >= w.start && offset < w.end
+	}
+
+	// overnight window, opened either today or yesterday
+	if w.days[t.Weekday()] && offset >= w.start {
+		return true
+	}
+	yesterday := (t.Weekday() + 6) % 7
+	return w.days[yesterday] && offset < w.end
+}
+
+// Next - returns next time when window opens, if window is already open -
+// returns given time
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	t = t.In(w.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.location)
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		opens := day.Add(w.start)
+		if opens.After(t) {
+			return opens
+		}
+	}
+	return t
+}
+
+func (w *Window) String() string {
+	return fmt.Sprintf("%s-%s %s", formatClock(w.start), formatClock(w.end), w.location)
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
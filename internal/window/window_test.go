@@ -0,0 +1,80 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fun 22:00-04:00",
+		"Mon-Fri 22:00",
+		"Mon-Fri 25:00-04:00",
+		"Mon-Fri 22:00-22:00",
+		"Mon-Fri 22:00-04:00 Mars/Olympus",
+		"Mon-Fri 22:00-04:00 UTC extra",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("expected error for window '%s'", s)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	// 2018-06-04 is a Monday
+	date := func(day, hour, min int) time.Time {
+		return time.Date(2018, 6, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		window string
+		t      time.Time
+		want   bool
+	}{
+		{"Mon-Fri 22:00-04:00 UTC", date(4, 23, 0), true},
+		{"Mon-Fri 22:00-04:00 UTC", date(5, 3, 59), true},
+		{"Mon-Fri 22:00-04:00 UTC", date(5, 4, 0), false},
+		{"Mon-Fri 22:00-04:00 UTC", date(4, 12, 0), false},
+		// Saturday morning belongs to Friday's window
+		{"Mon-Fri 22:00-04:00 UTC", date(9, 2, 0), true},
+		// Monday morning belongs to Sunday's window which is not allowed
+		{"Mon-Fri 22:00-04:00 UTC", date(4, 2, 0), false},
+		{"Sat,Sun 10:00-12:00", date(9, 11, 0), true},
+		{"Sat,Sun 10:00-12:00", date(8, 11, 0), false},
+		{"Fri-Mon 10:00-12:00", date(10, 11, 0), true},
+		{"Fri-Mon 10:00-12:00", date(5, 11, 0), false},
+		{"09:00-17:00", date(6, 9, 0), true},
+		{"09:00-17:00 Europe/Vilnius", date(6, 5, 0), false},
+		{"09:00-17:00 Europe/Vilnius", date(6, 7, 0), true},
+	}
+	for _, tt := range tests {
+		w, err := Parse(tt.window)
+		if err != nil {
+			t.Fatalf("failed to parse window '%s': %s", tt.window, err)
+		}
+		if got := w.Contains(tt.t); got != tt.want {
+			t.Errorf("window '%s' contains %s = %v, want %v", tt.window, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	w, err := Parse("Mon-Fri 22:00-04:00 UTC")
+	if err != nil {
+		t.Fatalf("failed to parse window: %s", err)
+	}
+
+	// Saturday noon, next window opens on Monday
+	got := w.Next(time.Date(2018, 6, 9, 12, 0, 0, 0, time.UTC))
+	want := time.Date(2018, 6, 11, 22, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("expected next window at %s, got: %s", want, got)
+	}
+
+	now := time.Date(2018, 6, 4, 23, 0, 0, 0, time.UTC)
+	if got := w.Next(now); !got.Equal(now) {
+		t.Errorf("expected window to be open, got: %s", got)
+	}
+}
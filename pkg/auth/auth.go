@@ -107,7 +107,7 @@ type User struct {
 
 func (a *DefaultAuthenticator) GenerateToken(u User) (*AuthResponse, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
-		"username": "admin",
+		"username": u.Username,
 		"exp":      time.Now().Add(expirationDelta).Unix(),
 		"iat":      time.Now().Unix(),
 	})
@@ -120,6 +120,7 @@ func (a *DefaultAuthenticator) GenerateToken(u User) (*AuthResponse, error) {
 
 	return &AuthResponse{
 		Token: tokenString,
+		User:  u,
 	}, nil
 }
 
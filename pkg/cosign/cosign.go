@@ -0,0 +1,300 @@
+// Package cosign - verifies cosign image signatures made with a key pair or keyless
+// (Fulcio certificate recorded in Rekor transparency log)
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+// signature layer annotations
+const (
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// signatureType - type of cosign simple signing payload
+const signatureType = "cosign container image signature"
+
+// ErrUnsigned - image has no signatures
+var ErrUnsigned = errors.New("image is not signed")
+
+// Identity - keyless signer, certificate has to be issued for OIDC issuer and a subject
+// (email or URI, for example GitHub Actions workflow) matching the expression
+type Identity struct {
+	Issuer  string
+	Subject *regexp.Regexp
+}
+
+// Opts - trusted keys and identities, keyless identities need Fulcio roots and Rekor keys
+type Opts struct {
+	PublicKeys []crypto.PublicKey
+
+	Identities  []Identity
+	FulcioRoots *x509.CertPool
+	RekorKeys   []crypto.PublicKey
+}
+
+// Verifier - verifies signatures of images
+type Verifier struct {
+	opts Opts
+}
+
+// New - creates verifier, at least one public key or identity has to be configured
+func New(opts Opts) (*Verifier, error) {
+	if len(opts.PublicKeys) == 0 && len(opts.Identities) == 0 {
+		return nil, fmt.Errorf("no public keys or keyless identities configured")
+	}
+	if len(opts.Identities) > 0 && (opts.FulcioRoots == nil || len(opts.RekorKeys) == 0) {
+		return nil, fmt.Errorf("keyless identities require Fulcio root certificates and Rekor public keys")
+	}
+	return &Verifier{opts: opts}, nil
+}
+
+// simpleSigning - signed payload
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// Verify - checks that at least one of the signatures is valid for the image digest and was
+// made with a trusted key or by a trusted identity
+func (v *Verifier) Verify(digest string, signatures []*registry.Signature) error {
+	if len(signatures) == 0 {
+		return ErrUnsigned
+	}
+
+	var reasons []string
+	for _, sig := range signatures {
+		err := v.verify(digest, sig)
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return fmt.Errorf("no valid signature: %s", strings.Join(reasons, "; "))
+}
+
+func (v *Verifier) verify(digest string, sig *registry.Signature) error {
+	var payload simpleSigning
+	if err := json.Unmarshal(sig.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid payload: %s", err)
+	}
+	if payload.Critical.Type != signatureType {
+		return fmt.Errorf("unexpected payload type %q", payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signature is for digest %s", payload.Critical.Image.DockerManifestDigest)
+	}
+
+	rawSig, err := base64.StdEncoding.DecodeString(sig.Annotations[signatureAnnotation])
+	if err != nil || len(rawSig) == 0 {
+		return fmt.Errorf("missing or invalid signature annotation")
+	}
+
+	if sig.Annotations[certificateAnnotation] != "" && len(v.opts.Identities) > 0 {
+		return v.verifyKeyless(sig, rawSig)
+	}
+
+	for _, key := range v.opts.PublicKeys {
+		if verifySignature(key, sig.Payload, rawSig) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature doesn't match any trusted key")
+}
+
+func (v *Verifier) verifyKeyless(sig *registry.Signature, rawSig []byte) error {
+	certs, err := parseCertificates([]byte(sig.Annotations[certificateAnnotation]))
+	if err != nil || len(certs) == 0 {
+		return fmt.Errorf("invalid certificate annotation")
+	}
+	cert := certs[0]
+
+	intermediates := x509.NewCertPool()
+	chain, _ := parseCertificates([]byte(sig.Annotations[chainAnnotation]))
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+
+	bundle := sig.Annotations[bundleAnnotation]
+	if bundle == "" {
+		return fmt.Errorf("keyless signature has no transparency log bundle")
+	}
+	signedAt, err := verifyBundle([]byte(bundle), v.opts.RekorKeys, sig.Payload, rawSig, cert)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log bundle: %s", err)
+	}
+
+	// Fulcio certificates are short lived, they have to be valid when the entry was logged
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.opts.FulcioRoots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted certificate: %s", err)
+	}
+
+	if err := verifySignature(cert.PublicKey, sig.Payload, rawSig); err != nil {
+		return fmt.Errorf("signature doesn't match certificate: %s", err)
+	}
+
+	issuer, subjects := certificateIdentity(cert)
+	for _, identity := range v.opts.Identities {
+		if identity.Issuer != issuer {
+			continue
+		}
+		for _, subject := range subjects {
+			if identity.Subject.MatchString(subject) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("signer %s (issuer %s) isn't trusted", strings.Join(subjects, ", "), issuer)
+}
+
+// verifySignature - cosign signs SHA256 digest of payload with ECDSA and RSA keys and the
+// payload itself with ed25519 keys
+func verifySignature(key crypto.PublicKey, payload, sig []byte) error {
+	digest := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, digest[:], sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil || rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, payload, sig) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+// ParsePublicKeys - PEM encoded public keys
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM encoded public keys found")
+	}
+	return keys, nil
+}
+
+// LoadPublicKeys - public keys from comma separated list of PEM files
+func LoadPublicKeys(paths string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range splitList(paths) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParsePublicKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	return keys, nil
+}
+
+// LoadCertificates - certificate pool from comma separated list of PEM files
+func LoadCertificates(paths string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range splitList(paths) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", path)
+		}
+	}
+	return pool, nil
+}
+
+// ParseIdentities - comma separated list of <issuer>=<subject regexp> entries, for example
+// https://token.actions.githubusercontent.com=https://github.com/org/repo/.*
+func ParseIdentities(value string) ([]Identity, error) {
+	var identities []Identity
+	for _, entry := range splitList(value) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid identity %q, expected <issuer>=<subject>", entry)
+		}
+		subject, err := regexp.Compile("^(?:" + parts[1] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject expression %q: %s", parts[1], err)
+		}
+		identities = append(identities, Identity{Issuer: parts[0], Subject: subject})
+	}
+	return identities, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,278 @@
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+)
+
+const testDigest = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func payload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.example.com/app"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+}
+
+func signECDSA(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+	return sig
+}
+
+func newECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	return key
+}
+
+func keySignature(sig []byte, digest string) *registry.Signature {
+	return &registry.Signature{
+		Payload:     payload(digest),
+		Annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+	key := newECDSAKey(t)
+	other := newECDSAKey(t)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	v, err := New(Opts{PublicKeys: []crypto.PublicKey{&key.PublicKey, edKey.Public()}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := v.Verify(testDigest, nil); err != ErrUnsigned {
+		t.Errorf("expected unsigned error, got %v", err)
+	}
+
+	valid := keySignature(signECDSA(t, key, payload(testDigest)), testDigest)
+	if err := v.Verify(testDigest, []*registry.Signature{valid}); err != nil {
+		t.Errorf("expected valid signature, got %s", err)
+	}
+
+	ed := keySignature(ed25519.Sign(edKey, payload(testDigest)), testDigest)
+	if err := v.Verify(testDigest, []*registry.Signature{ed}); err != nil {
+		t.Errorf("expected valid ed25519 signature, got %s", err)
+	}
+
+	untrusted := keySignature(signECDSA(t, other, payload(testDigest)), testDigest)
+	if err := v.Verify(testDigest, []*registry.Signature{untrusted}); err == nil {
+		t.Errorf("expected signature with untrusted key to be rejected")
+	}
+	// one valid signature is enough
+	if err := v.Verify(testDigest, []*registry.Signature{untrusted, valid}); err != nil {
+		t.Errorf("expected valid signature, got %s", err)
+	}
+
+	otherDigest := "sha256:" + strings.Repeat("0", 64)
+	if err := v.Verify(otherDigest, []*registry.Signature{valid}); err == nil || !strings.Contains(err.Error(), "signature is for digest") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
+
+// keyless - Fulcio root, signer certificate and Rekor key
+type keyless struct {
+	root      *x509.Certificate
+	rootKey   *ecdsa.PrivateKey
+	rekorKey  *ecdsa.PrivateKey
+	signerKey *ecdsa.PrivateKey
+}
+
+func newKeyless(t *testing.T) *keyless {
+	k := &keyless{rootKey: newECDSAKey(t), rekorKey: newECDSAKey(t), signerKey: newECDSAKey(t)}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.rootKey.PublicKey, k.rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root: %s", err)
+	}
+	k.root, _ = x509.ParseCertificate(der)
+	return k
+}
+
+// certificate - short lived signer certificate issued for the subject
+func (k *keyless) certificate(t *testing.T, email, issuer string, notBefore time.Time) []byte {
+	issuerExt, _ := asn1.Marshal(issuer)
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       notBefore,
+		NotAfter:        notBefore.Add(10 * time.Minute),
+		EmailAddresses:  []string{email},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidcIssuerV2, Value: issuerExt}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, k.root, &k.signerKey.PublicKey, k.rootKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// signature - keyless signature with Rekor bundle logged at integratedTime
+func (k *keyless) signature(t *testing.T, certPEM []byte, integratedTime time.Time) *registry.Signature {
+	p := payload(testDigest)
+	sig := signECDSA(t, k.signerKey, p)
+	hash := sha256.Sum256(p)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{
+				"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(hash[:])},
+			},
+			"signature": map[string]interface{}{
+				"content":   base64.StdEncoding.EncodeToString(sig),
+				"publicKey": map[string]string{"content": base64.StdEncoding.EncodeToString(certPEM)},
+			},
+		},
+	})
+	bp := bundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integratedTime.Unix(),
+		LogID:          logID(&k.rekorKey.PublicKey),
+		LogIndex:       42,
+	}
+	canonical, _ := json.Marshal(bp)
+	bundle, _ := json.Marshal(rekorBundle{
+		SignedEntryTimestamp: base64.StdEncoding.EncodeToString(signECDSA(t, k.rekorKey, canonical)),
+		Payload:              bp,
+	})
+
+	return &registry.Signature{
+		Payload: p,
+		Annotations: map[string]string{
+			signatureAnnotation:   base64.StdEncoding.EncodeToString(sig),
+			certificateAnnotation: string(certPEM),
+			bundleAnnotation:      string(bundle),
+		},
+	}
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	k := newKeyless(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(k.root)
+
+	identities, err := ParseIdentities("https://accounts.google.com=.*@example\\.com, https://token.actions.githubusercontent.com=https://github.com/org/.*")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, err := New(Opts{Identities: identities, FulcioRoots: roots, RekorKeys: []crypto.PublicKey{&k.rekorKey.PublicKey}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	signedAt := time.Now().Add(-time.Hour)
+	cert := k.certificate(t, "ci@example.com", "https://accounts.google.com", signedAt.Add(-time.Minute))
+
+	// certificate has expired since, it was valid when the signature was logged
+	if err := v.Verify(testDigest, []*registry.Signature{k.signature(t, cert, signedAt)}); err != nil {
+		t.Errorf("expected valid signature, got %s", err)
+	}
+
+	// logged after certificate expired
+	if err := v.Verify(testDigest, []*registry.Signature{k.signature(t, cert, signedAt.Add(30*time.Minute))}); err == nil {
+		t.Errorf("expected signature logged after certificate expiry to be rejected")
+	}
+
+	wrongSigner := k.certificate(t, "ci@example.org", "https://accounts.google.com", signedAt.Add(-time.Minute))
+	if err := v.Verify(testDigest, []*registry.Signature{k.signature(t, wrongSigner, signedAt)}); err == nil || !strings.Contains(err.Error(), "isn't trusted") {
+		t.Errorf("expected untrusted signer, got %v", err)
+	}
+
+	wrongIssuer := k.certificate(t, "ci@example.com", "https://issuer.example.com", signedAt.Add(-time.Minute))
+	if err := v.Verify(testDigest, []*registry.Signature{k.signature(t, wrongIssuer, signedAt)}); err == nil {
+		t.Errorf("expected untrusted issuer to be rejected")
+	}
+
+	// bundle of a different signature
+	sig := k.signature(t, cert, signedAt)
+	sig.Annotations[bundleAnnotation] = k.signature(t, cert, signedAt).Annotations[bundleAnnotation]
+	if err := v.Verify(testDigest, []*registry.Signature{sig}); err == nil {
+		t.Errorf("expected bundle of another signature to be rejected")
+	}
+
+	// log key isn't trusted
+	other := newKeyless(t)
+	other.root, other.rootKey, other.signerKey = k.root, k.rootKey, k.signerKey
+	if err := v.Verify(testDigest, []*registry.Signature{other.signature(t, cert, signedAt)}); err == nil {
+		t.Errorf("expected entry signed by untrusted log to be rejected")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Opts{}); err == nil {
+		t.Errorf("expected error without keys or identities")
+	}
+	identities, _ := ParseIdentities("https://accounts.google.com=ci@example.com")
+	if _, err := New(Opts{Identities: identities}); err == nil {
+		t.Errorf("expected error for identities without Fulcio roots and Rekor keys")
+	}
+}
+
+func TestParseIdentities(t *testing.T) {
+	identities, err := ParseIdentities("https://token.actions.githubusercontent.com=https://github.com/org/repo/.*")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(identities) != 1 || identities[0].Issuer != "https://token.actions.githubusercontent.com" {
+		t.Fatalf("unexpected identities: %v", identities)
+	}
+	if !identities[0].Subject.MatchString("https://github.com/org/repo/.github/workflows/release.yml@refs/heads/main") {
+		t.Errorf("expected subject to match")
+	}
+	if identities[0].Subject.MatchString("https://github.com/org/repo-fork/x") || identities[0].Subject.MatchString("prefix https://github.com/org/repo/x") {
+		t.Errorf("subject expression should be anchored")
+	}
+
+	for _, invalid := range []string{"issuer", "=subject", "issuer=("} {
+		if _, err := ParseIdentities(invalid); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	key := newECDSAKey(t)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	keys, err := ParsePublicKeys(append(data, data...))
+	if err != nil || len(keys) != 2 {
+		t.Errorf("expected two keys, got %d: %v", len(keys), err)
+	}
+	if _, err := ParsePublicKeys([]byte("not a key")); err == nil {
+		t.Errorf("expected error")
+	}
+}
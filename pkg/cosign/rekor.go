@@ -0,0 +1,152 @@
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Fulcio certificate extensions holding OIDC issuer, the first one is deprecated raw string
+var (
+	oidcIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidcIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// rekorBundle - transparency log entry with signed entry timestamp (SET) stored with
+// keyless signatures, allows offline verification
+type rekorBundle struct {
+	SignedEntryTimestamp string        `json:"SignedEntryTimestamp"`
+	Payload              bundlePayload `json:"Payload"`
+}
+
+// bundlePayload - fields are in canonical (sorted) order, SET signs their JSON encoding
+type bundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// rekordBody - hashedrekord and rekord entries record signature, signed payload hash and
+// signer certificate
+type rekordBody struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle - checks that the log entry was signed by a trusted Rekor key and records this
+// signature, returns time the entry was logged
+func verifyBundle(data []byte, rekorKeys []crypto.PublicKey, payload, sig []byte, cert *x509.Certificate) (time.Time, error) {
+	var bundle rekorBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return time.Time{}, err
+	}
+
+	set, err := base64.StdEncoding.DecodeString(bundle.SignedEntryTimestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %s", err)
+	}
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	digest := sha256.Sum256(canonical)
+
+	verified := false
+	for _, key := range rekorKeys {
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || logID(key) != bundle.Payload.LogID {
+			continue
+		}
+		if ecdsa.VerifyASN1(ecKey, digest[:], set) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return time.Time{}, fmt.Errorf("entry isn't signed by a trusted log")
+	}
+
+	bodyJSON, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry body: %s", err)
+	}
+	var body rekordBody
+	if err := json.Unmarshal(bodyJSON, &body); err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry body: %s", err)
+	}
+	if body.Kind != "hashedrekord" && body.Kind != "rekord" {
+		return time.Time{}, fmt.Errorf("unsupported entry kind %q", body.Kind)
+	}
+
+	payloadDigest := sha256.Sum256(payload)
+	if body.Spec.Data.Hash.Algorithm != "sha256" || body.Spec.Data.Hash.Value != hex.EncodeToString(payloadDigest[:]) {
+		return time.Time{}, fmt.Errorf("entry is for a different payload")
+	}
+	entrySig, err := base64.StdEncoding.DecodeString(body.Spec.Signature.Content)
+	if err != nil || !bytes.Equal(entrySig, sig) {
+		return time.Time{}, fmt.Errorf("entry is for a different signature")
+	}
+	entryCert, err := base64.StdEncoding.DecodeString(body.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry certificate: %s", err)
+	}
+	certs, err := parseCertificates(entryCert)
+	if err != nil || len(certs) == 0 || !certs[0].Equal(cert) {
+		return time.Time{}, fmt.Errorf("entry is for a different certificate")
+	}
+
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// logID - Rekor log ID is hex encoded SHA256 of its DER encoded public key
+func logID(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// certificateIdentity - OIDC issuer and subjects (email addresses and URIs) of Fulcio certificate
+func certificateIdentity(cert *x509.Certificate) (issuer string, subjects []string) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidcIssuerV2):
+			var value string
+			if _, err := asn1.Unmarshal(ext.Value, &value); err == nil {
+				issuer = value
+			}
+		case ext.Id.Equal(oidcIssuerV1) && issuer == "":
+			issuer = string(ext.Value)
+		}
+	}
+
+	subjects = append(subjects, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	return issuer, subjects
+}
@@ -3,15 +3,20 @@ package http
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
+	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
 )
 
 type approveRequest struct {
-	ID         string `json:"id"`
+	ID string `json:"id"`
+	// Voter - optional, votes are always recorded for the authenticated user so it
+	// must match it
 	Voter      string `json:"voter"`
 	Identifier string `json:"identifier"`
 	Action     string `json:"action"` // defaults to approve
@@ -25,18 +30,31 @@ const (
 	actionArchive = "archive"
 )
 
+// approvalsHandler - lists approvals, optionally filtered by ?status=pending|approved|rejected|archived
 func (s *TriggerServer) approvalsHandler(resp http.ResponseWriter, req *http.Request) {
 
 	// lists all (both archived)
-	approvals, err := s.store.ListApprovals(&types.GetApprovalQuery{})
+	all, err := s.store.ListApprovals(&types.GetApprovalQuery{})
 	if err != nil {
 		fmt.Fprintf(resp, "%s", err)
 		resp.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if len(approvals) == 0 {
-		approvals = make([]*types.Approval, 0)
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "", "archived", types.ApprovalStatusPending.String(), types.ApprovalStatusApproved.String(), types.ApprovalStatusRejected.String():
+		// ok
+	default:
+		http.Error(resp, fmt.Sprintf("unknown status '%s', should be one of pending, approved, rejected, archived", status), http.StatusBadRequest)
+		return
+	}
+
+	approvals := make([]*types.Approval, 0, len(all))
+	for _, approval := range all {
+		if status == "" || approvalStatus(approval) == status {
+			approvals = append(approvals, approval)
+		}
 	}
 
 	bts, err := json.Marshal(&approvals)
@@ -55,6 +73,15 @@ type resourceApprovalsUpdateRequest struct {
 	VotesRequired int    `json:"votesRequired"`
 }
 
+// approvalStatus - archived approvals are no longer actionable, others are pending until
+// approved or rejected
+func approvalStatus(approval *types.Approval) string {
+	if approval.Archived {
+		return "archived"
+	}
+	return approval.Status().String()
+}
+
 // approvalSetHandler allows to set/remove approvals for resources
 func (s *TriggerServer) approvalSetHandler(resp http.ResponseWriter, req *http.Request) {
 
@@ -130,15 +157,25 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 
 	var approval *types.Approval
 
+	voter, err := approvalVoter(req, ar.Voter)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// checking action
 	switch ar.Action {
 	case actionReject:
-		approval, err = s.approvalsManager.Reject(ar.Identifier)
+		approval, err = s.approvalsManager.Reject(ar.Identifier, voter)
 		if err != nil {
 			if err == store.ErrRecordNotFound {
 				http.Error(resp, fmt.Sprintf("approval '%s' not found", ar.Identifier), http.StatusNotFound)
 				return
 			}
+			if _, ok := err.(*approvals.VoterNotAllowedError); ok {
+				http.Error(resp, err.Error(), http.StatusForbidden)
+				return
+			}
 			resp.WriteHeader(http.StatusInternalServerError)
 			fmt.Fprintf(resp, "%s", err)
 			return
@@ -153,7 +190,7 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 		// deleting it
 		err := s.approvalsManager.Delete(&types.Approval{
 			ID: ar.ID,
-		})
+		}, voter)
 		if err != nil {
 			fmt.Fprintf(resp, "%s", err)
 			resp.WriteHeader(http.StatusInternalServerError)
@@ -183,12 +220,16 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 
 	default:
 		// "" or "approve"
-		approval, err = s.approvalsManager.Approve(ar.Identifier, ar.Voter)
+		approval, err = s.approvalsManager.Approve(ar.Identifier, voter)
 		if err != nil {
 			if err == store.ErrRecordNotFound {
 				http.Error(resp, fmt.Sprintf("approval '%s' not found", ar.Identifier), http.StatusNotFound)
 				return
 			}
+			if _, ok := err.(*approvals.VoterNotAllowedError); ok {
+				http.Error(resp, err.Error(), http.StatusForbidden)
+				return
+			}
 			resp.WriteHeader(http.StatusInternalServerError)
 			fmt.Fprintf(resp, "%s", err)
 			return
@@ -204,3 +245,112 @@ func (s *TriggerServer) approvalApproveHandler(resp http.ResponseWriter, req *ht
 
 	resp.Write(bts)
 }
+
+// approvalVoter - authenticated user votes and audit entries are recorded for, voter
+// supplied in the request can't be verified so it's refused unless it's the same user
+func approvalVoter(req *http.Request, supplied string) (string, error) {
+	voter := ""
+	if user := auth.GetAccountFromCtx(req.Context()); user != nil {
+		voter = user.Username
+	}
+	if supplied != "" && supplied != voter {
+		return "", fmt.Errorf("voter '%s' is not the authenticated user, votes are recorded for the authenticated user", supplied)
+	}
+	return voter, nil
+}
+
+// getApproval - approval by the {id} path variable
+func (s *TriggerServer) getApproval(resp http.ResponseWriter, req *http.Request) (*types.Approval, bool) {
+	id := getID(req)
+	approval, err := s.store.GetApproval(&types.GetApprovalQuery{ID: id})
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			http.Error(resp, fmt.Sprintf("approval '%s' not found", id), http.StatusNotFound)
+			return nil, false
+		}
+		response(nil, http.StatusInternalServerError, err, resp, req)
+		return nil, false
+	}
+	return approval, true
+}
+
+// approvalHandler - GET /v1/approvals/{id}
+func (s *TriggerServer) approvalHandler(resp http.ResponseWriter, req *http.Request) {
+	approval, ok := s.getApproval(resp, req)
+	if !ok {
+		return
+	}
+	response(approval, http.StatusOK, nil, resp, req)
+}
+
+type approvalVoteRequest struct {
+	Voter string `json:"voter"`
+}
+
+// approvalVoteHandler - POST /v1/approvals/{id}/approve and /v1/approvals/{id}/reject,
+// body is optional
+func (s *TriggerServer) approvalVoteHandler(action string) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		var vr approvalVoteRequest
+		if req.Body != nil {
+			defer req.Body.Close()
+			if err := json.NewDecoder(req.Body).Decode(&vr); err != nil && err != io.EOF {
+				http.Error(resp, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		existing, ok := s.getApproval(resp, req)
+		if !ok {
+			return
+		}
+		if existing.Archived {
+			http.Error(resp, fmt.Sprintf("approval '%s' is archived", existing.ID), http.StatusConflict)
+			return
+		}
+
+		voter, err := approvalVoter(req, vr.Voter)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var approval *types.Approval
+		if action == actionReject {
+			approval, err = s.approvalsManager.Reject(existing.Identifier, voter)
+		} else {
+			approval, err = s.approvalsManager.Approve(existing.Identifier, voter)
+		}
+		if err != nil {
+			if err == store.ErrRecordNotFound {
+				http.Error(resp, fmt.Sprintf("approval '%s' not found", existing.ID), http.StatusNotFound)
+				return
+			}
+			if _, ok := err.(*approvals.VoterNotAllowedError); ok {
+				http.Error(resp, err.Error(), http.StatusForbidden)
+				return
+			}
+			response(nil, http.StatusInternalServerError, err, resp, req)
+			return
+		}
+
+		response(approval, http.StatusOK, nil, resp, req)
+	}
+}
+
+// approvalDeleteHandler - DELETE /v1/approvals/{id}
+func (s *TriggerServer) approvalDeleteHandler(resp http.ResponseWriter, req *http.Request) {
+	approval, ok := s.getApproval(resp, req)
+	if !ok {
+		return
+	}
+
+	voter, err := approvalVoter(req, req.URL.Query().Get("voter"))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.approvalsManager.Delete(approval, voter)
+	response(&APIResponse{Status: "deleted"}, http.StatusOK, err, resp, req)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -179,7 +180,7 @@ func TestApprove(t *testing.T) {
 	}
 
 	// listing
-	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"voter": "foo","identifier": "dev/whd-dev:0.0.15"}`))
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"identifier": "dev/whd-dev:0.0.15"}`))
 	if err != nil {
 		t.Fatalf("failed to create req: %s", err)
 	}
@@ -204,7 +205,7 @@ func TestApprove(t *testing.T) {
 	}
 
 	voters := approved.GetVoters()
-	if voters[0] != "foo" {
+	if voters[0] != "admin" {
 		t.Errorf("unexpected voter: %s", voters[0])
 	}
 }
@@ -232,7 +233,7 @@ func TestApproveNotFound(t *testing.T) {
 	srv.registerRoutes(srv.router)
 
 	// listing
-	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"voter": "foo","identifier": "dev/whd-dev:0.0.15"}`))
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"identifier": "dev/whd-dev:0.0.15"}`))
 	if err != nil {
 		t.Fatalf("failed to create req: %s", err)
 	}
@@ -316,7 +317,7 @@ func TestSameVoter(t *testing.T) {
 		NewVersion:     "2.0.0",
 		CurrentVersion: "1.0.0",
 		VotesReceived:  1,
-		Voters:         map[string]interface{}{"foo": time.Now()},
+		Voters:         map[string]interface{}{"admin": time.Now()},
 	})
 
 	if err != nil {
@@ -324,7 +325,7 @@ func TestSameVoter(t *testing.T) {
 	}
 
 	// listing
-	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"voter": "foo", "identifier": "dev/12345"}`))
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"identifier": "dev/12345"}`))
 	if err != nil {
 		t.Fatalf("failed to create req: %s", err)
 	}
@@ -350,7 +351,7 @@ func TestSameVoter(t *testing.T) {
 
 	voters := approved.GetVoters()
 
-	if voters[0] != "foo" {
+	if voters[0] != "admin" {
 		t.Errorf("unexpected voter: %s", voters[0])
 	}
 }
@@ -391,7 +392,7 @@ func TestDifferentVoter(t *testing.T) {
 	}
 
 	// listing
-	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"voter": "foo", "identifier": "dev/12345"}`))
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"identifier": "dev/12345"}`))
 	if err != nil {
 		t.Fatalf("failed to create req: %s", err)
 	}
@@ -415,9 +416,9 @@ func TestDifferentVoter(t *testing.T) {
 		t.Errorf("expected to find 2 voters")
 	}
 
-	_, fooFound := approved.Voters["foo"]
+	_, fooFound := approved.Voters["admin"]
 	if !fooFound {
-		t.Errorf("expected to find 'foo' voter")
+		t.Errorf("expected to find 'admin' voter")
 	}
 	_, barFound := approved.Voters["bar"]
 	if !barFound {
@@ -425,6 +426,64 @@ func TestDifferentVoter(t *testing.T) {
 	}
 }
 
+func TestVoterNotApprover(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+	authenticator := auth.New(&auth.Opts{
+		Username: "admin",
+		Password: "pass",
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator:   authenticator,
+		Store:           store,
+	})
+	srv.registerRoutes(srv.router)
+
+	err := am.Create(&types.Approval{
+		Identifier:     "dev/12345",
+		VotesRequired:  1,
+		NewVersion:     "2.0.0",
+		CurrentVersion: "1.0.0",
+		Approvers:      "bar",
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"identifier": "dev/12345"}`))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.SetBasicAuth("admin", "pass")
+
+	rec := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "user 'admin' is not allowed to vote") {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+
+	approval, err := am.Get("dev/12345")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.VotesReceived != 0 {
+		t.Errorf("expected vote not to be counted")
+	}
+}
+
 func TestReject(t *testing.T) {
 	fp := &fakeProvider{}
 	store, teardown := NewTestingUtils()
@@ -459,7 +518,7 @@ func TestReject(t *testing.T) {
 	}
 
 	// listing
-	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"voter": "foo", "action": "reject", "identifier":"dev/12345"}`))
+	req, err := http.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(`{"action": "reject", "identifier":"dev/12345"}`))
 	if err != nil {
 		t.Fatalf("failed to create req: %s", err)
 	}
@@ -609,3 +668,128 @@ func TestAuthListApprovalsB(t *testing.T) {
 		t.Errorf("unexpected current version: %s", approvals[0].CurrentVersion)
 	}
 }
+
+func TestApprovalResourceEndpoints(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := NewTestingUtils()
+	defer teardown()
+
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+	authenticator := auth.New(&auth.Opts{
+		Username: "admin",
+		Password: "pass",
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+	srv := NewTriggerServer(&Opts{
+		Providers:       providers,
+		ApprovalManager: am,
+		Authenticator:   authenticator,
+		Store:           store,
+	})
+	srv.registerRoutes(srv.router)
+
+	for _, identifier := range []string{"dev/app-1", "dev/app-2", "dev/app-3"} {
+		err := am.Create(&types.Approval{
+			Identifier:     identifier,
+			VotesRequired:  1,
+			NewVersion:     "2.0.0",
+			CurrentVersion: "1.0.0",
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("admin", "pass")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+	get := func(identifier string) *types.Approval {
+		approval, err := am.Get(identifier)
+		if err != nil {
+			t.Fatalf("failed to get approval: %s", err)
+		}
+		return approval
+	}
+
+	app1, app2, app3 := get("dev/app-1"), get("dev/app-2"), get("dev/app-3")
+
+	// approving as the authenticated user
+	rec := do("POST", "/v1/approvals/"+app1.ID+"/approve", "")
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := get("dev/app-1").Voters["admin"]; !ok {
+		t.Errorf("expected authenticated user to be recorded as voter")
+	}
+
+	// votes can't be recorded for other users
+	rec = do("POST", "/v1/approvals/"+app2.ID+"/reject", `{"voter": "jane"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for a voter other than the authenticated user, got: %d", rec.Code)
+	}
+	if get("dev/app-2").Rejected {
+		t.Errorf("expected approval not to be rejected")
+	}
+	rec = do("POST", "/v1/approvals/"+app2.ID+"/reject", `{"voter": "admin"}`)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if !get("dev/app-2").Rejected {
+		t.Errorf("expected approval to be rejected")
+	}
+
+	// listing pending
+	rec = do("GET", "/v1/approvals?status=pending", "")
+	var pending []*types.Approval
+	if err := json.Unmarshal(rec.Body.Bytes(), &pending); err != nil {
+		t.Fatalf("failed to unmarshal response into approvals: %s", err)
+	}
+	if len(pending) != 1 || pending[0].ID != app3.ID {
+		t.Errorf("expected only dev/app-3 to be pending, got %d approvals", len(pending))
+	}
+	if rec = do("GET", "/v1/approvals?status=unknown", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for unknown status, got %d", rec.Code)
+	}
+
+	rec = do("GET", "/v1/approvals/"+app3.ID, "")
+	var approval types.Approval
+	if err := json.Unmarshal(rec.Body.Bytes(), &approval); err != nil {
+		t.Fatalf("failed to unmarshal response into approval: %s", err)
+	}
+	if approval.Identifier != "dev/app-3" {
+		t.Errorf("unexpected approval: %s", approval.Identifier)
+	}
+
+	// deleting
+	if rec = do("DELETE", "/v1/approvals/"+app3.ID, ""); rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+	if rec = do("GET", "/v1/approvals/"+app3.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected deleted approval to be not found, got %d", rec.Code)
+	}
+	if rec = do("POST", "/v1/approvals/"+app3.ID+"/approve", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected deleted approval to be not found, got %d", rec.Code)
+	}
+
+	logs, err := store.GetAuditLogs(&types.AuditLogQuery{ResourceKindFilter: []string{types.AuditResourceKindApproval}})
+	if err != nil {
+		t.Fatalf("failed to get audit logs: %s", err)
+	}
+	users := map[string]string{}
+	for _, entry := range logs {
+		users[entry.Action] = entry.Username
+	}
+	if users[types.AuditActionApprovalApproved] != "admin" || users[types.AuditActionApprovalRejected] != "admin" || users[types.AuditActionDeleted] != "admin" {
+		t.Errorf("unexpected audit log users: %v", users)
+	}
+}
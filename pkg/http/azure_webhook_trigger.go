@@ -1,8 +1,10 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -46,6 +48,7 @@ func init() {
 //}
 
 type azureWebhook struct {
+	Action string `json:"action"`
 	Target struct {
 		Repository string `json:"repository"`
 		Tag        string `json:"tag"`
@@ -56,9 +59,45 @@ type azureWebhook struct {
 	} `json:"request"`
 }
 
+// Event Grid event types handled by azureHandler
+const (
+	eventGridSubscriptionValidation = "Microsoft.EventGrid.SubscriptionValidationEvent"
+	eventGridImagePushed            = "Microsoft.ContainerRegistry.ImagePushed"
+)
+
+// eventGridEvent - Event Grid delivers batches of events, ACR push event data has
+// the same format as ACR webhooks
+// https://docs.microsoft.com/en-us/azure/event-grid/event-schema-container-registry
+type eventGridEvent struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"eventType"`
+	Subject   string          `json:"subject"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type eventGridValidationResponse struct {
+	ValidationResponse string `json:"validationResponse"`
+}
+
+// azureHandler - handles both ACR webhooks (single JSON object) and Event Grid
+// deliveries (JSON array of events)
 func (s *TriggerServer) azureHandler(resp http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.azureHandler: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		s.azureEventGridHandler(body, resp, req)
+		return
+	}
+
 	aw := azureWebhook{}
-	if err := json.NewDecoder(req.Body).Decode(&aw); err != nil {
+	if err := json.Unmarshal(body, &aw); err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("trigger.azureHandler: failed to decode request")
@@ -66,23 +105,83 @@ func (s *TriggerServer) azureHandler(resp http.ResponseWriter, req *http.Request
 		return
 	}
 
+	// sent when webhook is configured or tested from the portal
+	if aw.Action == "ping" {
+		resp.WriteHeader(http.StatusOK)
+		return
+	}
+
 	if aw.Target.Tag == "" {
 		resp.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(resp, "tag cannot be empty")
 		return
 	}
 
-	// for every updated tag generating event
+	s.triggerAzureEvent(&aw)
+
+	resp.WriteHeader(http.StatusOK)
+	return
+}
+
+func (s *TriggerServer) azureEventGridHandler(body []byte, resp http.ResponseWriter, req *http.Request) {
+	var events []eventGridEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.azureHandler: failed to decode event grid events")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range events {
+		switch event.EventType {
+		case eventGridSubscriptionValidation:
+			var validation struct {
+				ValidationCode string `json:"validationCode"`
+			}
+			if err := json.Unmarshal(event.Data, &validation); err != nil || validation.ValidationCode == "" {
+				resp.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(resp, "validation code cannot be empty")
+				return
+			}
+			log.WithFields(log.Fields{
+				"id": event.ID,
+			}).Info("trigger.azureHandler: event grid subscription validated")
+			response(&eventGridValidationResponse{ValidationResponse: validation.ValidationCode}, 200, nil, resp, req)
+			return
+		case eventGridImagePushed:
+			aw := azureWebhook{}
+			if err := json.Unmarshal(event.Data, &aw); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"id":    event.ID,
+				}).Error("trigger.azureHandler: failed to decode event grid event data")
+				continue
+			}
+			// pushes by digest only (for example manifest lists) have no tag to update to
+			if aw.Target.Tag == "" {
+				continue
+			}
+			s.triggerAzureEvent(&aw)
+		default:
+			log.WithFields(log.Fields{
+				"id":         event.ID,
+				"event_type": event.EventType,
+			}).Debug("trigger.azureHandler: ignoring event grid event")
+		}
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+func (s *TriggerServer) triggerAzureEvent(aw *azureWebhook) {
 	var DockerURL = aw.Request.Host + "/" + aw.Target.Repository
 	event := types.Event{}
 	event.CreatedAt = time.Now()
 	event.TriggerName = "azure"
-	event.Repository.Name = DockerURL // need to build this url..
+	event.Repository.Name = DockerURL
 	event.Repository.Tag = aw.Target.Tag
 	event.Repository.Digest = aw.Target.Digest
 	s.trigger(event)
 	newAzureWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
-
-	resp.WriteHeader(http.StatusOK)
-	return
 }
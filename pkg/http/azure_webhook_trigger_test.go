@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 
 	"net/http/httptest"
@@ -66,3 +67,117 @@ func TestAzureWebhookHandler(t *testing.T) {
 		t.Errorf("expected sha256:80f0d5c8786bb9e621a45ece0db56d11cdc624ad20da9fe62e9d25490f331d7d but got %s", fp.submitted[0].Repository.Digest)
 	}
 }
+
+var fakeEventGridValidation = `[{
+  "id": "2d1781af-3a4c-4d7c-bd0c-e34b19da4e66",
+  "topic": "/subscriptions/xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
+  "subject": "",
+  "data": {
+    "validationCode": "512d38b6-c7b8-40c8-89fe-f46f9e9622b6",
+    "validationUrl": "https://rp-eastus2.eventgrid.azure.net:553/eventsubscriptions/estest/validate?id=512d38b6-c7b8-40c8-89fe-f46f9e9622b6&t=2018-04-26T20:30:54.4538837Z&apiVersion=2018-05-01-preview&token=1A1A1A1A"
+  },
+  "eventType": "Microsoft.EventGrid.SubscriptionValidationEvent",
+  "eventTime": "2018-01-25T22:12:19.4556811Z",
+  "metadataVersion": "1",
+  "dataVersion": "1"
+}]`
+
+var fakeEventGridImagePushed = `[{
+  "id": "831e1650-001e-001b-66ab-eeb76e069631",
+  "topic": "/subscriptions/<subscription-id>/resourceGroups/<resource-group-name>/providers/Microsoft.ContainerRegistry/registries/<name>",
+  "subject": "aci-helloworld:v1",
+  "eventType": "Microsoft.ContainerRegistry.ImagePushed",
+  "eventTime": "2018-04-25T21:39:47.6549614Z",
+  "data": {
+    "id": "31c51664-e5bd-416a-a5df-e5206bc47ed0",
+    "timestamp": "2018-04-25T21:39:47.276585742Z",
+    "action": "push",
+    "target": {
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "size": 3023,
+      "digest": "sha256:213bbc182920ab41e18edc2001e06abcca6735d87782d9cef68abd83941cf0e5",
+      "length": 3023,
+      "repository": "aci-helloworld",
+      "tag": "v1"
+    },
+    "request": {
+      "id": "7c66f28b-de19-40a4-821c-6f5f6c0003a4",
+      "host": "demo.azurecr.io",
+      "method": "PUT",
+      "useragent": "docker/18.03.0-ce go/go1.9.4 git-commit/0520e24 os/windows arch/amd64 UpstreamClient(Docker-Client/18.03.0-ce \\(windows\\))"
+    }
+  },
+  "dataVersion": "1.0",
+  "metadataVersion": "1"
+},
+{
+  "id": "f1e1a0f0-001e-001b-66ab-eeb76e069632",
+  "subject": "aci-helloworld:v1",
+  "eventType": "Microsoft.ContainerRegistry.ImageDeleted",
+  "data": {"action": "delete", "target": {"repository": "aci-helloworld", "tag": "v0"}, "request": {"host": "demo.azurecr.io"}}
+}]`
+
+func TestAzureEventGridHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/azure", bytes.NewBuffer([]byte(fakeEventGridValidation)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+	var validation eventGridValidationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &validation); err != nil {
+		t.Fatalf("failed to decode validation response: %s", err)
+	}
+	if validation.ValidationResponse != "512d38b6-c7b8-40c8-89fe-f46f9e9622b6" {
+		t.Errorf("unexpected validation response: %s", validation.ValidationResponse)
+	}
+	if len(fp.submitted) != 0 {
+		t.Fatalf("validation shouldn't submit events")
+	}
+
+	req, err = http.NewRequest("POST", "/v1/webhooks/azure", bytes.NewBuffer([]byte(fakeEventGridImagePushed)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Name != "demo.azurecr.io/aci-helloworld" {
+		t.Errorf("expected demo.azurecr.io/aci-helloworld but got %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "v1" {
+		t.Errorf("expected v1 but got %s", fp.submitted[0].Repository.Tag)
+	}
+}
+
+func TestAzureWebhookPing(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/webhooks/azure", bytes.NewBuffer([]byte(`{"id": "1", "action": "ping"}`)))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Errorf("unexpected status code: %d", rec.Code)
+	}
+	if len(fp.submitted) != 0 {
+		t.Errorf("ping shouldn't submit events")
+	}
+}
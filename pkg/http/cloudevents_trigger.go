@@ -0,0 +1,236 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newCloudEventsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cloudevents_requests_total",
+		Help: "How many /v1/webhooks/cloudevents requests processed, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(newCloudEventsCounter)
+}
+
+// CloudEvents HTTP protocol binding content modes, see
+// https://github.com/cloudevents/spec/blob/v1.0/http-protocol-binding.md
+const (
+	cloudEventsStructuredContentType = "application/cloudevents+json"
+	cloudEventsBatchContentType      = "application/cloudevents-batch+json"
+	cloudEventsSpecVersion           = "1.0"
+)
+
+// cloudEvent - event in structured mode, binary mode attributes are read from ce- headers
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+func (e *cloudEvent) validate() error {
+	if e.SpecVersion != cloudEventsSpecVersion {
+		return fmt.Errorf("unsupported specversion '%s'", e.SpecVersion)
+	}
+	if e.ID == "" || e.Source == "" || e.Type == "" {
+		return fmt.Errorf("id, source and type attributes are required")
+	}
+	return nil
+}
+
+// cloudEventImageData - supported event data, either native webhook repository
+// ({"name": "karolisr/keel", "tag": "0.2.2"}), image reference ({"image": "karolisr/keel:0.2.2"})
+// or resources of Harbor "harbor.artifact.pushed" events
+type cloudEventImageData struct {
+	Name      string `json:"name"`
+	Tag       string `json:"tag"`
+	Digest    string `json:"digest"`
+	Image     string `json:"image"`
+	Resources []struct {
+		Digest      string `json:"digest"`
+		Tag         string `json:"tag"`
+		ResourceURL string `json:"resource_url"`
+	} `json:"resources"`
+}
+
+// isJSONContentType - only JSON event data is supported, missing content type means JSON
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// repositories - returns repositories pushed according to event data
+func (e *cloudEvent) repositories() ([]types.Repository, error) {
+	if !isJSONContentType(e.DataContentType) {
+		return nil, fmt.Errorf("unsupported datacontenttype '%s'", e.DataContentType)
+	}
+
+	data := []byte(e.Data)
+	if e.DataBase64 != "" {
+		var err error
+		data, err = base64.StdEncoding.DecodeString(e.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data_base64: %s", err)
+		}
+	}
+
+	var d cloudEventImageData
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to decode event data: %s", err)
+	}
+
+	var repositories []types.Repository
+	switch {
+	case d.Name != "" && d.Tag != "":
+		repositories = append(repositories, types.Repository{Name: d.Name, Tag: d.Tag, Digest: d.Digest})
+	case d.Image != "":
+		ref, err := image.Parse(d.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image %s: %s", d.Image, err)
+		}
+		repositories = append(repositories, types.Repository{Name: ref.Repository(), Tag: ref.Tag(), Digest: d.Digest})
+	default:
+		for _, r := range d.Resources {
+			if r.Tag == "" {
+				continue
+			}
+			resourceURL := r.ResourceURL
+			if idx := strings.Index(resourceURL, "@"); idx > 0 {
+				resourceURL = resourceURL[:idx]
+			}
+			ref, err := image.Parse(resourceURL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse repository %s: %s", r.ResourceURL, err)
+			}
+			repositories = append(repositories, types.Repository{Name: ref.Repository(), Tag: r.Tag, Digest: r.Digest})
+		}
+	}
+
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("event data doesn't contain an image")
+	}
+	return repositories, nil
+}
+
+// readCloudEvents - reads events in binary, structured or batched mode
+func readCloudEvents(req *http.Request) ([]*cloudEvent, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case cloudEventsStructuredContentType:
+		event := &cloudEvent{}
+		if err := json.Unmarshal(body, event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %s", err)
+		}
+		return []*cloudEvent{event}, nil
+	case cloudEventsBatchContentType:
+		var events []*cloudEvent
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("failed to decode events: %s", err)
+		}
+		return events, nil
+	}
+
+	return []*cloudEvent{{
+		SpecVersion:     req.Header.Get("ce-specversion"),
+		ID:              req.Header.Get("ce-id"),
+		Source:          req.Header.Get("ce-source"),
+		Type:            req.Header.Get("ce-type"),
+		Subject:         req.Header.Get("ce-subject"),
+		DataContentType: req.Header.Get("Content-Type"),
+		Data:            body,
+	}}, nil
+}
+
+// cloudEventsHandler - accepts CloudEvents carrying pushed images, for example from
+// Knative Eventing or Argo Events
+func (s *TriggerServer) cloudEventsHandler(resp http.ResponseWriter, req *http.Request) {
+	events, err := readCloudEvents(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.cloudEventsHandler: failed to read request")
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	var triggered []types.Event
+	for _, ce := range events {
+		if err := ce.validate(); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "invalid event %s: %s", ce.ID, err)
+			return
+		}
+
+		repositories, err := ce.repositories()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"id":     ce.ID,
+				"source": ce.Source,
+				"type":   ce.Type,
+			}).Error("trigger.cloudEventsHandler: failed to get image from event")
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "invalid event %s: %s", ce.ID, err)
+			return
+		}
+
+		for _, repo := range repositories {
+			event := types.Event{}
+			event.Repository = repo
+			event.CreatedAt = time.Now()
+			event.TriggerName = "cloudevents"
+			triggered = append(triggered, event)
+
+			log.WithFields(log.Fields{
+				"id":         ce.ID,
+				"source":     ce.Source,
+				"type":       ce.Type,
+				"repository": repo.Name,
+				"tag":        repo.Tag,
+			}).Debug("trigger.cloudEventsHandler: got event, processing")
+		}
+	}
+
+	// events are only triggered once the whole request is valid so that redelivered
+	// batches don't trigger them twice
+	for _, event := range triggered {
+		s.trigger(event)
+		newCloudEventsCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCloudEventsHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		headers     map[string]string
+		payload     string
+		code        int
+	}{
+		{
+			name:        "binary",
+			contentType: "application/json",
+			headers: map[string]string{
+				"ce-specversion": "1.0",
+				"ce-id":          "1",
+				"ce-source":      "/ci",
+				"ce-type":        "dev.keel.image.pushed",
+			},
+			payload: `{"name": "karolisr/keel", "tag": "0.2.2"}`,
+			code:    200,
+		},
+		{
+			name:        "binary missing attributes",
+			contentType: "application/json",
+			headers:     map[string]string{"ce-specversion": "1.0"},
+			payload:     `{"name": "karolisr/keel", "tag": "0.2.2"}`,
+			code:        400,
+		},
+		{
+			name:        "structured",
+			contentType: "application/cloudevents+json; charset=utf-8",
+			payload:     `{"specversion": "1.0", "id": "2", "source": "/ci", "type": "dev.keel.image.pushed", "data": {"image": "gcr.io/v2-namespace/hello-world:1.1.1"}}`,
+			code:        200,
+		},
+		{
+			name:        "structured base64",
+			contentType: "application/cloudevents+json",
+			payload:     `{"specversion": "1.0", "id": "3", "source": "/ci", "type": "dev.keel.image.pushed", "data_base64": "eyJpbWFnZSI6ICJrYXJvbGlzci9rZWVsOjAuMi4zIn0="}`,
+			code:        200,
+		},
+		{
+			name:        "harbor batch",
+			contentType: "application/cloudevents-batch+json",
+			payload: `[{"specversion": "1.0", "id": "4", "source": "/projects/1/webhook/policies/1", "type": "harbor.artifact.pushed", "datacontenttype": "application/json",
+				"data": {"resources": [{"digest": "sha256:954b378c", "tag": "1.2.3", "resource_url": "harbor.example.com/library/nginx:1.2.3"}], "repository": {"name": "nginx", "namespace": "library"}}}]`,
+			code: 200,
+		},
+		{
+			name:        "no image",
+			contentType: "application/cloudevents+json",
+			payload:     `{"specversion": "1.0", "id": "5", "source": "/ci", "type": "dev.keel.build.started", "data": {"build": 1}}`,
+			code:        400,
+		},
+		{
+			name:        "unsupported data",
+			contentType: "application/cloudevents+json",
+			payload:     `{"specversion": "1.0", "id": "6", "source": "/ci", "type": "dev.keel.image.pushed", "datacontenttype": "application/xml", "data": "<image/>"}`,
+			code:        400,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/v1/webhooks/cloudevents", bytes.NewBuffer([]byte(tc.payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.Header.Set("Content-Type", tc.contentType)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: unexpected status code: %d, expected: %d, body: %s", tc.name, rec.Code, tc.code, rec.Body.String())
+		}
+	}
+
+	expected := []struct{ name, tag string }{
+		{"karolisr/keel", "0.2.2"},
+		{"gcr.io/v2-namespace/hello-world", "1.1.1"},
+		{"index.docker.io/karolisr/keel", "0.2.3"},
+		{"harbor.example.com/library/nginx", "1.2.3"},
+	}
+	if len(fp.submitted) != len(expected) {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	for i, e := range expected {
+		if fp.submitted[i].Repository.Name != e.name || fp.submitted[i].Repository.Tag != e.tag {
+			t.Errorf("event %d: expected %s:%s, got %s:%s", i, e.name, e.tag, fp.submitted[i].Repository.Name, fp.submitted[i].Repository.Tag)
+		}
+	}
+	if fp.submitted[3].Repository.Digest != "sha256:954b378c" {
+		t.Errorf("unexpected digest: %s", fp.submitted[3].Repository.Digest)
+	}
+}
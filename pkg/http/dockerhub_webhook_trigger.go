@@ -1,9 +1,11 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/keel-hq/keel/types"
@@ -80,6 +82,58 @@ type dockerHubWebhook struct {
 	} `json:"repository"`
 }
 
+// dockerHubCallbackHosts - callbacks are only sent to Docker Hub so webhook payloads
+// can't make keel send requests anywhere else
+var dockerHubCallbackHosts = map[string]bool{
+	"registry.hub.docker.com": true,
+	"hub.docker.com":          true,
+}
+
+var dockerHubCallbackClient = &http.Client{Timeout: 10 * time.Second}
+
+// dockerHubCallback - Docker Hub marks webhook delivery as successful once callback_url
+// receives this payload
+type dockerHubCallback struct {
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+// validateDockerHubCallbackURL - callback has to be a Docker Hub https URL
+func validateDockerHubCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || !dockerHubCallbackHosts[u.Hostname()] {
+		return fmt.Errorf("callback URL %s doesn't point to Docker Hub", callbackURL)
+	}
+	return nil
+}
+
+// acknowledgeDockerHubWebhook - validates webhook chain by posting to callback_url
+func acknowledgeDockerHubWebhook(callbackURL string, repository string) error {
+	payload, err := json.Marshal(&dockerHubCallback{
+		State:       "success",
+		Description: fmt.Sprintf("%s received by keel", repository),
+		Context:     "keel",
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := dockerHubCallbackClient.Post(callbackURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // dockerHubHandler - used to react to dockerhub webhooks
 func (s *TriggerServer) dockerHubHandler(resp http.ResponseWriter, req *http.Request) {
 	dw := dockerHubWebhook{}
@@ -113,5 +167,23 @@ func (s *TriggerServer) dockerHubHandler(resp http.ResponseWriter, req *http.Req
 
 	resp.WriteHeader(http.StatusOK)
 
+	if dw.CallbackURL != "" {
+		if err := validateDockerHubCallbackURL(dw.CallbackURL); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": dw.Repository.RepoName,
+			}).Warn("trigger.dockerHubHandler: callback not sent")
+		} else {
+			go func() {
+				if err := acknowledgeDockerHubWebhook(dw.CallbackURL, dw.Repository.RepoName); err != nil {
+					log.WithFields(log.Fields{
+						"error": err,
+						"image": dw.Repository.RepoName,
+					}).Error("trigger.dockerHubHandler: failed to acknowledge webhook")
+				}
+			}()
+		}
+	}
+
 	newDockerhubWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
 }
@@ -2,12 +2,30 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
 
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// fakeDockerHubCallbacks - captures callbacks instead of sending them to Docker Hub
+func fakeDockerHubCallbacks() (chan *http.Request, func()) {
+	callbacks := make(chan *http.Request, 1)
+	original := dockerHubCallbackClient
+	dockerHubCallbackClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		callbacks <- req
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil)), Request: req}, nil
+	})}
+	return callbacks, func() { dockerHubCallbackClient = original }
+}
+
 var fakeRequest = `{
 	"push_data": {
 		"pushed_at": 1497467660,
@@ -36,6 +54,8 @@ var fakeRequest = `{
 }`
 
 func TestDockerhubWebhookHandler(t *testing.T) {
+	callbacks, restore := fakeDockerHubCallbacks()
+	defer restore()
 
 	fp := &fakeProvider{}
 	srv, teardown := NewTestingServer(fp)
@@ -67,4 +87,34 @@ func TestDockerhubWebhookHandler(t *testing.T) {
 	if fp.submitted[0].Repository.Tag != "0.1.7" {
 		t.Errorf("expected 0.1.7 but got %s", fp.submitted[0].Repository.Tag)
 	}
+
+	select {
+	case callback := <-callbacks:
+		if callback.URL.String() != "https://registry.hub.docker.com/u/karolisr/keel/hook/22hagb51h1gfb4eefc5f1g4j3abi0beg4/" {
+			t.Errorf("unexpected callback URL: %s", callback.URL)
+		}
+		var payload dockerHubCallback
+		if err := json.NewDecoder(callback.Body).Decode(&payload); err != nil {
+			t.Fatalf("failed to decode callback: %s", err)
+		}
+		if payload.State != "success" {
+			t.Errorf("unexpected callback state: %s", payload.State)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected webhook to be acknowledged")
+	}
+}
+
+func TestValidateDockerHubCallbackURL(t *testing.T) {
+	for callbackURL, valid := range map[string]bool{
+		"https://registry.hub.docker.com/u/karolisr/keel/hook/22hagb51h1gfb4eefc5f1g4j3abi0beg4/": true,
+		"http://registry.hub.docker.com/u/karolisr/keel/hook/22hagb51h1gfb4eefc5f1g4j3abi0beg4/":  false,
+		"https://registry.hub.docker.com.example.com/hook/":                                       false,
+		"https://169.254.169.254/latest/meta-data/":                                               false,
+	} {
+		err := validateDockerHubCallbackURL(callbackURL)
+		if (err == nil) != valid {
+			t.Errorf("validateDockerHubCallbackURL(%s) = %v, expected valid: %v", callbackURL, err, valid)
+		}
+	}
 }
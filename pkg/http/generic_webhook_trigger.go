@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gorilla/mux"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/keel-hq/keel/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newGenericWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "generic_webhook_requests_total",
+		Help: "How many /v1/webhooks/generic requests processed, partitioned by webhook and image.",
+	},
+	[]string{"webhook", "image"},
+)
+
+func init() {
+	prometheus.MustRegister(newGenericWebhooksCounter)
+}
+
+// GenericWebhook - maps payload of a webhook keel doesn't know to a repository event,
+// received on /v1/webhooks/generic/<name>. Expressions are either JSONPath, for example
+// {.data.image}, or Go templates, for example {{ .data.registry }}/{{ .data.image }}
+//
+//   - name: jfrog
+//     repository: 'jfrog.example.com/{{ .data.repo_key }}/{{ .data.image_name }}'
+//     tag: '{.data.tag}'
+//     digest: '{.data.sha256}'
+type GenericWebhook struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest,omitempty"`
+	// Secret - optional shared secret, passed as "secret" query parameter or X-Keel-Webhook-Secret header
+	Secret string `json:"secret,omitempty"`
+
+	repository fieldExpression
+	tag        fieldExpression
+	digest     fieldExpression
+}
+
+// fieldExpression - extracts a value from decoded JSON payload
+type fieldExpression interface {
+	evaluate(data interface{}) (string, error)
+}
+
+type jsonPathExpression struct {
+	path *jsonpath.JSONPath
+}
+
+func (e *jsonPathExpression) evaluate(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	err := e.path.Execute(&buf, data)
+	return buf.String(), err
+}
+
+type templateExpression struct {
+	tmpl *template.Template
+}
+
+func (e *templateExpression) evaluate(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	err := e.tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
+
+var genericWebhookFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+}
+
+func compileExpression(name, text string) (fieldExpression, error) {
+	if strings.Contains(text, "{{") {
+		tmpl, err := template.New(name).Funcs(genericWebhookFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return &templateExpression{tmpl: tmpl}, nil
+	}
+	path := jsonpath.New(name)
+	if err := path.Parse(text); err != nil {
+		return nil, err
+	}
+	return &jsonPathExpression{path: path}, nil
+}
+
+// compile - parses expressions of the webhook, digest is optional
+func (w *GenericWebhook) compile() error {
+	if w.Name == "" {
+		return fmt.Errorf("webhook name cannot be empty")
+	}
+	if w.Repository == "" || w.Tag == "" {
+		return fmt.Errorf("webhook %s: repository and tag expressions are required", w.Name)
+	}
+
+	var err error
+	w.repository, err = compileExpression("repository", w.Repository)
+	if err != nil {
+		return fmt.Errorf("webhook %s: invalid repository expression: %s", w.Name, err)
+	}
+	w.tag, err = compileExpression("tag", w.Tag)
+	if err != nil {
+		return fmt.Errorf("webhook %s: invalid tag expression: %s", w.Name, err)
+	}
+	if w.Digest != "" {
+		w.digest, err = compileExpression("digest", w.Digest)
+		if err != nil {
+			return fmt.Errorf("webhook %s: invalid digest expression: %s", w.Name, err)
+		}
+	}
+	return nil
+}
+
+// repositoryFrom - evaluates expressions against the payload
+func (w *GenericWebhook) repositoryFrom(data interface{}) (types.Repository, error) {
+	repo := types.Repository{}
+
+	var err error
+	repo.Name, err = w.repository.evaluate(data)
+	if err != nil {
+		return repo, fmt.Errorf("failed to evaluate repository expression: %s", err)
+	}
+	repo.Tag, err = w.tag.evaluate(data)
+	if err != nil {
+		return repo, fmt.Errorf("failed to evaluate tag expression: %s", err)
+	}
+	if w.digest != nil {
+		repo.Digest, err = w.digest.evaluate(data)
+		if err != nil {
+			return repo, fmt.Errorf("failed to evaluate digest expression: %s", err)
+		}
+	}
+	repo.Name = strings.TrimSpace(repo.Name)
+	repo.Tag = strings.TrimSpace(repo.Tag)
+	repo.Digest = strings.TrimSpace(repo.Digest)
+	return repo, nil
+}
+
+// LoadGenericWebhooks - reads list of generic webhooks from YAML or JSON file
+func LoadGenericWebhooks(path string) ([]*GenericWebhook, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []*GenericWebhook
+	if err := yaml.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	names := make(map[string]bool)
+	for _, w := range webhooks {
+		if err := w.compile(); err != nil {
+			return nil, err
+		}
+		if names[w.Name] {
+			return nil, fmt.Errorf("duplicate webhook name %s", w.Name)
+		}
+		names[w.Name] = true
+	}
+	return webhooks, nil
+}
+
+func (s *TriggerServer) genericHandler(resp http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	webhook, ok := s.genericWebhooks[name]
+	if !ok {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "webhook %s is not configured", name)
+		return
+	}
+
+	if webhook.Secret != "" && !validWebhookSecret(req, webhook.Secret) {
+		log.WithFields(log.Fields{
+			"webhook": name,
+		}).Warn("trigger.genericHandler: invalid webhook secret")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var payload interface{}
+	decoder := json.NewDecoder(req.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"webhook": name,
+		}).Error("trigger.genericHandler: failed to decode request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	repo, err := webhook.repositoryFrom(payload)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"webhook": name,
+		}).Error("trigger.genericHandler: failed to map payload")
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	if repo.Name == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "repository name cannot be empty")
+		return
+	}
+
+	if repo.Tag == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "repository tag cannot be empty")
+		return
+	}
+
+	event := types.Event{}
+	event.Repository = repo
+	event.CreatedAt = time.Now()
+	event.TriggerName = "generic-" + name
+
+	log.WithFields(log.Fields{
+		"webhook":    name,
+		"repository": repo.Name,
+		"tag":        repo.Tag,
+		"digest":     repo.Digest,
+	}).Debug("trigger.genericHandler: got event, processing")
+
+	s.trigger(event)
+
+	resp.WriteHeader(http.StatusOK)
+
+	newGenericWebhooksCounter.With(prometheus.Labels{"webhook": name, "image": event.Repository.Name}).Inc()
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var fakeGenericWebhooks = `
+- name: jfrog
+  repository: 'jfrog.example.com/{{ .data.repo_key }}/{{ lower .data.image_name }}'
+  tag: '{.data.tag}'
+  digest: '{.data.sha256}'
+  secret: s3cret
+- name: ci
+  repository: '{.build.image}'
+  tag: '{.build.number}'
+`
+
+func loadTestGenericWebhooks(t *testing.T, config string) []*GenericWebhook {
+	dir, err := ioutil.TempDir("", "keel-generic-webhooks")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "webhooks.yaml")
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %s", err)
+	}
+	webhooks, err := LoadGenericWebhooks(path)
+	if err != nil {
+		t.Fatalf("failed to load webhooks: %s", err)
+	}
+	return webhooks
+}
+
+func TestGenericWebhookHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	for _, w := range loadTestGenericWebhooks(t, fakeGenericWebhooks) {
+		srv.genericWebhooks[w.Name] = w
+	}
+
+	jfrog := `{"domain": "docker", "event_type": "pushed", "data": {"repo_key": "docker-local", "image_name": "Hello-World", "tag": "1.2.3", "sha256": "sha256:5b2d4e4a"}}`
+
+	for _, tc := range []struct {
+		path    string
+		payload string
+		code    int
+	}{
+		{path: "/v1/webhooks/generic/unknown", payload: `{}`, code: 404},
+		{path: "/v1/webhooks/generic/jfrog", payload: jfrog, code: 401},
+		{path: "/v1/webhooks/generic/jfrog?secret=s3cret", payload: jfrog, code: 200},
+		{path: "/v1/webhooks/generic/jfrog?secret=s3cret", payload: `{"data": {"tag": "1.2.3"}}`, code: 400},
+		{path: "/v1/webhooks/generic/ci", payload: `{"build": {"image": "karolisr/keel", "number": 1234}}`, code: 200},
+		{path: "/v1/webhooks/generic/ci", payload: `{"build": {"image": "", "number": 1234}}`, code: 400},
+	} {
+		req, err := http.NewRequest("POST", tc.path, bytes.NewBuffer([]byte(tc.payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s (%s): unexpected status code: %d, expected: %d, body: %s", tc.path, tc.payload, rec.Code, tc.code, rec.Body.String())
+		}
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Name != "jfrog.example.com/docker-local/hello-world" {
+		t.Errorf("unexpected repository: %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "1.2.3" || fp.submitted[0].Repository.Digest != "sha256:5b2d4e4a" {
+		t.Errorf("unexpected tag or digest: %s, %s", fp.submitted[0].Repository.Tag, fp.submitted[0].Repository.Digest)
+	}
+	if fp.submitted[0].TriggerName != "generic-jfrog" {
+		t.Errorf("unexpected trigger name: %s", fp.submitted[0].TriggerName)
+	}
+	if fp.submitted[1].Repository.Name != "karolisr/keel" || fp.submitted[1].Repository.Tag != "1234" {
+		t.Errorf("unexpected repository: %s:%s", fp.submitted[1].Repository.Name, fp.submitted[1].Repository.Tag)
+	}
+}
+
+func TestLoadGenericWebhooksInvalid(t *testing.T) {
+	for _, config := range []string{
+		`[{"name": "x", "repository": "{.image"}]`,
+		`[{"name": "x", "repository": "{.image}"}]`,
+		`[{"repository": "{.image}", "tag": "{.tag}"}]`,
+		`[{"name": "x", "repository": "{{ .image", "tag": "{.tag}"}]`,
+		`[{"name": "x", "repository": "{.image}", "tag": "{.tag}"}, {"name": "x", "repository": "{.image}", "tag": "{.tag}"}]`,
+	} {
+		dir, err := ioutil.TempDir("", "keel-generic-webhooks")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %s", err)
+		}
+		path := filepath.Join(dir, "webhooks.json")
+		ioutil.WriteFile(path, []byte(config), 0644)
+		if _, err := LoadGenericWebhooks(path); err == nil {
+			t.Errorf("expected error for config: %s", config)
+		}
+		os.RemoveAll(dir)
+	}
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -26,14 +30,37 @@ func init() {
 	prometheus.MustRegister(newGithubWebhooksCounter)
 }
 
-type githubWebhook struct {
-	Action          string `json:"action"`
-	RegistryPackage struct {
-		CreatedAt string `json:"created_at"`
-		HTMLURL   string `json:"html_url"`
-		ID        int    `json:"id"`
-		Name      string `json:"name"`
-		Owner     struct {
+// githubPackage - package of "registry_package" and "package" events, container
+// packages (ghcr.io) carry the pushed tag in container metadata of the version
+type githubPackage struct {
+	CreatedAt string `json:"created_at"`
+	HTMLURL   string `json:"html_url"`
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Owner     struct {
+		AvatarURL         string `json:"avatar_url"`
+		EventsURL         string `json:"events_url"`
+		FollowersURL      string `json:"followers_url"`
+		FollowingURL      string `json:"following_url"`
+		GistsURL          string `json:"gists_url"`
+		GravatarID        string `json:"gravatar_id"`
+		HTMLURL           string `json:"html_url"`
+		ID                int    `json:"id"`
+		Login             string `json:"login"`
+		NodeID            string `json:"node_id"`
+		OrganizationsURL  string `json:"organizations_url"`
+		ReceivedEventsURL string `json:"received_events_url"`
+		ReposURL          string `json:"repos_url"`
+		SiteAdmin         bool   `json:"site_admin"`
+		StarredURL        string `json:"starred_url"`
+		SubscriptionsURL  string `json:"subscriptions_url"`
+		Type              string `json:"type"`
+		URL               string `json:"url"`
+	} `json:"owner"`
+	PackageType    string `json:"package_type"`
+	PackageVersion struct {
+		Author struct {
 			AvatarURL         string `json:"avatar_url"`
 			EventsURL         string `json:"events_url"`
 			FollowersURL      string `json:"followers_url"`
@@ -52,65 +79,56 @@ type githubWebhook struct {
 			SubscriptionsURL  string `json:"subscriptions_url"`
 			Type              string `json:"type"`
 			URL               string `json:"url"`
-		} `json:"owner"`
-		PackageType    string `json:"package_type"`
-		PackageVersion struct {
-			Author struct {
-				AvatarURL         string `json:"avatar_url"`
-				EventsURL         string `json:"events_url"`
-				FollowersURL      string `json:"followers_url"`
-				FollowingURL      string `json:"following_url"`
-				GistsURL          string `json:"gists_url"`
-				GravatarID        string `json:"gravatar_id"`
-				HTMLURL           string `json:"html_url"`
-				ID                int    `json:"id"`
-				Login             string `json:"login"`
-				NodeID            string `json:"node_id"`
-				OrganizationsURL  string `json:"organizations_url"`
-				ReceivedEventsURL string `json:"received_events_url"`
-				ReposURL          string `json:"repos_url"`
-				SiteAdmin         bool   `json:"site_admin"`
-				StarredURL        string `json:"starred_url"`
-				SubscriptionsURL  string `json:"subscriptions_url"`
-				Type              string `json:"type"`
-				URL               string `json:"url"`
-			} `json:"author"`
-			Body                string        `json:"body"`
-			BodyHTML            string        `json:"body_html"`
-			CreatedAt           string        `json:"created_at"`
-			HTMLURL             string        `json:"html_url"`
-			ID                  int           `json:"id"`
-			InstallationCommand string        `json:"installation_command"`
-			Manifest            string        `json:"manifest"`
-			Metadata            []interface{} `json:"metadata"`
-			PackageFiles        []struct {
-				ContentType string      `json:"content_type"`
-				CreatedAt   string      `json:"created_at"`
-				DownloadURL string      `json:"download_url"`
-				ID          int         `json:"id"`
-				Md5         interface{} `json:"md5"`
-				Name        string      `json:"name"`
-				Sha1        interface{} `json:"sha1"`
-				Sha256      string      `json:"sha256"`
-				Size        int         `json:"size"`
-				State       string      `json:"state"`
-				UpdatedAt   string      `json:"updated_at"`
-			} `json:"package_files"`
-			Summary         string `json:"summary"`
-			TargetCommitish string `json:"target_commitish"`
-			TargetOid       string `json:"target_oid"`
-			UpdatedAt       string `json:"updated_at"`
-			Version         string `json:"version"`
-		} `json:"package_version"`
-		Registry struct {
-			AboutURL string `json:"about_url"`
-			Name     string `json:"name"`
-			Type     string `json:"type"`
-			URL      string `json:"url"`
-			Vendor   string `json:"vendor"`
-		} `json:"registry"`
-		UpdatedAt string `json:"updated_at"`
-	} `json:"registry_package"`
+		} `json:"author"`
+		Body                string        `json:"body"`
+		BodyHTML            string        `json:"body_html"`
+		CreatedAt           string        `json:"created_at"`
+		HTMLURL             string        `json:"html_url"`
+		ID                  int           `json:"id"`
+		InstallationCommand string        `json:"installation_command"`
+		Manifest            string        `json:"manifest"`
+		Metadata            []interface{} `json:"metadata"`
+		PackageFiles        []struct {
+			ContentType string      `json:"content_type"`
+			CreatedAt   string      `json:"created_at"`
+			DownloadURL string      `json:"download_url"`
+			ID          int         `json:"id"`
+			Md5         interface{} `json:"md5"`
+			Name        string      `json:"name"`
+			Sha1        interface{} `json:"sha1"`
+			Sha256      string      `json:"sha256"`
+			Size        int         `json:"size"`
+			State       string      `json:"state"`
+			UpdatedAt   string      `json:"updated_at"`
+		} `json:"package_files"`
+		Summary         string `json:"summary"`
+		TargetCommitish string `json:"target_commitish"`
+		TargetOid       string `json:"target_oid"`
+		UpdatedAt       string `json:"updated_at"`
+		Version         string `json:"version"`
+		// ContainerMetadata - set for container packages, version is the manifest digest
+		ContainerMetadata struct {
+			Tag struct {
+				Name   string `json:"name"`
+				Digest string `json:"digest"`
+			} `json:"tag"`
+		} `json:"container_metadata"`
+	} `json:"package_version"`
+	Registry struct {
+		AboutURL string `json:"about_url"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		URL      string `json:"url"`
+		Vendor   string `json:"vendor"`
+	} `json:"registry"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type githubWebhook struct {
+	Action          string        `json:"action"`
+	RegistryPackage githubPackage `json:"registry_package"`
+	// Package - "package" events, sent for GitHub Container Registry packages
+	Package    githubPackage `json:"package"`
 	Repository struct {
 		ArchiveURL       string      `json:"archive_url"`
 		Archived         bool        `json:"archived"`
@@ -227,48 +245,118 @@ type githubWebhook struct {
 	} `json:"sender"`
 }
 
+// githubSignatureHeader - HMAC SHA256 signature of the payload, sent when webhook has a secret
+const githubSignatureHeader = "X-Hub-Signature-256"
+
+// validGithubSignature - checks "sha256=<hex>" signature of the payload
+func validGithubSignature(signature string, payload []byte, secret string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
 // githubHandler - used to react to github webhooks
 func (s *TriggerServer) githubHandler(resp http.ResponseWriter, req *http.Request) {
-	gw := githubWebhook{}
-	if err := json.NewDecoder(req.Body).Decode(&gw); err != nil {
-		log.WithFields(log.Fields{
-			"error": err,
-		}).Error("trigger.githubHandler: failed to decode request")
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
 		resp.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if gw.RegistryPackage.PackageType != "docker" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "registry package type was not docker")
+	if secret := s.secrets().githubWebhookSecret; secret != "" && !validGithubSignature(req.Header.Get(githubSignatureHeader), payload, secret) {
+		log.Warn("trigger.githubHandler: invalid webhook signature")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
 	}
 
-	if gw.Repository.FullName == "" { // github package name
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "repository full name cannot be empty")
+	// sent when webhook is created
+	if req.Header.Get("X-GitHub-Event") == "ping" {
+		resp.WriteHeader(http.StatusOK)
 		return
 	}
 
-	if gw.RegistryPackage.Name == "" { // github package name
+	gw := githubWebhook{}
+	if err := json.Unmarshal(payload, &gw); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.githubHandler: failed to decode request")
 		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "repository package name cannot be empty")
 		return
 	}
 
-	if gw.RegistryPackage.PackageVersion.Version == "" { // tag
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "repository tag cannot be empty")
+	if gw.Action != "" && gw.Action != "published" {
+		resp.WriteHeader(http.StatusOK)
 		return
 	}
 
+	pkg := gw.RegistryPackage
+	if pkg.Name == "" {
+		pkg = gw.Package
+	}
+
 	event := types.Event{}
 	event.CreatedAt = time.Now()
 	event.TriggerName = "github"
-	event.Repository.Name = strings.Join(
-		[]string{"docker.pkg.github.com", gw.Repository.FullName, gw.RegistryPackage.Name},
-		"/",
-	)
-	event.Repository.Tag = gw.RegistryPackage.PackageVersion.Version
+
+	switch strings.ToLower(pkg.PackageType) {
+	case "docker":
+		if gw.Repository.FullName == "" { // github package name
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "repository full name cannot be empty")
+			return
+		}
+
+		if pkg.Name == "" { // github package name
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "repository package name cannot be empty")
+			return
+		}
+
+		if pkg.PackageVersion.Version == "" { // tag
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "repository tag cannot be empty")
+			return
+		}
+
+		event.Repository.Name = strings.Join(
+			[]string{"docker.pkg.github.com", gw.Repository.FullName, pkg.Name},
+			"/",
+		)
+		event.Repository.Tag = pkg.PackageVersion.Version
+	case "container":
+		owner := pkg.Namespace
+		if owner == "" {
+			owner = pkg.Owner.Login
+		}
+		if owner == "" || pkg.Name == "" {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "package owner and name cannot be empty")
+			return
+		}
+
+		// untagged manifests, i.e. platform images of multi-arch pushes
+		tag := pkg.PackageVersion.ContainerMetadata.Tag
+		if tag.Name == "" {
+			resp.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// ghcr.io image names are lowercase
+		event.Repository.Name = strings.ToLower("ghcr.io/" + owner + "/" + pkg.Name)
+		event.Repository.Tag = tag.Name
+		event.Repository.Digest = tag.Digest
+	default:
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "registry package type was not docker or container")
+		return
+	}
 
 	s.trigger(event)
 
@@ -2,7 +2,11 @@ package http
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"strings"
 
 	"net/http/httptest"
 	"testing"
@@ -272,3 +276,89 @@ func TestGithubWebhookHandler(t *testing.T) {
 		t.Errorf("expected 1.2.3 but got %s", fp.submitted[0].Repository.Tag)
 	}
 }
+
+var fakeGithubContainerWebhook = `{
+  "action": "published",
+  "package": {
+    "id": 1710218,
+    "name": "UtaiteBOX",
+    "namespace": "DingGGu",
+    "package_type": "CONTAINER",
+    "html_url": "https://github.com/users/DingGGu/packages/container/package/UtaiteBOX",
+    "owner": {
+      "login": "DingGGu",
+      "type": "User"
+    },
+    "package_version": {
+      "id": 4569283,
+      "version": "sha256:23d1a4ec2e1b8ea3dc7e3a63f44b1ae6b4bd9cdd24e2d8e2e0c48a6c6a5e3b8d",
+      "container_metadata": {
+        "tag": {
+          "name": "1.2.3",
+          "digest": "sha256:23d1a4ec2e1b8ea3dc7e3a63f44b1ae6b4bd9cdd24e2d8e2e0c48a6c6a5e3b8d"
+        }
+      }
+    },
+    "registry": {
+      "about_url": "https://docs.github.com/packages/learn-github-packages/introduction-to-github-packages",
+      "name": "GitHub CONTAINER registry",
+      "type": "CONTAINER",
+      "url": "https://ghcr.io/DingGGu",
+      "vendor": "GitHub Inc"
+    }
+  }
+}`
+
+func signGithubPayload(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGithubWebhookHandlerContainer(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.githubWebhookSecret = "s3cret"
+
+	untagged := strings.Replace(fakeGithubContainerWebhook, `"name": "1.2.3"`, `"name": ""`, 1)
+
+	for _, tc := range []struct {
+		name      string
+		payload   string
+		signature string
+		code      int
+	}{
+		{name: "missing signature", payload: fakeGithubContainerWebhook, code: 401},
+		{name: "wrong secret", payload: fakeGithubContainerWebhook, signature: signGithubPayload(fakeGithubContainerWebhook, "wrong"), code: 401},
+		{name: "untagged", payload: untagged, signature: signGithubPayload(untagged, "s3cret"), code: 200},
+		{name: "tagged", payload: fakeGithubContainerWebhook, signature: signGithubPayload(fakeGithubContainerWebhook, "s3cret"), code: 200},
+	} {
+		req, err := http.NewRequest("POST", "/v1/webhooks/github", bytes.NewBuffer([]byte(tc.payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.Header.Set("X-GitHub-Event", "package")
+		if tc.signature != "" {
+			req.Header.Set(githubSignatureHeader, tc.signature)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: unexpected status code: %d, expected: %d", tc.name, rec.Code, tc.code)
+		}
+	}
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Name != "ghcr.io/dingggu/utaitebox" {
+		t.Errorf("expected ghcr.io/dingggu/utaitebox but got %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "1.2.3" {
+		t.Errorf("expected 1.2.3 but got %s", fp.submitted[0].Repository.Tag)
+	}
+	if fp.submitted[0].Repository.Digest != "sha256:23d1a4ec2e1b8ea3dc7e3a63f44b1ae6b4bd9cdd24e2d8e2e0c48a6c6a5e3b8d" {
+		t.Errorf("unexpected digest: %s", fp.submitted[0].Repository.Digest)
+	}
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var newGitlabWebhooksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitlab_webhook_requests_total",
+		Help: "How many /v1/webhooks/gitlab requests processed, partitioned by image.",
+	},
+	[]string{"image"},
+)
+
+func init() {
+	prometheus.MustRegister(newGitlabWebhooksCounter)
+}
+
+// gitlabTokenHeader - GitLab sends "Secret token" of the webhook in this header
+const gitlabTokenHeader = "X-Gitlab-Token"
+
+// defaultGitlabRegistry - registry of gitlab.com projects
+const defaultGitlabRegistry = "registry.gitlab.com"
+
+// Example of GitLab pipeline event (trimmed)
+// {
+//     "object_kind": "pipeline",
+//     "object_attributes": {
+//         "id": 31,
+//         "ref": "v1.2.3",
+//         "tag": true,
+//         "sha": "bcbb5ec396a2c0f828686f14fac9b80b780504f2",
+//         "status": "success"
+//     },
+//     "project": {
+//         "id": 1,
+//         "name": "Gitlab Test",
+//         "path_with_namespace": "gitlab-org/gitlab-test",
+//         "web_url": "http://192.168.64.1:3005/gitlab-org/gitlab-test"
+//     }
+// }
+//
+// Images are expected to be pushed as $CI_REGISTRY_IMAGE:$CI_COMMIT_TAG for tag pipelines
+// and $CI_REGISTRY_IMAGE:$CI_COMMIT_REF_SLUG for branch pipelines, "image" query parameter
+// selects an image under the project path, i.e. $CI_REGISTRY_IMAGE/<image>
+
+type gitlabWebhook struct {
+	ObjectKind       string `json:"object_kind"`
+	ObjectAttributes struct {
+		ID     int    `json:"id"`
+		Ref    string `json:"ref"`
+		Tag    bool   `json:"tag"`
+		Sha    string `json:"sha"`
+		Status string `json:"status"`
+	} `json:"object_attributes"`
+	Project struct {
+		ID                int    `json:"id"`
+		Name              string `json:"name"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+}
+
+// gitlabRefSlug - same as $CI_COMMIT_REF_SLUG: lowercased ref shortened to 63 bytes with
+// everything except 0-9 and a-z replaced with -, no leading or trailing -
+func gitlabRefSlug(ref string) string {
+	slug := []byte(strings.ToLower(ref))
+	for i, c := range slug {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			slug[i] = '-'
+		}
+	}
+	if len(slug) > 63 {
+		slug = slug[:63]
+	}
+	return strings.Trim(string(slug), "-")
+}
+
+// gitlabRepository - registry path of the project image, GitLab registry paths are lowercase
+func gitlabRepository(registry, project, name string) string {
+	repository := registry + "/" + strings.ToLower(project)
+	if name = strings.Trim(name, "/"); name != "" {
+		repository += "/" + strings.ToLower(name)
+	}
+	return repository
+}
+
+func (s *TriggerServer) gitlabHandler(resp http.ResponseWriter, req *http.Request) {
+	if token := s.secrets().gitlabWebhookToken; token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get(gitlabTokenHeader)), []byte(token)) != 1 {
+		log.Warn("trigger.gitlabHandler: invalid webhook token")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	gw := gitlabWebhook{}
+	if err := json.NewDecoder(req.Body).Decode(&gw); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("trigger.gitlabHandler: failed to decode request")
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// only finished pipelines have images pushed
+	if gw.ObjectKind != "pipeline" || gw.ObjectAttributes.Status != "success" {
+		log.WithFields(log.Fields{
+			"kind":   gw.ObjectKind,
+			"status": gw.ObjectAttributes.Status,
+		}).Debug("trigger.gitlabHandler: event ignored")
+		resp.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if gw.Project.PathWithNamespace == "" || gw.ObjectAttributes.Ref == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "project path_with_namespace and ref cannot be empty")
+		return
+	}
+
+	tag := gw.ObjectAttributes.Ref
+	if !gw.ObjectAttributes.Tag {
+		tag = gitlabRefSlug(tag)
+	}
+
+	registry := s.gitlabRegistry
+	if registry == "" {
+		registry = defaultGitlabRegistry
+	}
+	repository := gitlabRepository(registry, gw.Project.PathWithNamespace, req.URL.Query().Get("image"))
+
+	imageRef, err := image.Parse(repository + ":" + tag)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"repository": repository,
+			"tag":        tag,
+		}).Error("trigger.gitlabHandler: failed to parse repository")
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "failed to parse repository %s, error: %s", repository, err)
+		return
+	}
+
+	event := types.Event{}
+	event.CreatedAt = time.Now()
+	event.TriggerName = "gitlab"
+	event.Repository.Name = imageRef.Repository()
+	event.Repository.Tag = imageRef.Tag()
+
+	log.WithFields(log.Fields{
+		"pipeline":   gw.ObjectAttributes.ID,
+		"ref":        gw.ObjectAttributes.Ref,
+		"tag":        event.Repository.Tag,
+		"repository": event.Repository.Name,
+	}).Debug("trigger.gitlabHandler: got pipeline event, processing")
+
+	s.trigger(event)
+	newGitlabWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+
+	resp.WriteHeader(http.StatusOK)
+}
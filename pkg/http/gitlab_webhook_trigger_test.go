@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var fakeGitlabPipelineWebhook = `{
+    "object_kind": "pipeline",
+    "object_attributes": {
+        "id": 31,
+        "ref": "%s",
+        "tag": %t,
+        "sha": "bcbb5ec396a2c0f828686f14fac9b80b780504f2",
+        "status": "%s"
+    },
+    "project": {
+        "id": 1,
+        "name": "Gitlab Test",
+        "path_with_namespace": "Gitlab-Org/gitlab-test",
+        "web_url": "http://192.168.64.1:3005/gitlab-org/gitlab-test"
+    }
+}`
+
+func TestGitlabWebhookHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.gitlabWebhookToken = "s3cret"
+	srv.gitlabRegistry = "registry.example.com:5050"
+
+	for _, tc := range []struct {
+		path    string
+		token   string
+		payload string
+		code    int
+	}{
+		{path: "/v1/webhooks/gitlab", token: "", payload: fmt.Sprintf(fakeGitlabPipelineWebhook, "v1.2.3", true, "success"), code: 401},
+		{path: "/v1/webhooks/gitlab", token: "wrong", payload: fmt.Sprintf(fakeGitlabPipelineWebhook, "v1.2.3", true, "success"), code: 401},
+		{path: "/v1/webhooks/gitlab", token: "s3cret", payload: fmt.Sprintf(fakeGitlabPipelineWebhook, "v1.2.3", true, "running"), code: 200},
+		{path: "/v1/webhooks/gitlab", token: "s3cret", payload: `{"object_kind": "push"}`, code: 200},
+		{path: "/v1/webhooks/gitlab", token: "s3cret", payload: fmt.Sprintf(fakeGitlabPipelineWebhook, "v1.2.3", true, "success"), code: 200},
+		{path: "/v1/webhooks/gitlab?image=api", token: "s3cret", payload: fmt.Sprintf(fakeGitlabPipelineWebhook, "Feature/New_UI", false, "success"), code: 200},
+	} {
+		req, err := http.NewRequest("POST", tc.path, bytes.NewBuffer([]byte(tc.payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		if tc.token != "" {
+			req.Header.Set(gitlabTokenHeader, tc.token)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s (token %q): unexpected status code: %d, expected: %d", tc.path, tc.token, rec.Code, tc.code)
+		}
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Name != "registry.example.com:5050/gitlab-org/gitlab-test" {
+		t.Errorf("unexpected repository: %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "v1.2.3" {
+		t.Errorf("expected v1.2.3 but got %s", fp.submitted[0].Repository.Tag)
+	}
+	if fp.submitted[1].Repository.Name != "registry.example.com:5050/gitlab-org/gitlab-test/api" {
+		t.Errorf("unexpected repository: %s", fp.submitted[1].Repository.Name)
+	}
+	if fp.submitted[1].Repository.Tag != "feature-new-ui" {
+		t.Errorf("expected feature-new-ui but got %s", fp.submitted[1].Repository.Tag)
+	}
+}
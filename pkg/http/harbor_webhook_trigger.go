@@ -1,9 +1,11 @@
 package http
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/types"
@@ -47,6 +49,10 @@ func init() {
 //             "repo_type": "private"
 //         }
 //     }
+// }
+//
+// Harbor 2.x sends the same payload with "type": "PUSH_ARTIFACT", resources pushed
+// by digest have an empty tag and <url>/<namespace>/<repo>@<digest> resource URL
 
 type harborWebhook struct {
 	Type      string `json:"type"`
@@ -68,7 +74,26 @@ type harborWebhook struct {
 	} `json:"event_data"`
 }
 
+// harbor event types, pushImage is sent by Harbor 1.x
+const (
+	harborEventPushImage    = "pushImage"
+	harborEventPushArtifact = "PUSH_ARTIFACT"
+)
+
+// validHarborAuthHeader - Harbor sends "Auth Header" of the webhook policy verbatim as
+// Authorization header. With authenticated webhooks it has to be the basic auth header
+// of the admin user as well
+func validHarborAuthHeader(req *http.Request, authHeader string) bool {
+	return subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte(authHeader)) == 1
+}
+
 func (s *TriggerServer) harborHandler(resp http.ResponseWriter, req *http.Request) {
+	if header := s.secrets().harborAuthHeader; header != "" && !validHarborAuthHeader(req, header) {
+		log.Warn("trigger.harborHandler: invalid auth header")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	hn := harborWebhook{}
 	if err := json.NewDecoder(req.Body).Decode(&hn); err != nil {
 		log.WithFields(log.Fields{
@@ -81,38 +106,55 @@ func (s *TriggerServer) harborHandler(resp http.ResponseWriter, req *http.Reques
 		"event": hn,
 	}).Debug("harborHandler: received event, looking for a pushImage tag")
 
-	if hn.Type == "pushImage" {
-		// go trough all the ressource items
-		for _, e := range hn.EventData.Resources {
-			imageRepo, err := image.Parse(e.ResourceURL)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error":      err,
-					"repository": e.ResourceURL,
-				}).Error("trigger.harborHandler: failed to parse repository")
+	if hn.Type != harborEventPushImage && !strings.EqualFold(hn.Type, harborEventPushArtifact) {
+		resp.WriteHeader(http.StatusOK)
+		return
+	}
 
-				resp.WriteHeader(http.StatusBadRequest)
-				fmt.Fprintf(resp, "failed to parse repository %s, error: %s", e.ResourceURL, err)
-				return
+	// go trough all the ressource items
+	for _, e := range hn.EventData.Resources {
+		resourceURL := e.ResourceURL
+		if idx := strings.Index(resourceURL, "@"); idx > 0 {
+			// artifacts pushed by digest don't have a tag to update to
+			if e.Tag == "" {
+				log.WithFields(log.Fields{
+					"repository": resourceURL,
+					"digest":     e.Digest,
+				}).Debug("harborHandler: artifact without a tag, skipping")
+				continue
 			}
+			resourceURL = resourceURL[:idx] + ":" + e.Tag
+		}
 
-			//create event
-			event := types.Event{}
-			event.CreatedAt = time.Now()
-			event.TriggerName = "harbor"
-			event.Repository.Name = imageRepo.Repository()
-			event.Repository.Tag = imageRepo.Tag()
-
+		imageRepo, err := image.Parse(resourceURL)
+		if err != nil {
 			log.WithFields(log.Fields{
-				"action":     hn.Type,
-				"tag":        imageRepo.Tag(),
-				"repository": imageRepo.Repository(),
-				"digest":     e.Digest,
-			}).Debug("harborHandler: got registry notification, processing")
-
-			s.trigger(event)
-			newHarborWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
+				"error":      err,
+				"repository": e.ResourceURL,
+			}).Error("trigger.harborHandler: failed to parse repository")
+
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "failed to parse repository %s, error: %s", e.ResourceURL, err)
+			return
 		}
+
+		//create event
+		event := types.Event{}
+		event.CreatedAt = time.Now()
+		event.TriggerName = "harbor"
+		event.Repository.Name = imageRepo.Repository()
+		event.Repository.Tag = imageRepo.Tag()
+		event.Repository.Digest = e.Digest
+
+		log.WithFields(log.Fields{
+			"action":     hn.Type,
+			"tag":        imageRepo.Tag(),
+			"repository": imageRepo.Repository(),
+			"digest":     e.Digest,
+		}).Debug("harborHandler: got registry notification, processing")
+
+		s.trigger(event)
+		newHarborWebhooksCounter.With(prometheus.Labels{"image": event.Repository.Name}).Inc()
 	}
 
 	resp.WriteHeader(http.StatusOK)
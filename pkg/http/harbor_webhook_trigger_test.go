@@ -121,3 +121,73 @@ func TestHarborWebhookHandlerMalformed(t *testing.T) {
 		t.Errorf("expected latest but got %s", fp.submitted[0].Repository.Tag)
 	}
 }
+
+var fakeHarborPushArtifactWebhook = `{
+    "type": "PUSH_ARTIFACT",
+    "occur_at": 1586922308,
+    "operator": "admin",
+    "event_data": {
+        "resources": [
+            {
+                "digest": "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8",
+                "tag": "1.2.3",
+                "resource_url": "harbor.example.com/library/repository:1.2.3"
+            },
+            {
+                "digest": "sha256:b4758aaed11c155a476b9857e1178f157759c99cb04c907a04993f5481eff848",
+                "tag": "",
+                "resource_url": "harbor.example.com/library/repository@sha256:b4758aaed11c155a476b9857e1178f157759c99cb04c907a04993f5481eff848"
+            }
+        ],
+        "repository": {
+            "date_created": 1586922308,
+            "name": "repository",
+            "namespace": "library",
+            "repo_full_name": "library/repository",
+            "repo_type": "public"
+        }
+    }
+}`
+
+func TestHarborWebhookHandlerPushArtifact(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.harborAuthHeader = "Bearer s3cret"
+
+	for _, tc := range []struct {
+		header string
+		code   int
+	}{
+		{header: "", code: 401},
+		{header: "Bearer wrong", code: 401},
+		{header: "Bearer s3cret", code: 200},
+	} {
+		req, err := http.NewRequest("POST", "/v1/webhooks/harbor", bytes.NewBuffer([]byte(fakeHarborPushArtifactWebhook)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("auth header %q: unexpected status code: %d, expected: %d", tc.header, rec.Code, tc.code)
+		}
+	}
+
+	// untagged artifact is skipped
+	if len(fp.submitted) != 1 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Name != "harbor.example.com/library/repository" {
+		t.Errorf("expected harbor.example.com/library/repository but got %s", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "1.2.3" {
+		t.Errorf("expected 1.2.3 but got %s", fp.submitted[0].Repository.Tag)
+	}
+	if fp.submitted[0].Repository.Digest != "sha256:8a9e9863dbb6e10edb5adfe917c00da84e1700fa76e7ed02476aa6e6fb8ee0d8" {
+		t.Errorf("unexpected digest: %s", fp.submitted[0].Repository.Digest)
+	}
+}
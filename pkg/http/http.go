@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,6 +17,7 @@ import (
 	"github.com/urfave/negroni"
 
 	"github.com/keel-hq/keel/approvals"
+	"github.com/keel-hq/keel/bot"
 	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/pkg/auth"
 	"github.com/keel-hq/keel/pkg/store"
@@ -47,6 +49,28 @@ type Opts struct {
 	UIDir string
 
 	AuthenticatedWebhooks bool
+
+	// QuayWebhookSecret - optional shared secret Quay notifications have to include
+	QuayWebhookSecret string
+
+	// HarborAuthHeader - optional Authorization header value Harbor notifications have to include
+	HarborAuthHeader string
+
+	// GitlabWebhookToken - optional secret token GitLab webhooks have to include
+	GitlabWebhookToken string
+	// GitlabRegistry - container registry of the GitLab instance, defaults to registry.gitlab.com
+	GitlabRegistry string
+
+	// GithubWebhookSecret - optional secret GitHub webhook payloads are signed with
+	GithubWebhookSecret string
+
+	// GenericWebhooks - webhooks with configured payload mapping, see LoadGenericWebhooks
+	GenericWebhooks []*GenericWebhook
+
+	// NativeWebhookSecret - optional secret native webhook payloads are signed with
+	NativeWebhookSecret string
+	// NativeWebhookTokens - optional tokens of native webhook sources, source name to token
+	NativeWebhookTokens map[string]string
 }
 
 // TriggerServer - webhook trigger & healthcheck server
@@ -66,10 +90,44 @@ type TriggerServer struct {
 	uiDir string
 
 	authenticatedWebhooks bool
+	gitlabRegistry        string
+	genericWebhooks       map[string]*GenericWebhook
+
+	secretsM sync.RWMutex
+	webhookSecrets
+
+	elector provider.Elector
+}
+
+// webhookSecrets - secrets and tokens webhooks are verified with, they can be replaced
+// while the server is running, see SetWebhookSecrets
+type webhookSecrets struct {
+	quayWebhookSecret   string
+	harborAuthHeader    string
+	gitlabWebhookToken  string
+	githubWebhookSecret string
+	nativeWebhookSecret string
+	nativeWebhookTokens map[string]string
+}
+
+func newWebhookSecrets(opts *Opts) webhookSecrets {
+	return webhookSecrets{
+		quayWebhookSecret:   opts.QuayWebhookSecret,
+		harborAuthHeader:    opts.HarborAuthHeader,
+		gitlabWebhookToken:  opts.GitlabWebhookToken,
+		githubWebhookSecret: opts.GithubWebhookSecret,
+		nativeWebhookSecret: opts.NativeWebhookSecret,
+		nativeWebhookTokens: opts.NativeWebhookTokens,
+	}
 }
 
 // NewTriggerServer - create new HTTP trigger based server
 func NewTriggerServer(opts *Opts) *TriggerServer {
+	genericWebhooks := make(map[string]*GenericWebhook)
+	for _, w := range opts.GenericWebhooks {
+		genericWebhooks[w.Name] = w
+	}
+
 	return &TriggerServer{
 		port:                  opts.Port,
 		grc:                   opts.GRC,
@@ -81,9 +139,32 @@ func NewTriggerServer(opts *Opts) *TriggerServer {
 		store:                 opts.Store,
 		uiDir:                 opts.UIDir,
 		authenticatedWebhooks: opts.AuthenticatedWebhooks,
+		gitlabRegistry:        opts.GitlabRegistry,
+		genericWebhooks:       genericWebhooks,
+		webhookSecrets:        newWebhookSecrets(opts),
 	}
 }
 
+// SetWebhookSecrets - replaces webhook secrets and tokens (Quay, Harbor, GitLab, GitHub
+// and native webhook fields of opts), used when they are rotated
+func (s *TriggerServer) SetWebhookSecrets(opts *Opts) {
+	s.secretsM.Lock()
+	defer s.secretsM.Unlock()
+	s.webhookSecrets = newWebhookSecrets(opts)
+}
+
+// secrets - current webhook secrets
+func (s *TriggerServer) secrets() webhookSecrets {
+	s.secretsM.RLock()
+	defer s.secretsM.RUnlock()
+	return s.webhookSecrets
+}
+
+// SetElector - sets leader elector, leadership is reported on /leaderz
+func (s *TriggerServer) SetElector(elector provider.Elector) {
+	s.elector = elector
+}
+
 // Start - start server
 func (s *TriggerServer) Start() error {
 
@@ -125,11 +206,18 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 
 	// health endpoint for k8s to be happy
 	mux.HandleFunc("/healthz", s.healthHandler).Methods("GET", "OPTIONS")
+	// readiness endpoint, followers are ready too so rolling updates can progress
+	mux.HandleFunc("/readyz", s.readyHandler).Methods("GET", "OPTIONS")
+	// leadership endpoint, followers return 503 when leader election is enabled
+	mux.HandleFunc("/leaderz", s.leaderHandler).Methods("GET", "OPTIONS")
 	// version handler
 	mux.HandleFunc("/version", s.versionHandler).Methods("GET", "OPTIONS")
 
 	mux.Handle("/metrics", promhttp.Handler())
 
+	// chat bot interactive callbacks (approval buttons), bots verify request signatures
+	mux.HandleFunc("/v1/bots/{name}/interactions", s.botInteractionHandler).Methods("POST")
+
 	if s.authenticator.Enabled() {
 		log.Info("authentication enabled, setting up admin HTTP handlers")
 		// auth
@@ -145,11 +233,22 @@ func (s *TriggerServer) registerRoutes(mux *mux.Router) {
 		mux.HandleFunc("/v1/approvals", s.requireAdminAuthorization(s.approvalApproveHandler)).Methods("POST", "OPTIONS")
 		// updating required approvals count
 		mux.HandleFunc("/v1/approvals", s.requireAdminAuthorization(s.approvalSetHandler)).Methods("PUT", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}", s.requireAdminAuthorization(s.approvalHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}", s.requireAdminAuthorization(s.approvalDeleteHandler)).Methods("DELETE", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}/approve", s.requireAdminAuthorization(s.approvalVoteHandler(actionApprove))).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/approvals/{id}/reject", s.requireAdminAuthorization(s.approvalVoteHandler(actionReject))).Methods("POST", "OPTIONS")
 
 		// available resources
 		mux.HandleFunc("/v1/resources", s.requireAdminAuthorization(s.resourcesHandler)).Methods("GET", "OPTIONS")
 
 		mux.HandleFunc("/v1/policies", s.requireAdminAuthorization(s.policyUpdateHandler)).Methods("PUT", "OPTIONS")
+		// previewing policy decisions
+		mux.HandleFunc("/v1/policies/explain", s.requireAdminAuthorization(s.policyExplainHandler)).Methods("POST", "OPTIONS")
+
+		// pausing/resuming updates
+		mux.HandleFunc("/v1/pause", s.requireAdminAuthorization(s.pauseStatusHandler)).Methods("GET", "OPTIONS")
+		mux.HandleFunc("/v1/pause", s.requireAdminAuthorization(s.pauseHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/resume", s.requireAdminAuthorization(s.resumeHandler)).Methods("POST", "OPTIONS")
 
 		// tracked images
 		mux.HandleFunc("/v1/tracked", s.requireAdminAuthorization(s.trackedHandler)).Methods("GET", "OPTIONS")
@@ -184,6 +283,9 @@ func (s *TriggerServer) registerWebhookRoutes(mux *mux.Router) {
 		mux.HandleFunc("/v1/webhooks/azure", s.requireAdminAuthorization(s.azureHandler)).Methods("POST", "OPTIONS")
 		mux.HandleFunc("/v1/webhooks/github", s.requireAdminAuthorization(s.githubHandler)).Methods("POST", "OPTIONS")
 		mux.HandleFunc("/v1/webhooks/harbor", s.requireAdminAuthorization(s.harborHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/gitlab", s.requireAdminAuthorization(s.gitlabHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/generic/{name}", s.requireAdminAuthorization(s.genericHandler)).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/cloudevents", s.requireAdminAuthorization(s.cloudEventsHandler)).Methods("POST", "OPTIONS")
 
 		// Docker registry notifications, used by Docker, Gitlab, Harbor
 		// https://docs.docker.com/registry/notifications/
@@ -196,6 +298,9 @@ func (s *TriggerServer) registerWebhookRoutes(mux *mux.Router) {
 		mux.HandleFunc("/v1/webhooks/azure", s.azureHandler).Methods("POST", "OPTIONS")
 		mux.HandleFunc("/v1/webhooks/github", s.githubHandler).Methods("POST", "OPTIONS")
 		mux.HandleFunc("/v1/webhooks/harbor", s.harborHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/gitlab", s.gitlabHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/generic/{name}", s.genericHandler).Methods("POST", "OPTIONS")
+		mux.HandleFunc("/v1/webhooks/cloudevents", s.cloudEventsHandler).Methods("POST", "OPTIONS")
 
 		// Docker registry notifications, used by Docker, Gitlab, Harbor
 		// https://docs.docker.com/registry/notifications/
@@ -204,10 +309,31 @@ func (s *TriggerServer) registerWebhookRoutes(mux *mux.Router) {
 	}
 }
 
+func (s *TriggerServer) botInteractionHandler(resp http.ResponseWriter, req *http.Request) {
+	handler := bot.InteractionHandler(mux.Vars(req)["name"])
+	if handler == nil {
+		http.Error(resp, "bot interactivity is not configured", http.StatusNotFound)
+		return
+	}
+	handler.ServeHTTP(resp, req)
+}
+
 func (s *TriggerServer) healthHandler(resp http.ResponseWriter, req *http.Request) {
 	resp.WriteHeader(http.StatusOK)
 }
 
+func (s *TriggerServer) readyHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.WriteHeader(http.StatusOK)
+}
+
+func (s *TriggerServer) leaderHandler(resp http.ResponseWriter, req *http.Request) {
+	if s.elector != nil && !s.elector.IsLeader() {
+		http.Error(resp, "not a leader", http.StatusServiceUnavailable)
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
 func (s *TriggerServer) versionHandler(resp http.ResponseWriter, req *http.Request) {
 	v := version.GetKeelVersion()
 
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeElector struct {
+	leader bool
+}
+
+func (e *fakeElector) IsLeader() bool {
+	return e.leader
+}
+
+func TestLeaderHandler(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	elector := &fakeElector{}
+	srv.SetElector(elector)
+
+	for _, tt := range []struct {
+		leader bool
+		code   int
+	}{
+		{leader: false, code: http.StatusServiceUnavailable},
+		{leader: true, code: http.StatusOK},
+	} {
+		elector.leader = tt.leader
+
+		req, err := http.NewRequest("GET", "/leaderz", nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Errorf("leader=%v: unexpected status code: %d", tt.leader, rec.Code)
+		}
+
+		// followers are ready so rolling updates don't wait for leadership
+		req, err = http.NewRequest("GET", "/readyz", nil)
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		rec = httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("leader=%v: unexpected readiness status code: %d", tt.leader, rec.Code)
+		}
+	}
+}
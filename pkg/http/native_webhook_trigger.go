@@ -1,9 +1,15 @@
 package http
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/types"
@@ -24,10 +30,88 @@ func init() {
 	prometheus.MustRegister(newNativeWebhooksCounter)
 }
 
+// native webhook authentication headers, signature is "sha256=<hex>" HMAC of the payload
+const (
+	nativeSignatureHeader = "X-Keel-Signature"
+	nativeTokenHeader     = "X-Keel-Token"
+)
+
+// ParseWebhookTokens - parses comma separated list of <source>=<token> entries
+func ParseWebhookTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid webhook token entry '%s', expected <source>=<token>", entry)
+		}
+		if _, ok := tokens[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicate webhook token source '%s'", parts[0])
+		}
+		tokens[parts[0]] = parts[1]
+	}
+	return tokens, nil
+}
+
+// validNativeSignature - checks "sha256=<hex>" HMAC signature of the payload
+func validNativeSignature(signature string, payload []byte, secret string) bool {
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
+// nativeTokenSource - returns source the token was issued for
+func nativeTokenSource(token string, tokens map[string]string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	found := ""
+	for source, t := range tokens {
+		// going through all tokens so the response time doesn't depend on the match
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			found = source
+		}
+	}
+	return found, found != ""
+}
+
+// authenticateNative - when secret or tokens are configured, requests have to be either
+// signed with the secret or carry one of the source tokens
+func (s *TriggerServer) authenticateNative(req *http.Request, payload []byte) (source string, ok bool) {
+	secrets := s.secrets()
+	if secrets.nativeWebhookSecret == "" && len(secrets.nativeWebhookTokens) == 0 {
+		return "", true
+	}
+	if secrets.nativeWebhookSecret != "" && validNativeSignature(req.Header.Get(nativeSignatureHeader), payload, secrets.nativeWebhookSecret) {
+		return "", true
+	}
+	return nativeTokenSource(req.Header.Get(nativeTokenHeader), secrets.nativeWebhookTokens)
+}
+
 // nativeHandler - used to trigger event directly
 func (s *TriggerServer) nativeHandler(resp http.ResponseWriter, req *http.Request) {
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	source, ok := s.authenticateNative(req, payload)
+	if !ok {
+		log.Warn("trigger.nativeHandler: invalid signature or token")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	repo := types.Repository{}
-	if err := json.NewDecoder(req.Body).Decode(&repo); err != nil {
+	if err := json.Unmarshal(payload, &repo); err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("failed to decode request")
@@ -52,6 +136,15 @@ func (s *TriggerServer) nativeHandler(resp http.ResponseWriter, req *http.Reques
 	event.Repository = repo
 	event.CreatedAt = time.Now()
 	event.TriggerName = "native"
+
+	if source != "" {
+		log.WithFields(log.Fields{
+			"source":     source,
+			"repository": repo.Name,
+			"tag":        repo.Tag,
+		}).Debug("trigger.nativeHandler: got event, processing")
+	}
+
 	s.trigger(event)
 
 	resp.WriteHeader(http.StatusOK)
@@ -2,6 +2,9 @@ package http
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -85,6 +88,11 @@ func (p *fakeProvider) List() []string {
 func (p *fakeProvider) Stop() {
 	return
 }
+func (p *fakeProvider) Pause()  {}
+func (p *fakeProvider) Resume() {}
+func (p *fakeProvider) Paused() bool {
+	return false
+}
 func (p *fakeProvider) GetName() string {
 	return "fp"
 }
@@ -136,3 +144,61 @@ func TestNativeWebhookHandlerNoRepoName(t *testing.T) {
 	}
 
 }
+
+func TestNativeWebhookHandlerAuthentication(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.nativeWebhookSecret = "s3cret"
+	srv.nativeWebhookTokens = map[string]string{"jenkins": "t0ken"}
+
+	payload := `{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		code    int
+	}{
+		{name: "no credentials", code: 401},
+		{name: "wrong signature", headers: map[string]string{nativeSignatureHeader: "sha256=00"}, code: 401},
+		{name: "unknown token", headers: map[string]string{nativeTokenHeader: "other"}, code: 401},
+		{name: "signature", headers: map[string]string{nativeSignatureHeader: signature}, code: 200},
+		{name: "token", headers: map[string]string{nativeTokenHeader: "t0ken"}, code: 200},
+	} {
+		req, err := http.NewRequest("POST", "/v1/webhooks/native", bytes.NewBuffer([]byte(payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s: unexpected status code: %d, expected: %d", tc.name, rec.Code, tc.code)
+		}
+	}
+
+	if len(fp.submitted) != 2 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+}
+
+func TestParseWebhookTokens(t *testing.T) {
+	tokens, err := ParseWebhookTokens(" jenkins=abc, gitlab=d=ef ,")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tokens) != 2 || tokens["jenkins"] != "abc" || tokens["gitlab"] != "d=ef" {
+		t.Errorf("unexpected tokens: %v", tokens)
+	}
+
+	for _, invalid := range []string{"jenkins", "=abc", "jenkins=", "a=1,a=2"} {
+		if _, err := ParseWebhookTokens(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+)
+
+type pauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+func (s *TriggerServer) pauseStatusHandler(resp http.ResponseWriter, req *http.Request) {
+	response(&pauseResponse{Paused: s.providers.Paused()}, 200, nil, resp, req)
+}
+
+func (s *TriggerServer) pauseHandler(resp http.ResponseWriter, req *http.Request) {
+	s.providers.Pause()
+	response(&pauseResponse{Paused: true}, 200, nil, resp, req)
+}
+
+func (s *TriggerServer) resumeHandler(resp http.ResponseWriter, req *http.Request) {
+	s.providers.Resume()
+	response(&pauseResponse{Paused: false}, 200, nil, resp, req)
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPauseResume(t *testing.T) {
+
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.SetBasicAuth("user-1", "secret")
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != 200 {
+			t.Fatalf("unexpected status code for %s %s: %d", method, path, rec.Code)
+		}
+		return rec
+	}
+
+	do("POST", "/v1/pause", nil)
+
+	var status pauseResponse
+	rec := do("GET", "/v1/pause", nil)
+	err := json.Unmarshal(rec.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatalf("failed to unmarshal response: %s", err)
+	}
+	if !status.Paused {
+		t.Errorf("expected updates to be paused")
+	}
+
+	do("POST", "/v1/webhooks/native", []byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`))
+	do("POST", "/v1/webhooks/native", []byte(`{"name": "gcr.io/v2-namespace/hello-world", "tag": "1.1.1"}`))
+
+	if len(fp.submitted) != 0 {
+		t.Fatalf("expected no events to be submitted while paused, got: %d", len(fp.submitted))
+	}
+
+	do("POST", "/v1/resume", nil)
+
+	if len(fp.submitted) != 1 {
+		t.Fatalf("expected recorded event to be replayed once, got: %d", len(fp.submitted))
+	}
+	if fp.submitted[0].Repository.Tag != "1.1.1" {
+		t.Errorf("unexpected tag: %s", fp.submitted[0].Repository.Tag)
+	}
+	if srv.providers.Paused() {
+		t.Errorf("expected updates to be resumed")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 )
 
 type resourcePolicyUpdateRequest struct {
@@ -54,3 +56,63 @@ func (s *TriggerServer) policyUpdateHandler(resp http.ResponseWriter, req *http.
 	fmt.Fprintf(resp, "resource with identifier '%s' not found", policyRequest.Identifier)
 	return
 }
+
+type policyExplainRequest struct {
+	// Image - image name, tag is used as current tag unless currentTag is set
+	Image      string `json:"image"`
+	CurrentTag string `json:"currentTag"`
+	NewTag     string `json:"newTag"`
+	Policy     string `json:"policy"`
+
+	MatchTag        bool     `json:"matchTag"`
+	MatchPreRelease *bool    `json:"matchPreRelease"`
+	AllowedTags     []string `json:"allowedTags"`
+	BlockedTags     []string `json:"blockedTags"`
+}
+
+// policyExplainHandler - evaluates policy without touching any resources and returns
+// the decision together with rules that made it
+func (s *TriggerServer) policyExplainHandler(resp http.ResponseWriter, req *http.Request) {
+	var explainRequest policyExplainRequest
+	dec := json.NewDecoder(req.Body)
+	defer req.Body.Close()
+
+	err := dec.Decode(&explainRequest)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "%s", err)
+		return
+	}
+
+	if explainRequest.Image != "" {
+		ref, err := image.Parse(explainRequest.Image)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid image: %s", err), http.StatusBadRequest)
+			return
+		}
+		if explainRequest.CurrentTag == "" {
+			explainRequest.CurrentTag = ref.Tag()
+		}
+		explainRequest.Image = ref.Name()
+	}
+
+	if explainRequest.CurrentTag == "" || explainRequest.NewTag == "" {
+		http.Error(resp, "currentTag and newTag cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	matchPreRelease := true
+	if explainRequest.MatchPreRelease != nil {
+		matchPreRelease = *explainRequest.MatchPreRelease
+	}
+
+	p := policy.GetPolicy(explainRequest.Policy, &policy.Options{
+		MatchTag:        explainRequest.MatchTag,
+		MatchPreRelease: matchPreRelease,
+		AllowedTags:     explainRequest.AllowedTags,
+		BlockedTags:     explainRequest.BlockedTags,
+	})
+
+	ctx := &policy.UpdateContext{Image: explainRequest.Image}
+	response(policy.Explain(p, ctx, explainRequest.CurrentTag, explainRequest.NewTag), 200, nil, resp, req)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/policy"
+)
+
+func TestPolicyExplain(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+
+	req, err := http.NewRequest("POST", "/v1/policies/explain", bytes.NewBufferString(`{"image": "gcr.io/v2-namespace/hello-world:1.1.1", "newTag": "2.0.0", "policy": "minor"}`))
+	if err != nil {
+		t.Fatalf("failed to create req: %s", err)
+	}
+	req.SetBasicAuth("user-1", "secret")
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status code: %d, body: %s", rec.Code, rec.Body.String())
+	}
+
+	var explanation policy.Explanation
+	err = json.Unmarshal(rec.Body.Bytes(), &explanation)
+	if err != nil {
+		t.Fatalf("failed to unmarshal response: %s", err)
+	}
+	if explanation.Allowed {
+		t.Errorf("expected major update to be denied")
+	}
+	if explanation.Reason != "1.1.1 -> 2.0.0 is a major update, minor policy doesn't allow it" {
+		t.Errorf("unexpected reason: %s", explanation.Reason)
+	}
+	if len(fp.submitted) != 0 {
+		t.Errorf("explain shouldn't submit events")
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -45,7 +46,25 @@ type quayWebhook struct {
 	UpdatedTags []string `json:"updated_tags"`
 }
 
+// webhookSecretHeader - header with shared secret for webhooks that can't sign payloads
+const webhookSecretHeader = "X-Keel-Webhook-Secret"
+
+// validWebhookSecret - checks shared secret passed as "secret" query parameter or header
+func validWebhookSecret(req *http.Request, secret string) bool {
+	provided := req.Header.Get(webhookSecretHeader)
+	if provided == "" {
+		provided = req.URL.Query().Get("secret")
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) == 1
+}
+
 func (s *TriggerServer) quayHandler(resp http.ResponseWriter, req *http.Request) {
+	if secret := s.secrets().quayWebhookSecret; secret != "" && !validWebhookSecret(req, secret) {
+		log.Warn("trigger.quayHandler: invalid webhook secret")
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	qw := quayWebhook{}
 	if err := json.NewDecoder(req.Body).Decode(&qw); err != nil {
 		log.WithFields(log.Fields{
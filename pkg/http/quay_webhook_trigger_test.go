@@ -53,3 +53,67 @@ func TestQuayWebhookHandler(t *testing.T) {
 		t.Errorf("expected 1.2.3 but got %s", fp.submitted[0].Repository.Tag)
 	}
 }
+
+func TestQuayWebhookHandlerSecret(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.quayWebhookSecret = "s3cret"
+
+	payload := `{"docker_url": "quay.io/mynamespace/repository", "updated_tags": ["1.2.3", "1.2.4"]}`
+
+	for _, tc := range []struct {
+		path   string
+		header string
+		code   int
+	}{
+		{path: "/v1/webhooks/quay", code: 401},
+		{path: "/v1/webhooks/quay?secret=wrong", code: 401},
+		{path: "/v1/webhooks/quay?secret=s3cret", code: 200},
+		{path: "/v1/webhooks/quay", header: "s3cret", code: 200},
+	} {
+		req, err := http.NewRequest("POST", tc.path, bytes.NewBuffer([]byte(payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		if tc.header != "" {
+			req.Header.Set(webhookSecretHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s (header %q): unexpected status code: %d, expected: %d", tc.path, tc.header, rec.Code, tc.code)
+		}
+	}
+
+	// one event per updated tag for both accepted requests
+	if len(fp.submitted) != 4 {
+		t.Fatalf("unexpected number of events submitted: %d", len(fp.submitted))
+	}
+	if fp.submitted[1].Repository.Tag != "1.2.4" {
+		t.Errorf("expected 1.2.4 but got %s", fp.submitted[1].Repository.Tag)
+	}
+}
+
+func TestSetWebhookSecrets(t *testing.T) {
+	fp := &fakeProvider{}
+	srv, teardown := NewTestingServer(fp)
+	defer teardown()
+	srv.quayWebhookSecret = "s3cret"
+
+	srv.SetWebhookSecrets(&Opts{QuayWebhookSecret: "rotated"})
+
+	payload := `{"docker_url": "quay.io/mynamespace/repository", "updated_tags": ["1.2.3"]}`
+	for secret, code := range map[string]int{"s3cret": 401, "rotated": 200} {
+		req, err := http.NewRequest("POST", "/v1/webhooks/quay", bytes.NewBuffer([]byte(payload)))
+		if err != nil {
+			t.Fatalf("failed to create req: %s", err)
+		}
+		req.Header.Set(webhookSecretHeader, secret)
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("secret %s: unexpected status code: %d, expected: %d", secret, rec.Code, code)
+		}
+	}
+}
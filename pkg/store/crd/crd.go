@@ -0,0 +1,328 @@
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// KeelApprovalResource - KeelApproval custom resource definition group, version and resource
+var KeelApprovalResource = schema.GroupVersionResource{
+	Group:    "keel.sh",
+	Version:  "v1alpha1",
+	Resource: "keelapprovals",
+}
+
+// ArchivedLabel - set on KeelApproval resources so pending approvals can be listed
+// without archived ones
+const ArchivedLabel = "keel.sh/archived"
+
+// KeelApprovalSpec - approval request, ApprovedBy and RejectedBy are set by users
+// (kubectl patch, GitOps) to vote, everything else is managed by Keel. Names can't be
+// verified so however many are listed they count as a single vote (see crdVoter)
+type KeelApprovalSpec struct {
+	Identifier     string       `json:"identifier"`
+	Provider       string       `json:"provider,omitempty"`
+	Message        string       `json:"message,omitempty"`
+	CurrentVersion string       `json:"currentVersion,omitempty"`
+	NewVersion     string       `json:"newVersion,omitempty"`
+	Digest         string       `json:"digest,omitempty"`
+	VotesRequired  int          `json:"votesRequired"`
+	Deadline       time.Time    `json:"deadline"`
+	Event          *types.Event `json:"event,omitempty"`
+
+	ExpiryAction       string `json:"expiryAction,omitempty"`
+	EscalationChannels string `json:"escalationChannels,omitempty"`
+	Approvers          string `json:"approvers,omitempty"`
+	Channels           string `json:"channels,omitempty"`
+
+	ApprovedBy []string `json:"approvedBy,omitempty"`
+	RejectedBy string   `json:"rejectedBy,omitempty"`
+}
+
+// KeelApprovalStatus - votes collected by Keel
+type KeelApprovalStatus struct {
+	Status        string      `json:"status"`
+	VotesReceived int         `json:"votesReceived"`
+	Voters        types.JSONB `json:"voters,omitempty"`
+	Rejected      bool        `json:"rejected"`
+	Archived      bool        `json:"archived"`
+	Escalated     bool        `json:"escalated,omitempty"`
+	RemindedAt    time.Time   `json:"remindedAt,omitempty"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	UpdatedAt     time.Time   `json:"updatedAt"`
+}
+
+// Store - keeps approvals as KeelApproval resources in Keel namespace, audit logs
+// are stored in the wrapped store
+type Store struct {
+	store.Store
+
+	client dynamic.ResourceInterface
+}
+
+// New - creates KeelApproval store, audit is used for everything but approvals
+func New(audit store.Store, client dynamic.Interface, namespace string) *Store {
+	return &Store{
+		Store:  audit,
+		client: client.Resource(KeelApprovalResource).Namespace(namespace),
+	}
+}
+
+func (s *Store) CreateApproval(approval *types.Approval) (*types.Approval, error) {
+	// generating ID, it's also the resource name
+	if approval.ID == "" {
+		approval.ID = uuid.New().String()
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(KeelApprovalResource.GroupVersion().String())
+	obj.SetKind("KeelApproval")
+	obj.SetName(approval.ID)
+	if err := setApproval(obj, approval); err != nil {
+		return nil, err
+	}
+
+	_, err := s.client.Create(obj, meta_v1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+func (s *Store) UpdateApproval(approval *types.Approval) error {
+	if approval.ID == "" {
+		return fmt.Errorf("ID not specified")
+	}
+	approval.UpdatedAt = time.Now()
+
+	// votes from users are kept, resource is fetched again on conflicts
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := s.client.Get(approval.ID, meta_v1.GetOptions{})
+		if err != nil {
+			return notFound(err)
+		}
+		if err := setApproval(obj, approval); err != nil {
+			return err
+		}
+		_, err = s.client.Update(obj, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *Store) GetApproval(q *types.GetApprovalQuery) (*types.Approval, error) {
+	if q.ID != "" {
+		obj, err := s.client.Get(q.ID, meta_v1.GetOptions{})
+		if err != nil {
+			return nil, notFound(err)
+		}
+		approval, err := getApproval(obj)
+		if err != nil {
+			return nil, err
+		}
+		if (q.Identifier != "" && approval.Identifier != q.Identifier) || (q.Archived && !approval.Archived) {
+			return nil, store.ErrRecordNotFound
+		}
+		return approval, nil
+	}
+
+	approvals, err := s.list(q.Archived)
+	if err != nil {
+		return nil, err
+	}
+	for _, approval := range approvals {
+		if approval.Identifier == q.Identifier {
+			return approval, nil
+		}
+	}
+	return nil, store.ErrRecordNotFound
+}
+
+// ListApprovals - like the SQL store, unset filters match everything so archived
+// approvals are listed too unless Archived is set
+func (s *Store) ListApprovals(q *types.GetApprovalQuery) ([]*types.Approval, error) {
+	obj, err := s.client.List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals []*types.Approval
+	for i := range obj.Items {
+		approval, err := getApproval(&obj.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		if q.Identifier != "" && approval.Identifier != q.Identifier {
+			continue
+		}
+		if q.Archived && !approval.Archived {
+			continue
+		}
+		approvals = append(approvals, approval)
+	}
+
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].UpdatedAt.After(approvals[j].UpdatedAt) })
+	return approvals, nil
+}
+
+func (s *Store) DeleteApproval(approval *types.Approval) error {
+	if approval.ID == "" {
+		return fmt.Errorf("ID not specified")
+	}
+	return notFound(s.client.Delete(approval.ID, &meta_v1.DeleteOptions{}))
+}
+
+// list - approvals with matching archived label, most recent first
+func (s *Store) list(archived bool) ([]*types.Approval, error) {
+	obj, err := s.client.List(meta_v1.ListOptions{LabelSelector: ArchivedLabel + "=" + strconv.FormatBool(archived)})
+	if err != nil {
+		return nil, err
+	}
+	approvals := make([]*types.Approval, 0, len(obj.Items))
+	for i := range obj.Items {
+		approval, err := getApproval(&obj.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, approval)
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].UpdatedAt.After(approvals[j].UpdatedAt) })
+	return approvals, nil
+}
+
+func notFound(err error) error {
+	if errors.IsNotFound(err) {
+		return store.ErrRecordNotFound
+	}
+	return err
+}
+
+// setApproval - sets Keel managed fields of the resource
+func setApproval(obj *unstructured.Unstructured, approval *types.Approval) error {
+	spec, _, err := getSpecStatus(obj)
+	if err != nil {
+		return err
+	}
+	spec.Identifier = approval.Identifier
+	spec.Provider = approval.Provider.String()
+	spec.Message = approval.Message
+	spec.CurrentVersion = approval.CurrentVersion
+	spec.NewVersion = approval.NewVersion
+	spec.Digest = approval.Digest
+	spec.VotesRequired = approval.VotesRequired
+	spec.Deadline = approval.Deadline
+	spec.Event = approval.Event
+	spec.ExpiryAction = approval.ExpiryAction
+	spec.EscalationChannels = approval.EscalationChannels
+	spec.Approvers = approval.Approvers
+	spec.Channels = approval.Channels
+
+	status := &KeelApprovalStatus{
+		Status:        approval.Status().String(),
+		VotesReceived: approval.VotesReceived,
+		Voters:        approval.Voters,
+		Rejected:      approval.Rejected,
+		Archived:      approval.Archived,
+		Escalated:     approval.Escalated,
+		RemindedAt:    approval.RemindedAt,
+		CreatedAt:     approval.CreatedAt,
+		UpdatedAt:     approval.UpdatedAt,
+	}
+	if approval.Archived {
+		status.Status = "archived"
+	}
+
+	for field, value := range map[string]interface{}{"spec": spec, "status": status} {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return err
+		}
+		obj.Object[field] = m
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ArchivedLabel] = strconv.FormatBool(approval.Archived)
+	obj.SetLabels(labels)
+	return nil
+}
+
+func getApproval(obj *unstructured.Unstructured) (*types.Approval, error) {
+	spec, status, err := getSpecStatus(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Approval{
+		ID:             obj.GetName(),
+		Archived:       status.Archived,
+		Provider:       providerType(spec.Provider),
+		Identifier:     spec.Identifier,
+		Event:          spec.Event,
+		Message:        spec.Message,
+		CurrentVersion: spec.CurrentVersion,
+		NewVersion:     spec.NewVersion,
+		Digest:         spec.Digest,
+		VotesRequired:  spec.VotesRequired,
+		VotesReceived:  status.VotesReceived,
+		Voters:         status.Voters,
+		Rejected:       status.Rejected,
+		Deadline:       spec.Deadline,
+		CreatedAt:      status.CreatedAt,
+
+		ExpiryAction:       spec.ExpiryAction,
+		EscalationChannels: spec.EscalationChannels,
+		Approvers:          spec.Approvers,
+		Channels:           spec.Channels,
+		RemindedAt:         status.RemindedAt,
+		Escalated:          status.Escalated,
+		UpdatedAt:          status.UpdatedAt,
+	}, nil
+}
+
+func getSpecStatus(obj *unstructured.Unstructured) (*KeelApprovalSpec, *KeelApprovalStatus, error) {
+	spec := &KeelApprovalSpec{}
+	status := &KeelApprovalStatus{}
+	for field, value := range map[string]interface{}{"spec": spec, "status": status} {
+		m, ok := obj.Object[field]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(b, value); err != nil {
+			return nil, nil, fmt.Errorf("invalid KeelApproval %s %s: %s", obj.GetName(), field, err)
+		}
+	}
+	return spec, status, nil
+}
+
+func providerType(name string) types.ProviderType {
+	for _, t := range []types.ProviderType{types.ProviderTypeKubernetes, types.ProviderTypeHelm} {
+		if t.String() == name {
+			return t
+		}
+	}
+	return types.ProviderTypeUnknown
+}
@@ -0,0 +1,223 @@
+package crd
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// fakeResource - in memory KeelApproval resources
+type fakeResource struct {
+	mu      sync.Mutex
+	objects map[string]*unstructured.Unstructured
+}
+
+func (f *fakeResource) Create(obj *unstructured.Unstructured, options meta_v1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[obj.GetName()]; ok {
+		return nil, errors.NewAlreadyExists(KeelApprovalResource.GroupResource(), obj.GetName())
+	}
+	f.objects[obj.GetName()] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (f *fakeResource) Update(obj *unstructured.Unstructured, options meta_v1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[obj.GetName()]; !ok {
+		return nil, errors.NewNotFound(KeelApprovalResource.GroupResource(), obj.GetName())
+	}
+	f.objects[obj.GetName()] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (f *fakeResource) UpdateStatus(obj *unstructured.Unstructured, options meta_v1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return f.Update(obj, options)
+}
+
+func (f *fakeResource) Delete(name string, options *meta_v1.DeleteOptions, subresources ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[name]; !ok {
+		return errors.NewNotFound(KeelApprovalResource.GroupResource(), name)
+	}
+	delete(f.objects, name)
+	return nil
+}
+
+func (f *fakeResource) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return nil
+}
+
+func (f *fakeResource) Get(name string, options meta_v1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[name]
+	if !ok {
+		return nil, errors.NewNotFound(KeelApprovalResource.GroupResource(), name)
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (f *fakeResource) List(opts meta_v1.ListOptions) (*unstructured.UnstructuredList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	for _, obj := range f.objects {
+		if selector.Matches(labels.Set(obj.GetLabels())) {
+			list.Items = append(list.Items, *obj.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeResource) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return watch.NewEmptyWatch(), nil
+}
+
+func (f *fakeResource) Patch(name string, pt k8stypes.PatchType, data []byte, options meta_v1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, nil
+}
+
+func newTestStore() (*Store, *fakeResource) {
+	f := &fakeResource{objects: make(map[string]*unstructured.Unstructured)}
+	return &Store{client: f}, f
+}
+
+func TestApprovalLifecycle(t *testing.T) {
+	s, f := newTestStore()
+
+	deadline := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	created, err := s.CreateApproval(&types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     "default/app:1.1.0",
+		Event:          &types.Event{Repository: types.Repository{Name: "app", Tag: "1.1.0"}},
+		Message:        "New image is available for deployment default/app",
+		CurrentVersion: "1.0.0",
+		NewVersion:     "1.1.0",
+		VotesRequired:  2,
+		Deadline:       deadline,
+	})
+	if err != nil {
+		t.Fatalf("failed to create approval: %s", err)
+	}
+	if created.ID == "" {
+		t.Fatalf("expected ID to be generated")
+	}
+
+	obj := f.objects[created.ID]
+	if obj.GetKind() != "KeelApproval" || obj.GetLabels()[ArchivedLabel] != "false" {
+		t.Errorf("unexpected resource: %v", obj)
+	}
+
+	approval, err := s.GetApproval(&types.GetApprovalQuery{Identifier: "default/app:1.1.0"})
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.ID != created.ID || approval.Provider != types.ProviderTypeKubernetes || approval.Event.Repository.Tag != "1.1.0" || !approval.Deadline.Equal(deadline) {
+		t.Errorf("unexpected approval: %+v", approval)
+	}
+
+	// user vote is kept when Keel updates the approval
+	obj.Object["spec"].(map[string]interface{})["approvedBy"] = []interface{}{"jane"}
+
+	approval.AddVoter("jane")
+	approval.VotesReceived = 1
+	if err := s.UpdateApproval(approval); err != nil {
+		t.Fatalf("failed to update approval: %s", err)
+	}
+	spec, status, _ := getSpecStatus(f.objects[created.ID])
+	if len(spec.ApprovedBy) != 1 || spec.ApprovedBy[0] != "jane" {
+		t.Errorf("expected user votes to be kept: %+v", spec)
+	}
+	if status.VotesReceived != 1 || status.Status != "pending" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	approval.Archived = true
+	if err := s.UpdateApproval(approval); err != nil {
+		t.Fatalf("failed to update approval: %s", err)
+	}
+	if _, err := s.GetApproval(&types.GetApprovalQuery{Identifier: "default/app:1.1.0"}); err != store.ErrRecordNotFound {
+		t.Errorf("expected archived approval not to be found, got: %v", err)
+	}
+	archived, err := s.ListApprovals(&types.GetApprovalQuery{Archived: true})
+	if err != nil || len(archived) != 1 {
+		t.Errorf("expected archived approval to be listed, got %d: %v", len(archived), err)
+	}
+
+	if err := s.DeleteApproval(approval); err != nil {
+		t.Fatalf("failed to delete approval: %s", err)
+	}
+	if _, err := s.GetApproval(&types.GetApprovalQuery{ID: approval.ID}); err != store.ErrRecordNotFound {
+		t.Errorf("expected deleted approval not to be found, got: %v", err)
+	}
+}
+
+type fakeApprover struct {
+	approved []string
+	rejected []string
+}
+
+func (a *fakeApprover) Approve(identifier, voter string) (*types.Approval, error) {
+	a.approved = append(a.approved, identifier+" "+voter)
+	return nil, nil
+}
+
+func (a *fakeApprover) Reject(identifier, voter string) (*types.Approval, error) {
+	a.rejected = append(a.rejected, identifier+" "+voter)
+	return nil, nil
+}
+
+func TestVote(t *testing.T) {
+	s, _ := newTestStore()
+	approver := &fakeApprover{}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"identifier": "default/app:1.1.0",
+			"approvedBy": []interface{}{"jane", "john"},
+		},
+	}}
+	s.vote(obj, approver)
+	if len(approver.approved) != 1 || approver.approved[0] != "default/app:1.1.0 kubernetes:keelapproval" {
+		t.Errorf("expected listed names to count as a single vote: %v", approver.approved)
+	}
+
+	// vote already counted, more names don't add votes
+	obj.Object["spec"].(map[string]interface{})["approvedBy"] = []interface{}{"jane", "john", "joe"}
+	obj.Object["status"] = map[string]interface{}{
+		"voters": map[string]interface{}{"kubernetes:keelapproval": "2019-01-01T00:00:00Z"},
+	}
+	s.vote(obj, approver)
+	if len(approver.approved) != 1 {
+		t.Errorf("expected no more votes: %v", approver.approved)
+	}
+
+	obj.Object["spec"].(map[string]interface{})["rejectedBy"] = "bob"
+	s.vote(obj, approver)
+	if len(approver.rejected) != 1 || approver.rejected[0] != "default/app:1.1.0 kubernetes:keelapproval" {
+		t.Errorf("expected rejection: %v", approver.rejected)
+	}
+
+	obj.Object["status"].(map[string]interface{})["archived"] = true
+	s.vote(obj, approver)
+	if len(approver.rejected) != 1 {
+		t.Errorf("expected archived approval to be ignored: %v", approver.rejected)
+	}
+}
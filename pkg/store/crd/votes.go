@@ -0,0 +1,111 @@
+package crd
+
+import (
+	"context"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	watchapi "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Approver - votes on approvals, implemented by approvals manager
+type Approver interface {
+	Approve(identifier, voter string) (*types.Approval, error)
+	Reject(identifier, voter string) (*types.Approval, error)
+}
+
+// crdVoter - all votes submitted through KeelApproval resources count as this single
+// voter. Names in spec.approvedBy and spec.rejectedBy are free text that anyone
+// allowed to patch keelapprovals can set, they are only logged
+const crdVoter = "kubernetes:keelapproval"
+
+// WatchVotes - watches KeelApproval resources and submits spec.approvedBy and
+// spec.rejectedBy as a vote of crdVoter, blocks until context is cancelled
+func (s *Store) WatchVotes(ctx context.Context, approver Approver) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = ArchivedLabel + "=false"
+			return s.client.List(options)
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watchapi.Interface, error) {
+			options.LabelSelector = ArchivedLabel + "=false"
+			return s.client.Watch(options)
+		},
+	}
+	informer := cache.NewSharedInformer(lw, &unstructured.Unstructured{}, 30*time.Minute)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.vote(obj, approver)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			s.vote(newObj, approver)
+		},
+	})
+
+	log.Info("store.crd: watching KeelApproval votes")
+	informer.Run(ctx.Done())
+}
+
+// vote - submits votes that weren't counted yet, approvals manager updates the
+// resource so the same votes are ignored on the next update
+func (s *Store) vote(obj interface{}, approver Approver) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	spec, status, err := getSpecStatus(u)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("store.crd: failed to read KeelApproval")
+		return
+	}
+	if status.Archived || status.Rejected {
+		return
+	}
+
+	if spec.RejectedBy != "" {
+		_, err := approver.Reject(spec.Identifier, crdVoter)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"identifier": spec.Identifier,
+				"rejectedBy": spec.RejectedBy,
+			}).Error("store.crd: failed to reject")
+		}
+		return
+	}
+
+	var approvedBy []string
+	for _, name := range spec.ApprovedBy {
+		if name != "" {
+			approvedBy = append(approvedBy, name)
+		}
+	}
+	if len(approvedBy) == 0 {
+		return
+	}
+	if _, counted := status.Voters[crdVoter]; counted {
+		return
+	}
+	_, err = approver.Approve(spec.Identifier, crdVoter)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"identifier": spec.Identifier,
+			"approvedBy": approvedBy,
+		}).Error("store.crd: failed to approve")
+		return
+	}
+	log.WithFields(log.Fields{
+		"identifier": spec.Identifier,
+		"approvedBy": approvedBy,
+	}).Info("store.crd: approved through KeelApproval resource")
+}
@@ -0,0 +1,32 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestRedactURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{uri: "/data/keel.db", want: "/data/keel.db"},
+		{uri: "postgres://keel:secret@db:5432/keel?sslmode=disable", want: "postgres://keel:xxxxx@db:5432/keel?sslmode=disable"},
+		{uri: "postgres://keel@db/keel", want: "postgres://keel@db/keel"},
+		{uri: "host=db user=keel password=secret dbname=keel", want: "host=db user=keel password=xxxxx dbname=keel"},
+		{uri: "host=db password='very secret' dbname=keel", want: "host=db password=xxxxx dbname=keel"},
+	}
+	for _, tt := range tests {
+		if got := RedactURI(tt.uri); got != tt.want {
+			t.Errorf("RedactURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestNewUnsupportedDatabase(t *testing.T) {
+	if _, err := New(Opts{DatabaseType: "mysql", URI: "keel:secret@/keel"}); err == nil {
+		t.Errorf("expected unsupported database type to be refused")
+	}
+	if _, err := New(Opts{DatabaseType: DatabaseTypePostgres}); err == nil {
+		t.Errorf("expected missing URI to be refused")
+	}
+}
@@ -2,6 +2,7 @@ package helm
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/pkg/store"
@@ -61,6 +62,14 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 			if plan.Config.ApprovalDeadline == 0 {
 				plan.Config.ApprovalDeadline = types.KeelApprovalDeadlineDefault
 			}
+			expiryAction, err := types.ParseApprovalExpiryAction(plan.Config.ApprovalExpiry)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"release": plan.Name,
+				}).Warn("provider.helm: failed to parse approval expiry action, approval will expire")
+				expiryAction = types.ApprovalExpiryExpire
+			}
 
 			// creating new one
 			approval := &types.Approval{
@@ -73,6 +82,11 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 				VotesReceived:  0,
 				Rejected:       false,
 				Deadline:       time.Now().Add(time.Duration(plan.Config.ApprovalDeadline) * time.Hour),
+
+				ExpiryAction:       expiryAction,
+				EscalationChannels: strings.Join(plan.Config.EscalationChannels, ","),
+				Approvers:          strings.Join(plan.Config.Approvers, ","),
+				Channels:           strings.Join(plan.Config.NotificationChannels, ","),
 			}
 
 			approval.Message = fmt.Sprintf("New image is available for release %s/%s (%s).",
@@ -45,9 +45,9 @@ func getImages(vals chartutil.Values) ([]*types.TrackedImage, error) {
 
 		trackedImage := &types.TrackedImage{
 			Image:        imageRef,
-			PollSchedule: keelCfg.PollSchedule,
-			Trigger:      keelCfg.Trigger,
-			Policy:       keelCfg.Plc,
+			PollSchedule: keelCfg.imagePollSchedule(&imageDetails),
+			Trigger:      keelCfg.imageTrigger(&imageDetails),
+			Policy:       keelCfg.imagePolicy(&imageDetails),
 		}
 
 		if imageDetails.ImagePullSecret != "" {
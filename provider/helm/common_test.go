@@ -1511,3 +1511,42 @@ prometheus:
           ##
           # serverName: ""
 `
+
+var chartValuesMultipleImages = `
+image:
+  repository: gcr.io/v2-namespace/hello-world
+  tag: 1.1.0
+sidecar:
+  image: karolisr/keel-sidecar:0.1.0
+
+keel:
+  trigger: poll
+  pollSchedule: "@every 30m"
+  images:
+    - repository: image.repository
+      tag: image.tag
+      policy: major
+    - repository: sidecar.image
+      policy: patch
+      trigger: default
+      pollSchedule: "@every 5m"
+`
+
+func Test_getImagesPerImageConfig(t *testing.T) {
+	vals, _ := chartutil.ReadValues([]byte(chartValuesMultipleImages))
+
+	images, err := getImages(vals)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(images))
+	}
+
+	if images[0].Policy.Name() != policy.SemverPolicyTypeMajor.String() || images[0].Trigger != types.TriggerTypePoll || images[0].PollSchedule != "@every 30m" {
+		t.Errorf("unexpected image config: %s, policy: %s", images[0], images[0].Policy.Name())
+	}
+	if images[1].Policy.Name() != policy.SemverPolicyTypePatch.String() || images[1].Trigger != types.TriggerTypeDefault || images[1].PollSchedule != "@every 5m" {
+		t.Errorf("unexpected image config: %s, policy: %s", images[1], images[1].Policy.Name())
+	}
+}
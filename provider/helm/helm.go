@@ -10,6 +10,7 @@ import (
 	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/schedule"
 
 	hapi_chart "k8s.io/helm/pkg/proto/hapi/chart"
 
@@ -20,7 +21,6 @@ import (
 	"github.com/ghodss/yaml"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/helm/pkg/chartutil"
-	"k8s.io/helm/pkg/helm"
 	"k8s.io/helm/pkg/strvals"
 )
 
@@ -90,10 +90,21 @@ type UpdatePlan struct {
 //   # trigger type, defaults to events such as pubsub, webhooks
 //   trigger: poll
 //   pollSchedule: "@every 2m"
+//   # only report updates, release is not upgraded
+//   dryRun: false
+//   # tags that are never updated to
+//   blockedTags: ["*-debug"]
 //   # images to track and update
 //   images:
 //     - repository: image.repository
 //       tag: image.tag
+//     # images can override release policy, trigger and poll schedule
+//     - repository: sidecar.image.repository
+//       tag: sidecar.image.tag
+//       digest: sidecar.image.digest
+//       policy: patch
+//       trigger: poll
+//       pollSchedule: "@every 10m"
 
 // Root - root element of the values yaml
 type Root struct {
@@ -107,10 +118,16 @@ type KeelChartConfig struct {
 	MatchPreRelease      bool              `json:"matchPreRelease"`
 	Trigger              types.TriggerType `json:"trigger"`
 	PollSchedule         string            `json:"pollSchedule"`
-	Approvals            int               `json:"approvals"`        // Minimum required approvals
-	ApprovalDeadline     int               `json:"approvalDeadline"` // Deadline in hours
+	Approvals            int               `json:"approvals"`          // Minimum required approvals
+	ApprovalDeadline     int               `json:"approvalDeadline"`   // Deadline in hours
+	ApprovalExpiry       string            `json:"approvalExpiry"`     // expire, escalate, approve or reject
+	EscalationChannels   []string          `json:"escalationChannels"` // notified once approval deadline is reached
+	Approvers            []string          `json:"approvers"`          // users and @groups allowed to vote
 	Images               []ImageDetails    `json:"images"`
 	NotificationChannels []string          `json:"notificationChannels"` // optional notification channels
+	DryRun               bool              `json:"dryRun"`               // only report updates
+	AllowedTags          []string          `json:"allowedTags"`          // optional glob or "regexp:" tag patterns
+	BlockedTags          []string          `json:"blockedTags"`          // tags matching these patterns are never used
 
 	Plc policy.Policy `json:"-"`
 }
@@ -122,6 +139,45 @@ type ImageDetails struct {
 	DigestPath      string `json:"digest"`
 	ReleaseNotes    string `json:"releaseNotes"`
 	ImagePullSecret string `json:"imagePullSecret"`
+
+	// optional image policy, trigger and poll schedule, release settings are used when not set
+	Policy       string             `json:"policy,omitempty"`
+	Trigger      *types.TriggerType `json:"trigger,omitempty"`
+	PollSchedule string             `json:"pollSchedule,omitempty"`
+}
+
+// imagePolicy - image policy with release tag matching options, release policy is used
+// when the image doesn't set its own
+func (c *KeelChartConfig) imagePolicy(details *ImageDetails) policy.Policy {
+	if details.Policy == "" {
+		return c.Plc
+	}
+	return policy.GetPolicy(details.Policy, c.policyOptions())
+}
+
+// imageTrigger - image trigger, defaults to release trigger
+func (c *KeelChartConfig) imageTrigger(details *ImageDetails) types.TriggerType {
+	if details.Trigger != nil {
+		return *details.Trigger
+	}
+	return c.Trigger
+}
+
+// imagePollSchedule - image poll schedule, defaults to release poll schedule
+func (c *KeelChartConfig) imagePollSchedule(details *ImageDetails) string {
+	if details.PollSchedule != "" {
+		return details.PollSchedule
+	}
+	return c.PollSchedule
+}
+
+func (c *KeelChartConfig) policyOptions() *policy.Options {
+	return &policy.Options{
+		MatchTag:        c.MatchTag,
+		MatchPreRelease: c.MatchPreRelease,
+		AllowedTags:     c.AllowedTags,
+		BlockedTags:     c.BlockedTags,
+	}
 }
 
 // Provider - helm provider, responsible for managing release updates
@@ -132,6 +188,9 @@ type Provider struct {
 
 	approvalManager approvals.Manager
 
+	// dryRun - updates are only reported, releases are never upgraded
+	dryRun bool
+
 	events chan *types.Event
 	stop   chan struct{}
 }
@@ -152,6 +211,11 @@ func (p *Provider) GetName() string {
 	return ProviderName
 }
 
+// SetDryRun - when enabled, release updates are only reported
+func (p *Provider) SetDryRun(dryRun bool) {
+	p.dryRun = dryRun
+}
+
 // Submit - submit event to provider
 func (p *Provider) Submit(event types.Event) error {
 	p.events <- &event
@@ -191,7 +255,7 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 			continue
 		}
 
-		cfg, err := getKeelConfig(vals)
+		_, err = getKeelConfig(vals)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":     err,
@@ -201,9 +265,6 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 			continue
 		}
 
-		if cfg.PollSchedule == "" {
-			cfg.PollSchedule = types.KeelPollDefaultSchedule
-		}
 		// used to check pod secrets
 		selector := fmt.Sprintf("app=%s,release=%s", release.Chart.Metadata.Name, release.Name)
 
@@ -218,6 +279,19 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		}
 
 		for _, img := range releaseImages {
+			// images can have their own poll schedules
+			if img.PollSchedule == "" {
+				img.PollSchedule = schedule.Default()
+			} else if err := schedule.Validate(img.PollSchedule); err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"schedule":  img.PollSchedule,
+					"image":     img.Image.Remote(),
+					"release":   release.Name,
+					"namespace": release.Namespace,
+				}).Error("provider.helm: failed to parse poll schedule, setting default schedule")
+				img.PollSchedule = schedule.Default()
+			}
 			img.Meta = map[string]string{
 				"selector":      selector,
 				"helm.sh/chart": fmt.Sprintf("%s-%s", release.Chart.Metadata.Name, release.Chart.Metadata.Version),
@@ -257,11 +331,45 @@ func (p *Provider) processEvent(event *types.Event) (err error) {
 		return err
 	}
 
-	approved := p.checkForApprovals(event, plans)
+	approved := p.checkForApprovals(event, p.checkForDryRun(plans))
 
 	return p.applyPlans(approved)
 }
 
+// checkForDryRun - filters out plans for releases in dry run mode and reports them instead
+func (p *Provider) checkForDryRun(plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+	for _, plan := range plans {
+		if !p.dryRun && !plan.Config.DryRun {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      plan.Name,
+			"namespace": plan.Namespace,
+			"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		}).Info("provider.helm: dry run, release not updated")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: "chart",
+			Identifier:   fmt.Sprintf("%s/%s/%s", "chart", plan.Namespace, plan.Name),
+			Name:         "dry run update",
+			Message:      fmt.Sprintf("Dry run: would update release %s/%s from %s to %s (%s)", plan.Namespace, plan.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(mapToSlice(plan.Values), ", ")),
+			CreatedAt:    time.Now(),
+			Type:         types.NotificationDryRun,
+			Level:        types.LevelInfo,
+			Channels:     plan.Config.NotificationChannels,
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": plan.Namespace,
+				"name":      plan.Name,
+			},
+		})
+	}
+	return allowedPlans
+}
+
 func (p *Provider) createUpdatePlans(event *types.Event) ([]*UpdatePlan, error) {
 	var plans []*UpdatePlan
 
@@ -312,7 +420,7 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 			},
 		})
 
-		err := updateHelmRelease(p.implementer, plan.Name, plan.Chart, plan.Values)
+		err := updateHelmRelease(p.implementer, plan.Namespace, plan.Name, plan.Chart, plan.Values)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":     err,
@@ -375,31 +483,22 @@ func (p *Provider) applyPlans(plans []*UpdatePlan) error {
 	return nil
 }
 
-func updateHelmRelease(implementer Implementer, releaseName string, chart *hapi_chart.Chart, overrideValues map[string]string) error {
+func updateHelmRelease(implementer Implementer, namespace, releaseName string, chart *hapi_chart.Chart, overrideValues map[string]string) error {
 
 	overrideBts, err := convertToYaml(mapToSlice(overrideValues))
 	if err != nil {
 		return err
 	}
 
-	resp, err := implementer.UpdateReleaseFromChart(releaseName, chart,
-		helm.UpdateValueOverrides(overrideBts),
-		helm.UpgradeDryRun(false),
-		helm.UpgradeRecreate(false),
-		helm.UpgradeForce(true),
-		helm.UpgradeDisableHooks(false),
-		helm.UpgradeTimeout(DefaultUpdateTimeout),
-		helm.ResetValues(false),
-		helm.ReuseValues(true),
-		helm.UpgradeWait(true))
-
+	version, err := implementer.UpdateRelease(namespace, releaseName, chart, overrideBts)
 	if err != nil {
 		return err
 	}
 
 	log.WithFields(log.Fields{
-		"version": resp.Release.Version,
-		"release": releaseName,
+		"version":   version,
+		"release":   releaseName,
+		"namespace": namespace,
 	}).Info("provider.helm: release updated")
 	return nil
 }
@@ -442,6 +541,15 @@ func values(chart *hapi_chart.Chart, config *hapi_chart.Config) (chartutil.Value
 	return chartutil.CoalesceValues(chart, config)
 }
 
+func hasImagePolicy(images []ImageDetails) bool {
+	for _, img := range images {
+		if img.Policy != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func getKeelConfig(vals chartutil.Values) (*KeelChartConfig, error) {
 	yamlFull, err := vals.YAML()
 	if err != nil {
@@ -456,13 +564,14 @@ func getKeelConfig(vals chartutil.Values) (*KeelChartConfig, error) {
 		return nil, fmt.Errorf("failed to parse keel config: %s", err)
 	}
 
-	if r.Keel.Policy == "" {
+	// release policy can be left out when images set their own
+	if r.Keel.Policy == "" && !hasImagePolicy(r.Keel.Images) {
 		return nil, ErrPolicyNotSpecified
 	}
 
 	cfg := r.Keel
 
-	cfg.Plc = policy.GetPolicy(cfg.Policy, &policy.Options{MatchTag: cfg.MatchTag, MatchPreRelease: cfg.MatchPreRelease})
+	cfg.Plc = policy.GetPolicy(cfg.Policy, cfg.policyOptions())
 
 	return &cfg, nil
 }
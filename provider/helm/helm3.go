@@ -0,0 +1,240 @@
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/ptypes/any"
+
+	"k8s.io/helm/pkg/helm"
+	hapi_chart "k8s.io/helm/pkg/proto/hapi/chart"
+	hapi_release "k8s.io/helm/pkg/proto/hapi/release"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Helm 3 release storage drivers, same as HELM_DRIVER values of the helm CLI
+const (
+	Helm3DriverSecret    = "secret"
+	Helm3DriverConfigMap = "configmap"
+)
+
+const helm3StatusDeployed = "deployed"
+
+// ErrHelm3UpgradeUnsupported - Helm 3 releases are only reported, they have to be upgraded with helm
+var ErrHelm3UpgradeUnsupported = errors.New("keel doesn't upgrade Helm 3 releases, upgrade the release with helm")
+
+// helm3Release - release as stored by Helm 3, only fields keel reads
+type helm3Release struct {
+	Name      string                 `json:"name,omitempty"`
+	Info      *helm3Info             `json:"info,omitempty"`
+	Chart     json.RawMessage        `json:"chart,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+	Manifest  string                 `json:"manifest,omitempty"`
+	Version   int                    `json:"version,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+type helm3Info struct {
+	FirstDeployed json.RawMessage `json:"first_deployed,omitempty"`
+	Status        string          `json:"status,omitempty"`
+}
+
+// helm3Chart - chart fields of the stored release, Helm 3 doesn't store subcharts
+// with the release
+type helm3Chart struct {
+	Metadata struct {
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		AppVersion  string `json:"appVersion"`
+		APIVersion  string `json:"apiVersion"`
+		Description string `json:"description"`
+		KubeVersion string `json:"kubeVersion"`
+	} `json:"metadata"`
+	Templates []*helm3File           `json:"templates"`
+	Values    map[string]interface{} `json:"values"`
+	Files     []*helm3File           `json:"files"`
+}
+
+type helm3File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// Helm3Implementer - reads Helm 3 releases from cluster storage without Tiller so keel
+// configuration in their values is tracked like with Helm 2 releases. Upgrades need the
+// Helm 3 SDK which requires a newer Kubernetes client than the one keel is built with,
+// so updates of Helm 3 releases are only reported (the provider runs in dry run mode)
+type Helm3Implementer struct {
+	client kubernetes.Interface
+	driver string
+}
+
+// NewHelm3Implementer - get new Helm 3 implementer, driver is the storage used by Helm
+// (secret or configmap)
+func NewHelm3Implementer(client kubernetes.Interface, driver string) (*Helm3Implementer, error) {
+	switch driver {
+	case "", "secrets", Helm3DriverSecret:
+		driver = Helm3DriverSecret
+	case "configmaps", Helm3DriverConfigMap:
+		driver = Helm3DriverConfigMap
+	default:
+		return nil, fmt.Errorf("unsupported Helm 3 storage driver '%s'", driver)
+	}
+
+	log.Infof("provider.helm: Helm 3 releases are read from %s storage, updates are only reported", driver)
+
+	return &Helm3Implementer{
+		client: client,
+		driver: driver,
+	}, nil
+}
+
+// ListReleases - list deployed releases in all namespaces, options are ignored
+func (i *Helm3Implementer) ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error) {
+	stored, err := i.list(meta_v1.NamespaceAll, "owner=helm,status="+helm3StatusDeployed)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &rls.ListReleasesResponse{}
+	for _, r := range stored {
+		release, err := r.toHapi()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"release":   r.Name,
+				"namespace": r.Namespace,
+			}).Error("provider.helm: failed to read Helm 3 release")
+			continue
+		}
+		resp.Releases = append(resp.Releases, release)
+	}
+	resp.Count = int64(len(resp.Releases))
+	return resp, nil
+}
+
+// UpdateRelease - Helm 3 releases are not upgraded by keel, see Helm3Implementer
+func (i *Helm3Implementer) UpdateRelease(namespace, name string, chart *hapi_chart.Chart, overrideValues []byte) (int32, error) {
+	return 0, ErrHelm3UpgradeUnsupported
+}
+
+func (i *Helm3Implementer) list(namespace, selector string) ([]*helm3Release, error) {
+	var encoded []string
+	opts := meta_v1.ListOptions{LabelSelector: selector}
+	if i.driver == Helm3DriverConfigMap {
+		list, err := i.client.CoreV1().ConfigMaps(namespace).List(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, cm := range list.Items {
+			encoded = append(encoded, cm.Data["release"])
+		}
+	} else {
+		list, err := i.client.CoreV1().Secrets(namespace).List(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range list.Items {
+			encoded = append(encoded, string(secret.Data["release"]))
+		}
+	}
+
+	var releases []*helm3Release
+	for _, e := range encoded {
+		r, err := decodeHelm3Release(e)
+		if err != nil {
+			log.WithError(err).Error("provider.helm: failed to decode Helm 3 release")
+			continue
+		}
+		releases = append(releases, r)
+	}
+	return releases, nil
+}
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// decodeHelm3Release - base64 encoded, usually gzipped, release JSON
+func decodeHelm3Release(data string) (*helm3Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		b, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var release helm3Release
+	if err := json.Unmarshal(b, &release); err != nil {
+		return nil, err
+	}
+	if release.Info == nil {
+		release.Info = &helm3Info{}
+	}
+	return &release, nil
+}
+
+// toHapi - converts release into Helm 2 release used by the provider
+func (r *helm3Release) toHapi() (*hapi_release.Release, error) {
+	var ch helm3Chart
+	if err := json.Unmarshal(r.Chart, &ch); err != nil {
+		return nil, fmt.Errorf("failed to decode chart: %s", err)
+	}
+
+	chartValues, err := yaml.Marshal(ch.Values)
+	if err != nil {
+		return nil, err
+	}
+	config, err := yaml.Marshal(r.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	chart := &hapi_chart.Chart{
+		Metadata: &hapi_chart.Metadata{
+			Name:        ch.Metadata.Name,
+			Version:     ch.Metadata.Version,
+			AppVersion:  ch.Metadata.AppVersion,
+			ApiVersion:  ch.Metadata.APIVersion,
+			Description: ch.Metadata.Description,
+			KubeVersion: ch.Metadata.KubeVersion,
+		},
+		Values: &hapi_chart.Config{Raw: string(chartValues)},
+	}
+	for _, t := range ch.Templates {
+		chart.Templates = append(chart.Templates, &hapi_chart.Template{Name: t.Name, Data: t.Data})
+	}
+	for _, f := range ch.Files {
+		chart.Files = append(chart.Files, &any.Any{TypeUrl: f.Name, Value: f.Data})
+	}
+
+	return &hapi_release.Release{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Version:   int32(r.Version),
+		Chart:     chart,
+		Config:    &hapi_chart.Config{Raw: string(config)},
+		Manifest:  r.Manifest,
+		Info: &hapi_release.Info{
+			Status: &hapi_release.Status{Code: hapi_release.Status_DEPLOYED},
+		},
+	}, nil
+}
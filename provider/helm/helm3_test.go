@@ -0,0 +1,107 @@
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	hapi_release5 "k8s.io/helm/pkg/proto/hapi/release"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+)
+
+var helm3ReleaseJSON = `{
+  "name": "web",
+  "namespace": "default",
+  "version": 3,
+  "info": {"status": "deployed", "first_deployed": "2024-01-02T03:04:05Z"},
+  "chart": {
+    "metadata": {"name": "web", "version": "1.2.0", "apiVersion": "v2", "appVersion": "0.1.0"},
+    "templates": [{"name": "templates/deployment.yaml", "data": "a2luZDogRGVwbG95bWVudA=="}],
+    "values": {"image": {"repository": "karolisr/keel", "tag": "0.1.0"}},
+    "files": [{"name": "README.md", "data": "IyB3ZWI="}]
+  },
+  "config": {
+    "image": {"tag": "0.2.0"},
+    "keel": {"policy": "all", "trigger": "poll", "images": [{"repository": "image.repository", "tag": "image.tag"}]}
+  },
+  "manifest": "---\n# Source: web/templates/deployment.yaml\nkind: Deployment\n"
+}`
+
+func TestDecodeHelm3Release(t *testing.T) {
+	// helm stores gzipped releases, older releases may not be compressed
+	plain := base64.StdEncoding.EncodeToString([]byte(helm3ReleaseJSON))
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(helm3ReleaseJSON))
+	w.Close()
+	gzipped := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	for _, encoded := range []string{plain, gzipped} {
+		release, err := decodeHelm3Release(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode release: %s", err)
+		}
+		if release.Name != "web" || release.Namespace != "default" || release.Version != 3 || release.Info.Status != "deployed" {
+			t.Errorf("unexpected release: %+v", release)
+		}
+		if string(release.Info.FirstDeployed) != `"2024-01-02T03:04:05Z"` {
+			t.Errorf("unexpected first deployed: %s", release.Info.FirstDeployed)
+		}
+	}
+}
+
+func TestHelm3ReleaseToHapi(t *testing.T) {
+	var r helm3Release
+	if err := json.Unmarshal([]byte(helm3ReleaseJSON), &r); err != nil {
+		t.Fatalf("failed to unmarshal release: %s", err)
+	}
+
+	release, err := r.toHapi()
+	if err != nil {
+		t.Fatalf("failed to convert release: %s", err)
+	}
+	if release.Chart.Metadata.Name != "web" || release.Chart.Metadata.Version != "1.2.0" {
+		t.Errorf("unexpected chart metadata: %+v", release.Chart.Metadata)
+	}
+	if len(release.Chart.Templates) != 1 || string(release.Chart.Templates[0].Data) != "kind: Deployment" {
+		t.Errorf("unexpected templates: %+v", release.Chart.Templates)
+	}
+	if len(release.Chart.Files) != 1 || release.Chart.Files[0].TypeUrl != "README.md" {
+		t.Errorf("unexpected files: %+v", release.Chart.Files)
+	}
+
+	// keel configuration is read from release values like with Helm 2 releases
+	fakeImpl := &fakeImplementer{
+		listReleasesResponse: &rls.ListReleasesResponse{
+			Releases: []*hapi_release5.Release{release},
+		},
+	}
+	approver, teardown := approver()
+	defer teardown()
+	provider := NewProvider(fakeImpl, &fakeSender{}, approver)
+
+	tracked, err := provider.TrackedImages()
+	if err != nil {
+		t.Fatalf("failed to get tracked images: %s", err)
+	}
+	if len(tracked) != 1 || tracked[0].Image.Remote() != "index.docker.io/karolisr/keel:0.2.0" || tracked[0].Namespace != "default" {
+		t.Errorf("unexpected tracked images: %+v", tracked)
+	}
+}
+
+func TestHelm3UpdateRelease(t *testing.T) {
+	impl, err := NewHelm3Implementer(nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := impl.UpdateRelease("default", "web", nil, nil); err != ErrHelm3UpgradeUnsupported {
+		t.Errorf("expected Helm 3 upgrades to be refused, got: %v", err)
+	}
+
+	if _, err := NewHelm3Implementer(nil, "sql"); err == nil {
+		t.Errorf("expected error for unsupported driver")
+	}
+}
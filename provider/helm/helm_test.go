@@ -64,25 +64,23 @@ type fakeImplementer struct {
 	listReleasesResponse *rls.ListReleasesResponse
 
 	// updated info
-	updatedRlsName string
-	updatedChart   *chart.Chart
-	updatedOptions []helm.UpdateOption
+	updatedNamespace string
+	updatedRlsName   string
+	updatedChart     *chart.Chart
+	updatedValues    []byte
 }
 
 func (i *fakeImplementer) ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error) {
 	return i.listReleasesResponse, nil
 }
 
-func (i *fakeImplementer) UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error) {
-	i.updatedRlsName = rlsName
+func (i *fakeImplementer) UpdateRelease(namespace, name string, chart *chart.Chart, overrideValues []byte) (int32, error) {
+	i.updatedNamespace = namespace
+	i.updatedRlsName = name
 	i.updatedChart = chart
-	i.updatedOptions = opts
+	i.updatedValues = overrideValues
 
-	return &rls.UpdateReleaseResponse{
-		Release: &hapi_release5.Release{
-			Version: 2,
-		},
-	}, nil
+	return 2, nil
 }
 
 // helper function to generate keel configuration
@@ -435,9 +433,10 @@ keel:
 		listReleasesResponse: &rls.ListReleasesResponse{
 			Releases: []*hapi_release5.Release{
 				&hapi_release5.Release{
-					Name:   "release-1",
-					Chart:  myChart,
-					Config: &chart.Config{Raw: ""},
+					Name:      "release-1",
+					Namespace: "default",
+					Chart:     myChart,
+					Config:    &chart.Config{Raw: ""},
 				},
 			},
 		},
@@ -462,8 +461,12 @@ keel:
 		t.Errorf("wrong chart updated")
 	}
 
-	if fakeImpl.updatedRlsName != "release-1" {
-		t.Errorf("unexpected release updated: %s", fakeImpl.updatedRlsName)
+	if fakeImpl.updatedRlsName != "release-1" || fakeImpl.updatedNamespace != "default" {
+		t.Errorf("unexpected release updated: %s/%s", fakeImpl.updatedNamespace, fakeImpl.updatedRlsName)
+	}
+
+	if string(fakeImpl.updatedValues) != "image:\n  tag: 0.0.11\n" {
+		t.Errorf("unexpected override values: %s", fakeImpl.updatedValues)
 	}
 }
 
@@ -13,10 +13,13 @@ var (
 	TillerAddress = "tiller-deploy:44134"
 )
 
-// Implementer - generic helm implementer used to abstract actual implementation
+// Implementer - generic helm implementer used to abstract actual implementation,
+// releases are listed and upgraded either through Tiller (Helm 2) or directly (Helm 3)
 type Implementer interface {
 	ListReleases(opts ...helm.ReleaseListOption) (*rls.ListReleasesResponse, error)
-	UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error)
+	// UpdateRelease - upgrades release with the chart, override values are merged with
+	// the values release was installed with. Returns new release version
+	UpdateRelease(namespace, name string, chart *chart.Chart, overrideValues []byte) (int32, error)
 }
 
 // HelmImplementer - actual helm implementer
@@ -46,3 +49,21 @@ func (i *HelmImplementer) ListReleases(opts ...helm.ReleaseListOption) (*rls.Lis
 func (i *HelmImplementer) UpdateReleaseFromChart(rlsName string, chart *chart.Chart, opts ...helm.UpdateOption) (*rls.UpdateReleaseResponse, error) {
 	return i.client.UpdateReleaseFromChart(rlsName, chart, opts...)
 }
+
+// UpdateRelease - update release through Tiller, release names are unique across namespaces
+func (i *HelmImplementer) UpdateRelease(namespace, name string, chart *chart.Chart, overrideValues []byte) (int32, error) {
+	resp, err := i.UpdateReleaseFromChart(name, chart,
+		helm.UpdateValueOverrides(overrideValues),
+		helm.UpgradeDryRun(false),
+		helm.UpgradeRecreate(false),
+		helm.UpgradeForce(true),
+		helm.UpgradeDisableHooks(false),
+		helm.UpgradeTimeout(DefaultUpdateTimeout),
+		helm.ResetValues(false),
+		helm.ReuseValues(true),
+		helm.UpgradeWait(true))
+	if err != nil {
+		return 0, err
+	}
+	return resp.Release.Version, nil
+}
@@ -51,13 +51,14 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 	}
 	log.Infof("policy for release %s/%s parsed: %s", namespace, name, keelCfg.Plc.Name())
 
-	if keelCfg.Plc.Type() == policy.PolicyTypeNone {
-		// policy is not set, ignoring release
-		return plan, false, nil
-	}
-
 	// checking for impacted images
 	for _, imageDetails := range keelCfg.Images {
+		plc := keelCfg.imagePolicy(&imageDetails)
+		if plc.Type() == policy.PolicyTypeNone {
+			// policy is not set, ignoring image
+			continue
+		}
+
 		imageRef, err := parseImage(vals, &imageDetails)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -68,7 +69,7 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 			continue
 		}
 
-		if imageRef.Repository() != eventRepoRef.Repository() {
+		if !image.RepositoryMatches(imageRef, eventRepoRef) {
 			log.WithFields(log.Fields{
 				"parsed_image_name": imageRef.Remote(),
 				"target_image_name": repo.Name,
@@ -76,7 +77,13 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 			continue
 		}
 
-		shouldUpdate, err := keelCfg.Plc.ShouldUpdate(imageRef.Tag(), eventRepoRef.Tag())
+		shouldUpdate, err := policy.ShouldUpdate(plc, &policy.UpdateContext{
+			Provider:  ProviderName,
+			Kind:      "release",
+			Namespace: namespace,
+			Name:      name,
+			Image:     imageRef.Remote(),
+		}, imageRef.Tag(), eventRepoRef.Tag())
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":           err,
@@ -90,7 +97,7 @@ func checkRelease(repo *types.Repository, namespace, name string, chart *hapi_ch
 			log.WithFields(log.Fields{
 				"parsed_image_name": imageRef.Remote(),
 				"target_image_name": repo.Name,
-				"policy":            keelCfg.Plc.Name(),
+				"policy":            plc.Name(),
 			}).Info("provider.helm: ignoring")
 			continue
 		}
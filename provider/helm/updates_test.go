@@ -464,3 +464,49 @@ image:
 		})
 	}
 }
+
+func Test_checkReleasePerImagePolicy(t *testing.T) {
+	chart := &hapi_chart.Chart{
+		Values:   &hapi_chart.Config{Raw: chartValuesMultipleImages},
+		Metadata: &hapi_chart.Metadata{Name: "app-x"},
+	}
+
+	tests := []struct {
+		name       string
+		repo       *types.Repository
+		wantUpdate bool
+		wantValues map[string]string
+	}{
+		{
+			name:       "major update allowed by image policy",
+			repo:       &types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "2.0.0"},
+			wantUpdate: true,
+			wantValues: map[string]string{"image.tag": "2.0.0"},
+		},
+		{
+			name:       "sidecar patch update",
+			repo:       &types.Repository{Name: "karolisr/keel-sidecar", Tag: "0.1.1"},
+			wantUpdate: true,
+			wantValues: map[string]string{"sidecar.image": "karolisr/keel-sidecar:0.1.1"},
+		},
+		{
+			name:       "sidecar minor update blocked by image policy",
+			repo:       &types.Repository{Name: "karolisr/keel-sidecar", Tag: "0.2.0"},
+			wantValues: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, update, err := checkRelease(tt.repo, "default", "release-1", chart, &hapi_chart.Config{Raw: ""})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if update != tt.wantUpdate {
+				t.Errorf("checkRelease() update = %v, want %v", update, tt.wantUpdate)
+			}
+			if !reflect.DeepEqual(plan.Values, tt.wantValues) {
+				t.Errorf("checkRelease() values = %v, want %v", plan.Values, tt.wantValues)
+			}
+		})
+	}
+}
@@ -2,11 +2,14 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/keel-hq/keel/pkg/store"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -18,7 +21,21 @@ func getApprovalIdentifier(resourceIdentifier, version string) string {
 // checkForApprovals - filters out deployments and only passes forward approved ones
 func (p *Provider) checkForApprovals(event *types.Event, plans []*UpdatePlan) (approvedPlans []*UpdatePlan) {
 	approvedPlans = []*UpdatePlan{}
+	groups := make(map[string][]*UpdatePlan)
+	var groupOrder []string
 	for _, plan := range plans {
+		if p.groupApprovals {
+			minApprovals, err := getMinApprovals(plan)
+			if err == nil && minApprovals > 0 {
+				identifier := p.groupApprovalIdentifier(event, plan)
+				if _, ok := groups[identifier]; !ok {
+					groupOrder = append(groupOrder, identifier)
+				}
+				groups[identifier] = append(groups[identifier], plan)
+				continue
+			}
+		}
+
 		approved, err := p.isApproved(event, plan)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -32,14 +49,33 @@ func (p *Provider) checkForApprovals(event *types.Event, plans []*UpdatePlan) (a
 			approvedPlans = append(approvedPlans, plan)
 		}
 	}
+
+	for _, identifier := range groupOrder {
+		approved, err := p.isGroupApproved(event, identifier, groups[identifier])
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": identifier,
+			}).Error("provider.kubernetes: failed to check grouped approval status")
+			continue
+		}
+		if approved {
+			approvedPlans = append(approvedPlans, groups[identifier]...)
+		}
+	}
 	return approvedPlans
 }
 
 // updateComplete is called after we successfully update resource
 func (p *Provider) updateComplete(plan *UpdatePlan) error {
-	return p.approvalManager.Archive(getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion))
+	if plan.ApprovalGroup != "" {
+		// archived once all resources of the group are updated
+		return nil
+	}
+	return p.approvalManager.Archive(p.approvalIdentifier(plan))
 }
 
+// getInt - gets integer setting from annotations or labels, annotations take precedence
 func getInt(key string, labels map[string]string, annotations map[string]string) (int, error) {
 
 	var (
@@ -47,7 +83,7 @@ func getInt(key string, labels map[string]string, annotations map[string]string)
 		ok     bool
 	)
 
-	valStr, ok = labels[key]
+	valStr, ok = annotations[key]
 	if ok {
 		valInt, err := strconv.Atoi(valStr)
 		if err != nil {
@@ -56,7 +92,7 @@ func getInt(key string, labels map[string]string, annotations map[string]string)
 		return valInt, nil
 	}
 
-	valStr, ok = annotations[key]
+	valStr, ok = labels[key]
 	if ok {
 		valInt, err := strconv.Atoi(valStr)
 		if err != nil {
@@ -68,30 +104,103 @@ func getInt(key string, labels map[string]string, annotations map[string]string)
 	return 0, nil
 }
 
-func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error) {
+// getString - gets setting from annotations or labels, annotations take precedence
+func getString(key string, labels map[string]string, annotations map[string]string) string {
+	if v, ok := annotations[key]; ok {
+		return v
+	}
+	return labels[key]
+}
 
-	minApprovals, err := getInt(types.KeelMinimumApprovalsLabel, plan.Resource.GetLabels(), plan.Resource.GetAnnotations())
+// getMinApprovals - gets required approvals for the plan. Containers can require their
+// own approvals count (keel.sh/approvals.<container name>), highest count among
+// containers that are being updated to the new version wins, plan can raise it further
+func getMinApprovals(plan *UpdatePlan) (int, error) {
+	labels := plan.Resource.GetLabels()
+	annotations := plan.Resource.GetAnnotations()
+
+	minApprovals, err := getInt(types.KeelMinimumApprovalsLabel, labels, annotations)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
-	if minApprovals == 0 {
-		return true, nil
+	for _, c := range plan.Resource.Containers() {
+		ref, err := image.Parse(c.Image)
+		if err != nil || ref.Tag() != plan.NewVersion {
+			continue
+		}
+
+		containerApprovals, err := getInt(types.KeelContainerKey(types.KeelMinimumApprovalsLabel, c.Name), labels, annotations)
+		if err != nil {
+			return 0, err
+		}
+		if containerApprovals > minApprovals {
+			minApprovals = containerApprovals
+		}
+	}
+
+	if plan.RequiredApprovals > minApprovals {
+		minApprovals = plan.RequiredApprovals
 	}
 
+	return minApprovals, nil
+}
+
+// newApproval - approval request for the plan with resource approval settings
+func (p *Provider) newApproval(event *types.Event, plan *UpdatePlan, identifier string, minApprovals int) *types.Approval {
 	// deadline
-	deadline := types.KeelApprovalDeadlineDefault
-	d, err := getInt(types.KeelApprovalDeadlineLabel, plan.Resource.GetLabels(), plan.Resource.GetAnnotations())
+	deadline := time.Duration(types.KeelApprovalDeadlineDefault) * time.Hour
+	if v := getString(types.KeelApprovalDeadlineLabel, plan.Resource.GetLabels(), plan.Resource.GetAnnotations()); v != "" {
+		d, err := types.ParseApprovalDeadline(v)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"resource": plan.Resource.GetName(),
+			}).Warn("failed to parse approvals deadline, using default value")
+		} else {
+			deadline = d
+		}
+	}
+
+	expiryAction, err := types.ParseApprovalExpiryAction(plan.Resource.GetAnnotations()[types.KeelApprovalExpiryAnnotation])
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error":    err,
 			"resource": plan.Resource.GetName(),
-		}).Warn("failed to parse approvals deadline, using default value")
-	} else if d != 0 {
-		deadline = d
+		}).Warn("failed to parse approval expiry action, approval will expire")
+		expiryAction = types.ApprovalExpiryExpire
+	}
+
+	return &types.Approval{
+		Provider:       types.ProviderTypeKubernetes,
+		Identifier:     identifier,
+		Event:          event,
+		CurrentVersion: plan.CurrentVersion,
+		NewVersion:     plan.NewVersion,
+		VotesRequired:  minApprovals,
+		VotesReceived:  0,
+		Rejected:       false,
+		Deadline:       time.Now().Add(deadline),
+
+		ExpiryAction:       expiryAction,
+		EscalationChannels: plan.Resource.GetAnnotations()[types.KeelApprovalEscalationChannelsAnnotation],
+		Approvers:          plan.Resource.GetAnnotations()[types.KeelApproversAnnotation],
+		Channels:           strings.Join(p.notificationChannels(plan.Resource), ","),
+	}
+}
+
+func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error) {
+
+	minApprovals, err := getMinApprovals(plan)
+	if err != nil {
+		return false, err
+	}
+
+	if minApprovals == 0 {
+		return true, nil
 	}
 
-	identifier := getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion)
+	identifier := p.approvalIdentifier(plan)
 
 	// checking for existing approval
 	existing, err := p.approvalManager.Get(identifier)
@@ -106,23 +215,15 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 			}
 
 			// creating new one
-			approval := &types.Approval{
-				Provider:       types.ProviderTypeKubernetes,
-				Identifier:     identifier,
-				Event:          event,
-				CurrentVersion: plan.CurrentVersion,
-				NewVersion:     plan.NewVersion,
-				VotesRequired:  minApprovals,
-				VotesReceived:  0,
-				Rejected:       false,
-				Deadline:       time.Now().Add(time.Duration(deadline) * time.Hour),
-			}
-
+			approval := p.newApproval(event, plan, identifier, minApprovals)
 			approval.Message = fmt.Sprintf("New image is available for resource %s/%s (%s).",
 				plan.Resource.Namespace,
 				plan.Resource.Name,
 				approval.Delta(),
 			)
+			if plan.ApprovalReason != "" {
+				approval.Message = approval.Message + " " + plan.ApprovalReason
+			}
 
 			return false, p.approvalManager.Create(approval)
 		}
@@ -142,5 +243,11 @@ func (p *Provider) isApproved(event *types.Event, plan *UpdatePlan) (bool, error
 	// 	"new":      event.Repository.Digest,
 	// }).Info("digests match")
 
-	return existing.Status() == types.ApprovalStatusApproved, nil
+	if existing.Status() != types.ApprovalStatusApproved {
+		return false, nil
+	}
+	plan.ApprovedBy = existing.GetVoters()
+	sort.Strings(plan.ApprovedBy)
+
+	return true, nil
 }
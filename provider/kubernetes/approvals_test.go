@@ -340,3 +340,24 @@ func TestApprovalsCleanup(t *testing.T) {
 		t.Logf("approval status: %v, identifier: %s", approvals[0].Archived, approvals[0].Identifier)
 	}
 }
+
+func TestGetIntAnnotationsPrecedence(t *testing.T) {
+	labels := map[string]string{types.KeelMinimumApprovalsLabel: "1"}
+	annotations := map[string]string{types.KeelMinimumApprovalsLabel: "3"}
+
+	val, err := getInt(types.KeelMinimumApprovalsLabel, labels, annotations)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 3 {
+		t.Errorf("expected annotation value 3, got: %d", val)
+	}
+
+	val, err = getInt(types.KeelMinimumApprovalsLabel, labels, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected label value 1, got: %d", val)
+	}
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestArgoRollout(image string, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata": map[string]interface{}{
+			"name":      "rollout-1",
+			"namespace": "xxxx",
+			"labels": map[string]interface{}{
+				types.KeelPolicyLabel: "all",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "hello",
+							"image": image,
+						},
+					},
+				},
+			},
+		},
+		"status": status,
+	}}
+}
+
+func TestProcessEventArgoRollout(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestArgoRollout("gcr.io/v2-namespace/hello-world:1.1.1", nil)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("rollout was not updated")
+	}
+	if fp.updated.Kind() != "rollout" {
+		t.Errorf("unexpected kind: %s", fp.updated.Kind())
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+	if fp.updated.GetSpecAnnotations()[types.KeelUpdateTimeAnnotation] == "" {
+		t.Errorf("expected update time annotation to be set")
+	}
+}
+
+func TestGetArgoRolloutStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     map[string]interface{}
+		wantDone   bool
+		wantFailed bool
+	}{
+		{
+			name:   "in progress",
+			status: map[string]interface{}{"updatedReplicas": int64(1), "availableReplicas": int64(2)},
+		},
+		{
+			name:     "done",
+			status:   map[string]interface{}{"updatedReplicas": int64(2), "availableReplicas": int64(2)},
+			wantDone: true,
+		},
+		{
+			name:       "aborted",
+			status:     map[string]interface{}{"abort": true, "updatedReplicas": int64(1)},
+			wantFailed: true,
+		},
+		{
+			name:       "degraded",
+			status:     map[string]interface{}{"phase": "Degraded"},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, failed := getRolloutStatus(MustParseGR(newTestArgoRollout("karolisr/keel:0.2.0", tt.status)))
+			if done != tt.wantDone {
+				t.Errorf("getRolloutStatus() done = %v, want %v", done, tt.wantDone)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("getRolloutStatus() failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
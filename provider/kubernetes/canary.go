@@ -0,0 +1,423 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// canaryLabel - added to canary deployment selector and pod labels so canary pods
+// are not adopted by the original deployment
+const canaryLabel = "keel.sh/canary"
+
+// canarySuffix - appended to the name of cloned canary deployments
+const canarySuffix = "-keel-canary"
+
+// canaryState - running canary deployment for a resource
+type canaryState struct {
+	version   string
+	namespace string
+	name      string
+	started   time.Time
+}
+
+// canaries - running canaries keyed by resource identifier and versions whose canaries
+// failed, keyed by image repository and tag
+type canaries struct {
+	mu      sync.Mutex
+	running map[string]*canaryState
+	aborted map[string]string
+}
+
+func (c *canaries) get(identifier string) *canaryState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running[identifier]
+}
+
+func (c *canaries) set(identifier string, state *canaryState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running == nil {
+		c.running = make(map[string]*canaryState)
+	}
+	c.running[identifier] = state
+}
+
+func (c *canaries) remove(identifier string) {
+	c.mu.Lock()
+	delete(c.running, identifier)
+	c.mu.Unlock()
+}
+
+// identifiers - returns identifiers of resources with running canaries
+func (c *canaries) identifiers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var identifiers []string
+	for identifier := range c.running {
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+func (c *canaries) abort(key, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.aborted == nil {
+		c.aborted = make(map[string]string)
+	}
+	c.aborted[key] = reason
+}
+
+func (c *canaries) abortReason(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reason, ok := c.aborted[key]
+	return reason, ok
+}
+
+// canaryKey - versions are aborted per image so that all resources using it stay on the
+// previous version
+func canaryKey(ref *image.Reference) string {
+	return image.MatchKey(ref) + ":" + ref.Tag()
+}
+
+// isDesignatedCanary - resources with keel.sh/canary set to "true" are updated before
+// all other resources using the same image
+func isDesignatedCanary(annotations map[string]string) bool {
+	return strings.ToLower(strings.TrimSpace(annotations[types.KeelCanaryAnnotation])) == "true"
+}
+
+// getCanaryPercentage - gets percentage of replicas that should run in a cloned
+// canary deployment, for example "25%"
+func getCanaryPercentage(annotations map[string]string) (int, bool, error) {
+	val := strings.TrimSpace(annotations[types.KeelCanaryAnnotation])
+	if !strings.HasSuffix(val, "%") {
+		return 0, false, nil
+	}
+	pct, err := strconv.Atoi(strings.TrimSuffix(val, "%"))
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, false, fmt.Errorf("invalid canary percentage '%s'", val)
+	}
+	return pct, true, nil
+}
+
+// getCanaryTimeout - canaries use rollout timeout if it's set
+func getCanaryTimeout(resource *k8s.GenericResource) time.Duration {
+	if timeout, ok := getRolloutTimeout(resource); ok {
+		return timeout
+	}
+	return types.KeelCanaryTimeoutDefault
+}
+
+// newCanaryDeployment - clones updated deployment, canary runs a percentage of original
+// replicas (at least one) and gets its own selector label, everything keel related is
+// removed so the clone itself is never tracked
+func newCanaryDeployment(deployment *apps_v1.Deployment, pct int) *apps_v1.Deployment {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	canaryReplicas := int32(math.Ceil(float64(replicas) * float64(pct) / 100))
+	if canaryReplicas < 1 {
+		canaryReplicas = 1
+	}
+
+	labels := map[string]string{canaryLabel: "true"}
+	for k, v := range deployment.Labels {
+		if !strings.HasPrefix(k, "keel.sh/") {
+			labels[k] = v
+		}
+	}
+
+	spec := *deployment.Spec.DeepCopy()
+	spec.Replicas = &canaryReplicas
+	if spec.Selector == nil {
+		spec.Selector = &meta_v1.LabelSelector{}
+	}
+	if spec.Selector.MatchLabels == nil {
+		spec.Selector.MatchLabels = make(map[string]string)
+	}
+	spec.Selector.MatchLabels[canaryLabel] = "true"
+	if spec.Template.Labels == nil {
+		spec.Template.Labels = make(map[string]string)
+	}
+	spec.Template.Labels[canaryLabel] = "true"
+
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      deployment.Name + canarySuffix,
+			Namespace: deployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: spec,
+	}
+}
+
+// abortedEventKey - event key, empty if the event image can't be parsed
+func abortedEventKey(repo *types.Repository) string {
+	ref, err := image.Parse(repo.String())
+	if err != nil {
+		return ""
+	}
+	return canaryKey(ref)
+}
+
+// checkForAbortedCanaries - filters out plans for versions whose canary failed
+func (p *Provider) checkForAbortedCanaries(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	reason, ok := p.canaries.abortReason(abortedEventKey(&event.Repository))
+	if !ok {
+		return plans
+	}
+	log.WithFields(log.Fields{
+		"image":  event.Repository.Name,
+		"tag":    event.Repository.Tag,
+		"reason": reason,
+	}).Info("provider.kubernetes: canary for this version failed, skipping updates")
+	return []*UpdatePlan{}
+}
+
+// abortCanary - stops promotion of a version after its canary failed
+func (p *Provider) abortCanary(key string, resource *k8s.GenericResource, version, reason string) {
+	if key != "" {
+		p.canaries.abort(key, reason)
+	}
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"version":   version,
+		"reason":    reason,
+	}).Warn("provider.kubernetes: canary failed, update aborted")
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "canary aborted",
+		Message:      fmt.Sprintf("Canary of %s %s/%s version %s %s, update aborted", resource.Kind(), resource.Namespace, resource.Name, version, reason),
+		CreatedAt:    timeutil.Now(),
+		Type:         types.NotificationDeploymentUpdate,
+		Level:        types.LevelError,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
+
+// abortRolledBackCanary - designated canaries that were rolled back abort the version
+func (p *Provider) abortRolledBackCanary(plan *UpdatePlan, reason string) {
+	if !isDesignatedCanary(plan.Resource.GetAnnotations()) {
+		return
+	}
+	for idx, img := range getUpdatableImages(plan.Resource) {
+		if idx < len(plan.PreviousImages) && plan.PreviousImages[idx] == img {
+			continue
+		}
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		p.abortCanary(canaryKey(ref), plan.Resource, plan.NewVersion, reason)
+	}
+}
+
+// checkForCanary - deployments with a canary percentage are not updated directly, a cloned
+// canary deployment is created first and the update is staged until the canary is healthy.
+// Failed canaries are removed and the version is aborted.
+func (p *Provider) checkForCanary(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		deployment, isDeployment := resource.GetResource().(*apps_v1.Deployment)
+		pct, ok, err := getCanaryPercentage(resource.GetAnnotations())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse canary percentage, skipping update")
+			continue
+		}
+		if !ok || !isDeployment {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		state := p.canaries.get(resource.Identifier)
+		if state == nil || state.version != plan.NewVersion {
+			p.startCanary(event, plan, deployment, pct, state)
+			continue
+		}
+
+		// keeping update staged while canary is running
+		p.staged.add(resource.Identifier, event)
+
+		live, err := p.implementer.Deployment(resource.Namespace, state.name)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      state.name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: failed to get canary deployment")
+			continue
+		}
+		gr, err := k8s.NewGenericResource(live)
+		if err != nil {
+			continue
+		}
+
+		done, failed := getRolloutStatus(gr)
+		switch {
+		case done:
+			log.WithFields(log.Fields{
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+				"version":   plan.NewVersion,
+			}).Info("provider.kubernetes: canary is healthy, promoting update")
+			p.stopCanary(resource.Identifier, state)
+			allowedPlans = append(allowedPlans, plan)
+		case failed:
+			p.stopCanary(resource.Identifier, state)
+			p.abortCanary(abortedEventKey(&event.Repository), resource, plan.NewVersion, "failed")
+		case timeutil.Now().Sub(state.started) > getCanaryTimeout(resource):
+			p.stopCanary(resource.Identifier, state)
+			p.abortCanary(abortedEventKey(&event.Repository), resource, plan.NewVersion, fmt.Sprintf("didn't become healthy in %s", getCanaryTimeout(resource)))
+		}
+	}
+
+	return allowedPlans
+}
+
+// startCanary - creates canary deployment for the new version, canary of a previous
+// version is replaced
+func (p *Provider) startCanary(event *types.Event, plan *UpdatePlan, deployment *apps_v1.Deployment, pct int, previous *canaryState) {
+	resource := plan.Resource
+	if previous != nil {
+		p.stopCanary(resource.Identifier, previous)
+	}
+
+	canary := newCanaryDeployment(deployment, pct)
+	started := timeutil.Now()
+	_, err := p.implementer.CreateDeployment(canary)
+	if errors.IsAlreadyExists(err) {
+		started, err = p.replaceCanary(canary)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      canary.Name,
+			"namespace": canary.Namespace,
+		}).Error("provider.kubernetes: failed to create canary deployment")
+		return
+	}
+
+	p.canaries.set(resource.Identifier, &canaryState{
+		version:   plan.NewVersion,
+		namespace: canary.Namespace,
+		name:      canary.Name,
+		started:   started,
+	})
+	p.staged.add(resource.Identifier, event)
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "canary started",
+		Message:      fmt.Sprintf("Started canary %s/%s with %d replica(s) for update %s->%s", canary.Namespace, canary.Name, *canary.Spec.Replicas, plan.CurrentVersion, plan.NewVersion),
+		CreatedAt:    timeutil.Now(),
+		Type:         types.NotificationPreDeploymentUpdate,
+		Level:        types.LevelInfo,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
+
+// replaceCanary - canary deployments left behind (ie: keel restarted while a canary was
+// running) are adopted when they run the same images, otherwise they are updated to the
+// new version. Returns when the canary was started
+func (p *Provider) replaceCanary(canary *apps_v1.Deployment) (time.Time, error) {
+	existing, err := p.implementer.Deployment(canary.Namespace, canary.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if existing.DeletionTimestamp != nil {
+		return time.Time{}, fmt.Errorf("previous canary deployment is being deleted")
+	}
+
+	if sameContainerImages(existing.Spec.Template.Spec.Containers, canary.Spec.Template.Spec.Containers) {
+		log.WithFields(log.Fields{
+			"name":      canary.Name,
+			"namespace": canary.Namespace,
+		}).Info("provider.kubernetes: adopting existing canary deployment")
+		return existing.CreationTimestamp.Time, nil
+	}
+
+	existing.Labels = canary.Labels
+	existing.Spec = canary.Spec
+	gr, err := k8s.NewGenericResource(existing)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := p.implementer.Update(gr); err != nil {
+		return time.Time{}, err
+	}
+	log.WithFields(log.Fields{
+		"name":      canary.Name,
+		"namespace": canary.Namespace,
+	}).Info("provider.kubernetes: replaced existing canary deployment")
+	return timeutil.Now(), nil
+}
+
+func sameContainerImages(a, b []v1.Container) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx].Name != b[idx].Name || a[idx].Image != b[idx].Image {
+			return false
+		}
+	}
+	return true
+}
+
+// stopCanary - deletes canary deployment
+func (p *Provider) stopCanary(identifier string, state *canaryState) {
+	p.canaries.remove(identifier)
+	p.staged.remove(identifier)
+
+	err := p.implementer.DeleteDeployment(state.namespace, state.name)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      state.name,
+			"namespace": state.namespace,
+		}).Error("provider.kubernetes: failed to delete canary deployment")
+	}
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newCanaryTestProvider(t *testing.T, fp *fakeImplementer, sender *fakeSender, deployments ...*apps_v1.Deployment) (*Provider, *k8s.GenericResourceCache, func()) {
+	grc := &k8s.GenericResourceCache{}
+	for _, d := range deployments {
+		grc.Add(MustParseGR(d))
+	}
+	approver, teardown := approver()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		teardown()
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	return provider, grc, teardown
+}
+
+var canaryTestEvent = &types.Event{Repository: types.Repository{
+	Name: "gcr.io/v2-namespace/hello-world",
+	Tag:  "1.1.2",
+}}
+
+func TestCanaryPercentagePromoted(t *testing.T) {
+	fp := &fakeImplementer{}
+	provider, _, teardown := newCanaryTestProvider(t, fp, &fakeSender{},
+		newTestDeployment("xxxx", "web", 4, map[string]string{types.KeelCanaryAnnotation: "25%"}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"}))
+	defer teardown()
+
+	_, err := provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("deployment shouldn't be updated before canary is healthy")
+	}
+	if len(fp.createdDeployments) != 1 {
+		t.Fatalf("expected canary deployment to be created")
+	}
+
+	canary := fp.createdDeployments[0]
+	if canary.Name != "web-keel-canary" || *canary.Spec.Replicas != 1 {
+		t.Errorf("unexpected canary: %s, replicas %d", canary.Name, *canary.Spec.Replicas)
+	}
+	if canary.Spec.Template.Spec.Containers[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected canary image: %s", canary.Spec.Template.Spec.Containers[0].Image)
+	}
+	if canary.Spec.Selector.MatchLabels[canaryLabel] != "true" || canary.Spec.Template.Labels[canaryLabel] != "true" {
+		t.Errorf("expected canary selector label")
+	}
+	if _, ok := canary.Labels[types.KeelPolicyLabel]; ok {
+		t.Errorf("canary shouldn't be tracked by keel")
+	}
+
+	// canary still starting
+	fp.deployment = canary.DeepCopy()
+	provider.processStaged()
+	if fp.updated != nil {
+		t.Fatalf("deployment shouldn't be updated before canary is healthy")
+	}
+
+	fp.deployment.Generation = 1
+	fp.deployment.Status = apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	provider.processStaged()
+
+	if fp.updated == nil {
+		t.Fatalf("expected deployment to be promoted")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+	if len(fp.deletedDeployments) != 1 || fp.deletedDeployments[0] != "xxxx/web-keel-canary" {
+		t.Errorf("expected canary to be deleted, got: %v", fp.deletedDeployments)
+	}
+}
+
+func TestCanaryPercentageAborted(t *testing.T) {
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+	provider, _, teardown := newCanaryTestProvider(t, fp, sender,
+		newTestDeployment("xxxx", "web", 4, map[string]string{types.KeelCanaryAnnotation: "25%"}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"}))
+	defer teardown()
+
+	_, err := provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	fp.deployment = fp.createdDeployments[0].DeepCopy()
+	fp.deployment.Status.Conditions = []apps_v1.DeploymentCondition{
+		{Type: apps_v1.DeploymentProgressing, Status: v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+	}
+	provider.processStaged()
+
+	if fp.updated != nil {
+		t.Fatalf("deployment shouldn't be updated after canary failed")
+	}
+	if len(fp.deletedDeployments) != 1 {
+		t.Errorf("expected canary to be deleted")
+	}
+	if sender.sentEvent.Name != "canary aborted" {
+		t.Errorf("expected canary aborted notification, got: %s", sender.sentEvent.Name)
+	}
+
+	// aborted version is not retried
+	_, err = provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if len(fp.createdDeployments) != 1 {
+		t.Errorf("expected aborted version not to start another canary")
+	}
+}
+
+func TestDesignatedCanary(t *testing.T) {
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+	healthy := apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 4, UpdatedReplicas: 4, AvailableReplicas: 4}
+	provider, grc, teardown := newCanaryTestProvider(t, fp, sender,
+		newTestDeployment("canary", "web", 4, map[string]string{types.KeelCanaryAnnotation: "true"}, healthy, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"}),
+		newTestDeployment("prod", "web", 4, map[string]string{types.KeelCanaryAnnotation: ""}, healthy, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"}))
+	defer teardown()
+
+	_, err := provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil || fp.updated.Namespace != "canary" {
+		t.Fatalf("expected only canary to be updated, got: %v", fp.updated)
+	}
+
+	// canary rollout failed
+	failed := newTestDeployment("canary", "web", 4, map[string]string{types.KeelCanaryAnnotation: "true"}, apps_v1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Conditions: []apps_v1.DeploymentCondition{
+			{Type: apps_v1.DeploymentProgressing, Status: v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		},
+	}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.2"})
+	grc.Add(MustParseGR(failed))
+	fp.updated = nil
+
+	provider.processStaged()
+	if fp.updated != nil {
+		t.Fatalf("prod shouldn't be updated after canary failed")
+	}
+	if sender.sentEvent.Name != "canary aborted" {
+		t.Errorf("expected canary aborted notification, got: %s", sender.sentEvent.Name)
+	}
+	if _, ok := provider.canaries.abortReason(abortedEventKey(&canaryTestEvent.Repository)); !ok {
+		t.Errorf("expected version to be aborted")
+	}
+}
+
+func TestCanaryLeftBehind(t *testing.T) {
+	fp := &fakeImplementer{
+		createDeploymentErr: errors.NewAlreadyExists(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web-keel-canary"),
+	}
+	deployment := newTestDeployment("xxxx", "web", 4, map[string]string{types.KeelCanaryAnnotation: "25%"}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"})
+	provider, _, teardown := newCanaryTestProvider(t, fp, &fakeSender{}, deployment)
+	defer teardown()
+
+	// canary of an older version is updated to the new one
+	previous := newCanaryDeployment(deployment, 25)
+	fp.deployment = previous.DeepCopy()
+	_, err := provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil || fp.updated.Name != "web-keel-canary" || fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Fatalf("expected canary to be replaced, got: %v", fp.updated)
+	}
+	if state := provider.canaries.get("deployment/xxxx/web"); state == nil || state.version != "1.1.2" {
+		t.Fatalf("expected canary to be running, got: %+v", state)
+	}
+
+	// canary of the same version is adopted after restart
+	provider.canaries.remove("deployment/xxxx/web")
+	fp.updated = nil
+	fp.deployment = fp.deployment.DeepCopy()
+	fp.deployment.Spec.Template.Spec.Containers[0].Image = "gcr.io/v2-namespace/hello-world:1.1.2"
+	_, err = provider.processEvent(canaryTestEvent)
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Errorf("expected canary to be adopted, got update: %v", fp.updated)
+	}
+	if state := provider.canaries.get("deployment/xxxx/web"); state == nil || state.name != "web-keel-canary" {
+		t.Errorf("expected canary to be adopted, got: %+v", state)
+	}
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+)
+
+// Cluster - additional cluster managed by the same keel instance
+type Cluster struct {
+	Name string
+	// ConfigPath - kubeconfig path
+	ConfigPath string
+	// Context - kubeconfig context, current context is used if empty
+	Context string
+}
+
+// ParseClusters - parses comma separated list of clusters, each entry is
+// "[name=][kubeconfig path][#context]". Entry without a path separator or '#'
+// is treated as a context name, missing path defaults to defaultConfigPath
+// and missing name defaults to context name or kubeconfig file name.
+func ParseClusters(clusters, defaultConfigPath string) ([]Cluster, error) {
+	var result []Cluster
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(clusters, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var c Cluster
+		rest := entry
+		if idx := strings.Index(entry, "="); idx != -1 {
+			c.Name = strings.TrimSpace(entry[:idx])
+			rest = strings.TrimSpace(entry[idx+1:])
+		}
+
+		switch {
+		case strings.Contains(rest, "#"):
+			idx := strings.Index(rest, "#")
+			c.ConfigPath = rest[:idx]
+			c.Context = rest[idx+1:]
+		case strings.ContainsRune(rest, filepath.Separator) || strings.Contains(rest, "/"):
+			c.ConfigPath = rest
+		default:
+			c.Context = rest
+		}
+
+		if c.ConfigPath == "" {
+			c.ConfigPath = defaultConfigPath
+		}
+		if c.ConfigPath == "" {
+			return nil, fmt.Errorf("cluster '%s': kubeconfig path is missing", entry)
+		}
+
+		if c.Name == "" {
+			if c.Context != "" {
+				c.Name = c.Context
+			} else {
+				c.Name = strings.TrimSuffix(filepath.Base(c.ConfigPath), filepath.Ext(c.ConfigPath))
+			}
+		}
+
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate cluster name '%s'", c.Name)
+		}
+		seen[c.Name] = true
+
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// SetCluster - sets the name of the cluster this provider manages when keel manages
+// several clusters. Provider name, notifications and approvals include cluster name.
+func (p *Provider) SetCluster(name string) {
+	p.cluster = name
+	p.sender = &clusterSender{Sender: p.sender, cluster: name}
+}
+
+// approvalIdentifier - approvals are per cluster so the same workload in
+// different clusters can be approved separately
+func (p *Provider) approvalIdentifier(plan *UpdatePlan) string {
+	identifier := getApprovalIdentifier(plan.Resource.Identifier, plan.NewVersion)
+	if p.cluster != "" {
+		return p.cluster + "/" + identifier
+	}
+	return identifier
+}
+
+// clusterSender - adds cluster name to notifications
+type clusterSender struct {
+	notification.Sender
+	cluster string
+}
+
+func (s *clusterSender) Send(event types.EventNotification) error {
+	metadata := map[string]string{"cluster": s.cluster}
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	event.Metadata = metadata
+	event.Message = fmt.Sprintf("[%s] %s", s.cluster, event.Message)
+	return s.Sender.Send(event)
+}
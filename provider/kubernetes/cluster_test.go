@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseClusters(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters string
+		want     []Cluster
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			clusters: "",
+			want:     nil,
+		},
+		{
+			name:     "contexts",
+			clusters: "staging, prod",
+			want: []Cluster{
+				{Name: "staging", ConfigPath: "/root/.kube/config", Context: "staging"},
+				{Name: "prod", ConfigPath: "/root/.kube/config", Context: "prod"},
+			},
+		},
+		{
+			name:     "paths and names",
+			clusters: "/etc/keel/eu.yaml,us=/etc/keel/us.yaml#us-east,dev=#minikube",
+			want: []Cluster{
+				{Name: "eu", ConfigPath: "/etc/keel/eu.yaml"},
+				{Name: "us", ConfigPath: "/etc/keel/us.yaml", Context: "us-east"},
+				{Name: "dev", ConfigPath: "/root/.kube/config", Context: "minikube"},
+			},
+		},
+		{
+			name:     "duplicate names",
+			clusters: "prod,prod=/etc/keel/prod.yaml",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClusters(tt.clusters, "/root/.kube/config")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClusters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClusters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterProvider(t *testing.T) {
+	fs := &fakeSender{}
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeImplementer{}, fs, approver, &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetCluster("prod")
+
+	if provider.GetName() != "kubernetes/prod" {
+		t.Errorf("unexpected provider name: %s", provider.GetName())
+	}
+
+	plan := &UpdatePlan{
+		Resource: MustParseGR(&apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		}),
+		NewVersion: "1.1.2",
+	}
+	if id := provider.approvalIdentifier(plan); id != "prod/deployment/default/web:1.1.2" {
+		t.Errorf("unexpected approval identifier: %s", id)
+	}
+
+	provider.sender.Send(types.EventNotification{
+		Message:  "updated",
+		Metadata: map[string]string{"name": "web"},
+	})
+	if fs.sentEvent.Metadata["cluster"] != "prod" || fs.sentEvent.Metadata["name"] != "web" {
+		t.Errorf("unexpected notification metadata: %v", fs.sentEvent.Metadata)
+	}
+	if fs.sentEvent.Message != "[prod] updated" {
+		t.Errorf("unexpected notification message: %s", fs.sentEvent.Message)
+	}
+}
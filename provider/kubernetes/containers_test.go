@@ -0,0 +1,215 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// sidecarTestContainers - application and sidecar containers with different images
+var sidecarTestContainers = []v1.Container{
+	{Name: "app", Image: "gcr.io/v2-namespace/hello-world:1.1.1"},
+	{Name: "sidecar", Image: "gcr.io/v2-namespace/proxy:1.1.1"},
+}
+
+func TestProcessEventContainerPolicies(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{
+		types.KeelPolicyLabel:              "patch",
+		types.KeelPolicyLabel + ".sidecar": "major",
+	}, apps_v1.DeploymentStatus{}, sidecarTestContainers...)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	// minor bump for app container is not allowed by patch policy
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.2.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("app container should not have been updated")
+	}
+
+	// sidecar container has its own policy
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/proxy",
+		Tag:  "2.0.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("sidecar container was not updated")
+	}
+
+	if fp.updated.Containers()[1].Image != "gcr.io/v2-namespace/proxy:2.0.0" {
+		t.Errorf("unexpected sidecar image: %s", fp.updated.Containers()[1].Image)
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.1" {
+		t.Errorf("unexpected app image: %s", fp.updated.Containers()[0].Image)
+	}
+}
+
+func TestCheckRequestedApprovalContainer(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{
+		types.KeelPolicyLabel: "all",
+		types.KeelContainerKey(types.KeelMinimumApprovalsLabel, "app"): "2",
+	}, apps_v1.DeploymentStatus{}, sidecarTestContainers...)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	// sidecar doesn't require approvals
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/proxy",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("sidecar container was not updated")
+	}
+
+	fp.updated = nil
+
+	// app container requires approvals
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.3",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("app container should be waiting for approvals")
+	}
+
+	approval, err := provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.3")
+	if err != nil {
+		t.Fatalf("failed to find approval, err: %s", err)
+	}
+	if approval.VotesRequired != 2 {
+		t.Errorf("expected 2 votes required, got: %d", approval.VotesRequired)
+	}
+}
+
+func TestTrackedImagesContainerPolicies(t *testing.T) {
+	deployment := newTestDeployment("xxxx", "dep-1", 1, map[string]string{
+		types.KeelPolicyLabel + ".sidecar": "minor",
+	}, apps_v1.DeploymentStatus{}, sidecarTestContainers...)
+	// only sidecar container has a policy
+	delete(deployment.Labels, types.KeelPolicyLabel)
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(deployment))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeImplementer{}, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	imgs, err := provider.TrackedImages()
+	if err != nil {
+		t.Fatalf("failed to get tracked images: %s", err)
+	}
+
+	if len(imgs) != 1 {
+		t.Fatalf("expected only sidecar image to be tracked, got: %d", len(imgs))
+	}
+	if imgs[0].Image.Repository() != "gcr.io/v2-namespace/proxy" {
+		t.Errorf("unexpected tracked image: %s", imgs[0].Image.Repository())
+	}
+}
+
+func TestIgnoreContainers(t *testing.T) {
+	for _, ignored := range []string{"sidecar", "app, sidecar", "gcr.io/*/proxy", "*proxy:1.1.*"} {
+		fp := &fakeImplementer{}
+
+		grc := &k8s.GenericResourceCache{}
+		grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{
+			types.KeelPolicyLabel:                "all",
+			types.KeelIgnoreContainersAnnotation: ignored,
+		}, apps_v1.DeploymentStatus{}, sidecarTestContainers...)))
+
+		approver, teardown := approver()
+		provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+		if err != nil {
+			t.Fatalf("failed to get provider: %s", err)
+		}
+
+		_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+			Name: "gcr.io/v2-namespace/proxy",
+			Tag:  "1.2.0",
+		}})
+		teardown()
+		if err != nil {
+			t.Fatalf("got error while processing event: %s", err)
+		}
+		if fp.updated != nil {
+			t.Errorf("%s: ignored sidecar container shouldn't be updated", ignored)
+		}
+
+		imgs, err := provider.TrackedImages()
+		if err != nil {
+			t.Fatalf("failed to get tracked images: %s", err)
+		}
+		for _, img := range imgs {
+			if img.Image.Repository() == "gcr.io/v2-namespace/proxy" {
+				t.Errorf("%s: ignored sidecar image shouldn't be tracked", ignored)
+			}
+		}
+	}
+
+	// app container is still updated
+	fp := &fakeImplementer{}
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{
+		types.KeelPolicyLabel:                "all",
+		types.KeelIgnoreContainersAnnotation: "sidecar",
+	}, apps_v1.DeploymentStatus{}, sidecarTestContainers...)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.2.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("app container was not updated")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.2.0" {
+		t.Errorf("unexpected app image: %s", fp.updated.Containers()[0].Image)
+	}
+}
@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestCronJob(labels map[string]string) *v1beta1.CronJob {
+	return &v1beta1.CronJob{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "cron-1",
+			Namespace:   "xxxx",
+			Labels:      labels,
+			Annotations: map[string]string{},
+		},
+		Spec: v1beta1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			JobTemplate: v1beta1.JobTemplateSpec{
+				Spec: batch_v1.JobSpec{
+					Template: v1.PodTemplateSpec{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestProcessEventCronJob(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestCronJob(map[string]string{types.KeelPolicyLabel: "minor"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	// major bump should be ignored by minor policy
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "2.0.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("cronjob should not have been updated")
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.2.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("cronjob was not updated")
+	}
+
+	cj, ok := fp.updated.GetResource().(*v1beta1.CronJob)
+	if !ok {
+		t.Fatalf("expected cronjob, got: %s", fp.updated.Kind())
+	}
+
+	if cj.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image != "gcr.io/v2-namespace/hello-world:1.2.0" {
+		t.Errorf("unexpected image: %s", cj.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image)
+	}
+
+	if _, ok := cj.Spec.JobTemplate.Spec.Template.Annotations[types.KeelUpdateTimeAnnotation]; !ok {
+		t.Errorf("expected job pod template to have update time annotation")
+	}
+}
+
+func TestCheckRequestedApprovalCronJob(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestCronJob(map[string]string{
+		types.KeelPolicyLabel:           "all",
+		types.KeelMinimumApprovalsLabel: "1",
+	})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	updated, err := provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if len(updated) != 0 {
+		t.Errorf("expected to find 0 updated cronjobs but found %d", len(updated))
+	}
+
+	approval, err := provider.approvalManager.Get("cronjob/xxxx/cron-1:1.1.2")
+	if err != nil {
+		t.Fatalf("failed to find approval, err: %s", err)
+	}
+
+	if approval.Provider != types.ProviderTypeKubernetes {
+		t.Errorf("wrong provider: %s", approval.Provider)
+	}
+}
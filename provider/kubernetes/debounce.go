@@ -0,0 +1,149 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pendingEvent - events waiting for debounce interval to pass, one per pushed tag
+type pendingEvent struct {
+	key    string
+	events []*types.Event
+	timer  *time.Timer
+}
+
+// SetEventDebounce - sets interval for coalescing events for the same repository. Events
+// are applied only once no new events for the repository arrive during the interval. Semver
+// tags are coalesced together and each resource is updated to the highest tag its policy
+// allows, other tags (such as "latest") are only coalesced with pushes of the same tag. Zero
+// interval (default) disables debouncing.
+func (p *Provider) SetEventDebounce(interval time.Duration) {
+	p.eventDebounce = interval
+}
+
+// debounceKey - repository and tag class of the event, semver tags are collected together
+// while other tags are only replaced by the same tag so workloads tracking them don't miss
+// their update
+func debounceKey(event *types.Event) string {
+	key := event.Repository.Name
+	ref, err := image.Parse(event.Repository.String())
+	if err == nil {
+		key = ref.Repository()
+	}
+
+	if _, err := semver.NewVersion(event.Repository.Tag); err == nil {
+		return key + "|semver"
+	}
+	return key + "|tag:" + event.Repository.Tag
+}
+
+// debounceEvent - queues event and restarts the interval of the repository and tag class,
+// an event for a tag that is already pending replaces it
+func (p *Provider) debounceEvent(event *types.Event) {
+	key := debounceKey(event)
+
+	pe := &pendingEvent{key: key}
+	if existing, ok := p.pending[key]; ok {
+		existing.timer.Stop()
+		for _, e := range existing.events {
+			if e.Repository.Tag != event.Repository.Tag {
+				pe.events = append(pe.events, e)
+			}
+		}
+	}
+	pe.events = append(pe.events, event)
+
+	pe.timer = time.AfterFunc(p.eventDebounce, func() {
+		select {
+		case p.debounced <- pe:
+		case <-p.stop:
+		}
+	})
+	p.pending[key] = pe
+}
+
+// flushEvent - processes debounced events unless they were replaced by newer ones. Plans are
+// created for every pending tag and each resource keeps the highest tag allowed by its policy,
+// so a resource tracking patch releases still gets its update when a new major version is
+// pushed in the same interval
+func (p *Provider) flushEvent(pe *pendingEvent) {
+	if p.pending[pe.key] != pe {
+		return
+	}
+	delete(p.pending, pe.key)
+
+	if len(pe.events) == 1 {
+		p.processDebounced(pe.events[0], nil)
+		return
+	}
+
+	best := make(map[string]*UpdatePlan)
+	eventPlans := make([][]*UpdatePlan, len(pe.events))
+	for idx, event := range pe.events {
+		plans, err := p.createUpdatePlans(&event.Repository)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": event.Repository.Name,
+				"tag":   event.Repository.Tag,
+			}).Error("provider.kubernetes: failed to create update plans")
+			continue
+		}
+		for _, plan := range plans {
+			current, ok := best[plan.Resource.Identifier]
+			if !ok || preferTag(plan.NewVersion, current.NewVersion) {
+				best[plan.Resource.Identifier] = plan
+			}
+		}
+		eventPlans[idx] = plans
+	}
+
+	for idx, event := range pe.events {
+		plans := []*UpdatePlan{}
+		for _, plan := range eventPlans[idx] {
+			if best[plan.Resource.Identifier] == plan {
+				plans = append(plans, plan)
+			}
+		}
+		if len(plans) > 0 {
+			p.processDebounced(event, plans)
+		}
+	}
+}
+
+// processDebounced - applies event, plans are created from the event when nil
+func (p *Provider) processDebounced(event *types.Event, plans []*UpdatePlan) {
+	var err error
+	if plans == nil {
+		_, err = p.processEvent(event)
+	} else {
+		_, err = p.processPlans(event, plans)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": event.Repository.Name,
+			"tag":   event.Repository.Tag,
+		}).Error("provider.kubernetes: failed to process event")
+	}
+}
+
+// preferTag - checks whether new tag should replace pending one, semver tags are
+// compared by version, otherwise (same tag pushed again) the latest event wins
+func preferTag(newTag, pendingTag string) bool {
+	newVersion, err := semver.NewVersion(newTag)
+	if err != nil {
+		return true
+	}
+	pendingVersion, err := semver.NewVersion(pendingTag)
+	if err != nil {
+		return true
+	}
+	return !newVersion.LessThan(pendingVersion)
+}
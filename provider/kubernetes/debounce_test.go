@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPreferTag(t *testing.T) {
+	tests := []struct {
+		newTag, pendingTag string
+		want               bool
+	}{
+		{"1.2.0", "1.1.0", true},
+		{"1.1.0", "1.2.0", false},
+		{"1.2.0", "1.2.0", true},
+		{"latest", "1.2.0", true},
+		{"build-2", "build-1", true},
+	}
+	for _, tt := range tests {
+		if got := preferTag(tt.newTag, tt.pendingTag); got != tt.want {
+			t.Errorf("preferTag(%s, %s) = %v, want %v", tt.newTag, tt.pendingTag, got, tt.want)
+		}
+	}
+}
+
+func TestDebounceKey(t *testing.T) {
+	key := func(tag string) string {
+		return debounceKey(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: tag}})
+	}
+
+	if key("1.2.0") != key("1.3.0") {
+		t.Errorf("expected semver tags to share the key")
+	}
+	if key("1.2.0") == key("latest") {
+		t.Errorf("expected semver and non-semver tags to have different keys")
+	}
+	if key("latest") == key("develop") {
+		t.Errorf("expected different non-semver tags to have different keys")
+	}
+	if key("latest") != key("latest") {
+		t.Errorf("expected the same tag to share the key")
+	}
+}
+
+func TestDebounceEvents(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetEventDebounce(20 * time.Millisecond)
+
+	for _, tag := range []string{"1.1.2", "1.1.4", "1.1.3"} {
+		provider.debounceEvent(&types.Event{Repository: types.Repository{
+			Name: "gcr.io/v2-namespace/hello-world",
+			Tag:  tag,
+		}})
+	}
+
+	if len(provider.pending) != 1 {
+		t.Fatalf("expected 1 pending event, got: %d", len(provider.pending))
+	}
+
+	select {
+	case pe := <-provider.debounced:
+		provider.flushEvent(pe)
+	case <-time.After(time.Second):
+		t.Fatalf("debounced event was not flushed")
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("resource was not updated")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.4" {
+		t.Errorf("expected highest tag to be applied, got: %s", fp.updated.Containers()[0].Image)
+	}
+	if len(provider.pending) != 0 {
+		t.Errorf("expected no pending events, got: %d", len(provider.pending))
+	}
+
+	// semver and non-semver tags pushed in the same window are both kept
+	for _, tag := range []string{"1.2.0", "latest"} {
+		provider.debounceEvent(&types.Event{Repository: types.Repository{
+			Name: "gcr.io/v2-namespace/hello-world",
+			Tag:  tag,
+		}})
+	}
+	if len(provider.pending) != 2 {
+		t.Errorf("expected 2 pending events, got: %d", len(provider.pending))
+	}
+}
+
+// updatesImplementer - records every updated resource
+type updatesImplementer struct {
+	*fakeImplementer
+	updates []*k8s.GenericResource
+}
+
+func (i *updatesImplementer) Update(obj *k8s.GenericResource) error {
+	i.updates = append(i.updates, obj)
+	return nil
+}
+
+func TestDebounceEventsPerResource(t *testing.T) {
+	fp := &updatesImplementer{fakeImplementer: &fakeImplementer{}}
+
+	grc := &k8s.GenericResourceCache{}
+	for _, policy := range []string{"patch", "major"} {
+		grc.Add(MustParseGR(&apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "dep-" + policy,
+				Namespace:   "xxxx",
+				Labels:      map[string]string{types.KeelPolicyLabel: policy},
+				Annotations: map[string]string{},
+			},
+			Spec: apps_v1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Image: "gcr.io/v2-namespace/hello-world:1.2.3",
+							},
+						},
+					},
+				},
+			},
+		}))
+	}
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetEventDebounce(20 * time.Millisecond)
+
+	for _, tag := range []string{"1.2.4", "2.0.0"} {
+		provider.debounceEvent(&types.Event{Repository: types.Repository{
+			Name: "gcr.io/v2-namespace/hello-world",
+			Tag:  tag,
+		}})
+	}
+
+	select {
+	case pe := <-provider.debounced:
+		provider.flushEvent(pe)
+	case <-time.After(time.Second):
+		t.Fatalf("debounced event was not flushed")
+	}
+
+	images := make(map[string]string)
+	for _, resource := range fp.updates {
+		images[resource.Name] = resource.Containers()[0].Image
+	}
+	if len(fp.updates) != 2 {
+		t.Errorf("expected 2 updates, got: %d", len(fp.updates))
+	}
+	if images["dep-patch"] != "gcr.io/v2-namespace/hello-world:1.2.4" {
+		t.Errorf("expected patch policy resource to get 1.2.4, got: %s", images["dep-patch"])
+	}
+	if images["dep-major"] != "gcr.io/v2-namespace/hello-world:2.0.0" {
+		t.Errorf("expected major policy resource to get 2.0.0, got: %s", images["dep-major"])
+	}
+}
@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+)
+
+// isDigestPinned - checks whether resource wants updated images to be pinned
+// to their manifest digest (keel.sh/digest=true), annotations take precedence
+func isDigestPinned(labels map[string]string, annotations map[string]string) bool {
+	if val, ok := annotations[types.KeelDigestAnnotation]; ok {
+		return val == "true"
+	}
+	return labels[types.KeelDigestAnnotation] == "true"
+}
+
+// pinDigests - replaces updated container images with name:tag@digest references
+// so rollouts are immutable even if tag is later moved
+func (p *Provider) pinDigests(repo *types.Repository, resource *k8s.GenericResource) error {
+	eventRepoRef, err := image.Parse(repo.String())
+	if err != nil {
+		return err
+	}
+
+	digest := repo.Digest
+
+	for _, c := range getUpdatableContainers(resource) {
+		ref, err := image.Parse(c.Image)
+		if err != nil {
+			continue
+		}
+		if !image.RepositoryMatches(ref, eventRepoRef) || ref.Tag() != repo.Tag || ref.Digest() != "" {
+			continue
+		}
+
+		if digest == "" {
+			digest, err = p.resolveDigest(ref, resource)
+			if err != nil {
+				return fmt.Errorf("failed to resolve digest for %s: %s", c.Image, err)
+			}
+		}
+
+		c.update(resource, c.Image+"@"+digest)
+	}
+
+	return nil
+}
+
+func (p *Provider) resolveDigest(ref *image.Reference, resource *k8s.GenericResource) (string, error) {
+	return p.registryClient.Digest(p.registryOpts(ref, resource))
+}
+
+// registryOpts - registry client options for the image, credentials are looked up
+// with the image pull secrets of the resource
+func (p *Provider) registryOpts(ref *image.Reference, resource *k8s.GenericResource) registry.Opts {
+	var secrets []string
+	specifiedSecret := getImagePullSecretFromMeta(resource.GetLabels(), resource.GetAnnotations())
+	if specifiedSecret != "" {
+		secrets = append(secrets, specifiedSecret)
+	}
+	secrets = append(secrets, resource.GetImagePullSecrets()...)
+
+	creds := credentialshelper.GetCredentials(&types.TrackedImage{
+		Image:          ref,
+		Namespace:      resource.Namespace,
+		Secrets:        secrets,
+		ServiceAccount: resource.GetServiceAccountName(),
+		Provider:       ProviderName,
+		Meta:           make(map[string]string),
+	})
+
+	return registry.Opts{
+		Registry: ref.Scheme() + "://" + ref.Registry(),
+		Name:     ref.ShortName(),
+		Tag:      ref.Tag(),
+		Username: creds.Username,
+		Password: creds.Password,
+	}
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testDigest = "sha256:a5d5b1a55b5e2d3a1cd95c6d2e4cd29d5c2b81bd1b2e4a2f1e1a5b1c4fa6b2c3"
+
+type fakeRegistryClient struct {
+	opts    registry.Opts
+	digest  string
+	created time.Time
+}
+
+func (c *fakeRegistryClient) Get(opts registry.Opts) (*registry.Repository, error) {
+	return nil, nil
+}
+
+func (c *fakeRegistryClient) Digest(opts registry.Opts) (string, error) {
+	c.opts = opts
+	return c.digest, nil
+}
+
+func (c *fakeRegistryClient) Created(opts registry.Opts) (time.Time, error) {
+	c.opts = opts
+	return c.created, nil
+}
+
+func TestProcessEventDigestPinned(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{types.KeelPolicyLabel: "force", types.KeelDigestAnnotation: "true"}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:latest"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	rc := &fakeRegistryClient{digest: testDigest}
+	provider.registryClient = rc
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "latest",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("resource was not updated")
+	}
+
+	expected := "gcr.io/v2-namespace/hello-world:latest@" + testDigest
+	if fp.updated.Containers()[0].Image != expected {
+		t.Errorf("expected image %s, got: %s", expected, fp.updated.Containers()[0].Image)
+	}
+
+	if rc.opts.Registry != "https://gcr.io" || rc.opts.Name != "v2-namespace/hello-world" || rc.opts.Tag != "latest" {
+		t.Errorf("unexpected registry opts: %#v", rc.opts)
+	}
+}
+
+func TestProcessEventDigestFromEvent(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 1, map[string]string{types.KeelPolicyLabel: "force", types.KeelDigestAnnotation: "true"}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:latest@sha256:0000000000000000000000000000000000000000000000000000000000000000"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	rc := &fakeRegistryClient{}
+	provider.registryClient = rc
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name:   "gcr.io/v2-namespace/hello-world",
+		Tag:    "latest",
+		Digest: testDigest,
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("resource was not updated")
+	}
+
+	expected := "gcr.io/v2-namespace/hello-world:latest@" + testDigest
+	if fp.updated.Containers()[0].Image != expected {
+		t.Errorf("expected image %s, got: %s", expected, fp.updated.Containers()[0].Image)
+	}
+
+	if rc.opts.Name != "" {
+		t.Errorf("registry shouldn't be queried when event has digest")
+	}
+}
@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetDryRun - when enabled, updates are only reported, resources are never updated
+func (p *Provider) SetDryRun(dryRun bool) {
+	p.dryRun = dryRun
+}
+
+// isDryRun - checks whether dry run is enabled for the resource through annotations or labels
+func isDryRun(labels map[string]string, annotations map[string]string) bool {
+	val, ok := annotations[types.KeelDryRunAnnotation]
+	if !ok {
+		val = labels[types.KeelDryRunAnnotation]
+	}
+	return strings.ToLower(strings.TrimSpace(val)) == "true"
+}
+
+// checkForDryRun - filters out plans for resources in dry run mode, would-be
+// updates are logged and sent as notifications (which also records them in the audit log)
+func (p *Provider) checkForDryRun(plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		if !p.dryRun && !isDryRun(resource.GetLabels(), resource.GetAnnotations()) {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"update":    fmt.Sprintf("%s->%s", plan.CurrentVersion, plan.NewVersion),
+		}).Info("provider.kubernetes: dry run, resource not updated")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "dry run update",
+			Message:      fmt.Sprintf("Dry run: would update %s %s/%s from %s to %s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
+			CreatedAt:    timeutil.Now(),
+			Type:         types.NotificationDryRun,
+			Level:        types.LevelInfo,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+			},
+		})
+	}
+
+	return allowedPlans
+}
@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProcessEventDryRun(t *testing.T) {
+	deployment := func(annotations map[string]string) *apps_v1.Deployment {
+		return &apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "dep-1",
+				Namespace:   "xxxx",
+				Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+				Annotations: annotations,
+			},
+			Spec: apps_v1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		globalDryRun bool
+		annotations  map[string]string
+		wantUpdated  bool
+	}{
+		{name: "disabled", annotations: map[string]string{}, wantUpdated: true},
+		{name: "annotation", annotations: map[string]string{types.KeelDryRunAnnotation: "true"}},
+		{name: "global", globalDryRun: true, annotations: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := &fakeImplementer{}
+			sender := &fakeSender{}
+
+			grc := &k8s.GenericResourceCache{}
+			grc.Add(MustParseGR(deployment(tt.annotations)))
+
+			approver, teardown := approver()
+			defer teardown()
+			provider, err := NewProvider(fp, sender, approver, grc)
+			if err != nil {
+				t.Fatalf("failed to get provider: %s", err)
+			}
+			provider.SetDryRun(tt.globalDryRun)
+
+			_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+				Name: "gcr.io/v2-namespace/hello-world",
+				Tag:  "1.1.2",
+			}})
+			if err != nil {
+				t.Fatalf("got error while processing event: %s", err)
+			}
+
+			if (fp.updated != nil) != tt.wantUpdated {
+				t.Fatalf("expected updated: %v, got: %v", tt.wantUpdated, fp.updated != nil)
+			}
+			if tt.wantUpdated {
+				return
+			}
+			if sender.sentEvent.Type != types.NotificationDryRun {
+				t.Errorf("expected dry run notification, got: %s", sender.sentEvent.Type)
+			}
+			if sender.sentEvent.Message != "Dry run: would update deployment xxxx/dep-1 from 1.1.1 to 1.1.2 (gcr.io/v2-namespace/hello-world:1.1.2)" {
+				t.Errorf("unexpected message: %s", sender.sentEvent.Message)
+			}
+		})
+	}
+}
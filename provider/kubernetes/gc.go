@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// gcInterval - how often state of deleted resources is collected, resources have to be
+// missing from the cache for at least this long before their state is removed so
+// nothing is lost while informers are syncing
+var gcInterval = 5 * time.Minute
+
+// approvalResourceIdentifier - returns identifier of the resource approval belongs to,
+// false if approval was created by another provider or for another cluster
+func (p *Provider) approvalResourceIdentifier(approval *types.Approval) (string, bool) {
+	if approval.Provider != types.ProviderTypeKubernetes {
+		return "", false
+	}
+	identifier := approval.Identifier
+	if p.cluster != "" {
+		if !strings.HasPrefix(identifier, p.cluster+"/") {
+			return "", false
+		}
+		identifier = strings.TrimPrefix(identifier, p.cluster+"/")
+	}
+	// grouped approvals aren't bound to a single resource
+	if strings.HasPrefix(identifier, groupApprovalPrefix) {
+		return "", false
+	}
+	if idx := strings.Index(identifier, ":"); idx > 0 {
+		identifier = identifier[:idx]
+	}
+	// kind/namespace/name, anything else is prefixed with another cluster name
+	if strings.Count(identifier, "/") != 2 {
+		return "", false
+	}
+	return identifier, true
+}
+
+// collectGarbage - removes approvals, queued updates and canaries of resources that
+// were deleted from the cluster
+func (p *Provider) collectGarbage() {
+	existing := make(map[string]bool)
+	for _, gr := range p.cache.Values() {
+		existing[gr.Identifier] = true
+	}
+
+	referenced := make(map[string][]*types.Approval)
+	approvals, err := p.approvalManager.List()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("provider.kubernetes: failed to list approvals for garbage collection")
+	}
+	for _, approval := range approvals {
+		if identifier, ok := p.approvalResourceIdentifier(approval); ok {
+			referenced[identifier] = append(referenced[identifier], approval)
+		}
+	}
+	for _, q := range []*deferredUpdates{p.deferred, p.paused, p.staged} {
+		for _, identifier := range q.identifiers() {
+			if _, ok := referenced[identifier]; !ok {
+				referenced[identifier] = nil
+			}
+		}
+	}
+	for _, identifier := range p.canaries.identifiers() {
+		if _, ok := referenced[identifier]; !ok {
+			referenced[identifier] = nil
+		}
+	}
+
+	now := timeutil.Now()
+	for identifier := range p.missing {
+		if _, ok := referenced[identifier]; !ok || existing[identifier] {
+			delete(p.missing, identifier)
+		}
+	}
+
+	for identifier, resourceApprovals := range referenced {
+		if existing[identifier] {
+			continue
+		}
+		since, ok := p.missing[identifier]
+		if !ok {
+			p.missing[identifier] = now
+			continue
+		}
+		if now.Sub(since) < gcInterval {
+			continue
+		}
+		p.removeResourceState(identifier, resourceApprovals)
+		delete(p.missing, identifier)
+	}
+}
+
+// removeResourceState - removes everything provider keeps for a deleted resource
+func (p *Provider) removeResourceState(identifier string, approvals []*types.Approval) {
+	for _, approval := range approvals {
+		err := p.approvalManager.Delete(approval, "")
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"identifier": approval.Identifier,
+			}).Error("provider.kubernetes: failed to delete approval of deleted resource")
+		}
+	}
+
+	p.deferred.remove(identifier)
+	p.paused.remove(identifier)
+	p.staged.remove(identifier)
+	if state := p.canaries.get(identifier); state != nil {
+		p.stopCanary(identifier, state)
+	}
+
+	log.WithFields(log.Fields{
+		"identifier": identifier,
+		"approvals":  len(approvals),
+	}).Info("provider.kubernetes: resource was deleted, state removed")
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "xxxx"},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeImplementer{}, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	for _, identifier := range []string{"deployment/xxxx/dep-1:1.1.2", "deployment/xxxx/gone:1.1.2", "other/deployment/xxxx/gone:1.1.2"} {
+		err = provider.approvalManager.Create(&types.Approval{
+			Provider:      types.ProviderTypeKubernetes,
+			Identifier:    identifier,
+			VotesRequired: 1,
+			Deadline:      now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to create approval: %s", err)
+		}
+	}
+	provider.deferred.add("deployment/xxxx/gone", &types.Event{})
+
+	// first pass only marks missing resources
+	provider.collectGarbage()
+	if _, err := provider.approvalManager.Get("deployment/xxxx/gone:1.1.2"); err != nil {
+		t.Fatalf("approval shouldn't be removed on first pass: %s", err)
+	}
+
+	now = now.Add(gcInterval)
+	provider.collectGarbage()
+
+	if _, err := provider.approvalManager.Get("deployment/xxxx/gone:1.1.2"); err == nil {
+		t.Errorf("expected approval of deleted resource to be removed")
+	}
+	if _, err := provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.2"); err != nil {
+		t.Errorf("approval of existing resource shouldn't be removed: %s", err)
+	}
+	if _, err := provider.approvalManager.Get("other/deployment/xxxx/gone:1.1.2"); err != nil {
+		t.Errorf("approval from another cluster shouldn't be removed: %s", err)
+	}
+	if len(provider.deferred.identifiers()) != 0 {
+		t.Errorf("expected deferred update of deleted resource to be removed")
+	}
+	if len(provider.missing) != 0 {
+		t.Errorf("expected missing resources to be reset, got: %v", provider.missing)
+	}
+}
+
+func TestCollectGarbageRecreatedResource(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	grc := &k8s.GenericResourceCache{}
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeImplementer{}, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.paused.add("deployment/xxxx/dep-1", &types.Event{})
+
+	provider.collectGarbage()
+
+	// resource shows up again, for example informers finished syncing
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "xxxx"},
+	}))
+	now = now.Add(gcInterval)
+	provider.collectGarbage()
+
+	if len(provider.paused.identifiers()) != 1 {
+		t.Errorf("paused update of existing resource shouldn't be removed")
+	}
+}
@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/keel-hq/keel/pkg/store"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// groupApprovalPrefix - grouped approval identifiers, ie: group/gcr.io/project/app:1.2.0
+const groupApprovalPrefix = "group/"
+
+// SetGroupApprovals - when enabled, resources that require approvals for the same image
+// version share a single approval and are updated together once it's approved
+func (p *Provider) SetGroupApprovals(enabled bool) {
+	p.groupApprovals = enabled
+}
+
+// groupApprovalIdentifier - grouped approvals are per image version and cluster
+func (p *Provider) groupApprovalIdentifier(event *types.Event, plan *UpdatePlan) string {
+	identifier := groupApprovalPrefix + getApprovalIdentifier(event.Repository.Name, plan.NewVersion)
+	if p.cluster != "" {
+		return p.cluster + "/" + identifier
+	}
+	return identifier
+}
+
+// isGroupApproved - checks approval shared by the plans, approval is created with the
+// highest required votes and approvers, channels of all resources
+func (p *Provider) isGroupApproved(event *types.Event, identifier string, plans []*UpdatePlan) (bool, error) {
+	existing, err := p.approvalManager.Get(identifier)
+	if err != nil {
+		if err != store.ErrRecordNotFound {
+			return false, err
+		}
+		if event.TriggerName == types.TriggerTypeApproval.String() {
+			return false, nil
+		}
+		return false, p.approvalManager.Create(p.newGroupApproval(event, identifier, plans))
+	}
+
+	if existing.Status() != types.ApprovalStatusApproved {
+		return false, nil
+	}
+
+	approvedBy := existing.GetVoters()
+	sort.Strings(approvedBy)
+	for _, plan := range plans {
+		plan.ApprovedBy = approvedBy
+		plan.ApprovalGroup = identifier
+	}
+	return true, nil
+}
+
+func (p *Provider) newGroupApproval(event *types.Event, identifier string, plans []*UpdatePlan) *types.Approval {
+	var (
+		approval  *types.Approval
+		resources []string
+		reasons   []string
+		approvers = make(map[string]bool)
+		channels  = make(map[string]bool)
+	)
+	for _, plan := range plans {
+		minApprovals, _ := getMinApprovals(plan)
+		a := p.newApproval(event, plan, identifier, minApprovals)
+		if approval == nil || a.VotesRequired > approval.VotesRequired {
+			approval = a
+		}
+		for _, approver := range a.GetApprovers() {
+			approvers[approver] = true
+		}
+		for _, channel := range a.GetChannels() {
+			channels[channel] = true
+		}
+		resources = append(resources, plan.Resource.Namespace+"/"+plan.Resource.Name)
+		if plan.ApprovalReason != "" {
+			reasons = append(reasons, plan.ApprovalReason)
+		}
+	}
+
+	approval.Approvers = joinKeys(approvers)
+	approval.Channels = joinKeys(channels)
+	approval.Message = fmt.Sprintf("New image is available for %d resources (%s): %s. Resources are updated together once approved.",
+		len(resources), approval.Delta(), strings.Join(resources, ", "))
+	if len(reasons) > 0 {
+		approval.Message = approval.Message + " " + strings.Join(reasons, " ")
+	}
+	return approval
+}
+
+func joinKeys(m map[string]bool) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// checkForCompleteGroups - plans of a grouped approval are only updated together, when
+// some of them are held back (update windows, canaries, disruption budgets) the whole
+// group waits and approval is kept until all resources can be updated
+func (p *Provider) checkForCompleteGroups(approved []*UpdatePlan, plans []*UpdatePlan) []*UpdatePlan {
+	expected := make(map[string]int)
+	for _, plan := range approved {
+		if plan.ApprovalGroup != "" {
+			expected[plan.ApprovalGroup]++
+		}
+	}
+	if len(expected) == 0 {
+		return plans
+	}
+
+	ready := make(map[string]int)
+	for _, plan := range plans {
+		if plan.ApprovalGroup != "" {
+			ready[plan.ApprovalGroup]++
+		}
+	}
+
+	complete := []*UpdatePlan{}
+	for _, plan := range plans {
+		if plan.ApprovalGroup != "" && ready[plan.ApprovalGroup] != expected[plan.ApprovalGroup] {
+			log.WithFields(log.Fields{
+				"approval":  plan.ApprovalGroup,
+				"name":      plan.Resource.Name,
+				"namespace": plan.Resource.Namespace,
+				"ready":     fmt.Sprintf("%d/%d", ready[plan.ApprovalGroup], expected[plan.ApprovalGroup]),
+			}).Info("provider.kubernetes: grouped update deferred until all resources can be updated")
+			continue
+		}
+		complete = append(complete, plan)
+	}
+	return complete
+}
+
+// archiveGroups - grouped approvals are archived once all their resources were updated,
+// failed updates are retried with the approval still in place
+func (p *Provider) archiveGroups(plans []*UpdatePlan, failed map[string]bool) {
+	archived := make(map[string]bool)
+	for _, plan := range plans {
+		group := plan.ApprovalGroup
+		if group == "" || failed[group] || archived[group] {
+			continue
+		}
+		archived[group] = true
+		if err := p.approvalManager.Archive(group); err != nil {
+			log.WithFields(log.Fields{
+				"error":    err,
+				"approval": group,
+			}).Warn("provider.kubernetes: got error while archiving grouped approval after successful update")
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func groupedDeployment(name, approvals string, annotations map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all", types.KeelMinimumApprovalsLabel: approvals},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGroupedApproval(t *testing.T) {
+	fp := &fakeImplementer{}
+	fp.namespaces = &v1.NamespaceList{
+		Items: []v1.Namespace{
+			{ObjectMeta: meta_v1.ObjectMeta{Name: "xxxx"}},
+		},
+	}
+	deployments := []*apps_v1.Deployment{
+		groupedDeployment("dep-1", "1", map[string]string{types.KeelApproversAnnotation: "alice"}),
+		groupedDeployment("dep-2", "2", map[string]string{types.KeelApproversAnnotation: "bob"}),
+	}
+
+	grs := MustParseGRS(deployments)
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(grs...)
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetGroupApprovals(true)
+
+	repo := types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}
+
+	deps, err := provider.processEvent(&types.Event{Repository: repo})
+	if err != nil {
+		t.Fatalf("failed to get deployments: %s", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("expected to find 0 updated deployments but found %d", len(deps))
+	}
+
+	approvals, err := provider.approvalManager.List()
+	if err != nil {
+		t.Fatalf("failed to list approvals: %s", err)
+	}
+	if len(approvals) != 1 {
+		t.Fatalf("expected a single grouped approval, got %d", len(approvals))
+	}
+
+	identifier := "group/gcr.io/v2-namespace/hello-world:1.1.2"
+	approval := approvals[0]
+	if approval.Identifier != identifier {
+		t.Errorf("unexpected identifier: %s", approval.Identifier)
+	}
+	if approval.VotesRequired != 2 {
+		t.Errorf("expected 2 required votes, got %d", approval.VotesRequired)
+	}
+	if approval.Approvers != "alice,bob" {
+		t.Errorf("unexpected approvers: %s", approval.Approvers)
+	}
+	if !strings.Contains(approval.Message, "xxxx/dep-1, xxxx/dep-2") {
+		t.Errorf("resources missing from message: %s", approval.Message)
+	}
+
+	for _, voter := range []string{"alice", "bob"} {
+		if _, err := provider.approvalManager.Approve(identifier, voter); err != nil {
+			t.Fatalf("failed to approve: %s", err)
+		}
+	}
+
+	deps, err = provider.processEvent(&types.Event{Repository: repo, TriggerName: types.TriggerTypeApproval.String()})
+	if err != nil {
+		t.Fatalf("failed to get deployments: %s", err)
+	}
+	if len(deps) != 2 {
+		t.Errorf("expected to find 2 updated deployments but found %d", len(deps))
+	}
+
+	approvals, err = provider.approvalManager.List()
+	if err != nil {
+		t.Fatalf("failed to list approvals: %s", err)
+	}
+	if len(approvals) != 1 || !approvals[0].Archived {
+		t.Errorf("expected grouped approval to be archived")
+	}
+}
+
+func TestGroupedApprovalIncomplete(t *testing.T) {
+	provider := &Provider{}
+
+	approved := []*UpdatePlan{
+		{Resource: MustParseGR(groupedDeployment("dep-1", "1", nil)), ApprovalGroup: "group/app:1.0.0"},
+		{Resource: MustParseGR(groupedDeployment("dep-2", "1", nil)), ApprovalGroup: "group/app:1.0.0"},
+		{Resource: MustParseGR(groupedDeployment("dep-3", "0", nil))},
+	}
+
+	// dep-2 held back, ie: outside of its update window
+	plans := provider.checkForCompleteGroups(approved, []*UpdatePlan{approved[0], approved[2]})
+	if len(plans) != 1 || plans[0].Resource.Name != "dep-3" {
+		t.Fatalf("expected only ungrouped plan to pass, got %d plans", len(plans))
+	}
+
+	plans = provider.checkForCompleteGroups(approved, approved)
+	if len(plans) != 3 {
+		t.Errorf("expected all plans to pass, got %d", len(plans))
+	}
+}
@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultUpdateHistoryLimit - default number of entries kept in keel.sh/update-history annotation
+const DefaultUpdateHistoryLimit = 10
+
+// historyEntry - single update stored in keel.sh/update-history annotation
+type historyEntry struct {
+	Time       string   `json:"time"`
+	Previous   []string `json:"previous"`
+	New        []string `json:"new"`
+	Trigger    string   `json:"trigger,omitempty"`
+	ApprovedBy []string `json:"approvedBy,omitempty"`
+}
+
+// SetHistoryLimit - sets number of updates kept in keel.sh/update-history annotation,
+// 0 disables update history
+func (p *Provider) SetHistoryLimit(limit int) {
+	p.historyLimit = limit
+}
+
+// getUpdateHistory - parses update history from annotations, newest entries first
+func getUpdateHistory(annotations map[string]string) ([]historyEntry, error) {
+	var history []historyEntry
+	val, ok := annotations[types.KeelUpdateHistoryAnnotation]
+	if !ok || val == "" {
+		return history, nil
+	}
+	err := json.Unmarshal([]byte(val), &history)
+	return history, err
+}
+
+// recordHistory - prepends update to keel.sh/update-history annotation, only changed images
+// are recorded and oldest entries are dropped once history limit is reached
+func (p *Provider) recordHistory(plan *UpdatePlan, annotations map[string]string) {
+	history, err := getUpdateHistory(annotations)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      plan.Resource.Name,
+			"namespace": plan.Resource.Namespace,
+		}).Warn("provider.kubernetes: failed to parse update history, resetting it")
+		history = nil
+	}
+
+	entry := historyEntry{
+		Time:       timeutil.Now().UTC().Format(time.RFC3339),
+		Trigger:    plan.Trigger,
+		ApprovedBy: plan.ApprovedBy,
+	}
+
+	current := getUpdatableImages(plan.Resource)
+	for idx, previous := range plan.PreviousImages {
+		if idx >= len(current) || current[idx] == previous {
+			continue
+		}
+		entry.Previous = append(entry.Previous, previous)
+		entry.New = append(entry.New, current[idx])
+	}
+
+	history = append([]historyEntry{entry}, history...)
+	if len(history) > p.historyLimit {
+		history = history[:p.historyLimit]
+	}
+
+	bts, err := json.Marshal(history)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      plan.Resource.Name,
+			"namespace": plan.Resource.Namespace,
+		}).Error("provider.kubernetes: failed to encode update history")
+		return
+	}
+	annotations[types.KeelUpdateHistoryAnnotation] = string(bts)
+}
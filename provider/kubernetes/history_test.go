@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateHistory(t *testing.T) {
+	timeutil.Now = func() time.Time {
+		return time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	}
+	defer func() { timeutil.Now = time.Now }()
+
+	fp := &fakeImplementer{}
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+						{
+							Image: "gcr.io/v2-namespace/sidecar:1.0.0",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	provider.SetHistoryLimit(2)
+
+	for _, tag := range []string{"1.1.2", "1.1.3", "1.1.4"} {
+		_, err = provider.processEvent(&types.Event{
+			Repository:  types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: tag},
+			TriggerName: "poll",
+		})
+		if err != nil {
+			t.Fatalf("got error while processing event: %s", err)
+		}
+		if fp.updated == nil {
+			t.Fatalf("expected resource to be updated")
+		}
+		// cache is updated by informers
+		grc.Add(fp.updated)
+	}
+
+	history, err := getUpdateHistory(fp.updated.GetAnnotations())
+	if err != nil {
+		t.Fatalf("failed to parse update history: %s", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got: %d", len(history))
+	}
+
+	entry := history[0]
+	if entry.Time != "2019-03-01T10:00:00Z" {
+		t.Errorf("unexpected time: %s", entry.Time)
+	}
+	if entry.Trigger != "poll" {
+		t.Errorf("unexpected trigger: %s", entry.Trigger)
+	}
+	if len(entry.Previous) != 1 || entry.Previous[0] != "gcr.io/v2-namespace/hello-world:1.1.3" {
+		t.Errorf("unexpected previous images: %v", entry.Previous)
+	}
+	if len(entry.New) != 1 || entry.New[0] != "gcr.io/v2-namespace/hello-world:1.1.4" {
+		t.Errorf("unexpected new images: %v", entry.New)
+	}
+	if history[1].New[0] != "gcr.io/v2-namespace/hello-world:1.1.3" {
+		t.Errorf("expected oldest entry to be dropped, got: %v", history[1].New)
+	}
+}
@@ -0,0 +1,343 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	hookPreUpdate  = "pre-update"
+	hookPostUpdate = "post-update"
+
+	// hookLabel - set on hook Jobs created by keel, value is hook phase
+	hookLabel = "keel.sh/hook"
+)
+
+// hookCheckInterval - how often hook Job status is checked
+var hookCheckInterval = 2 * time.Second
+
+// jobHook - Job hook spec, when image is empty the new image of the
+// first updated container is used
+type jobHook struct {
+	Image   string            `json:"image"`
+	Command []string          `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+}
+
+// hookPayload - body of HTTP hook requests
+type hookPayload struct {
+	Phase           string   `json:"phase"`
+	Provider        string   `json:"provider"`
+	Kind            string   `json:"kind"`
+	Identifier      string   `json:"identifier"`
+	Namespace       string   `json:"namespace"`
+	Name            string   `json:"name"`
+	PreviousVersion string   `json:"previousVersion"`
+	NewVersion      string   `json:"newVersion"`
+	PreviousImages  []string `json:"previousImages"`
+	Images          []string `json:"images"`
+}
+
+// getHookTimeout - gets hook timeout from resource annotations
+func getHookTimeout(annotations map[string]string) time.Duration {
+	timeoutStr, ok := annotations[types.KeelHookTimeoutAnnotation]
+	if !ok {
+		return types.KeelHookTimeoutDefault
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"timeout": timeoutStr,
+		}).Warn("provider.kubernetes: failed to parse hook timeout, using default")
+		return types.KeelHookTimeoutDefault
+	}
+	return timeout
+}
+
+// runHook - runs pre or post update hook configured through resource annotations,
+// resources without hooks are ignored
+func (p *Provider) runHook(phase string, plan *UpdatePlan) error {
+	annotation := types.KeelPreUpdateHookAnnotation
+	if phase == hookPostUpdate {
+		annotation = types.KeelPostUpdateHookAnnotation
+	}
+
+	annotations := plan.Resource.GetAnnotations()
+	hook := strings.TrimSpace(annotations[annotation])
+	if hook == "" {
+		return nil
+	}
+	timeout := getHookTimeout(annotations)
+
+	log.WithFields(log.Fields{
+		"name":      plan.Resource.Name,
+		"kind":      plan.Resource.Kind(),
+		"namespace": plan.Resource.Namespace,
+		"phase":     phase,
+	}).Info("provider.kubernetes: running update hook")
+
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		return p.runHTTPHook(phase, hook, plan, timeout)
+	}
+
+	var spec jobHook
+	err := json.Unmarshal([]byte(hook), &spec)
+	if err != nil {
+		return fmt.Errorf("invalid %s hook, expected URL or job spec: %s", phase, err)
+	}
+	return p.runJobHook(phase, &spec, plan, timeout)
+}
+
+// runHTTPHook - sends update details to the hook URL, any non 2xx response is a failure
+func (p *Provider) runHTTPHook(phase, url string, plan *UpdatePlan, timeout time.Duration) error {
+	resource := plan.Resource
+	body, err := json.Marshal(&hookPayload{
+		Phase:           phase,
+		Provider:        p.GetName(),
+		Kind:            resource.Kind(),
+		Identifier:      resource.Identifier,
+		Namespace:       resource.Namespace,
+		Name:            resource.Name,
+		PreviousVersion: plan.CurrentVersion,
+		NewVersion:      plan.NewVersion,
+		PreviousImages:  plan.PreviousImages,
+		Images:          getUpdatableImages(resource),
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s hook request failed: %s", phase, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s hook returned status %d", phase, resp.StatusCode)
+	}
+	return nil
+}
+
+// hookImage - new image of the first updated container
+func hookImage(plan *UpdatePlan) string {
+	images := getUpdatableImages(plan.Resource)
+	for idx, img := range images {
+		if idx < len(plan.PreviousImages) && plan.PreviousImages[idx] != img {
+			return img
+		}
+	}
+	if len(images) > 0 {
+		return images[0]
+	}
+	return ""
+}
+
+// hookJobName - Job names are limited to 63 characters as they are used as pod labels
+func hookJobName(name, phase string) string {
+	suffix := fmt.Sprintf("-%s-%d", phase, timeutil.Now().Unix())
+	if len(name)+len(suffix) > 63 {
+		name = strings.TrimRight(name[:63-len(suffix)], "-.")
+	}
+	return name + suffix
+}
+
+// newHookJob - creates Job for the hook in resource namespace, image pull secrets are
+// copied from the resource and update details are passed as environment variables
+func newHookJob(phase string, spec *jobHook, plan *UpdatePlan) *batch_v1.Job {
+	resource := plan.Resource
+
+	image := spec.Image
+	if image == "" {
+		image = hookImage(plan)
+	}
+
+	env := []v1.EnvVar{
+		{Name: "KEEL_HOOK_PHASE", Value: phase},
+		{Name: "KEEL_RESOURCE_KIND", Value: resource.Kind()},
+		{Name: "KEEL_RESOURCE_NAMESPACE", Value: resource.Namespace},
+		{Name: "KEEL_RESOURCE_NAME", Value: resource.Name},
+		{Name: "KEEL_PREVIOUS_VERSION", Value: plan.CurrentVersion},
+		{Name: "KEEL_NEW_VERSION", Value: plan.NewVersion},
+	}
+	var names []string
+	for k := range spec.Env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		env = append(env, v1.EnvVar{Name: k, Value: spec.Env[k]})
+	}
+
+	var pullSecrets []v1.LocalObjectReference
+	for _, secret := range resource.GetImagePullSecrets() {
+		pullSecrets = append(pullSecrets, v1.LocalObjectReference{Name: secret})
+	}
+
+	backoffLimit := int32(0)
+
+	return &batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      hookJobName(resource.Name, phase),
+			Namespace: resource.Namespace,
+			Labels:    map[string]string{hookLabel: phase},
+		},
+		Spec: batch_v1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Labels: map[string]string{hookLabel: phase},
+				},
+				Spec: v1.PodSpec{
+					RestartPolicy:    v1.RestartPolicyNever,
+					ImagePullSecrets: pullSecrets,
+					Containers: []v1.Container{
+						{
+							Name:    "hook",
+							Image:   image,
+							Command: spec.Command,
+							Args:    spec.Args,
+							Env:     env,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// runJobHook - creates hook Job and waits for it to complete, the Job is deleted once it
+// completes, fails or times out
+func (p *Provider) runJobHook(phase string, spec *jobHook, plan *UpdatePlan, timeout time.Duration) error {
+	job, err := p.implementer.CreateJob(newHookJob(phase, spec, plan))
+	if err != nil {
+		return fmt.Errorf("failed to create %s hook job: %s", phase, err)
+	}
+	defer p.deleteHookJob(job)
+
+	ticker := time.NewTicker(hookCheckInterval)
+	defer ticker.Stop()
+
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case <-p.stop:
+			return fmt.Errorf("provider stopped while waiting for %s hook job %s", phase, job.Name)
+		case <-deadline:
+			return fmt.Errorf("%s hook job %s didn't finish in %s", phase, job.Name, timeout)
+		case <-ticker.C:
+			current, err := p.implementer.Job(job.Namespace, job.Name)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"job":       job.Name,
+					"namespace": job.Namespace,
+				}).Warn("provider.kubernetes: failed to get hook job status")
+				continue
+			}
+			for _, c := range current.Status.Conditions {
+				if c.Status != v1.ConditionTrue {
+					continue
+				}
+				switch c.Type {
+				case batch_v1.JobComplete:
+					return nil
+				case batch_v1.JobFailed:
+					return fmt.Errorf("%s hook job %s failed: %s", phase, job.Name, c.Message)
+				}
+			}
+		}
+	}
+}
+
+// deleteHookJob - deletes finished or abandoned hook Job together with its pods
+func (p *Provider) deleteHookJob(job *batch_v1.Job) {
+	err := p.implementer.DeleteJob(job.Namespace, job.Name)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"job":       job.Name,
+			"namespace": job.Namespace,
+		}).Warn("provider.kubernetes: failed to delete hook job")
+	}
+}
+
+// runPreUpdateHooks - runs pre-update hooks of all plans in parallel and returns their
+// errors by plan, plans processed together wait for the slowest hook instead of all of them
+// one after another. Updates of other events are processed once the hooks finish, hooks
+// should be kept short (see keel.sh/hookTimeout)
+func (p *Provider) runPreUpdateHooks(plans []*UpdatePlan) map[*UpdatePlan]error {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	failed := make(map[*UpdatePlan]error)
+	for _, plan := range plans {
+		wg.Add(1)
+		go func(plan *UpdatePlan) {
+			defer wg.Done()
+			if err := p.runHook(hookPreUpdate, plan); err != nil {
+				mu.Lock()
+				failed[plan] = err
+				mu.Unlock()
+			}
+		}(plan)
+	}
+	wg.Wait()
+	return failed
+}
+
+// runPostUpdateHook - post update hook failures are only reported as the update is already applied
+func (p *Provider) runPostUpdateHook(plan *UpdatePlan) {
+	err := p.runHook(hookPostUpdate, plan)
+	if err != nil {
+		p.sendHookFailure(plan, err, fmt.Sprintf("%s %s/%s post-update hook failed after update %s->%s, error: %s", plan.Resource.Kind(), plan.Resource.Namespace, plan.Resource.Name, plan.CurrentVersion, plan.NewVersion, err))
+	}
+}
+
+func (p *Provider) sendHookFailure(plan *UpdatePlan, err error, msg string) {
+	resource := plan.Resource
+
+	log.WithFields(log.Fields{
+		"error":     err,
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+	}).Error("provider.kubernetes: update hook failed")
+
+	p.sender.Send(types.EventNotification{
+		Name:         "update hook",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      msg,
+		CreatedAt:    timeutil.Now(),
+		Type:         types.NotificationUpdateHook,
+		Level:        types.LevelError,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
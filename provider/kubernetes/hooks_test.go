@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newHookTestProvider(t *testing.T, fp *fakeImplementer, annotations map[string]string) (*Provider, *fakeSender, func()) {
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	sender := &fakeSender{}
+	approver, teardown := approver()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		teardown()
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	return provider, sender, teardown
+}
+
+func TestHTTPHooks(t *testing.T) {
+	var payloads []hookPayload
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload hookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	fp := &fakeImplementer{}
+	provider, _, teardown := newHookTestProvider(t, fp, map[string]string{
+		types.KeelPreUpdateHookAnnotation:  srv.URL + "/pre",
+		types.KeelPostUpdateHookAnnotation: srv.URL + "/post",
+	})
+	defer teardown()
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated")
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("expected pre and post hooks to be called, got: %d", len(payloads))
+	}
+	if payloads[0].Phase != hookPreUpdate || payloads[1].Phase != hookPostUpdate {
+		t.Errorf("unexpected hook order: %s, %s", payloads[0].Phase, payloads[1].Phase)
+	}
+	if payloads[0].PreviousVersion != "1.1.1" || payloads[0].NewVersion != "1.1.2" || payloads[0].Name != "dep-1" {
+		t.Errorf("unexpected payload: %+v", payloads[0])
+	}
+}
+
+func TestHTTPPreUpdateHookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	fp := &fakeImplementer{}
+	provider, sender, teardown := newHookTestProvider(t, fp, map[string]string{
+		types.KeelPreUpdateHookAnnotation: srv.URL,
+	})
+	defer teardown()
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Errorf("resource shouldn't be updated when pre-update hook fails")
+	}
+	if sender.sentEvent.Type != types.NotificationUpdateHook || sender.sentEvent.Level != types.LevelError {
+		t.Errorf("expected hook failure notification, got: %+v", sender.sentEvent)
+	}
+}
+
+func TestJobHooks(t *testing.T) {
+	hookCheckInterval = 10 * time.Millisecond
+	defer func() { hookCheckInterval = 2 * time.Second }()
+
+	fp := &fakeImplementer{
+		jobConditions: []batch_v1.JobCondition{{Type: batch_v1.JobComplete, Status: v1.ConditionTrue}},
+	}
+	provider, _, teardown := newHookTestProvider(t, fp, map[string]string{
+		types.KeelPreUpdateHookAnnotation:  `{"command": ["./migrate", "up"], "env": {"DB": "main"}}`,
+		types.KeelPostUpdateHookAnnotation: `{"image": "curlimages/curl:7.70.0", "args": ["http://dep-1/healthz"]}`,
+	})
+	defer teardown()
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated")
+	}
+	if len(fp.createdJobs) != 2 {
+		t.Fatalf("expected 2 hook jobs, got: %d", len(fp.createdJobs))
+	}
+
+	pre := fp.createdJobs[0]
+	if pre.Namespace != "xxxx" || pre.Labels[hookLabel] != hookPreUpdate {
+		t.Errorf("unexpected pre-update job: %s/%s %v", pre.Namespace, pre.Name, pre.Labels)
+	}
+	container := pre.Spec.Template.Spec.Containers[0]
+	if container.Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("expected pre-update hook to use new image, got: %s", container.Image)
+	}
+	if len(container.Command) != 2 || container.Command[0] != "./migrate" {
+		t.Errorf("unexpected command: %v", container.Command)
+	}
+	last := container.Env[len(container.Env)-1]
+	if last.Name != "DB" || last.Value != "main" {
+		t.Errorf("expected custom env to be set, got: %v", container.Env)
+	}
+
+	if img := fp.createdJobs[1].Spec.Template.Spec.Containers[0].Image; img != "curlimages/curl:7.70.0" {
+		t.Errorf("unexpected post-update hook image: %s", img)
+	}
+	if len(fp.deletedJobs) != 2 || fp.deletedJobs[0] != "xxxx/"+pre.Name {
+		t.Errorf("expected finished hook jobs to be deleted, got: %v", fp.deletedJobs)
+	}
+}
+
+func TestJobPreUpdateHookFailure(t *testing.T) {
+	hookCheckInterval = 10 * time.Millisecond
+	defer func() { hookCheckInterval = 2 * time.Second }()
+
+	fp := &fakeImplementer{
+		jobConditions: []batch_v1.JobCondition{{Type: batch_v1.JobFailed, Status: v1.ConditionTrue, Message: "BackoffLimitExceeded"}},
+	}
+	provider, sender, teardown := newHookTestProvider(t, fp, map[string]string{
+		types.KeelPreUpdateHookAnnotation: `{"command": ["./migrate", "up"]}`,
+	})
+	defer teardown()
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Errorf("resource shouldn't be updated when pre-update hook fails")
+	}
+	if len(fp.deletedJobs) != 1 {
+		t.Errorf("expected failed hook job to be deleted, got: %v", fp.deletedJobs)
+	}
+	if sender.sentEvent.Type != types.NotificationUpdateHook {
+		t.Errorf("expected hook failure notification, got: %+v", sender.sentEvent)
+	}
+}
+
+func TestJobPreUpdateHookTimeout(t *testing.T) {
+	hookCheckInterval = 10 * time.Millisecond
+	defer func() { hookCheckInterval = 2 * time.Second }()
+
+	fp := &fakeImplementer{}
+	provider, _, teardown := newHookTestProvider(t, fp, map[string]string{
+		types.KeelPreUpdateHookAnnotation: `{"command": ["./migrate", "up"]}`,
+		types.KeelHookTimeoutAnnotation:   "50ms",
+	})
+	defer teardown()
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Errorf("resource shouldn't be updated when pre-update hook times out")
+	}
+	if len(fp.createdJobs) != 1 || len(fp.deletedJobs) != 1 || fp.deletedJobs[0] != "xxxx/"+fp.createdJobs[0].Name {
+		t.Errorf("expected timed out hook job to be deleted, got: %v", fp.deletedJobs)
+	}
+}
+
+func TestHookJobName(t *testing.T) {
+	name := hookJobName("a-very-long-deployment-name-that-goes-on-and-on-and-on-forever", hookPreUpdate)
+	if len(name) > 63 {
+		t.Errorf("job name too long: %s", name)
+	}
+}
@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// hasReplicas - checks whether resource replica count can be managed by an autoscaler
+func hasReplicas(obj *k8s.GenericResource) bool {
+	switch resource := obj.GetResource().(type) {
+	case *apps_v1.Deployment, *apps_v1.StatefulSet:
+		return true
+	case *unstructured.Unstructured:
+		_, found, _ := unstructured.NestedFieldNoCopy(resource.Object, "spec", "replicas")
+		return found
+	}
+	return false
+}
+
+// isAutoscaled - checks whether any of the HorizontalPodAutoscalers targets the resource
+func isAutoscaled(hpas []autoscaling_v1.HorizontalPodAutoscaler, obj *k8s.GenericResource) bool {
+	for _, hpa := range hpas {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Name == obj.Name && strings.EqualFold(ref.Kind, obj.Kind()) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyReplicas - sets replica count from the live object so an update doesn't
+// reset replicas that were changed by an autoscaler
+func copyReplicas(obj *k8s.GenericResource, live interface{}) error {
+	switch resource := obj.GetResource().(type) {
+	case *apps_v1.Deployment:
+		l, ok := live.(*apps_v1.Deployment)
+		if !ok {
+			return fmt.Errorf("unexpected live object type %T", live)
+		}
+		resource.Spec.Replicas = l.Spec.Replicas
+	case *apps_v1.StatefulSet:
+		l, ok := live.(*apps_v1.StatefulSet)
+		if !ok {
+			return fmt.Errorf("unexpected live object type %T", live)
+		}
+		resource.Spec.Replicas = l.Spec.Replicas
+	case *unstructured.Unstructured:
+		l, ok := live.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected live object type %T", live)
+		}
+		replicas, found, err := unstructured.NestedFieldCopy(l.Object, "spec", "replicas")
+		if err != nil {
+			return err
+		}
+		if !found {
+			unstructured.RemoveNestedField(resource.Object, "spec", "replicas")
+			return nil
+		}
+		return unstructured.SetNestedField(resource.Object, replicas, "spec", "replicas")
+	default:
+		return fmt.Errorf("unsupported object type")
+	}
+	return nil
+}
+
+// autoscaled - checks whether resource is a scale target of a HorizontalPodAutoscaler
+func (i *KubernetesImplementer) autoscaled(obj *k8s.GenericResource) bool {
+	if !hasReplicas(obj) {
+		return false
+	}
+	hpas, err := i.client.AutoscalingV1().HorizontalPodAutoscalers(obj.Namespace).List(meta_v1.ListOptions{})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      obj.Name,
+			"namespace": obj.Namespace,
+		}).Warn("provider.kubernetes: failed to list horizontal pod autoscalers")
+		return false
+	}
+	return isAutoscaled(hpas.Items, obj)
+}
+
+// fallbackUpdate - sends whole resource when it can't be patched, replica count
+// of autoscaled resources is taken from the live object
+func (i *KubernetesImplementer) fallbackUpdate(obj *k8s.GenericResource) error {
+	if !i.autoscaled(obj) {
+		return i.update(obj)
+	}
+
+	var (
+		live interface{}
+		err  error
+	)
+	switch resource := obj.GetResource().(type) {
+	case *apps_v1.Deployment:
+		live, err = i.client.AppsV1().Deployments(resource.Namespace).Get(resource.Name, meta_v1.GetOptions{})
+	case *apps_v1.StatefulSet:
+		live, err = i.client.AppsV1().StatefulSets(resource.Namespace).Get(resource.Name, meta_v1.GetOptions{})
+	case *unstructured.Unstructured:
+		gvr, ok := k8s.CustomResource(resource)
+		if !ok {
+			return fmt.Errorf("unsupported custom resource")
+		}
+		live, err = i.dynamicClient.Resource(gvr).Namespace(resource.GetNamespace()).Get(resource.GetName(), meta_v1.GetOptions{})
+	default:
+		return fmt.Errorf("unsupported object type")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get current replica count: %s", err)
+	}
+
+	err = copyReplicas(obj, live)
+	if err != nil {
+		return err
+	}
+	return i.update(obj)
+}
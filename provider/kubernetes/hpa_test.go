@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestIsAutoscaled(t *testing.T) {
+	hpas := []autoscaling_v1.HorizontalPodAutoscaler{
+		{
+			Spec: autoscaling_v1.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscaling_v1.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+			},
+		},
+	}
+
+	web := MustParseGR(&apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"}})
+	if !hasReplicas(web) || !isAutoscaled(hpas, web) {
+		t.Errorf("expected deployment to be autoscaled")
+	}
+
+	other := MustParseGR(&apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "default"}})
+	if isAutoscaled(hpas, other) {
+		t.Errorf("expected deployment not to be autoscaled")
+	}
+
+	sts := MustParseGR(&apps_v1.StatefulSet{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"}})
+	if isAutoscaled(hpas, sts) {
+		t.Errorf("expected statefulset with the same name not to be autoscaled")
+	}
+
+	cronJob := MustParseGR(&v1beta1.CronJob{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"}})
+	if hasReplicas(cronJob) {
+		t.Errorf("cronjobs don't have replicas")
+	}
+}
+
+func TestCopyReplicas(t *testing.T) {
+	gr := MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       apps_v1.DeploymentSpec{Replicas: int32Ptr(1)},
+	})
+	live := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       apps_v1.DeploymentSpec{Replicas: int32Ptr(7)},
+	}
+	err := copyReplicas(gr, live)
+	if err != nil {
+		t.Fatalf("failed to copy replicas: %s", err)
+	}
+	if *gr.GetResource().(*apps_v1.Deployment).Spec.Replicas != 7 {
+		t.Errorf("expected live replica count")
+	}
+
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": int64(2)},
+	}}
+	liveRollout := rollout.DeepCopy()
+	unstructured.SetNestedField(liveRollout.Object, int64(5), "spec", "replicas")
+
+	gr = MustParseGR(rollout)
+	if !hasReplicas(gr) {
+		t.Fatalf("expected rollout to have replicas")
+	}
+	err = copyReplicas(gr, liveRollout)
+	if err != nil {
+		t.Fatalf("failed to copy replicas: %s", err)
+	}
+	replicas, _, _ := unstructured.NestedInt64(gr.GetResource().(*unstructured.Unstructured).Object, "spec", "replicas")
+	if replicas != 5 {
+		t.Errorf("expected live replica count, got: %d", replicas)
+	}
+
+	if err := copyReplicas(gr, live); err == nil {
+		t.Errorf("expected error for mismatched live object type")
+	}
+}
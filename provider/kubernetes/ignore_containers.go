@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/ryanuber/go-glob"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// getIgnoredContainers - parses comma separated keel.sh/ignore-containers annotation.
+// Label values can't contain commas or wildcards so only annotation is checked.
+func getIgnoredContainers(annotations map[string]string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(annotations[types.KeelIgnoreContainersAnnotation], ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// isIgnoredContainer - checks whether container name, image or image repository
+// matches any of the patterns, patterns can contain '*' wildcards
+func isIgnoredContainer(c v1.Container, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	candidates := []string{c.Name, c.Image}
+	ref, err := image.Parse(c.Image)
+	if err == nil {
+		candidates = append(candidates, ref.Repository())
+	}
+
+	for _, pattern := range patterns {
+		for _, candidate := range candidates {
+			if glob.Glob(pattern, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,13 +2,20 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
 
 	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	v1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8s_types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -21,11 +28,20 @@ import (
 // Implementer - thing wrapper around currently used k8s APIs
 type Implementer interface {
 	Namespaces() (*v1.NamespaceList, error)
+	Deployment(namespace, name string) (*apps_v1.Deployment, error)
 	Deployments(namespace string) (*apps_v1.DeploymentList, error)
+	CreateDeployment(deployment *apps_v1.Deployment) (*apps_v1.Deployment, error)
+	DeleteDeployment(namespace, name string) error
+	DaemonSets(namespace string) (*apps_v1.DaemonSetList, error)
 	Update(obj *k8s.GenericResource) error
 	Secret(namespace, name string) (*v1.Secret, error)
+	ServiceAccount(namespace, name string) (*v1.ServiceAccount, error)
 	Pods(namespace, labelSelector string) (*v1.PodList, error)
 	DeletePod(namespace, name string, opts *meta_v1.DeleteOptions) error
+	CreateJob(job *batch_v1.Job) (*batch_v1.Job, error)
+	PodDisruptionBudgets(namespace string) (*policy_v1beta1.PodDisruptionBudgetList, error)
+	Job(namespace, name string) (*batch_v1.Job, error)
+	DeleteJob(namespace, name string) error
 
 	ConfigMaps(namespace string) core_v1.ConfigMapInterface
 }
@@ -35,6 +51,11 @@ type Implementer interface {
 type KubernetesImplementer struct {
 	cfg    *rest.Config
 	client *kubernetes.Clientset
+
+	// dynamicClient - used for custom resources such as Argo Rollouts and Knative Services
+	dynamicClient dynamic.Interface
+
+	updateMethod string
 }
 
 // Opts - implementer options, usually for k8s deployments
@@ -44,12 +65,27 @@ type Opts struct {
 	InCluster  bool
 	ConfigPath string
 	Master     string
+	// Context - kubeconfig context, current context is used if empty
+	Context string
+
+	// UpdateMethod - either "update" (default) or "patch"
+	UpdateMethod string
 }
 
 // NewKubernetesImplementer - create new k8s implementer
 func NewKubernetesImplementer(opts *Opts) (*KubernetesImplementer, error) {
 	cfg := &rest.Config{}
 
+	updateMethod := opts.UpdateMethod
+	switch updateMethod {
+	case "":
+		updateMethod = UpdateMethodUpdate
+	case UpdateMethodUpdate, UpdateMethodPatch:
+		// ok
+	default:
+		return nil, fmt.Errorf("unknown update method '%s', should be either '%s' or '%s'", updateMethod, UpdateMethodUpdate, UpdateMethodPatch)
+	}
+
 	if opts.InCluster {
 		var err error
 		cfg, err = rest.InClusterConfig()
@@ -62,7 +98,10 @@ func NewKubernetesImplementer(opts *Opts) (*KubernetesImplementer, error) {
 		log.Info("provider.kubernetes: using in-cluster configuration")
 	} else if opts.ConfigPath != "" {
 		var err error
-		cfg, err = clientcmd.BuildConfigFromFlags("", opts.ConfigPath)
+		cfg, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: opts.ConfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: opts.Context},
+		).ClientConfig()
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
@@ -81,7 +120,15 @@ func NewKubernetesImplementer(opts *Opts) (*KubernetesImplementer, error) {
 		return nil, err
 	}
 
-	return &KubernetesImplementer{client: client, cfg: cfg}, nil
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("provider.kubernetes: failed to create dynamic kubernetes client")
+		return nil, err
+	}
+
+	return &KubernetesImplementer{client: client, dynamicClient: dynamicClient, cfg: cfg, updateMethod: updateMethod}, nil
 }
 
 func (i *KubernetesImplementer) Client() *kubernetes.Clientset {
@@ -92,12 +139,42 @@ func (i *KubernetesImplementer) Config() *rest.Config {
 	return i.cfg
 }
 
+// DynamicClient - client for custom resources
+func (i *KubernetesImplementer) DynamicClient() dynamic.Interface {
+	return i.dynamicClient
+}
+
 // Namespaces - get all namespaces
 func (i *KubernetesImplementer) Namespaces() (*v1.NamespaceList, error) {
 	namespaces := i.client.CoreV1().Namespaces()
 	return namespaces.List(meta_v1.ListOptions{})
 }
 
+// Platforms - distinct os/arch of cluster nodes, sorted by number of nodes
+func (i *KubernetesImplementer) Platforms() ([]registry.Platform, error) {
+	nodes, err := i.client.CoreV1().Nodes().List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[registry.Platform]int)
+	var platforms []registry.Platform
+	for _, node := range nodes.Items {
+		p := registry.Platform{OS: node.Status.NodeInfo.OperatingSystem, Architecture: node.Status.NodeInfo.Architecture}
+		if p.OS == "" || p.Architecture == "" {
+			continue
+		}
+		if counts[p] == 0 {
+			platforms = append(platforms, p)
+		}
+		counts[p]++
+	}
+	sort.SliceStable(platforms, func(a, b int) bool {
+		return counts[platforms[a]] > counts[platforms[b]]
+	})
+	return platforms, nil
+}
+
 // Deployment - get specific deployment for namespace/name
 func (i *KubernetesImplementer) Deployment(namespace, name string) (*apps_v1.Deployment, error) {
 	dep := i.client.AppsV1().Deployments(namespace)
@@ -111,8 +188,32 @@ func (i *KubernetesImplementer) Deployments(namespace string) (*apps_v1.Deployme
 	return l, err
 }
 
-// Update converts generic resource into specific kubernetes type and updates it
+// CreateDeployment - create deployment, used for canaries
+func (i *KubernetesImplementer) CreateDeployment(deployment *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	return i.client.AppsV1().Deployments(deployment.Namespace).Create(deployment)
+}
+
+// DeleteDeployment - delete deployment together with its pods
+func (i *KubernetesImplementer) DeleteDeployment(namespace, name string) error {
+	propagation := meta_v1.DeletePropagationForeground
+	return i.client.AppsV1().Deployments(namespace).Delete(name, &meta_v1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// DaemonSets - get all daemonsets for namespace
+func (i *KubernetesImplementer) DaemonSets(namespace string) (*apps_v1.DaemonSetList, error) {
+	return i.client.AppsV1().DaemonSets(namespace).List(meta_v1.ListOptions{})
+}
+
+// Update converts generic resource into specific kubernetes type and updates it,
+// replica count of resources scaled by a HorizontalPodAutoscaler is never changed
 func (i *KubernetesImplementer) Update(obj *k8s.GenericResource) error {
+	if i.updateMethod == UpdateMethodPatch || i.autoscaled(obj) {
+		return i.patch(obj)
+	}
+	return i.update(obj)
+}
+
+func (i *KubernetesImplementer) update(obj *k8s.GenericResource) error {
 	// retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 	// 	// Retrieve the latest version of Deployment before attempting update
 	// 	// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
@@ -142,17 +243,70 @@ func (i *KubernetesImplementer) Update(obj *k8s.GenericResource) error {
 		if err != nil {
 			return err
 		}
+	case *unstructured.Unstructured:
+		gvr, ok := k8s.CustomResource(resource)
+		if !ok {
+			return fmt.Errorf("unsupported custom resource")
+		}
+		if isJob(gvr) {
+			return i.recreateJob(resource, gvr)
+		}
+		_, err := i.dynamicClient.Resource(gvr).Namespace(resource.GetNamespace()).Update(resource, meta_v1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported object type")
 	}
 	return nil
 }
 
+// patch sends strategic merge patch that only changes images and annotations
+func (i *KubernetesImplementer) patch(obj *k8s.GenericResource) error {
+	if _, ok := obj.GetResource().(*unstructured.Unstructured); ok {
+		// custom resources don't support strategic merge patches
+		return i.fallbackUpdate(obj)
+	}
+
+	data, err := getStrategicMergePatch(obj)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      obj.Name,
+			"namespace": obj.Namespace,
+		}).Warn("provider.kubernetes: failed to create patch, falling back to update")
+		return i.fallbackUpdate(obj)
+	}
+
+	return i.sendPatch(obj, data)
+}
+
+func (i *KubernetesImplementer) sendPatch(obj *k8s.GenericResource, data []byte) (err error) {
+	switch resource := obj.GetResource().(type) {
+	case *apps_v1.Deployment:
+		_, err = i.client.AppsV1().Deployments(resource.Namespace).Patch(resource.Name, k8s_types.StrategicMergePatchType, data)
+	case *apps_v1.StatefulSet:
+		_, err = i.client.AppsV1().StatefulSets(resource.Namespace).Patch(resource.Name, k8s_types.StrategicMergePatchType, data)
+	case *apps_v1.DaemonSet:
+		_, err = i.client.AppsV1().DaemonSets(resource.Namespace).Patch(resource.Name, k8s_types.StrategicMergePatchType, data)
+	case *v1beta1.CronJob:
+		_, err = i.client.BatchV1beta1().CronJobs(resource.Namespace).Patch(resource.Name, k8s_types.StrategicMergePatchType, data)
+	default:
+		return fmt.Errorf("unsupported object type")
+	}
+	return err
+}
+
 // Secret - get secret
 func (i *KubernetesImplementer) Secret(namespace, name string) (*v1.Secret, error) {
 	return i.client.CoreV1().Secrets(namespace).Get(name, meta_v1.GetOptions{})
 }
 
+// ServiceAccount - get service account
+func (i *KubernetesImplementer) ServiceAccount(namespace, name string) (*v1.ServiceAccount, error) {
+	return i.client.CoreV1().ServiceAccounts(namespace).Get(name, meta_v1.GetOptions{})
+}
+
 // Pods - get pods
 func (i *KubernetesImplementer) Pods(namespace, labelSelector string) (*v1.PodList, error) {
 	return i.client.CoreV1().Pods(namespace).List(meta_v1.ListOptions{LabelSelector: labelSelector})
@@ -163,6 +317,27 @@ func (i *KubernetesImplementer) DeletePod(namespace, name string, opts *meta_v1.
 	return i.client.CoreV1().Pods(namespace).Delete(name, opts)
 }
 
+// CreateJob - create job, used to run update hooks
+func (i *KubernetesImplementer) CreateJob(job *batch_v1.Job) (*batch_v1.Job, error) {
+	return i.client.BatchV1().Jobs(job.Namespace).Create(job)
+}
+
+// Job - get job by name
+func (i *KubernetesImplementer) Job(namespace, name string) (*batch_v1.Job, error) {
+	return i.client.BatchV1().Jobs(namespace).Get(name, meta_v1.GetOptions{})
+}
+
+// DeleteJob - delete job, its pods are garbage collected in the background
+func (i *KubernetesImplementer) DeleteJob(namespace, name string) error {
+	propagation := meta_v1.DeletePropagationBackground
+	return i.client.BatchV1().Jobs(namespace).Delete(name, &meta_v1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// PodDisruptionBudgets - get all pod disruption budgets for namespace
+func (i *KubernetesImplementer) PodDisruptionBudgets(namespace string) (*policy_v1beta1.PodDisruptionBudgetList, error) {
+	return i.client.PolicyV1beta1().PodDisruptionBudgets(namespace).List(meta_v1.ListOptions{})
+}
+
 // ConfigMaps - returns an interface to config maps for a specified namespace
 func (i *KubernetesImplementer) ConfigMaps(namespace string) core_v1.ConfigMapInterface {
 	return i.client.CoreV1().ConfigMaps(namespace)
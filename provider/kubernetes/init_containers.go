@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// updatableContainer - container or init container that can be updated by keel
+type updatableContainer struct {
+	v1.Container
+
+	index int
+	init  bool
+}
+
+// update - sets new container image on the resource
+func (c updatableContainer) update(resource *k8s.GenericResource, image string) {
+	if c.init {
+		resource.UpdateInitContainer(c.index, image)
+		return
+	}
+	resource.UpdateContainer(c.index, image)
+}
+
+// hasInitContainerUpdates - checks whether init containers are opted in for updates
+// through annotations or labels
+func hasInitContainerUpdates(labels map[string]string, annotations map[string]string) bool {
+	val, ok := annotations[types.KeelInitContainersAnnotation]
+	if !ok {
+		val = labels[types.KeelInitContainersAnnotation]
+	}
+	return strings.ToLower(strings.TrimSpace(val)) == "true"
+}
+
+// getUpdatableContainers - returns resource containers followed by init containers
+// if they are opted in for updates, containers listed in keel.sh/ignore-containers are
+// skipped. Ephemeral containers are not part of pod templates so they can't be updated
+// through workload resources.
+func getUpdatableContainers(resource *k8s.GenericResource) []updatableContainer {
+	var containers []updatableContainer
+	labels := resource.GetLabels()
+	annotations := resource.GetAnnotations()
+	ignored := getIgnoredContainers(annotations)
+
+	for idx, c := range resource.Containers() {
+		if isIgnoredContainer(c, ignored) {
+			continue
+		}
+		containers = append(containers, updatableContainer{Container: c, index: idx})
+	}
+	if !hasInitContainerUpdates(labels, annotations) {
+		return containers
+	}
+	for idx, c := range resource.InitContainers() {
+		if isIgnoredContainer(c, ignored) {
+			continue
+		}
+		containers = append(containers, updatableContainer{Container: c, index: idx, init: true})
+	}
+	return containers
+}
+
+// getUpdatableImages - returns images of containers that can be updated by keel
+func getUpdatableImages(resource *k8s.GenericResource) []string {
+	var images []string
+	for _, c := range getUpdatableContainers(resource) {
+		images = append(images, c.Image)
+	}
+	return images
+}
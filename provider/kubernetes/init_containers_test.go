@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// migrateTestContainer - init container added to the test deployment
+var migrateTestContainer = v1.Container{Name: "migrate", Image: "gcr.io/v2-namespace/migrations:1.1.1"}
+
+func TestProcessEventInitContainers(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantUpdated bool
+	}{
+		{
+			name:        "not opted in",
+			annotations: map[string]string{},
+		},
+		{
+			name:        "opted in",
+			annotations: map[string]string{types.KeelInitContainersAnnotation: "true"},
+			wantUpdated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := &fakeImplementer{}
+			grc := &k8s.GenericResourceCache{}
+			deployment := newTestDeployment("xxxx", "dep-1", 1, tt.annotations, apps_v1.DeploymentStatus{}, v1.Container{Name: "app", Image: "gcr.io/v2-namespace/hello-world:1.1.1"})
+			deployment.Spec.Template.Spec.InitContainers = []v1.Container{migrateTestContainer}
+			grc.Add(MustParseGR(deployment))
+
+			approver, teardown := approver()
+			defer teardown()
+			provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+			if err != nil {
+				t.Fatalf("failed to get provider: %s", err)
+			}
+
+			_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+				Name: "gcr.io/v2-namespace/migrations",
+				Tag:  "1.1.2",
+			}})
+			if err != nil {
+				t.Fatalf("got error while processing event: %s", err)
+			}
+
+			if !tt.wantUpdated {
+				if fp.updated != nil {
+					t.Errorf("resource shouldn't be updated")
+				}
+				return
+			}
+
+			if fp.updated == nil {
+				t.Fatalf("resource was not updated")
+			}
+			if fp.updated.InitContainers()[0].Image != "gcr.io/v2-namespace/migrations:1.1.2" {
+				t.Errorf("unexpected init container image: %s", fp.updated.InitContainers()[0].Image)
+			}
+			if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.1" {
+				t.Errorf("container shouldn't be updated, got: %s", fp.updated.Containers()[0].Image)
+			}
+		})
+	}
+}
+
+func TestTrackedImagesInitContainers(t *testing.T) {
+	grc := &k8s.GenericResourceCache{}
+	deployment := newTestDeployment("xxxx", "dep-1", 1, map[string]string{types.KeelInitContainersAnnotation: "true"}, apps_v1.DeploymentStatus{}, v1.Container{Name: "app", Image: "gcr.io/v2-namespace/hello-world:1.1.1"})
+	deployment.Spec.Template.Spec.InitContainers = []v1.Container{migrateTestContainer}
+	grc.Add(MustParseGR(deployment))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(&fakeImplementer{}, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	imgs, err := provider.TrackedImages()
+	if err != nil {
+		t.Fatalf("failed to get tracked images: %s", err)
+	}
+
+	if len(imgs) != 2 {
+		t.Fatalf("expected 2 tracked images, got: %d", len(imgs))
+	}
+	if imgs[1].Image.Repository() != "gcr.io/v2-namespace/migrations" {
+		t.Errorf("unexpected tracked image: %s", imgs[1].Image.Repository())
+	}
+}
+
+func TestGetStrategicMergePatchInitContainers(t *testing.T) {
+	deployment := newTestDeployment("xxxx", "dep-1", 1, nil, apps_v1.DeploymentStatus{}, v1.Container{Name: "app", Image: "gcr.io/v2-namespace/hello-world:1.1.1"})
+	deployment.Spec.Template.Spec.InitContainers = []v1.Container{migrateTestContainer}
+	gr := MustParseGR(deployment)
+
+	data, err := getStrategicMergePatch(gr)
+	if err != nil {
+		t.Fatalf("failed to create patch: %s", err)
+	}
+
+	expected := `{"metadata":{},"spec":{"template":{"metadata":{},"spec":{"containers":[{"name":"app","image":"gcr.io/v2-namespace/hello-world:1.1.1"}],"initContainers":[{"name":"migrate","image":"gcr.io/v2-namespace/migrations:1.1.1"}]}}}}`
+	if string(data) != expected {
+		t.Errorf("unexpected patch:\n%s\nexpected:\n%s", string(data), expected)
+	}
+}
@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/keel-hq/keel/internal/k8s"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// labels added by the Job controller, new Job gets its own
+var jobControllerLabels = []string{"controller-uid", "job-name"}
+
+func isJob(gvr schema.GroupVersionResource) bool {
+	return gvr.Group == k8s.JobResource.Group && gvr.Resource == k8s.JobResource.Resource
+}
+
+// newJobFromExisting - prepares Job for re-creation: server populated metadata, status and
+// generated selector are removed, everything else (including suspend) is kept
+func newJobFromExisting(job *unstructured.Unstructured) *unstructured.Unstructured {
+	u := job.DeepCopy()
+
+	for _, field := range []string{"resourceVersion", "uid", "selfLink", "creationTimestamp", "generation", "managedFields"} {
+		unstructured.RemoveNestedField(u.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(u.Object, "status")
+
+	if manual, _, _ := unstructured.NestedBool(u.Object, "spec", "manualSelector"); manual {
+		return u
+	}
+
+	unstructured.RemoveNestedField(u.Object, "spec", "selector")
+	labels, found, _ := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "labels")
+	if found {
+		for _, l := range jobControllerLabels {
+			delete(labels, l)
+		}
+		unstructured.SetNestedStringMap(u.Object, labels, "spec", "template", "metadata", "labels")
+	}
+	return u
+}
+
+// recreateJob - Job pod template is immutable so Jobs are updated by deleting them and creating
+// them again with the new image. Jobs with active pods are not interrupted.
+func (i *KubernetesImplementer) recreateJob(job *unstructured.Unstructured, gvr schema.GroupVersionResource) error {
+	if active, _, _ := unstructured.NestedInt64(job.Object, "status", "active"); active > 0 {
+		return fmt.Errorf("job %s/%s is still running", job.GetNamespace(), job.GetName())
+	}
+
+	client := i.dynamicClient.Resource(gvr).Namespace(job.GetNamespace())
+
+	propagation := meta_v1.DeletePropagationBackground
+	err := client.Delete(job.GetName(), &meta_v1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %s", err)
+	}
+
+	_, err = client.Create(newJobFromExisting(job), meta_v1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create job: %s", err)
+	}
+
+	log.WithFields(log.Fields{
+		"name":      job.GetName(),
+		"namespace": job.GetNamespace(),
+	}).Info("provider.kubernetes: job re-created")
+
+	return nil
+}
+
+// getJobStatus - Job is done once Complete condition is true, Failed condition means failure
+func getJobStatus(obj *unstructured.Unstructured) (done bool, failed bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range conditions {
+		c, ok := item.(map[string]interface{})
+		if !ok || c["status"] != string(v1.ConditionTrue) {
+			continue
+		}
+		switch c["type"] {
+		case "Complete":
+			return true, false
+		case "Failed":
+			return false, true
+		}
+	}
+	return false, false
+}
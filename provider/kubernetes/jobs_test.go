@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestJob(image string, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]interface{}{
+			"name":            "migrate",
+			"namespace":       "xxxx",
+			"uid":             "8b5c3e0a",
+			"resourceVersion": "1234",
+			"labels":          map[string]interface{}{types.KeelPolicyLabel: "all"},
+		},
+		"spec": map[string]interface{}{
+			"suspend": true,
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"controller-uid": "8b5c3e0a"},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app":            "migrate",
+						"controller-uid": "8b5c3e0a",
+						"job-name":       "migrate",
+					},
+				},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "migrate",
+							"image": image,
+						},
+					},
+				},
+			},
+		},
+		"status": status,
+	}}
+}
+
+func TestProcessEventJob(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestJob("gcr.io/v2-namespace/migrate:1.1.1", nil)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/migrate",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("job was not updated")
+	}
+	if fp.updated.Identifier != "job/xxxx/migrate" {
+		t.Errorf("unexpected identifier: %s", fp.updated.Identifier)
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/migrate:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+}
+
+func TestNewJobFromExisting(t *testing.T) {
+	job := newJobFromExisting(newTestJob("gcr.io/v2-namespace/migrate:1.1.2", map[string]interface{}{"succeeded": int64(1)}))
+
+	if job.GetResourceVersion() != "" || job.GetUID() != "" {
+		t.Errorf("expected server populated metadata to be removed: %v", job.Object["metadata"])
+	}
+	if _, found := job.Object["status"]; found {
+		t.Errorf("expected status to be removed")
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(job.Object, "spec", "selector"); found {
+		t.Errorf("expected generated selector to be removed")
+	}
+	labels, _, _ := unstructured.NestedStringMap(job.Object, "spec", "template", "metadata", "labels")
+	if len(labels) != 1 || labels["app"] != "migrate" {
+		t.Errorf("unexpected template labels: %v", labels)
+	}
+	if suspend, _, _ := unstructured.NestedBool(job.Object, "spec", "suspend"); !suspend {
+		t.Errorf("expected job to stay suspended")
+	}
+	if job.GetName() != "migrate" || job.GetLabels()[types.KeelPolicyLabel] != "all" {
+		t.Errorf("expected name and labels to be kept")
+	}
+}
+
+func TestGetJobStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     map[string]interface{}
+		wantDone   bool
+		wantFailed bool
+	}{
+		{name: "running", status: map[string]interface{}{"active": int64(1)}},
+		{
+			name: "complete",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Complete", "status": "True"},
+			}},
+			wantDone: true,
+		},
+		{
+			name: "failed",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Failed", "status": "True"},
+			}},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, failed := getRolloutStatus(MustParseGR(newTestJob("gcr.io/v2-namespace/migrate:1.1.2", tt.status)))
+			if done != tt.wantDone || failed != tt.wantFailed {
+				t.Errorf("getRolloutStatus() = %v, %v, want %v, %v", done, failed, tt.wantDone, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestKnativeService(image string, labels map[string]interface{}, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":       "hello",
+			"namespace":  "xxxx",
+			"generation": int64(2),
+			"labels":     labels,
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"image": image,
+						},
+					},
+				},
+			},
+		},
+		"status": status,
+	}}
+}
+
+func TestProcessEventKnativeService(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestKnativeService("gcr.io/v2-namespace/hello-world:1.1.1", map[string]interface{}{
+		types.KeelPolicyLabel: "all",
+	}, nil)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("knative service was not updated")
+	}
+	if fp.updated.Identifier != "knativeservice/xxxx/hello" {
+		t.Errorf("unexpected identifier: %s", fp.updated.Identifier)
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+}
+
+func TestKnativeServiceApprovals(t *testing.T) {
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestKnativeService("gcr.io/v2-namespace/hello-world:1.1.1", map[string]interface{}{
+		types.KeelPolicyLabel:           "all",
+		types.KeelMinimumApprovalsLabel: "1",
+	}, nil)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated != nil {
+		t.Fatalf("knative service shouldn't be updated without approval")
+	}
+
+	approval, err := provider.approvalManager.Get("knativeservice/xxxx/hello:1.1.2")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.VotesRequired != 1 {
+		t.Errorf("unexpected required votes: %d", approval.VotesRequired)
+	}
+}
+
+func TestGetKnativeServiceStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     map[string]interface{}
+		wantDone   bool
+		wantFailed bool
+	}{
+		{
+			name:   "not observed yet",
+			status: map[string]interface{}{"observedGeneration": int64(1)},
+		},
+		{
+			name: "ready",
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+				},
+			},
+			wantDone: true,
+		},
+		{
+			name: "failed",
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "reason": "RevisionFailed"},
+				},
+			},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, failed := getRolloutStatus(MustParseGR(newTestKnativeService("karolisr/keel:0.2.0", nil, tt.status)))
+			if done != tt.wantDone {
+				t.Errorf("getRolloutStatus() done = %v, want %v", done, tt.wantDone)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("getRolloutStatus() failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/Masterminds/semver"
-	"github.com/rusenask/cron"
 
 	v1 "k8s.io/api/core/v1"
 
@@ -15,11 +14,14 @@ import (
 
 	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/extension/scanner"
 	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/internal/policy"
+	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
 	"github.com/keel-hq/keel/util/policies"
+	"github.com/keel-hq/keel/util/schedule"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -56,6 +58,10 @@ type GenericResourceCache interface {
 	// The slice and its contents should be treated as read-only.
 	Values() []*k8s.GenericResource
 
+	// ValuesByRepository returns a copy of resources that are using
+	// specified image repository.
+	ValuesByRepository(repository string) []*k8s.GenericResource
+
 	// Register registers ch to receive a value when Notify is called.
 	Register(chan int, int)
 }
@@ -70,6 +76,24 @@ type UpdatePlan struct {
 	CurrentVersion string
 	// New version that's already in the deployment
 	NewVersion string
+
+	// PreviousImages - container images (followed by init container images if they are
+	// updated too) before the update, used for rollbacks
+	PreviousImages []string
+
+	// Trigger - name of the trigger that submitted the event
+	Trigger string
+	// ApprovedBy - voters that approved the update
+	ApprovedBy []string
+
+	// RequiredApprovals - approvals required regardless of resource settings, for example
+	// when the new image has vulnerabilities, ApprovalReason is added to approval message
+	RequiredApprovals int
+	ApprovalReason    string
+
+	// ApprovalGroup - grouped approval identifier when the update was approved together
+	// with other resources, see SetGroupApprovals
+	ApprovalGroup string
 }
 
 func (p *UpdatePlan) String() string {
@@ -89,16 +113,90 @@ type Provider struct {
 
 	cache GenericResourceCache
 
+	// cluster - cluster name, only set when keel manages several clusters
+	cluster string
+
+	// dryRun - updates are only reported, see also keel.sh/dryRun annotation
+	dryRun bool
+
+	// historyLimit - number of entries kept in keel.sh/update-history annotation, 0 disables history
+	historyLimit int
+
+	// groupApprovals - resources updated to the same image version share a single approval
+	groupApprovals bool
+
+	// routes - notification channels and webhooks by resource namespace and labels
+	routes *notification.Routes
+
+	// rolloutNotificationTimeout - rollouts of resources without keel.sh/rollout-timeout are
+	// monitored for this long to send their outcome, 0 disables monitoring
+	rolloutNotificationTimeout time.Duration
+
+	// registryClient - used to resolve image digests when digest pinning is enabled and
+	// image creation times for keel.sh/min-age
+	registryClient registry.Client
+
+	// signatures, verifier - cosign signature verification of new images, see SetSignatureVerifier
+	signatures       signatureRegistry
+	verifier         signatureVerifier
+	verifySignatures bool
+	rejected         *rejectedImages
+
+	// scan, vulnerabilityThreshold, vulnerabilityAction - vulnerability scan of new images,
+	// see SetVulnerabilityGate
+	scan                   func(target *scanner.Target) (*scanner.Report, error)
+	vulnerabilityThreshold string
+	vulnerabilityAction    string
+	vulnerable             *rejectedImages
+
+	// rolledBack - images that failed to roll out, see checkForRolledBack
+	rolledBack *rejectedImages
+
+	// deferred - updates waiting for their maintenance window
+	deferred *deferredUpdates
+
+	// paused - updates recorded for resources with keel.sh/paused annotation
+	paused *deferredUpdates
+
+	// staged - updates waiting for rollouts of lower weight resources, canaries or
+	// pod disruption budgets
+	staged *deferredUpdates
+
+	canaries *canaries
+
+	// missing - when resources referenced by approvals or queued updates were first
+	// found missing from the cache, used for garbage collection
+	missing map[string]time.Time
+
+	// eventDebounce - events for the same repository are coalesced during this interval
+	eventDebounce time.Duration
+	pending       map[string]*pendingEvent
+	debounced     chan *pendingEvent
+
 	events chan *types.Event
 	stop   chan struct{}
 }
 
 // NewProvider - create new kubernetes based provider
 func NewProvider(implementer Implementer, sender notification.Sender, approvalManager approvals.Manager, cache GenericResourceCache) (*Provider, error) {
+	registryClient := registry.New()
 	return &Provider{
 		implementer:     implementer,
 		cache:           cache,
 		approvalManager: approvalManager,
+		registryClient:  registryClient,
+		signatures:      registryClient,
+		rejected:        &rejectedImages{},
+		scan:            scanner.Scan,
+		vulnerable:      &rejectedImages{},
+		rolledBack:      &rejectedImages{},
+		deferred:        &deferredUpdates{},
+		paused:          &deferredUpdates{},
+		staged:          &deferredUpdates{},
+		canaries:        &canaries{},
+		missing:         make(map[string]time.Time),
+		pending:         make(map[string]*pendingEvent),
+		debounced:       make(chan *pendingEvent),
 		events:          make(chan *types.Event, 100),
 		stop:            make(chan struct{}),
 		sender:          sender,
@@ -113,6 +211,9 @@ func (p *Provider) Submit(event types.Event) error {
 
 // GetName - get provider name
 func (p *Provider) GetName() string {
+	if p.cluster != "" {
+		return ProviderName + "/" + p.cluster
+	}
 	return ProviderName
 }
 
@@ -130,13 +231,13 @@ func getImagePullSecretFromMeta(labels map[string]string, annotations map[string
 
 	searchKey := strings.ToLower(types.KeelImagePullSecretAnnotation)
 
-	for k, v := range labels {
+	for k, v := range annotations {
 		if strings.ToLower(k) == searchKey {
 			return v
 		}
 	}
 
-	for k, v := range annotations {
+	for k, v := range labels {
 		if strings.ToLower(k) == searchKey {
 			return v
 		}
@@ -155,24 +256,25 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 
 		// ignoring unlabelled deployments
 		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		if plc.Type() == policy.PolicyTypeNone {
+		hasContainerPolicies := policy.HasContainerPolicies(labels, annotations)
+		if plc.Type() == policy.PolicyTypeNone && !hasContainerPolicies {
 			continue
 		}
 
-		schedule, ok := annotations[types.KeelPollScheduleAnnotation]
+		pollSchedule, ok := annotations[types.KeelPollScheduleAnnotation]
 		if ok {
-			_, err := cron.Parse(schedule)
+			err := schedule.Validate(pollSchedule)
 			if err != nil {
 				log.WithFields(log.Fields{
 					"error":     err,
-					"schedule":  schedule,
+					"schedule":  pollSchedule,
 					"name":      gr.Name,
 					"namespace": gr.Namespace,
 				}).Error("provider.kubernetes: failed to parse poll schedule, setting default schedule")
-				schedule = types.KeelPollDefaultSchedule
+				pollSchedule = schedule.Default()
 			}
 		} else {
-			schedule = types.KeelPollDefaultSchedule
+			pollSchedule = schedule.Default()
 		}
 
 		// trigger type, we only care for "poll" type triggers
@@ -186,8 +288,17 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 		}
 		secrets = append(secrets, gr.GetImagePullSecrets()...)
 
-		images := gr.GetImages()
-		for _, img := range images {
+		for _, c := range getUpdatableContainers(gr) {
+			img := c.Image
+
+			containerPlc := plc
+			if hasContainerPolicies {
+				containerPlc = policy.GetContainerPolicyFromLabelsOrAnnotations(c.Name, labels, annotations)
+				if containerPlc.Type() == policy.PolicyTypeNone {
+					continue
+				}
+			}
+
 			ref, err := image.Parse(img)
 			if err != nil {
 				log.WithFields(log.Fields{
@@ -208,14 +319,15 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 			}
 
 			trackedImages = append(trackedImages, &types.TrackedImage{
-				Image:        ref,
-				PollSchedule: schedule,
-				Trigger:      trigger,
-				Provider:     ProviderName,
-				Namespace:    gr.Namespace,
-				Secrets:      secrets,
-				Meta:         make(map[string]string),
-				Policy:       plc,
+				Image:          ref,
+				PollSchedule:   pollSchedule,
+				Trigger:        trigger,
+				Provider:       ProviderName,
+				Namespace:      gr.Namespace,
+				Secrets:        secrets,
+				ServiceAccount: gr.GetServiceAccountName(),
+				Meta:           make(map[string]string),
+				Policy:         containerPlc,
 			})
 		}
 	}
@@ -224,9 +336,27 @@ func (p *Provider) TrackedImages() ([]*types.TrackedImage, error) {
 }
 
 func (p *Provider) startInternal() error {
+	deferredTicker := time.NewTicker(deferredCheckInterval)
+	defer deferredTicker.Stop()
+
+	gcTicker := time.NewTicker(gcInterval)
+	defer gcTicker.Stop()
+
 	for {
 		select {
+		case <-deferredTicker.C:
+			p.processDeferred()
+			p.processPaused()
+			p.processStaged()
+		case <-gcTicker.C:
+			p.collectGarbage()
+		case pe := <-p.debounced:
+			p.flushEvent(pe)
 		case event := <-p.events:
+			if p.eventDebounce > 0 {
+				p.debounceEvent(event)
+				continue
+			}
 			_, err := p.processEvent(event)
 			if err != nil {
 				log.WithFields(log.Fields{
@@ -247,7 +377,12 @@ func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResou
 	if err != nil {
 		return nil, err
 	}
+	return p.processPlans(event, plans)
+}
 
+// processPlans - runs update plans created for the event through approvals, windows and
+// the other checks and applies the remaining ones
+func (p *Provider) processPlans(event *types.Event, plans []*UpdatePlan) (updated []*k8s.GenericResource, err error) {
 	if len(plans) == 0 {
 		log.WithFields(log.Fields{
 			"image": event.Repository.Name,
@@ -256,40 +391,70 @@ func (p *Provider) processEvent(event *types.Event) (updated []*k8s.GenericResou
 		return
 	}
 
-	approvedPlans := p.checkForApprovals(event, plans)
+	for _, plan := range plans {
+		plan.Trigger = event.TriggerName
+	}
+
+	approvedPlans := p.checkForApprovals(event, p.checkForPaused(event, p.checkForOrdering(event, p.checkForVulnerabilities(event, p.checkForSignatures(event, p.checkForMinAge(event, p.checkForAbortedCanaries(event, p.checkForRolledBack(event, p.checkForDryRun(plans)))))))))
 
-	return p.updateDeployments(approvedPlans)
+	return p.updateDeployments(p.checkForCompleteGroups(approvedPlans, p.checkForDisruptionBudgets(event, p.checkForCanary(event, p.checkForWindows(event, approvedPlans)))))
+}
+
+// sendPreparingUpdate - notifies that the resource is about to be updated
+func (p *Provider) sendPreparingUpdate(plan *UpdatePlan) {
+	resource := plan.Resource
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "preparing to update resource",
+		Message:      fmt.Sprintf("Preparing to update %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationPreDeploymentUpdate,
+		Level:        types.LevelDebug,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+			"image":     strings.Join(resource.GetImages(), ", "),
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+		},
+	})
 }
 
 func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.GenericResource, err error) {
+	failedGroups := make(map[string]bool)
+	defer p.archiveGroups(plans, failedGroups)
+
+	for _, plan := range plans {
+		p.sendPreparingUpdate(plan)
+	}
+	hookErrors := p.runPreUpdateHooks(plans)
+
 	for _, plan := range plans {
 		resource := plan.Resource
 
 		annotations := resource.GetAnnotations()
 
-		notificationChannels := types.ParseEventNotificationChannels(annotations)
+		notificationChannels := p.notificationChannels(resource)
+		notificationWebhooks := p.notificationWebhooks(resource)
 
-		p.sender.Send(types.EventNotification{
-			ResourceKind: resource.Kind(),
-			Identifier:   resource.Identifier,
-			Name:         "preparing to update resource",
-			Message:      fmt.Sprintf("Preparing to update %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", ")),
-			CreatedAt:    time.Now(),
-			Type:         types.NotificationPreDeploymentUpdate,
-			Level:        types.LevelDebug,
-			Channels:     notificationChannels,
-			Metadata: map[string]string{
-				"provider":  p.GetName(),
-				"namespace": resource.GetNamespace(),
-				"name":      resource.GetName(),
-			},
-		})
-
-		var err error
+		err := hookErrors[plan]
+		if err != nil {
+			p.sendHookFailure(plan, err, fmt.Sprintf("%s %s/%s pre-update hook failed, update %s->%s skipped, error: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, err))
+			failedGroups[plan.ApprovalGroup] = true
+			continue
+		}
 
 		timestamp := time.Now().Format(time.RFC3339)
 		annotations["kubernetes.io/change-cause"] = fmt.Sprintf("keel automated update, version %s -> %s [%s]", plan.CurrentVersion, plan.NewVersion, timestamp)
 
+		if p.historyLimit > 0 {
+			p.recordHistory(plan, annotations)
+		}
+
 		resource.SetAnnotations(annotations)
 
 		err = p.implementer.Update(resource)
@@ -312,16 +477,40 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 				Type:         types.NotificationDeploymentUpdate,
 				Level:        types.LevelError,
 				Channels:     notificationChannels,
+				Webhooks:     notificationWebhooks,
 				Metadata: map[string]string{
 					"provider":  p.GetName(),
 					"namespace": resource.GetNamespace(),
 					"name":      resource.GetName(),
+					"image":     strings.Join(resource.GetImages(), ", "),
+					"previous":  plan.CurrentVersion,
+					"new":       plan.NewVersion,
 				},
 			})
 
+			failedGroups[plan.ApprovalGroup] = true
 			continue
 		}
 
+		err = p.applyUpdateStrategy(resource)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"kind":      resource.Kind(),
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: got error while applying resource update strategy")
+		}
+
+		if timeout, ok := getRolloutTimeout(resource); ok {
+			go p.monitorRollout(plan, timeout, true)
+		} else {
+			if p.rolloutNotificationTimeout > 0 {
+				go p.monitorRollout(plan, p.rolloutNotificationTimeout, false)
+			}
+			p.runPostUpdateHook(plan)
+		}
+
 		err = p.updateComplete(plan)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -340,6 +529,18 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			msg = fmt.Sprintf("Successfully updated %s %s/%s %s->%s (%s)", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, strings.Join(resource.GetImages(), ", "))
 		}
 
+		metadata := map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+			"image":     strings.Join(resource.GetImages(), ", "),
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+		}
+		if len(plan.ApprovedBy) > 0 {
+			metadata["approvedBy"] = strings.Join(plan.ApprovedBy, ", ")
+		}
+
 		err = p.sender.Send(types.EventNotification{
 			ResourceKind: resource.Kind(),
 			Identifier:   resource.Identifier,
@@ -349,11 +550,8 @@ func (p *Provider) updateDeployments(plans []*UpdatePlan) (updated []*k8s.Generi
 			Type:         types.NotificationDeploymentUpdate,
 			Level:        types.LevelSuccess,
 			Channels:     notificationChannels,
-			Metadata: map[string]string{
-				"provider":  p.GetName(),
-				"namespace": resource.GetNamespace(),
-				"name":      resource.GetName(),
-			},
+			Webhooks:     notificationWebhooks,
+			Metadata:     metadata,
 		})
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -405,16 +603,23 @@ func getDesiredImage(delta map[string]string, currentImage string) (string, erro
 func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, error) {
 	impacted := []*UpdatePlan{}
 
-	for _, resource := range p.cache.Values() {
+	ref, err := image.Parse(repo.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range p.cache.ValuesByRepository(ref.Repository()) {
 
 		labels := resource.GetLabels()
 		annotations := resource.GetAnnotations()
 
 		plc := policy.GetPolicyFromLabelsOrAnnotations(labels, annotations)
-		if plc.Type() == policy.PolicyTypeNone {
+		if plc.Type() == policy.PolicyTypeNone && !policy.HasContainerPolicies(labels, annotations) {
 			continue
 		}
 
+		previousImages := getUpdatableImages(resource)
+
 		updated, shouldUpdateDeployment, err := checkForUpdate(plc, repo, resource)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -426,7 +631,21 @@ func (p *Provider) createUpdatePlans(repo *types.Repository) ([]*UpdatePlan, err
 			continue
 		}
 
+		if shouldUpdateDeployment && isDigestPinned(labels, annotations) {
+			err = p.pinDigests(repo, updated.Resource)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"name":      resource.Name,
+					"kind":      resource.Kind(),
+					"namespace": resource.Namespace,
+				}).Error("provider.kubernetes: failed to pin image digest, skipping update")
+				continue
+			}
+		}
+
 		if shouldUpdateDeployment {
+			updated.PreviousImages = previousImages
 			impacted = append(impacted, updated)
 		}
 	}
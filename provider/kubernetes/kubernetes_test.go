@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -14,7 +15,9 @@ import (
 	"github.com/keel-hq/keel/types"
 
 	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
@@ -38,6 +41,11 @@ func (p *fakeProvider) List() []string {
 func (p *fakeProvider) Stop() {
 	return
 }
+func (p *fakeProvider) Pause()  {}
+func (p *fakeProvider) Resume() {}
+func (p *fakeProvider) Paused() bool {
+	return false
+}
 func (p *fakeProvider) GetName() string {
 	return "fp"
 }
@@ -46,6 +54,7 @@ type fakeImplementer struct {
 	namespaces     *v1.NamespaceList
 	deployment     *apps_v1.Deployment
 	deploymentList *apps_v1.DeploymentList
+	daemonSetList  *apps_v1.DaemonSetList
 
 	podList     *v1.PodList
 	deletedPods []*v1.Pod
@@ -54,6 +63,18 @@ type fakeImplementer struct {
 	updated *k8s.GenericResource
 
 	availableSecret *v1.Secret
+
+	// canary deployments, createDeploymentErr is returned by CreateDeployment
+	createdDeployments  []*apps_v1.Deployment
+	createDeploymentErr error
+	deletedDeployments  []string
+
+	pdbList *policy_v1beta1.PodDisruptionBudgetList
+
+	// createdJobs - hook jobs, jobConditions are set as status of returned jobs
+	createdJobs   []*batch_v1.Job
+	jobConditions []batch_v1.JobCondition
+	deletedJobs   []string
 }
 
 func (i *fakeImplementer) Namespaces() (*v1.NamespaceList, error) {
@@ -68,6 +89,23 @@ func (i *fakeImplementer) Deployments(namespace string) (*apps_v1.DeploymentList
 	return i.deploymentList, nil
 }
 
+func (i *fakeImplementer) CreateDeployment(deployment *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	if i.createDeploymentErr != nil {
+		return nil, i.createDeploymentErr
+	}
+	i.createdDeployments = append(i.createdDeployments, deployment)
+	return deployment, nil
+}
+
+func (i *fakeImplementer) DeleteDeployment(namespace, name string) error {
+	i.deletedDeployments = append(i.deletedDeployments, namespace+"/"+name)
+	return nil
+}
+
+func (i *fakeImplementer) DaemonSets(namespace string) (*apps_v1.DaemonSetList, error) {
+	return i.daemonSetList, nil
+}
+
 func (i *fakeImplementer) Update(obj *k8s.GenericResource) error {
 	i.updated = obj
 	return nil
@@ -77,6 +115,10 @@ func (i *fakeImplementer) Secret(namespace, name string) (*v1.Secret, error) {
 	return i.availableSecret, nil
 }
 
+func (i *fakeImplementer) ServiceAccount(namespace, name string) (*v1.ServiceAccount, error) {
+	return nil, fmt.Errorf("service account %s/%s not found", namespace, name)
+}
+
 func (i *fakeImplementer) Pods(namespace, labelSelector string) (*v1.PodList, error) {
 	return i.podList, nil
 }
@@ -94,6 +136,34 @@ func (i *fakeImplementer) DeletePod(namespace, name string, opts *meta_v1.Delete
 	return nil
 }
 
+func (i *fakeImplementer) CreateJob(job *batch_v1.Job) (*batch_v1.Job, error) {
+	i.createdJobs = append(i.createdJobs, job)
+	return job, nil
+}
+
+func (i *fakeImplementer) Job(namespace, name string) (*batch_v1.Job, error) {
+	for _, job := range i.createdJobs {
+		if job.Namespace == namespace && job.Name == name {
+			current := job.DeepCopy()
+			current.Status.Conditions = i.jobConditions
+			return current, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s/%s not found", namespace, name)
+}
+
+func (i *fakeImplementer) DeleteJob(namespace, name string) error {
+	i.deletedJobs = append(i.deletedJobs, namespace+"/"+name)
+	return nil
+}
+
+func (i *fakeImplementer) PodDisruptionBudgets(namespace string) (*policy_v1beta1.PodDisruptionBudgetList, error) {
+	if i.pdbList == nil {
+		return &policy_v1beta1.PodDisruptionBudgetList{}, nil
+	}
+	return i.pdbList, nil
+}
+
 func (i *fakeImplementer) ConfigMaps(namespace string) core_v1.ConfigMapInterface {
 	return nil
 }
@@ -199,6 +269,31 @@ func MustParseGRS(objs []*apps_v1.Deployment) []*k8s.GenericResource {
 	return grs
 }
 
+// newTestDeployment - deployment with "all" policy whose pods are selected by
+// app=<name> label, Generation is 1. Containers are copied so tests can share them
+func newTestDeployment(namespace, name string, replicas int32, annotations map[string]string, status apps_v1.DeploymentStatus, containers ...v1.Container) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Generation:  1,
+			Labels:      map[string]string{types.KeelPolicyLabel: "all", "app": name},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: int32Ptr(replicas),
+			Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec: v1.PodSpec{
+					Containers: append([]v1.Container(nil), containers...),
+				},
+			},
+		},
+		Status: status,
+	}
+}
+
 func TestGetImpacted(t *testing.T) {
 	fp := &fakeImplementer{}
 	fp.namespaces = &v1.NamespaceList{
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getMinAge - gets minimum image age from resource annotations or labels, 0 if not set
+func getMinAge(labels map[string]string, annotations map[string]string) (time.Duration, error) {
+	val, ok := annotations[types.KeelMinAgeAnnotation]
+	if !ok {
+		val, ok = labels[types.KeelMinAgeAnnotation]
+	}
+	if !ok || val == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(val)
+}
+
+// checkForMinAge - filters out plans for images that were created less than keel.sh/min-age
+// ago, these updates are deferred until images are old enough. Updates are deferred as well
+// if creation time can't be retrieved from the registry
+func (p *Provider) checkForMinAge(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+	now := timeutil.Now()
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		minAge, err := getMinAge(resource.GetLabels(), resource.GetAnnotations())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse minimum image age, skipping update")
+			continue
+		}
+		if minAge <= 0 {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		ref, err := image.Parse(event.Repository.Name + ":" + plan.NewVersion)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     event.Repository.Name,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse image, skipping update")
+			continue
+		}
+
+		created, err := p.registryClient.Created(p.registryOpts(ref, resource))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     ref.Remote(),
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: failed to get image creation time, deferring update")
+			p.deferred.add(resource.Identifier, event)
+			continue
+		}
+
+		if now.Sub(created) >= minAge {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if !p.deferred.add(resource.Identifier, event) {
+			continue
+		}
+
+		until := created.Add(minAge)
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     ref.Remote(),
+			"created":   created,
+			"until":     until,
+		}).Info("provider.kubernetes: image is younger than minimum age, deferring update")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "deferred update",
+			Message:      fmt.Sprintf("Update of %s %s/%s %s->%s deferred until image is %s old at %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, minAge, until.Format(time.RFC3339)),
+			CreatedAt:    now,
+			Type:         types.NotificationPreDeploymentUpdate,
+			Level:        types.LevelInfo,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+			},
+		})
+	}
+
+	return allowedPlans
+}
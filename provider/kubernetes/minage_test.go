@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProcessEventMinAge(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	timeutil.Now = func() time.Time { return now }
+	defer func() { timeutil.Now = time.Now }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dep-1",
+			Namespace: "xxxx",
+			Labels:    map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{
+				types.KeelMinAgeAnnotation: "24h",
+			},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	registryClient := &fakeRegistryClient{created: now.Add(-time.Hour)}
+	provider.registryClient = registryClient
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated with an image younger than minimum age")
+	}
+	if sender.sentEvent.Name != "deferred update" {
+		t.Errorf("expected deferred update notification, got: %s", sender.sentEvent.Name)
+	}
+	if registryClient.opts.Name != "v2-namespace/hello-world" || registryClient.opts.Tag != "1.1.2" {
+		t.Errorf("unexpected registry opts: %+v", registryClient.opts)
+	}
+
+	now = now.Add(22 * time.Hour)
+	provider.processDeferred()
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated with an image younger than minimum age")
+	}
+
+	now = now.Add(2 * time.Hour)
+	provider.processDeferred()
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated once image is old enough")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+}
@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getRolloutWeight - gets rollout weight from resource annotations or labels, resources
+// without weight have weight 0 and designated canaries always go first
+func getRolloutWeight(labels map[string]string, annotations map[string]string) (int, error) {
+	if isDesignatedCanary(annotations) {
+		return math.MinInt32, nil
+	}
+	val, ok := annotations[types.KeelRolloutWeightAnnotation]
+	if !ok {
+		val, ok = labels[types.KeelRolloutWeightAnnotation]
+	}
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimSpace(val))
+}
+
+// runsVersion - checks whether any of updatable resource containers already runs the event version
+func runsVersion(resource *k8s.GenericResource, eventRef *image.Reference, tag string) bool {
+	for _, img := range getUpdatableImages(resource) {
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		if image.RepositoryMatches(ref, eventRef) && ref.Tag() == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingRollouts - resources with weight lower than maxWeight that already run the
+// new version but their rollout hasn't finished yet, designated canaries whose rollout
+// failed are returned separately
+func (p *Provider) pendingRollouts(repo *types.Repository, maxWeight int) (pending []*k8s.GenericResource, failedCanary *k8s.GenericResource) {
+	eventRef, err := image.Parse(repo.Name)
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, gr := range p.cache.ValuesByRepository(repo.Name) {
+		weight, err := getRolloutWeight(gr.GetLabels(), gr.GetAnnotations())
+		if err != nil || weight >= maxWeight {
+			continue
+		}
+		if !runsVersion(gr, eventRef, repo.Tag) {
+			continue
+		}
+		done, failed := getRolloutStatus(gr)
+		if failed && isDesignatedCanary(gr.GetAnnotations()) {
+			return pending, gr
+		}
+		if !done {
+			pending = append(pending, gr)
+		}
+	}
+	return pending, nil
+}
+
+// checkForOrdering - when an image is used by resources with different rollout weights,
+// only the lowest weight resources are updated, the rest is staged and updated once
+// rollouts of lower weight resources become healthy
+func (p *Provider) checkForOrdering(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	weights := make(map[*UpdatePlan]int, len(plans))
+	lowest := 0
+	for _, plan := range plans {
+		resource := plan.Resource
+		weight, err := getRolloutWeight(resource.GetLabels(), resource.GetAnnotations())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse rollout weight, skipping update")
+			continue
+		}
+		if len(weights) == 0 || weight < lowest {
+			lowest = weight
+		}
+		weights[plan] = weight
+	}
+
+	// lower weight resources that were updated earlier have to finish their rollouts first
+	pending, failedCanary := p.pendingRollouts(&event.Repository, lowest)
+	if failedCanary != nil {
+		p.abortCanary(abortedEventKey(&event.Repository), failedCanary, event.Repository.Tag, "failed")
+		for _, plan := range plans {
+			p.staged.remove(plan.Resource.Identifier)
+		}
+		return allowedPlans
+	}
+
+	for _, plan := range plans {
+		weight, ok := weights[plan]
+		if !ok {
+			continue
+		}
+		resource := plan.Resource
+
+		if weight == lowest && len(pending) == 0 {
+			p.staged.remove(resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if !p.staged.add(resource.Identifier, event) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"weight":    weight,
+		}).Info("provider.kubernetes: waiting for lower weight resources, staging update")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "staged update",
+			Message:      fmt.Sprintf("Update of %s %s/%s %s->%s (weight %d) is waiting for lower weight resources to be updated", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, weight),
+			CreatedAt:    timeutil.Now(),
+			Type:         types.NotificationPreDeploymentUpdate,
+			Level:        types.LevelInfo,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+			},
+		})
+	}
+
+	return allowedPlans
+}
+
+// processStaged - re-processes staged events, next weight is updated once
+// rollouts of the previous one are healthy
+func (p *Provider) processStaged() {
+	for _, event := range p.staged.next() {
+		_, err := p.processEvent(event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": event.Repository.Name,
+				"tag":   event.Repository.Tag,
+			}).Error("provider.kubernetes: failed to process staged event")
+		}
+	}
+	p.staged.prune()
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestProcessEventRolloutWeight(t *testing.T) {
+	fp := &fakeImplementer{}
+	healthy := apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("staging", "web", 1, map[string]string{types.KeelRolloutWeightAnnotation: "1"}, healthy, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"})))
+	grc.Add(MustParseGR(newTestDeployment("prod", "web", 1, map[string]string{types.KeelRolloutWeightAnnotation: "2"}, healthy, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil || fp.updated.Namespace != "staging" {
+		t.Fatalf("expected only staging to be updated, got: %v", fp.updated)
+	}
+	if _, ok := provider.staged.entries["deployment/prod/web"]; !ok {
+		t.Errorf("expected prod update to be staged")
+	}
+
+	// staging rollout in progress
+	grc.Add(MustParseGR(newTestDeployment("staging", "web", 1, map[string]string{types.KeelRolloutWeightAnnotation: "1"}, apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 1}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.2"})))
+	fp.updated = nil
+
+	provider.processStaged()
+	if fp.updated != nil {
+		t.Fatalf("prod shouldn't be updated before staging rollout is healthy")
+	}
+
+	// staging rollout finished
+	grc.Add(MustParseGR(newTestDeployment("staging", "web", 1, map[string]string{types.KeelRolloutWeightAnnotation: "1"}, healthy, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.2"})))
+
+	provider.processStaged()
+	if fp.updated == nil || fp.updated.Namespace != "prod" {
+		t.Fatalf("expected prod to be updated, got: %v", fp.updated)
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+}
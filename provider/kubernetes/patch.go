@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/keel-hq/keel/internal/k8s"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1beta1 "k8s.io/api/batch/v1beta1"
+)
+
+// available update methods
+const (
+	// UpdateMethodUpdate - sends whole resource object to the API server
+	UpdateMethodUpdate = "update"
+	// UpdateMethodPatch - sends strategic merge patch that only changes container
+	// images and annotations, doesn't race with replica count changes (HPA) or other controllers
+	UpdateMethodPatch = "patch"
+)
+
+type patchContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type patchPodSpec struct {
+	Containers     []patchContainer `json:"containers"`
+	InitContainers []patchContainer `json:"initContainers,omitempty"`
+}
+
+type patchMeta struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type patchPodTemplate struct {
+	Metadata patchMeta    `json:"metadata"`
+	Spec     patchPodSpec `json:"spec"`
+}
+
+type patchTemplateSpec struct {
+	Template patchPodTemplate `json:"template"`
+}
+
+type patchCronJobSpec struct {
+	JobTemplate struct {
+		Spec patchTemplateSpec `json:"spec"`
+	} `json:"jobTemplate"`
+}
+
+type patch struct {
+	Metadata patchMeta   `json:"metadata"`
+	Spec     interface{} `json:"spec"`
+}
+
+// getStrategicMergePatch - creates a patch with resource annotations, pod template annotations
+// and container images. Containers are merged by name so every container has to be named.
+func getStrategicMergePatch(obj *k8s.GenericResource) ([]byte, error) {
+	template := patchPodTemplate{
+		Metadata: patchMeta{Annotations: obj.GetSpecAnnotations()},
+	}
+	for _, c := range obj.Containers() {
+		if c.Name == "" {
+			return nil, fmt.Errorf("container with image %s has no name", c.Image)
+		}
+		template.Spec.Containers = append(template.Spec.Containers, patchContainer{Name: c.Name, Image: c.Image})
+	}
+	for _, c := range obj.InitContainers() {
+		template.Spec.InitContainers = append(template.Spec.InitContainers, patchContainer{Name: c.Name, Image: c.Image})
+	}
+
+	p := patch{
+		Metadata: patchMeta{Annotations: obj.GetResourceAnnotations()},
+	}
+
+	switch obj.GetResource().(type) {
+	case *apps_v1.Deployment, *apps_v1.StatefulSet, *apps_v1.DaemonSet:
+		p.Spec = patchTemplateSpec{Template: template}
+	case *v1beta1.CronJob:
+		spec := patchCronJobSpec{}
+		spec.JobTemplate.Spec.Template = template
+		p.Spec = spec
+	default:
+		return nil, fmt.Errorf("unsupported object type")
+	}
+
+	return json.Marshal(p)
+}
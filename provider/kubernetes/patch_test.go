@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetStrategicMergePatch(t *testing.T) {
+	replicas := int32(5)
+	dep := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{"kubernetes.io/change-cause": "keel automated update"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Annotations: map[string]string{types.KeelUpdateTimeAnnotation: "now"},
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:  "app",
+							Image: "gcr.io/v2-namespace/hello-world:1.1.2",
+							Ports: []v1.ContainerPort{{ContainerPort: 80}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := getStrategicMergePatch(MustParseGR(dep))
+	if err != nil {
+		t.Fatalf("failed to get patch: %s", err)
+	}
+
+	var got map[string]interface{}
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("failed to decode patch: %s", err)
+	}
+
+	var want map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"metadata": {"annotations": {"kubernetes.io/change-cause": "keel automated update"}},
+		"spec": {
+			"template": {
+				"metadata": {"annotations": {"keel.sh/update-time": "now"}},
+				"spec": {"containers": [{"name": "app", "image": "gcr.io/v2-namespace/hello-world:1.1.2"}]}
+			}
+		}
+	}`), &want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected patch: %s", string(data))
+	}
+}
+
+func TestGetStrategicMergePatchUnnamedContainer(t *testing.T) {
+	dep := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "dep-1", Namespace: "xxxx"},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Image: "gcr.io/v2-namespace/hello-world:1.1.2"},
+					},
+				},
+			},
+		},
+	}
+
+	_, err := getStrategicMergePatch(MustParseGR(dep))
+	if err == nil {
+		t.Errorf("expected error for unnamed container")
+	}
+}
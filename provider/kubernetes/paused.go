@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isPaused - checks whether resource updates are paused through annotations or labels
+func isPaused(labels map[string]string, annotations map[string]string) bool {
+	val, ok := annotations[types.KeelPausedAnnotation]
+	if !ok {
+		val = labels[types.KeelPausedAnnotation]
+	}
+	return strings.ToLower(strings.TrimSpace(val)) == "true"
+}
+
+// checkForPaused - filters out plans for paused resources, events for them are
+// recorded and replayed once resource is no longer paused
+func (p *Provider) checkForPaused(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		if !isPaused(resource.GetLabels(), resource.GetAnnotations()) {
+			p.paused.remove(resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if !p.paused.add(resource.Identifier, event) {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Info("provider.kubernetes: resource is paused, update recorded")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "paused update",
+			Message:      fmt.Sprintf("Update of %s %s/%s %s->%s skipped, resource is paused", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion),
+			CreatedAt:    timeutil.Now(),
+			Type:         types.NotificationPreDeploymentUpdate,
+			Level:        types.LevelInfo,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+			},
+		})
+	}
+
+	return allowedPlans
+}
+
+// processPaused - replays events recorded for paused resources, updates are
+// applied for resources that are no longer paused
+func (p *Provider) processPaused() {
+	for _, event := range p.paused.next() {
+		_, err := p.processEvent(event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": event.Repository.Name,
+				"tag":   event.Repository.Tag,
+			}).Error("provider.kubernetes: failed to process paused event")
+		}
+	}
+	p.paused.prune()
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProcessEventPaused(t *testing.T) {
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	deployment := func(annotations map[string]string) *apps_v1.Deployment {
+		return &apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "dep-1",
+				Namespace:   "xxxx",
+				Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+				Annotations: annotations,
+			},
+			Spec: apps_v1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(deployment(map[string]string{types.KeelPausedAnnotation: "true"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated != nil {
+		t.Fatalf("paused resource shouldn't be updated")
+	}
+	if sender.sentEvent.Name != "paused update" {
+		t.Errorf("expected paused update notification, got: %s", sender.sentEvent.Name)
+	}
+
+	// still paused
+	provider.processPaused()
+	if fp.updated != nil {
+		t.Fatalf("paused resource shouldn't be updated")
+	}
+
+	// resuming
+	grc.Add(MustParseGR(deployment(map[string]string{})))
+	provider.processPaused()
+
+	if fp.updated == nil {
+		t.Fatalf("skipped update should be applied once resource is resumed")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+
+	if len(provider.paused.next()) != 0 {
+		t.Errorf("expected no recorded updates after resume")
+	}
+}
+
+func TestIsPaused(t *testing.T) {
+	if !isPaused(nil, map[string]string{types.KeelPausedAnnotation: "true"}) {
+		t.Errorf("expected resource to be paused by annotation")
+	}
+	if !isPaused(map[string]string{types.KeelPausedAnnotation: "True"}, nil) {
+		t.Errorf("expected resource to be paused by label")
+	}
+	if isPaused(map[string]string{types.KeelPausedAnnotation: "true"}, map[string]string{types.KeelPausedAnnotation: "false"}) {
+		t.Errorf("expected annotation to take precedence over label")
+	}
+	if isPaused(nil, nil) {
+		t.Errorf("expected resource not to be paused")
+	}
+}
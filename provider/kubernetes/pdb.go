@@ -0,0 +1,206 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// available disruption budget policies, see keel.sh/disruptionBudget annotation
+const (
+	disruptionBudgetWarn   = "warn"
+	disruptionBudgetDefer  = "defer"
+	disruptionBudgetIgnore = "ignore"
+)
+
+// getDisruptionBudgetPolicy - gets disruption budget policy from annotations, defaults to warn
+func getDisruptionBudgetPolicy(annotations map[string]string) string {
+	switch policy := strings.ToLower(strings.TrimSpace(annotations[types.KeelDisruptionBudgetAnnotation])); policy {
+	case disruptionBudgetDefer, disruptionBudgetIgnore:
+		return policy
+	}
+	return disruptionBudgetWarn
+}
+
+// rolloutDisruption - returns replica count, pod template labels and how many pods
+// the rollout takes down at once, only Deployments and StatefulSets are checked
+func rolloutDisruption(resource *k8s.GenericResource) (replicas int, podLabels map[string]string, unavailable int, ok bool) {
+	switch obj := resource.GetResource().(type) {
+	case *apps_v1.Deployment:
+		replicas = 1
+		if obj.Spec.Replicas != nil {
+			replicas = int(*obj.Spec.Replicas)
+		}
+		if obj.Spec.Strategy.Type == apps_v1.RecreateDeploymentStrategyType {
+			return replicas, obj.Spec.Template.Labels, replicas, true
+		}
+		maxUnavailable := intstr.FromString("25%")
+		if obj.Spec.Strategy.RollingUpdate != nil && obj.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
+			maxUnavailable = *obj.Spec.Strategy.RollingUpdate.MaxUnavailable
+		}
+		n, err := intstr.GetValueFromIntOrPercent(&maxUnavailable, replicas, false)
+		if err != nil {
+			return 0, nil, 0, false
+		}
+		return replicas, obj.Spec.Template.Labels, n, true
+	case *apps_v1.StatefulSet:
+		if obj.Spec.UpdateStrategy.Type == apps_v1.OnDeleteStatefulSetStrategyType {
+			return 0, nil, 0, false
+		}
+		replicas = 1
+		if obj.Spec.Replicas != nil {
+			replicas = int(*obj.Spec.Replicas)
+		}
+		// pods are replaced one by one
+		return replicas, obj.Spec.Template.Labels, 1, true
+	}
+	return 0, nil, 0, false
+}
+
+// allowedDisruptions - how many pods the budget allows to be disrupted, status is used
+// as well once the budget controller has observed it
+func allowedDisruptions(pdb *policy_v1beta1.PodDisruptionBudget, replicas int) (int, error) {
+	allowed := replicas
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		maxUnavailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MaxUnavailable, replicas, true)
+		if err != nil {
+			return 0, err
+		}
+		allowed = maxUnavailable
+	case pdb.Spec.MinAvailable != nil:
+		minAvailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MinAvailable, replicas, true)
+		if err != nil {
+			return 0, err
+		}
+		allowed = replicas - minAvailable
+	}
+	if pdb.Status.ObservedGeneration > 0 && int(pdb.Status.PodDisruptionsAllowed) < allowed {
+		allowed = int(pdb.Status.PodDisruptionsAllowed)
+	}
+	if allowed < 0 {
+		allowed = 0
+	}
+	return allowed, nil
+}
+
+// checkDisruptionBudgets - returns description of the budget violation if the rollout
+// needs to take down more pods than a matching budget allows or the budget doesn't
+// allow any disruptions at all
+func checkDisruptionBudgets(pdbs []policy_v1beta1.PodDisruptionBudget, resource *k8s.GenericResource) (string, bool) {
+	replicas, podLabels, unavailable, ok := rolloutDisruption(resource)
+	if !ok {
+		return "", false
+	}
+
+	for idx := range pdbs {
+		pdb := &pdbs[idx]
+		if pdb.Spec.Selector == nil || (len(pdb.Spec.Selector.MatchLabels) == 0 && len(pdb.Spec.Selector.MatchExpressions) == 0) {
+			continue
+		}
+		selector, err := meta_v1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+
+		allowed, err := allowedDisruptions(pdb, replicas)
+		if err != nil {
+			continue
+		}
+		if allowed == 0 || unavailable > allowed {
+			return fmt.Sprintf("PodDisruptionBudget %s allows %d disruption(s), rollout of %d replica(s) takes down %d pod(s) at once", pdb.Name, allowed, replicas, unavailable), true
+		}
+	}
+	return "", false
+}
+
+// checkForDisruptionBudgets - warns about or defers updates that would violate
+// PodDisruptionBudgets of the resource, deferred updates are retried until the budget allows them
+func (p *Provider) checkForDisruptionBudgets(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+	budgets := make(map[string][]policy_v1beta1.PodDisruptionBudget)
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		policy := getDisruptionBudgetPolicy(resource.GetAnnotations())
+		if policy == disruptionBudgetIgnore {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		pdbs, ok := budgets[resource.Namespace]
+		if !ok {
+			list, err := p.implementer.PodDisruptionBudgets(resource.Namespace)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":     err,
+					"namespace": resource.Namespace,
+				}).Warn("provider.kubernetes: failed to list pod disruption budgets")
+			} else {
+				pdbs = list.Items
+			}
+			budgets[resource.Namespace] = pdbs
+		}
+
+		violation, violated := checkDisruptionBudgets(pdbs, resource)
+		if !violated {
+			if policy == disruptionBudgetDefer {
+				p.staged.remove(resource.Identifier)
+			}
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if policy == disruptionBudgetWarn {
+			allowedPlans = append(allowedPlans, plan)
+			p.sendDisruptionBudgetNotification(plan, types.LevelWarn, fmt.Sprintf("Update of %s %s/%s %s->%s may be blocked: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, violation))
+			continue
+		}
+
+		if !p.staged.add(resource.Identifier, event) {
+			continue
+		}
+		p.sendDisruptionBudgetNotification(plan, types.LevelInfo, fmt.Sprintf("Update of %s %s/%s %s->%s deferred: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, violation))
+	}
+
+	return allowedPlans
+}
+
+func (p *Provider) sendDisruptionBudgetNotification(plan *UpdatePlan, level types.Level, msg string) {
+	resource := plan.Resource
+
+	log.WithFields(log.Fields{
+		"name":      resource.Name,
+		"kind":      resource.Kind(),
+		"namespace": resource.Namespace,
+		"policy":    getDisruptionBudgetPolicy(resource.GetAnnotations()),
+	}).Warn("provider.kubernetes: update would violate pod disruption budget")
+
+	p.sender.Send(types.EventNotification{
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Name:         "disruption budget",
+		Message:      msg,
+		CreatedAt:    timeutil.Now(),
+		Type:         types.NotificationPreDeploymentUpdate,
+		Level:        level,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
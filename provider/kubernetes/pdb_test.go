@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newPDB(maxUnavailable, minAvailable *intstr.IntOrString) policy_v1beta1.PodDisruptionBudget {
+	return policy_v1beta1.PodDisruptionBudget{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web-pdb", Namespace: "xxxx"},
+		Spec: policy_v1beta1.PodDisruptionBudgetSpec{
+			Selector:       &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MaxUnavailable: maxUnavailable,
+			MinAvailable:   minAvailable,
+		},
+	}
+}
+
+func TestCheckDisruptionBudgets(t *testing.T) {
+	zero := intstr.FromInt(0)
+	one := intstr.FromInt(1)
+	half := intstr.FromString("50%")
+
+	tests := []struct {
+		name     string
+		pdb      policy_v1beta1.PodDisruptionBudget
+		replicas int32
+		strategy apps_v1.DeploymentStrategyType
+		want     bool
+	}{
+		{
+			name:     "single replica, maxUnavailable 0",
+			pdb:      newPDB(&zero, nil),
+			replicas: 1,
+			strategy: apps_v1.RollingUpdateDeploymentStrategyType,
+			want:     true,
+		},
+		{
+			name:     "minAvailable equals replicas",
+			pdb:      newPDB(nil, &one),
+			replicas: 1,
+			strategy: apps_v1.RollingUpdateDeploymentStrategyType,
+			want:     true,
+		},
+		{
+			name:     "rolling update within budget",
+			pdb:      newPDB(&one, nil),
+			replicas: 4,
+			strategy: apps_v1.RollingUpdateDeploymentStrategyType,
+			want:     false,
+		},
+		{
+			name:     "recreate takes down all pods",
+			pdb:      newPDB(nil, &half),
+			replicas: 4,
+			strategy: apps_v1.RecreateDeploymentStrategyType,
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newTestDeployment("xxxx", "web", tt.replicas, nil, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"})
+			deployment.Spec.Strategy.Type = tt.strategy
+			_, got := checkDisruptionBudgets([]policy_v1beta1.PodDisruptionBudget{tt.pdb}, MustParseGR(deployment))
+			if got != tt.want {
+				t.Errorf("checkDisruptionBudgets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	other := newPDB(&zero, nil)
+	other.Spec.Selector.MatchLabels = map[string]string{"app": "api"}
+	if _, got := checkDisruptionBudgets([]policy_v1beta1.PodDisruptionBudget{other}, MustParseGR(newTestDeployment("xxxx", "web", 1, nil, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"}))); got {
+		t.Errorf("budget selecting other pods shouldn't be violated")
+	}
+}
+
+func TestProcessEventDisruptionBudget(t *testing.T) {
+	zero := intstr.FromInt(0)
+	event := &types.Event{Repository: types.Repository{Name: "gcr.io/v2-namespace/hello-world", Tag: "1.1.2"}}
+
+	for _, policy := range []string{"", "defer"} {
+		fp := &fakeImplementer{
+			pdbList: &policy_v1beta1.PodDisruptionBudgetList{Items: []policy_v1beta1.PodDisruptionBudget{newPDB(&zero, nil)}},
+		}
+		grc := &k8s.GenericResourceCache{}
+		grc.Add(MustParseGR(newTestDeployment("xxxx", "web", 1, map[string]string{types.KeelDisruptionBudgetAnnotation: policy}, apps_v1.DeploymentStatus{}, v1.Container{Image: "gcr.io/v2-namespace/hello-world:1.1.1"})))
+
+		approver, teardown := approver()
+		provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+		if err != nil {
+			t.Fatalf("failed to get provider: %s", err)
+		}
+
+		_, err = provider.processEvent(event)
+		if err != nil {
+			t.Fatalf("got error while processing event: %s", err)
+		}
+
+		switch policy {
+		case "":
+			if fp.updated == nil {
+				t.Errorf("expected update to be applied with a warning")
+			}
+		case "defer":
+			if fp.updated != nil {
+				t.Fatalf("expected update to be deferred")
+			}
+
+			// budget relaxed
+			fp.pdbList = &policy_v1beta1.PodDisruptionBudgetList{}
+			provider.processStaged()
+			if fp.updated == nil {
+				t.Errorf("expected deferred update to be applied once budget allows it")
+			}
+		}
+		teardown()
+	}
+}
@@ -0,0 +1,451 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rolloutCheckInterval - how often resource status is checked while monitoring a rollout
+var rolloutCheckInterval = 5 * time.Second
+
+// SetRolloutNotifications - rollout outcome of updated resources is sent once it converges,
+// fails or the timeout passes. Resources with keel.sh/rollout-timeout are always monitored
+func (p *Provider) SetRolloutNotifications(timeout time.Duration) {
+	p.rolloutNotificationTimeout = timeout
+}
+
+// getRolloutTimeout - gets rollout monitoring timeout from resource annotations,
+// monitoring is disabled if annotation is not set
+func getRolloutTimeout(resource *k8s.GenericResource) (time.Duration, bool) {
+	timeoutStr, ok := resource.GetAnnotations()[types.KeelRolloutTimeoutAnnotation]
+	if !ok {
+		return 0, false
+	}
+
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"timeout":   timeoutStr,
+			"name":      resource.Name,
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: failed to parse rollout timeout, rollout will not be monitored")
+		return 0, false
+	}
+
+	return timeout, true
+}
+
+// getRolloutStatus - checks whether resource rollout has converged or failed. CronJobs
+// don't have a rollout so they are always considered done.
+func getRolloutStatus(resource *k8s.GenericResource) (done bool, failed bool) {
+	switch obj := resource.GetResource().(type) {
+	case *apps_v1.Deployment:
+		if obj.Status.ObservedGeneration < obj.Generation {
+			return false, false
+		}
+		for _, c := range obj.Status.Conditions {
+			if c.Type == apps_v1.DeploymentProgressing && c.Status == v1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+				return false, true
+			}
+		}
+		replicas := int32(1)
+		if obj.Spec.Replicas != nil {
+			replicas = *obj.Spec.Replicas
+		}
+		return obj.Status.UpdatedReplicas == replicas &&
+			obj.Status.Replicas == replicas &&
+			obj.Status.AvailableReplicas == replicas, false
+	case *apps_v1.StatefulSet:
+		if obj.Status.ObservedGeneration < obj.Generation {
+			return false, false
+		}
+		replicas := int32(1)
+		if obj.Spec.Replicas != nil {
+			replicas = *obj.Spec.Replicas
+		}
+		return obj.Status.UpdatedReplicas == replicas && obj.Status.ReadyReplicas == replicas, false
+	case *apps_v1.DaemonSet:
+		if obj.Status.ObservedGeneration < obj.Generation {
+			return false, false
+		}
+		return obj.Status.UpdatedNumberScheduled == obj.Status.DesiredNumberScheduled &&
+			obj.Status.NumberAvailable == obj.Status.DesiredNumberScheduled, false
+	case *unstructured.Unstructured:
+		switch resource.Kind() {
+		case "knativeservice":
+			return getKnativeServiceStatus(obj)
+		case "job":
+			return getJobStatus(obj)
+		}
+		return getArgoRolloutStatus(obj)
+	}
+	return true, false
+}
+
+// getArgoRolloutStatus - Argo Rollout is done once all replicas are updated and
+// available, aborted or degraded rollouts are considered failed
+func getArgoRolloutStatus(obj *unstructured.Unstructured) (done bool, failed bool) {
+	if aborted, _, _ := unstructured.NestedBool(obj.Object, "status", "abort"); aborted {
+		return false, true
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase == "Degraded" {
+		return false, true
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+	return updated == replicas && available == replicas, false
+}
+
+// getKnativeServiceStatus - Knative Service is done once the latest generation
+// is observed and Ready condition is true, false Ready condition means failure
+func getKnativeServiceStatus(obj *unstructured.Unstructured) (done bool, failed bool) {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observed < obj.GetGeneration() {
+		return false, false
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range conditions {
+		c, ok := item.(map[string]interface{})
+		if !ok || c["type"] != "Ready" {
+			continue
+		}
+		switch c["status"] {
+		case string(v1.ConditionTrue):
+			return true, false
+		case string(v1.ConditionFalse):
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// getCachedResource - gets latest version of the resource from the cache
+func (p *Provider) getCachedResource(resource *k8s.GenericResource) (*k8s.GenericResource, bool) {
+	for _, img := range resource.GetImages() {
+		ref, err := image.Parse(img)
+		if err != nil {
+			continue
+		}
+		for _, gr := range p.cache.ValuesByRepository(ref.Repository()) {
+			if gr.Identifier == resource.Identifier {
+				return gr, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// monitorRollout - waits for updated resource to converge and sends the rollout outcome,
+// rolls back to previous images if rollout fails or doesn't finish before timeout when
+// rollback is set. Monitoring stops once resource images are changed again by a newer
+// update or manually, such changes are never rolled back
+func (p *Provider) monitorRollout(plan *UpdatePlan, timeout time.Duration, rollback bool) {
+	ticker := time.NewTicker(rolloutCheckInterval)
+	defer ticker.Stop()
+
+	started := time.Now()
+	deadline := time.After(timeout)
+	expected := strings.Join(getUpdatableImages(plan.Resource), ",")
+	previous := strings.Join(plan.PreviousImages, ",")
+
+	// last seen state of the updated resource
+	current := plan.Resource
+
+	// superseded - resource images are neither the updated nor the previous ones
+	superseded := func(images string) bool {
+		if images == expected || images == previous || previous == "" {
+			return false
+		}
+		log.WithFields(log.Fields{
+			"name":      plan.Resource.Name,
+			"kind":      plan.Resource.Kind(),
+			"namespace": plan.Resource.Namespace,
+			"expected":  expected,
+			"images":    images,
+		}).Info("provider.kubernetes: resource images changed since the update, stopping rollout monitoring")
+		return true
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-deadline:
+			gr, ok := p.getCachedResource(plan.Resource)
+			images := ""
+			if ok {
+				images = strings.Join(getUpdatableImages(gr), ",")
+				if superseded(images) {
+					return
+				}
+			}
+			reason := fmt.Sprintf("rollout didn't finish in %s", timeout)
+			p.sendRolloutOutcome(plan, current, time.Since(started), reason)
+			if rollback {
+				// only the update we were monitoring is rolled back
+				if images != expected {
+					log.WithFields(log.Fields{
+						"name":      plan.Resource.Name,
+						"kind":      plan.Resource.Kind(),
+						"namespace": plan.Resource.Namespace,
+					}).Warn("provider.kubernetes: resource doesn't run updated images, not rolling back")
+					return
+				}
+				p.rollback(plan, reason)
+			}
+			return
+		case <-ticker.C:
+			gr, ok := p.getCachedResource(plan.Resource)
+			if !ok {
+				continue
+			}
+			images := strings.Join(getUpdatableImages(gr), ",")
+			if superseded(images) {
+				return
+			}
+			// waiting for cache to catch up with our update
+			if images != expected {
+				continue
+			}
+			current = gr
+
+			done, failed := getRolloutStatus(current)
+			if failed {
+				p.sendRolloutOutcome(plan, current, time.Since(started), "rollout failed")
+				if rollback {
+					p.rollback(plan, "rollout failed")
+				}
+				return
+			}
+			if done {
+				log.WithFields(log.Fields{
+					"name":      plan.Resource.Name,
+					"kind":      plan.Resource.Kind(),
+					"namespace": plan.Resource.Namespace,
+					"version":   plan.NewVersion,
+				}).Info("provider.kubernetes: resource rollout finished")
+				p.sendRolloutOutcome(plan, current, time.Since(started), "")
+				if rollback {
+					p.runPostUpdateHook(plan)
+				}
+				return
+			}
+		}
+	}
+}
+
+// getReplicaStatus - updated and available replicas of the resource, empty for resources
+// without replicas
+func getReplicaStatus(resource *k8s.GenericResource) string {
+	switch obj := resource.GetResource().(type) {
+	case *apps_v1.Deployment:
+		replicas := int32(1)
+		if obj.Spec.Replicas != nil {
+			replicas = *obj.Spec.Replicas
+		}
+		return fmt.Sprintf("%d/%d updated, %d/%d available", obj.Status.UpdatedReplicas, replicas, obj.Status.AvailableReplicas, replicas)
+	case *apps_v1.StatefulSet:
+		replicas := int32(1)
+		if obj.Spec.Replicas != nil {
+			replicas = *obj.Spec.Replicas
+		}
+		return fmt.Sprintf("%d/%d updated, %d/%d ready", obj.Status.UpdatedReplicas, replicas, obj.Status.ReadyReplicas, replicas)
+	case *apps_v1.DaemonSet:
+		return fmt.Sprintf("%d/%d updated, %d/%d available", obj.Status.UpdatedNumberScheduled, obj.Status.DesiredNumberScheduled, obj.Status.NumberAvailable, obj.Status.DesiredNumberScheduled)
+	case *unstructured.Unstructured:
+		if resource.Kind() != "rollout" {
+			return ""
+		}
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		return fmt.Sprintf("%d/%d updated, %d/%d available", updated, replicas, available, replicas)
+	}
+	return ""
+}
+
+// sendRolloutOutcome - success notification once rollout converged, error notification
+// with the failure reason otherwise
+func (p *Provider) sendRolloutOutcome(plan *UpdatePlan, current *k8s.GenericResource, duration time.Duration, failure string) {
+	resource := plan.Resource
+	duration = duration.Round(time.Second)
+	replicas := getReplicaStatus(current)
+
+	level := types.LevelSuccess
+	msg := fmt.Sprintf("%s %s/%s rollout %s->%s finished in %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, duration)
+	if failure != "" {
+		level = types.LevelError
+		msg = fmt.Sprintf("%s %s/%s %s->%s %s after %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, failure, duration)
+	}
+	if replicas != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, replicas)
+	}
+
+	p.sender.Send(types.EventNotification{
+		Name:         "rollout outcome",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      msg,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationRolloutOutcome,
+		Level:        level,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+			"image":     strings.Join(resource.GetImages(), ", "),
+			"previous":  plan.CurrentVersion,
+			"new":       plan.NewVersion,
+			"duration":  duration.String(),
+			"replicas":  replicas,
+		},
+	})
+}
+
+// rollback - reverts resource containers to images that were running before the update
+func (p *Provider) rollback(plan *UpdatePlan, reason string) {
+	resource, ok := p.getCachedResource(plan.Resource)
+	if !ok {
+		resource = plan.Resource
+	}
+
+	containers := getUpdatableContainers(resource)
+	if len(containers) != len(plan.PreviousImages) {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: resource containers changed, cannot roll back")
+		return
+	}
+
+	for idx, img := range plan.PreviousImages {
+		if containers[idx].Image != img {
+			// the version is skipped until a newer one arrives, otherwise the next
+			// poll would apply it again
+			p.rolledBack.add(resource.Identifier, containers[idx].Image)
+			containers[idx].update(resource, img)
+		}
+	}
+
+	annotations := resource.GetAnnotations()
+	annotations["kubernetes.io/change-cause"] = fmt.Sprintf("keel automated rollback, version %s -> %s [%s]", plan.NewVersion, plan.CurrentVersion, time.Now().Format(time.RFC3339))
+	resource.SetAnnotations(annotations)
+
+	level := types.LevelError
+	msg := fmt.Sprintf("%s %s/%s %s, rolled back %s->%s", resource.Kind(), resource.Namespace, resource.Name, reason, plan.NewVersion, plan.CurrentVersion)
+
+	err := p.implementer.Update(resource)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+		}).Error("provider.kubernetes: got error while rolling back resource")
+		level = types.LevelFatal
+		msg = fmt.Sprintf("%s %s/%s %s, rollback %s->%s failed, error: %s", resource.Kind(), resource.Namespace, resource.Name, reason, plan.NewVersion, plan.CurrentVersion, err)
+	} else {
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"reason":    reason,
+			"previous":  plan.NewVersion,
+			"new":       plan.CurrentVersion,
+		}).Warn("provider.kubernetes: resource rolled back")
+	}
+
+	p.abortRolledBackCanary(plan, reason)
+
+	p.sender.Send(types.EventNotification{
+		Name:         "rollback resource",
+		ResourceKind: resource.Kind(),
+		Identifier:   resource.Identifier,
+		Message:      msg,
+		CreatedAt:    time.Now(),
+		Type:         types.NotificationDeploymentRollback,
+		Level:        level,
+		Channels:     p.notificationChannels(resource),
+		Webhooks:     p.notificationWebhooks(resource),
+		Metadata: map[string]string{
+			"provider":  p.GetName(),
+			"namespace": resource.GetNamespace(),
+			"name":      resource.GetName(),
+		},
+	})
+}
+
+// manualTrigger - events submitted through the native webhook retry versions that
+// were rolled back
+const manualTrigger = "native"
+
+// checkForRolledBack - filters out plans that would apply an image that was rolled back,
+// the record is cleared once another version is planned or the same version is submitted
+// through the native webhook
+func (p *Provider) checkForRolledBack(event *types.Event, plans []*UpdatePlan) []*UpdatePlan {
+	allowedPlans := []*UpdatePlan{}
+	for _, plan := range plans {
+		rejected, ok := p.rolledBack.get(plan.Resource.Identifier)
+		if !ok {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if event.TriggerName == manualTrigger || !containsImage(updatedImages(plan), rejected) {
+			p.rolledBack.remove(plan.Resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":      plan.Resource.Name,
+			"kind":      plan.Resource.Kind(),
+			"namespace": plan.Resource.Namespace,
+			"image":     rejected,
+		}).Info("provider.kubernetes: version was rolled back, skipping update until a newer version arrives")
+	}
+	return allowedPlans
+}
+
+// updatedImages - new images of containers changed by the plan
+func updatedImages(plan *UpdatePlan) []string {
+	var images []string
+	for idx, img := range getUpdatableImages(plan.Resource) {
+		if idx < len(plan.PreviousImages) && plan.PreviousImages[idx] == img {
+			continue
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+func containsImage(images []string, image string) bool {
+	for _, img := range images {
+		if img == image {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// rolloutTestAnnotations - short rollout timeout so monitoring tests finish quickly
+var rolloutTestAnnotations = map[string]string{types.KeelRolloutTimeoutAnnotation: "1s"}
+
+func TestGetRolloutStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     apps_v1.DeploymentStatus
+		wantDone   bool
+		wantFailed bool
+	}{
+		{
+			name:   "not observed yet",
+			status: apps_v1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:   "in progress",
+			status: apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2},
+		},
+		{
+			name:     "done",
+			status:   apps_v1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			wantDone: true,
+		},
+		{
+			name: "progress deadline exceeded",
+			status: apps_v1.DeploymentStatus{
+				ObservedGeneration: 1,
+				Replicas:           3,
+				UpdatedReplicas:    1,
+				Conditions: []apps_v1.DeploymentCondition{
+					{Type: apps_v1.DeploymentProgressing, Status: v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+				},
+			},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, failed := getRolloutStatus(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, tt.status, v1.Container{Image: "karolisr/keel:0.2.0"})))
+			if done != tt.wantDone {
+				t.Errorf("getRolloutStatus() done = %v, want %v", done, tt.wantDone)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("getRolloutStatus() failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestMonitorRolloutRollback(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	// cache already has updated resource which failed to progress
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           3,
+		UpdatedReplicas:    1,
+		Conditions: []apps_v1.DeploymentCondition{
+			{Type: apps_v1.DeploymentProgressing, Status: v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		},
+	}, v1.Container{Image: "karolisr/keel:0.3.0"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.monitorRollout(&UpdatePlan{
+		Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:0.3.0"})),
+		CurrentVersion: "0.2.0",
+		NewVersion:     "0.3.0",
+		PreviousImages: []string{"karolisr/keel:0.2.0"},
+	}, time.Second, true)
+
+	if fp.updated == nil {
+		t.Fatalf("resource was not rolled back")
+	}
+	if fp.updated.Containers()[0].Image != "karolisr/keel:0.2.0" {
+		t.Errorf("unexpected image after rollback: %s", fp.updated.Containers()[0].Image)
+	}
+	if sender.sentEvent.Type != types.NotificationDeploymentRollback {
+		t.Errorf("expected rollback notification, got: %s", sender.sentEvent.Type)
+	}
+}
+
+func TestMonitorRolloutDone(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &fakeImplementer{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           2,
+		UpdatedReplicas:    2,
+		AvailableReplicas:  2,
+	}, v1.Container{Image: "karolisr/keel:0.3.0"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.monitorRollout(&UpdatePlan{
+		Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:0.3.0"})),
+		CurrentVersion: "0.2.0",
+		NewVersion:     "0.3.0",
+		PreviousImages: []string{"karolisr/keel:0.2.0"},
+	}, time.Second, true)
+
+	if fp.updated != nil {
+		t.Errorf("resource shouldn't have been rolled back")
+	}
+}
+
+func TestMonitorRolloutOutcome(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           2,
+		UpdatedReplicas:    2,
+		AvailableReplicas:  2,
+	}, v1.Container{Image: "karolisr/keel:0.3.0"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.monitorRollout(&UpdatePlan{
+		Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:0.3.0"})),
+		CurrentVersion: "0.2.0",
+		NewVersion:     "0.3.0",
+	}, time.Second, false)
+
+	if sender.sentEvent.Type != types.NotificationRolloutOutcome || sender.sentEvent.Level != types.LevelSuccess {
+		t.Fatalf("expected successful rollout outcome, got: %s %s", sender.sentEvent.Type, sender.sentEvent.Level)
+	}
+	if sender.sentEvent.Metadata["replicas"] != "2/2 updated, 2/2 available" {
+		t.Errorf("unexpected replica status: %s", sender.sentEvent.Metadata["replicas"])
+	}
+	if !strings.Contains(sender.sentEvent.Message, "rollout 0.2.0->0.3.0 finished in") {
+		t.Errorf("unexpected message: %s", sender.sentEvent.Message)
+	}
+}
+
+func TestMonitorRolloutOutcomeTimeout(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	// rollout is still in progress
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           4,
+		UpdatedReplicas:    2,
+		AvailableReplicas:  3,
+	}, v1.Container{Image: "karolisr/keel:0.3.0"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.monitorRollout(&UpdatePlan{
+		Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:0.3.0"})),
+		CurrentVersion: "0.2.0",
+		NewVersion:     "0.3.0",
+		PreviousImages: []string{"karolisr/keel:0.2.0"},
+	}, 50*time.Millisecond, false)
+
+	if fp.updated != nil {
+		t.Errorf("resource shouldn't be rolled back without rollout timeout annotation")
+	}
+	if sender.sentEvent.Type != types.NotificationRolloutOutcome || sender.sentEvent.Level != types.LevelError {
+		t.Fatalf("expected failed rollout outcome, got: %s %s", sender.sentEvent.Type, sender.sentEvent.Level)
+	}
+	if !strings.Contains(sender.sentEvent.Message, "rollout didn't finish in 50ms") {
+		t.Errorf("unexpected message: %s", sender.sentEvent.Message)
+	}
+}
+
+func TestMonitorRolloutSuperseded(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	// resource was updated again before the monitored rollout finished
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           3,
+		UpdatedReplicas:    1,
+	}, v1.Container{Image: "karolisr/keel:0.4.0"})))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	started := time.Now()
+	provider.monitorRollout(&UpdatePlan{
+		Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:0.3.0"})),
+		CurrentVersion: "0.2.0",
+		NewVersion:     "0.3.0",
+		PreviousImages: []string{"karolisr/keel:0.2.0"},
+	}, time.Second, true)
+
+	if time.Since(started) >= time.Second {
+		t.Errorf("expected monitoring to stop before the timeout")
+	}
+	if fp.updated != nil {
+		t.Errorf("newer images shouldn't have been rolled back")
+	}
+	if sender.sentEvent.Type == types.NotificationRolloutOutcome || sender.sentEvent.Type == types.NotificationDeploymentRollback {
+		t.Errorf("unexpected notification: %s", sender.sentEvent.Type)
+	}
+}
+
+func TestCheckForRolledBack(t *testing.T) {
+	fp := &fakeImplementer{}
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	plan := func(version string) *UpdatePlan {
+		return &UpdatePlan{
+			Resource:       MustParseGR(newTestDeployment("xxxx", "dep-1", 2, rolloutTestAnnotations, apps_v1.DeploymentStatus{}, v1.Container{Image: "karolisr/keel:" + version})),
+			CurrentVersion: "0.2.0",
+			NewVersion:     version,
+			PreviousImages: []string{"karolisr/keel:0.2.0"},
+		}
+	}
+
+	provider.rollback(plan("0.3.0"), "rollout failed")
+
+	event := &types.Event{TriggerName: types.TriggerTypePoll.String()}
+	if plans := provider.checkForRolledBack(event, []*UpdatePlan{plan("0.3.0")}); len(plans) != 0 {
+		t.Errorf("expected rolled back version to be skipped, got %d plans", len(plans))
+	}
+
+	event = &types.Event{TriggerName: manualTrigger}
+	if plans := provider.checkForRolledBack(event, []*UpdatePlan{plan("0.3.0")}); len(plans) != 1 {
+		t.Errorf("expected native webhook to retry rolled back version, got %d plans", len(plans))
+	}
+
+	provider.rollback(plan("0.3.0"), "rollout failed")
+
+	event = &types.Event{TriggerName: types.TriggerTypePoll.String()}
+	if plans := provider.checkForRolledBack(event, []*UpdatePlan{plan("0.3.1")}); len(plans) != 1 {
+		t.Errorf("expected newer version to be allowed, got %d plans", len(plans))
+	}
+	if plans := provider.checkForRolledBack(event, []*UpdatePlan{plan("0.3.0")}); len(plans) != 1 {
+		t.Errorf("expected record to be cleared by newer version, got %d plans", len(plans))
+	}
+}
@@ -0,0 +1,34 @@
+package kubernetes
+
+import (
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+)
+
+// SetNotificationRoutes - notifications of resources matching a route are sent to the
+// route channels and webhooks
+func (p *Provider) SetNotificationRoutes(routes *notification.Routes) {
+	p.routes = routes
+}
+
+// notificationChannels - keel.sh/notify annotation channels, channels of the first
+// matching notification route otherwise
+func (p *Provider) notificationChannels(resource *k8s.GenericResource) []string {
+	channels := types.ParseEventNotificationChannels(resource.GetAnnotations())
+	if len(channels) > 0 {
+		return channels
+	}
+	if route := p.routes.Match(resource.Namespace, resource.GetLabels()); route != nil {
+		return route.Channels
+	}
+	return channels
+}
+
+// notificationWebhooks - webhook URLs of the first matching notification route
+func (p *Provider) notificationWebhooks(resource *k8s.GenericResource) []string {
+	if route := p.routes.Match(resource.Namespace, resource.GetLabels()); route != nil {
+		return route.Webhooks
+	}
+	return nil
+}
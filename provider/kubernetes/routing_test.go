@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/notification"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotificationRoutes(t *testing.T) {
+	routes, err := notification.ParseRoutes([]byte(`
+- namespaces: [team-a]
+  channels: ["#team-a"]
+- labels: {team: b}
+  channels: ["#team-b"]
+  webhooks: ["https://team-b.example.com/keel"]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse routes: %s", err)
+	}
+
+	grs := MustParseGRS([]*apps_v1.Deployment{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "a", Namespace: "team-a"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "b", Namespace: "default", Labels: map[string]string{"team": "b"}}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "c", Namespace: "team-a", Annotations: map[string]string{types.KeelNotificationChanAnnotation: "#c"}}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "d", Namespace: "default"}},
+	})
+
+	p := &Provider{}
+	p.SetNotificationRoutes(routes)
+
+	for i, tt := range []struct {
+		channels []string
+		webhooks []string
+	}{
+		{channels: []string{"#team-a"}},
+		{channels: []string{"#team-b"}, webhooks: []string{"https://team-b.example.com/keel"}},
+		{channels: []string{"#c"}},
+		{channels: []string{}},
+	} {
+		if channels := p.notificationChannels(grs[i]); !reflect.DeepEqual(channels, tt.channels) {
+			t.Errorf("%s: expected channels %v, got %v", grs[i].Name, tt.channels, channels)
+		}
+		if webhooks := p.notificationWebhooks(grs[i]); !reflect.DeepEqual(webhooks, tt.webhooks) {
+			t.Errorf("%s: expected webhooks %v, got %v", grs[i].Name, tt.webhooks, webhooks)
+		}
+	}
+}
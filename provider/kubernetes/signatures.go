@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/keel-hq/keel/pkg/cosign"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// signatureRegistry - resolves image digest and fetches its cosign signatures
+type signatureRegistry interface {
+	Signatures(opts registry.Opts) (digest string, signatures []*registry.Signature, err error)
+}
+
+// signatureVerifier - checks signatures against trusted keys and identities
+type signatureVerifier interface {
+	Verify(digest string, signatures []*registry.Signature) error
+}
+
+// rejectedImages - last rejected image of each resource, rejections are only
+// notified once as the same update is evaluated on every poll
+type rejectedImages struct {
+	mu     sync.Mutex
+	images map[string]string
+}
+
+// add - records rejected image, returns false if it was already rejected
+func (r *rejectedImages) add(identifier, image string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.images == nil {
+		r.images = make(map[string]string)
+	}
+	if r.images[identifier] == image {
+		return false
+	}
+	r.images[identifier] = image
+	return true
+}
+
+func (r *rejectedImages) get(identifier string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	image, ok := r.images[identifier]
+	return image, ok
+}
+
+func (r *rejectedImages) remove(identifier string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.images, identifier)
+}
+
+// SetSignatureVerifier - enables cosign signature verification of new images, for all
+// resources when required is set, otherwise only for resources with keel.sh/verifySignature=true
+func (p *Provider) SetSignatureVerifier(verifier *cosign.Verifier, required bool) {
+	if verifier == nil {
+		return
+	}
+	p.verifier = verifier
+	p.verifySignatures = required
+}
+
+// shouldVerifySignature - keel.sh/verifySignature annotation or label overrides global setting
+func shouldVerifySignature(labels map[string]string, annotations map[string]string, required bool) bool {
+	val, ok := annotations[types.KeelVerifySignatureAnnotation]
+	if !ok {
+		val, ok = labels[types.KeelVerifySignatureAnnotation]
+	}
+	if !ok {
+		return required
+	}
+	return strings.ToLower(strings.TrimSpace(val)) == "true"
+}
+
+// checkForSignatures - filters out plans for images without a valid signature, these updates
+// are refused and notified. Updates are deferred if signatures can't be fetched
+func (p *Provider) checkForSignatures(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		if p.verifier == nil || !shouldVerifySignature(resource.GetLabels(), resource.GetAnnotations(), p.verifySignatures) {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		ref, err := image.Parse(event.Repository.Name + ":" + plan.NewVersion)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     event.Repository.Name,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse image, skipping update")
+			continue
+		}
+
+		digest, signatures, err := p.signatures.Signatures(p.registryOpts(ref, resource))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     ref.Remote(),
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: failed to get image signatures, deferring update")
+			p.deferred.add(resource.Identifier, event)
+			continue
+		}
+
+		err = p.verifier.Verify(digest, signatures)
+		if err == nil {
+			p.rejected.remove(resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"image":     ref.Remote(),
+			"digest":    digest,
+		}).Warn("provider.kubernetes: image signature verification failed, update refused")
+
+		if !p.rejected.add(resource.Identifier, ref.Remote()+"@"+digest) {
+			continue
+		}
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "signature rejected",
+			Message:      fmt.Sprintf("Update of %s %s/%s %s->%s refused, image %s signature verification failed: %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, ref.Remote(), err),
+			CreatedAt:    timeutil.Now(),
+			Type:         types.NotificationSignatureRejected,
+			Level:        types.LevelError,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+				"image":     ref.Remote(),
+				"digest":    digest,
+			},
+		})
+	}
+
+	return allowedPlans
+}
@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/registry"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeSignatureRegistry struct {
+	opts   registry.Opts
+	digest string
+	err    error
+}
+
+func (r *fakeSignatureRegistry) Signatures(opts registry.Opts) (string, []*registry.Signature, error) {
+	r.opts = opts
+	return r.digest, []*registry.Signature{{Payload: []byte("{}")}}, r.err
+}
+
+type fakeVerifier struct {
+	err      error
+	verified int
+}
+
+func (v *fakeVerifier) Verify(digest string, signatures []*registry.Signature) error {
+	v.verified++
+	return v.err
+}
+
+func newSignatureTestProvider(t *testing.T, annotations map[string]string) (*Provider, *fakeImplementer, *fakeSender, func()) {
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: annotations,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+	return provider, fp, sender, teardown
+}
+
+func TestProcessEventSignatureRejected(t *testing.T) {
+	provider, fp, sender, teardown := newSignatureTestProvider(t, map[string]string{types.KeelVerifySignatureAnnotation: "true"})
+	defer teardown()
+
+	sigRegistry := &fakeSignatureRegistry{digest: "sha256:abc"}
+	verifier := &fakeVerifier{err: fmt.Errorf("signature doesn't match any trusted key")}
+	provider.signatures = sigRegistry
+	provider.verifier = verifier
+
+	event := &types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}}
+	if _, err := provider.processEvent(event); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated with an image without valid signature")
+	}
+	if sender.sentEvent.Type != types.NotificationSignatureRejected || sender.sentEvent.Level != types.LevelError {
+		t.Errorf("expected signature rejected notification, got: %+v", sender.sentEvent)
+	}
+	if sigRegistry.opts.Name != "v2-namespace/hello-world" || sigRegistry.opts.Tag != "1.1.2" {
+		t.Errorf("unexpected registry opts: %+v", sigRegistry.opts)
+	}
+
+	// same rejection isn't notified again
+	sender.sentEvent = types.EventNotification{}
+	if _, err := provider.processEvent(event); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if sender.sentEvent.Name != "" {
+		t.Errorf("rejection shouldn't be notified twice, got: %s", sender.sentEvent.Name)
+	}
+
+	verifier.err = nil
+	if _, err := provider.processEvent(event); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated once signature is valid")
+	}
+}
+
+func TestProcessEventSignatureRequired(t *testing.T) {
+	// verification enabled for all resources, resource opts out
+	provider, fp, _, teardown := newSignatureTestProvider(t, map[string]string{types.KeelVerifySignatureAnnotation: "false"})
+	defer teardown()
+
+	verifier := &fakeVerifier{err: fmt.Errorf("image is not signed")}
+	provider.signatures = &fakeSignatureRegistry{digest: "sha256:abc"}
+	provider.verifier = verifier
+	provider.verifySignatures = true
+
+	_, err := provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if verifier.verified != 0 || fp.updated == nil {
+		t.Errorf("expected resource that opted out to be updated without verification")
+	}
+}
+
+func TestShouldVerifySignature(t *testing.T) {
+	tests := []struct {
+		labels, annotations map[string]string
+		required            bool
+		want                bool
+	}{
+		{required: false, want: false},
+		{required: true, want: true},
+		{annotations: map[string]string{types.KeelVerifySignatureAnnotation: "true"}, want: true},
+		{labels: map[string]string{types.KeelVerifySignatureAnnotation: "true"}, want: true},
+		{annotations: map[string]string{types.KeelVerifySignatureAnnotation: "false"}, labels: map[string]string{types.KeelVerifySignatureAnnotation: "true"}, want: false},
+		{annotations: map[string]string{types.KeelVerifySignatureAnnotation: "false"}, required: true, want: false},
+	}
+	for i, tt := range tests {
+		if got := shouldVerifySignature(tt.labels, tt.annotations, tt.required); got != tt.want {
+			t.Errorf("%d: expected %t, got %t", i, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statefulSetPodReadyTimeout - how long recycled StatefulSet pod replacement is waited for
+// when keel.sh/rolloutTimeout is not set
+var statefulSetPodReadyTimeout = 10 * time.Minute
+
+// applyUpdateStrategy - makes sure that updated pod template actually reaches
+// running pods. StatefulSets with RollingUpdate strategy are rolled out by the
+// controller, OnDelete strategy only creates new pods once old ones are deleted so
+// they are left alone unless keel.sh/recyclePods is set
+func (p *Provider) applyUpdateStrategy(resource *k8s.GenericResource) error {
+	ss, ok := resource.GetResource().(*apps_v1.StatefulSet)
+	if !ok {
+		return nil
+	}
+
+	switch ss.Spec.UpdateStrategy.Type {
+	case apps_v1.OnDeleteStatefulSetStrategyType:
+		if ss.Annotations[types.KeelRecyclePodsAnnotation] != "true" {
+			log.WithFields(log.Fields{
+				"name":      ss.Name,
+				"namespace": ss.Namespace,
+			}).Info("provider.kubernetes: statefulset uses OnDelete update strategy, pods will be updated once they are deleted")
+			return nil
+		}
+		timeout, ok := getRolloutTimeout(resource)
+		if !ok {
+			timeout = statefulSetPodReadyTimeout
+		}
+		go p.recycleStatefulSetPods(ss, timeout)
+	default:
+		if ss.Spec.UpdateStrategy.RollingUpdate != nil && ss.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+			log.WithFields(log.Fields{
+				"name":      ss.Name,
+				"namespace": ss.Namespace,
+				"partition": *ss.Spec.UpdateStrategy.RollingUpdate.Partition,
+			}).Info("provider.kubernetes: statefulset has rolling update partition set, pods below partition ordinal will not be updated")
+		}
+	}
+
+	return nil
+}
+
+// recycleStatefulSetPods - deletes pods one at a time (highest ordinal first, same as the
+// controller), next pod is only deleted once the replacement of the previous one is ready
+func (p *Provider) recycleStatefulSetPods(ss *apps_v1.StatefulSet, timeout time.Duration) {
+	selector, err := meta_v1.LabelSelectorAsSelector(ss.Spec.Selector)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      ss.Name,
+			"namespace": ss.Namespace,
+		}).Error("provider.kubernetes: failed to parse statefulset selector, pods will not be recycled")
+		return
+	}
+
+	podList, err := p.implementer.Pods(ss.Namespace, selector.String())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      ss.Name,
+			"namespace": ss.Namespace,
+		}).Error("provider.kubernetes: failed to list statefulset pods, pods will not be recycled")
+		return
+	}
+
+	pods := podList.Items
+	sort.Slice(pods, func(i, j int) bool {
+		return getPodOrdinal(ss.Name, &pods[i]) > getPodOrdinal(ss.Name, &pods[j])
+	})
+
+	for _, pod := range pods {
+		log.WithFields(log.Fields{
+			"name":      ss.Name,
+			"namespace": ss.Namespace,
+			"pod":       pod.Name,
+		}).Info("provider.kubernetes: statefulset uses OnDelete update strategy, deleting pod")
+
+		err = p.implementer.DeletePod(ss.Namespace, pod.Name, &meta_v1.DeleteOptions{})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      ss.Name,
+				"namespace": ss.Namespace,
+				"pod":       pod.Name,
+			}).Error("provider.kubernetes: failed to delete statefulset pod, stopping pod recycling")
+			return
+		}
+
+		err = p.waitForPodReplacement(ss.Namespace, selector.String(), &pod, timeout)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      ss.Name,
+				"namespace": ss.Namespace,
+				"pod":       pod.Name,
+			}).Error("provider.kubernetes: statefulset pod replacement is not ready, stopping pod recycling")
+			return
+		}
+	}
+}
+
+// waitForPodReplacement - waits for a new pod with the same name as the deleted one to be ready
+func (p *Provider) waitForPodReplacement(namespace, selector string, deleted *v1.Pod, timeout time.Duration) error {
+	ticker := time.NewTicker(rolloutCheckInterval)
+	defer ticker.Stop()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-p.stop:
+			return fmt.Errorf("provider stopped")
+		case <-deadline:
+			return fmt.Errorf("pod wasn't ready in %s", timeout)
+		case <-ticker.C:
+			podList, err := p.implementer.Pods(namespace, selector)
+			if err != nil {
+				continue
+			}
+			for _, pod := range podList.Items {
+				if pod.Name == deleted.Name && pod.UID != deleted.UID && isPodReady(&pod) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getPodOrdinal - gets StatefulSet pod ordinal from its name (<statefulset name>-<ordinal>),
+// returns -1 if pod name doesn't follow this convention
+func getPodOrdinal(statefulSetName string, pod *v1.Pod) int {
+	suffix := strings.TrimPrefix(pod.Name, statefulSetName+"-")
+	if suffix == pod.Name {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
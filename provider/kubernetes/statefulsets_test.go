@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+func newTestStatefulSet(strategy apps_v1.StatefulSetUpdateStrategyType) *apps_v1.StatefulSet {
+	return &apps_v1.StatefulSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "sts",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{},
+		},
+		Spec: apps_v1.StatefulSetSpec{
+			Selector: &meta_v1.LabelSelector{
+				MatchLabels: map[string]string{"app": "sts"},
+			},
+			UpdateStrategy: apps_v1.StatefulSetUpdateStrategy{
+				Type: strategy,
+			},
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestProcessEventStatefulSetOnDelete(t *testing.T) {
+	fp := &fakeImplementer{
+		podList: &v1.PodList{
+			Items: []v1.Pod{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-0", Namespace: "xxxx"}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-2", Namespace: "xxxx"}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-1", Namespace: "xxxx"}},
+			},
+		},
+	}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestStatefulSet(apps_v1.OnDeleteStatefulSetStrategyType)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("statefulset was not updated")
+	}
+
+	if len(fp.deletedPods) != 0 {
+		t.Errorf("expected OnDelete pods to be left alone, got %d deleted", len(fp.deletedPods))
+	}
+}
+
+// recreatingImplementer - replaces deleted pods, replacements become ready once pods
+// are listed again
+type recreatingImplementer struct {
+	*fakeImplementer
+	mu sync.Mutex
+}
+
+func (i *recreatingImplementer) Pods(namespace, labelSelector string) (*v1.PodList, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	list := i.podList.DeepCopy()
+	for idx := range i.podList.Items {
+		i.podList.Items[idx].Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	}
+	return list, nil
+}
+
+func (i *recreatingImplementer) DeletePod(namespace, name string, opts *meta_v1.DeleteOptions) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, pod := range i.podList.Items {
+		if pod.UID == ktypes.UID(pod.Name+"-new") && !isPodReady(&pod) {
+			return fmt.Errorf("pod %s deleted before %s was ready", name, pod.Name)
+		}
+		if pod.Name == name {
+			i.podList.Items[idx].UID = ktypes.UID(name + "-new")
+			i.podList.Items[idx].Status.Conditions = nil
+		}
+	}
+	return i.fakeImplementer.DeletePod(namespace, name, opts)
+}
+
+func TestRecycleStatefulSetPods(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	fp := &recreatingImplementer{fakeImplementer: &fakeImplementer{
+		podList: &v1.PodList{
+			Items: []v1.Pod{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-0", Namespace: "xxxx", UID: "sts-0"}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-2", Namespace: "xxxx", UID: "sts-2"}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-1", Namespace: "xxxx", UID: "sts-1"}},
+			},
+		},
+	}}
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	ss := newTestStatefulSet(apps_v1.OnDeleteStatefulSetStrategyType)
+	ss.Annotations[types.KeelRecyclePodsAnnotation] = "true"
+	provider.recycleStatefulSetPods(ss, time.Second)
+
+	expected := []string{"sts-2", "sts-1", "sts-0"}
+	if len(fp.deletedPods) != len(expected) {
+		t.Fatalf("expected %d pods to be deleted, got: %d", len(expected), len(fp.deletedPods))
+	}
+	for idx, name := range expected {
+		if fp.deletedPods[idx].Name != name {
+			t.Errorf("expected pod %s to be deleted at position %d, got: %s", name, idx, fp.deletedPods[idx].Name)
+		}
+	}
+}
+
+func TestRecycleStatefulSetPodsNotReady(t *testing.T) {
+	rolloutCheckInterval = 10 * time.Millisecond
+	defer func() { rolloutCheckInterval = 5 * time.Second }()
+
+	// deleted pods are never replaced
+	fp := &fakeImplementer{
+		podList: &v1.PodList{
+			Items: []v1.Pod{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-0", Namespace: "xxxx", UID: "sts-0"}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-1", Namespace: "xxxx", UID: "sts-1"}},
+			},
+		},
+	}
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, &k8s.GenericResourceCache{})
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	provider.recycleStatefulSetPods(newTestStatefulSet(apps_v1.OnDeleteStatefulSetStrategyType), 50*time.Millisecond)
+
+	if len(fp.deletedPods) != 1 || fp.deletedPods[0].Name != "sts-1" {
+		t.Errorf("expected only sts-1 to be deleted, got: %v", fp.deletedPods)
+	}
+}
+
+func TestProcessEventStatefulSetRollingUpdate(t *testing.T) {
+	fp := &fakeImplementer{
+		podList: &v1.PodList{
+			Items: []v1.Pod{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "sts-0", Namespace: "xxxx"}},
+			},
+		},
+	}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(newTestStatefulSet(apps_v1.RollingUpdateStatefulSetStrategyType)))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated == nil {
+		t.Fatalf("statefulset was not updated")
+	}
+
+	if len(fp.deletedPods) != 0 {
+		t.Errorf("expected no pods to be deleted for rolling update strategy, got: %d", len(fp.deletedPods))
+	}
+}
@@ -27,7 +27,10 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 		"policy":    plc.Name(),
 	}).Debug("provider.kubernetes.checkVersionedDeployment: keel policy found, checking resource...")
 	shouldUpdateDeployment = false
-	for idx, c := range resource.Containers() {
+	labels := resource.GetLabels()
+	annotations := resource.GetAnnotations()
+	hasContainerPolicies := policy.HasContainerPolicies(labels, annotations)
+	for _, c := range getUpdatableContainers(resource) {
 		containerImageRef, err := image.Parse(c.Image)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -48,7 +51,7 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 			"image":             c.Image,
 		}).Debug("provider.kubernetes: checking image")
 
-		if containerImageRef.Repository() != eventRepoRef.Repository() {
+		if !image.RepositoryMatches(containerImageRef, eventRepoRef) {
 			log.WithFields(log.Fields{
 				"parsed_image_name": containerImageRef.Remote(),
 				"target_image_name": repo.Name,
@@ -56,13 +59,27 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 			continue
 		}
 
-		shouldUpdateContainer, err := plc.ShouldUpdate(containerImageRef.Tag(), eventRepoRef.Tag())
+		containerPlc := plc
+		if hasContainerPolicies {
+			containerPlc = policy.GetContainerPolicyFromLabelsOrAnnotations(c.Name, labels, annotations)
+		}
+
+		shouldUpdateContainer, err := policy.ShouldUpdate(containerPlc, &policy.UpdateContext{
+			Provider:    ProviderName,
+			Kind:        resource.Kind(),
+			Namespace:   resource.Namespace,
+			Name:        resource.Name,
+			Container:   c.Name,
+			Image:       containerImageRef.Remote(),
+			Labels:      labels,
+			Annotations: annotations,
+		}, containerImageRef.Tag(), eventRepoRef.Tag())
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error":             err,
 				"parsed_image_name": containerImageRef.Remote(),
 				"target_image_name": repo.Name,
-				"policy":            plc.Name(),
+				"policy":            containerPlc.Name(),
 			}).Error("provider.kubernetes: failed to check whether container should be updated")
 			continue
 		}
@@ -76,9 +93,9 @@ func checkForUpdate(plc policy.Policy, repo *types.Repository, resource *k8s.Gen
 
 		// updating image
 		if containerImageRef.Registry() == image.DefaultRegistryHostname {
-			resource.UpdateContainer(idx, fmt.Sprintf("%s:%s", containerImageRef.ShortName(), repo.Tag))
+			c.update(resource, fmt.Sprintf("%s:%s", containerImageRef.ShortName(), repo.Tag))
 		} else {
-			resource.UpdateContainer(idx, fmt.Sprintf("%s:%s", containerImageRef.Repository(), repo.Tag))
+			c.update(resource, fmt.Sprintf("%s:%s", containerImageRef.Repository(), repo.Tag))
 		}
 
 		shouldUpdateDeployment = true
@@ -8,6 +8,7 @@ import (
 	"github.com/keel-hq/keel/internal/k8s"
 	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
 	"github.com/keel-hq/keel/util/timeutil"
 
 	apps_v1 "k8s.io/api/apps/v1"
@@ -1141,3 +1142,51 @@ func TestProvider_checkForUpdateSemver(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessEventImageMatchName(t *testing.T) {
+	image.SetMatchMode(image.MatchName)
+	defer image.SetMatchMode(image.MatchCanonical)
+
+	fp := &fakeImplementer{}
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "dep-1",
+			Namespace:   "xxxx",
+			Labels:      map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "mirror.example.com/library/nginx:1.15.0",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, &fakeSender{}, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "nginx",
+		Tag:  "1.16.0",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("mirrored image was not updated")
+	}
+	if fp.updated.Containers()[0].Image != "mirror.example.com/library/nginx:1.16.0" {
+		t.Errorf("expected mirror registry to be kept, got: %s", fp.updated.Containers()[0].Image)
+	}
+}
@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keel-hq/keel/extension/scanner"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Vulnerability gate actions
+const (
+	// VulnerabilityActionBlock - updates to images with vulnerabilities are refused
+	VulnerabilityActionBlock = "block"
+	// VulnerabilityActionApprove - updates to images with vulnerabilities require an approval
+	VulnerabilityActionApprove = "approve"
+)
+
+// SetVulnerabilityGate - enables vulnerability scan of new images for all resources, threshold
+// is the lowest severity that holds back an update. Resources can override both with
+// keel.sh/vulnerabilityThreshold and keel.sh/vulnerabilityAction
+func (p *Provider) SetVulnerabilityGate(threshold, action string) {
+	p.vulnerabilityThreshold = threshold
+	p.vulnerabilityAction = action
+}
+
+// vulnerabilityGate - threshold and action of the resource, ok is false when scan is disabled
+func vulnerabilityGate(labels map[string]string, annotations map[string]string, threshold, action string) (scanner.Severity, string, bool) {
+	get := func(key, defaultValue string) string {
+		val, ok := annotations[key]
+		if !ok {
+			val, ok = labels[key]
+		}
+		if !ok {
+			return defaultValue
+		}
+		return strings.ToLower(strings.TrimSpace(val))
+	}
+
+	threshold = get(types.KeelVulnerabilityThresholdAnnotation, threshold)
+	if threshold == "" || threshold == "none" {
+		return scanner.SeverityUnknown, "", false
+	}
+	severity, err := scanner.ParseSeverity(threshold)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"threshold": threshold,
+		}).Warn("provider.kubernetes: invalid vulnerability threshold, using critical")
+		severity = scanner.SeverityCritical
+	}
+
+	action = get(types.KeelVulnerabilityActionAnnotation, action)
+	if action != VulnerabilityActionApprove {
+		action = VulnerabilityActionBlock
+	}
+	return severity, action, true
+}
+
+// checkForVulnerabilities - scans new images, updates to images with vulnerabilities at or
+// above the threshold are either refused or require an approval. Updates are deferred until
+// the image is scanned
+func (p *Provider) checkForVulnerabilities(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		threshold, action, ok := vulnerabilityGate(resource.GetLabels(), resource.GetAnnotations(), p.vulnerabilityThreshold, p.vulnerabilityAction)
+		if !ok {
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		ref, err := image.Parse(event.Repository.Name + ":" + plan.NewVersion)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     event.Repository.Name,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse image, skipping update")
+			continue
+		}
+
+		opts := p.registryOpts(ref, resource)
+		digest, err := p.registryClient.Digest(opts)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     ref.Remote(),
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: failed to get image digest for vulnerability scan, deferring update")
+			p.deferred.add(resource.Identifier, event)
+			continue
+		}
+
+		report, err := p.scan(&scanner.Target{
+			Image:       ref,
+			Digest:      digest,
+			Credentials: &types.Credentials{Username: opts.Username, Password: opts.Password},
+		})
+		switch {
+		case err == scanner.ErrNoScanner:
+			// nothing can scan this registry, gate can't hold back updates forever
+			log.WithFields(log.Fields{
+				"image":     ref.Remote(),
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: no vulnerability scanner available for image, skipping scan")
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		case err == scanner.ErrNotScanned:
+			log.WithFields(log.Fields{
+				"image":     ref.Remote(),
+				"digest":    digest,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Info("provider.kubernetes: image hasn't been scanned yet, deferring update")
+			p.deferred.add(resource.Identifier, event)
+			continue
+		case err != nil:
+			log.WithFields(log.Fields{
+				"error":     err,
+				"image":     ref.Remote(),
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Warn("provider.kubernetes: vulnerability scan failed, deferring update")
+			p.deferred.add(resource.Identifier, event)
+			continue
+		}
+
+		found := report.AtLeast(threshold)
+		if found == 0 {
+			p.vulnerable.remove(resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"name":            resource.Name,
+			"kind":            resource.Kind(),
+			"namespace":       resource.Namespace,
+			"image":           ref.Remote(),
+			"digest":          digest,
+			"scanner":         report.Scanner,
+			"vulnerabilities": report.Summary(),
+			"threshold":       threshold.String(),
+			"action":          action,
+		}).Warn("provider.kubernetes: image has vulnerabilities above threshold")
+
+		level := types.LevelError
+		message := fmt.Sprintf("Update of %s %s/%s %s->%s refused, image %s has %d vulnerabilities of %s or higher severity (%s).", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, ref.Remote(), found, threshold, report.Summary())
+		if action == VulnerabilityActionApprove {
+			if plan.RequiredApprovals < 1 {
+				plan.RequiredApprovals = 1
+			}
+			plan.ApprovalReason = fmt.Sprintf("Image has %d vulnerabilities of %s or higher severity (%s).", found, threshold, report.Summary())
+			allowedPlans = append(allowedPlans, plan)
+
+			level = types.LevelWarn
+			message = fmt.Sprintf("Update of %s %s/%s %s->%s requires approval, image %s has %d vulnerabilities of %s or higher severity (%s).", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, ref.Remote(), found, threshold, report.Summary())
+		}
+
+		if !p.vulnerable.add(resource.Identifier, ref.Remote()+"@"+digest) {
+			continue
+		}
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "vulnerabilities found",
+			Message:      message,
+			CreatedAt:    timeutil.Now(),
+			Type:         types.NotificationVulnerabilitiesFound,
+			Level:        level,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+				"image":     ref.Remote(),
+				"digest":    digest,
+				"scanner":   report.Scanner,
+			},
+		})
+	}
+
+	return allowedPlans
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/keel-hq/keel/extension/scanner"
+	"github.com/keel-hq/keel/types"
+)
+
+type fakeScan struct {
+	target *scanner.Target
+	report *scanner.Report
+	err    error
+}
+
+func (s *fakeScan) scan(target *scanner.Target) (*scanner.Report, error) {
+	s.target = target
+	return s.report, s.err
+}
+
+var vulnerabilityTestEvent = &types.Event{Repository: types.Repository{
+	Name: "gcr.io/v2-namespace/hello-world",
+	Tag:  "1.1.2",
+}}
+
+func TestProcessEventVulnerabilitiesBlocked(t *testing.T) {
+	provider, fp, sender, teardown := newSignatureTestProvider(t, map[string]string{types.KeelVulnerabilityThresholdAnnotation: "high"})
+	defer teardown()
+
+	provider.registryClient = &fakeRegistryClient{digest: testDigest}
+	s := &fakeScan{report: &scanner.Report{Scanner: "trivy", Counts: map[scanner.Severity]int{scanner.SeverityCritical: 1, scanner.SeverityLow: 4}}}
+	provider.scan = s.scan
+
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated with a vulnerable image")
+	}
+	if s.target.Digest != testDigest || s.target.Image.Remote() != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected scan target: %+v", s.target)
+	}
+	if sender.sentEvent.Type != types.NotificationVulnerabilitiesFound || sender.sentEvent.Level != types.LevelError {
+		t.Errorf("expected vulnerabilities found notification, got: %+v", sender.sentEvent)
+	}
+	if !strings.Contains(sender.sentEvent.Message, "1 vulnerabilities of high or higher severity (1 critical, 4 low)") {
+		t.Errorf("unexpected message: %s", sender.sentEvent.Message)
+	}
+
+	// same rejection isn't notified again
+	sender.sentEvent = types.EventNotification{}
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if sender.sentEvent.Name != "" {
+		t.Errorf("rejection shouldn't be notified twice, got: %s", sender.sentEvent.Name)
+	}
+
+	s.report = &scanner.Report{Counts: map[scanner.Severity]int{scanner.SeverityMedium: 2}}
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated without vulnerabilities above threshold")
+	}
+}
+
+func TestProcessEventVulnerabilitiesRequireApproval(t *testing.T) {
+	provider, fp, sender, teardown := newSignatureTestProvider(t, map[string]string{types.KeelVulnerabilityActionAnnotation: "approve"})
+	defer teardown()
+
+	provider.registryClient = &fakeRegistryClient{digest: testDigest}
+	provider.scan = (&fakeScan{report: &scanner.Report{Counts: map[scanner.Severity]int{scanner.SeverityHigh: 2}}}).scan
+	provider.SetVulnerabilityGate("medium", VulnerabilityActionBlock)
+
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated before approval")
+	}
+	if sender.sentEvent.Type != types.NotificationVulnerabilitiesFound || sender.sentEvent.Level != types.LevelWarn {
+		t.Errorf("expected vulnerabilities found warning, got: %+v", sender.sentEvent)
+	}
+
+	approval, err := provider.approvalManager.Get("deployment/xxxx/dep-1:1.1.2")
+	if err != nil {
+		t.Fatalf("failed to get approval: %s", err)
+	}
+	if approval.VotesRequired != 1 {
+		t.Errorf("expected 1 required vote, got %d", approval.VotesRequired)
+	}
+	if !strings.HasSuffix(approval.Message, "Image has 2 vulnerabilities of medium or higher severity (2 high).") {
+		t.Errorf("unexpected approval message: %s", approval.Message)
+	}
+}
+
+func TestProcessEventNotScanned(t *testing.T) {
+	provider, fp, _, teardown := newSignatureTestProvider(t, nil)
+	defer teardown()
+
+	provider.registryClient = &fakeRegistryClient{digest: testDigest}
+	s := &fakeScan{err: scanner.ErrNotScanned}
+	provider.scan = s.scan
+	provider.SetVulnerabilityGate("critical", VulnerabilityActionBlock)
+
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated before image is scanned")
+	}
+	if len(provider.deferred.identifiers()) != 1 {
+		t.Errorf("expected update to be deferred")
+	}
+
+	// no scanner supports the registry
+	s.err = scanner.ErrNoScanner
+	if _, err := provider.processEvent(vulnerabilityTestEvent); err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+	if fp.updated == nil {
+		t.Fatalf("expected resource to be updated when no scanner is available")
+	}
+}
+
+func TestVulnerabilityGate(t *testing.T) {
+	tests := []struct {
+		labels, annotations map[string]string
+		threshold, action   string
+		wantOk              bool
+		wantSeverity        scanner.Severity
+		wantAction          string
+	}{
+		{wantOk: false},
+		{threshold: "high", wantOk: true, wantSeverity: scanner.SeverityHigh, wantAction: VulnerabilityActionBlock},
+		{threshold: "high", action: VulnerabilityActionApprove, wantOk: true, wantSeverity: scanner.SeverityHigh, wantAction: VulnerabilityActionApprove},
+		{annotations: map[string]string{types.KeelVulnerabilityThresholdAnnotation: "none"}, threshold: "high", wantOk: false},
+		{labels: map[string]string{types.KeelVulnerabilityThresholdAnnotation: "Critical"}, wantOk: true, wantSeverity: scanner.SeverityCritical, wantAction: VulnerabilityActionBlock},
+		{annotations: map[string]string{types.KeelVulnerabilityThresholdAnnotation: "low"}, labels: map[string]string{types.KeelVulnerabilityThresholdAnnotation: "critical"}, wantOk: true, wantSeverity: scanner.SeverityLow, wantAction: VulnerabilityActionBlock},
+		{annotations: map[string]string{types.KeelVulnerabilityThresholdAnnotation: "severe"}, wantOk: true, wantSeverity: scanner.SeverityCritical, wantAction: VulnerabilityActionBlock},
+	}
+	for i, tt := range tests {
+		severity, action, ok := vulnerabilityGate(tt.labels, tt.annotations, tt.threshold, tt.action)
+		if ok != tt.wantOk || (ok && (severity != tt.wantSeverity || action != tt.wantAction)) {
+			t.Errorf("%d: unexpected gate %s %s %t", i, severity, action, ok)
+		}
+	}
+}
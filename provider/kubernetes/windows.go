@@ -0,0 +1,174 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/internal/window"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deferredCheckInterval - how often deferred updates are checked against their maintenance windows
+var deferredCheckInterval = time.Minute
+
+// deferredUpdates - updates that are waiting for their maintenance window,
+// keyed by resource identifier
+type deferredUpdates struct {
+	mu      sync.Mutex
+	round   int
+	entries map[string]*deferredUpdate
+}
+
+type deferredUpdate struct {
+	event *types.Event
+	// round - last processing round when update was still deferred
+	round int
+}
+
+// add - queues event for a resource, returns false if update for this version was already queued
+func (d *deferredUpdates) add(identifier string, event *types.Event) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries == nil {
+		d.entries = make(map[string]*deferredUpdate)
+	}
+	existing, ok := d.entries[identifier]
+	d.entries[identifier] = &deferredUpdate{event: event, round: d.round}
+	return !ok || existing.event.Repository.String() != event.Repository.String()
+}
+
+func (d *deferredUpdates) remove(identifier string) {
+	d.mu.Lock()
+	delete(d.entries, identifier)
+	d.mu.Unlock()
+}
+
+// identifiers - returns identifiers of resources with queued updates
+func (d *deferredUpdates) identifiers() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var identifiers []string
+	for identifier := range d.entries {
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+// next - starts new processing round and returns queued events
+func (d *deferredUpdates) next() []*types.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.round++
+	var events []*types.Event
+	seen := make(map[*types.Event]bool)
+	for _, entry := range d.entries {
+		// several resources might be waiting for the same event
+		if !seen[entry.event] {
+			seen[entry.event] = true
+			events = append(events, entry.event)
+		}
+	}
+	return events
+}
+
+// prune - removes updates that were not deferred again during the current
+// round, for example resource got deleted or updated to a newer version
+func (d *deferredUpdates) prune() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for identifier, entry := range d.entries {
+		if entry.round < d.round {
+			delete(d.entries, identifier)
+		}
+	}
+}
+
+// getUpdateWindow - gets maintenance window from resource annotations or labels
+func getUpdateWindow(labels map[string]string, annotations map[string]string) (*window.Window, error) {
+	val, ok := annotations[types.KeelUpdateWindowAnnotation]
+	if !ok {
+		val, ok = labels[types.KeelUpdateWindowAnnotation]
+	}
+	if !ok {
+		return nil, nil
+	}
+	return window.Parse(val)
+}
+
+// checkForWindows - filters out plans for resources that are outside of their maintenance
+// window, these updates are queued and applied once window opens
+func (p *Provider) checkForWindows(event *types.Event, plans []*UpdatePlan) (allowedPlans []*UpdatePlan) {
+	allowedPlans = []*UpdatePlan{}
+	now := timeutil.Now()
+
+	for _, plan := range plans {
+		resource := plan.Resource
+		w, err := getUpdateWindow(resource.GetLabels(), resource.GetAnnotations())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"name":      resource.Name,
+				"namespace": resource.Namespace,
+			}).Error("provider.kubernetes: failed to parse update window, skipping update")
+			continue
+		}
+
+		if w == nil || w.Contains(now) {
+			p.deferred.remove(resource.Identifier)
+			allowedPlans = append(allowedPlans, plan)
+			continue
+		}
+
+		if !p.deferred.add(resource.Identifier, event) {
+			continue
+		}
+
+		next := w.Next(now)
+		log.WithFields(log.Fields{
+			"name":      resource.Name,
+			"kind":      resource.Kind(),
+			"namespace": resource.Namespace,
+			"window":    w.String(),
+			"next":      next,
+		}).Info("provider.kubernetes: resource is outside of its update window, deferring update")
+
+		p.sender.Send(types.EventNotification{
+			ResourceKind: resource.Kind(),
+			Identifier:   resource.Identifier,
+			Name:         "deferred update",
+			Message:      fmt.Sprintf("Update of %s %s/%s %s->%s deferred until update window opens at %s", resource.Kind(), resource.Namespace, resource.Name, plan.CurrentVersion, plan.NewVersion, next.Format(time.RFC3339)),
+			CreatedAt:    now,
+			Type:         types.NotificationPreDeploymentUpdate,
+			Level:        types.LevelInfo,
+			Channels:     p.notificationChannels(resource),
+			Webhooks:     p.notificationWebhooks(resource),
+			Metadata: map[string]string{
+				"provider":  p.GetName(),
+				"namespace": resource.GetNamespace(),
+				"name":      resource.GetName(),
+			},
+		})
+	}
+
+	return allowedPlans
+}
+
+// processDeferred - re-processes deferred events, updates are applied
+// for resources whose update windows are now open
+func (p *Provider) processDeferred() {
+	for _, event := range p.deferred.next() {
+		_, err := p.processEvent(event)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"image": event.Repository.Name,
+				"tag":   event.Repository.Tag,
+			}).Error("provider.kubernetes: failed to process deferred event")
+		}
+	}
+	p.deferred.prune()
+}
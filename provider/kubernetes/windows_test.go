@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/internal/k8s"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/timeutil"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProcessEventUpdateWindow(t *testing.T) {
+	defer func() { timeutil.Now = time.Now }()
+
+	fp := &fakeImplementer{}
+	sender := &fakeSender{}
+
+	grc := &k8s.GenericResourceCache{}
+	grc.Add(MustParseGR(&apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "dep-1",
+			Namespace: "xxxx",
+			Labels:    map[string]string{types.KeelPolicyLabel: "all"},
+			Annotations: map[string]string{
+				types.KeelUpdateWindowAnnotation: "Mon-Fri 22:00-04:00 UTC",
+			},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image: "gcr.io/v2-namespace/hello-world:1.1.1",
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	approver, teardown := approver()
+	defer teardown()
+	provider, err := NewProvider(fp, sender, approver, grc)
+	if err != nil {
+		t.Fatalf("failed to get provider: %s", err)
+	}
+
+	// Monday noon, outside of the window
+	timeutil.Now = func() time.Time { return time.Date(2018, 6, 4, 12, 0, 0, 0, time.UTC) }
+
+	_, err = provider.processEvent(&types.Event{Repository: types.Repository{
+		Name: "gcr.io/v2-namespace/hello-world",
+		Tag:  "1.1.2",
+	}})
+	if err != nil {
+		t.Fatalf("got error while processing event: %s", err)
+	}
+
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated outside of the window")
+	}
+	if sender.sentEvent.Name != "deferred update" {
+		t.Errorf("expected deferred update notification, got: %s", sender.sentEvent.Name)
+	}
+
+	// still outside
+	provider.processDeferred()
+	if fp.updated != nil {
+		t.Fatalf("resource shouldn't be updated outside of the window")
+	}
+
+	// Monday night, window is open
+	timeutil.Now = func() time.Time { return time.Date(2018, 6, 4, 23, 0, 0, 0, time.UTC) }
+	provider.processDeferred()
+
+	if fp.updated == nil {
+		t.Fatalf("resource was not updated once window opened")
+	}
+	if fp.updated.Containers()[0].Image != "gcr.io/v2-namespace/hello-world:1.1.2" {
+		t.Errorf("unexpected image: %s", fp.updated.Containers()[0].Image)
+	}
+	if len(provider.deferred.next()) != 0 {
+		t.Errorf("expected deferred queue to be empty")
+	}
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"sync"
 
 	"github.com/keel-hq/keel/approvals"
 	"github.com/keel-hq/keel/types"
@@ -23,6 +24,16 @@ type Providers interface {
 	TrackedImages() ([]*types.TrackedImage, error)
 	List() []string // list all providers
 	Stop()          // stop all providers
+
+	Pause()       // stop applying updates, events are recorded
+	Resume()      // resume applying updates, recorded events are replayed
+	Paused() bool // whether updates are paused
+}
+
+// Elector - reports whether this replica is the leader, only the leader
+// processes events when several replicas are running
+type Elector interface {
+	IsLeader() bool
 }
 
 // New - new providers registry
@@ -52,6 +63,14 @@ type DefaultProviders struct {
 	providers        map[string]Provider
 	approvalsManager approvals.Manager
 	stopCh           chan struct{}
+
+	elector Elector
+
+	mu     sync.Mutex
+	paused bool
+	// skipped - events received while paused, keyed by image reference
+	skipped      map[string]types.Event
+	skippedOrder []string
 }
 
 func (p *DefaultProviders) subscribeToApproved() {
@@ -77,8 +96,30 @@ func (p *DefaultProviders) subscribeToApproved() {
 
 }
 
+// SetElector - sets leader elector, events are ignored while this replica
+// is not the leader
+func (p *DefaultProviders) SetElector(elector Elector) {
+	p.elector = elector
+}
+
 // Submit - submit event to all providers
 func (p *DefaultProviders) Submit(event types.Event) error {
+	if p.elector != nil && !p.elector.IsLeader() {
+		log.WithFields(log.Fields{
+			"event":   event.Repository,
+			"trigger": event.TriggerName,
+		}).Debug("provider.Submit: not a leader, ignoring event")
+		return nil
+	}
+
+	if p.record(event) {
+		log.WithFields(log.Fields{
+			"event":   event.Repository,
+			"trigger": event.TriggerName,
+		}).Info("provider.Submit: updates are paused, event recorded")
+		return nil
+	}
+
 	for _, provider := range p.providers {
 		err := provider.Submit(event)
 		if err != nil {
@@ -127,3 +168,60 @@ func (p *DefaultProviders) Stop() {
 		provider.Stop()
 	}
 }
+
+// record - records event if updates are paused, returns false if
+// event should be submitted
+func (p *DefaultProviders) record(event types.Event) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return false
+	}
+	if p.skipped == nil {
+		p.skipped = make(map[string]types.Event)
+	}
+	key := event.Repository.String()
+	if _, ok := p.skipped[key]; !ok {
+		p.skippedOrder = append(p.skippedOrder, key)
+	}
+	p.skipped[key] = event
+	return true
+}
+
+// Pause - pauses all providers, incoming events are recorded and
+// replayed once providers are resumed
+func (p *DefaultProviders) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+	log.Info("provider.defaultProviders: updates paused")
+}
+
+// Resume - resumes providers and replays events that were recorded
+// while paused
+func (p *DefaultProviders) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	var events []types.Event
+	for _, key := range p.skippedOrder {
+		events = append(events, p.skipped[key])
+	}
+	p.skipped = nil
+	p.skippedOrder = nil
+	p.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"skipped": len(events),
+	}).Info("provider.defaultProviders: updates resumed, replaying skipped events")
+
+	for _, event := range events {
+		p.Submit(event)
+	}
+}
+
+// Paused - returns true if updates are paused
+func (p *DefaultProviders) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
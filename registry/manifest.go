@@ -0,0 +1,213 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+// EnvPlatforms - comma separated list of platforms (os/arch[/variant]) images are selected
+// for from multi-arch manifest lists, defaults to platforms of cluster nodes
+const EnvPlatforms = "REGISTRY_PLATFORMS"
+
+// manifest media types
+const (
+	mediaTypeOCIIndex         = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifestList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest      = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeManifestV2       = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeSignedManifestV1 = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+)
+
+// Platform - operating system and architecture an image is built for
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// matches - variant is only compared when it's set, arm64 images without variant are v8
+func (p Platform) matches(other Platform) bool {
+	if p.OS != other.OS || p.Architecture != other.Architecture {
+		return false
+	}
+	if p.Variant == "" {
+		return true
+	}
+	variant := other.Variant
+	if variant == "" && other.Architecture == "arm64" {
+		variant = "v8"
+	}
+	return p.Variant == variant
+}
+
+// ParsePlatforms - parses comma separated list of os/arch[/variant] platforms
+func ParsePlatforms(s string) ([]Platform, error) {
+	var platforms []Platform
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", entry)
+		}
+		p := Platform{OS: parts[0], Architecture: parts[1]}
+		if len(parts) == 3 {
+			p.Variant = parts[2]
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, nil
+}
+
+var (
+	platformsM sync.RWMutex
+	platforms  = []Platform{{OS: "linux", Architecture: "amd64"}}
+)
+
+// SetPlatforms - platforms of cluster nodes, images for the first one are selected from
+// manifest lists. When several platforms are set, digests of manifest lists are used
+// so pinned images can be pulled by all nodes
+func SetPlatforms(p []Platform) {
+	if len(p) == 0 {
+		return
+	}
+	platformsM.Lock()
+	defer platformsM.Unlock()
+	platforms = p
+}
+
+// Platforms - configured platforms
+func Platforms() []Platform {
+	platformsM.RLock()
+	defer platformsM.RUnlock()
+	return platforms
+}
+
+type manifestList struct {
+	Manifests []struct {
+		MediaType string   `json:"mediaType"`
+		Digest    string   `json:"digest"`
+		Platform  Platform `json:"platform"`
+	} `json:"manifests"`
+}
+
+// resolvedManifest - image manifest of the tag, for manifest lists the manifest of
+// the configured platform
+type resolvedManifest struct {
+	// Digest - digest identifying the image for the configured platforms, digest of
+	// manifest list when several platforms are configured
+	Digest string
+	// ManifestDigest - digest of the platform manifest
+	ManifestDigest string
+	MediaType      string
+	Body           []byte
+}
+
+// resolveManifest - fetches manifest of the reference, selects image for the configured
+// platform if the reference is a manifest list or OCI image index
+func resolveManifest(hub *registry.Registry, name, reference string) (*resolvedManifest, error) {
+	digest, mediaType, body, err := fetchManifest(hub, name, reference, []string{
+		mediaTypeOCIIndex,
+		mediaTypeManifestList,
+		mediaTypeOCIManifest,
+		mediaTypeManifestV2,
+		mediaTypeSignedManifestV1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType != mediaTypeOCIIndex && mediaType != mediaTypeManifestList {
+		return &resolvedManifest{Digest: digest, ManifestDigest: digest, MediaType: mediaType, Body: body}, nil
+	}
+
+	var list manifestList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest list of %s:%s: %s", name, reference, err)
+	}
+
+	configured := Platforms()
+	platform := configured[0]
+	for _, m := range list.Manifests {
+		if !platform.matches(m.Platform) {
+			continue
+		}
+		manifestDigest, mediaType, body, err := fetchManifest(hub, name, m.Digest, []string{m.MediaType})
+		if err != nil {
+			return nil, err
+		}
+		resolved := &resolvedManifest{Digest: manifestDigest, ManifestDigest: manifestDigest, MediaType: mediaType, Body: body}
+		if len(configured) > 1 {
+			resolved.Digest = digest
+		}
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("%s:%s has no image for platform %s", name, reference, platform)
+}
+
+// fetchManifest - gets manifest, its media type and digest (computed from the body if
+// registry doesn't return Docker-Content-Digest)
+func fetchManifest(hub *registry.Registry, name, reference string, accept []string) (digest, mediaType string, body []byte, err error) {
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", strings.TrimSuffix(hub.URL, "/"), name, reference)
+	hub.Logf("registry.manifest.get url=%s", url)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", nil, err
+	}
+	req.Header.Set("Accept", strings.Join(accept, ", "))
+
+	resp, err := hub.Client.Do(req)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	mediaType = resp.Header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	// some registries serve manifests as application/json, media type is in the manifest
+	if mediaType == "" || mediaType == "application/json" || mediaType == "text/plain" {
+		var m struct {
+			MediaType string `json:"mediaType"`
+			Manifests []json.RawMessage
+		}
+		if err := json.Unmarshal(body, &m); err == nil {
+			mediaType = m.MediaType
+			if mediaType == "" && len(m.Manifests) > 0 {
+				mediaType = mediaTypeOCIIndex
+			}
+		}
+	}
+
+	digest = resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return digest, mediaType, body, nil
+}
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const arm64Manifest = `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "config": {"digest": "sha256:arm64config"}}`
+
+func newMultiArchServer(t *testing.T) *httptest.Server {
+	amd64 := "sha256:" + strings.Repeat("a", 64)
+	arm64 := "sha256:" + strings.Repeat("b", 64)
+	index := "sha256:" + strings.Repeat("c", 64)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/app/manifests/1.0.0":
+			if !strings.Contains(r.Header.Get("Accept"), mediaTypeOCIIndex) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", mediaTypeOCIIndex)
+			w.Header().Set("Docker-Content-Digest", index)
+			fmt.Fprintf(w, `{"schemaVersion": 2, "mediaType": %q, "manifests": [
+				{"mediaType": %q, "digest": %q, "platform": {"os": "linux", "architecture": "amd64"}},
+				{"mediaType": %q, "digest": %q, "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+				{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:attestation", "platform": {"os": "unknown", "architecture": "unknown"}}
+			]}`, mediaTypeOCIIndex, mediaTypeOCIManifest, amd64, mediaTypeOCIManifest, arm64)
+		case "/v2/org/app/manifests/" + amd64:
+			w.Header().Set("Content-Type", mediaTypeOCIManifest)
+			w.Header().Set("Docker-Content-Digest", amd64)
+			fmt.Fprint(w, `{"schemaVersion": 2, "config": {"digest": "sha256:amd64config"}}`)
+		case "/v2/org/app/manifests/" + arm64:
+			// registry without content type and digest headers
+			fmt.Fprint(w, arm64Manifest)
+		case "/v2/org/app/blobs/sha256:amd64config":
+			fmt.Fprint(w, `{"created": "2023-01-02T00:00:00Z"}`)
+		case "/v2/org/app/blobs/sha256:arm64config":
+			fmt.Fprint(w, `{"created": "2023-01-03T00:00:00Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDigestMultiArch(t *testing.T) {
+	srv := newMultiArchServer(t)
+	defer srv.Close()
+	defer SetPlatforms(Platforms())
+
+	c := New()
+	opts := Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0"}
+
+	SetPlatforms([]Platform{{OS: "linux", Architecture: "amd64"}})
+	digest, err := c.Digest(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if digest != "sha256:"+strings.Repeat("a", 64) {
+		t.Errorf("expected amd64 image digest, got %s", digest)
+	}
+
+	SetPlatforms([]Platform{{OS: "linux", Architecture: "arm64"}})
+	digest, err = c.Digest(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// digest is computed from manifest when registry doesn't return it
+	sum := sha256.Sum256([]byte(arm64Manifest))
+	if digest != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Errorf("expected arm64 image digest, got %s", digest)
+	}
+	created, err := c.Created(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !created.Equal(time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected arm64 image creation time, got %s", created)
+	}
+
+	// nodes of several platforms pull pinned image by manifest list digest
+	SetPlatforms([]Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}})
+	digest, err = c.Digest(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if digest != "sha256:"+strings.Repeat("c", 64) {
+		t.Errorf("expected index digest, got %s", digest)
+	}
+	created, err = c.Created(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !created.Equal(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected amd64 image creation time, got %s", created)
+	}
+
+	SetPlatforms([]Platform{{OS: "windows", Architecture: "amd64"}})
+	if _, err := c.Digest(opts); err == nil || !strings.Contains(err.Error(), "no image for platform windows/amd64") {
+		t.Errorf("expected missing platform error, got %v", err)
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := ParsePlatforms("linux/amd64, linux/arm/v7")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(platforms) != 2 || platforms[1] != (Platform{OS: "linux", Architecture: "arm", Variant: "v7"}) {
+		t.Errorf("unexpected platforms: %v", platforms)
+	}
+	if platforms[1].String() != "linux/arm/v7" {
+		t.Errorf("unexpected string: %s", platforms[1])
+	}
+
+	for _, invalid := range []string{"linux", "linux/", "linux/arm/v7/x"} {
+		if _, err := ParsePlatforms(invalid); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
+
+func TestPlatformMatches(t *testing.T) {
+	tests := []struct {
+		platform, image Platform
+		want            bool
+	}{
+		{Platform{OS: "linux", Architecture: "amd64"}, Platform{OS: "linux", Architecture: "amd64"}, true},
+		{Platform{OS: "linux", Architecture: "arm"}, Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, true},
+		{Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, false},
+		{Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, Platform{OS: "linux", Architecture: "arm64"}, true},
+		{Platform{OS: "linux", Architecture: "amd64"}, Platform{OS: "windows", Architecture: "amd64"}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.platform.matches(tt.image); got != tt.want {
+			t.Errorf("%d: expected %t, got %t", i, tt.want, got)
+		}
+	}
+}
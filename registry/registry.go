@@ -1,20 +1,31 @@
 package registry
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"hash/fnv"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rusenask/docker-registry-client/registry"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// EnvInsecure - uses insecure registry client to skip cert verification
+// EnvInsecure - uses insecure registry client to skip cert verification, "true" for all
+// registries or a comma separated list of registry hosts
 const EnvInsecure = "INSECURE_REGISTRY"
 
+// EnvMaxTags - maximum number of tags listed per repository, newest tags are listed
+// where registry supports it (Docker Hub, GCR, Artifact Registry). Unlimited by default
+const EnvMaxTags = "REGISTRY_MAX_TAGS"
+
 // errors
 var (
 	ErrTagNotSupplied = errors.New("tag not supplied")
@@ -30,18 +41,46 @@ type Repository struct {
 type Client interface {
 	Get(opts Opts) (*Repository, error)
 	Digest(opts Opts) (string, error)
+	Created(opts Opts) (time.Time, error)
 }
 
 // New - new registry client
 func New() *DefaultClient {
-	insecure := false
-	if os.Getenv(EnvInsecure) == "true" {
-		insecure = true
+	certsDir := os.Getenv(EnvCertsDir)
+	if certsDir == "" {
+		certsDir = DefaultCertsDir
+	}
+	maxTags, err := strconv.Atoi(os.Getenv(EnvMaxTags))
+	if err != nil && os.Getenv(EnvMaxTags) != "" {
+		log.WithFields(log.Fields{
+			"error": err,
+			"value": os.Getenv(EnvMaxTags),
+		}).Warn("registry.New: invalid maximum number of tags, listing all tags")
+	}
+	mirrors, err := parseMirrors(os.Getenv(EnvMirrors))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"value": os.Getenv(EnvMirrors),
+		}).Error("registry.New: invalid registry mirrors, mirrors are not used")
+	}
+	proxy, err := proxyFunc(os.Getenv(EnvProxy), os.Getenv(EnvNoProxy))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"value": os.Getenv(EnvProxy),
+		}).Error("registry.New: invalid registry proxy, using HTTPS_PROXY")
+		proxy, _ = proxyFunc("", os.Getenv(EnvNoProxy))
 	}
 	return &DefaultClient{
 		mu:         &sync.Mutex{},
 		registries: make(map[uint32]*registry.Registry),
-		insecure:   insecure,
+		insecure:   parseInsecureRegistries(os.Getenv(EnvInsecure)),
+		certsDir:   certsDir,
+		maxTags:    maxTags,
+		mirrors:    mirrors,
+		proxy:      proxy,
+		hubClient:  &http.Client{Timeout: 30 * time.Second, Transport: newTransport(proxy, nil)},
 	}
 }
 
@@ -50,7 +89,15 @@ type DefaultClient struct {
 	// a map of registries to reuse for polling
 	mu         *sync.Mutex
 	registries map[uint32]*registry.Registry
-	insecure   bool
+	insecure   insecureRegistries
+	// certsDir - per registry CA and client certificates, see EnvCertsDir
+	certsDir string
+	// maxTags - limits number of listed tags, 0 lists all of them
+	maxTags   int
+	hubClient *http.Client
+	// mirrors - registries (by host) that are accessed through mirrors
+	mirrors map[string]*mirror
+	proxy   func(*http.Request) (*url.URL, error)
 }
 
 // Opts - registry client opts. If username & password are not supplied
@@ -77,20 +124,33 @@ func (c *DefaultClient) getRegistryClient(registryAddress, username, password st
 
 	var r *registry.Registry
 
+	if m := c.mirrorFor(registryAddress); m != nil {
+		mirrorAddress := m.url
+		// HTTP fallback of insecure registries applies to their mirror
+		if strings.HasPrefix(registryAddress, "http://") {
+			mirrorAddress = strings.Replace(mirrorAddress, "https://", "http://", 1)
+		}
+		registryAddress, username, password = mirrorAddress, m.username, m.password
+	}
+
 	h := hash(registryAddress + username + password)
 	r, ok := c.registries[h]
 	if ok {
 		return r, nil
 	}
 
-	url := strings.TrimSuffix(registryAddress, "/")
-	if os.Getenv(EnvInsecure) == "true" {
-		r = registry.NewInsecure(url, username, password)
-	} else {
-		r = registry.New(url, username, password)
+	tlsConfig, err := c.tlsConfig(registryAddress)
+	if err != nil {
+		return nil, err
 	}
 
-	r.Logf = LogFormatter
+	registryURL := strings.TrimSuffix(registryAddress, "/")
+	transport := newTransport(c.proxy, tlsConfig)
+	r = &registry.Registry{
+		URL:    registryURL,
+		Client: &http.Client{Transport: wrapTransport(transport, registryURL, username, password)},
+		Logf:   LogFormatter,
+	}
 
 	c.registries[h] = r
 
@@ -107,9 +167,21 @@ INIT_CLIENT:
 		return nil, err
 	}
 
-	tags, err := hub.Tags(opts.Name)
+	// Docker Hub registry API lists tags alphabetically, its API can list the newest ones
+	if c.maxTags > 0 && isDockerHub(opts.Registry) && c.mirrorFor(opts.Registry) == nil {
+		tags, err := listHubTags(c.hubClient, opts.Name, opts.Username, opts.Password, c.maxTags)
+		if err == nil {
+			return &Repository{Tags: tags}, nil
+		}
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  opts.Name,
+		}).Debug("registry.Get: failed to list newest tags through Docker Hub API, using registry API")
+	}
+
+	tags, err := listTags(hub, opts.Name, c.maxTags)
 	if err != nil {
-		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.isInsecure(opts.Registry) {
 			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
 			goto INIT_CLIENT
 		}
@@ -122,7 +194,8 @@ INIT_CLIENT:
 	return repo, nil
 }
 
-// Digest - get digest for repo
+// Digest - get digest for repo, digest of the image for configured platform is returned
+// for multi-arch images (see SetPlatforms)
 func (c *DefaultClient) Digest(opts Opts) (string, error) {
 	if opts.Tag == "" {
 		return "", ErrTagNotSupplied
@@ -135,14 +208,122 @@ INIT_CLIENT:
 		return "", err
 	}
 
-	manifestDigest, err := hub.ManifestDigest(opts.Name, opts.Tag)
+	manifest, err := resolveManifest(hub, opts.Name, opts.Tag)
 	if err != nil {
-		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.insecure {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.isInsecure(opts.Registry) {
 			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
 			goto INIT_CLIENT
 		}
 		return "", err
 	}
 
-	return manifestDigest.String(), nil
+	return manifest.Digest, nil
+}
+
+// imageConfig - subset of image configuration blob
+type imageConfig struct {
+	Created time.Time `json:"created"`
+}
+
+// imageManifest - subset of schema2/OCI manifest and schema1 signed manifest
+type imageManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	History []struct {
+		V1Compatibility string `json:"v1Compatibility"`
+	} `json:"history"`
+}
+
+// Created - get image creation time from its configuration blob, schema1 manifests
+// are checked for registries that don't serve schema2 ones. Image for configured
+// platform is checked for multi-arch images
+func (c *DefaultClient) Created(opts Opts) (time.Time, error) {
+	if opts.Tag == "" {
+		return time.Time{}, ErrTagNotSupplied
+	}
+
+	// fallback to HTTP if the registry doesn't speak HTTPS https://github.com/keel-hq/keel/issues/331
+INIT_CLIENT:
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	resolved, err := resolveManifest(hub, opts.Name, opts.Tag)
+	if err != nil {
+		if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") && strings.HasPrefix(opts.Registry, "https://") && c.isInsecure(opts.Registry) {
+			opts.Registry = strings.Replace(opts.Registry, "https://", "http://", 1)
+			goto INIT_CLIENT
+		}
+		return time.Time{}, err
+	}
+
+	var manifest imageManifest
+	if err := json.Unmarshal(resolved.Body, &manifest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode manifest of %s:%s: %s", opts.Name, opts.Tag, err)
+	}
+
+	var config imageConfig
+	if manifest.Config.Digest != "" {
+		err = getJSON(hub, fmt.Sprintf("%s/v2/%s/blobs/%s", strings.TrimSuffix(hub.URL, "/"), opts.Name, manifest.Config.Digest), &config)
+		if err != nil {
+			return time.Time{}, err
+		}
+	} else {
+		if len(manifest.History) == 0 {
+			return time.Time{}, fmt.Errorf("manifest of %s:%s has no history", opts.Name, opts.Tag)
+		}
+		err = json.Unmarshal([]byte(manifest.History[0].V1Compatibility), &config)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if config.Created.IsZero() {
+		return time.Time{}, fmt.Errorf("creation time of %s:%s is not available", opts.Name, opts.Tag)
+	}
+	return config.Created, nil
+}
+
+func getJSON(hub *registry.Registry, url string, response interface{}) error {
+	hub.Logf("registry.blob.get url=%s", url)
+
+	resp, err := hub.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// StatusCode - returns HTTP status code of registry error response, 0 if error isn't
+// a registry response
+func StatusCode(err error) int {
+	var statusErr *registry.HttpStatusError
+	if errors.As(err, &statusErr) && statusErr.Response != nil {
+		return statusErr.Response.StatusCode
+	}
+	return 0
+}
+
+// RetryAfter - returns delay requested by registry in Retry-After header of error response
+func RetryAfter(err error) time.Duration {
+	var statusErr *registry.HttpStatusError
+	if !errors.As(err, &statusErr) || statusErr.Response == nil {
+		return 0
+	}
+	value := statusErr.Response.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestDigest(t *testing.T) {
@@ -315,4 +316,69 @@ var tagsResp = `{
 	}
 	
 
-	
\ No newline at end of file
+	
+func TestCreated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/keelhq/keel/manifests/0.8.0":
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			fmt.Fprintln(w, `{
+				"schemaVersion": 2,
+				"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+				"config": {
+					"mediaType": "application/vnd.docker.container.image.v1+json",
+					"size": 1512,
+					"digest": "sha256:8247920c5cbae94a266528c46aa796a3f5bcdd6dddc8cdc95382d6210fb3ba29"
+				},
+				"layers": []
+			}`)
+		case "/v2/keelhq/keel/blobs/sha256:8247920c5cbae94a266528c46aa796a3f5bcdd6dddc8cdc95382d6210fb3ba29":
+			fmt.Fprintln(w, `{"architecture": "amd64", "created": "2018-01-15T20:42:14.793524683Z"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := New()
+	created, err := client.Created(Opts{
+		Registry: ts.URL,
+		Name:     "keelhq/keel",
+		Tag:      "0.8.0",
+	})
+	if err != nil {
+		t.Fatalf("error while getting creation time: %s", err)
+	}
+
+	if created.Format(time.RFC3339) != "2018-01-15T20:42:14Z" {
+		t.Errorf("unexpected creation time: %s", created)
+	}
+}
+
+func TestStatusCodeAndRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := New()
+	_, err := client.Get(Opts{
+		Registry: srv.URL,
+		Name:     "karolisr/keel",
+		Tag:      "0.2.2",
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if code := StatusCode(err); code != http.StatusTooManyRequests {
+		t.Errorf("unexpected status code: %d (error: %s)", code, err)
+	}
+	if retry := RetryAfter(err); retry != 7*time.Second {
+		t.Errorf("unexpected retry after: %s", retry)
+	}
+
+	if StatusCode(fmt.Errorf("connection refused")) != 0 || RetryAfter(fmt.Errorf("connection refused")) != 0 {
+		t.Errorf("expected no status for non HTTP errors")
+	}
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+// manifest media types accepted when resolving digests of signed images, cosign signs
+// the digest of image index for multi-arch images
+var manifestMediaTypes = []string{
+	mediaTypeOCIIndex,
+	mediaTypeManifestList,
+	mediaTypeOCIManifest,
+	mediaTypeManifestV2,
+}
+
+// Signature - layer of cosign signature image, payload is the signed JSON document and
+// annotations hold signature, certificate and transparency log bundle
+type Signature struct {
+	Payload     []byte
+	Annotations map[string]string
+}
+
+type signatureManifest struct {
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// Signatures - resolves digest of the image tag and returns cosign signatures stored in
+// sha256-<digest>.sig tag of the repository, no signatures are returned for unsigned images
+func (c *DefaultClient) Signatures(opts Opts) (digest string, signatures []*Signature, err error) {
+	if opts.Tag == "" {
+		return "", nil, ErrTagNotSupplied
+	}
+
+	hub, err := c.getRegistryClient(opts.Registry, opts.Username, opts.Password)
+	if err != nil {
+		return "", nil, err
+	}
+
+	digest, err = manifestDigest(hub, opts.Name, opts.Tag)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sigTag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	body, err := get(hub, fmt.Sprintf("%s/v2/%s/manifests/%s", hub.URL, opts.Name, sigTag), manifestMediaTypes[2:])
+	if err != nil {
+		if StatusCode(err) == http.StatusNotFound {
+			return digest, nil, nil
+		}
+		return "", nil, err
+	}
+
+	var manifest signatureManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return "", nil, fmt.Errorf("failed to decode signature manifest %s: %s", sigTag, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		payload, err := get(hub, fmt.Sprintf("%s/v2/%s/blobs/%s", hub.URL, opts.Name, layer.Digest), nil)
+		if err != nil {
+			return "", nil, err
+		}
+		sum := sha256.Sum256(payload)
+		if layer.Digest != "sha256:"+hex.EncodeToString(sum[:]) {
+			return "", nil, fmt.Errorf("signature payload doesn't match digest %s", layer.Digest)
+		}
+		signatures = append(signatures, &Signature{Payload: payload, Annotations: layer.Annotations})
+	}
+
+	return digest, signatures, nil
+}
+
+// manifestDigest - digest of manifest (or manifest list) the tag points to
+func manifestDigest(hub *registry.Registry, name, tag string) (string, error) {
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", hub.URL, name, tag)
+	hub.Logf("registry.manifest.head url=%s", url)
+
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := hub.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry didn't return digest of %s:%s", name, tag)
+	}
+	return digest, nil
+}
+
+func get(hub *registry.Registry, url string, accept []string) ([]byte, error) {
+	hub.Logf("registry.get url=%s", url)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	resp, err := hub.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
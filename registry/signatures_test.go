@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignatures(t *testing.T) {
+	payload := []byte(`{"critical":{}}`)
+	sum := sha256.Sum256(payload)
+	layerDigest := "sha256:" + hex.EncodeToString(sum[:])
+	imageDigest := "sha256:" + fmt.Sprintf("%064d", 1)
+	unsignedDigest := "sha256:" + fmt.Sprintf("%064d", 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/app/manifests/1.0.0":
+			if r.Method != http.MethodHead || r.Header.Get("Accept") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", imageDigest)
+		case "/v2/org/app/manifests/2.0.0":
+			w.Header().Set("Docker-Content-Digest", unsignedDigest)
+		case "/v2/org/app/manifests/sha256-" + fmt.Sprintf("%064d", 1) + ".sig":
+			fmt.Fprintf(w, `{"schemaVersion": 2, "layers": [{"mediaType": "application/vnd.dev.cosign.simplesigning.v1+json", "digest": %q, "annotations": {"dev.cosignproject.cosign/signature": "c2ln"}}]}`, layerDigest)
+		case "/v2/org/app/blobs/" + layerDigest:
+			w.Write(payload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New()
+	digest, signatures, err := c.Signatures(Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if digest != imageDigest {
+		t.Errorf("unexpected digest: %s", digest)
+	}
+	if len(signatures) != 1 || string(signatures[0].Payload) != string(payload) || signatures[0].Annotations["dev.cosignproject.cosign/signature"] != "c2ln" {
+		t.Fatalf("unexpected signatures: %+v", signatures)
+	}
+
+	digest, signatures, err = c.Signatures(Opts{Registry: srv.URL, Name: "org/app", Tag: "2.0.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if digest != unsignedDigest || len(signatures) != 0 {
+		t.Errorf("expected unsigned image, got %s %v", digest, signatures)
+	}
+}
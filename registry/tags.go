@@ -0,0 +1,223 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+// tagsPageSize - number of tags requested per page, registries are free to return less
+const tagsPageSize = 100
+
+// dockerHubAPI - Docker Hub API, unlike the registry API it can list tags newest first
+var dockerHubAPI = "https://hub.docker.com"
+
+type tagsResponse struct {
+	Tags []string `json:"tags"`
+	// Manifest - GCR and Artifact Registry extension describing every image of the repository
+	Manifest map[string]struct {
+		Tags           []string `json:"tag"`
+		TimeUploadedMs string   `json:"timeUploadedMs"`
+	} `json:"manifest"`
+}
+
+// listTags - lists repository tags following Link headers, registries that don't send them
+// are paginated with the last parameter. When max is set, listing stops after max tags and
+// newest tags are returned where registry can tell which ones they are
+func listTags(hub *registry.Registry, name string, max int) ([]string, error) {
+	base := fmt.Sprintf("%s/v2/%s/tags/list", strings.TrimSuffix(hub.URL, "/"), name)
+	next := fmt.Sprintf("%s?n=%d", base, tagsPageSize)
+
+	var tags []string
+	uploaded := map[string]int64{}
+	seen := map[string]bool{}
+	requested := map[string]bool{}
+
+	for next != "" && !requested[next] {
+		requested[next] = true
+		hub.Logf("registry.tags url=%s repository=%s", next, name)
+
+		resp, err := hub.Client.Get(next)
+		if err != nil {
+			return nil, err
+		}
+		var page tagsResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tags of %s: %s", name, err)
+		}
+
+		added := 0
+		for _, tag := range page.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+				added++
+			}
+		}
+		for _, m := range page.Manifest {
+			ms, _ := strconv.ParseInt(m.TimeUploadedMs, 10, 64)
+			for _, tag := range m.Tags {
+				if ms > uploaded[tag] {
+					uploaded[tag] = ms
+				}
+			}
+		}
+
+		// upload times are needed to tell the newest tags, they come in a single response
+		if max > 0 && len(tags) >= max && len(uploaded) == 0 {
+			break
+		}
+
+		next, err = nextLink(resp, next)
+		if err != nil {
+			return nil, err
+		}
+		// a full page without a link, continuing after the last tag (registries that ignore
+		// the parameter return tags we have already seen)
+		if next == "" && len(page.Tags) >= tagsPageSize && added > 0 {
+			next = fmt.Sprintf("%s?n=%d&last=%s", base, tagsPageSize, url.QueryEscape(page.Tags[len(page.Tags)-1]))
+		}
+	}
+
+	if max > 0 && len(uploaded) > 0 {
+		sort.SliceStable(tags, func(i, j int) bool {
+			return uploaded[tags[i]] > uploaded[tags[j]]
+		})
+	}
+	if max > 0 && len(tags) > max {
+		tags = tags[:max]
+	}
+	return tags, nil
+}
+
+// RFC 5988 Link header, some registries (quay.io) don't wrap the URL in angle brackets
+// or quote the rel parameter
+var nextLinkRE = regexp.MustCompile(`^ *<?([^;>]+)>? *(?:;[^;]*)*; *rel="?next"?(?:;.*)?`)
+
+// nextLink - next page URL resolved against the current one, links can be relative or
+// point to another host (registries redirecting to storage)
+func nextLink(resp *http.Response, current string) (string, error) {
+	for _, link := range resp.Header[http.CanonicalHeaderKey("Link")] {
+		parts := nextLinkRE.FindStringSubmatch(link)
+		if parts == nil {
+			continue
+		}
+		base, err := url.Parse(current)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return "", fmt.Errorf("invalid next page link %q: %s", parts[1], err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+	return "", nil
+}
+
+// isDockerHub - whether registry address points to Docker Hub
+func isDockerHub(registryAddress string) bool {
+	host := registryAddress
+	if u, err := url.Parse(registryAddress); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "docker.io", "registry.hub.docker.com":
+		return true
+	}
+	return false
+}
+
+type hubTagsResponse struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+// listHubTags - lists up to max most recently pushed tags through Docker Hub API
+func listHubTags(client *http.Client, name, username, password string, max int) ([]string, error) {
+	token := ""
+	if username != "" && password != "" {
+		var err error
+		token, err = hubLogin(client, username, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	pageSize := tagsPageSize
+	if max < pageSize {
+		pageSize = max
+	}
+	next := fmt.Sprintf("%s/v2/repositories/%s/tags?page_size=%d&ordering=last_updated", dockerHubAPI, name, pageSize)
+
+	var tags []string
+	for next != "" && len(tags) < max {
+		req, err := http.NewRequest(http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "JWT "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page hubTagsResponse
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, next)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range page.Results {
+			tags = append(tags, result.Name)
+		}
+		next = page.Next
+	}
+
+	if len(tags) > max {
+		tags = tags[:max]
+	}
+	return tags, nil
+}
+
+func hubLogin(client *http.Client, username, password string) (string, error) {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Post(dockerHubAPI+"/v2/users/login", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Docker Hub login failed with status code %d", resp.StatusCode)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return "", err
+	}
+	return login.Token, nil
+}
@@ -0,0 +1,204 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+func numberedTags(n int) []string {
+	var tags []string
+	for i := 0; i < n; i++ {
+		tags = append(tags, fmt.Sprintf("1.0.%03d", i))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagsPage - returns page of tags after last, like docker distribution does
+func tagsPage(all []string, r *http.Request) []string {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil {
+		n = len(all)
+	}
+	last := r.URL.Query().Get("last")
+	start := sort.SearchStrings(all, last)
+	if last != "" && start < len(all) && all[start] == last {
+		start++
+	}
+	end := start + n
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end]
+}
+
+func newTestHub(url string) *registry.Registry {
+	return &registry.Registry{URL: url, Client: http.DefaultClient, Logf: registry.Quiet}
+}
+
+func TestListTagsLinkHeader(t *testing.T) {
+	all := numberedTags(250)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := tagsPage(all, r)
+		if len(page) > 0 && page[len(page)-1] != all[len(all)-1] {
+			last := page[len(page)-1]
+			// first link is relative, following ones absolute
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`</v2/foo/bar/tags/list?n=100&last=%s>; rel="next"`, last))
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`%s/v2/foo/bar/tags/list?n=100&last=%s; rel=next`, srv.URL, last))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "foo/bar", "tags": page})
+	}))
+	defer srv.Close()
+
+	tags, err := listTags(newTestHub(srv.URL), "foo/bar", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, all) {
+		t.Errorf("expected %d tags, got %d", len(all), len(tags))
+	}
+}
+
+func TestListTagsLastParameter(t *testing.T) {
+	all := numberedTags(230)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(map[string]interface{}{"tags": tagsPage(all, r)})
+	}))
+	defer srv.Close()
+
+	tags, err := listTags(newTestHub(srv.URL), "foo/bar", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, all) {
+		t.Errorf("expected %d tags, got %d", len(all), len(tags))
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+
+	// cap stops listing early
+	requests = 0
+	tags, err = listTags(newTestHub(srv.URL), "foo/bar", 150)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, all[:150]) {
+		t.Errorf("expected 150 tags, got %d", len(tags))
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestListTagsIgnoredParameters(t *testing.T) {
+	all := numberedTags(150)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(map[string]interface{}{"tags": all})
+	}))
+	defer srv.Close()
+
+	tags, err := listTags(newTestHub(srv.URL), "foo/bar", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, all) {
+		t.Errorf("expected %d tags, got %d", len(all), len(tags))
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestListTagsNewestFirst(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"name": "project/app",
+			"tags": ["1.0.0", "1.1.0", "1.2.0", "latest"],
+			"manifest": {
+				"sha256:a": {"tag": ["1.0.0"], "timeUploadedMs": "1000"},
+				"sha256:b": {"tag": ["1.2.0", "latest"], "timeUploadedMs": "3000"},
+				"sha256:c": {"tag": ["1.1.0"], "timeUploadedMs": "2000"}
+			}
+		}`)
+	}))
+	defer srv.Close()
+
+	tags, err := listTags(newTestHub(srv.URL), "project/app", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"1.2.0", "latest", "1.1.0"}) {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
+
+func TestListHubTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/users/login":
+			fmt.Fprint(w, `{"token": "secret"}`)
+		case "/v2/repositories/library/nginx/tags":
+			if r.Header.Get("Authorization") != "JWT secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("ordering") != "last_updated" {
+				t.Errorf("tags not requested newest first")
+			}
+			if r.URL.Query().Get("page") == "" {
+				fmt.Fprintf(w, `{"next": "http://%s/v2/repositories/library/nginx/tags?page=2&page_size=2&ordering=last_updated", "results": [{"name": "1.3.0"}, {"name": "latest"}]}`, r.Host)
+				return
+			}
+			fmt.Fprint(w, `{"next": null, "results": [{"name": "1.2.0"}, {"name": "1.1.0"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(api string) { dockerHubAPI = api }(dockerHubAPI)
+	dockerHubAPI = srv.URL
+
+	tags, err := listHubTags(http.DefaultClient, "nginx", "user", "pass", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"1.3.0", "latest", "1.2.0"}) {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	if _, err := listHubTags(http.DefaultClient, "nginx", "", "", 3); err == nil {
+		t.Errorf("expected error without credentials")
+	}
+}
+
+func TestIsDockerHub(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"https://index.docker.io":      true,
+		"https://registry-1.docker.io": true,
+		"docker.io":                    true,
+		"https://quay.io":              false,
+		"https://gcr.io":               false,
+	} {
+		if isDockerHub(addr) != expected {
+			t.Errorf("isDockerHub(%s) != %t", addr, expected)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvCertsDir - directory with TLS configuration of registries, laid out like Docker's
+// certs.d: <host[:port]>/*.crt are CA certificates trusted in addition to system ones,
+// <host[:port]>/*.cert and matching *.key files are client certificates
+const EnvCertsDir = "REGISTRY_CERTS_DIR"
+
+// DefaultCertsDir - default registry TLS configuration directory
+const DefaultCertsDir = "/etc/keel/certs.d"
+
+// insecureRegistries - registries with disabled certificate verification, INSECURE_REGISTRY
+// is either "true" for all registries or a comma separated list of registry hosts
+type insecureRegistries struct {
+	all   bool
+	hosts map[string]bool
+}
+
+func parseInsecureRegistries(s string) insecureRegistries {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "true":
+		return insecureRegistries{all: true}
+	case "", "false":
+		return insecureRegistries{}
+	}
+	ir := insecureRegistries{hosts: make(map[string]bool)}
+	for _, host := range strings.Split(s, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			ir.hosts[registryHost(host)] = true
+		}
+	}
+	return ir
+}
+
+// isInsecure - whether certificate verification is disabled for the registry, HTTP is
+// also tried for insecure registries that don't speak HTTPS
+func (c *DefaultClient) isInsecure(registryAddress string) bool {
+	return c.insecure.all || c.insecure.hosts[registryHost(registryAddress)]
+}
+
+// tlsConfig - TLS configuration of the registry, nil when the registry has no custom
+// configuration
+func (c *DefaultClient) tlsConfig(registryAddress string) (*tls.Config, error) {
+	host := registryAddress
+	if u, err := url.Parse(registryAddress); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	cfg, err := loadCertsDir(filepath.Join(c.certsDir, host))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration of %s: %s", host, err)
+	}
+	if c.isInsecure(registryAddress) {
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg, nil
+}
+
+// loadCertsDir - reads CA and client certificates from registry directory, nil if the
+// directory doesn't exist
+func loadCertsDir(dir string) (*tls.Config, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cfg := &tls.Config{}
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name())
+		switch {
+		case strings.HasSuffix(f.Name(), ".crt"):
+			if cfg.RootCAs == nil {
+				cfg.RootCAs, err = x509.SystemCertPool()
+				if err != nil {
+					cfg.RootCAs = x509.NewCertPool()
+				}
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if !cfg.RootCAs.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no certificates found in %s", path)
+			}
+		case strings.HasSuffix(f.Name(), ".cert"):
+			keyPath := strings.TrimSuffix(path, ".cert") + ".key"
+			cert, err := tls.LoadX509KeyPair(path, keyPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate %s: %s", path, err)
+			}
+			cfg.Certificates = append(cfg.Certificates, cert)
+		case strings.HasSuffix(f.Name(), ".key"):
+			if _, err := os.Stat(strings.TrimSuffix(path, ".key") + ".cert"); err != nil {
+				return nil, fmt.Errorf("client key %s has no matching certificate", path)
+			}
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "private CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA: %s", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue - certificate and key PEM signed by the CA
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "registry"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to issue certificate: %s", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestDigestCustomTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+
+	pair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %s", err)
+	}
+	clients := x509.NewCertPool()
+	clients.AddCert(ca.cert)
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", mediaTypeManifestV2)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Write([]byte(`{"schemaVersion": 2}`))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{pair}, ClientCAs: clients, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	certsDir, err := ioutil.TempDir("", "certs.d")
+	if err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(certsDir)
+
+	opts := Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0"}
+
+	c := New()
+	c.certsDir = certsDir
+	if _, err := c.Digest(opts); err == nil {
+		t.Fatalf("expected registry with private CA to be rejected")
+	}
+
+	dir := filepath.Join(certsDir, host)
+	os.MkdirAll(dir, 0700)
+	ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca.pem, 0600)
+	ioutil.WriteFile(filepath.Join(dir, "client.cert"), clientCert, 0600)
+	ioutil.WriteFile(filepath.Join(dir, "client.key"), clientKey, 0600)
+
+	c = New()
+	c.certsDir = certsDir
+	got, err := c.Digest(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != digest {
+		t.Errorf("unexpected digest: %s", got)
+	}
+}
+
+func TestLoadCertsDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := loadCertsDir(filepath.Join(dir, "missing"))
+	if err != nil || cfg != nil {
+		t.Errorf("expected no configuration for missing directory, got %v %v", cfg, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "client.key"), []byte("key"), 0600)
+	if _, err := loadCertsDir(dir); err == nil {
+		t.Errorf("expected error for key without certificate")
+	}
+	os.Remove(filepath.Join(dir, "client.key"))
+
+	ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("not a certificate"), 0600)
+	if _, err := loadCertsDir(dir); err == nil {
+		t.Errorf("expected error for invalid CA")
+	}
+}
+
+func TestInsecureRegistries(t *testing.T) {
+	c := &DefaultClient{insecure: parseInsecureRegistries("registry.local:5000, docker.io")}
+	tests := map[string]bool{
+		"https://registry.local:5000": true,
+		"http://registry.local:5000":  true,
+		"https://registry.local":      false,
+		"https://index.docker.io":     true,
+		"https://gcr.io":              false,
+	}
+	for registry, want := range tests {
+		if got := c.isInsecure(registry); got != want {
+			t.Errorf("%s: expected %t, got %t", registry, want, got)
+		}
+	}
+
+	c.insecure = parseInsecureRegistries("true")
+	if !c.isInsecure("https://gcr.io") {
+		t.Errorf("expected all registries to be insecure")
+	}
+	c.insecure = parseInsecureRegistries("false")
+	if c.isInsecure("https://gcr.io") {
+		t.Errorf("expected registry to be secure")
+	}
+}
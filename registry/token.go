@@ -0,0 +1,333 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rusenask/docker-registry-client/registry"
+)
+
+const (
+	// defaultTokenExpiry - token lifetime when auth server doesn't return expires_in
+	defaultTokenExpiry = 60 * time.Second
+	// tokenExpiryMargin - tokens are refreshed this long before they expire
+	tokenExpiryMargin = 10 * time.Second
+)
+
+// tokens - bearer tokens shared by all registry clients
+var tokens = &tokenCache{tokens: make(map[string]*cachedToken), challenges: make(map[string]*challenge)}
+
+// tokenCache - bearer tokens by auth realm, service, scope and credentials
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	// challenges - token auth realm and service of registry hosts, learnt from their
+	// first 401 response so tokens can be sent with the first request
+	challenges map[string]*challenge
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+type challenge struct {
+	realm   string
+	service string
+	scope   string
+}
+
+func (c *tokenCache) get(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tokens[key]
+	if !ok {
+		return ""
+	}
+	if time.Now().After(t.expires) {
+		delete(c.tokens, key)
+		return ""
+	}
+	return t.token
+}
+
+func (c *tokenCache) set(key, token string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, t := range c.tokens {
+		if now.After(t.expires) {
+			delete(c.tokens, k)
+		}
+	}
+	c.tokens[key] = &cachedToken{token: token, expires: expires}
+}
+
+func (c *tokenCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
+
+func (c *tokenCache) challenge(host string) *challenge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.challenges[host]
+}
+
+func (c *tokenCache) setChallenge(host string, ch *challenge) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenges[host] = ch
+}
+
+// repositoryPath - repository name in registry API paths
+var repositoryPath = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs|tags)/`)
+
+// requestScope - pull scope of repository the request is for, empty for other requests
+func requestScope(req *http.Request) string {
+	m := repositoryPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return ""
+	}
+	return "repository:" + m[1] + ":pull"
+}
+
+// tokenTransport - token authentication (https://docs.docker.com/registry/spec/auth/token/)
+// with tokens cached until they expire, so polling doesn't authenticate for every request
+type tokenTransport struct {
+	transport http.RoundTripper
+	client    *http.Client
+	username  string
+	password  string
+	cache     *tokenCache
+}
+
+func newTokenTransport(transport http.RoundTripper, username, password string) *tokenTransport {
+	return &tokenTransport{
+		transport: transport,
+		client:    &http.Client{Transport: transport},
+		username:  username,
+		password:  password,
+		cache:     tokens,
+	}
+}
+
+// key - cache key of token for the scope, credentials are part of the key as tokens
+// grant access of the user that requested them
+func (t *tokenTransport) key(ch *challenge, scope string) string {
+	sum := sha256.Sum256([]byte(ch.realm + "\x00" + ch.service + "\x00" + scope + "\x00" + t.username + "\x00" + t.password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scope := requestScope(req)
+
+	var cachedKey string
+	if ch := t.cache.challenge(req.URL.Host); ch != nil && scope != "" {
+		key := t.key(ch, scope)
+		if token := t.cache.get(key); token != "" {
+			cachedKey = key
+			req = withToken(req, token)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	ch := parseChallenge(resp)
+	if ch == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	// cached token was rejected, it may have been revoked
+	if cachedKey != "" {
+		t.cache.remove(cachedKey)
+	}
+	t.cache.setChallenge(req.URL.Host, &challenge{realm: ch.realm, service: ch.service})
+
+	token, expires, authResp, err := t.auth(ch)
+	if err != nil || authResp != nil {
+		return authResp, err
+	}
+	t.cache.set(t.key(ch, ch.scope), token, expires)
+	if scope != "" && scope != ch.scope {
+		t.cache.set(t.key(ch, scope), token, expires)
+	}
+
+	return t.transport.RoundTrip(withToken(req, token))
+}
+
+type tokenResponse struct {
+	Token       string    `json:"token"`
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int       `json:"expires_in"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
+// auth - requests token for the challenge, non-200 auth server responses are returned
+// as response
+func (t *tokenTransport) auth(ch *challenge) (string, time.Time, *http.Response, error) {
+	u, err := url.Parse(ch.realm)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	q := u.Query()
+	q.Set("service", ch.service)
+	if ch.scope != "" {
+		q.Set("scope", ch.scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, resp, nil
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", time.Time{}, nil, fmt.Errorf("failed to decode token response: %s", err)
+	}
+	token := tr.Token
+	if token == "" {
+		token = tr.AccessToken
+	}
+
+	expiresIn := time.Duration(tr.ExpiresIn) * time.Second
+	if expiresIn < defaultTokenExpiry {
+		expiresIn = defaultTokenExpiry
+	}
+	issuedAt := time.Now()
+	if !tr.IssuedAt.IsZero() && tr.IssuedAt.Before(issuedAt) {
+		issuedAt = tr.IssuedAt
+	}
+	return token, issuedAt.Add(expiresIn - tokenExpiryMargin), nil, nil
+}
+
+// parseChallenge - bearer challenge of 401 response
+func parseChallenge(resp *http.Response) *challenge {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	for _, c := range parseAuthHeader(resp.Header.Get("WWW-Authenticate")) {
+		if c.scheme == "bearer" {
+			return &challenge{realm: c.params["realm"], service: c.params["service"], scope: c.params["scope"]}
+		}
+	}
+	return nil
+}
+
+type authChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseAuthHeader - parses WWW-Authenticate header, for example
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func parseAuthHeader(header string) []authChallenge {
+	var challenges []authChallenge
+	s := header
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+		i := 0
+		for i < len(s) && s[i] != ' ' && s[i] != ',' {
+			i++
+		}
+		if i == 0 {
+			s = s[1:]
+			continue
+		}
+		c := authChallenge{scheme: strings.ToLower(s[:i]), params: make(map[string]string)}
+		s = s[i:]
+
+		// parameters until next scheme
+		for {
+			s = strings.TrimLeft(s, " \t")
+			for len(s) > 0 && s[0] == ',' {
+				s = strings.TrimLeft(s[1:], " \t")
+			}
+			eq := 0
+			for eq < len(s) && s[eq] != '=' && s[eq] != ' ' && s[eq] != ',' {
+				eq++
+			}
+			if eq == 0 || eq >= len(s) || s[eq] != '=' {
+				break
+			}
+			name := strings.ToLower(s[:eq])
+			s = s[eq+1:]
+			var value string
+			if len(s) > 0 && s[0] == '"' {
+				j := 1
+				var b []byte
+				for j < len(s) && s[j] != '"' {
+					if s[j] == '\\' && j+1 < len(s) {
+						j++
+					}
+					b = append(b, s[j])
+					j++
+				}
+				value = string(b)
+				if j < len(s) {
+					j++
+				}
+				s = s[j:]
+			} else {
+				j := 0
+				for j < len(s) && s[j] != ',' && s[j] != ' ' {
+					j++
+				}
+				value = s[:j]
+				s = s[j:]
+			}
+			c.params[name] = value
+		}
+		challenges = append(challenges, c)
+	}
+	return challenges
+}
+
+// withToken - copy of request with bearer token
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// wrapTransport - registry authentication and error handling, same as registry.WrapTransport
+// with cached bearer tokens
+func wrapTransport(transport *http.Transport, url, username, password string) http.RoundTripper {
+	return &registry.ErrorTransport{
+		Transport: &registry.BasicTransport{
+			Transport: newTokenTransport(transport, username, password),
+			URL:       url,
+			Username:  username,
+			Password:  password,
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenAuthRegistry - registry requiring bearer tokens issued by its /token endpoint,
+// valid tokens are kept in the returned map
+func newTokenAuthRegistry(t *testing.T, expiresIn int) (*httptest.Server, *int32, *int32, map[string]bool) {
+	var issued, unauthorized int32
+	valid := map[string]bool{}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" || r.URL.Query().Get("service") != "registry.test" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			n := atomic.AddInt32(&issued, 1)
+			token := fmt.Sprintf("token-%d", n)
+			valid[token] = true
+			fmt.Fprintf(w, `{"token": %q, "expires_in": %d}`, token, expiresIn)
+			return
+		}
+
+		if !valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			atomic.AddInt32(&unauthorized, 1)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="repository:org/app:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeManifestV2)
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		w.Write([]byte(`{"schemaVersion": 2}`))
+	}))
+	return srv, &issued, &unauthorized, valid
+}
+
+func TestTokenCache(t *testing.T) {
+	srv, issued, unauthorized, valid := newTokenAuthRegistry(t, 300)
+	defer srv.Close()
+
+	c := New()
+	opts := Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0", Username: "user", Password: "pass"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Digest(opts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if *issued != 1 || *unauthorized != 1 {
+		t.Errorf("expected a single authentication, got %d tokens and %d unauthorized requests", *issued, *unauthorized)
+	}
+
+	// other clients share the cache
+	if _, err := New().Digest(opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *issued != 1 {
+		t.Errorf("expected cached token to be used by another client, got %d tokens", *issued)
+	}
+
+	// revoked token
+	for token := range valid {
+		delete(valid, token)
+	}
+	if _, err := c.Digest(opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *issued != 2 {
+		t.Errorf("expected new token after rejection, got %d tokens", *issued)
+	}
+
+	// wrong credentials aren't served cached token
+	_, err := New().Digest(Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0", Username: "user", Password: "wrong"})
+	if StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestTokenCacheExpiry(t *testing.T) {
+	srv, issued, _, _ := newTokenAuthRegistry(t, 0)
+	defer srv.Close()
+
+	c := New()
+	opts := Opts{Registry: srv.URL, Name: "org/app", Tag: "1.0.0", Username: "user", Password: "pass"}
+	if _, err := c.Digest(opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tokens.mu.Lock()
+	for _, token := range tokens.tokens {
+		if token.expires.Before(time.Now().Add(defaultTokenExpiry - tokenExpiryMargin - time.Second)) {
+			t.Errorf("expected default expiry, got %s", token.expires)
+		}
+		token.expires = time.Now().Add(-time.Second)
+	}
+	tokens.mu.Unlock()
+
+	if _, err := c.Digest(opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *issued != 2 {
+		t.Errorf("expected new token after expiry, got %d tokens", *issued)
+	}
+}
+
+func TestParseAuthHeader(t *testing.T) {
+	challenges := parseAuthHeader(`Basic realm="registry", Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull,push" `)
+	if len(challenges) != 2 {
+		t.Fatalf("expected 2 challenges, got %d: %v", len(challenges), challenges)
+	}
+	if challenges[0].scheme != "basic" || challenges[0].params["realm"] != "registry" {
+		t.Errorf("unexpected basic challenge: %v", challenges[0])
+	}
+	bearer := challenges[1]
+	if bearer.scheme != "bearer" || bearer.params["realm"] != "https://auth.docker.io/token" || bearer.params["service"] != "registry.docker.io" || bearer.params["scope"] != "repository:library/nginx:pull,push" {
+		t.Errorf("unexpected bearer challenge: %v", bearer)
+	}
+}
@@ -78,11 +78,60 @@ func (g *DefaultGetter) Get(image *types.TrackedImage) (*types.Credentials, erro
 			image.Secrets = secrets
 		}
 	}
-	if len(image.Secrets) == 0 {
+
+	if len(image.Secrets) > 0 {
+		creds, found := g.getCredentialsFromSecrets(image, image.Secrets)
+		if found {
+			return creds, nil
+		}
+	}
+
+	// pods get image pull secrets of their service account as well
+	saSecrets := g.lookupServiceAccountSecrets(image)
+	if len(saSecrets) > 0 {
+		creds, found := g.getCredentialsFromSecrets(image, saSecrets)
+		if found {
+			return creds, nil
+		}
+	}
+
+	if len(image.Secrets) == 0 && len(saSecrets) == 0 {
 		return nil, ErrSecretsNotSpecified
 	}
 
-	return g.getCredentialsFromSecret(image)
+	return &types.Credentials{}, nil
+}
+
+// lookupServiceAccountSecrets - image pull secrets of the service account that aren't
+// among image secrets already
+func (g *DefaultGetter) lookupServiceAccountSecrets(image *types.TrackedImage) []string {
+	if image.ServiceAccount == "" {
+		return nil
+	}
+
+	sa, err := g.kubernetesImplementer.ServiceAccount(image.Namespace, image.ServiceAccount)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"namespace":       image.Namespace,
+			"service_account": image.ServiceAccount,
+			"error":           err,
+		}).Debug("secrets.defaultGetter: failed to get service account")
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, s := range image.Secrets {
+		checked[s] = true
+	}
+
+	var secrets []string
+	for _, ref := range sa.ImagePullSecrets {
+		if !checked[ref.Name] {
+			checked[ref.Name] = true
+			secrets = append(secrets, ref.Name)
+		}
+	}
+	return secrets
 }
 
 func (g *DefaultGetter) lookupDefaultDockerConfig(image *types.TrackedImage) (*types.Credentials, bool) {
@@ -138,12 +187,13 @@ func getPodImagePullSecrets(pod *v1.Pod) []string {
 	return secrets
 }
 
-func (g *DefaultGetter) getCredentialsFromSecret(image *types.TrackedImage) (*types.Credentials, error) {
+// getCredentialsFromSecrets - looks for registry credentials in docker configuration
+// secrets, secret type doesn't matter as long as it has .dockerconfigjson or .dockercfg key
+func (g *DefaultGetter) getCredentialsFromSecrets(image *types.TrackedImage, secretRefs []string) (*types.Credentials, bool) {
 
-	credentials := &types.Credentials{}
 	secretFound := false
 
-	for _, secretRef := range image.Secrets {
+	for _, secretRef := range secretRefs {
 		secret, err := g.kubernetesImplementer.Secret(image.Namespace, secretRef)
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -155,79 +205,27 @@ func (g *DefaultGetter) getCredentialsFromSecret(image *types.TrackedImage) (*ty
 			continue
 		}
 
-		var dockerCfg DockerCfg
-
-		switch secret.Type {
-		case v1.SecretTypeDockercfg:
-			secretDataBts, ok := secret.Data[dockerConfigKey]
-			if !ok {
-				log.WithFields(log.Fields{
-					"image":      image.Image.Repository(),
-					"namespace":  image.Namespace,
-					"secret_ref": secretRef,
-					"type":       secret.Type,
-					"data":       secret.Data,
-				}).Warn("secrets.defaultGetter: secret is missing key '.dockerconfig', ensure that key exists")
-				continue
-			}
-			dockerCfg, err = decodeSecret(secretDataBts)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"image":       image.Image.Repository(),
-					"namespace":   image.Namespace,
-					"secret_ref":  secretRef,
-					"secret_data": string(secretDataBts),
-					"error":       err,
-				}).Error("secrets.defaultGetter: failed to decode secret")
-				continue
-			}
-			secretFound = true
-		case v1.SecretTypeDockerConfigJson:
-			secretDataBts, ok := secret.Data[dockerConfigJSONKey]
-			if !ok {
-				log.WithFields(log.Fields{
-					"image":      image.Image.Repository(),
-					"namespace":  image.Namespace,
-					"secret_ref": secretRef,
-					"type":       secret.Type,
-					"data":       secret.Data,
-				}).Warn("secrets.defaultGetter: secret is missing key '.dockerconfigjson', ensure that key exists")
-				continue
-			}
-			secretFound = true
-
-			dockerCfg, err = DecodeDockerCfgJson(secretDataBts)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"image":       image.Image.Repository(),
-					"namespace":   image.Namespace,
-					"secret_ref":  secretRef,
-					"secret_data": string(secretDataBts),
-					"error":       err,
-				}).Error("secrets.defaultGetter: failed to decode secret")
-				continue
-			}
-			secretFound = true
-
-		default:
+		dockerCfg, err := dockerCfgFromSecret(secret)
+		if err != nil {
 			log.WithFields(log.Fields{
 				"image":      image.Image.Repository(),
 				"namespace":  image.Namespace,
 				"secret_ref": secretRef,
 				"type":       secret.Type,
-			}).Warn("secrets.defaultGetter: supplied secret is not kubernetes.io/dockercfg, ignoring")
+				"error":      err,
+			}).Warn("secrets.defaultGetter: failed to read docker configuration from secret")
 			continue
 		}
+		secretFound = true
 
 		creds, found := credentialsFromConfig(image, dockerCfg)
 		if found {
-			return creds, nil
-		} else {
-			log.WithFields(log.Fields{
-				"secret_ref": secretRef,
-				"image":      image.Image.String(),
-			}).Warn("secrets.defaultGetter: registry not found among secrets")
+			return creds, true
 		}
+		log.WithFields(log.Fields{
+			"secret_ref": secretRef,
+			"image":      image.Image.String(),
+		}).Warn("secrets.defaultGetter: registry not found among secrets")
 	}
 
 	if secretFound {
@@ -236,19 +234,31 @@ func (g *DefaultGetter) getCredentialsFromSecret(image *types.TrackedImage) (*ty
 			"provider":  image.Provider,
 			"registry":  image.Image.Registry(),
 			"image":     image.Image.Repository(),
-			"secrets":   image.Secrets,
+			"secrets":   secretRefs,
 		}).Warn("secrets.defaultGetter.lookupSecrets: secret found but couldn't detect authentication for the desired registry")
-	} else if len(image.Secrets) > 0 {
+	} else {
 		log.WithFields(log.Fields{
 			"namespace": image.Namespace,
 			"provider":  image.Provider,
 			"registry":  image.Image.Registry(),
 			"image":     image.Image.Repository(),
-			"secrets":   image.Secrets,
+			"secrets":   secretRefs,
 		}).Errorf("secrets.defaultGetter.lookupSecrets: docker credentials were not found among secrets, is secret in the namespace '%s'?", image.Namespace)
 	}
 
-	return credentials, nil
+	return &types.Credentials{}, false
+}
+
+// dockerCfgFromSecret - kubernetes.io/dockerconfigjson and kubernetes.io/dockercfg secrets,
+// opaque secrets holding the same keys are accepted as well
+func dockerCfgFromSecret(secret *v1.Secret) (DockerCfg, error) {
+	if data, ok := secret.Data[dockerConfigJSONKey]; ok {
+		return DecodeDockerCfgJson(data)
+	}
+	if data, ok := secret.Data[dockerConfigKey]; ok {
+		return decodeSecret(data)
+	}
+	return nil, fmt.Errorf("secret has neither '%s' nor '%s' key", dockerConfigJSONKey, dockerConfigKey)
 }
 
 func credentialsFromConfig(image *types.TrackedImage, cfg DockerCfg) (*types.Credentials, bool) {
@@ -338,23 +348,40 @@ func domainOnly(registry string) string {
 	return registry
 }
 
+// decodeSecret - decodes .dockercfg, some tools write it in .dockerconfigjson format
 func decodeSecret(data []byte) (DockerCfg, error) {
-	var cfg DockerCfg
-	err := json.Unmarshal(data, &cfg)
-	if err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return decodeDockerConfig(data)
 }
 
+// DecodeDockerCfgJson - decodes .dockerconfigjson, legacy format without "auths" is
+// accepted as well
 func DecodeDockerCfgJson(data []byte) (DockerCfg, error) {
-	// var cfg DockerCfg
-	var cfg DockerCfgJSON
-	err := json.Unmarshal(data, &cfg)
-	if err != nil {
+	return decodeDockerConfig(data)
+}
+
+// decodeDockerConfig - decodes registry auth entries of docker configuration, other keys
+// (credsStore, credHelpers, HttpHeaders) and entries that aren't auth objects are skipped
+func decodeDockerConfig(data []byte) (DockerCfg, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
 		return nil, err
 	}
-	return cfg.Auths, nil
+	if auths, ok := raw["auths"]; ok {
+		raw = nil
+		if err := json.Unmarshal(auths, &raw); err != nil {
+			return nil, fmt.Errorf("failed to decode auths: %s", err)
+		}
+	}
+
+	cfg := make(DockerCfg)
+	for registry, entry := range raw {
+		var auth Auth
+		if err := json.Unmarshal(entry, &auth); err != nil {
+			continue
+		}
+		cfg[registry] = &auth
+	}
+	return cfg, nil
 }
 
 func EncodeDockerCfgJson(cfg *DockerCfg) ([]byte, error) {
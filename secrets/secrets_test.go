@@ -453,6 +453,129 @@ func TestLookupWithPortedRegistry(t *testing.T) {
 	}
 }
 
+func TestGetServiceAccountSecret(t *testing.T) {
+	imgRef, _ := image.Parse("quay.io/karolisr/webhook-demo:0.0.11")
+
+	impl := &testutil.FakeK8sImplementer{
+		AvailableSecret: map[string]*v1.Secret{
+			"dockerhub": &v1.Secret{
+				Data: map[string][]byte{
+					dockerConfigKey: []byte(secretDataPayload),
+				},
+				Type: v1.SecretTypeDockercfg,
+			},
+			"quay": &v1.Secret{
+				Data: map[string][]byte{
+					dockerConfigJSONKey: []byte(secretDockerConfigJSONPayload),
+				},
+				Type: v1.SecretTypeDockerConfigJson,
+			},
+		},
+		AvailableServiceAccounts: map[string]*v1.ServiceAccount{
+			"deployer": &v1.ServiceAccount{
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "dockerhub"}, {Name: "quay"}},
+			},
+		},
+	}
+
+	getter := NewGetter(impl, nil)
+
+	// pod secrets don't have credentials for the registry
+	creds, err := getter.Get(&types.TrackedImage{
+		Image:          imgRef,
+		Namespace:      "default",
+		Secrets:        []string{"dockerhub"},
+		ServiceAccount: "deployer",
+	})
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "keeluser+keeltest" {
+		t.Errorf("unexpected username: %s", creds.Username)
+	}
+
+	// no pod secrets
+	creds, err = getter.Get(&types.TrackedImage{
+		Image:          imgRef,
+		Namespace:      "default",
+		ServiceAccount: "deployer",
+	})
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "keeluser+keeltest" {
+		t.Errorf("unexpected username: %s", creds.Username)
+	}
+
+	_, err = getter.Get(&types.TrackedImage{
+		Image:          imgRef,
+		Namespace:      "default",
+		ServiceAccount: "default",
+	})
+	if err != ErrSecretsNotSpecified {
+		t.Errorf("expected secrets not specified error, got %v", err)
+	}
+}
+
+func TestGetOpaqueSecretWithCredsStore(t *testing.T) {
+	imgRef, _ := image.Parse("quay.io/karolisr/webhook-demo:0.0.11")
+
+	payload := `{
+		"auths": {
+			"gcr.io": {},
+			"quay.io": {"auth": "` + mustEncode("user-q:pass-q") + `"}
+		},
+		"credsStore": "desktop",
+		"credHelpers": {"gcr.io": "gcloud"}
+	}`
+	impl := &testutil.FakeK8sImplementer{
+		AvailableSecret: map[string]*v1.Secret{
+			"opaque": &v1.Secret{
+				Data: map[string][]byte{
+					dockerConfigJSONKey: []byte(payload),
+				},
+				Type: v1.SecretTypeOpaque,
+			},
+		},
+	}
+
+	getter := NewGetter(impl, nil)
+	creds, err := getter.Get(&types.TrackedImage{
+		Image:     imgRef,
+		Namespace: "default",
+		Secrets:   []string{"opaque"},
+	})
+	if err != nil {
+		t.Fatalf("failed to get creds: %s", err)
+	}
+	if creds.Username != "user-q" || creds.Password != "pass-q" {
+		t.Errorf("unexpected credentials: %s:%s", creds.Username, creds.Password)
+	}
+}
+
+func TestDecodeDockerConfig(t *testing.T) {
+	// legacy .dockercfg, .dockerconfigjson and config with only credentials helpers
+	for payload, registries := range map[string]int{
+		secretDataPayload:             1,
+		secretDockerConfigJSONPayload: 1,
+		`{"auths": {"quay.io": {"auth": "abc"}, "gcr.io": {"auth": "def"}}, "HttpHeaders": {"User-Agent": "docker"}}`: 2,
+		`{"credsStore": "osxkeychain"}`: 0,
+	} {
+		cfg, err := decodeDockerConfig([]byte(payload))
+		if err != nil {
+			t.Errorf("failed to decode %s: %s", payload, err)
+			continue
+		}
+		if len(cfg) != registries {
+			t.Errorf("expected %d registries in %s, got %d", registries, payload, len(cfg))
+		}
+	}
+
+	if _, err := decodeDockerConfig([]byte("not json")); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
 func Test_decodeBase64Secret(t *testing.T) {
 	type args struct {
 		authSecret string
@@ -0,0 +1,23 @@
+package ecr
+
+import (
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/keel-hq/keel/internal/awsauth"
+)
+
+// newSession - session using default credential chain (web identity when running with IRSA)
+// and optionally assuming another role on top of them
+func newSession(region, roleARN string) (*session.Session, error) {
+	sess, err := awsauth.NewSession(region)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleARN != "" {
+		sess.Config.Credentials = stscreds.NewCredentials(sess, roleARN)
+	}
+
+	return sess, nil
+}
@@ -0,0 +1,221 @@
+package ecr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// receiveErrorBackoff - how long to wait before polling queue again after an error
+var receiveErrorBackoff = 10 * time.Second
+
+// Opts - SQS subscriber options
+type Opts struct {
+	// QueueURL - SQS queue receiving ECR "Image Action" EventBridge events
+	QueueURL string
+	// Region - queue region, taken from queue URL when empty
+	Region string
+	// RoleARN - optional role assumed to read the queue
+	RoleARN string
+
+	Providers provider.Providers
+}
+
+// queueClient - the part of SQS API the trigger needs
+type queueClient interface {
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error)
+}
+
+// Subscriber - long polls SQS queue with ECR events
+type Subscriber struct {
+	providers provider.Providers
+	queueURL  string
+	client    queueClient
+}
+
+// NewSubscriber - creates SQS subscriber, credentials are resolved with the default
+// AWS chain (environment, shared config, instance role) or IRSA web identity
+func NewSubscriber(opts *Opts) (*Subscriber, error) {
+	region := opts.Region
+	if region == "" {
+		var err error
+		region, err = queueRegion(opts.QueueURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sess, err := newSession(region, opts.RoleARN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %s", err)
+	}
+
+	return &Subscriber{
+		providers: opts.Providers,
+		queueURL:  opts.QueueURL,
+		client:    sqs.New(sess),
+	}, nil
+}
+
+// queueRegion - region from queue URL, https://sqs.<region>.amazonaws.com/<account>/<name>
+func queueRegion(queueURL string) (string, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid queue URL: %s", err)
+	}
+	parts := strings.Split(u.Hostname(), ".")
+	if len(parts) < 4 || parts[0] != "sqs" {
+		return "", fmt.Errorf("can't get region from queue URL %s, set region explicitly", queueURL)
+	}
+	return parts[1], nil
+}
+
+// Start - polls queue until context is cancelled
+func (s *Subscriber) Start(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"queue": s.queueURL,
+	}).Info("trigger.ecr: receiving events...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		err := s.receive(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"queue": s.queueURL,
+			}).Error("trigger.ecr: failed to receive messages")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(receiveErrorBackoff):
+			}
+		}
+	}
+}
+
+func (s *Subscriber) receive(ctx context.Context) error {
+	out, err := s.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(s.queueURL),
+		MaxNumberOfMessages: aws.Int64(10),
+		WaitTimeSeconds:     aws.Int64(20),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range out.Messages {
+		s.handle(msg)
+
+		// events that can't be processed are removed as well, retrying won't help them
+		_, err := s.client.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(s.queueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"message_id": aws.StringValue(msg.MessageId),
+			}).Error("trigger.ecr: failed to delete message")
+		}
+	}
+	return nil
+}
+
+// imageAction - ECR "Image Action" EventBridge event
+// https://docs.aws.amazon.com/AmazonECR/latest/userguide/ecr-eventbridge.html
+type imageAction struct {
+	DetailType string `json:"detail-type"`
+	Source     string `json:"source"`
+	Account    string `json:"account"`
+	Region     string `json:"region"`
+	Detail     struct {
+		Result         string `json:"result"`
+		RepositoryName string `json:"repository-name"`
+		ImageDigest    string `json:"image-digest"`
+		ActionType     string `json:"action-type"`
+		ImageTag       string `json:"image-tag"`
+	} `json:"detail"`
+}
+
+// snsNotification - events routed through SNS topic are wrapped in notification
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+func (s *Subscriber) handle(msg *sqs.Message) {
+	body := []byte(aws.StringValue(msg.Body))
+
+	var notification snsNotification
+	if err := json.Unmarshal(body, &notification); err == nil && notification.Type == "Notification" {
+		body = []byte(notification.Message)
+	}
+
+	event, ok, err := parseImageAction(body)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"message_id": aws.StringValue(msg.MessageId),
+		}).Warn("trigger.ecr: failed to decode message")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"image": event.Repository.Name,
+		"tag":   event.Repository.Tag,
+	}).Debug("trigger.ecr: got push event")
+
+	s.providers.Submit(*event)
+}
+
+// parseImageAction - converts successful ECR push to keel event, false for other events
+func parseImageAction(body []byte) (*types.Event, bool, error) {
+	var action imageAction
+	if err := json.Unmarshal(body, &action); err != nil {
+		return nil, false, err
+	}
+
+	if action.Source != "aws.ecr" || action.DetailType != "ECR Image Action" {
+		return nil, false, nil
+	}
+	if action.Detail.ActionType != "PUSH" || action.Detail.Result != "SUCCESS" || action.Detail.ImageTag == "" {
+		return nil, false, nil
+	}
+
+	domain := "amazonaws.com"
+	if strings.HasPrefix(action.Region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+
+	return &types.Event{
+		Repository: types.Repository{
+			Name:   fmt.Sprintf("%s.dkr.ecr.%s.%s/%s", action.Account, action.Region, domain, action.Detail.RepositoryName),
+			Tag:    action.Detail.ImageTag,
+			Digest: action.Detail.ImageDigest,
+		},
+		CreatedAt:   time.Now(),
+		TriggerName: "ecr",
+	}, true, nil
+}
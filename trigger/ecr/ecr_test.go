@@ -0,0 +1,116 @@
+package ecr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	"github.com/keel-hq/keel/types"
+)
+
+var imagePushEvent = `{
+  "version": "0",
+  "id": "13cde686-328b-6117-af20-0e5566167482",
+  "detail-type": "ECR Image Action",
+  "source": "aws.ecr",
+  "account": "123456789012",
+  "time": "2019-11-16T01:54:34Z",
+  "region": "us-west-2",
+  "resources": [],
+  "detail": {
+    "result": "SUCCESS",
+    "repository-name": "my-repository-name",
+    "image-digest": "sha256:7f5b2640fe6fb4f46592dfd3410c4a79dac4f89e4782432e0378abcd1234",
+    "action-type": "PUSH",
+    "image-tag": "1.2.3"
+  }
+}`
+
+type fakeProviders struct {
+	submitted []types.Event
+}
+
+func (p *fakeProviders) Submit(event types.Event) error {
+	p.submitted = append(p.submitted, event)
+	return nil
+}
+func (p *fakeProviders) TrackedImages() ([]*types.TrackedImage, error) { return nil, nil }
+func (p *fakeProviders) List() []string                                { return nil }
+func (p *fakeProviders) Stop()                                         {}
+func (p *fakeProviders) Pause()                                        {}
+func (p *fakeProviders) Resume()                                       {}
+func (p *fakeProviders) Paused() bool                                  { return false }
+
+type fakeQueue struct {
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (q *fakeQueue) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	out := &sqs.ReceiveMessageOutput{Messages: q.messages}
+	q.messages = nil
+	return out, nil
+}
+
+func (q *fakeQueue) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	q.deleted = append(q.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestParseImageAction(t *testing.T) {
+	event, ok, err := parseImageAction([]byte(imagePushEvent))
+	if err != nil || !ok {
+		t.Fatalf("expected push event, got: %v, %s", ok, err)
+	}
+	if event.Repository.Name != "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-repository-name" {
+		t.Errorf("unexpected image: %s", event.Repository.Name)
+	}
+	if event.Repository.Tag != "1.2.3" {
+		t.Errorf("unexpected tag: %s", event.Repository.Tag)
+	}
+
+	for _, body := range []string{
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "detail": {"action-type": "DELETE", "result": "SUCCESS", "image-tag": "1.2.3"}}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "detail": {"action-type": "PUSH", "result": "FAILURE", "image-tag": "1.2.3"}}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Action", "detail": {"action-type": "PUSH", "result": "SUCCESS"}}`,
+		`{"source": "aws.ecr", "detail-type": "ECR Image Scan", "detail": {}}`,
+	} {
+		if _, ok, err := parseImageAction([]byte(body)); ok || err != nil {
+			t.Errorf("expected event to be ignored: %s", body)
+		}
+	}
+}
+
+func TestReceive(t *testing.T) {
+	fp := &fakeProviders{}
+	queue := &fakeQueue{messages: []*sqs.Message{
+		{MessageId: aws.String("1"), ReceiptHandle: aws.String("r-1"), Body: aws.String(imagePushEvent)},
+		{MessageId: aws.String("2"), ReceiptHandle: aws.String("r-2"), Body: aws.String(fmt.Sprintf(`{"Type": "Notification", "Message": %q}`, imagePushEvent))},
+		{MessageId: aws.String("3"), ReceiptHandle: aws.String("r-3"), Body: aws.String("not json")},
+	}}
+	s := &Subscriber{providers: fp, queueURL: "https://sqs.us-west-2.amazonaws.com/123456789012/keel", client: queue}
+
+	if err := s.receive(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(fp.submitted) != 2 {
+		t.Fatalf("expected direct and SNS wrapped events to be submitted, got: %d", len(fp.submitted))
+	}
+	if len(queue.deleted) != 3 {
+		t.Errorf("expected all messages to be deleted, got: %v", queue.deleted)
+	}
+}
+
+func TestQueueRegion(t *testing.T) {
+	region, err := queueRegion("https://sqs.eu-west-1.amazonaws.com/123456789012/keel")
+	if err != nil || region != "eu-west-1" {
+		t.Errorf("unexpected region: %s, %v", region, err)
+	}
+	if _, err := queueRegion("http://localhost:9324/queue/keel"); err == nil {
+		t.Errorf("expected error for URL without region")
+	}
+}
@@ -0,0 +1,275 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/keel-hq/keel/provider"
+	"github.com/keel-hq/keel/types"
+	"github.com/keel-hq/keel/util/image"
+
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+// connection settings, initial connection is retried with reconnect wait doubling after
+// every failed attempt up to maxReconnectWait, lost connections are reestablished by the client
+var (
+	dialTimeout      = 10 * time.Second
+	requestTimeout   = 10 * time.Second
+	reconnectWait    = 2 * time.Second
+	maxReconnectWait = 2 * time.Minute
+)
+
+// Opts - NATS subscriber options
+type Opts struct {
+	// URLs - comma separated list of servers, nats://[user:pass@]host:port or tls://...,
+	// servers are tried in order when (re)connecting
+	URLs string
+	// Subject - subject with image push messages, wildcards are allowed
+	Subject string
+	// Queue - optional queue group, ignored for JetStream consumers
+	Queue string
+
+	// Stream and Durable - JetStream stream and durable consumer name, messages are only
+	// acknowledged once they were submitted to providers
+	Stream  string
+	Durable string
+
+	Providers provider.Providers
+}
+
+// Subscriber - receives image push messages from NATS
+type Subscriber struct {
+	providers provider.Providers
+	urls      []string
+	subject   string
+	queue     string
+	stream    string
+	durable   string
+}
+
+// NewSubscriber - creates NATS subscriber
+func NewSubscriber(opts *Opts) (*Subscriber, error) {
+	var urls []string
+	for _, u := range strings.Split(opts.URLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("NATS server URL is required")
+	}
+	if opts.Durable == "" && opts.Subject == "" {
+		return nil, fmt.Errorf("NATS subject is required")
+	}
+	if opts.Durable != "" && opts.Stream == "" {
+		return nil, fmt.Errorf("JetStream stream is required for durable consumer %s", opts.Durable)
+	}
+
+	return &Subscriber{
+		providers: opts.Providers,
+		urls:      urls,
+		subject:   opts.Subject,
+		queue:     opts.Queue,
+		stream:    opts.Stream,
+		durable:   opts.Durable,
+	}, nil
+}
+
+// Start - receives messages until context is cancelled, retrying until connection and
+// subscription are established
+func (s *Subscriber) Start(ctx context.Context) {
+	wait := reconnectWait
+	for {
+		err := s.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"error":     err,
+			"servers":   s.redactedURLs(),
+			"reconnect": wait.String(),
+		}).Error("trigger.nats: connection failed")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+	}
+}
+
+// run - connects, subscribes and handles messages until context is cancelled or the
+// connection is closed
+func (s *Subscriber) run(ctx context.Context) error {
+	closed := make(chan struct{})
+	nc, err := nats.Connect(strings.Join(s.urls, ","),
+		nats.Name("keel"),
+		nats.Timeout(dialTimeout),
+		nats.DontRandomize(),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(reconnectWait),
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			if err != nil {
+				log.WithError(err).Warn("trigger.nats: disconnected, reconnecting...")
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.WithField("server", redactURL(nc.ConnectedUrl())).Info("trigger.nats: reconnected")
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			close(closed)
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	if s.durable != "" {
+		js, err := nc.JetStream(nats.MaxWait(requestTimeout))
+		if err != nil {
+			return err
+		}
+		// only new messages are delivered to a new consumer, existing consumer with the
+		// same configuration is reused
+		_, err = js.Subscribe(s.subject, s.handle,
+			nats.BindStream(s.stream),
+			nats.Durable(s.durable),
+			nats.DeliverSubject(s.deliverSubject()),
+			nats.DeliverNew(),
+			nats.AckExplicit(),
+			nats.ManualAck(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to JetStream consumer: %s", err)
+		}
+	} else {
+		if _, err := nc.QueueSubscribe(s.subject, s.queue, s.handle); err != nil {
+			return err
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"server":  redactURL(nc.ConnectedUrl()),
+		"subject": s.subject,
+		"stream":  s.stream,
+		"durable": s.durable,
+	}).Info("trigger.nats: receiving events...")
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-closed:
+		return nc.LastError()
+	}
+}
+
+// deliverSubject - durable consumers keep delivering to the same subject across reconnects
+func (s *Subscriber) deliverSubject() string {
+	return "keel.deliver." + s.stream + "." + s.durable
+}
+
+func (s *Subscriber) handle(msg *nats.Msg) {
+	event, err := parseMessage(msg.Data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"subject": msg.Subject,
+		}).Warn("trigger.nats: failed to decode message")
+		// redelivering won't help
+		s.acknowledge(msg, msg.Term)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"image": event.Repository.Name,
+		"tag":   event.Repository.Tag,
+	}).Debug("trigger.nats: got push event")
+
+	if err := s.providers.Submit(*event); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"image": event.Repository.Name,
+		}).Error("trigger.nats: failed to submit event")
+		s.acknowledge(msg, msg.Nak)
+		return
+	}
+	s.acknowledge(msg, msg.Ack)
+}
+
+// acknowledge - only JetStream messages are acknowledged, replies to core NATS
+// messages are left to request handlers
+func (s *Subscriber) acknowledge(msg *nats.Msg, ack func(...nats.AckOpt) error) {
+	if s.durable == "" {
+		return
+	}
+	if err := ack(); err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"subject": msg.Subject,
+		}).Error("trigger.nats: failed to acknowledge message")
+	}
+}
+
+func (s *Subscriber) redactedURLs() string {
+	urls := make([]string, len(s.urls))
+	for i, u := range s.urls {
+		urls[i] = redactURL(u)
+	}
+	return strings.Join(urls, ",")
+}
+
+// pushMessage - either native webhook payload ({"name": "karolisr/keel", "tag": "0.2.2"})
+// or image reference ({"image": "karolisr/keel:0.2.2"})
+type pushMessage struct {
+	Name   string `json:"name"`
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	Image  string `json:"image"`
+}
+
+func parseMessage(data []byte) (*types.Event, error) {
+	var m pushMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	event := &types.Event{
+		CreatedAt:   time.Now(),
+		TriggerName: "nats",
+	}
+	switch {
+	case m.Name != "" && m.Tag != "":
+		event.Repository = types.Repository{Name: m.Name, Tag: m.Tag, Digest: m.Digest}
+	case m.Image != "":
+		ref, err := image.Parse(m.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image %s: %s", m.Image, err)
+		}
+		event.Repository = types.Repository{Name: ref.Repository(), Tag: ref.Tag(), Digest: m.Digest}
+	default:
+		return nil, fmt.Errorf("message has neither name and tag nor image")
+	}
+	return event, nil
+}
+
+// redactURL - removes credentials from server URL before logging it
+func redactURL(url string) string {
+	scheme := ""
+	if idx := strings.Index(url, "://"); idx >= 0 {
+		scheme, url = url[:idx+3], url[idx+3:]
+	}
+	if idx := strings.LastIndex(url, "@"); idx >= 0 {
+		url = "***@" + url[idx+1:]
+	}
+	return scheme + url
+}
@@ -0,0 +1,189 @@
+package poll
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var registryBackoffsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "registry_backoffs_total",
+		Help: "How many times polling backed off because registry was rate limiting or failing, partitioned by registry.",
+	},
+	[]string{"registry"},
+)
+
+func init() {
+	prometheus.MustRegister(registryBackoffsCounter)
+}
+
+// default registry request limits
+const (
+	DefaultWorkers             = 10
+	DefaultRegistryConcurrency = 3
+)
+
+// backoff after rate limited or failed requests, it doubles with every such request and
+// resets after a successful one
+var (
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// backoffError - returned without contacting registry while it's backing off
+type backoffError struct {
+	registry string
+	until    time.Time
+}
+
+func (e *backoffError) Error() string {
+	return fmt.Sprintf("registry %s is rate limiting or failing, checks are paused for %s", e.registry, time.Until(e.until).Round(time.Second))
+}
+
+// registryLimit - concurrency and backoff state of a single registry
+type registryLimit struct {
+	sem chan struct{}
+
+	mu      sync.Mutex
+	backoff time.Duration
+	until   time.Time
+}
+
+func (l *registryLimit) backingOff() (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.until, time.Now().Before(l.until)
+}
+
+// record - backs off after 429 and 5xx responses, Retry-After is respected when it asks
+// for a longer delay
+func (l *registryLimit) record(reg string, err error) {
+	status := registry.StatusCode(err)
+	if err != nil && status != http.StatusTooManyRequests && status < 500 {
+		// not found, unauthorized and connection errors don't depend on the load
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err == nil {
+		l.backoff = 0
+		return
+	}
+
+	if l.backoff == 0 {
+		l.backoff = minBackoff
+	} else if l.backoff *= 2; l.backoff > maxBackoff {
+		l.backoff = maxBackoff
+	}
+	delay := l.backoff
+	if retryAfter := registry.RetryAfter(err); retryAfter > delay {
+		delay = retryAfter
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+	l.until = time.Now().Add(delay)
+
+	registryBackoffsCounter.With(prometheus.Labels{"registry": reg}).Inc()
+	log.WithFields(log.Fields{
+		"registry": reg,
+		"status":   status,
+		"backoff":  delay.String(),
+	}).Warn("trigger.poll.limitedClient: registry is rate limiting or failing, backing off")
+}
+
+// limitedClient - registry client running requests on a limited number of workers, requests
+// to a single registry are limited further and paused while the registry is backing off
+type limitedClient struct {
+	client      registry.Client
+	workers     chan struct{}
+	perRegistry int
+
+	mu         sync.Mutex
+	registries map[string]*registryLimit
+}
+
+func newLimitedClient(client registry.Client, workers, perRegistry int) *limitedClient {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if perRegistry <= 0 {
+		perRegistry = DefaultRegistryConcurrency
+	}
+	return &limitedClient{
+		client:      client,
+		workers:     make(chan struct{}, workers),
+		perRegistry: perRegistry,
+		registries:  make(map[string]*registryLimit),
+	}
+}
+
+func (c *limitedClient) limit(reg string) *registryLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.registries[reg]
+	if !ok {
+		l = &registryLimit{sem: make(chan struct{}, c.perRegistry)}
+		c.registries[reg] = l
+	}
+	return l
+}
+
+// do - waits for a registry slot first so that a slow registry doesn't hold workers
+// needed by others
+func (c *limitedClient) do(opts registry.Opts, fn func() error) error {
+	reg := strings.TrimSuffix(opts.Registry, "/")
+	l := c.limit(reg)
+	if until, ok := l.backingOff(); ok {
+		return &backoffError{registry: reg, until: until}
+	}
+
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+	c.workers <- struct{}{}
+	defer func() { <-c.workers }()
+
+	// registry might have started backing off while waiting
+	if until, ok := l.backingOff(); ok {
+		return &backoffError{registry: reg, until: until}
+	}
+
+	err := fn()
+	l.record(reg, err)
+	return err
+}
+
+func (c *limitedClient) Get(opts registry.Opts) (repo *registry.Repository, err error) {
+	err = c.do(opts, func() error {
+		repo, err = c.client.Get(opts)
+		return err
+	})
+	return repo, err
+}
+
+func (c *limitedClient) Digest(opts registry.Opts) (digest string, err error) {
+	err = c.do(opts, func() error {
+		digest, err = c.client.Digest(opts)
+		return err
+	})
+	return digest, err
+}
+
+func (c *limitedClient) Created(opts registry.Opts) (created time.Time, err error) {
+	err = c.do(opts, func() error {
+		created, err = c.client.Created(opts)
+		return err
+	})
+	return created, err
+}
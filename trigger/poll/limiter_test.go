@@ -0,0 +1,148 @@
+package poll
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/registry"
+
+	dockerregistry "github.com/rusenask/docker-registry-client/registry"
+)
+
+// countingClient - counts concurrent requests per registry, returns queued errors
+type countingClient struct {
+	mu      sync.Mutex
+	current map[string]int
+	max     map[string]int
+	calls   int
+	errs    []error
+	delay   time.Duration
+}
+
+func (c *countingClient) Digest(opts registry.Opts) (string, error) {
+	c.mu.Lock()
+	c.calls++
+	c.current[opts.Registry]++
+	if c.current[opts.Registry] > c.max[opts.Registry] {
+		c.max[opts.Registry] = c.current[opts.Registry]
+	}
+	var err error
+	if len(c.errs) > 0 {
+		err, c.errs = c.errs[0], c.errs[1:]
+	}
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.current[opts.Registry]--
+	c.mu.Unlock()
+	return "sha256:123", err
+}
+
+func (c *countingClient) Get(opts registry.Opts) (*registry.Repository, error) {
+	_, err := c.Digest(opts)
+	return &registry.Repository{}, err
+}
+
+func (c *countingClient) Created(opts registry.Opts) (time.Time, error) {
+	_, err := c.Digest(opts)
+	return time.Time{}, err
+}
+
+func newCountingClient() *countingClient {
+	return &countingClient{current: map[string]int{}, max: map[string]int{}}
+}
+
+func statusError(code int, retryAfter string) error {
+	resp := &http.Response{StatusCode: code, Header: http.Header{}}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return &dockerregistry.HttpStatusError{Response: resp}
+}
+
+func TestLimitedClientConcurrency(t *testing.T) {
+	cc := newCountingClient()
+	cc.delay = 20 * time.Millisecond
+	client := newLimitedClient(cc, 3, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, reg := range []string{"https://gcr.io", "https://quay.io"} {
+			wg.Add(1)
+			go func(reg string) {
+				defer wg.Done()
+				if _, err := client.Digest(registry.Opts{Registry: reg, Name: "foo/bar", Tag: "latest"}); err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			}(reg)
+		}
+	}
+	wg.Wait()
+
+	for reg, max := range cc.max {
+		if max > 2 {
+			t.Errorf("expected at most 2 concurrent requests to %s, got %d", reg, max)
+		}
+	}
+	if cc.calls != 20 {
+		t.Errorf("expected 20 calls, got %d", cc.calls)
+	}
+}
+
+func TestLimitedClientBackoff(t *testing.T) {
+	cc := newCountingClient()
+	cc.errs = []error{statusError(http.StatusTooManyRequests, "")}
+	client := newLimitedClient(cc, 3, 2)
+	opts := registry.Opts{Registry: "https://index.docker.io", Name: "foo/bar", Tag: "latest"}
+
+	if _, err := client.Digest(opts); registry.StatusCode(err) != http.StatusTooManyRequests {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+
+	// registry isn't contacted while backing off, other registries are
+	if _, err := client.Digest(opts); err == nil {
+		t.Errorf("expected backoff error")
+	} else if _, ok := err.(*backoffError); !ok {
+		t.Errorf("expected backoff error, got %v", err)
+	}
+	if _, err := client.Digest(registry.Opts{Registry: "https://gcr.io", Name: "foo/bar", Tag: "latest"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if cc.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", cc.calls)
+	}
+
+	l := client.limit("https://index.docker.io")
+	if l.backoff != minBackoff {
+		t.Errorf("unexpected backoff: %s", l.backoff)
+	}
+
+	// backoff doubles, Retry-After asking for a longer delay wins
+	l.until = time.Now()
+	cc.errs = []error{statusError(http.StatusServiceUnavailable, "120")}
+	client.Digest(opts)
+	if l.backoff != 2*minBackoff {
+		t.Errorf("unexpected backoff: %s", l.backoff)
+	}
+	if remaining := time.Until(l.until); remaining < 110*time.Second {
+		t.Errorf("expected Retry-After to be respected, backing off for %s", remaining)
+	}
+
+	// not found doesn't change backoff, success resets it
+	l.until = time.Now()
+	cc.errs = []error{statusError(http.StatusNotFound, "")}
+	client.Digest(opts)
+	if _, ok := l.backingOff(); ok || l.backoff != 2*minBackoff {
+		t.Errorf("unexpected backoff after not found: %s", l.backoff)
+	}
+	if _, err := client.Digest(opts); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if l.backoff != 0 {
+		t.Errorf("expected backoff to be reset, got %s", l.backoff)
+	}
+}
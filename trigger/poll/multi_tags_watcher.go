@@ -6,6 +6,7 @@ import (
 
 	"github.com/Masterminds/semver"
 	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
@@ -94,6 +95,19 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 	versions := semverSort(tags)
 
 	for _, trackedImage := range getRelatedTrackedImages(j.details.trackedImage, trackedImages) {
+		if plc, ok := trackedImage.Policy.(policy.Policy); ok && policy.Ordered(plc) {
+			if tag, ok := bestTag(plc, trackedImage.Image.Tag(), tags); ok && !exists(tag, events) {
+				events = append(events, types.Event{
+					Repository: types.Repository{
+						Name: j.details.trackedImage.Image.Repository(),
+						Tag:  tag,
+					},
+					TriggerName: types.TriggerTypePoll.String(),
+				})
+			}
+			continue
+		}
+
 		// Current version tag might not be a valid semver one
 		currentVersion, invalidCurrentVersion := semver.NewVersion(trackedImage.Image.Tag())
 		// matches, going through tags
@@ -135,6 +149,20 @@ func (j *WatchRepositoryTagsJob) computeEvents(tags []string) ([]types.Event, er
 	return events, nil
 }
 
+// bestTag - evaluates every tag against the best one found so far, policies that order
+// tags (see policy.Ordered) only accept tags that come after the current one
+func bestTag(plc policy.Policy, current string, tags []string) (string, bool) {
+	best := current
+	for _, tag := range tags {
+		update, err := plc.ShouldUpdate(best, tag)
+		if err != nil || !update {
+			continue
+		}
+		best = tag
+	}
+	return best, best != current
+}
+
 func exists(tag string, events []types.Event) bool {
 	for _, e := range events {
 		if tag == e.Repository.Tag {
@@ -165,7 +193,7 @@ func semverSort(tags []string) []*semver.Version {
 }
 
 func getRelatedTrackedImages(ours *types.TrackedImage, all []*types.TrackedImage) []*types.TrackedImage {
-	b := all[:0]
+	var b []*types.TrackedImage
 	for _, x := range all {
 		if x.Image.Repository() == ours.Image.Repository() {
 			b = append(b, x)
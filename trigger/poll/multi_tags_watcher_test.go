@@ -114,8 +114,13 @@ func testRunHelper(testCases []runTestCase, availableTags []string, t *testing.T
 		}
 		t.Errorf("expected "+strconv.Itoa(nbEvents)+" events, got: %d [%s]", len(fp.submitted), strings.Join(tags, ", "))
 	} else {
-		for i, testCase := range testCases {
+		i := 0
+		for _, testCase := range testCases {
+			if testCase.currentTag == testCase.expectedTag {
+				continue
+			}
 			submitted := fp.submitted[i]
+			i++
 
 			if submitted.Repository.Name != "index.docker.io/foo/bar" {
 				t.Errorf("unexpected event repository name: %s", submitted.Repository.Name)
@@ -177,6 +182,77 @@ func TestWatchAllTagsMixedPolicyAll(t *testing.T) {
 	testRunHelper(testCases, availableTags, t)
 }
 
+func mustPolicy(t *testing.T, name string) policy.Policy {
+	plc := policy.GetPolicy(name, &policy.Options{})
+	if plc.Type() == policy.PolicyTypeNone {
+		t.Fatalf("failed to parse policy %s", name)
+	}
+	return plc
+}
+
+func TestWatchAllTagsNumber(t *testing.T) {
+	availableTags := []string{"build-9", "build-12", "build-10", "latest", "1.2.3"}
+	testRunHelper([]runTestCase{{"build-9", "build-12", mustPolicy(t, "number:build-")}}, availableTags, t)
+	testRunHelper([]runTestCase{{"build-12", "build-12", mustPolicy(t, "number:build-")}}, availableTags, t)
+}
+
+func TestWatchAllTagsRegexpCaptures(t *testing.T) {
+	availableTags := []string{"1.9", "1.10", "2.1", "latest"}
+	plc := mustPolicy(t, `regexp:^(?P<major>\d+)\.(?P<build>\d+)$`)
+	testRunHelper([]runTestCase{{"1.9", "2.1", plc}}, availableTags, t)
+}
+
+func TestWatchAllTagsGlobSemver(t *testing.T) {
+	availableTags := []string{"app-1.2.3", "app-1.10.0", "worker-2.0.0", "app-1.4.0"}
+	testRunHelper([]runTestCase{{"app-1.2.3", "app-1.10.0", mustPolicy(t, "glob:app-{semver}")}}, availableTags, t)
+}
+
+func TestWatchAllTagsLexicographic(t *testing.T) {
+	availableTags := []string{"20240117T1030-abc", "20240118T0900-def", "20231201T1200-123", "latest"}
+	testRunHelper([]runTestCase{{"20240117T1030-abc", "20240118T0900-def", mustPolicy(t, "lexicographic:2024*")}}, availableTags, t)
+}
+
+func TestWatchAllTagsTimestamp(t *testing.T) {
+	availableTags := []string{"build-2024.01.17", "build-2024.02.01", "build-2023.12.24", "latest"}
+	testRunHelper([]runTestCase{{"build-2024.01.17", "build-2024.02.01", mustPolicy(t, "timestamp:build-2006.01.02")}}, availableTags, t)
+}
+
+func TestWatchOrderedPolicyWatchesRepository(t *testing.T) {
+	imgA, _ := image.Parse("gcr.io/v2-namespace/hello-world:build-9")
+	fp := &fakeProvider{}
+	store, teardown := newTestingUtils()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		digestToReturn: "sha256:0604af35299dd37ff23937d115d103532948b568a9dd8197d14c256a8ab8b0bb",
+		tagsToReturn:   []string{"build-9", "build-10"},
+	}
+	watcher := NewRepositoryWatcher(providers, frc)
+
+	err := watcher.Watch(&types.TrackedImage{
+		Image:        imgA,
+		Trigger:      types.TriggerTypePoll,
+		Provider:     "fp",
+		PollSchedule: "@every 10m",
+		Policy:       mustPolicy(t, "number:build-"),
+	})
+	if err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	details, ok := watcher.watched["gcr.io/v2-namespace/hello-world"]
+	if !ok {
+		t.Fatalf("expected repository tags job, watched: %v", watcher.watched)
+	}
+	if _, ok := details.job.(*WatchRepositoryTagsJob); !ok {
+		t.Errorf("expected repository tags job, got: %T", details.job)
+	}
+}
+
 func Test_semverSort(t *testing.T) {
 	tags := []string{"1.3.0", "aa1.0.0", "zzz", "1.3.0-dev", "1.5.0", "2.0.0-alpha", "1.3.0-dev1", "1.8.0-alpha", "1.3.1-dev", "123", "1.2.3-rc.1.2+meta"}
 	expectedTags := []string{"2.0.0-alpha", "1.8.0-alpha", "1.5.0", "1.3.1-dev", "1.3.0", "1.3.0-dev1", "1.3.0-dev", "1.2.3-rc.1.2+meta"}
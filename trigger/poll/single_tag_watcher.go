@@ -28,6 +28,9 @@ func NewWatchTagJob(providers provider.Providers, registryClient registry.Client
 
 // Run - main function to check schedule
 func (j *WatchTagJob) Run() {
+	j.details.mu.Lock()
+	defer j.details.mu.Unlock()
+
 	creds := credentialshelper.GetCredentials(j.details.trackedImage)
 	reg := j.details.trackedImage.Image.Scheme() + "://" + j.details.trackedImage.Image.Registry()
 	currentDigest, err := j.registryClient.Digest(registry.Opts{
@@ -57,9 +60,6 @@ func (j *WatchTagJob) Run() {
 
 	// checking whether image digest has changed
 	if j.details.digest != currentDigest {
-		// updating digest
-		j.details.digest = currentDigest
-
 		event := types.Event{
 			Repository: types.Repository{
 				Name:   j.details.trackedImage.Image.Repository(),
@@ -73,15 +73,17 @@ func (j *WatchTagJob) Run() {
 			"new_digest": currentDigest,
 		}).Info("trigger.poll.WatchTagJob: digest change detected, submiting event to providers")
 
-		// j.providers.Submit(event)
 		err := j.providers.Submit(event)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"repository": j.details.trackedImage.Image.Repository(),
 				"digest":     currentDigest,
 				"error":      err,
-			}).Error("trigger.poll.WatchRepositoryTagsJob: error while submitting an event")
+			}).Error("trigger.poll.WatchTagJob: error while submitting an event")
+			// digest is kept so the change is submitted again on the next check
+			return
 		}
 
+		j.details.digest = currentDigest
 	}
 }
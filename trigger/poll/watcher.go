@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/keel-hq/keel/extension/credentialshelper"
+	"github.com/keel-hq/keel/internal/policy"
 	"github.com/keel-hq/keel/provider"
 	"github.com/keel-hq/keel/registry"
 	"github.com/keel-hq/keel/types"
 	"github.com/keel-hq/keel/util/image"
+	"github.com/keel-hq/keel/util/schedule"
 	"github.com/keel-hq/keel/util/version"
 	"github.com/rusenask/cron"
 
@@ -50,6 +53,8 @@ type watchDetails struct {
 	digest       string // image digest
 	latest       string // latest tag
 	schedule     string
+	job          cron.Job
+	scheduled    bool // job was added to cron
 
 	mu sync.RWMutex
 }
@@ -66,18 +71,45 @@ type RepositoryWatcher struct {
 	watched map[string]*watchDetails
 
 	cron *cron.Cron
+
+	// jitter - maximum random delay of scheduled checks
+	jitter time.Duration
+
+	// workers - number of images checked at once when they are added
+	workers int
 }
 
-// NewRepositoryWatcher - create new repository watcher
+// NewRepositoryWatcher - create new repository watcher, registry requests are limited
+// to DefaultWorkers at once and DefaultRegistryConcurrency per registry
 func NewRepositoryWatcher(providers provider.Providers, registryClient registry.Client) *RepositoryWatcher {
 	c := cron.New()
 
 	return &RepositoryWatcher{
 		providers:      providers,
-		registryClient: registryClient,
+		registryClient: newLimitedClient(registryClient, DefaultWorkers, DefaultRegistryConcurrency),
 		watched:        make(map[string]*watchDetails),
 		cron:           c,
+		workers:        DefaultWorkers,
+	}
+}
+
+// SetConcurrency - sets how many registry requests run at once in total and per registry,
+// should be called before images are watched
+func (w *RepositoryWatcher) SetConcurrency(workers, perRegistry int) {
+	client := w.registryClient
+	if limited, ok := client.(*limitedClient); ok {
+		client = limited.client
 	}
+	limited := newLimitedClient(client, workers, perRegistry)
+	w.registryClient = limited
+	w.workers = cap(limited.workers)
+}
+
+// SetJitter - delays every scheduled check by a random duration of up to max (but no more
+// than half of the schedule interval) so that images sharing a schedule don't hit registries
+// at the same time
+func (w *RepositoryWatcher) SetJitter(max time.Duration) {
+	w.jitter = max
 }
 
 // Start - starts repository watcher
@@ -94,12 +126,42 @@ func getImageIdentifier(ref *image.Reference) string {
 	_, err := version.GetVersion(ref.Tag())
 	// if failed to parse version, will need to watch digest
 	if err != nil {
-		return ref.Registry() + "/" + ref.ShortName() + ":" + ref.Tag()
+		return getDigestWatchIdentifier(ref)
 	}
 
+	return getRepositoryWatchIdentifier(ref)
+}
+
+func getRepositoryWatchIdentifier(ref *image.Reference) string {
 	return ref.Registry() + "/" + ref.ShortName()
 }
 
+func getDigestWatchIdentifier(ref *image.Reference) string {
+	return ref.Registry() + "/" + ref.ShortName() + ":" + ref.Tag()
+}
+
+// watchesDigest - non semver tags (latest, stable, etc.) and tags pinned by force policy
+// with keel.sh/match-tag are mutable, changes are detected by the digest behind them.
+// Policies that order other tags (number, timestamp, etc.) watch all repository tags
+func watchesDigest(ti *types.TrackedImage) bool {
+	plc, ok := ti.Policy.(policy.Policy)
+	if ok && policy.MatchesTag(plc) {
+		return true
+	}
+	if ok && policy.Ordered(plc) {
+		return false
+	}
+	_, err := version.GetVersion(ti.Image.Tag())
+	return err != nil
+}
+
+func getTrackedImageIdentifier(ti *types.TrackedImage) string {
+	if watchesDigest(ti) {
+		return getDigestWatchIdentifier(ti.Image)
+	}
+	return getRepositoryWatchIdentifier(ti.Image)
+}
+
 // Unwatch - stop watching for changes
 func (w *RepositoryWatcher) Unwatch(imageName string) error {
 	imageRef, err := image.Parse(imageName)
@@ -110,11 +172,13 @@ func (w *RepositoryWatcher) Unwatch(imageName string) error {
 		}).Error("trigger.poll.RepositoryWatcher.Unwatch: failed to parse image")
 		return err
 	}
-	key := getImageIdentifier(imageRef)
-	_, ok := w.watched[key]
-	if ok {
-		w.cron.DeleteJob(key)
-		delete(w.watched, key)
+	// image might be watched by digest because of its policy
+	for _, key := range []string{getRepositoryWatchIdentifier(imageRef), getDigestWatchIdentifier(imageRef)} {
+		_, ok := w.watched[key]
+		if ok {
+			w.cron.DeleteJob(key)
+			delete(w.watched, key)
+		}
 	}
 
 	return nil
@@ -125,18 +189,27 @@ func (w *RepositoryWatcher) Unwatch(imageName string) error {
 func (w *RepositoryWatcher) Watch(images ...*types.TrackedImage) error {
 
 	var errs []string
+	var added []*pendingJob
 	tracked := map[string]bool{}
 
 	for _, image := range images {
 		if image.Trigger != types.TriggerTypePoll {
 			continue
 		}
-		identifier, err := w.watch(image)
+		identifier, pending, err := w.watch(image)
 		if err != nil {
 			errs = append(errs, err.Error())
 			continue
 		}
 		tracked[identifier] = true
+		if pending != nil {
+			added = append(added, pending)
+		}
+	}
+
+	for key, err := range w.startJobs(added) {
+		errs = append(errs, err.Error())
+		delete(tracked, key)
 	}
 
 	pollTriggerTrackedImages.Set(float64(len(tracked)))
@@ -167,48 +240,48 @@ func (w *RepositoryWatcher) unwatch(tracked map[string]bool) {
 	}
 }
 
-func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, error) {
+// pendingJob - watch job of a new image, scheduled once its initial check succeeded
+type pendingJob struct {
+	key     string
+	details *watchDetails
+	sched   cron.Schedule
+}
 
-	if image.PollSchedule == "" {
-		return "", fmt.Errorf("cron schedule cannot be empty")
-	}
+func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, *pendingJob, error) {
 
-	_, err := cron.Parse(image.PollSchedule)
+	sched, err := schedule.Parse(image.PollSchedule)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error":    err,
 			"image":    image.String(),
 			"schedule": image.PollSchedule,
 		}).Error("trigger.poll.RepositoryWatcher.addJob: invalid cron schedule")
-		return "", fmt.Errorf("invalid cron schedule: %s", err)
+		return "", nil, fmt.Errorf("invalid cron schedule: %s", err)
 	}
 
-	key := getImageIdentifier(image.Image)
+	key := getTrackedImageIdentifier(image)
 
 	// checking whether it's already being watched
 	details, ok := w.watched[key]
 	if !ok {
-		// err = w.addJob(imageRef, registryUsername, registryPassword, schedule)
-		err = w.addJob(image, image.PollSchedule)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"image": image.String(),
-			}).Error("trigger.poll.RepositoryWatcher.Watch: failed to add image watch job")
-			return "", err
-		}
-		return key, nil
+		details = w.newWatchDetails(image)
+		// adding job to internal map, other images with the same key update its details
+		w.watched[key] = details
+		return key, &pendingJob{key: key, details: details, sched: sched}, nil
 	}
 
-	// checking schedule
+	// checking schedule, job is rescheduled as cron can only update jobs with its own schedules
 	if details.schedule != image.PollSchedule {
-		err := w.cron.UpdateJob(key, image.PollSchedule)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"image": image.String(),
-			}).Error("trigger.poll.RepositoryWatcher.Watch: failed to update image watch job")
+		log.WithFields(log.Fields{
+			"job_name": key,
+			"image":    image.String(),
+			"schedule": image.PollSchedule,
+		}).Info("trigger.poll.RepositoryWatcher: schedule changed, updating watch job")
+		if details.scheduled {
+			w.cron.DeleteJob(key)
+			w.cron.Schedule(key, schedule.WithJitter(sched, w.jitter), details.job)
 		}
+		details.schedule = image.PollSchedule
 	}
 
 	details.mu.Lock()
@@ -218,11 +291,72 @@ func (w *RepositoryWatcher) watch(image *types.TrackedImage) (string, error) {
 	details.mu.Unlock()
 
 	// nothing to do
-	return key, nil
+	return key, nil, nil
+}
+
+// newWatchDetails - for versioned (semver) tags we setup a watch all tags job and for
+// mutable tags we create a single tag watcher which checks digest
+func (w *RepositoryWatcher) newWatchDetails(ti *types.TrackedImage) *watchDetails {
+	details := &watchDetails{
+		trackedImage: ti,
+		latest:       ti.Image.Tag(),
+		schedule:     ti.PollSchedule,
+	}
+	if watchesDigest(ti) {
+		details.job = NewWatchTagJob(w.providers, w.registryClient, details)
+	} else {
+		details.job = NewWatchRepositoryTagsJob(w.providers, w.registryClient, details)
+	}
+	return details
+}
+
+// startJobs - runs initial checks of new images in parallel (registry requests are limited
+// by the registry client) and schedules jobs that succeeded, failed ones are removed
+func (w *RepositoryWatcher) startJobs(pending []*pendingJob) map[string]error {
+	failed := make(map[string]error)
+	if len(pending) == 0 {
+		return failed
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, w.workers)
+	)
+	for _, p := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p *pendingJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := w.initJob(p.key, p.details); err != nil {
+				mu.Lock()
+				failed[p.key] = err
+				mu.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	for _, p := range pending {
+		if _, ok := failed[p.key]; ok {
+			delete(w.watched, p.key)
+			continue
+		}
+		// schedule might have been changed by another image with the same key
+		sched := p.sched
+		if p.details.schedule != p.details.trackedImage.PollSchedule {
+			sched, _ = schedule.Parse(p.details.schedule)
+		}
+		w.cron.Schedule(p.key, schedule.WithJitter(sched, w.jitter), p.details.job)
+		p.details.scheduled = true
+	}
+	return failed
 }
 
-func (w *RepositoryWatcher) addJob(ti *types.TrackedImage, schedule string) error {
-	// getting initial digest
+// initJob - gets initial digest and runs the job now
+func (w *RepositoryWatcher) initJob(key string, details *watchDetails) error {
+	ti := details.trackedImage
 	reg := ti.Image.Scheme() + "://" + ti.Image.Registry()
 
 	creds := credentialshelper.GetCredentials(ti)
@@ -244,49 +378,32 @@ func (w *RepositoryWatcher) addJob(ti *types.TrackedImage, schedule string) erro
 		return err
 	}
 
-	key := getImageIdentifier(ti.Image)
-	details := &watchDetails{
-		trackedImage: ti,
-		digest:       digest, // current image digest
-		latest:       ti.Image.Tag(),
-		schedule:     schedule,
+	details.mu.Lock()
+	details.digest = digest // current image digest
+	// workload pinned to a digest, changes of the tag since the workload was updated
+	// are picked up by the first check
+	if ti.Image.Digest() != "" {
+		details.digest = ti.Image.Digest()
 	}
+	details.mu.Unlock()
 
-	// adding job to internal map
-	w.watched[key] = details
-
-	// checking tag type, for versioned (semver) tags we setup a watch all tags job
-	// and for non-semver types we create a single tag watcher which
-	// checks digest
-	_, err = version.GetVersion(ti.Image.Tag())
-	if err != nil {
-		// adding new job
-		job := NewWatchTagJob(w.providers, w.registryClient, details)
+	if _, ok := details.job.(*WatchTagJob); ok {
 		log.WithFields(log.Fields{
 			"job_name": key,
 			"image":    ti.Image.String(),
 			"digest":   digest,
-			"schedule": schedule,
+			"schedule": details.schedule,
 		}).Info("trigger.poll.RepositoryWatcher: new watch tag digest job added")
-
-		// running it now
-		job.Run()
-
-		return w.cron.AddJob(key, schedule, job)
+	} else {
+		log.WithFields(log.Fields{
+			"job_name": key,
+			"image":    ti.Image.String(),
+			"digest":   digest,
+			"schedule": details.schedule,
+		}).Info("trigger.poll.RepositoryWatcher: new watch repository tags job added")
 	}
 
-	// adding new job
-	job := NewWatchRepositoryTagsJob(w.providers, w.registryClient, details)
-	log.WithFields(log.Fields{
-		"job_name": key,
-		"image":    ti.Image.String(),
-		"digest":   digest,
-		"schedule": schedule,
-	}).Info("trigger.poll.RepositoryWatcher: new watch repository tags job added")
-
 	// running it now
-	job.Run()
-
-	return w.cron.AddJob(key, schedule, job)
-
+	details.job.Run()
+	return nil
 }
@@ -2,8 +2,11 @@ package poll
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/keel-hq/keel/approvals"
 	// "github.com/keel-hq/keel/cache/memory"
@@ -29,6 +32,7 @@ func mustParse(img string, schedule string) *types.TrackedImage {
 
 // ======== fake registry client for testing =======
 type fakeRegistryClient struct {
+	mu   sync.Mutex
 	opts registry.Opts // opts set if anything called Digest(opts Opts)
 
 	digestToReturn string
@@ -37,6 +41,8 @@ type fakeRegistryClient struct {
 }
 
 func (c *fakeRegistryClient) Get(opts registry.Opts) (*registry.Repository, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.opts = opts
 	return &registry.Repository{
 		Name: opts.Name,
@@ -45,17 +51,29 @@ func (c *fakeRegistryClient) Get(opts registry.Opts) (*registry.Repository, erro
 }
 
 func (c *fakeRegistryClient) Digest(opts registry.Opts) (digest string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.opts = opts
 	return c.digestToReturn, nil
 }
 
+func (c *fakeRegistryClient) Created(opts registry.Opts) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts = opts
+	return time.Time{}, nil
+}
+
 // ======== fake provider for testing =======
 type fakeProvider struct {
+	mu        sync.Mutex
 	submitted []types.Event
 	images    []*types.TrackedImage
 }
 
 func (p *fakeProvider) Submit(event types.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.submitted = append(p.submitted, event)
 	return nil
 }
@@ -543,3 +561,146 @@ func TestUnwatchAfterNotTrackedAnymore(t *testing.T) {
 		t.Errorf("expected to find watching 3 entries, found: %d", len(watcher.watched))
 	}
 }
+
+func TestWatchScheduleChanged(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := newTestingUtils()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		digestToReturn: "sha256:0604af35299dd37ff23937d115d103532948b568a9dd8197d14c256a8ab8b0bb",
+		tagsToReturn:   []string{"1.1.1"},
+	}
+
+	watcher := NewRepositoryWatcher(providers, frc)
+	watcher.SetJitter(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.Start(ctx)
+
+	if err := watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:1.1.1", "@every 10m")); err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	// standard cron expression, minutes first
+	if err := watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:1.1.1", "0 3 * * *")); err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	entries := watcher.cron.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected list of cron entries: %d", len(entries))
+	}
+	if watcher.watched["gcr.io/v2-namespace/hello-world"].schedule != "0 3 * * *" {
+		t.Errorf("unexpected schedule: %s", watcher.watched["gcr.io/v2-namespace/hello-world"].schedule)
+	}
+
+	next := entries[0].Next
+	if next.Hour() != 3 || next.Minute() > 1 {
+		t.Errorf("expected next run at 03:00 with up to a minute of jitter, got %s", next)
+	}
+
+	if err := watcher.Watch(mustParse("gcr.io/v2-namespace/hello-world:1.1.1", "@every 5 minutes")); err == nil {
+		t.Errorf("expected error for invalid schedule")
+	}
+}
+
+// failingProviders - providers rejecting all events
+type failingProviders struct {
+	provider.Providers
+	submitted int
+}
+
+func (p *failingProviders) Submit(event types.Event) error {
+	p.submitted++
+	return fmt.Errorf("providers are stopped")
+}
+
+func TestWatchTagJobSubmitFailed(t *testing.T) {
+	fp := &failingProviders{}
+	frc := &fakeRegistryClient{
+		digestToReturn: "sha256:0604af35299dd37ff23937d115d103532948b568a9dd8197d14c256a8ab8b0bb",
+	}
+
+	reference, _ := image.Parse("foo/bar:latest")
+	details := &watchDetails{
+		trackedImage: &types.TrackedImage{
+			Image: reference,
+		},
+		digest: "sha256:123123123",
+	}
+
+	job := NewWatchTagJob(fp, frc, details)
+	job.Run()
+	job.Run()
+
+	// change is submitted again as it wasn't accepted
+	if fp.submitted != 2 {
+		t.Errorf("expected 2 submitted events, got %d", fp.submitted)
+	}
+	if details.digest != "sha256:123123123" {
+		t.Errorf("digest shouldn't be updated, got %s", details.digest)
+	}
+}
+
+func TestWatchForceMatchTagByDigest(t *testing.T) {
+	fp := &fakeProvider{}
+	store, teardown := newTestingUtils()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	frc := &fakeRegistryClient{
+		digestToReturn: "sha256:0604af35299dd37ff23937d115d103532948b568a9dd8197d14c256a8ab8b0bb",
+		tagsToReturn:   []string{"1.1.1", "1.2.0"},
+	}
+
+	watcher := NewRepositoryWatcher(providers, frc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.Start(ctx)
+
+	// semver tag re-pushed in place, workload pinned to an older digest
+	pinned := mustParse("gcr.io/v2-namespace/hello-world:1.1.1@sha256:b2c6a6b7f7d8e4d1c0c1b3b1c6f2b9e0d5a4c3b2a1f0e9d8c7b6a5f4e3d2c1b0", "@every 10m")
+	pinned.Policy = policy.NewForcePolicy(true)
+	// semver policy on the same repository keeps watching tags
+	semver := mustParse("gcr.io/v2-namespace/hello-world:1.1.1", "@every 10m")
+	semver.Policy = policy.NewSemverPolicy(policy.SemverPolicyTypeMinor, true)
+
+	if err := watcher.Watch(pinned, semver); err != nil {
+		t.Fatalf("failed to watch: %s", err)
+	}
+
+	details, ok := watcher.watched["gcr.io/v2-namespace/hello-world:1.1.1"]
+	if !ok {
+		t.Fatalf("digest watch job not found, watched: %v", watcher.watched)
+	}
+	if _, ok := details.job.(*WatchTagJob); !ok {
+		t.Errorf("expected digest watch job, got %T", details.job)
+	}
+	if _, ok := watcher.watched["gcr.io/v2-namespace/hello-world"]; !ok {
+		t.Errorf("tags watch job not found")
+	}
+
+	// digest changed since the workload was updated
+	if details.digest != frc.digestToReturn {
+		t.Errorf("expected digest to be updated, got %s", details.digest)
+	}
+	var found bool
+	for _, event := range fp.submitted {
+		if event.Repository.Tag == "1.1.1" && event.Repository.Digest == frc.digestToReturn {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected digest change event, got %v", fp.submitted)
+	}
+}
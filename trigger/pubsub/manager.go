@@ -18,8 +18,8 @@ type DefaultManager struct {
 	client Subscriber
 	// existing subscribers
 	mu *sync.Mutex
-	// a map of GCR URIs and subscribers to those URIs
-	// i.e. key could be something like: gcr.io%2Fmy-project
+	// a map of registry topics and subscribers to those topics
+	// i.e. key could be something like: gcr or projects/other-project/topics/gcr
 	subscribers map[string]context.Context
 
 	// projectID is required to correctly set GCR subscriptions
@@ -96,13 +96,14 @@ func (s *DefaultManager) scan(ctx context.Context) error {
 
 	for _, trackedImage := range trackedImages {
 		if !isGoogleContainerRegistry(trackedImage.Image.Registry()) {
-			log.Debugf("registry %s is not a GCR, skipping", trackedImage.Image.Registry())
+			log.Debugf("registry %s is not a GCR or Artifact Registry, skipping", trackedImage.Image.Registry())
 			continue
 		}
 
 		// uri
 		// https://cloud.google.com/container-registry/docs/configuring-notifications
-		s.ensureSubscription("gcr")
+		// https://cloud.google.com/artifact-registry/docs/configure-notifications
+		s.ensureSubscription(containerRegistryTopic(s.projectID, imageProject(trackedImage.Image)))
 	}
 	return nil
 }
@@ -122,7 +123,7 @@ func (s *DefaultManager) ensureSubscription(gcrURI string) {
 	if !ok {
 		ctx, cancel := context.WithCancel(s.ctx)
 		s.subscribers[gcrURI] = ctx
-		subName := containerRegistrySubName(s.clusterName, s.projectID, gcrURI)
+		subName := containerRegistrySubName(s.clusterName, s.projectID, topicSubName(gcrURI))
 		go func() {
 			defer cancel()
 			err := s.client.Subscribe(s.ctx, gcrURI, subName)
@@ -74,6 +74,11 @@ func (p *fakeProvider) List() []string {
 func (p *fakeProvider) Stop() {
 	return
 }
+func (p *fakeProvider) Pause()  {}
+func (p *fakeProvider) Resume() {}
+func (p *fakeProvider) Paused() bool {
+	return false
+}
 func (p *fakeProvider) GetName() string {
 	return "fp"
 }
@@ -104,6 +109,8 @@ func TestCheckDeployment(t *testing.T) {
 		mu:          &sync.Mutex{},
 		ctx:         context.Background(),
 		subscribers: make(map[string]context.Context),
+		projectID:   "v2-namespace",
+		clusterName: "cluster",
 	}
 
 	err := mng.scan(context.Background())
@@ -117,5 +124,56 @@ func TestCheckDeployment(t *testing.T) {
 	if fs.TimesSubscribed != 1 {
 		t.Errorf("expected to find one subscription, found: %d", fs.TimesSubscribed)
 	}
+	if fs.SubscribedTopicName != "gcr" {
+		t.Errorf("unexpected topic: %s", fs.SubscribedTopicName)
+	}
+
+}
+
+func TestCheckDeploymentArtifactRegistry(t *testing.T) {
+	img, _ := image.Parse("us-east1-docker.pkg.dev/other-project/my-repo/hello-world:1.1")
+	fp := &fakeProvider{
+		images: []*types.TrackedImage{
+			&types.TrackedImage{
+				Image:    img,
+				Provider: "fp",
+			},
+		},
+	}
+
+	store, teardown := newTestingUtils()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+
+	providers := provider.New([]provider.Provider{fp}, am)
+
+	fs := &fakeSubscriber{}
+	mng := &DefaultManager{
+		providers:   providers,
+		client:      fs,
+		mu:          &sync.Mutex{},
+		ctx:         context.Background(),
+		subscribers: make(map[string]context.Context),
+		projectID:   "my-project",
+		clusterName: "cluster",
+	}
 
+	err := mng.scan(context.Background())
+	if err != nil {
+		t.Errorf("failed to scan: %s", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if fs.TimesSubscribed != 1 {
+		t.Errorf("expected to find one subscription, found: %d", fs.TimesSubscribed)
+	}
+	if fs.SubscribedTopicName != "projects/other-project/topics/gcr" {
+		t.Errorf("unexpected topic: %s", fs.SubscribedTopicName)
+	}
+	if fs.SubscribedSubName != "keel-cluster-my-project-gcr-other-project" {
+		t.Errorf("unexpected subscription name: %s", fs.SubscribedSubName)
+	}
 }
@@ -3,6 +3,7 @@ package pubsub
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"net"
@@ -66,32 +67,47 @@ func NewPubsubSubscriber(opts *Opts) (*PubsubSubscriber, error) {
 	}, nil
 }
 
-// Message - expected message from gcr
+// Message - expected message from gcr, Artifact Registry sends the same payload
+// with <location>-docker.pkg.dev/<project>/<repository>/<image> image names
 type Message struct {
 	Action string `json:"action,omitempty"`
 	Digest string `json:"digest"`
 	Tag    string `json:"tag,omitempty"`
 }
 
-func (s *PubsubSubscriber) ensureTopic(ctx context.Context, id string) error {
-	topic := s.client.Topic(id)
+// topic - topic is either an ID in subscriber's project or fully qualified
+// projects/<project>/topics/<id> name for registries in other projects
+func (s *PubsubSubscriber) topic(name string) (*pubsub.Topic, bool) {
+	if parts := strings.Split(name, "/"); len(parts) == 4 && parts[0] == "projects" && parts[2] == "topics" {
+		return s.client.TopicInProject(parts[3], parts[1]), parts[1] == s.project
+	}
+	return s.client.Topic(name), true
+}
+
+func (s *PubsubSubscriber) ensureTopic(ctx context.Context, name string) (*pubsub.Topic, error) {
+	topic, own := s.topic(name)
 	exists, err := topic.Exists(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check whether topic exists, error: %s", err)
+		return nil, fmt.Errorf("failed to check whether topic exists, error: %s", err)
 	}
 
 	if exists {
 		log.WithFields(log.Fields{
-			"topic": id,
+			"topic": name,
 		}).Debug("trigger.pubsub: topic exists")
-		return nil
+		return topic, nil
 	}
 
-	_, err = s.client.CreateTopic(ctx, id)
-	return err
+	// topics are only created in keel's own project, registries in other projects
+	// need notifications set up there
+	if !own {
+		return nil, fmt.Errorf("topic %s doesn't exist, create it to receive registry notifications", name)
+	}
+
+	return s.client.CreateTopic(ctx, topic.ID())
 }
 
-func (s *PubsubSubscriber) ensureSubscription(ctx context.Context, subscriptionID, topicID string) error {
+func (s *PubsubSubscriber) ensureSubscription(ctx context.Context, subscriptionID string, topic *pubsub.Topic) error {
 	sub := s.client.Subscription(subscriptionID)
 	exists, err := sub.Exists(ctx)
 	if err != nil {
@@ -100,13 +116,13 @@ func (s *PubsubSubscriber) ensureSubscription(ctx context.Context, subscriptionI
 	if exists {
 		log.WithFields(log.Fields{
 			"subscription": subscriptionID,
-			"topic":        topicID,
+			"topic":        topic.String(),
 		}).Debug("trigger.pubsub: subscription exists")
 		return nil
 	}
 
 	_, err = s.client.CreateSubscription(ctx, subscriptionID, pubsub.SubscriptionConfig{
-		Topic:       s.client.Topic(topicID),
+		Topic:       topic,
 		AckDeadline: 10 * time.Second,
 	})
 	if err != nil {
@@ -118,12 +134,12 @@ func (s *PubsubSubscriber) ensureSubscription(ctx context.Context, subscriptionI
 // Subscribe - initiate PubsubSubscriber
 func (s *PubsubSubscriber) Subscribe(ctx context.Context, topic, subscription string) error {
 	// ensuring that topic exists
-	err := s.ensureTopic(ctx, topic)
+	t, err := s.ensureTopic(ctx, topic)
 	if err != nil {
 		return err
 	}
 
-	err = s.ensureSubscription(ctx, subscription, topic)
+	err = s.ensureSubscription(ctx, subscription, t)
 	if err != nil {
 		return err
 	}
@@ -109,3 +109,34 @@ func TestCallbackNoTag(t *testing.T) {
 		t.Errorf("expected repo tag %s but got %s", "latest", fp.submitted[0].Repository.Tag)
 	}
 }
+
+func TestCallbackArtifactRegistry(t *testing.T) {
+
+	fp := &fakeProvider{}
+	store, teardown := newTestingUtils()
+	defer teardown()
+	am := approvals.New(&approvals.Opts{
+		Store: store,
+	})
+	providers := provider.New([]provider.Provider{fp}, am)
+	sub := &PubsubSubscriber{disableAck: true, providers: providers}
+
+	dataMsg := &Message{
+		Action: "INSERT",
+		Digest: "us-east1-docker.pkg.dev/my-project/my-repo/hello-world@sha256:6ec128e26cd5",
+		Tag:    "us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1.1",
+	}
+	data, _ := json.Marshal(dataMsg)
+
+	sub.callback(context.Background(), &pubsub.Message{Data: data})
+
+	if len(fp.submitted) == 0 {
+		t.Fatalf("no events found in provider")
+	}
+	if fp.submitted[0].Repository.Name != "us-east1-docker.pkg.dev/my-project/my-repo/hello-world" {
+		t.Errorf("expected repo name %s but got %s", "us-east1-docker.pkg.dev/my-project/my-repo/hello-world", fp.submitted[0].Repository.Name)
+	}
+	if fp.submitted[0].Repository.Tag != "1.1.1" {
+		t.Errorf("expected repo tag %s but got %s", "1.1.1", fp.submitted[0].Repository.Tag)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/keel-hq/keel/util/image"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -56,5 +58,44 @@ func getClusterName(metadataEndpoint string) (string, error) {
 // Theoretically if someone publishes messages for updated images to
 // google pubsub - we could turn this off
 func isGoogleContainerRegistry(registry string) bool {
-	return strings.Contains(registry, "gcr.io")
+	return strings.Contains(registry, "gcr.io") || isArtifactRegistry(registry)
+}
+
+// isArtifactRegistry - Artifact Registry docker repositories are served from
+// <location>-docker.pkg.dev
+func isArtifactRegistry(registry string) bool {
+	return strings.HasSuffix(registry, "-docker.pkg.dev")
+}
+
+// imageProject - returns project ID of GCR (gcr.io/<project>/<image>) and
+// Artifact Registry (<location>-docker.pkg.dev/<project>/<repository>/<image>) images,
+// domain scoped projects (gcr.io/example.com/project/image) are returned as example.com:project
+func imageProject(ref *image.Reference) string {
+	parts := strings.Split(ref.ShortName(), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	if strings.Contains(parts[0], ".") && len(parts) > 2 {
+		return parts[0] + ":" + parts[1]
+	}
+	return parts[0]
+}
+
+// containerRegistryTopic - registries publish notifications to the gcr topic of
+// the project the image belongs to, topics of other projects are fully qualified
+func containerRegistryTopic(ownProject, imageProject string) string {
+	if imageProject == "" || imageProject == ownProject {
+		return "gcr"
+	}
+	return "projects/" + imageProject + "/topics/gcr"
+}
+
+// topicSubName - subscription suffix for a topic, subscription IDs can't contain
+// slashes or colons so fully qualified topics are shortened to gcr-<project>
+func topicSubName(topic string) string {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 4 {
+		return topic
+	}
+	return "gcr-" + strings.NewReplacer(":", "-", ".", "-").Replace(parts[1])
 }
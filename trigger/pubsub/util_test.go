@@ -30,6 +30,11 @@ func Test_isGoogleContainerRegistry(t *testing.T) {
 			args: args{registry: unsafeImageRef("gcr.io/v2-namespace/hello-world:1.1").Registry()},
 			want: true,
 		},
+		{
+			name: "artifact registry",
+			args: args{registry: unsafeImageRef("us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1").Registry()},
+			want: true,
+		},
 		{
 			name: "docker registry",
 			args: args{registry: unsafeImageRef("docker.io/v2-namespace/hello-world:1.1").Registry()},
@@ -50,6 +55,44 @@ func Test_isGoogleContainerRegistry(t *testing.T) {
 	}
 }
 
+func Test_imageProject(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "gcr.io/v2-namespace/hello-world:1.1", want: "v2-namespace"},
+		{image: "eu.gcr.io/example.com/project/hello-world:1.1", want: "example.com:project"},
+		{image: "us-east1-docker.pkg.dev/my-project/my-repo/hello-world:1.1", want: "my-project"},
+		{image: "gcr.io/hello-world:1.1", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := imageProject(unsafeImageRef(tt.image)); got != tt.want {
+				t.Errorf("imageProject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_containerRegistryTopic(t *testing.T) {
+	if got := containerRegistryTopic("my-project", "my-project"); got != "gcr" {
+		t.Errorf("expected own project topic, got: %s", got)
+	}
+	if got := containerRegistryTopic("my-project", ""); got != "gcr" {
+		t.Errorf("expected own project topic, got: %s", got)
+	}
+	topic := containerRegistryTopic("my-project", "example.com:other")
+	if topic != "projects/example.com:other/topics/gcr" {
+		t.Errorf("unexpected topic: %s", topic)
+	}
+	if got := topicSubName(topic); got != "gcr-example-com-other" {
+		t.Errorf("unexpected subscription name: %s", got)
+	}
+	if got := topicSubName("gcr"); got != "gcr" {
+		t.Errorf("unexpected subscription name: %s", got)
+	}
+}
+
 func TestClusterName(t *testing.T) {
 
 	cn := "my-cluster-x"
@@ -5,9 +5,52 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Approval expiry actions, taken once approval deadline is reached
+const (
+	// ApprovalExpiryExpire - approval request is removed (default)
+	ApprovalExpiryExpire = "expire"
+	// ApprovalExpiryEscalate - approval request is sent again, escalation channels
+	// are notified and deadline is extended once
+	ApprovalExpiryEscalate = "escalate"
+	// ApprovalExpiryApprove - update is approved
+	ApprovalExpiryApprove = "approve"
+	// ApprovalExpiryReject - update is rejected until the extended deadline
+	ApprovalExpiryReject = "reject"
+)
+
+// ParseApprovalExpiryAction - validates approval expiry action, empty action is "expire"
+func ParseApprovalExpiryAction(action string) (string, error) {
+	switch action {
+	case "":
+		return ApprovalExpiryExpire, nil
+	case ApprovalExpiryExpire, ApprovalExpiryEscalate, ApprovalExpiryApprove, ApprovalExpiryReject:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown approval expiry action '%s', expected expire, escalate, approve or reject", action)
+}
+
+// ParseApprovalDeadline - parses approval deadline, either a number of hours
+// or a duration such as "90m"
+func ParseApprovalDeadline(deadline string) (time.Duration, error) {
+	d, err := time.ParseDuration(deadline)
+	if err != nil {
+		hours, convErr := strconv.Atoi(deadline)
+		if convErr != nil {
+			return 0, err
+		}
+		d = time.Duration(hours) * time.Hour
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("approval deadline has to be positive, got '%s'", deadline)
+	}
+	return d, nil
+}
+
 type GetApprovalQuery struct {
 	ID         string
 	Identifier string
@@ -61,6 +104,25 @@ type Approval struct {
 	// Deadline for this request
 	Deadline time.Time `json:"deadline"`
 
+	// Approvers - comma separated users and @groups allowed to vote, everyone
+	// can vote when empty
+	Approvers string `json:"approvers"`
+
+	// Channels - comma separated notification channels of the resource, reminders
+	// and digests are sent there
+	Channels string `json:"channels"`
+	// RemindedAt - when approval request was last sent again as a reminder
+	RemindedAt time.Time `json:"remindedAt"`
+
+	// ExpiryAction - what happens once deadline is reached, see ApprovalExpiry* actions
+	ExpiryAction string `json:"expiryAction"`
+	// EscalationChannels - comma separated notification channels pinged once
+	// deadline is reached
+	EscalationChannels string `json:"escalationChannels"`
+	// Escalated - expiry action was already taken and deadline extended, approval
+	// expires when the new deadline is reached
+	Escalated bool `json:"escalated"`
+
 	// When this approval was created
 	CreatedAt time.Time `json:"createdAt"`
 	// WHen this approval was updated
@@ -84,6 +146,54 @@ func (a *Approval) AddVoter(voter string) {
 	a.Voters[voter] = time.Now()
 }
 
+// GetApprovers - users and @groups allowed to vote
+func (a *Approval) GetApprovers() []string {
+	var approvers []string
+	for _, approver := range strings.Split(a.Approvers, ",") {
+		if approver = strings.TrimSpace(approver); approver != "" {
+			approvers = append(approvers, approver)
+		}
+	}
+	return approvers
+}
+
+// CanVote - checks whether voter is allowed to vote, groups maps group names
+// (without "@") to their members
+func (a *Approval) CanVote(voter string, groups map[string][]string) bool {
+	approvers := a.GetApprovers()
+	if len(approvers) == 0 {
+		return true
+	}
+	if voter == "" {
+		return false
+	}
+	for _, approver := range approvers {
+		if !strings.HasPrefix(approver, "@") {
+			if strings.EqualFold(approver, voter) {
+				return true
+			}
+			continue
+		}
+		for _, member := range groups[strings.TrimPrefix(approver, "@")] {
+			if strings.EqualFold(member, voter) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetChannels - notification channels, empty for default channels
+func (a *Approval) GetChannels() []string {
+	var channels []string
+	for _, channel := range strings.Split(a.Channels, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
 // ApprovalStatus - approval status type used in approvals
 // to determine whether it was rejected/approved or still pending
 type ApprovalStatus int
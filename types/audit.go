@@ -10,10 +10,11 @@ const (
 	AuditActionDeleted = "deleted"
 
 	// Approval specific actions
-	AuditActionApprovalApproved = "approved"
-	AuditActionApprovalRejected = "rejected"
-	AuditActionApprovalExpired  = "expired"
-	AuditActionApprovalArchived = "archived"
+	AuditActionApprovalApproved  = "approved"
+	AuditActionApprovalRejected  = "rejected"
+	AuditActionApprovalExpired   = "expired"
+	AuditActionApprovalArchived  = "archived"
+	AuditActionApprovalEscalated = "escalated"
 
 	// audit specific resource kinds (others are set by
 	// providers, ie: deployment, daemonset, helm chart)
@@ -9,27 +9,47 @@ import (
 
 var (
 	_NotificationNameToValue = map[string]Notification{
-		"PreProviderSubmitNotification":   PreProviderSubmitNotification,
-		"PostProviderSubmitNotification":  PostProviderSubmitNotification,
-		"NotificationPreDeploymentUpdate": NotificationPreDeploymentUpdate,
-		"NotificationDeploymentUpdate":    NotificationDeploymentUpdate,
-		"NotificationPreReleaseUpdate":    NotificationPreReleaseUpdate,
-		"NotificationReleaseUpdate":       NotificationReleaseUpdate,
-		"NotificationSystemEvent":         NotificationSystemEvent,
-		"NotificationUpdateApproved":      NotificationUpdateApproved,
-		"NotificationUpdateRejected":      NotificationUpdateRejected,
+		"PreProviderSubmitNotification":    PreProviderSubmitNotification,
+		"PostProviderSubmitNotification":   PostProviderSubmitNotification,
+		"NotificationPreDeploymentUpdate":  NotificationPreDeploymentUpdate,
+		"NotificationDeploymentUpdate":     NotificationDeploymentUpdate,
+		"NotificationPreReleaseUpdate":     NotificationPreReleaseUpdate,
+		"NotificationReleaseUpdate":        NotificationReleaseUpdate,
+		"NotificationSystemEvent":          NotificationSystemEvent,
+		"NotificationUpdateApproved":       NotificationUpdateApproved,
+		"NotificationUpdateRejected":       NotificationUpdateRejected,
+		"NotificationDeploymentRollback":   NotificationDeploymentRollback,
+		"NotificationDryRun":               NotificationDryRun,
+		"NotificationUpdateHook":           NotificationUpdateHook,
+		"NotificationSignatureRejected":    NotificationSignatureRejected,
+		"NotificationVulnerabilitiesFound": NotificationVulnerabilitiesFound,
+		"NotificationApprovalExpired":      NotificationApprovalExpired,
+		"NotificationApprovalReminder":     NotificationApprovalReminder,
+		"NotificationApprovalDigest":       NotificationApprovalDigest,
+		"NotificationRolloutOutcome":       NotificationRolloutOutcome,
+		"NotificationDigest":               NotificationDigest,
 	}
 
 	_NotificationValueToName = map[Notification]string{
-		PreProviderSubmitNotification:   "PreProviderSubmitNotification",
-		PostProviderSubmitNotification:  "PostProviderSubmitNotification",
-		NotificationPreDeploymentUpdate: "NotificationPreDeploymentUpdate",
-		NotificationDeploymentUpdate:    "NotificationDeploymentUpdate",
-		NotificationPreReleaseUpdate:    "NotificationPreReleaseUpdate",
-		NotificationReleaseUpdate:       "NotificationReleaseUpdate",
-		NotificationSystemEvent:         "NotificationSystemEvent",
-		NotificationUpdateApproved:      "NotificationUpdateApproved",
-		NotificationUpdateRejected:      "NotificationUpdateRejected",
+		PreProviderSubmitNotification:    "PreProviderSubmitNotification",
+		PostProviderSubmitNotification:   "PostProviderSubmitNotification",
+		NotificationPreDeploymentUpdate:  "NotificationPreDeploymentUpdate",
+		NotificationDeploymentUpdate:     "NotificationDeploymentUpdate",
+		NotificationPreReleaseUpdate:     "NotificationPreReleaseUpdate",
+		NotificationReleaseUpdate:        "NotificationReleaseUpdate",
+		NotificationSystemEvent:          "NotificationSystemEvent",
+		NotificationUpdateApproved:       "NotificationUpdateApproved",
+		NotificationUpdateRejected:       "NotificationUpdateRejected",
+		NotificationDeploymentRollback:   "NotificationDeploymentRollback",
+		NotificationDryRun:               "NotificationDryRun",
+		NotificationUpdateHook:           "NotificationUpdateHook",
+		NotificationSignatureRejected:    "NotificationSignatureRejected",
+		NotificationVulnerabilitiesFound: "NotificationVulnerabilitiesFound",
+		NotificationApprovalExpired:      "NotificationApprovalExpired",
+		NotificationApprovalReminder:     "NotificationApprovalReminder",
+		NotificationApprovalDigest:       "NotificationApprovalDigest",
+		NotificationRolloutOutcome:       "NotificationRolloutOutcome",
+		NotificationDigest:               "NotificationDigest",
 	}
 )
 
@@ -37,15 +57,25 @@ func init() {
 	var v Notification
 	if _, ok := interface{}(v).(fmt.Stringer); ok {
 		_NotificationNameToValue = map[string]Notification{
-			interface{}(PreProviderSubmitNotification).(fmt.Stringer).String():   PreProviderSubmitNotification,
-			interface{}(PostProviderSubmitNotification).(fmt.Stringer).String():  PostProviderSubmitNotification,
-			interface{}(NotificationPreDeploymentUpdate).(fmt.Stringer).String(): NotificationPreDeploymentUpdate,
-			interface{}(NotificationDeploymentUpdate).(fmt.Stringer).String():    NotificationDeploymentUpdate,
-			interface{}(NotificationPreReleaseUpdate).(fmt.Stringer).String():    NotificationPreReleaseUpdate,
-			interface{}(NotificationReleaseUpdate).(fmt.Stringer).String():       NotificationReleaseUpdate,
-			interface{}(NotificationSystemEvent).(fmt.Stringer).String():         NotificationSystemEvent,
-			interface{}(NotificationUpdateApproved).(fmt.Stringer).String():      NotificationUpdateApproved,
-			interface{}(NotificationUpdateRejected).(fmt.Stringer).String():      NotificationUpdateRejected,
+			interface{}(PreProviderSubmitNotification).(fmt.Stringer).String():    PreProviderSubmitNotification,
+			interface{}(PostProviderSubmitNotification).(fmt.Stringer).String():   PostProviderSubmitNotification,
+			interface{}(NotificationPreDeploymentUpdate).(fmt.Stringer).String():  NotificationPreDeploymentUpdate,
+			interface{}(NotificationDeploymentUpdate).(fmt.Stringer).String():     NotificationDeploymentUpdate,
+			interface{}(NotificationPreReleaseUpdate).(fmt.Stringer).String():     NotificationPreReleaseUpdate,
+			interface{}(NotificationReleaseUpdate).(fmt.Stringer).String():        NotificationReleaseUpdate,
+			interface{}(NotificationSystemEvent).(fmt.Stringer).String():          NotificationSystemEvent,
+			interface{}(NotificationUpdateApproved).(fmt.Stringer).String():       NotificationUpdateApproved,
+			interface{}(NotificationUpdateRejected).(fmt.Stringer).String():       NotificationUpdateRejected,
+			interface{}(NotificationDeploymentRollback).(fmt.Stringer).String():   NotificationDeploymentRollback,
+			interface{}(NotificationDryRun).(fmt.Stringer).String():               NotificationDryRun,
+			interface{}(NotificationUpdateHook).(fmt.Stringer).String():           NotificationUpdateHook,
+			interface{}(NotificationSignatureRejected).(fmt.Stringer).String():    NotificationSignatureRejected,
+			interface{}(NotificationVulnerabilitiesFound).(fmt.Stringer).String(): NotificationVulnerabilitiesFound,
+			interface{}(NotificationApprovalExpired).(fmt.Stringer).String():      NotificationApprovalExpired,
+			interface{}(NotificationApprovalReminder).(fmt.Stringer).String():     NotificationApprovalReminder,
+			interface{}(NotificationApprovalDigest).(fmt.Stringer).String():       NotificationApprovalDigest,
+			interface{}(NotificationRolloutOutcome).(fmt.Stringer).String():       NotificationRolloutOutcome,
+			interface{}(NotificationDigest).(fmt.Stringer).String():               NotificationDigest,
 		}
 	}
 }
@@ -13,13 +13,16 @@ type Credentials struct {
 
 // TrackedImage - tracked image data+metadata
 type TrackedImage struct {
-	Image        *image.Reference  `json:"image"`
-	Trigger      TriggerType       `json:"trigger"`
-	PollSchedule string            `json:"pollSchedule"`
-	Provider     string            `json:"provider"`
-	Namespace    string            `json:"namespace"`
-	Secrets      []string          `json:"secrets"`
-	Meta         map[string]string `json:"meta"` // metadata supplied by providers
+	Image        *image.Reference `json:"image"`
+	Trigger      TriggerType      `json:"trigger"`
+	PollSchedule string           `json:"pollSchedule"`
+	Provider     string           `json:"provider"`
+	Namespace    string           `json:"namespace"`
+	Secrets      []string         `json:"secrets"`
+	// ServiceAccount - service account of workload pods, its image pull secrets are
+	// used when credentials aren't found among Secrets
+	ServiceAccount string            `json:"serviceAccount,omitempty"`
+	Meta           map[string]string `json:"meta"` // metadata supplied by providers
 	// a list of pre-release tags, ie: 1.0.0-dev, 1.5.0-prod get translated into
 	// dev, prod
 	// combined semver tags
@@ -28,6 +28,10 @@ const KeelImagePullSecretAnnotation = "keel.sh/imagePullSecret"
 // changes
 const KeelTriggerLabel = "keel.sh/trigger"
 
+// KeelInheritDefaultsAnnotation - label or annotation, set to "false" to opt the workload out
+// of default policies set on its Namespace, in KeelPolicy resources or in keel configuration
+const KeelInheritDefaultsAnnotation = "keel.sh/inherit-defaults"
+
 // KeelForceTagMatchLabel - label that checks whether tags match before force updating
 const KeelForceTagMatchLegacyLabel = "keel.sh/match-tag"
 const KeelForceTagMatchLabel = "keel.sh/matchTag"
@@ -35,7 +39,17 @@ const KeelForceTagMatchLabel = "keel.sh/matchTag"
 // KeelMatchPreReleaseAnnotation - label or annotation to set pre-release matching for SemVer, defaults to true for backward compatibility
 const KeelMatchPreReleaseAnnotation = "keel.sh/matchPreRelease"
 
-// KeelPollScheduleAnnotation - optional variable to setup custom schedule for polling, defaults to @every 10m
+// KeelAllowedTagsAnnotation - comma separated glob (or "regexp:" prefixed) patterns, when set
+// only matching tags are considered, regardless of the policy
+const KeelAllowedTagsAnnotation = "keel.sh/allowed-tags"
+
+// KeelBlockedTagsAnnotation - comma separated glob (or "regexp:" prefixed) patterns of tags
+// that are never updated to, for example "*-debug,*-dirty,nightly-*"
+const KeelBlockedTagsAnnotation = "keel.sh/blocked-tags"
+
+// KeelPollScheduleAnnotation - optional variable to setup custom schedule for polling, either
+// "@every 5m" (or other descriptor) or cron expression with 5 (or 6, with seconds) fields,
+// defaults to KeelPollDefaultSchedule unless overridden with --poll-default-schedule
 const KeelPollScheduleAnnotation = "keel.sh/pollSchedule"
 
 // KeelPollDefaultSchedule - defaul polling schedule
@@ -54,15 +68,121 @@ const KeelMinimumApprovalsLabel = "keel.sh/approvals"
 // KeelUpdateTimeAnnotation - update time
 const KeelUpdateTimeAnnotation = "keel.sh/update-time"
 
-// KeelApprovalDeadlineLabel - approval deadline
+// KeelApprovalDeadlineLabel - approval deadline, hours or duration such as "90m"
 const KeelApprovalDeadlineLabel = "keel.sh/approvalDeadline"
 
+// KeelApproversAnnotation - comma separated users and @groups allowed to vote,
+// anyone can vote when not set. Plain names match users authenticated with the API,
+// bot users are "<platform>:<user ID>" (ie: "slack:U024BE7LH") and all KeelApproval
+// resource votes are a single "kubernetes:keelapproval" voter
+const KeelApproversAnnotation = "keel.sh/approvers"
+
 // KeelApprovalDeadlineDefault - default deadline in hours
 const KeelApprovalDeadlineDefault = 24
 
+// KeelApprovalExpiryAnnotation - what happens once approval deadline is reached:
+// "expire" (default), "escalate", "approve" or "reject", see ApprovalExpiry* actions
+const KeelApprovalExpiryAnnotation = "keel.sh/approvalExpiry"
+
+// KeelApprovalEscalationChannelsAnnotation - comma separated notification channels
+// pinged when approval deadline is reached
+const KeelApprovalEscalationChannelsAnnotation = "keel.sh/approvalEscalationChannels"
+
+// KeelRolloutTimeoutAnnotation - optional duration (for example "5m") to monitor resource
+// rollout after an update, image is rolled back if the rollout doesn't converge in time.
+// Rolled back version isn't applied again until a newer version arrives or it's submitted
+// through the native webhook
+const KeelRolloutTimeoutAnnotation = "keel.sh/rolloutTimeout"
+
+// KeelRolloutWeightAnnotation - optional integer, when an image is used by several resources
+// (for example staging and production namespaces) lower weights are updated first and higher
+// weights are only updated after rollouts of lower weight resources become healthy
+const KeelRolloutWeightAnnotation = "keel.sh/rolloutWeight"
+
+// KeelCanaryAnnotation - "true" marks a designated canary that is updated before all other
+// resources using the same image, the rest is updated once the canary is healthy. A percentage
+// such as "20%" on a Deployment runs the new version in a cloned canary deployment with that
+// share of replicas first. Versions whose canary fails are not rolled out.
+const KeelCanaryAnnotation = "keel.sh/canary"
+
+// KeelCanaryTimeoutDefault - how long canaries have to become healthy unless
+// keel.sh/rolloutTimeout is set
+const KeelCanaryTimeoutDefault = 10 * time.Minute
+
+// KeelDisruptionBudgetAnnotation - what to do when an update would violate a PodDisruptionBudget
+// of the resource: "warn" (default) applies the update and sends a warning, "defer" waits until
+// the budget allows the rollout and "ignore" skips the check
+const KeelDisruptionBudgetAnnotation = "keel.sh/disruptionBudget"
+
+// KeelMinAgeAnnotation - optional duration (for example "24h") new images have to exist in
+// the registry for before they are rolled out, younger images are deferred
+const KeelMinAgeAnnotation = "keel.sh/min-age"
+
+// KeelUpdateWindowAnnotation - optional maintenance window, updates outside of it are deferred,
+// for example "Mon-Fri 22:00-04:00 UTC"
+const KeelUpdateWindowAnnotation = "keel.sh/update-window"
+
+// KeelInitContainersAnnotation - when set to "true", init container images are updated
+// with the same policy as regular containers
+const KeelInitContainersAnnotation = "keel.sh/initContainers"
+
+// KeelIgnoreContainersAnnotation - comma separated list of container names or image
+// patterns (with '*' wildcards) that keel never updates, for example "istio-proxy,*/vault:*"
+const KeelIgnoreContainersAnnotation = "keel.sh/ignore-containers"
+
+// KeelPausedAnnotation - when set to "true", updates for the resource are recorded but not
+// applied, skipped updates are replayed once annotation is removed
+const KeelPausedAnnotation = "keel.sh/paused"
+
+// KeelDryRunAnnotation - when set to "true", updates for the resource are evaluated and
+// reported but never applied
+const KeelDryRunAnnotation = "keel.sh/dryRun"
+
+// KeelUpdateHistoryAnnotation - JSON list of updates applied by keel (newest first) with
+// previous and new images, time, trigger and approvers
+const KeelUpdateHistoryAnnotation = "keel.sh/update-history"
+
+// KeelRecyclePodsAnnotation - when set to "true", pods of StatefulSets with OnDelete update
+// strategy are deleted one at a time after an update, waiting for each replacement to be ready
+const KeelRecyclePodsAnnotation = "keel.sh/recyclePods"
+
+// KeelPreUpdateHookAnnotation - hook executed before an update is applied, either an HTTP(S) URL
+// that receives a POST request with update details or a Job spec such as
+// {"image": "migrate:1.2.0", "command": ["./migrate", "up"]}. Update is skipped if the hook fails.
+// Hooks of updates processed together run in parallel, other events wait for them to finish
+const KeelPreUpdateHookAnnotation = "keel.sh/preUpdateHook"
+
+// KeelPostUpdateHookAnnotation - hook executed after an update is applied (after the rollout
+// finishes if keel.sh/rolloutTimeout is set), same format as keel.sh/preUpdateHook
+const KeelPostUpdateHookAnnotation = "keel.sh/postUpdateHook"
+
+// KeelHookTimeoutAnnotation - optional duration (for example "10m") hooks are allowed to run for
+const KeelHookTimeoutAnnotation = "keel.sh/hookTimeout"
+
+// KeelHookTimeoutDefault - default hook timeout
+const KeelHookTimeoutDefault = 5 * time.Minute
+
+// KeelVerifySignatureAnnotation - "true" requires new images to have a valid cosign signature
+// before they are rolled out, "false" opts out when verification is enabled for all resources
+const KeelVerifySignatureAnnotation = "keel.sh/verifySignature"
+
+// KeelVulnerabilityThresholdAnnotation - lowest CVE severity (low, medium, high, critical)
+// that holds back an update, "none" disables the scan for the resource
+const KeelVulnerabilityThresholdAnnotation = "keel.sh/vulnerabilityThreshold"
+
+// KeelVulnerabilityActionAnnotation - what happens to updates with vulnerabilities at or above
+// the threshold, "block" refuses them and "approve" requires an approval
+const KeelVulnerabilityActionAnnotation = "keel.sh/vulnerabilityAction"
+
 // KeelReleasePage - optional release notes URL passed on with notification
 const KeelReleaseNotesURL = "keel.sh/releaseNotes"
 
+// KeelContainerKey - returns container specific variant of a keel label or annotation,
+// for example keel.sh/policy.sidecar sets policy only for container named "sidecar"
+func KeelContainerKey(key, containerName string) string {
+	return key + "." + containerName
+}
+
 // Repository - represents main docker repository fields that
 // keel cares about
 type Repository struct {
@@ -188,6 +308,8 @@ type EventNotification struct {
 	// Channels is an optional variable to override
 	// default channel(-s) when performing an update
 	Channels []string `json:"-"`
+	// Webhooks - optional webhook URLs to override default webhook endpoint
+	Webhooks []string `json:"-"`
 
 	Metadata map[string]string `json:"metadata"`
 }
@@ -238,6 +360,34 @@ const (
 
 	NotificationUpdateApproved
 	NotificationUpdateRejected
+
+	NotificationDeploymentRollback
+
+	// NotificationDryRun - update that would have been applied if dry run wasn't enabled
+	NotificationDryRun
+
+	// NotificationUpdateHook - pre or post update hook result
+	NotificationUpdateHook
+
+	// NotificationSignatureRejected - update refused because new image isn't signed by a
+	// trusted key or identity
+	NotificationSignatureRejected
+	// NotificationVulnerabilitiesFound - new image has vulnerabilities above the configured
+	// severity threshold
+	NotificationVulnerabilitiesFound
+
+	// NotificationApprovalExpired - approval deadline was reached, request was escalated,
+	// approved or rejected
+	NotificationApprovalExpired
+
+	// NotificationApprovalReminder - approval is still pending after the reminder period
+	NotificationApprovalReminder
+	// NotificationApprovalDigest - daily list of pending approvals
+	NotificationApprovalDigest
+	// NotificationRolloutOutcome - updated resource rollout converged, failed or timed out
+	NotificationRolloutOutcome
+	// NotificationDigest - notifications batched over the digest interval
+	NotificationDigest
 )
 
 func (n Notification) String() string {
@@ -260,6 +410,26 @@ func (n Notification) String() string {
 		return "update approved"
 	case NotificationUpdateRejected:
 		return "update rejected "
+	case NotificationDeploymentRollback:
+		return "deployment rollback"
+	case NotificationDryRun:
+		return "dry run update"
+	case NotificationUpdateHook:
+		return "update hook"
+	case NotificationSignatureRejected:
+		return "signature rejected"
+	case NotificationVulnerabilitiesFound:
+		return "vulnerabilities found"
+	case NotificationApprovalExpired:
+		return "approval deadline reached"
+	case NotificationApprovalReminder:
+		return "approval reminder"
+	case NotificationApprovalDigest:
+		return "pending approvals digest"
+	case NotificationRolloutOutcome:
+		return "rollout outcome"
+	case NotificationDigest:
+		return "notification digest"
 	default:
 		return "unknown"
 	}
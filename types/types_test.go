@@ -157,3 +157,55 @@ func TestParseReleaseNotesURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseApprovalDeadline(t *testing.T) {
+	tests := []struct {
+		deadline string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{deadline: "24", want: 24 * time.Hour},
+		{deadline: "24h", want: 24 * time.Hour},
+		{deadline: "90m", want: 90 * time.Minute},
+		{deadline: "0", wantErr: true},
+		{deadline: "-1h", wantErr: true},
+		{deadline: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseApprovalDeadline(tt.deadline)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseApprovalDeadline(%q) error = %v, wantErr %v", tt.deadline, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseApprovalDeadline(%q) = %s, want %s", tt.deadline, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalCanVote(t *testing.T) {
+	groups := map[string][]string{"platform-team": {"carol"}}
+	tests := []struct {
+		approvers string
+		voter     string
+		want      bool
+	}{
+		{approvers: "", voter: "anyone", want: true},
+		{approvers: "", voter: "", want: true},
+		{approvers: "alice,bob", voter: "bob", want: true},
+		{approvers: "alice,bob", voter: "Alice", want: true},
+		{approvers: "alice,bob", voter: "mallory", want: false},
+		{approvers: "alice,bob", voter: "", want: false},
+		{approvers: "alice, @platform-team", voter: "carol", want: true},
+		{approvers: "@sre", voter: "carol", want: false},
+		{approvers: "alice,slack:U024BE7LH", voter: "slack:U024BE7LH", want: true},
+		{approvers: "alice,slack:U024BE7LH", voter: "slack:alice", want: false},
+		{approvers: "alice", voter: "kubernetes:keelapproval", want: false},
+	}
+	for _, tt := range tests {
+		a := &Approval{Approvers: tt.approvers}
+		if got := a.CanVote(tt.voter, groups); got != tt.want {
+			t.Errorf("CanVote(%q) with approvers %q = %v, want %v", tt.voter, tt.approvers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package image
+
+import (
+	"fmt"
+)
+
+// MatchMode - defines which image repositories are considered the same when
+// events are matched with workloads and Helm releases
+type MatchMode string
+
+// available match modes
+const (
+	// MatchCanonical - registry and repository have to match after normalization, Docker Hub
+	// references such as "nginx", "docker.io/library/nginx" and "index.docker.io/nginx" are equal
+	MatchCanonical MatchMode = "canonical"
+	// MatchName - only repository path is compared and registry is ignored, for example
+	// "mirror.example.com/library/nginx" matches events for "nginx", useful with registry mirrors
+	MatchName MatchMode = "name"
+)
+
+// matchMode - set once on startup, shared by providers, triggers and caches
+var matchMode = MatchCanonical
+
+// ParseMatchMode - parses match mode name
+func ParseMatchMode(mode string) (MatchMode, error) {
+	switch MatchMode(mode) {
+	case "", MatchCanonical:
+		return MatchCanonical, nil
+	case MatchName:
+		return MatchName, nil
+	}
+	return "", fmt.Errorf("unknown image match mode '%s', should be either '%s' or '%s'", mode, MatchCanonical, MatchName)
+}
+
+// SetMatchMode - sets how image repositories are matched, should be called before
+// caches are populated
+func SetMatchMode(mode MatchMode) {
+	matchMode = mode
+}
+
+// MatchKey - returns key that is the same for all matching references
+func MatchKey(ref *Reference) string {
+	if matchMode == MatchName {
+		return ref.ShortName()
+	}
+	return ref.Repository()
+}
+
+// RepositoryMatches - checks whether both references point to the same repository
+func RepositoryMatches(a, b *Reference) bool {
+	return MatchKey(a) == MatchKey(b)
+}
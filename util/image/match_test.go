@@ -0,0 +1,51 @@
+package image
+
+import (
+	"testing"
+)
+
+func TestRepositoryMatches(t *testing.T) {
+	defer SetMatchMode(MatchCanonical)
+
+	tests := []struct {
+		mode  MatchMode
+		a, b  string
+		match bool
+	}{
+		{MatchCanonical, "nginx", "docker.io/library/nginx:1.2", true},
+		{MatchCanonical, "index.docker.io/nginx", "nginx:latest", true},
+		{MatchCanonical, "registry-1.docker.io/library/nginx", "docker.io/nginx", true},
+		{MatchCanonical, "registry.hub.docker.com/karolisr/webhook-demo", "karolisr/webhook-demo:0.0.1", true},
+		{MatchCanonical, "mirror.example.com/library/nginx", "nginx", false},
+		{MatchCanonical, "gcr.io/v2-namespace/hello-world", "gcr.io/v2-namespace/other", false},
+		{MatchName, "mirror.example.com/library/nginx:1.1", "nginx:1.2", true},
+		{MatchName, "eu.gcr.io/v2-namespace/hello-world", "gcr.io/v2-namespace/hello-world", true},
+		{MatchName, "gcr.io/v2-namespace/hello-world", "gcr.io/other-namespace/hello-world", false},
+	}
+
+	for _, tt := range tests {
+		SetMatchMode(tt.mode)
+		a, err := Parse(tt.a)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %s", tt.a, err)
+		}
+		b, err := Parse(tt.b)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %s", tt.b, err)
+		}
+		if RepositoryMatches(a, b) != tt.match {
+			t.Errorf("%s: expected %s and %s match to be %v", tt.mode, tt.a, tt.b, tt.match)
+		}
+	}
+}
+
+func TestParseMatchMode(t *testing.T) {
+	for _, mode := range []string{"", "canonical", "name"} {
+		if _, err := ParseMatchMode(mode); err != nil {
+			t.Errorf("unexpected error for '%s': %s", mode, err)
+		}
+	}
+	if _, err := ParseMatchMode("strict"); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+}
@@ -6,9 +6,10 @@ import (
 
 // Reference is an opaque object that include identifier such as a name, tag, repository, registry, etc...
 type Reference struct {
-	named  Named  `json:"named"`
-	tag    string `json:"tag"`
-	scheme string `json:"scheme"` // registry scheme, i.e. http, https
+	named  Named
+	tag    string
+	digest string // only set when image has both tag and digest (ie: debian:8.2@sha256:...)
+	scheme string // registry scheme, i.e. http, https
 }
 
 func (r Reference) String() string {
@@ -33,6 +34,12 @@ func (r Reference) Tag() string {
 	return ""
 }
 
+// Digest returns the image's digest when image is referenced by both tag and digest
+// (ie: debian:8.2@sha256:...), for images referenced only by digest use Tag().
+func (r Reference) Digest() string {
+	return r.digest
+}
+
 // Registry returns the image's registry. (ie: host[:port])
 func (r Reference) Registry() string {
 	return r.named.Hostname()
@@ -84,15 +91,9 @@ func Parse(remote string) (*Reference, error) {
 
 	n = WithDefaultTag(n)
 
-	var t string
-	switch x := n.(type) {
-	case Canonical:
-		t = "@" + x.Digest().String()
-	case NamedTagged:
-		t = ":" + x.Tag()
-	}
+	t, d := getTagAndDigest(n)
 
-	return &Reference{named: n, tag: t, scheme: scheme}, nil
+	return &Reference{named: n, tag: t, digest: d, scheme: scheme}, nil
 }
 
 // ParseRepo - parses remote
@@ -109,15 +110,9 @@ func ParseRepo(remote string) (*Repository, error) {
 
 	n = WithDefaultTag(n)
 
-	var t string
-	switch x := n.(type) {
-	case Canonical:
-		t = "@" + x.Digest().String()
-	case NamedTagged:
-		t = ":" + x.Tag()
-	}
+	t, d := getTagAndDigest(n)
 
-	ref := &Reference{named: n, tag: t, scheme: scheme}
+	ref := &Reference{named: n, tag: t, digest: d, scheme: scheme}
 
 	return &Repository{
 		Name:       ref.Name(),
@@ -129,3 +124,18 @@ func ParseRepo(remote string) (*Repository, error) {
 		Scheme:     ref.scheme,
 	}, nil
 }
+
+// getTagAndDigest - returns reference tag (":tag" or "@digest") and digest, digest
+// is only returned separately when image has both tag and digest (name:tag@digest)
+func getTagAndDigest(n Named) (tag string, digest string) {
+	switch x := n.(type) {
+	case Canonical:
+		if tagged, ok := n.(NamedTagged); ok && tagged.Tag() != "" {
+			return ":" + tagged.Tag(), x.Digest().String()
+		}
+		return "@" + x.Digest().String(), ""
+	case NamedTagged:
+		return ":" + x.Tag(), ""
+	}
+	return "", ""
+}
@@ -34,6 +34,40 @@ func TestShortParseWithTag(t *testing.T) {
 
 }
 
+func TestParseWithTagAndDigest(t *testing.T) {
+	digest := "sha256:a5d5b1a55b5e2d3a1cd95c6d2e4cd29d5c2b81bd1b2e4a2f1e1a5b1c4fa6b2c3"
+
+	reference, err := Parse("gcr.io/v2-namespace/hello-world:1.1@" + digest)
+	if err != nil {
+		t.Fatalf("error while parsing tag: %s", err)
+	}
+
+	if reference.Tag() != "1.1" {
+		t.Errorf("unexpected tag: %s", reference.Tag())
+	}
+
+	if reference.Digest() != digest {
+		t.Errorf("unexpected digest: %s", reference.Digest())
+	}
+
+	if reference.Repository() != "gcr.io/v2-namespace/hello-world" {
+		t.Errorf("unexpected repository: %s", reference.Repository())
+	}
+
+	digestOnly, err := Parse("gcr.io/v2-namespace/hello-world@" + digest)
+	if err != nil {
+		t.Fatalf("error while parsing tag: %s", err)
+	}
+
+	if digestOnly.Tag() != digest {
+		t.Errorf("unexpected tag: %s", digestOnly.Tag())
+	}
+
+	if digestOnly.Digest() != "" {
+		t.Errorf("unexpected digest: %s", digestOnly.Digest())
+	}
+}
+
 func TestParseRepo(t *testing.T) {
 	type args struct {
 		remote string
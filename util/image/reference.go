@@ -25,6 +25,13 @@ const (
 	DefaultRepoPrefix = "library/"
 )
 
+// dockerHubHostnames - Docker Hub registry aliases, normalized to DefaultRegistryHostname
+var dockerHubHostnames = map[string]bool{
+	WrongRegistryHostname:     true,
+	"registry-1.docker.io":    true,
+	"registry.hub.docker.com": true,
+}
+
 // Repository is an object created from Named interface
 type Repository struct {
 	Name       string // Name returns the image's name. (ie: debian[:8.2])
@@ -79,6 +86,18 @@ func ParseNamed(s string) (Named, error) {
 		return nil, err
 	}
 	if canonical, isCanonical := named.(reference.Canonical); isCanonical {
+		// image pinned to a digest while keeping the tag, i.e. name:tag@sha256:...
+		if tagged, isTagged := named.(reference.NamedTagged); isTagged {
+			t, err := WithTag(r, tagged.Tag())
+			if err != nil {
+				return nil, err
+			}
+			ref, err := reference.WithDigest(t, canonical.Digest())
+			if err != nil {
+				return nil, err
+			}
+			return &taggedCanonicalRef{namedRef{ref}}, nil
+		}
 		return WithDigest(r, canonical.Digest())
 	}
 
@@ -134,6 +153,9 @@ type taggedRef struct {
 type canonicalRef struct {
 	namedRef
 }
+type taggedCanonicalRef struct {
+	namedRef
+}
 
 func (r *namedRef) FullName() string {
 	hostname, remoteName := splitHostname(r.Name())
@@ -153,6 +175,12 @@ func (r *taggedRef) Tag() string {
 func (r *canonicalRef) Digest() digest.Digest {
 	return r.namedRef.Named.(reference.Canonical).Digest()
 }
+func (r *taggedCanonicalRef) Tag() string {
+	return r.namedRef.Named.(reference.NamedTagged).Tag()
+}
+func (r *taggedCanonicalRef) Digest() digest.Digest {
+	return r.namedRef.Named.(reference.Canonical).Digest()
+}
 
 // WithDefaultTag adds a default tag to a reference if it only has a repo name.
 func WithDefaultTag(ref Named) Named {
@@ -183,7 +211,7 @@ func splitHostname(name string) (hostname, remoteName string) {
 	} else {
 		hostname, remoteName = name[:i], name[i+1:]
 	}
-	if hostname == WrongRegistryHostname {
+	if dockerHubHostnames[hostname] {
 		hostname = DefaultRegistryHostname
 	}
 	if hostname == DefaultRegistryHostname && !strings.ContainsRune(remoteName, '/') {
@@ -0,0 +1,124 @@
+// Package schedule - polling schedules, keel.sh/pollSchedule accepts descriptors
+// ("@every 5m", "@hourly"), standard 5 field cron expressions ("*/5 * * * *") and
+// 6 field expressions with seconds ("0 */5 * * * *")
+package schedule
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/rusenask/cron"
+)
+
+var (
+	defaultMu       sync.RWMutex
+	defaultSchedule = types.KeelPollDefaultSchedule
+)
+
+// Parse - parses and validates schedule
+func Parse(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("schedule cannot be empty")
+	}
+
+	var (
+		s   cron.Schedule
+		err error
+	)
+	switch {
+	case strings.HasPrefix(spec, "@every"):
+		// parsed here as cron rounds invalid and too short durations up to a second
+		d, perr := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if perr != nil {
+			return nil, fmt.Errorf("invalid @every duration: %s", perr)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every duration must be at least 1s, got %s", d)
+		}
+		return cron.Every(d), nil
+	case strings.HasPrefix(spec, "@"):
+		s, err = cron.Parse(spec)
+	default:
+		switch len(strings.Fields(spec)) {
+		case 5:
+			s, err = cron.ParseStandard(spec)
+		case 6:
+			s, err = cron.Parse(spec)
+		default:
+			return nil, fmt.Errorf("expected 5 or 6 cron fields or a descriptor such as '@every 5m', got '%s'", spec)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// for example "0 0 30 2 *"
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule '%s' never runs", spec)
+	}
+	return s, nil
+}
+
+// Validate - checks whether schedule is valid
+func Validate(spec string) error {
+	_, err := Parse(spec)
+	return err
+}
+
+// SetDefault - overrides schedule used for workloads without keel.sh/pollSchedule
+func SetDefault(spec string) error {
+	if err := Validate(spec); err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultSchedule = strings.TrimSpace(spec)
+	defaultMu.Unlock()
+	return nil
+}
+
+// Default - returns schedule used for workloads without keel.sh/pollSchedule
+func Default() string {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultSchedule
+}
+
+// jitterSchedule - delays every run by a random offset so that images with the same
+// schedule are not polled at once, offset is removed before calculating the next run
+// so runs don't drift
+type jitterSchedule struct {
+	schedule cron.Schedule
+	max      time.Duration
+	offset   time.Duration
+}
+
+// WithJitter - delays runs of schedule by up to max, jitter is limited to half of the
+// interval between runs so that no run is skipped
+func WithJitter(s cron.Schedule, max time.Duration) cron.Schedule {
+	if max <= 0 {
+		return s
+	}
+	return &jitterSchedule{schedule: s, max: max}
+}
+
+func (s *jitterSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.Add(-s.offset))
+	if next.IsZero() {
+		return next
+	}
+
+	max := s.max
+	if interval := s.schedule.Next(next).Sub(next); interval/2 < max {
+		max = interval / 2
+	}
+	s.offset = 0
+	if max > 0 {
+		s.offset = time.Duration(rand.Int63n(int64(max)))
+	}
+	return next.Add(s.offset)
+}
@@ -0,0 +1,86 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keel-hq/keel/types"
+	"github.com/rusenask/cron"
+)
+
+func TestParse(t *testing.T) {
+	start := time.Date(2018, 3, 1, 10, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec    string
+		next    time.Time
+		wantErr bool
+	}{
+		{spec: "@every 5m", next: start.Add(5 * time.Minute)},
+		{spec: " @every 1h30m ", next: start.Add(90 * time.Minute)},
+		{spec: "@hourly", next: time.Date(2018, 3, 1, 11, 0, 0, 0, time.UTC)},
+		// standard cron, minutes first
+		{spec: "*/15 * * * *", next: time.Date(2018, 3, 1, 10, 15, 0, 0, time.UTC)},
+		// with seconds
+		{spec: "0 */15 * * * *", next: time.Date(2018, 3, 1, 10, 15, 0, 0, time.UTC)},
+		{spec: "", wantErr: true},
+		{spec: "@every", wantErr: true},
+		{spec: "@every 10ms", wantErr: true},
+		{spec: "@every 5 minutes", wantErr: true},
+		{spec: "@sometimes", wantErr: true},
+		{spec: "* * *", wantErr: true},
+		{spec: "61 * * * *", wantErr: true},
+		{spec: "0 0 30 2 *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := s.Next(start); !got.Equal(tt.next) {
+				t.Errorf("Next() = %s, want %s", got, tt.next)
+			}
+		})
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	defer SetDefault(types.KeelPollDefaultSchedule)
+
+	if Default() != types.KeelPollDefaultSchedule {
+		t.Errorf("unexpected default schedule: %s", Default())
+	}
+	if err := SetDefault("every 10s"); err == nil {
+		t.Errorf("expected error for invalid schedule")
+	}
+	if err := SetDefault("*/5 * * * *"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if Default() != "*/5 * * * *" {
+		t.Errorf("unexpected default schedule: %s", Default())
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	if s := WithJitter(cron.Every(time.Minute), 0); s != cron.Every(time.Minute) {
+		t.Errorf("expected schedule without jitter")
+	}
+
+	start := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	s := WithJitter(cron.Every(time.Minute), time.Hour)
+
+	now := start
+	for i := 1; i <= 100; i++ {
+		next := s.Next(now)
+		base := start.Add(time.Duration(i) * time.Minute)
+		// jitter is limited to half of the interval and doesn't accumulate
+		if next.Before(base) || !next.Before(base.Add(30*time.Second)) {
+			t.Fatalf("run %d at %s, expected within 30s after %s", i, next, base)
+		}
+		now = next
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/keel-hq/keel/util/image"
 
 	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
@@ -18,15 +20,24 @@ type FakeK8sImplementer struct {
 	NamespacesList   *v1.NamespaceList
 	DeploymentSingle *apps_v1.Deployment
 	DeploymentList   *apps_v1.DeploymentList
+	DaemonSetList    *apps_v1.DaemonSetList
 
 	// stores value of an updated deployment
 	Updated *k8s.GenericResource
 
 	AvailableSecret map[string]*v1.Secret
 
+	AvailableServiceAccounts map[string]*v1.ServiceAccount
+
 	AvailablePods *v1.PodList
 	DeletedPods   []*v1.Pod
 
+	CreatedJobs []*batch_v1.Job
+	DeletedJobs []string
+
+	CreatedDeployments []*apps_v1.Deployment
+	DeletedDeployments []string
+
 	// error to return
 	Error error
 }
@@ -46,6 +57,11 @@ func (i *FakeK8sImplementer) Deployments(namespace string) (*apps_v1.DeploymentL
 	return i.DeploymentList, nil
 }
 
+// DaemonSets - available daemonsets
+func (i *FakeK8sImplementer) DaemonSets(namespace string) (*apps_v1.DaemonSetList, error) {
+	return i.DaemonSetList, nil
+}
+
 // Update - update deployment
 func (i *FakeK8sImplementer) Update(obj *k8s.GenericResource) error {
 	i.Updated = obj
@@ -64,6 +80,18 @@ func (i *FakeK8sImplementer) Secret(namespace, name string) (*v1.Secret, error)
 	return s, nil
 }
 
+// ServiceAccount - get service account
+func (i *FakeK8sImplementer) ServiceAccount(namespace, name string) (*v1.ServiceAccount, error) {
+	if i.Error != nil {
+		return nil, i.Error
+	}
+	sa, ok := i.AvailableServiceAccounts[name]
+	if !ok {
+		return nil, fmt.Errorf("service account %s not found", name)
+	}
+	return sa, nil
+}
+
 // Pods - available pods
 func (i *FakeK8sImplementer) Pods(namespace, labelSelector string) (*v1.PodList, error) {
 	return i.AvailablePods, nil
@@ -88,6 +116,45 @@ func (i *FakeK8sImplementer) DeletePod(namespace, name string, opts *meta_v1.Del
 	return nil
 }
 
+// CreateDeployment - adds deployment to CreatedDeployments list
+func (i *FakeK8sImplementer) CreateDeployment(deployment *apps_v1.Deployment) (*apps_v1.Deployment, error) {
+	i.CreatedDeployments = append(i.CreatedDeployments, deployment)
+	return deployment, i.Error
+}
+
+// DeleteDeployment - adds deployment to DeletedDeployments list
+func (i *FakeK8sImplementer) DeleteDeployment(namespace, name string) error {
+	i.DeletedDeployments = append(i.DeletedDeployments, namespace+"/"+name)
+	return i.Error
+}
+
+// DeleteJob - adds job to DeletedJobs list
+func (i *FakeK8sImplementer) DeleteJob(namespace, name string) error {
+	i.DeletedJobs = append(i.DeletedJobs, namespace+"/"+name)
+	return i.Error
+}
+
+// PodDisruptionBudgets - returns no budgets
+func (i *FakeK8sImplementer) PodDisruptionBudgets(namespace string) (*policy_v1beta1.PodDisruptionBudgetList, error) {
+	return &policy_v1beta1.PodDisruptionBudgetList{}, nil
+}
+
+// CreateJob - adds job to CreatedJobs list
+func (i *FakeK8sImplementer) CreateJob(job *batch_v1.Job) (*batch_v1.Job, error) {
+	i.CreatedJobs = append(i.CreatedJobs, job)
+	return job, i.Error
+}
+
+// Job - returns last created job with the same name
+func (i *FakeK8sImplementer) Job(namespace, name string) (*batch_v1.Job, error) {
+	for idx := len(i.CreatedJobs) - 1; idx >= 0; idx-- {
+		if i.CreatedJobs[idx].Namespace == namespace && i.CreatedJobs[idx].Name == name {
+			return i.CreatedJobs[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("job %s/%s not found", namespace, name)
+}
+
 func GetTrackedImage(i string) *types.TrackedImage {
 	ref, err := image.Parse(i)
 	if err != nil {